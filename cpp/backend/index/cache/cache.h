@@ -97,6 +97,13 @@ class Cached {
     return *hash_;
   }
 
+  // Calls the given operation for each key registered in this index and its
+  // ordinal number. The iteration order is defined by the wrapped index.
+  template <typename Op>
+  absl::Status ForEach(const Op& op) const {
+    return index_.ForEach(op);
+  }
+
   // Flush unsaved index keys to disk.
   absl::Status Flush() { return index_.Flush(); }
 
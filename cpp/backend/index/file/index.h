@@ -92,6 +92,12 @@ class FileIndex {
   // Computes a hash over the full content of this index.
   absl::StatusOr<Hash> GetHash() const;
 
+  // Calls the given operation for each key registered in this index and its
+  // ordinal number. The iteration order is undefined. The iteration is aborted
+  // by the first error returned by the operation.
+  template <typename Op>
+  absl::Status ForEach(const Op& op) const;
+
   // Flush unsaved index keys to disk.
   absl::Status Flush();
 
@@ -435,6 +441,26 @@ void FileIndex<K, I, F, page_size>::Dump() const {
   }
 }
 
+template <Trivial K, std::integral I, template <std::size_t> class F,
+          std::size_t page_size>
+template <typename Op>
+absl::Status FileIndex<K, I, F, page_size>::ForEach(const Op& op) const {
+  // All entries are listed by the pages of the buckets.
+  for (std::size_t i = 0; i < num_buckets_; i++) {
+    ASSIGN_OR_RETURN(Page * cur, primary_pool_.template Get<Page>(i));
+    while (cur != nullptr) {
+      for (std::size_t j = 0; j < cur->Size(); j++) {
+        const Entry& entry = (*cur)[j];
+        RETURN_IF_ERROR(op(entry.key, entry.value));
+      }
+      PageId next = cur->GetNext();
+      ASSIGN_OR_RETURN(cur, overflow_pool_.template Get<Page>(next));
+      cur = next != 0 ? cur : nullptr;
+    }
+  }
+  return absl::OkStatus();
+}
+
 template <Trivial K, std::integral I, template <std::size_t> class F,
           std::size_t page_size>
 MemoryFootprint FileIndex<K, I, F, page_size>::GetMemoryFootprint() const {
@@ -40,6 +40,28 @@ class MultiLevelDbIndex : public internal::LevelDbIndexBase<K, I, 0> {
     return Open(path);
   }
 
+  // Calls the given operation for each key registered in this index and its
+  // ordinal number, in the order of the keys. The iteration is aborted by the
+  // first error returned by the operation.
+  template <typename Op>
+  absl::Status ForEach(const Op& op) const {
+    ASSIGN_OR_RETURN(auto iter, ldb_.Begin());
+    while (iter.Valid()) {
+      // Besides the keys, the database holds the hash and the last index.
+      auto key = iter.Key();
+      std::string_view view(key.data(), key.size());
+      if (key.size() == sizeof(K) && view != GetHashKey() &&
+          view != GetLastIndexKey()) {
+        K k;
+        std::memcpy(&k, key.data(), sizeof(K));
+        ASSIGN_OR_RETURN(auto value, internal::ParseDBResult<I>(iter.Value()));
+        RETURN_IF_ERROR(op(k, value));
+      }
+      RETURN_IF_ERROR(iter.Next());
+    }
+    return iter.Status();
+  }
+
  private:
   explicit MultiLevelDbIndex(LevelDb ldb)
       : internal::LevelDbIndexBase<K, I, 0>(), ldb_(std::move(ldb)) {}
@@ -91,6 +91,20 @@ class InMemoryIndex {
     return hash_;
   }
 
+  // Calls the given operation for each key registered in this index and its
+  // ordinal number, in the order of the ordinal numbers. The iteration is
+  // aborted by the first error returned by the operation.
+  template <typename Op>
+  absl::Status ForEach(const Op& op) const {
+    const auto& list = *list_;
+    for (std::size_t i = 0; i < list.size(); i++) {
+      if (auto status = op(list[i], I(i)); !status.ok()) {
+        return status;
+      }
+    }
+    return absl::OkStatus();
+  }
+
   // Creates a snapshot of this index shielded from future additions that can be
   // safely accessed concurrently to other operations. It internally references
   // state of this index and thus must not outlive this index object.
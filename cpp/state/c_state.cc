@@ -10,9 +10,11 @@
 
 #include "state/c_state.h"
 
+#include <array>
 #include <cstddef>
 #include <cstring>
 #include <filesystem>
+#include <fstream>
 #include <span>
 #include <sstream>
 #include <string_view>
@@ -23,6 +25,7 @@
 #include "common/memory_usage.h"
 #include "common/type.h"
 #include "state/configurations.h"
+#include "state/content_visitor.h"
 #include "state/s1/state.h"
 #include "state/s2/state.h"
 #include "state/s3/state.h"
@@ -57,6 +60,8 @@ class WorldState {
 
   virtual MemoryFootprint GetMemoryFootprint() const = 0;
 
+  virtual absl::Status VisitContent(ContentVisitor& visitor) = 0;
+
   virtual absl::Status Flush() = 0;
   virtual absl::Status Close() = 0;
 };
@@ -118,6 +123,10 @@ class WorldStateWrapper : public WorldState {
     return state_.GetMemoryFootprint();
   }
 
+  absl::Status VisitContent(ContentVisitor& visitor) override {
+    return state_.VisitContent(visitor);
+  }
+
  protected:
   State state_;
 
@@ -179,12 +188,66 @@ class WorldStateWrapper : public WorldState {
       return MemoryFootprint(*this);
     }
 
+    absl::Status VisitContent(ContentVisitor&) override {
+      return absl::UnimplementedError(
+          "Content enumeration is not supported for archive states");
+    }
+
    private:
     Archive& archive_;
     BlockId block_;
   };
 };
 
+// A ContentWriter serializes the visited content into an output stream. Each
+// account is encoded by an 'A' followed by its address, balance, nonce, the
+// length of its code as a 4-byte little-endian integer, and the code. Each
+// storage slot is encoded by an 'S' followed by its address, key, and value.
+class ContentWriter : public ContentVisitor {
+ public:
+  ContentWriter(std::ostream& out) : out_(out) {}
+
+  absl::Status VisitAccount(const Address& address, const Balance& balance,
+                            const Nonce& nonce,
+                            std::span<const std::byte> code) override {
+    std::uint32_t size = code.size();
+    std::array<char, 4> length{
+        static_cast<char>(size), static_cast<char>(size >> 8),
+        static_cast<char>(size >> 16), static_cast<char>(size >> 24)};
+    out_.put('A');
+    Write(address);
+    Write(balance);
+    Write(nonce);
+    Write(length);
+    out_.write(reinterpret_cast<const char*>(code.data()), code.size());
+    return GetStatus();
+  }
+
+  absl::Status VisitSlot(const Address& address, const Key& key,
+                         const Value& value) override {
+    out_.put('S');
+    Write(address);
+    Write(key);
+    Write(value);
+    return GetStatus();
+  }
+
+ private:
+  template <Trivial T>
+  void Write(const T& value) {
+    out_.write(reinterpret_cast<const char*>(&value), sizeof(T));
+  }
+
+  absl::Status GetStatus() const {
+    if (!out_) {
+      return absl::InternalError("Failed to write content");
+    }
+    return absl::OkStatus();
+  }
+
+  std::ostream& out_;
+};
+
 template <typename State>
 WorldState* OpenState(const std::filesystem::path& directory,
                       bool with_archive) {
@@ -420,4 +483,29 @@ void Carmen_GetMemoryFootprint(C_State state, char** out,
   std::memcpy(*out, data.data(), data.size());
 }
 
+C_bool Carmen_ExportContent(C_State state, const char* file, int length) {
+  auto& s = *reinterpret_cast<carmen::WorldState*>(state);
+  std::filesystem::path path(std::string_view(file, length));
+  std::ofstream out(path, std::ios::binary | std::ios::trunc);
+  if (!out) {
+    std::cout << "WARNING: Failed to open content file: " << path << "\n"
+              << std::flush;
+    return false;
+  }
+  carmen::ContentWriter writer(out);
+  auto res = s.VisitContent(writer);
+  if (res.ok()) {
+    out.close();
+    if (!out) {
+      res = absl::InternalError("Failed to close content file");
+    }
+  }
+  if (!res.ok()) {
+    std::cout << "WARNING: Failed to export content: " << res << "\n"
+              << std::flush;
+    return false;
+  }
+  return true;
+}
+
 }  // extern "C"
@@ -137,6 +137,17 @@ void Carmen_GetHash(C_State state, C_Hash out_hash);
 // caller.
 void Carmen_GetMemoryFootprint(C_State state, char** out, uint64_t* out_length);
 
+// ------------------------------ Content Export ------------------------------
+
+// Writes all existing accounts and their non-zero storage slots of the given
+// state into the given file, replacing its content. Each account is encoded by
+// an 'A' followed by its address, balance, nonce, the length of its code as a
+// 4-byte little-endian integer, and the code. Each storage slot is encoded by
+// an 'S' followed by its address, key, and value. The order of the records is
+// undefined. Returns true on success, false otherwise. Archive states do not
+// support the export of their content.
+C_bool Carmen_ExportContent(C_State state, const char* file, int length);
+
 #if __cplusplus
 }
 #endif
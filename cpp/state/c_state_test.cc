@@ -10,6 +10,10 @@
 
 #include "state/c_state.h"
 
+#include <fstream>
+#include <iterator>
+#include <string>
+
 #include "common/account_state.h"
 #include "common/file_util.h"
 #include "common/hash.h"
@@ -508,6 +512,73 @@ TEST_P(CStateTest, MemoryFootprintCanBeObtained) {
   free(data);
 }
 
+// Appends the binary representation of the given value to the given string.
+template <Trivial T>
+void Append(std::string& out, const T& value) {
+  out.append(reinterpret_cast<const char*>(&value), sizeof(T));
+}
+
+TEST_P(CStateTest, ContentCanBeExported) {
+  auto state = GetState();
+  ASSERT_NE(state, nullptr);
+
+  Address addr1{0x01};
+  Address addr2{0x02};
+  Balance balance{0x03};
+  Nonce nonce{0x04};
+  Key key{0x05};
+  Value value{0x06};
+  std::vector<std::byte> code({std::byte{12}, std::byte{14}});
+  Carmen_CreateAccount(state, &addr1);
+  Carmen_SetBalance(state, &addr1, &balance);
+  Carmen_SetNonce(state, &addr1, &nonce);
+  Carmen_SetCode(state, &addr1, code.data(), code.size());
+  Carmen_SetStorageValue(state, &addr1, &key, &value);
+
+  // Deleted accounts and their slots are not exported.
+  Carmen_CreateAccount(state, &addr2);
+  Carmen_SetStorageValue(state, &addr2, &key, &value);
+  Carmen_DeleteAccount(state, &addr2);
+
+  TempFile file;
+  auto path = file.GetPath().string();
+  ASSERT_TRUE(Carmen_ExportContent(state, path.c_str(), path.size()));
+
+  std::ifstream in(path, std::ios::binary);
+  std::string content((std::istreambuf_iterator<char>(in)),
+                      std::istreambuf_iterator<char>());
+
+  std::string account = "A";
+  Append(account, addr1);
+  Append(account, balance);
+  Append(account, nonce);
+  Append(account, std::array<char, 4>{2, 0, 0, 0});
+  Append(account, code[0]);
+  Append(account, code[1]);
+
+  std::string slot = "S";
+  Append(slot, addr1);
+  Append(slot, key);
+  Append(slot, value);
+
+  EXPECT_EQ(content.size(), account.size() + slot.size());
+  EXPECT_NE(content.find(account), std::string::npos);
+  EXPECT_NE(content.find(slot), std::string::npos);
+}
+
+TEST_P(CStateTest, ContentOfArchiveStatesCanNotBeExported) {
+  if (GetParam().archive == kArchive_None) {
+    return;  // This test is only relevant when archives are enabled
+  }
+  auto state = GetState();
+  auto archive = Carmen_GetArchiveState(state, 0);
+  ASSERT_NE(archive, nullptr);
+  TempFile file;
+  auto path = file.GetPath().string();
+  EXPECT_FALSE(Carmen_ExportContent(archive, path.c_str(), path.size()));
+  Carmen_ReleaseState(archive);
+}
+
 TEST_P(CStateTest, CanBeStoredAndReloaded) {
   const Config& config = GetParam();
   if (config.state == kState_Memory) {
@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+#pragma once
+
+#include <cstddef>
+#include <span>
+
+#include "absl/status/status.h"
+#include "common/type.h"
+
+namespace carmen {
+
+// A ContentVisitor is notified about the accounts and storage slots of a state
+// enumerated by the state's VisitContent function. An error returned by any of
+// the visit functions aborts the enumeration.
+class ContentVisitor {
+ public:
+  virtual ~ContentVisitor() {}
+
+  // Called once for each existing account.
+  virtual absl::Status VisitAccount(const Address& address,
+                                    const Balance& balance, const Nonce& nonce,
+                                    std::span<const std::byte> code) = 0;
+
+  // Called once for each non-zero storage slot of an existing account. Slots
+  // may be reported before or after their account.
+  virtual absl::Status VisitSlot(const Address& address, const Key& key,
+                                 const Value& value) = 0;
+};
+
+}  // namespace carmen
@@ -13,8 +13,11 @@
 #include <cstdint>
 #include <filesystem>
 #include <optional>
+#include <span>
 #include <utility>
+#include <vector>
 
+#include "absl/container/flat_hash_map.h"
 #include "absl/status/status.h"
 #include "absl/status/statusor.h"
 #include "archive/archive.h"
@@ -24,6 +27,7 @@
 #include "common/memory_usage.h"
 #include "common/status_util.h"
 #include "common/type.h"
+#include "state/content_visitor.h"
 #include "state/schema.h"
 #include "state/update.h"
 
@@ -126,6 +130,10 @@ class State {
   // performed on it.
   absl::Status Close();
 
+  // Reports all existing accounts and their non-zero storage slots to the
+  // given visitor.
+  absl::Status VisitContent(ContentVisitor& visitor) const;
+
   // Summarizes the memory usage of this state object.
   MemoryFootprint GetMemoryFootprint() const;
 
@@ -545,4 +553,48 @@ MemoryFootprint State<Config>::GetMemoryFootprint() const {
   return res;
 }
 
+template <typename Config>
+absl::Status State<Config>::VisitContent(ContentVisitor& visitor) const {
+  // Report all existing accounts and collect their addresses.
+  absl::flat_hash_map<AddressId, Address> accounts;
+  RETURN_IF_ERROR(address_index_.ForEach(
+      [&](const Address& address, AddressId id) -> absl::Status {
+        ASSIGN_OR_RETURN(auto state, account_states_.Get(id));
+        if (state != AccountState::kExists) {
+          return absl::OkStatus();
+        }
+        ASSIGN_OR_RETURN(auto balance, balances_.Get(id));
+        ASSIGN_OR_RETURN(auto nonce, nonces_.Get(id));
+        ASSIGN_OR_RETURN(auto code, GetCode(address));
+        accounts[id] = address;
+        return visitor.VisitAccount(
+            address, balance, nonce,
+            std::span<const std::byte>(code.Data(), code.Size()));
+      }));
+
+  // Keys are only referenced by their IDs in the slot index.
+  std::vector<Key> keys;
+  RETURN_IF_ERROR(
+      key_index_.ForEach([&](const Key& key, KeyId id) -> absl::Status {
+        if (keys.size() <= id) {
+          keys.resize(id + 1);
+        }
+        keys[id] = key;
+        return absl::OkStatus();
+      }));
+
+  // Report all non-zero slots of existing accounts.
+  return slot_index_.ForEach([&](const Slot& slot, SlotId id) -> absl::Status {
+    auto pos = accounts.find(slot.address);
+    if (pos == accounts.end() || keys.size() <= slot.key) {
+      return absl::OkStatus();
+    }
+    ASSIGN_OR_RETURN(auto value, value_store_.Get(id));
+    if (value == Value{}) {
+      return absl::OkStatus();
+    }
+    return visitor.VisitSlot(pos->second, keys[slot.key], value);
+  });
+}
+
 }  // namespace carmen::s1
@@ -13,8 +13,10 @@
 #include <cstdint>
 #include <filesystem>
 #include <optional>
+#include <span>
 #include <utility>
 
+#include "absl/container/flat_hash_map.h"
 #include "absl/status/status.h"
 #include "absl/status/statusor.h"
 #include "archive/archive.h"
@@ -24,6 +26,7 @@
 #include "common/memory_usage.h"
 #include "common/status_util.h"
 #include "common/type.h"
+#include "state/content_visitor.h"
 #include "state/schema.h"
 #include "state/update.h"
 
@@ -113,6 +116,10 @@ class State {
   // performed on it.
   absl::Status Close();
 
+  // Reports all existing accounts and their non-zero storage slots to the
+  // given visitor.
+  absl::Status VisitContent(ContentVisitor& visitor) const;
+
   // Summarizes the memory usage of this state object.
   MemoryFootprint GetMemoryFootprint() const;
 
@@ -527,4 +534,40 @@ MemoryFootprint State<Config>::GetMemoryFootprint() const {
   return res;
 }
 
+template <typename Config>
+absl::Status State<Config>::VisitContent(ContentVisitor& visitor) const {
+  // Report all existing accounts and collect their addresses.
+  absl::flat_hash_map<AddressId, std::pair<Address, Reincarnation>> accounts;
+  RETURN_IF_ERROR(address_index_.ForEach(
+      [&](const Address& address, AddressId id) -> absl::Status {
+        ASSIGN_OR_RETURN(auto state, account_states_.Get(id));
+        if (state != AccountState::kExists) {
+          return absl::OkStatus();
+        }
+        ASSIGN_OR_RETURN(auto balance, balances_.Get(id));
+        ASSIGN_OR_RETURN(auto nonce, nonces_.Get(id));
+        ASSIGN_OR_RETURN(auto code, GetCode(address));
+        ASSIGN_OR_RETURN(auto reincarnation, reincarnations_.Get(id));
+        accounts[id] = {address, reincarnation};
+        return visitor.VisitAccount(
+            address, balance, nonce,
+            std::span<const std::byte>(code.Data(), code.Size()));
+      }));
+
+  // Report all non-zero slots of the current reincarnation of existing
+  // accounts.
+  return slot_index_.ForEach([&](const Slot& slot, SlotId id) -> absl::Status {
+    auto pos = accounts.find(slot.address);
+    if (pos == accounts.end()) {
+      return absl::OkStatus();
+    }
+    const auto& [address, reincarnation] = pos->second;
+    ASSIGN_OR_RETURN(const SlotValue& value, value_store_.Get(id));
+    if (value.reincarnation != reincarnation || value.value == Value{}) {
+      return absl::OkStatus();
+    }
+    return visitor.VisitSlot(address, slot.key, value.value);
+  });
+}
+
 }  // namespace carmen::s3
@@ -96,6 +96,10 @@ func (w *ldbArchiveWrapper) Close() error {
 	return w.db.Close()
 }
 
+func (w *ldbArchiveWrapper) GetUpdates(from, to uint64) ([]archive.BlockUpdate, error) {
+	return w.Archive.(archive.HistorySource).GetUpdates(from, to)
+}
+
 var (
 	addr1 = common.Address{0x01}
 )
@@ -12,6 +12,8 @@ package ldb
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/Fantom-foundation/Carmen/go/backend"
 	"sync"
@@ -19,22 +21,21 @@ import (
 
 	"github.com/Fantom-foundation/Carmen/go/backend/archive"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
 type Archive struct {
-	db                       *backend.LevelDbMemoryFootprintWrapper
+	db                       backend.KVStore
 	reincarnationNumberCache map[common.Address]int
 	accountHashCache         *common.LruCache[common.Address, common.Hash]
-	batch                    leveldb.Batch
+	batch                    backend.KVBatch
 	lastBlockCache           blockCache
 	addMutex                 sync.Mutex
 }
 
-func NewArchive(db *backend.LevelDbMemoryFootprintWrapper) (*Archive, error) {
+func NewArchive(db backend.KVStore) (*Archive, error) {
 	return &Archive{
 		db:                       db,
+		batch:                    db.NewBatch(),
 		reincarnationNumberCache: map[common.Address]int{},
 		accountHashCache:         common.NewLruCache[common.Address, common.Hash](100_000),
 	}, nil
@@ -108,7 +109,7 @@ func (a *Archive) Add(block uint64, update common.Update, _ any) error {
 	blockK.set(block)
 	a.batch.Put(blockK[:], blockHash[:])
 
-	err = a.db.Write(&a.batch, nil)
+	err = a.db.Write(a.batch)
 	if err != nil {
 		return err
 	}
@@ -200,8 +201,7 @@ func (a *Archive) getLastBlock() (number uint64, empty bool, hash common.Hash, e
 
 // getLastBlockSlow represents the slow path of getLastBlock() method (extracted to allow inlining the fast path)
 func (a *Archive) getLastBlockSlow() (number uint64, empty bool, hash common.Hash, err error) {
-	keyRange := getBlockKeyRangeFromHighest()
-	it := a.db.NewIterator(&keyRange, nil)
+	it := a.db.NewIterator(getBlockKeyRangeFromHighest())
 	defer it.Release()
 
 	if it.Next() {
@@ -221,8 +221,7 @@ func (a *Archive) GetBlockHeight() (block uint64, empty bool, err error) {
 func (a *Archive) getStatus(block uint64, account common.Address) (exists bool, reincarnation int, err error) {
 	var key accountBlockKey
 	key.set(backend.AccountArchiveKey, account, block)
-	keyRange := key.getRange()
-	it := a.db.NewIterator(&keyRange, &opt.ReadOptions{})
+	it := a.db.NewIterator(key.getRange())
 	defer it.Release()
 
 	if it.Next() {
@@ -242,8 +241,7 @@ func (a *Archive) Exists(block uint64, account common.Address) (exists bool, err
 func (a *Archive) GetBalance(block uint64, account common.Address) (balance common.Balance, err error) {
 	var key accountBlockKey
 	key.set(backend.BalanceArchiveKey, account, block)
-	keyRange := key.getRange()
-	it := a.db.NewIterator(&keyRange, nil)
+	it := a.db.NewIterator(key.getRange())
 	defer it.Release()
 
 	if it.Next() {
@@ -256,8 +254,7 @@ func (a *Archive) GetBalance(block uint64, account common.Address) (balance comm
 func (a *Archive) GetCode(block uint64, account common.Address) (code []byte, err error) {
 	var key accountBlockKey
 	key.set(backend.CodeArchiveKey, account, block)
-	keyRange := key.getRange()
-	it := a.db.NewIterator(&keyRange, nil)
+	it := a.db.NewIterator(key.getRange())
 	defer it.Release()
 
 	if it.Next() {
@@ -271,8 +268,7 @@ func (a *Archive) GetCode(block uint64, account common.Address) (code []byte, er
 func (a *Archive) GetNonce(block uint64, account common.Address) (nonce common.Nonce, err error) {
 	var key accountBlockKey
 	key.set(backend.NonceArchiveKey, account, block)
-	keyRange := key.getRange()
-	it := a.db.NewIterator(&keyRange, nil)
+	it := a.db.NewIterator(key.getRange())
 	defer it.Release()
 
 	if it.Next() {
@@ -290,8 +286,7 @@ func (a *Archive) GetStorage(block uint64, account common.Address, slot common.K
 
 	var key accountKeyBlockKey
 	key.set(backend.StorageArchiveKey, account, reincarnation, slot, block)
-	keyRange := key.getRange()
-	it := a.db.NewIterator(&keyRange, nil)
+	it := a.db.NewIterator(key.getRange())
 	defer it.Release()
 
 	if it.Next() {
@@ -304,7 +299,7 @@ func (a *Archive) GetStorage(block uint64, account common.Address, slot common.K
 func (a *Archive) GetHash(block uint64) (hash common.Hash, err error) {
 	var blockK blockKey
 	blockK.set(block)
-	hashBytes, err := a.db.Get(blockK[:], nil)
+	hashBytes, err := a.db.Get(blockK[:])
 	copy(hash[:], hashBytes)
 	return hash, err
 }
@@ -323,8 +318,7 @@ func (a *Archive) getLastAccountHash(account common.Address) (hash common.Hash,
 func (a *Archive) GetAccountHash(block uint64, account common.Address) (hash common.Hash, err error) {
 	var key accountBlockKey
 	key.set(backend.AccountHashArchiveKey, account, block)
-	keyRange := key.getRange()
-	it := a.db.NewIterator(&keyRange, nil)
+	it := a.db.NewIterator(key.getRange())
 	defer it.Release()
 
 	if it.Next() {
@@ -334,6 +328,185 @@ func (a *Archive) GetAccountHash(block uint64, account common.Address) (hash com
 	return common.Hash{}, it.Error()
 }
 
+// GetUpdates reconstructs the updates of the blocks in the range [from, to]
+// from the block, status, balance, code, nonce, and storage key spaces. The
+// entries are located through the index of entries by block, thus the costs
+// of this operation are proportional to the number of changes in the range.
+// The index is not maintained by Add but extended by this operation, which
+// requires a scan of the complete archive if blocks have been added since the
+// index was last extended.
+func (a *Archive) GetUpdates(from, to uint64) ([]archive.BlockUpdate, error) {
+	if err := a.ensureBlockUpdateIndex(); err != nil {
+		return nil, fmt.Errorf("failed to index archive by blocks; %w", err)
+	}
+
+	blocks := []uint64{}
+	it := a.db.NewIterator(getBlockKeyRangeFrom(to))
+	for it.Next() {
+		var blockK blockKey
+		copy(blockK[:], it.Key())
+		block := blockK.get()
+		if block < from {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	changes := map[uint64]*common.Update{}
+	getUpdate := func(block uint64) *common.Update {
+		update, found := changes[block]
+		if !found {
+			update = &common.Update{}
+			changes[block] = update
+		}
+		return update
+	}
+
+	it = a.db.NewIterator(getBlockUpdateKeyRange(from, to))
+	defer it.Release()
+	for it.Next() {
+		block, entry := blockUpdateKey(it.Key()).get()
+		if len(entry) == 0 {
+			continue
+		}
+		value, err := a.db.Get(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexed entry of block %d; %w", block, err)
+		}
+		update := getUpdate(block)
+		table := backend.TableSpace(entry[0])
+		if table == backend.StorageArchiveKey {
+			// Slots are always written for the reincarnation of the account
+			// resulting from the status changes of the same block.
+			var key accountKeyBlockKey
+			copy(key[:], entry)
+			account, _, slot, _ := key.get()
+			var slotValue common.Value
+			copy(slotValue[:], value)
+			update.AppendSlotUpdate(account, slot, slotValue)
+			continue
+		}
+		var key accountBlockKey
+		copy(key[:], entry)
+		account, _ := key.get()
+		switch table {
+		case backend.AccountArchiveKey:
+			var accountStatusV accountStatusValue
+			copy(accountStatusV[:], value)
+			if exists, _ := accountStatusV.get(); exists {
+				update.AppendCreateAccount(account)
+			} else {
+				update.AppendDeleteAccount(account)
+			}
+		case backend.BalanceArchiveKey:
+			var balance common.Balance
+			copy(balance[:], value)
+			update.AppendBalanceUpdate(account, balance)
+		case backend.CodeArchiveKey:
+			code := make([]byte, len(value))
+			copy(code, value)
+			update.AppendCodeUpdate(account, code)
+		case backend.NonceArchiveKey:
+			var nonce common.Nonce
+			copy(nonce[:], value)
+			update.AppendNonceUpdate(account, nonce)
+		default:
+			return nil, fmt.Errorf("invalid indexed entry of block %d in table %c", block, table)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	return archive.ToBlockUpdates(blocks, changes)
+}
+
+// blockUpdateIndexBatchSize is the number of index entries written at once
+// while indexing an existing archive.
+const blockUpdateIndexBatchSize = 10_000
+
+// ensureBlockUpdateIndex indexes the entries of the account and storage tables
+// by block in a single pass over the archive, unless the index covers all
+// blocks. Only entries of blocks not covered yet are added to the index.
+func (a *Archive) ensureBlockUpdateIndex() error {
+	a.addMutex.Lock()
+	defer a.addMutex.Unlock()
+	last, empty, _, err := a.getLastBlock()
+	if err != nil || empty {
+		return err
+	}
+	indexed, err := a.getNumIndexedBlocks()
+	if err != nil || indexed > last {
+		return err
+	}
+
+	batch := a.db.NewBatch()
+	batchSize := 0
+	tables := []backend.TableSpace{
+		backend.AccountArchiveKey,
+		backend.BalanceArchiveKey,
+		backend.CodeArchiveKey,
+		backend.NonceArchiveKey,
+		backend.StorageArchiveKey,
+	}
+	for _, table := range tables {
+		it := a.db.NewIterator(backend.KVPrefix([]byte{byte(table)}))
+		for it.Next() {
+			key := it.Key()
+			var block uint64
+			if table == backend.StorageArchiveKey {
+				var slotK accountKeyBlockKey
+				copy(slotK[:], key)
+				_, _, _, block = slotK.get()
+			} else {
+				var accountK accountBlockKey
+				copy(accountK[:], key)
+				_, block = accountK.get()
+			}
+			if block < indexed {
+				continue
+			}
+			batch.Put(newBlockUpdateKey(block, key), []byte{})
+			if batchSize++; batchSize >= blockUpdateIndexBatchSize {
+				if err := a.db.Write(batch); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+				batchSize = 0
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	var numBlocks [8]byte
+	binary.BigEndian.PutUint64(numBlocks[:], last+1)
+	batch.Put(blockUpdateIndexNumBlocksKey, numBlocks[:])
+	return a.db.Write(batch)
+}
+
+// getNumIndexedBlocks provides the number of blocks covered by the index of
+// entries by block.
+func (a *Archive) getNumIndexedBlocks() (uint64, error) {
+	value, err := a.db.Get(blockUpdateIndexNumBlocksKey)
+	if errors.Is(err, backend.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("invalid number of indexed blocks entry of length %d", len(value))
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
 // GetMemoryFootprint provides the size of the archive in memory in bytes
 func (a *Archive) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(unsafe.Sizeof(*a))
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package ldb
+
+import (
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/backend"
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func addBlockUpdateIndexTestBlocks(t *testing.T, archive *Archive, from, to uint64) {
+	t.Helper()
+	addr := common.Address{1}
+	for block := from; block < to; block++ {
+		update := common.Update{
+			Balances: []common.BalanceUpdate{{Account: addr, Balance: common.Balance{byte(block + 1)}}},
+			Slots:    []common.SlotUpdate{{Account: addr, Key: common.Key{1}, Value: common.Value{byte(block + 1)}}},
+		}
+		if block == 0 {
+			update.CreatedAccounts = []common.Address{addr}
+		}
+		if err := archive.Add(block, update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+}
+
+func checkBlockUpdateIndexTestUpdates(t *testing.T, archive *Archive, from, to uint64) {
+	t.Helper()
+	addr := common.Address{1}
+	updates, err := archive.GetUpdates(from, to)
+	if err != nil {
+		t.Fatalf("failed to get updates: %v", err)
+	}
+	if len(updates) != int(to-from+1) {
+		t.Fatalf("unexpected number of blocks, got %v", updates)
+	}
+	for i, cur := range updates {
+		block := from + uint64(i)
+		want := common.Update{
+			Balances: []common.BalanceUpdate{{Account: addr, Balance: common.Balance{byte(block + 1)}}},
+			Slots:    []common.SlotUpdate{{Account: addr, Key: common.Key{1}, Value: common.Value{byte(block + 1)}}},
+		}
+		if cur.Block != block || cur.Update.String() != want.String() {
+			t.Errorf("unexpected update of block %d, wanted %v, got %v", block, &want, &cur.Update)
+		}
+	}
+}
+
+func TestArchive_GetUpdates_BlockIndexIsExtendedOnDemand(t *testing.T) {
+	db, err := backend.OpenLevelDb(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("failed to open LevelDB: %v", err)
+	}
+	defer db.Close()
+	archive, err := NewArchive(db)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	addBlockUpdateIndexTestBlocks(t, archive, 0, 3)
+
+	// Adding blocks does not maintain the index.
+	it := db.NewIterator(backend.KVPrefix([]byte{byte(backend.BlockUpdateArchiveKey)}))
+	if it.Next() {
+		t.Errorf("adding blocks should not create index entries")
+	}
+	it.Release()
+
+	checkBlockUpdateIndexTestUpdates(t, archive, 1, 2)
+	if indexed, err := archive.getNumIndexedBlocks(); err != nil || indexed != 3 {
+		t.Errorf("unexpected number of indexed blocks, wanted 3, got %d, err %v", indexed, err)
+	}
+
+	// Blocks added after the index was created are indexed by the next query.
+	addBlockUpdateIndexTestBlocks(t, archive, 3, 5)
+	checkBlockUpdateIndexTestUpdates(t, archive, 1, 4)
+	if indexed, err := archive.getNumIndexedBlocks(); err != nil || indexed != 5 {
+		t.Errorf("unexpected number of indexed blocks, wanted 5, got %d, err %v", indexed, err)
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"encoding/binary"
 	"github.com/Fantom-foundation/Carmen/go/backend"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const blockSize = 8                 // block number size (uint64)
@@ -38,16 +37,16 @@ func (k *blockKey) get() (block uint64) {
 }
 
 // getBlockKeyRangeFrom provides a key range for iterating blocks from the given block to the first block
-func getBlockKeyRangeFrom(block uint64) util.Range {
+func getBlockKeyRangeFrom(block uint64) *backend.KVRange {
 	var start, end blockKey
 	start.set(block)
 	end[0] = start[0]
 	copy(end[1:], limitBlock)
-	return util.Range{Start: start[:], Limit: end[:]}
+	return &backend.KVRange{Start: start[:], Limit: end[:]}
 }
 
 // getBlockKeyRangeFromHighest provides a key range for iterating from the highest block to the first
-func getBlockKeyRangeFromHighest() util.Range {
+func getBlockKeyRangeFromHighest() *backend.KVRange {
 	return getBlockKeyRangeFrom(maxBlock)
 }
 
@@ -63,11 +62,17 @@ func (k *accountBlockKey) set(table backend.TableSpace, account common.Address,
 	binary.BigEndian.PutUint64(k[1+common.AddressSize:], maxBlock-block)
 }
 
+func (k *accountBlockKey) get() (account common.Address, block uint64) {
+	copy(account[:], k[1:1+common.AddressSize])
+	block = maxBlock - binary.BigEndian.Uint64(k[1+common.AddressSize:])
+	return account, block
+}
+
 // getRange provides a key range for iterating the account value from the given block to the first block
-func (k *accountBlockKey) getRange() util.Range {
+func (k *accountBlockKey) getRange() *backend.KVRange {
 	end := *k
 	copy(end[1+common.AddressSize:], limitBlock)
-	return util.Range{Start: k[:], Limit: end[:]}
+	return &backend.KVRange{Start: k[:], Limit: end[:]}
 }
 
 // accountKeyBlockKey is a key for storage slots, it consists of
@@ -86,13 +91,56 @@ func (k *accountKeyBlockKey) set(table backend.TableSpace, account common.Addres
 	binary.BigEndian.PutUint64(k[1+common.AddressSize+reincSize+common.KeySize:], maxBlock-block)
 }
 
+func (k *accountKeyBlockKey) get() (account common.Address, reincarnation int, slot common.Key, block uint64) {
+	copy(account[:], k[1:1+common.AddressSize])
+	reincarnation = int(binary.BigEndian.Uint32(k[1+common.AddressSize:]))
+	copy(slot[:], k[1+common.AddressSize+reincSize:])
+	block = maxBlock - binary.BigEndian.Uint64(k[1+common.AddressSize+reincSize+common.KeySize:])
+	return account, reincarnation, slot, block
+}
+
 // getRange provides a key range for iterating the slot value from the given block to the first block
-func (k *accountKeyBlockKey) getRange() util.Range {
+func (k *accountKeyBlockKey) getRange() *backend.KVRange {
 	end := *k
 	copy(end[1+common.AddressSize+reincSize+common.KeySize:], limitBlock)
-	return util.Range{Start: k[:], Limit: end[:]}
+	return &backend.KVRange{Start: k[:], Limit: end[:]}
+}
+
+// blockUpdateKey is a key indexing the entries of the account and storage
+// tables by block, it consists of
+// * the tablespace
+// * the block number, in ascending order
+// * the key of the indexed entry
+// The key of the tablespace itself marks the index as complete.
+type blockUpdateKey []byte
+
+func newBlockUpdateKey(block uint64, entry []byte) blockUpdateKey {
+	k := make(blockUpdateKey, 1+blockSize+len(entry))
+	k[0] = byte(backend.BlockUpdateArchiveKey)
+	binary.BigEndian.PutUint64(k[1:], block)
+	copy(k[1+blockSize:], entry)
+	return k
 }
 
+func (k blockUpdateKey) get() (block uint64, entry []byte) {
+	return binary.BigEndian.Uint64(k[1:]), k[1+blockSize:]
+}
+
+// getBlockUpdateKeyRange provides a key range for iterating the indexed entries of the blocks [from, to]
+func getBlockUpdateKeyRange(from, to uint64) *backend.KVRange {
+	start := newBlockUpdateKey(from, nil)
+	end := newBlockUpdateKey(to, nil)
+	if to >= maxBlock {
+		copy(end[1:], limitBlock)
+	} else {
+		binary.BigEndian.PutUint64(end[1:], to+1)
+	}
+	return &backend.KVRange{Start: start, Limit: end}
+}
+
+// blockUpdateIndexNumBlocksKey is the key of the number of blocks covered by the index of entries by block
+var blockUpdateIndexNumBlocksKey = []byte{byte(backend.BlockUpdateArchiveKey)}
+
 // accountStatusValue is a value for account status, it consists of
 // * the account existence status (1 for existing account, 0 otherwise)
 // * the reincarnation number (references the storage, incremented on account creation/destroying)
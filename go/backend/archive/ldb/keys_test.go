@@ -72,3 +72,48 @@ func TestAccountKeyBlockKey(t *testing.T) {
 		t.Errorf("the range does not include block 0; %x > %x", blockRange.Limit, block0[:])
 	}
 }
+
+func TestAccountBlockKey_Get(t *testing.T) {
+	var key accountBlockKey
+	key.set(backend.BalanceArchiveKey, common.Address{0x01}, 12)
+	account, block := key.get()
+	if account != (common.Address{0x01}) || block != 12 {
+		t.Errorf("unexpected key content, got %x and %d", account, block)
+	}
+}
+
+func TestAccountKeyBlockKey_Get(t *testing.T) {
+	var key accountKeyBlockKey
+	key.set(backend.StorageArchiveKey, common.Address{0x01}, 3, common.Key{0x02}, 12)
+	account, reincarnation, slot, block := key.get()
+	if account != (common.Address{0x01}) || reincarnation != 3 || slot != (common.Key{0x02}) || block != 12 {
+		t.Errorf("unexpected key content, got %x, %d, %x, and %d", account, reincarnation, slot, block)
+	}
+}
+
+func TestBlockUpdateKey(t *testing.T) {
+	var entry accountBlockKey
+	entry.set(backend.BalanceArchiveKey, common.Address{0x01}, 12)
+	key := newBlockUpdateKey(12, entry[:])
+	block, got := key.get()
+	if block != 12 || !bytes.Equal(got, entry[:]) {
+		t.Errorf("unexpected key content, got %d and %x", block, got)
+	}
+
+	blockRange := getBlockUpdateKeyRange(12, 13)
+	for _, block := range []uint64{12, 13} {
+		key := newBlockUpdateKey(block, entry[:])
+		if bytes.Compare(blockRange.Start, key) > 0 || bytes.Compare(blockRange.Limit, key) <= 0 {
+			t.Errorf("the range does not include block %d", block)
+		}
+	}
+	for _, block := range []uint64{11, 14} {
+		key := newBlockUpdateKey(block, entry[:])
+		if bytes.Compare(blockRange.Start, key) <= 0 && bytes.Compare(blockRange.Limit, key) > 0 {
+			t.Errorf("the range includes block %d", block)
+		}
+	}
+	if blockRange := getBlockUpdateKeyRange(0, maxBlock); bytes.Compare(blockRange.Limit, newBlockUpdateKey(maxBlock, entry[:])) <= 0 {
+		t.Errorf("the range does not include the max block")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package archive
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// HistorySource is implemented by archives able to reconstruct the updates
+// of the blocks they contain. It enables the migration of the history of an
+// archive into an archive of a different type.
+type HistorySource interface {
+	// GetUpdates reconstructs the updates of all blocks recorded in the range
+	// [from, to], in ascending block order. Blocks recorded with an empty
+	// update are included, blocks not recorded by the archive are not.
+	GetUpdates(from, to uint64) ([]BlockUpdate, error)
+}
+
+// BlockUpdate is the update recorded by an archive for a single block.
+type BlockUpdate struct {
+	Block  uint64
+	Update common.Update
+}
+
+// ToBlockUpdates assembles the result of a HistorySource from the list of
+// recorded blocks and the changes collected for those blocks. Blocks without
+// changes are reported with an empty update. The resulting updates are
+// normalized.
+func ToBlockUpdates(blocks []uint64, changes map[uint64]*common.Update) ([]BlockUpdate, error) {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	res := make([]BlockUpdate, 0, len(blocks))
+	for _, block := range blocks {
+		entry := BlockUpdate{Block: block}
+		if update, found := changes[block]; found {
+			if err := update.Normalize(); err != nil {
+				return nil, fmt.Errorf("invalid update of block %d: %w", block, err)
+			}
+			entry.Update = *update
+		}
+		res = append(res, entry)
+	}
+	return res, nil
+}
+
+// DefaultMigrationBatchSize is the number of blocks reconstructed from the
+// source archive at once by Migrate, unless configured otherwise.
+const DefaultMigrationBatchSize = 1_000
+
+// MigrationConfig defines optional parameters of a migration.
+type MigrationConfig struct {
+	// BatchSize is the number of blocks reconstructed from the source at
+	// once. Larger batches require more memory but fewer scans of the
+	// source. If 0, DefaultMigrationBatchSize is used.
+	BatchSize int
+	// VerifyHashes enables the comparison of the hashes of source and
+	// target after each block. It should only be enabled if both archives
+	// use the same hash definition.
+	VerifyHashes bool
+	// Progress, if set, is called after each migrated block.
+	Progress func(block uint64)
+}
+
+// Migrate replays the history of the source archive into the target archive
+// by reconstructing the update of each block of the source and adding it to
+// the target. The source has to implement the HistorySource interface. If the
+// target already contains blocks, for instance due to an interrupted
+// migration, the migration is resumed after the last block of the target.
+// On success, the target archive is flushed.
+func Migrate(source, target Archive, config MigrationConfig) error {
+	history, ok := source.(HistorySource)
+	if !ok {
+		return fmt.Errorf("source archive does not support the reconstruction of updates")
+	}
+	batchSize := uint64(config.BatchSize)
+	if batchSize == 0 {
+		batchSize = DefaultMigrationBatchSize
+	}
+
+	height, empty, err := source.GetBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to get block height of source: %w", err)
+	}
+	if empty {
+		return target.Flush()
+	}
+
+	next := uint64(0)
+	last, targetEmpty, err := target.GetBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to get block height of target: %w", err)
+	}
+	if !targetEmpty {
+		if last > height {
+			return fmt.Errorf("target archive is ahead of source archive, target height %d, source height %d", last, height)
+		}
+		if config.VerifyHashes {
+			if err := verifyBlockHash(source, target, last); err != nil {
+				return fmt.Errorf("target archive is not a prefix of the source archive: %w", err)
+			}
+		}
+		if last == height {
+			return target.Flush()
+		}
+		next = last + 1
+	}
+
+	for from := next; from <= height; from += batchSize {
+		to := height
+		if height-from >= batchSize {
+			to = from + batchSize - 1
+		}
+		updates, err := history.GetUpdates(from, to)
+		if err != nil {
+			return fmt.Errorf("failed to reconstruct updates of blocks %d-%d: %w", from, to, err)
+		}
+		for _, update := range updates {
+			if err := target.Add(update.Block, update.Update, nil); err != nil {
+				return fmt.Errorf("failed to add block %d to target: %w", update.Block, err)
+			}
+			if config.VerifyHashes {
+				if err := verifyBlockHash(source, target, update.Block); err != nil {
+					return err
+				}
+			}
+			if config.Progress != nil {
+				config.Progress(update.Block)
+			}
+		}
+		if to == height {
+			break
+		}
+	}
+	return target.Flush()
+}
+
+// verifyBlockHash checks that both archives report the same hash for the
+// given block.
+func verifyBlockHash(source, target Archive, block uint64) error {
+	want, err := source.GetHash(block)
+	if err != nil {
+		return fmt.Errorf("failed to get hash of block %d from source: %w", block, err)
+	}
+	got, err := target.GetHash(block)
+	if err != nil {
+		return fmt.Errorf("failed to get hash of block %d from target: %w", block, err)
+	}
+	if want != got {
+		return fmt.Errorf("hash mismatch at block %d, source %x, target %x", block, want, got)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package archive_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/archive"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	migrationAddr2 = common.Address{0x02}
+	migrationKey1  = common.Key{0x01}
+	migrationKey2  = common.Key{0x02}
+)
+
+// migrationHistory is a history covering all kinds of changes, including
+// blocks without updates, gaps, and re-created accounts. Like the updates
+// produced by the StateDB, deletions include the reset of account fields,
+// which are not cleared implicitly by flat archives.
+var migrationHistory = []archive.BlockUpdate{
+	{Block: 0, Update: common.Update{
+		CreatedAccounts: []common.Address{addr1},
+		Balances:        []common.BalanceUpdate{{Account: addr1, Balance: common.Balance{31: 0x12}}},
+		Nonces:          []common.NonceUpdate{{Account: addr1, Nonce: common.Nonce{7: 0x01}}},
+		Codes:           []common.CodeUpdate{{Account: addr1, Code: []byte{0x01, 0x02}}},
+		Slots:           []common.SlotUpdate{{Account: addr1, Key: migrationKey1, Value: common.Value{31: 0x01}}},
+	}},
+	{Block: 2, Update: common.Update{
+		CreatedAccounts: []common.Address{migrationAddr2},
+		Balances:        []common.BalanceUpdate{{Account: migrationAddr2, Balance: common.Balance{31: 0x34}}},
+		Slots: []common.SlotUpdate{
+			{Account: addr1, Key: migrationKey1, Value: common.Value{31: 0x02}},
+			{Account: addr1, Key: migrationKey2, Value: common.Value{31: 0x03}},
+		},
+	}},
+	{Block: 3},
+	{Block: 5, Update: common.Update{
+		DeletedAccounts: []common.Address{addr1},
+		Balances:        []common.BalanceUpdate{{Account: addr1}},
+		Nonces:          []common.NonceUpdate{{Account: addr1}},
+		Codes:           []common.CodeUpdate{{Account: addr1, Code: []byte{}}},
+	}},
+	{Block: 6, Update: common.Update{
+		CreatedAccounts: []common.Address{addr1},
+		Balances:        []common.BalanceUpdate{{Account: addr1, Balance: common.Balance{31: 0x56}}},
+		Slots:           []common.SlotUpdate{{Account: migrationAddr2, Key: migrationKey1, Value: common.Value{31: 0x04}}},
+	}},
+}
+
+// getMigrationFactories provides the archives supporting migrations.
+func getMigrationFactories(t *testing.T) []archiveFactory {
+	res := []archiveFactory{}
+	for _, factory := range getArchiveFactories(t) {
+		if factory.label != "S4" {
+			res = append(res, factory)
+		}
+	}
+	return res
+}
+
+func addHistory(t *testing.T, a archive.Archive, history []archive.BlockUpdate) {
+	t.Helper()
+	for _, cur := range history {
+		if err := a.Add(cur.Block, cur.Update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", cur.Block, err)
+		}
+	}
+}
+
+// checkSameHistory checks that both archives report the same account and
+// storage information for all blocks up to the given block.
+func checkSameHistory(t *testing.T, want, got archive.Archive, last uint64) {
+	t.Helper()
+	for block := uint64(0); block <= last; block++ {
+		for _, account := range []common.Address{addr1, migrationAddr2} {
+			wantExists, err1 := want.Exists(block, account)
+			gotExists, err2 := got.Exists(block, account)
+			if err1 != nil || err2 != nil || wantExists != gotExists {
+				t.Errorf("block %d, account %x: different existence, wanted %t, got %t, errors %v, %v", block, account, wantExists, gotExists, err1, err2)
+			}
+			wantBalance, err1 := want.GetBalance(block, account)
+			gotBalance, err2 := got.GetBalance(block, account)
+			if err1 != nil || err2 != nil || wantBalance != gotBalance {
+				t.Errorf("block %d, account %x: different balance, wanted %x, got %x, errors %v, %v", block, account, wantBalance, gotBalance, err1, err2)
+			}
+			wantNonce, err1 := want.GetNonce(block, account)
+			gotNonce, err2 := got.GetNonce(block, account)
+			if err1 != nil || err2 != nil || wantNonce != gotNonce {
+				t.Errorf("block %d, account %x: different nonce, wanted %x, got %x, errors %v, %v", block, account, wantNonce, gotNonce, err1, err2)
+			}
+			wantCode, err1 := want.GetCode(block, account)
+			gotCode, err2 := got.GetCode(block, account)
+			if err1 != nil || err2 != nil || !bytes.Equal(wantCode, gotCode) {
+				t.Errorf("block %d, account %x: different code, wanted %x, got %x, errors %v, %v", block, account, wantCode, gotCode, err1, err2)
+			}
+			for _, key := range []common.Key{migrationKey1, migrationKey2} {
+				wantValue, err1 := want.GetStorage(block, account, key)
+				gotValue, err2 := got.GetStorage(block, account, key)
+				if err1 != nil || err2 != nil || wantValue != gotValue {
+					t.Errorf("block %d, account %x, key %x: different value, wanted %x, got %x, errors %v, %v", block, account, key, wantValue, gotValue, err1, err2)
+				}
+			}
+		}
+	}
+}
+
+func TestGetUpdates_ReconstructsRecordedUpdates(t *testing.T) {
+	for _, factory := range getMigrationFactories(t) {
+		if factory.customHash {
+			continue // MPT archives do not retain gaps and empty accounts
+		}
+		t.Run(factory.label, func(t *testing.T) {
+			a := factory.getArchive(t.TempDir())
+			defer a.Close()
+			addHistory(t, a, migrationHistory)
+
+			got, err := a.(archive.HistorySource).GetUpdates(0, 10)
+			if err != nil {
+				t.Fatalf("failed to get updates: %v", err)
+			}
+			if len(got) != len(migrationHistory) {
+				t.Fatalf("unexpected number of blocks, wanted %d, got %d", len(migrationHistory), len(got))
+			}
+			for i, want := range migrationHistory {
+				if want.Block != got[i].Block || want.Update.String() != got[i].Update.String() {
+					t.Errorf("unexpected update of block %d, wanted %v, got %v", want.Block, &want.Update, &got[i].Update)
+				}
+			}
+
+			got, err = a.(archive.HistorySource).GetUpdates(1, 3)
+			if err != nil {
+				t.Fatalf("failed to get updates: %v", err)
+			}
+			if len(got) != 2 || got[0].Block != 2 || got[1].Block != 3 {
+				t.Errorf("unexpected blocks in range, got %v", got)
+			}
+		})
+	}
+}
+
+func TestMigrate_HistoryIsRetainedBetweenAllArchiveTypes(t *testing.T) {
+	for _, sourceFactory := range getMigrationFactories(t) {
+		for _, targetFactory := range getMigrationFactories(t) {
+			t.Run(sourceFactory.label+"_to_"+targetFactory.label, func(t *testing.T) {
+				source := sourceFactory.getArchive(t.TempDir())
+				defer source.Close()
+				target := targetFactory.getArchive(t.TempDir())
+				defer target.Close()
+				addHistory(t, source, migrationHistory)
+
+				config := archive.MigrationConfig{
+					BatchSize:    2,
+					VerifyHashes: sourceFactory.label == targetFactory.label || (!sourceFactory.customHash && !targetFactory.customHash),
+				}
+				if err := archive.Migrate(source, target, config); err != nil {
+					t.Fatalf("failed to migrate archive: %v", err)
+				}
+				height, empty, err := target.GetBlockHeight()
+				if err != nil || empty || height != 6 {
+					t.Fatalf("unexpected block height of target, wanted 6, got %d, empty %t, err %v", height, empty, err)
+				}
+				checkSameHistory(t, source, target, height)
+			})
+		}
+	}
+}
+
+func TestMigrate_HistoriesSpanningManyBatchesAreMigrated(t *testing.T) {
+	history := []archive.BlockUpdate{}
+	for i := 0; i < 50; i++ {
+		account := common.Address{byte(i % 7)}
+		update := common.Update{
+			Balances: []common.BalanceUpdate{{Account: account, Balance: common.Balance{31: byte(i)}}},
+			Slots:    []common.SlotUpdate{{Account: account, Key: common.Key{byte(i % 3)}, Value: common.Value{31: byte(i + 1)}}},
+		}
+		if i < 7 {
+			update.CreatedAccounts = []common.Address{account}
+		}
+		history = append(history, archive.BlockUpdate{Block: uint64(i), Update: update})
+	}
+
+	for _, factory := range getMigrationFactories(t) {
+		t.Run(factory.label, func(t *testing.T) {
+			source := factory.getArchive(t.TempDir())
+			defer source.Close()
+			target := factory.getArchive(t.TempDir())
+			defer target.Close()
+			addHistory(t, source, history)
+
+			migrated := 0
+			config := archive.MigrationConfig{
+				BatchSize:    3,
+				VerifyHashes: true,
+				Progress: func(uint64) {
+					migrated++
+				},
+			}
+			if err := archive.Migrate(source, target, config); err != nil {
+				t.Fatalf("failed to migrate archive: %v", err)
+			}
+			if migrated != len(history) {
+				t.Errorf("unexpected number of migrated blocks, wanted %d, got %d", len(history), migrated)
+			}
+			for _, block := range []uint64{0, 17, 49} {
+				for i := 0; i < 7; i++ {
+					account := common.Address{byte(i)}
+					for _, key := range []common.Key{{0}, {1}, {2}} {
+						want, err1 := source.GetStorage(block, account, key)
+						got, err2 := target.GetStorage(block, account, key)
+						if err1 != nil || err2 != nil || want != got {
+							t.Errorf("block %d, account %x, key %x: different value, wanted %x, got %x, errors %v, %v", block, account, key, want, got, err1, err2)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMigrate_InterruptedMigrationCanBeResumed(t *testing.T) {
+	for _, factory := range getMigrationFactories(t) {
+		t.Run(factory.label, func(t *testing.T) {
+			source := factory.getArchive(t.TempDir())
+			defer source.Close()
+			target := factory.getArchive(t.TempDir())
+			defer target.Close()
+
+			config := archive.MigrationConfig{VerifyHashes: true}
+			addHistory(t, source, migrationHistory[:2])
+			if err := archive.Migrate(source, target, config); err != nil {
+				t.Fatalf("failed to migrate archive: %v", err)
+			}
+
+			migrated := []uint64{}
+			config.Progress = func(block uint64) {
+				migrated = append(migrated, block)
+			}
+			addHistory(t, source, migrationHistory[2:])
+			if err := archive.Migrate(source, target, config); err != nil {
+				t.Fatalf("failed to resume migration: %v", err)
+			}
+			if len(migrated) == 0 || migrated[0] != 3 {
+				t.Errorf("migration should be resumed at block 3, got %v", migrated)
+			}
+			checkSameHistory(t, source, target, 6)
+
+			// A completed migration is a no-op.
+			migrated = migrated[:0]
+			if err := archive.Migrate(source, target, config); err != nil {
+				t.Fatalf("failed to repeat migration: %v", err)
+			}
+			if len(migrated) != 0 {
+				t.Errorf("no blocks should be migrated, got %v", migrated)
+			}
+		})
+	}
+}
+
+func TestMigrate_DivergingTargetIsDetected(t *testing.T) {
+	for _, factory := range getMigrationFactories(t) {
+		t.Run(factory.label, func(t *testing.T) {
+			source := factory.getArchive(t.TempDir())
+			defer source.Close()
+			target := factory.getArchive(t.TempDir())
+			defer target.Close()
+
+			addHistory(t, source, migrationHistory)
+			addHistory(t, target, []archive.BlockUpdate{{Block: 0, Update: common.Update{
+				CreatedAccounts: []common.Address{migrationAddr2},
+			}}})
+			err := archive.Migrate(source, target, archive.MigrationConfig{VerifyHashes: true})
+			if err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+				t.Errorf("diverging target should be detected, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrate_SourceNotProvidingHistoryIsRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	target := archive.NewMockArchive(ctrl)
+	if err := archive.Migrate(source, target, archive.MigrationConfig{}); err == nil {
+		t.Errorf("source not providing history should be rejected")
+	}
+}
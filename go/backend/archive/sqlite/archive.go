@@ -62,6 +62,21 @@ const (
 	kCreateAccountHashTable = "CREATE TABLE IF NOT EXISTS account_hash (account BLOB, block INT, hash BLOB, PRIMARY KEY(account,block))"
 	kAddAccountHashStmt     = "INSERT INTO account_hash(account, block, hash) VALUES (?,?,?)"
 	kGetAccountHashStmt     = "SELECT hash FROM account_hash WHERE account = ? AND block <= ? ORDER BY block DESC LIMIT 1"
+
+	// The block indexes enable the reconstruction of the updates of block
+	// ranges without scanning the tables completely.
+	kCreateStatusBlockIndex  = "CREATE INDEX IF NOT EXISTS status_block ON status(block)"
+	kCreateBalanceBlockIndex = "CREATE INDEX IF NOT EXISTS balance_block ON balance(block)"
+	kCreateCodeBlockIndex    = "CREATE INDEX IF NOT EXISTS code_block ON code(block)"
+	kCreateNonceBlockIndex   = "CREATE INDEX IF NOT EXISTS nonce_block ON nonce(block)"
+	kCreateValueBlockIndex   = "CREATE INDEX IF NOT EXISTS storage_block ON storage(block)"
+
+	kGetBlocksInRangeStmt  = "SELECT number FROM block WHERE number >= ? AND number <= ?"
+	kGetStatusInRangeStmt  = "SELECT account, block, exist FROM status WHERE block >= ? AND block <= ?"
+	kGetBalanceInRangeStmt = "SELECT account, block, value FROM balance WHERE block >= ? AND block <= ?"
+	kGetCodeInRangeStmt    = "SELECT account, block, code FROM code WHERE block >= ? AND block <= ?"
+	kGetNonceInRangeStmt   = "SELECT account, block, value FROM nonce WHERE block >= ? AND block <= ?"
+	kGetStorageInRangeStmt = "SELECT account, slot, block, value FROM storage WHERE block >= ? AND block <= ?"
 )
 
 type Archive struct {
@@ -124,6 +139,11 @@ func NewArchive(file string) (*Archive, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create account hash table; %s", err)
 	}
+	for _, cmd := range []string{kCreateStatusBlockIndex, kCreateBalanceBlockIndex, kCreateCodeBlockIndex, kCreateNonceBlockIndex, kCreateValueBlockIndex} {
+		if _, err = db.Exec(cmd); err != nil {
+			return nil, fmt.Errorf("failed to create block index with %s; %s", cmd, err)
+		}
+	}
 
 	addBlock, err := db.Prepare(kAddBlockStmt)
 	if err != nil {
@@ -509,6 +529,127 @@ func (a *Archive) GetAccountHash(block uint64, account common.Address) (hash com
 	return a.getAccountHash(nil, block, account)
 }
 
+// GetUpdates reconstructs the updates of the blocks in the range [from, to]
+// from the block, status, balance, code, nonce, and storage tables. Rows are
+// located through the block indexes of the tables, thus the costs of this
+// operation are proportional to the number of changes in the range.
+func (a *Archive) GetUpdates(from, to uint64) ([]archive.BlockUpdate, error) {
+	blocks := []uint64{}
+	if err := a.queryRange(kGetBlocksInRangeStmt, from, to, func(rows *sql.Rows) error {
+		var block uint64
+		if err := rows.Scan(&block); err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get blocks; %w", err)
+	}
+
+	changes := map[uint64]*common.Update{}
+	getUpdate := func(block uint64) *common.Update {
+		update, found := changes[block]
+		if !found {
+			update = &common.Update{}
+			changes[block] = update
+		}
+		return update
+	}
+
+	if err := a.queryRange(kGetStatusInRangeStmt, from, to, func(rows *sql.Rows) error {
+		var accountBytes sql.RawBytes
+		var block uint64
+		var exists bool
+		if err := rows.Scan(&accountBytes, &block, &exists); err != nil {
+			return err
+		}
+		var account common.Address
+		copy(account[:], accountBytes)
+		if exists {
+			getUpdate(block).AppendCreateAccount(account)
+		} else {
+			getUpdate(block).AppendDeleteAccount(account)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get status updates; %w", err)
+	}
+
+	// Balance, code, and nonce rows share the same layout.
+	accountQueries := []struct {
+		name  string
+		query string
+		add   func(update *common.Update, account common.Address, value []byte)
+	}{
+		{"balance", kGetBalanceInRangeStmt, func(update *common.Update, account common.Address, value []byte) {
+			var balance common.Balance
+			copy(balance[:], value)
+			update.AppendBalanceUpdate(account, balance)
+		}},
+		{"code", kGetCodeInRangeStmt, func(update *common.Update, account common.Address, value []byte) {
+			update.AppendCodeUpdate(account, append([]byte{}, value...))
+		}},
+		{"nonce", kGetNonceInRangeStmt, func(update *common.Update, account common.Address, value []byte) {
+			var nonce common.Nonce
+			copy(nonce[:], value)
+			update.AppendNonceUpdate(account, nonce)
+		}},
+	}
+	for _, cur := range accountQueries {
+		if err := a.queryRange(cur.query, from, to, func(rows *sql.Rows) error {
+			var accountBytes, value sql.RawBytes
+			var block uint64
+			if err := rows.Scan(&accountBytes, &block, &value); err != nil {
+				return err
+			}
+			var account common.Address
+			copy(account[:], accountBytes)
+			cur.add(getUpdate(block), account, value)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to get %s updates; %w", cur.name, err)
+		}
+	}
+
+	// Slots are always written for the reincarnation of the account
+	// resulting from the status changes of the same block.
+	if err := a.queryRange(kGetStorageInRangeStmt, from, to, func(rows *sql.Rows) error {
+		var accountBytes, slotBytes, valueBytes sql.RawBytes
+		var block uint64
+		if err := rows.Scan(&accountBytes, &slotBytes, &block, &valueBytes); err != nil {
+			return err
+		}
+		var account common.Address
+		var slot common.Key
+		var value common.Value
+		copy(account[:], accountBytes)
+		copy(slot[:], slotBytes)
+		copy(value[:], valueBytes)
+		getUpdate(block).AppendSlotUpdate(account, slot, value)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get storage updates; %w", err)
+	}
+
+	return archive.ToBlockUpdates(blocks, changes)
+}
+
+// queryRange runs the given query for the block range [from, to] and calls
+// the given function for each resulting row.
+func (a *Archive) queryRange(query string, from, to uint64, consume func(*sql.Rows) error) error {
+	rows, err := a.db.Query(query, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := consume(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // GetMemoryFootprint provides the size of the archive in memory in bytes
 func (a *Archive) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(unsafe.Sizeof(*a))
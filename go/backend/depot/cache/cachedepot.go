@@ -98,6 +98,23 @@ func (m *Depot[I]) Close() error {
 	return m.depot.Close()
 }
 
+// GetCacheStatistics summarizes the usage of the value cache of this depot.
+func (m *Depot[I]) GetCacheStatistics() common.CacheStatistics {
+	return m.getAdaptiveCache().GetCacheStatistics()
+}
+
+// SetCacheCapacity changes the number of values retained in the value cache
+// of this depot.
+func (m *Depot[I]) SetCacheCapacity(capacity int) {
+	m.getAdaptiveCache().SetCacheCapacity(capacity)
+}
+
+func (m *Depot[I]) getAdaptiveCache() common.AdaptiveCache {
+	return common.NewAdaptiveLruCache(m.cache, func(value []byte) uintptr {
+		return uintptr(cap(value)) // memory consumed by the code slice
+	})
+}
+
 // GetMemoryFootprint provides the size of the depot in memory in bytes
 func (m *Depot[I]) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(unsafe.Sizeof(*m))
@@ -108,3 +125,8 @@ func (m *Depot[I]) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("sourceDepot", m.depot.GetMemoryFootprint())
 	return mf
 }
+
+// GetDiskFootprint provides the size of the depot on disk in bytes
+func (m *Depot[I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return m.depot.GetDiskFootprint()
+}
@@ -36,6 +36,9 @@ type Depot[I common.Identifier] interface {
 	// provides the size of the depot in memory in bytes
 	common.MemoryFootprintProvider
 
+	// provides the size of the depot on disk in bytes
+	common.DiskFootprintProvider
+
 	// Also, depots need to be flush and closable.
 	common.FlushAndCloser
 
@@ -79,6 +79,21 @@ func getDepotsFactories(tb testing.TB, branchingFactor int, groupSize int) (stor
 				return &ldbDepotWrapper{dep, db}
 			},
 		},
+		{
+			label: "Pebble",
+			getDepot: func(tempDir string) depot.Depot[uint32] {
+				db, err := backend.OpenPebbleDb(tempDir, nil)
+				if err != nil {
+					tb.Fatalf("failed to open Pebble; %s", err)
+				}
+				hashTree := htldb.CreateHashTreeFactory(db, backend.DepotCodeKey, branchingFactor)
+				dep, err := ldb.NewDepot[uint32](db, backend.DepotCodeKey, common.Identifier32Serializer{}, hashTree, groupSize)
+				if err != nil {
+					tb.Fatalf("failed to create depot; %s", err)
+				}
+				return &ldbDepotWrapper{dep, db}
+			},
+		},
 		{
 			label: "CachedFile",
 			getDepot: func(tempDir string) depot.Depot[uint32] {
@@ -662,3 +677,28 @@ func compareHashes(depots map[string]depot.Depot[uint32]) error {
 	}
 	return nil
 }
+
+func TestDepotDiskFootprint(t *testing.T) {
+	for _, factory := range getDepotsFactories(t, BranchingFactor, GroupSize) {
+		t.Run(factory.label, func(t *testing.T) {
+			d := factory.getDepot(t.TempDir())
+			defer d.Close()
+
+			if err := d.Set(1, D); err != nil {
+				t.Fatalf("failed to set into a depot; %s", err)
+			}
+			if err := d.Flush(); err != nil {
+				t.Fatalf("failed to flush depot; %s", err)
+			}
+
+			df, err := d.GetDiskFootprint()
+			if err != nil {
+				t.Fatalf("failed to get disk footprint; %s", err)
+			}
+			persisted := factory.label == "File" || factory.label == "CachedFile"
+			if got := df.Total() > 0; got != persisted {
+				t.Errorf("unexpected disk footprint %d", df.Total())
+			}
+		})
+	}
+}
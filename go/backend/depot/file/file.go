@@ -29,6 +29,7 @@ const LengthSize = 4 // uint32
 
 // Depot is a file-based Depot implementation
 type Depot[I common.Identifier] struct {
+	directory       string
 	contentsFile    *os.File
 	offsetsFile     *os.File
 	hashTree        hashtree.HashTree
@@ -59,6 +60,7 @@ func NewDepot[I common.Identifier](path string,
 	}
 
 	m := &Depot[I]{
+		directory:       path,
 		contentsFile:    contentsFile,
 		offsetsFile:     offsetsFile,
 		indexSerializer: indexSerializer,
@@ -403,6 +405,11 @@ func (m *Depot[I]) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// GetDiskFootprint provides the size of the depot on disk in bytes
+func (m *Depot[I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.GetDirectoryDiskFootprint(m.directory)
+}
+
 func (m *Depot[I]) getFragmentationReport() string {
 	fragRatio := float32(m.fragmentedCalls) / float32(m.pagesCalls)
 	return fmt.Sprintf("(pagesCalls: %d, fragmented: %d, fragRatio: %f)", m.pagesCalls, m.fragmentedCalls, fragRatio)
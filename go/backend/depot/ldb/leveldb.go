@@ -18,16 +18,14 @@ import (
 	"github.com/Fantom-foundation/Carmen/go/backend/hashtree"
 	"github.com/Fantom-foundation/Carmen/go/backend/memsnap"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"unsafe"
 )
 
 const LengthSize = 4 // uint32
 
-// Depot is an LevelDB backed store.Depot implementation
+// Depot is a key-value store (LevelDB or Pebble) backed store.Depot implementation
 type Depot[I common.Identifier] struct {
-	db              backend.LevelDB
+	db              backend.KVStore
 	table           backend.TableSpace
 	hashTree        hashtree.HashTree
 	indexSerializer common.Serializer[I]
@@ -37,7 +35,7 @@ type Depot[I common.Identifier] struct {
 }
 
 // NewDepot constructs a new instance of Depot.
-func NewDepot[I common.Identifier](db backend.LevelDB,
+func NewDepot[I common.Identifier](db backend.KVStore,
 	table backend.TableSpace,
 	indexSerializer common.Serializer[I],
 	hashtreeFactory hashtree.Factory,
@@ -71,8 +69,7 @@ func (m *Depot[I]) GetPage(hashGroup int) ([]byte, error) {
 	startKey, endKey := m.hashGroupRange(hashGroup)
 	startDbKey := m.convertKey(I(startKey)).ToBytes()
 	endDbKey := m.convertKey(I(endKey)).ToBytes()
-	keysRange := util.Range{Start: startDbKey, Limit: endDbKey}
-	iter := m.db.NewIterator(&keysRange, nil)
+	iter := m.db.NewIterator(&backend.KVRange{Start: startDbKey, Limit: endDbKey})
 	defer iter.Release()
 
 	// the output consists of values lengths prefix and the values itself
@@ -145,7 +142,7 @@ func (m *Depot[I]) Set(id I, value []byte) error {
 		}
 	}
 
-	err := m.db.Put(m.convertKey(id).ToBytes(), value, nil)
+	err := m.db.Put(m.convertKey(id).ToBytes(), value)
 	if err != nil {
 		return err
 	}
@@ -155,8 +152,8 @@ func (m *Depot[I]) Set(id I, value []byte) error {
 
 // Get a value of the item (or nil if not defined)
 func (m *Depot[I]) Get(id I) (out []byte, err error) {
-	out, err = m.db.Get(m.convertKey(id).ToBytes(), nil)
-	if err == leveldb.ErrNotFound {
+	out, err = m.db.Get(m.convertKey(id).ToBytes())
+	if err == backend.ErrNotFound {
 		return nil, nil
 	}
 	return
@@ -182,8 +179,8 @@ func getPagesCountDbKey(table backend.TableSpace) []byte {
 
 // loadPagesCount loads the amount of pages in the depot into the pagesCount field
 func (m *Depot[I]) loadPagesCount() error {
-	val, err := m.db.Get(getPagesCountDbKey(m.table), nil)
-	if err == leveldb.ErrNotFound {
+	val, err := m.db.Get(getPagesCountDbKey(m.table))
+	if err == backend.ErrNotFound {
 		m.pagesCount = 0
 		return nil
 	}
@@ -198,7 +195,7 @@ func (m *Depot[I]) loadPagesCount() error {
 func (m *Depot[I]) setPagesCount(count int) error {
 	m.pagesCount = count
 	val := binary.LittleEndian.AppendUint32(nil, uint32(count))
-	return m.db.Put(getPagesCountDbKey(m.table), val, nil)
+	return m.db.Put(getPagesCountDbKey(m.table), val)
 }
 
 // GetHash provides a hash of the page (in the latest state)
@@ -249,18 +246,17 @@ func (m *Depot[I]) reset() error {
 		return fmt.Errorf("failed to reset hashTree; %s", err)
 	}
 
-	r := util.Range{Start: []byte{byte(m.table)}, Limit: []byte{byte(m.table) + 1}}
-	iter := m.db.NewIterator(&r, nil)
+	iter := m.db.NewIterator(&backend.KVRange{Start: []byte{byte(m.table)}, Limit: []byte{byte(m.table) + 1}})
 	defer iter.Release()
 
-	batch := leveldb.Batch{}
+	batch := m.db.NewBatch()
 	for iter.Next() {
 		batch.Delete(iter.Key())
 	}
 	if iter.Error() != nil {
 		return iter.Error()
 	}
-	if err := m.db.Write(&batch, nil); err != nil {
+	if err := m.db.Write(batch); err != nil {
 		return fmt.Errorf("failed delete depot content; %s", err)
 	}
 	return nil
@@ -326,3 +322,10 @@ func (m *Depot[I]) GetMemoryFootprint() *common.MemoryFootprint {
 	}
 	return mf
 }
+
+// GetDiskFootprint provides the size of the depot on disk in bytes, which is
+// always zero. The data is kept in a key-value store shared with other
+// components, so the disk usage of the store is reported once by its owner.
+func (m *Depot[I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
@@ -258,3 +258,9 @@ func (m *Depot[I]) GetMemoryFootprint() *common.MemoryFootprint {
 	}
 	return mf
 }
+
+// GetDiskFootprint provides the size of the depot on disk in bytes, which is
+// zero since all data is kept in memory.
+func (m *Depot[I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
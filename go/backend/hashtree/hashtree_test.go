@@ -51,6 +51,16 @@ func getHashTreeFactories(tb testing.TB) (factories []hashTreeFactory) {
 				return closingHashtreeWrapper{htldb.CreateHashTreeFactory(db, backend.DepotCodeKey, 3).Create(testingPageProvider{pages: pages}), db}
 			},
 		},
+		{
+			label: "Pebble",
+			getHashtree: func(tempDir string, pages [][]byte) closingHashtreeWrapper {
+				db, err := backend.OpenPebbleDb(tempDir, nil)
+				if err != nil {
+					tb.Fatalf("failed to open Pebble; %s", err)
+				}
+				return closingHashtreeWrapper{htldb.CreateHashTreeFactory(db, backend.DepotCodeKey, 3).Create(testingPageProvider{pages: pages}), db}
+			},
+		},
 	}
 }
 
@@ -17,8 +17,6 @@ import (
 	"github.com/Fantom-foundation/Carmen/go/backend"
 	"github.com/Fantom-foundation/Carmen/go/backend/hashtree"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"hash"
 	"unsafe"
 )
@@ -32,7 +30,7 @@ const (
 // HashTree is a structure allowing to make a hash of the whole database state.
 // It obtains hashes of individual data pages and reduce them to a hash of the entire state.
 type HashTree struct {
-	db           backend.LevelDB
+	db           backend.KVStore
 	table        backend.TableSpace
 	factor       int          // the branching factor - amount of child nodes per one parent node
 	dirtyPages   map[int]bool // set of dirty flags of the tree nodes
@@ -42,13 +40,13 @@ type HashTree struct {
 
 // hashTreeFactory is used for implementation of hashTreeFactory method
 type hashTreeFactory struct {
-	db              backend.LevelDB
+	db              backend.KVStore
 	table           backend.TableSpace
 	branchingFactor int
 }
 
 // CreateHashTreeFactory creates a new instance of the hashTreeFactory
-func CreateHashTreeFactory(db backend.LevelDB, table backend.TableSpace, branchingFactor int) *hashTreeFactory {
+func CreateHashTreeFactory(db backend.KVStore, table backend.TableSpace, branchingFactor int) *hashTreeFactory {
 	return &hashTreeFactory{db: db, table: table, branchingFactor: branchingFactor}
 }
 
@@ -58,7 +56,7 @@ func (f *hashTreeFactory) Create(pageProvider hashtree.PageProvider) hashtree.Ha
 }
 
 // NewHashTree constructs a new HashTree
-func NewHashTree(db backend.LevelDB, table backend.TableSpace, branchingFactor int, pageProvider hashtree.PageProvider) *HashTree {
+func NewHashTree(db backend.KVStore, table backend.TableSpace, branchingFactor int, pageProvider hashtree.PageProvider) *HashTree {
 	return &HashTree{
 		db:           db,
 		table:        table,
@@ -72,18 +70,17 @@ func NewHashTree(db backend.LevelDB, table backend.TableSpace, branchingFactor i
 func (ht *HashTree) Reset() error {
 	dbStartKey := getNodeDbKey(ht.table, 0, 0).ToBytes()
 	dbEndKey := getNodeDbKey(ht.table, MaxLayer, MaxNode).ToBytes()
-	r := util.Range{Start: dbStartKey, Limit: dbEndKey}
-	iter := ht.db.NewIterator(&r, nil)
+	iter := ht.db.NewIterator(&backend.KVRange{Start: dbStartKey, Limit: dbEndKey})
 	defer iter.Release()
 
-	var batch leveldb.Batch
+	batch := ht.db.NewBatch()
 	for iter.Next() {
 		batch.Delete(iter.Key())
 	}
 	if err := iter.Error(); err != nil {
 		return err
 	}
-	err := ht.db.Write(&batch, nil)
+	err := ht.db.Write(batch)
 	ht.dirtyPages = map[int]bool{}
 	return err
 }
@@ -117,10 +114,10 @@ func (ht *HashTree) GetPageHash(page int) (common.Hash, error) {
 	return GetPageHashFromLdb(ht.table, page, ht.db)
 }
 
-// GetPageHashFromLdb provides a hash of the tree leaf node from given LevelDB snapshot
-func GetPageHashFromLdb(table backend.TableSpace, page int, db backend.LevelDBReader) (common.Hash, error) {
+// GetPageHashFromLdb provides a hash of the tree leaf node from given key-value store or snapshot
+func GetPageHashFromLdb(table backend.TableSpace, page int, db backend.KVReader) (common.Hash, error) {
 	dbKey := getNodeDbKey(table, 0, page).ToBytes()
-	hashBytes, err := db.Get(dbKey, nil)
+	hashBytes, err := db.Get(dbKey)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to get page hash; %s", err)
 	}
@@ -139,8 +136,7 @@ func (ht *HashTree) childrenOfNode(layer, node int) (data []byte, err error) {
 	lastNode := firstNode + ht.factor
 	dbStartKey := getNodeDbKey(ht.table, layer-1, firstNode).ToBytes()
 	dbEndKey := getNodeDbKey(ht.table, layer-1, lastNode).ToBytes()
-	r := util.Range{Start: dbStartKey, Limit: dbEndKey}
-	iter := ht.db.NewIterator(&r, nil)
+	iter := ht.db.NewIterator(&backend.KVRange{Start: dbStartKey, Limit: dbEndKey})
 	defer iter.Release()
 
 	// create the page first
@@ -163,8 +159,7 @@ func (ht *HashTree) layerLength(layer int) (length int, err error) {
 	// set the range for full layer
 	firstNode := getNodeDbKey(ht.table, layer, 0).ToBytes()
 	lastNode := getNodeDbKey(ht.table, layer, 0xFFFFFFFF).ToBytes()
-	r := util.Range{Start: firstNode, Limit: lastNode}
-	iter := ht.db.NewIterator(&r, nil)
+	iter := ht.db.NewIterator(&backend.KVRange{Start: firstNode, Limit: lastNode})
 	defer iter.Release()
 	if iter.Last() {
 		key := iter.Key()
@@ -180,8 +175,7 @@ func (ht *HashTree) getRootHash() (hash []byte, err error) {
 	// set the range for full layers
 	firstNode := getNodeDbKey(ht.table, 0, 0).ToBytes()
 	lastNode := getNodeDbKey(ht.table, 0xFF, 0).ToBytes()
-	r := util.Range{Start: firstNode, Limit: lastNode}
-	iter := ht.db.NewIterator(&r, nil)
+	iter := ht.db.NewIterator(&backend.KVRange{Start: firstNode, Limit: lastNode})
 	defer iter.Release()
 	if iter.Last() {
 		hash = iter.Value()
@@ -194,7 +188,7 @@ func (ht *HashTree) getRootHash() (hash []byte, err error) {
 // updateNode updates the hash-node value to the given value
 func (ht *HashTree) updateNode(layer, node int, nodeHash []byte) error {
 	dbKey := getNodeDbKey(ht.table, layer, node).ToBytes()
-	return ht.db.Put(dbKey, nodeHash, nil)
+	return ht.db.Put(dbKey, nodeHash)
 }
 
 // parentOf provides an index of a parent node, by the child index
@@ -92,6 +92,12 @@ func (m *Index[K, I]) GetProof() (backend.Proof, error) {
 	return index.NewIndexProof(common.Hash{}, hash), nil
 }
 
+// ForEach calls the given function for each key registered in the wrapped
+// index and its identifier.
+func (m *Index[K, I]) ForEach(visit func(key K, id I) error) error {
+	return index.ForEach[K, I](m.wrapped, visit)
+}
+
 func (m *Index[K, I]) CreateSnapshot() (backend.Snapshot, error) {
 	return m.wrapped.CreateSnapshot()
 }
@@ -112,3 +118,8 @@ func (m *Index[K, I]) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("sourceIndex", m.wrapped.GetMemoryFootprint())
 	return mf
 }
+
+// GetDiskFootprint provides the size of the index on disk in bytes
+func (m *Index[K, I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return m.wrapped.GetDiskFootprint()
+}
@@ -440,6 +440,11 @@ func (m *Index[K, I]) GetMemoryFootprint() *common.MemoryFootprint {
 	return memoryFootprint
 }
 
+// GetDiskFootprint provides the size of the index on disk in bytes
+func (m *Index[K, I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.GetDirectoryDiskFootprint(m.path)
+}
+
 func readMetadata[I common.Identifier](path string, indexSerializer common.Serializer[I]) (hash common.Hash, numBuckets, records int, lastIndex I, err error) {
 	metadataFile, err := os.OpenFile(path+"/metadata.dat", os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
@@ -43,6 +43,9 @@ type Index[K comparable, I common.Identifier] interface {
 	// provides the size of the index in memory in bytes
 	common.MemoryFootprintProvider
 
+	// provides the size of the index on disk in bytes
+	common.DiskFootprintProvider
+
 	// Also, indexes need to be flush and closable.
 	common.FlushAndCloser
 
@@ -11,6 +11,7 @@
 package index_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -88,6 +89,21 @@ func initIndexesMap() map[string]func(t *testing.T) index.Index[common.Address,
 			})
 			return cache.NewIndex[common.Address, uint32](ldbindex, 10)
 		},
+		"pebbleIndex": func(t *testing.T) index.Index[common.Address, uint32] {
+			db, err := backend.OpenPebbleDb(t.TempDir(), nil)
+			if err != nil {
+				t.Fatalf("failed to init pebble; %s", err)
+			}
+			pebbleIndex, err := ldb.NewIndex[common.Address, uint32](db, backend.BalanceStoreKey, keySerializer, idSerializer)
+			if err != nil {
+				t.Fatalf("failed to init pebble; %s", err)
+			}
+			t.Cleanup(func() {
+				_ = pebbleIndex.Close()
+				_ = db.Close()
+			})
+			return pebbleIndex
+		},
 	}
 }
 
@@ -131,6 +147,75 @@ func TestIndex_SizeIsAccuratelyReported(t *testing.T) {
 	}
 }
 
+func TestIndex_ForEachVisitsAllKeys(t *testing.T) {
+	for name, idx := range initIndexesMap() {
+		t.Run(name, func(t *testing.T) {
+			tested := idx(t)
+			want := map[common.Address]uint32{}
+			for i := 0; i < 1000; i++ {
+				key := common.Address{byte(i), byte(i >> 8)}
+				id, err := tested.GetOrAdd(key)
+				if err != nil {
+					t.Fatalf("failed to register new key: %v", err)
+				}
+				want[key] = id
+			}
+			if err := tested.Flush(); err != nil {
+				t.Fatalf("failed to flush index: %v", err)
+			}
+
+			got := map[common.Address]uint32{}
+			err := index.ForEach(tested, func(key common.Address, id uint32) error {
+				if _, found := got[key]; found {
+					t.Errorf("key %v visited twice", key)
+				}
+				got[key] = id
+				return nil
+			})
+			if errors.Is(err, backend.ErrSnapshotNotSupported) {
+				t.Skip("index does not support enumeration")
+			}
+			if err != nil {
+				t.Fatalf("failed to enumerate index: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Errorf("wrong number of visited keys, wanted %d, got %d", len(want), len(got))
+			}
+			for key, id := range want {
+				if got[key] != id {
+					t.Errorf("wrong id of key %v, wanted %d, got %d", key, id, got[key])
+				}
+			}
+		})
+	}
+}
+
+func TestIndex_ForEachAbortsOnError(t *testing.T) {
+	for name, idx := range initIndexesMap() {
+		t.Run(name, func(t *testing.T) {
+			tested := idx(t)
+			for i := 0; i < 10; i++ {
+				if _, err := tested.GetOrAdd(common.Address{byte(i)}); err != nil {
+					t.Fatalf("failed to register new key: %v", err)
+				}
+			}
+
+			injectedErr := fmt.Errorf("injected error")
+			visited := 0
+			err := index.ForEach(tested, func(common.Address, uint32) error {
+				visited++
+				return injectedErr
+			})
+			if errors.Is(err, backend.ErrSnapshotNotSupported) {
+				t.Skip("index does not support enumeration")
+			}
+			if !errors.Is(err, injectedErr) || visited != 1 {
+				t.Errorf("enumeration should be aborted by first error, got %v after %d keys", err, visited)
+			}
+		})
+	}
+}
+
 func TestIndexesInitialHash(t *testing.T) {
 	indexes := initIndexesMap()
 
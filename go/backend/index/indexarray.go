@@ -152,3 +152,15 @@ func (m *Array[K, I]) GetMemoryFootprint() *common.MemoryFootprint {
 	}
 	return mf
 }
+
+func (m *Array[K, I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	df := common.NewDiskFootprint(0)
+	for i, index := range m.indexes {
+		child, err := index.GetDiskFootprint()
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild(strconv.FormatInt(int64(i), 10), child)
+	}
+	return df, nil
+}
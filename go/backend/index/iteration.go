@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package index
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// Iterable is an optional interface of indexes able to enumerate their
+// content directly. It is implemented by indexes not supporting snapshots,
+// which are otherwise used by ForEach to enumerate the keys of an index.
+type Iterable[K comparable, I common.Identifier] interface {
+	// ForEach calls the given function for each registered key and its
+	// identifier. The order of the visited keys is undefined. The iteration
+	// is aborted by the first error returned by the visitor.
+	ForEach(visit func(key K, id I) error) error
+}
+
+// ForEach calls the given function for each key registered in the given
+// index and its identifier. Indexes implementing the Iterable interface
+// are enumerated directly, all other indexes are enumerated through a
+// snapshot, which lists keys in the order of their identifiers. The order
+// of the visited keys is thus undefined in general. The iteration is
+// aborted by the first error returned by the visitor.
+func ForEach[K comparable, I common.Identifier](index Index[K, I], visit func(key K, id I) error) error {
+	if iterable, ok := index.(Iterable[K, I]); ok {
+		return iterable.ForEach(visit)
+	}
+
+	snapshot, err := index.CreateSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to enumerate index: %w", err)
+	}
+	indexSnapshot, ok := snapshot.(*IndexSnapshot[K])
+	if !ok {
+		return errors.Join(fmt.Errorf("unsupported index snapshot type: %T", snapshot), snapshot.Release())
+	}
+	next := I(0)
+	for i := 0; i < indexSnapshot.GetNumParts(); i++ {
+		part, err := indexSnapshot.GetPart(i)
+		if err != nil {
+			return errors.Join(err, snapshot.Release())
+		}
+		for _, key := range part.(*IndexPart[K]).GetKeys() {
+			if err := visit(key, next); err != nil {
+				return errors.Join(err, snapshot.Release())
+			}
+			next++
+		}
+	}
+	return snapshot.Release()
+}
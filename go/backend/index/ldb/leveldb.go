@@ -18,8 +18,6 @@ import (
 	"github.com/Fantom-foundation/Carmen/go/backend/index"
 	"github.com/Fantom-foundation/Carmen/go/backend/index/indexhash"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/errors"
 )
 
 const (
@@ -29,7 +27,7 @@ const (
 
 // Index represents a key-value store for holding the index data.
 type Index[K comparable, I common.Identifier] struct {
-	db              backend.LevelDB
+	db              backend.KVStore
 	table           backend.TableSpace
 	keySerializer   common.Serializer[K]
 	indexSerializer common.Serializer[I]
@@ -39,7 +37,7 @@ type Index[K comparable, I common.Identifier] struct {
 
 // NewIndex creates a new instance of the index backed by a persisted database
 func NewIndex[K comparable, I common.Identifier](
-	db backend.LevelDB,
+	db backend.KVStore,
 	table backend.TableSpace,
 	keySerializer common.Serializer[K],
 	indexSerializer common.Serializer[I]) (p *Index[K, I], err error) {
@@ -47,8 +45,8 @@ func NewIndex[K comparable, I common.Identifier](
 	// read the last hash from the database
 	var hash common.Hash
 	hashDbKey := strToDBKey(table, HashKey).ToBytes()
-	hashBytes, err := db.Get(hashDbKey, nil)
-	if err != nil && err != errors.ErrNotFound {
+	hashBytes, err := db.Get(hashDbKey)
+	if err != nil && err != backend.ErrNotFound {
 		return
 	}
 	if err == nil {
@@ -58,8 +56,8 @@ func NewIndex[K comparable, I common.Identifier](
 	// read the last index from the database
 	var lastIndex I
 	lastDbKey := strToDBKey(table, LastIndexKey).ToBytes()
-	lastIndexBytes, err := db.Get(lastDbKey, nil)
-	if err != nil && err != errors.ErrNotFound {
+	lastIndexBytes, err := db.Get(lastDbKey)
+	if err != nil && err != backend.ErrNotFound {
 		return
 	}
 	if err == nil {
@@ -88,9 +86,9 @@ func (m *Index[K, I]) Size() I {
 func (m *Index[K, I]) GetOrAdd(key K) (idx I, err error) {
 	var val []byte
 	dbKey := m.convertKey(key).ToBytes()
-	if val, err = m.db.Get(dbKey, nil); err != nil {
+	if val, err = m.db.Get(dbKey); err != nil {
 		// if the error is actually a non-existing key, we assign a new index
-		if err == errors.ErrNotFound {
+		if err == backend.ErrNotFound {
 
 			// map the input key to the next level as well as storing the next index
 			idx = m.lastIndex
@@ -100,10 +98,10 @@ func (m *Index[K, I]) GetOrAdd(key K) (idx I, err error) {
 			nextIdxArr := m.indexSerializer.ToBytes(m.lastIndex)
 
 			lastIndexDbKey := m.convertKeyStr(LastIndexKey).ToBytes()
-			batch := new(leveldb.Batch)
+			batch := m.db.NewBatch()
 			batch.Put(lastIndexDbKey, nextIdxArr)
 			batch.Put(dbKey, idxArr)
-			if err = m.db.Write(batch, nil); err != nil {
+			if err = m.db.Write(batch); err != nil {
 				return
 			}
 			m.hashIndex.AddKey(key)
@@ -121,8 +119,8 @@ func (m *Index[K, I]) GetOrAdd(key K) (idx I, err error) {
 func (m *Index[K, I]) Get(key K) (idx I, err error) {
 	var val []byte
 	dbKey := m.convertKey(key).ToBytes()
-	if val, err = m.db.Get(dbKey, nil); err != nil {
-		if err == errors.ErrNotFound {
+	if val, err = m.db.Get(dbKey); err != nil {
+		if err == backend.ErrNotFound {
 			err = index.ErrNotFound
 		}
 		return
@@ -135,10 +133,33 @@ func (m *Index[K, I]) Get(key K) (idx I, err error) {
 // Contains returns whether the key exists in the mapping or not.
 func (m *Index[K, I]) Contains(key K) bool {
 	dbKey := m.convertKey(key).ToBytes()
-	exists, _ := m.db.Has(dbKey, nil)
+	exists, _ := m.db.Has(dbKey)
 	return exists
 }
 
+// ForEach calls the given function for each registered key and its
+// identifier in the order of the serialized keys.
+func (m *Index[K, I]) ForEach(visit func(key K, id I) error) error {
+	hashDbKey := m.convertKeyStr(HashKey)
+	lastDbKey := m.convertKeyStr(LastIndexKey)
+	keySize := m.keySerializer.Size()
+
+	iter := m.db.NewIterator(backend.KVPrefix([]byte{byte(m.table)}))
+	defer iter.Release()
+	for iter.Next() {
+		var dbKey backend.DbKey
+		copy(dbKey[:], iter.Key())
+		if dbKey == hashDbKey || dbKey == lastDbKey {
+			continue
+		}
+		key := m.keySerializer.FromBytes(dbKey[1 : 1+keySize])
+		if err := visit(key, m.indexSerializer.FromBytes(iter.Value())); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
 // GetStateHash returns the index hash.
 func (m *Index[K, I]) GetStateHash() (hash common.Hash, err error) {
 	return m.hashIndex.Commit()
@@ -152,7 +173,7 @@ func (m *Index[K, I]) Flush() error {
 	}
 
 	hashDbKey := m.convertKeyStr(HashKey).ToBytes()
-	return m.db.Put(hashDbKey, hash[:], nil)
+	return m.db.Put(hashDbKey, hash[:])
 }
 
 func (m *Index[K, I]) Close() error {
@@ -198,6 +219,13 @@ func (m *Index[K, I]) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// GetDiskFootprint provides the size of the index on disk in bytes, which is
+// always zero. The data is kept in a key-value store shared with other
+// components, so the disk usage of the store is reported once by its owner.
+func (m *Index[K, I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
+
 // strToDBKey converts the input key to its respective table space key
 func strToDBKey(t backend.TableSpace, key string) backend.DbKey {
 	return backend.ToDBKey(t, []byte(key))
@@ -289,7 +289,7 @@ func reopenIndexDb(t *testing.T, idx index.Index[common.Address, uint32], db *ba
 }
 
 // createIndex creates a new instance of the index using the input database
-func createIndex(t *testing.T, db backend.LevelDB) index.Index[common.Address, uint32] {
+func createIndex(t *testing.T, db backend.KVStore) index.Index[common.Address, uint32] {
 	idx, err := NewIndex[common.Address, uint32](db, backend.BalanceStoreKey, common.AddressSerializer{}, common.Identifier32Serializer{})
 	if err != nil {
 		t.Fatalf("Cannot open Index, err: %s", err)
@@ -117,3 +117,9 @@ func (m *LinearHashIndex[K, I]) GetMemoryFootprint() *common.MemoryFootprint {
 
 	return memoryFootprint
 }
+
+// GetDiskFootprint provides the size of the index on disk in bytes, which is
+// zero since all data is kept in memory.
+func (m *LinearHashIndex[K, I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
@@ -205,3 +205,9 @@ func (m *Index[K, I]) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.SetNote(fmt.Sprintf("(items: %d)", len(m.data)))
 	return mf
 }
+
+// GetDiskFootprint provides the size of the index on disk in bytes, which is
+// zero since all data is kept in memory.
+func (m *Index[K, I]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package backend
+
+import (
+	"errors"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// ErrNotFound is returned by key-value stores for keys not present in the store.
+var ErrNotFound = errors.New("key not found")
+
+// KVReader is the read interface shared by key-value stores and their
+// snapshots. It is implemented by the different key-value engines (LevelDB,
+// Pebble) such that the components built on top of them are engine agnostic.
+type KVReader interface {
+	// Get gets the value for the given key. It returns ErrNotFound if the
+	// store does not contain the key.
+	//
+	// The returned slice is its own copy, it is safe to modify the contents
+	// of the returned slice.
+	// It is safe to modify the contents of the argument after Get returns.
+	Get(key []byte) (value []byte, err error)
+
+	// Has returns true if the store does contain the given key.
+	//
+	// It is safe to modify the contents of the argument after Has returns.
+	Has(key []byte) (bool, error)
+
+	// NewIterator returns an iterator for the latest state of the underlying
+	// store. The returned iterator is not safe for concurrent use, but it is
+	// safe to use multiple iterators concurrently, with each in a dedicated
+	// goroutine.
+	//
+	// The range limits the iterator to keys in the given range. A nil range
+	// or a nil Range.Start is treated as a key before all keys in the store.
+	// And a nil Range.Limit is treated as a key after all keys in the store.
+	//
+	// WARNING: Any slice returned by iterator (e.g. slice returned by calling
+	// Iterator.Key() or Iterator.Value() methods), its content should not be
+	// modified and is only valid until the iterator is moved.
+	//
+	// The iterator must be released after use, by calling Release method.
+	NewIterator(keys *KVRange) KVIterator
+}
+
+// KVStore is a persistent ordered key-value store.
+type KVStore interface {
+	KVReader
+
+	// Put sets the value for the given key. It overwrites any previous value
+	// for that key.
+	//
+	// It is safe to modify the contents of the arguments after Put returns.
+	Put(key, value []byte) error
+
+	// Delete deletes the value for the given key.
+	//
+	// It is safe to modify the contents of the arguments after Delete returns.
+	Delete(key []byte) error
+
+	// NewBatch creates an empty batch of updates to be applied to this store
+	// using Write.
+	NewBatch() KVBatch
+
+	// Write applies the given batch, created by NewBatch of this store,
+	// atomically to the store. A batch may only be written once, it needs to
+	// be reset before being reused.
+	Write(batch KVBatch) error
+
+	// GetSnapshot returns a latest snapshot of the underlying store. A
+	// snapshot is a frozen snapshot of the store state at a particular point
+	// in time. The content of snapshot are guaranteed to be consistent.
+	//
+	// The snapshot must be released after use, by calling Release method.
+	GetSnapshot() (KVSnapshot, error)
+
+	common.MemoryFootprintProvider
+}
+
+// KVSnapshot is a frozen, read-only view on a KVStore.
+type KVSnapshot interface {
+	KVReader
+
+	// Release releases the snapshot. The snapshot must not be used afterwards.
+	Release()
+}
+
+// KVBatch collects updates to be applied atomically to a KVStore.
+type KVBatch interface {
+	// Put appends a put operation of the given key/value pair to the batch.
+	// It is safe to modify the contents of the arguments after Put returns.
+	Put(key, value []byte)
+
+	// Delete appends a delete operation of the given key to the batch.
+	// It is safe to modify the contents of the argument after Delete returns.
+	Delete(key []byte)
+
+	// Reset removes all operations from the batch such that it can be reused.
+	Reset()
+}
+
+// KVIterator iterates over the key/value pairs of a KVStore in key order.
+type KVIterator interface {
+	// Next moves the iterator to the next key/value pair, or to the first
+	// one if the iterator has not been positioned yet. It returns false if
+	// the iterator is exhausted.
+	Next() bool
+
+	// Last moves the iterator to the last key/value pair. It returns false
+	// if the iterator is empty.
+	Last() bool
+
+	// Key returns the key of the current key/value pair, or nil if done.
+	Key() []byte
+
+	// Value returns the value of the current key/value pair, or nil if done.
+	Value() []byte
+
+	// Error returns any accumulated error. Exhausting all the key/value
+	// pairs is not considered to be an error.
+	Error() error
+
+	// Release releases associated resources. Release should always succeed
+	// and can be called multiple times without causing error.
+	Release()
+}
+
+// KVRange is a key range [Start, Limit) of a KVStore.
+type KVRange struct {
+	// Start of the key range, included in the range.
+	Start []byte
+
+	// Limit of the key range, not included in the range.
+	Limit []byte
+}
+
+// KVPrefix returns the key range covering all keys with the given prefix.
+func KVPrefix(prefix []byte) *KVRange {
+	var limit []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			limit = make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i]++
+			break
+		}
+	}
+	return &KVRange{Start: prefix, Limit: limit}
+}
+
+// errorIterator is an empty iterator reporting a fixed error.
+type errorIterator struct {
+	err error
+}
+
+func (i errorIterator) Next() bool    { return false }
+func (i errorIterator) Last() bool    { return false }
+func (i errorIterator) Key() []byte   { return nil }
+func (i errorIterator) Value() []byte { return nil }
+func (i errorIterator) Error() error  { return i.err }
+func (i errorIterator) Release()      {}
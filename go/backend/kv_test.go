@@ -0,0 +1,259 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package backend
+
+import (
+	"io"
+	"slices"
+	"testing"
+)
+
+type closableKVStore interface {
+	KVStore
+	io.Closer
+}
+
+func getKVStoreFactories() map[string]func(t *testing.T, dir string) closableKVStore {
+	return map[string]func(t *testing.T, dir string) closableKVStore{
+		"LevelDb": func(t *testing.T, dir string) closableKVStore {
+			db, err := OpenLevelDb(dir, nil)
+			if err != nil {
+				t.Fatalf("failed to open LevelDB: %v", err)
+			}
+			return db
+		},
+		"Pebble": func(t *testing.T, dir string) closableKVStore {
+			db, err := OpenPebbleDb(dir, nil)
+			if err != nil {
+				t.Fatalf("failed to open Pebble: %v", err)
+			}
+			return db
+		},
+	}
+}
+
+func collectKeys(t *testing.T, iter KVIterator) []string {
+	t.Helper()
+	defer iter.Release()
+	res := []string{}
+	for iter.Next() {
+		res = append(res, string(iter.Key()))
+	}
+	if err := iter.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	return res
+}
+
+func TestKVStore_GetReturnsStoredValuesAndErrNotFoundForMissingKeys(t *testing.T) {
+	for name, open := range getKVStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			db := open(t, t.TempDir())
+			defer db.Close()
+
+			if _, err := db.Get([]byte("a")); err != ErrNotFound {
+				t.Errorf("unexpected error for missing key, wanted %v, got %v", ErrNotFound, err)
+			}
+			if found, err := db.Has([]byte("a")); err != nil || found {
+				t.Errorf("missing key should not be found, got %t, %v", found, err)
+			}
+			if err := db.Put([]byte("a"), []byte{1, 2}); err != nil {
+				t.Fatalf("failed to put value: %v", err)
+			}
+			if value, err := db.Get([]byte("a")); err != nil || !slices.Equal(value, []byte{1, 2}) {
+				t.Errorf("unexpected value, got %v, %v", value, err)
+			}
+			if found, err := db.Has([]byte("a")); err != nil || !found {
+				t.Errorf("stored key should be found, got %t, %v", found, err)
+			}
+			if err := db.Delete([]byte("a")); err != nil {
+				t.Fatalf("failed to delete value: %v", err)
+			}
+			if _, err := db.Get([]byte("a")); err != ErrNotFound {
+				t.Errorf("unexpected error for deleted key, wanted %v, got %v", ErrNotFound, err)
+			}
+		})
+	}
+}
+
+func TestKVStore_IteratorsCoverTheirRange(t *testing.T) {
+	for name, open := range getKVStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			db := open(t, t.TempDir())
+			defer db.Close()
+
+			for _, key := range []string{"a", "ba", "bb", "c", "d"} {
+				if err := db.Put([]byte(key), []byte(key)); err != nil {
+					t.Fatalf("failed to put value: %v", err)
+				}
+			}
+
+			if got, want := collectKeys(t, db.NewIterator(nil)), []string{"a", "ba", "bb", "c", "d"}; !slices.Equal(got, want) {
+				t.Errorf("unexpected keys, wanted %v, got %v", want, got)
+			}
+			if got, want := collectKeys(t, db.NewIterator(&KVRange{Start: []byte("b"), Limit: []byte("d")})), []string{"ba", "bb", "c"}; !slices.Equal(got, want) {
+				t.Errorf("unexpected keys, wanted %v, got %v", want, got)
+			}
+			if got, want := collectKeys(t, db.NewIterator(KVPrefix([]byte("b")))), []string{"ba", "bb"}; !slices.Equal(got, want) {
+				t.Errorf("unexpected keys, wanted %v, got %v", want, got)
+			}
+
+			iter := db.NewIterator(&KVRange{Start: []byte("a"), Limit: []byte("c")})
+			defer iter.Release()
+			if !iter.Last() || string(iter.Key()) != "bb" || string(iter.Value()) != "bb" {
+				t.Errorf("unexpected last key, got %s", iter.Key())
+			}
+		})
+	}
+}
+
+func TestKVStore_BatchesAreAppliedOnWrite(t *testing.T) {
+	for name, open := range getKVStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			db := open(t, t.TempDir())
+			defer db.Close()
+
+			if err := db.Put([]byte("a"), []byte{1}); err != nil {
+				t.Fatalf("failed to put value: %v", err)
+			}
+			batch := db.NewBatch()
+			batch.Put([]byte("b"), []byte{2})
+			batch.Delete([]byte("a"))
+			if found, _ := db.Has([]byte("b")); found {
+				t.Errorf("batch should not be applied before being written")
+			}
+			if err := db.Write(batch); err != nil {
+				t.Fatalf("failed to write batch: %v", err)
+			}
+			if got, want := collectKeys(t, db.NewIterator(nil)), []string{"b"}; !slices.Equal(got, want) {
+				t.Errorf("unexpected keys, wanted %v, got %v", want, got)
+			}
+
+			batch.Reset()
+			batch.Put([]byte("c"), []byte{3})
+			if err := db.Write(batch); err != nil {
+				t.Fatalf("failed to write reset batch: %v", err)
+			}
+			if got, want := collectKeys(t, db.NewIterator(nil)), []string{"b", "c"}; !slices.Equal(got, want) {
+				t.Errorf("unexpected keys, wanted %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestKVStore_BatchesOfOtherEnginesAreRejected(t *testing.T) {
+	ldb := getKVStoreFactories()["LevelDb"](t, t.TempDir())
+	defer ldb.Close()
+	pebble := getKVStoreFactories()["Pebble"](t, t.TempDir())
+	defer pebble.Close()
+
+	if err := ldb.Write(pebble.NewBatch()); err == nil {
+		t.Errorf("writing a Pebble batch to LevelDB should fail")
+	}
+	if err := pebble.Write(ldb.NewBatch()); err == nil {
+		t.Errorf("writing a LevelDB batch to Pebble should fail")
+	}
+}
+
+func TestKVStore_SnapshotsAreNotAffectedByUpdates(t *testing.T) {
+	for name, open := range getKVStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			db := open(t, t.TempDir())
+			defer db.Close()
+
+			if err := db.Put([]byte("a"), []byte{1}); err != nil {
+				t.Fatalf("failed to put value: %v", err)
+			}
+			snapshot, err := db.GetSnapshot()
+			if err != nil {
+				t.Fatalf("failed to get snapshot: %v", err)
+			}
+			defer snapshot.Release()
+			if err := db.Put([]byte("a"), []byte{2}); err != nil {
+				t.Fatalf("failed to put value: %v", err)
+			}
+			if err := db.Put([]byte("b"), []byte{3}); err != nil {
+				t.Fatalf("failed to put value: %v", err)
+			}
+
+			if value, err := snapshot.Get([]byte("a")); err != nil || !slices.Equal(value, []byte{1}) {
+				t.Errorf("unexpected value in snapshot, got %v, %v", value, err)
+			}
+			if _, err := snapshot.Get([]byte("b")); err != ErrNotFound {
+				t.Errorf("unexpected error for key missing in snapshot, wanted %v, got %v", ErrNotFound, err)
+			}
+			if got, want := collectKeys(t, snapshot.NewIterator(nil)), []string{"a"}; !slices.Equal(got, want) {
+				t.Errorf("unexpected keys, wanted %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestKVStore_ContentIsPreservedWhenReopened(t *testing.T) {
+	for name, open := range getKVStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			db := open(t, dir)
+			if err := db.Put([]byte("a"), []byte{1}); err != nil {
+				t.Fatalf("failed to put value: %v", err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatalf("failed to close store: %v", err)
+			}
+
+			db = open(t, dir)
+			defer db.Close()
+			if value, err := db.Get([]byte("a")); err != nil || !slices.Equal(value, []byte{1}) {
+				t.Errorf("unexpected value after reopening, got %v, %v", value, err)
+			}
+		})
+	}
+}
+
+func TestKVStore_AccessesToClosedStoresFail(t *testing.T) {
+	for name, open := range getKVStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			db := open(t, t.TempDir())
+			if err := db.Close(); err != nil {
+				t.Fatalf("failed to close store: %v", err)
+			}
+			if _, err := db.Get([]byte("a")); err == nil {
+				t.Errorf("get on closed store should fail")
+			}
+			if err := db.Put([]byte("a"), []byte{1}); err == nil {
+				t.Errorf("put on closed store should fail")
+			}
+			iter := db.NewIterator(nil)
+			if iter.Next() || iter.Error() == nil {
+				t.Errorf("iterating closed store should fail")
+			}
+			iter.Release()
+		})
+	}
+}
+
+func TestKVPrefix_CoversAllKeysWithPrefix(t *testing.T) {
+	tests := []struct {
+		prefix []byte
+		limit  []byte
+	}{
+		{[]byte{1}, []byte{2}},
+		{[]byte{1, 0xff}, []byte{2}},
+		{[]byte{0xff, 0xff}, nil},
+		{[]byte{}, nil},
+	}
+	for _, test := range tests {
+		keys := KVPrefix(test.prefix)
+		if !slices.Equal(keys.Start, test.prefix) || !slices.Equal(keys.Limit, test.limit) {
+			t.Errorf("unexpected range for prefix %v, wanted [%v, %v), got [%v, %v)", test.prefix, test.prefix, test.limit, keys.Start, keys.Limit)
+		}
+	}
+}
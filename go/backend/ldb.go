@@ -62,6 +62,8 @@ const (
 	StorageArchiveKey TableSpace = '6'
 	// AccountHashArchiveKey is a tablespace for archive account hashes
 	AccountHashArchiveKey TableSpace = '7'
+	// BlockUpdateArchiveKey is a tablespace for archive index of changes by block numbers
+	BlockUpdateArchiveKey TableSpace = '8'
 )
 
 // DbKey expects max size of the 36B key plus at most two bytes
@@ -82,119 +84,6 @@ func ToDBKey(t TableSpace, key []byte) DbKey {
 	return dbKey
 }
 
-// LevelDB is an interface missing in original LevelDB design.
-// It contains methods common for the LevelDB instance and its Transactions.
-// It allows for easy switching between transactional and non-transactional accesses.
-type LevelDB interface {
-
-	// Get gets the value for the given key. It returns ErrNotFound if the
-	// DB does not contain the key.
-	//
-	// The returned slice is its own copy, it is safe to modify the contents
-	// of the returned slice.
-	// It is safe to modify the contents of the argument after Get returns.
-	Get(key []byte, ro *opt.ReadOptions) (value []byte, err error)
-
-	// Has returns true if the DB does contain the given key.
-	//
-	// It is safe to modify the contents of the argument after Has returns.
-	Has(key []byte, ro *opt.ReadOptions) (bool, error)
-
-	// NewIterator returns an iterator for the latest snapshot of the
-	// underlying DB.
-	// The returned iterator is not safe for concurrent use, but it is safe to use
-	// multiple iterators concurrently, with each in a dedicated goroutine.
-	// It is also safe to use an iterator concurrently with modifying its
-	// underlying DB. The resultant key/value pairs are guaranteed to be
-	// consistent.
-	//
-	// Slice allows slicing the iterator to only contains keys in the given
-	// range. A nil Range.Start is treated as a key before all keys in the
-	// DB. And a nil Range.Limit is treated as a key after all keys in
-	// the DB.
-	//
-	// WARNING: Any slice returned by iterator (e.g. slice returned by calling
-	// Iterator.Key() or Iterator.Key() methods), its content should not be modified
-	// unless noted otherwise.
-	//
-	// The iterator must be released after use, by calling Release method.
-	//
-	// Also read Iterator documentation of the leveldb/iterator package.
-	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
-
-	// Put sets the value for the given key. It overwrites any previous value
-	// for that key; a DB is not a multi-map.
-	// Please note that the transaction is not compacted until committed, so if you
-	// write 10 same keys, then those 10 same keys are in the transaction.
-	//
-	// It is safe to modify the contents of the arguments after Put returns.
-	Put(key, value []byte, wo *opt.WriteOptions) error
-
-	// Delete deletes the value for the given key.
-	// Please note that the transaction is not compacted until committed, so if you
-	// write 10 same keys, then those 10 same keys are in the transaction.
-	//
-	// It is safe to modify the contents of the arguments after Delete returns.
-	Delete(key []byte, wo *opt.WriteOptions) error
-
-	// Write apply the given batch to the DB. The batch records will be applied
-	// sequentially. Write might be used concurrently, when used concurrently and
-	// batch is small enough, write will try to merge the batches. Set NoWriteMerge
-	// option to true to disable write merge.
-	//
-	// It is safe to modify the contents of the arguments after Write returns but
-	// not before. Write will not modify content of the batch.
-	Write(batch *leveldb.Batch, wo *opt.WriteOptions) error
-
-	// GetSnapshot returns a latest snapshot of the underlying DB. A snapshot
-	// is a frozen snapshot of a DB state at a particular point in time. The
-	// content of snapshot are guaranteed to be consistent.
-	//
-	// The snapshot must be released after use, by calling Release method.
-	GetSnapshot() (*leveldb.Snapshot, error)
-
-	common.MemoryFootprintProvider
-}
-
-// LevelDBReader is an interface missing in original LevelDB design.
-// It contains methods common for the LevelDB instance and its Snapshots.
-type LevelDBReader interface {
-	// Get gets the value for the given key. It returns ErrNotFound if the
-	// DB does not contain the key.
-	//
-	// The returned slice is its own copy, it is safe to modify the contents
-	// of the returned slice.
-	// It is safe to modify the contents of the argument after Get returns.
-	Get(key []byte, ro *opt.ReadOptions) (value []byte, err error)
-
-	// Has returns true if the DB does contain the given key.
-	//
-	// It is safe to modify the contents of the argument after Has returns.
-	Has(key []byte, ro *opt.ReadOptions) (bool, error)
-
-	// NewIterator returns an iterator for the latest snapshot of the
-	// underlying DB.
-	// The returned iterator is not safe for concurrent use, but it is safe to use
-	// multiple iterators concurrently, with each in a dedicated goroutine.
-	// It is also safe to use an iterator concurrently with modifying its
-	// underlying DB. The resultant key/value pairs are guaranteed to be
-	// consistent.
-	//
-	// Slice allows slicing the iterator to only contains keys in the given
-	// range. A nil Range.Start is treated as a key before all keys in the
-	// DB. And a nil Range.Limit is treated as a key after all keys in
-	// the DB.
-	//
-	// WARNING: Any slice returned by iterator (e.g. slice returned by calling
-	// Iterator.Key() or Iterator.Key() methods), its content should not be modified
-	// unless noted otherwise.
-	//
-	// The iterator must be released after use, by calling Release method.
-	//
-	// Also read Iterator documentation of the leveldb/iterator package.
-	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
-}
-
 // OpenLevelDb opens the LevelDB connection and provides it wrapped in memory-footprint-reporting object.
 func OpenLevelDb(path string, options *opt.Options) (wrapped *LevelDbMemoryFootprintWrapper, err error) {
 	ldb, err := leveldb.OpenFile(path, options)
@@ -203,18 +92,52 @@ func OpenLevelDb(path string, options *opt.Options) (wrapped *LevelDbMemoryFootp
 	}
 	mf := common.NewMemoryFootprint(0)
 	mf.AddChild("writeBuffer", common.NewMemoryFootprint(uintptr(options.GetWriteBuffer())))
-	return &LevelDbMemoryFootprintWrapper{ldb, mf}, nil
+	return &LevelDbMemoryFootprintWrapper{levelDbReader{ldb}, ldb, mf}, nil
 }
 
-// LevelDbMemoryFootprintWrapper is a LevelDB wrapper adding a memory footprint providing method.
+// LevelDbMemoryFootprintWrapper is a LevelDB wrapper implementing the KVStore
+// interface and adding a memory footprint providing method.
 type LevelDbMemoryFootprintWrapper struct {
-	*leveldb.DB
+	levelDbReader
+	db *leveldb.DB
 	mf *common.MemoryFootprint
 }
 
+func (wrapper *LevelDbMemoryFootprintWrapper) Put(key, value []byte) error {
+	return wrapper.db.Put(key, value, nil)
+}
+
+func (wrapper *LevelDbMemoryFootprintWrapper) Delete(key []byte) error {
+	return wrapper.db.Delete(key, nil)
+}
+
+func (wrapper *LevelDbMemoryFootprintWrapper) NewBatch() KVBatch {
+	return new(leveldb.Batch)
+}
+
+func (wrapper *LevelDbMemoryFootprintWrapper) Write(batch KVBatch) error {
+	ldbBatch, err := toLevelDbBatch(batch)
+	if err != nil {
+		return err
+	}
+	return wrapper.db.Write(ldbBatch, nil)
+}
+
+func (wrapper *LevelDbMemoryFootprintWrapper) GetSnapshot() (KVSnapshot, error) {
+	snap, err := wrapper.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return levelDbSnapshot{levelDbReader{snap}, snap}, nil
+}
+
+func (wrapper *LevelDbMemoryFootprintWrapper) Close() error {
+	return wrapper.db.Close()
+}
+
 func (wrapper *LevelDbMemoryFootprintWrapper) GetMemoryFootprint() *common.MemoryFootprint {
 	var ldbStats leveldb.DBStats
-	err := wrapper.DB.Stats(&ldbStats)
+	err := wrapper.db.Stats(&ldbStats)
 	if err != nil {
 		panic(fmt.Errorf("failed to get LevelDB Stats; %s", err))
 	}
@@ -223,23 +146,105 @@ func (wrapper *LevelDbMemoryFootprintWrapper) GetMemoryFootprint() *common.Memor
 }
 
 func (wrapper *LevelDbMemoryFootprintWrapper) OpenTransaction() (*LevelDbTransactionMemoryFootprintWrapper, error) {
-	tx, err := wrapper.DB.OpenTransaction()
+	tx, err := wrapper.db.OpenTransaction()
 	if err != nil {
 		return nil, err
 	}
-	return &LevelDbTransactionMemoryFootprintWrapper{tx, wrapper.mf}, nil
+	return &LevelDbTransactionMemoryFootprintWrapper{levelDbReader{tx}, tx, wrapper.mf}, nil
 }
 
-// LevelDbTransactionMemoryFootprintWrapper is a LevelDB transaction wrapper adding a memory footprint method.
+// LevelDbTransactionMemoryFootprintWrapper is a LevelDB transaction wrapper
+// implementing the KVStore interface and adding a memory footprint method.
 type LevelDbTransactionMemoryFootprintWrapper struct {
-	*leveldb.Transaction
+	levelDbReader
+	tx *leveldb.Transaction
 	mf *common.MemoryFootprint
 }
 
-func (wrapper *LevelDbTransactionMemoryFootprintWrapper) GetSnapshot() (*leveldb.Snapshot, error) {
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) Put(key, value []byte) error {
+	return wrapper.tx.Put(key, value, nil)
+}
+
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) Delete(key []byte) error {
+	return wrapper.tx.Delete(key, nil)
+}
+
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) NewBatch() KVBatch {
+	return new(leveldb.Batch)
+}
+
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) Write(batch KVBatch) error {
+	ldbBatch, err := toLevelDbBatch(batch)
+	if err != nil {
+		return err
+	}
+	return wrapper.tx.Write(ldbBatch, nil)
+}
+
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) GetSnapshot() (KVSnapshot, error) {
 	return nil, fmt.Errorf("unable to get snapshot from a transaction")
 }
 
+// Commit commits the transaction. The transaction must not be used afterwards.
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) Commit() error {
+	return wrapper.tx.Commit()
+}
+
+// Discard discards the transaction. The transaction must not be used afterwards.
+func (wrapper *LevelDbTransactionMemoryFootprintWrapper) Discard() {
+	wrapper.tx.Discard()
+}
+
 func (wrapper *LevelDbTransactionMemoryFootprintWrapper) GetMemoryFootprint() *common.MemoryFootprint {
 	return wrapper.mf
 }
+
+// levelDbSource contains methods common for the LevelDB instance, its
+// transactions, and its snapshots.
+type levelDbSource interface {
+	Get(key []byte, ro *opt.ReadOptions) (value []byte, err error)
+	Has(key []byte, ro *opt.ReadOptions) (bool, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+// levelDbReader implements the KVReader interface on top of a LevelDB source.
+type levelDbReader struct {
+	source levelDbSource
+}
+
+func (r levelDbReader) Get(key []byte) ([]byte, error) {
+	value, err := r.source.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (r levelDbReader) Has(key []byte) (bool, error) {
+	return r.source.Has(key, nil)
+}
+
+func (r levelDbReader) NewIterator(keys *KVRange) KVIterator {
+	if keys == nil {
+		return r.source.NewIterator(nil, nil)
+	}
+	return r.source.NewIterator(&util.Range{Start: keys.Start, Limit: keys.Limit}, nil)
+}
+
+// levelDbSnapshot implements the KVSnapshot interface for LevelDB snapshots.
+type levelDbSnapshot struct {
+	levelDbReader
+	snap *leveldb.Snapshot
+}
+
+func (s levelDbSnapshot) Release() {
+	s.snap.Release()
+}
+
+func toLevelDbBatch(batch KVBatch) (*leveldb.Batch, error) {
+	res, ok := batch.(*leveldb.Batch)
+	if !ok {
+		return nil, fmt.Errorf("unsupported batch type %T, batches must be created by the LevelDB store", batch)
+	}
+	return res, nil
+}
@@ -13,21 +13,19 @@ package ldb
 import (
 	"github.com/Fantom-foundation/Carmen/go/backend"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"unsafe"
 )
 
-// MultiMap is a LevelDB multimap.MultiMap implementation - it maps IDs to values
+// MultiMap is a key-value store (LevelDB or Pebble) backed multimap.MultiMap implementation - it maps IDs to values
 type MultiMap[K any, V any] struct {
-	db              backend.LevelDB
+	db              backend.KVStore
 	table           backend.TableSpace
 	keySerializer   common.Serializer[K]
 	valueSerializer common.Serializer[V]
 }
 
 func NewMultiMap[K any, V any](
-	db backend.LevelDB,
+	db backend.KVStore,
 	table backend.TableSpace,
 	keySerializer common.Serializer[K],
 	valueSerializer common.Serializer[V],
@@ -45,7 +43,7 @@ func (m *MultiMap[K, V]) Add(key K, value V) error {
 	var dbKey DbKey[K, V]
 	dbKey.SetTableKey(m.table, key, m.keySerializer)
 	dbKey.SetValue(value, m.valueSerializer)
-	return m.db.Put(dbKey[:], nil, nil)
+	return m.db.Put(dbKey[:], nil)
 }
 
 // Remove removes a single key/value entry.
@@ -53,23 +51,23 @@ func (m *MultiMap[K, V]) Remove(key K, value V) error {
 	var dbKey DbKey[K, V]
 	dbKey.SetTableKey(m.table, key, m.keySerializer)
 	dbKey.SetValue(value, m.valueSerializer)
-	return m.db.Delete(dbKey[:], nil)
+	return m.db.Delete(dbKey[:])
 }
 
-func (m *MultiMap[K, V]) getRangeForKey(key K) util.Range {
+func (m *MultiMap[K, V]) getRangeForKey(key K) *backend.KVRange {
 	var startDbKey, endDbKey DbKey[K, V]
 	startDbKey.SetTableKey(m.table, key, m.keySerializer)
 	endDbKey.CopyFrom(&startDbKey)
 	endDbKey.SetMaxValue()
 
-	return util.Range{Start: startDbKey[:], Limit: endDbKey[:]}
+	return &backend.KVRange{Start: startDbKey[:], Limit: endDbKey[:]}
 }
 
 // RemoveAll removes all entries with the given key.
 func (m *MultiMap[K, V]) RemoveAll(key K) error {
 	keysRange := m.getRangeForKey(key)
-	batch := new(leveldb.Batch)
-	iter := m.db.NewIterator(&keysRange, nil)
+	batch := m.db.NewBatch()
+	iter := m.db.NewIterator(keysRange)
 	defer iter.Release()
 
 	for iter.Next() {
@@ -79,13 +77,13 @@ func (m *MultiMap[K, V]) RemoveAll(key K) error {
 		return err
 	}
 
-	return m.db.Write(batch, nil)
+	return m.db.Write(batch)
 }
 
 // GetAll provides all values associated with the given key.
 func (m *MultiMap[K, V]) GetAll(key K) ([]V, error) {
 	keysRange := m.getRangeForKey(key)
-	iter := m.db.NewIterator(&keysRange, nil)
+	iter := m.db.NewIterator(keysRange)
 	defer iter.Release()
 
 	values := make([]V, 0, 64)
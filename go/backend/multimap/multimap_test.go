@@ -49,6 +49,17 @@ func getMultiMapFactories(tb testing.TB) (stores []multimapFactory) {
 				return &multiMapClosingWrapper{mm, []func() error{db.Close}}
 			},
 		},
+		{
+			label: "Pebble",
+			getMultiMap: func(tempDir string) MultiMap[uint32, uint64] {
+				db, err := backend.OpenPebbleDb(tempDir, nil)
+				if err != nil {
+					tb.Fatalf("failed to init pebble store; %s", err)
+				}
+				mm := ldb.NewMultiMap[uint32, uint64](db, backend.AddressSlotMultiMapKey, common.Identifier32Serializer{}, common.Identifier64Serializer{})
+				return &multiMapClosingWrapper{mm, []func() error{db.Close}}
+			},
+		},
 		{
 			label: "BTree",
 			getMultiMap: func(tempDir string) MultiMap[uint32, uint64] {
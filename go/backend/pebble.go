@@ -0,0 +1,267 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/cockroachdb/pebble"
+)
+
+// OpenPebbleDb opens a Pebble database in the given directory and provides it
+// as a KVStore. If no options are given, Pebble's defaults are used. Unless a
+// logger is configured, informational messages of Pebble are dropped.
+func OpenPebbleDb(path string, options *pebble.Options) (*PebbleDb, error) {
+	if options == nil {
+		options = &pebble.Options{}
+	}
+	if options.Logger == nil && options.LoggerAndTracer == nil {
+		patched := *options
+		patched.Logger = pebbleLogger{}
+		options = &patched
+	}
+	db, err := pebble.Open(path, options)
+	if err != nil {
+		return nil, err
+	}
+	closed := new(atomic.Bool)
+	return &PebbleDb{pebbleReader{db, closed}, db, closed}, nil
+}
+
+// PebbleDb is a KVStore implementation backed by a Pebble database.
+type PebbleDb struct {
+	pebbleReader
+	db *pebble.DB
+	// Pebble panics on accesses to closed databases, which are thus
+	// intercepted and reported as errors like for LevelDB.
+	closed *atomic.Bool
+}
+
+func (p *PebbleDb) Put(key, value []byte) error {
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	return p.db.Set(key, value, pebble.NoSync)
+}
+
+func (p *PebbleDb) Delete(key []byte) error {
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	return p.db.Delete(key, pebble.NoSync)
+}
+
+func (p *PebbleDb) NewBatch() KVBatch {
+	return &pebbleBatch{p.db.NewBatch()}
+}
+
+func (p *PebbleDb) Write(batch KVBatch) error {
+	b, ok := batch.(*pebbleBatch)
+	if !ok {
+		return fmt.Errorf("unsupported batch type %T, batches must be created by the Pebble store", batch)
+	}
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	return p.db.Apply(b.batch, pebble.NoSync)
+}
+
+func (p *PebbleDb) GetSnapshot() (KVSnapshot, error) {
+	if p.closed.Load() {
+		return nil, pebble.ErrClosed
+	}
+	snap := p.db.NewSnapshot()
+	return pebbleSnapshot{pebbleReader{snap, p.closed}, snap}, nil
+}
+
+// Flush flushes the content of the memtable to disk.
+func (p *PebbleDb) Flush() error {
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	return p.db.Flush()
+}
+
+func (p *PebbleDb) Close() error {
+	if p.closed.Swap(true) {
+		return pebble.ErrClosed
+	}
+	return p.db.Close()
+}
+
+func (p *PebbleDb) GetMemoryFootprint() *common.MemoryFootprint {
+	metrics := p.db.Metrics()
+	mf := common.NewMemoryFootprint(unsafe.Sizeof(*p))
+	mf.AddChild("memTable", common.NewMemoryFootprint(uintptr(metrics.MemTable.Size)))
+	mf.AddChild("blockCache", common.NewMemoryFootprint(uintptr(metrics.BlockCache.Size)))
+	return mf
+}
+
+// pebbleSource contains methods common for the Pebble instance and its snapshots.
+type pebbleSource interface {
+	Get(key []byte) ([]byte, io.Closer, error)
+	NewIter(o *pebble.IterOptions) (*pebble.Iterator, error)
+}
+
+// pebbleReader implements the KVReader interface on top of a Pebble source.
+type pebbleReader struct {
+	source pebbleSource
+	closed *atomic.Bool // < set once the underlying database is closed
+}
+
+func (r pebbleReader) Get(key []byte) ([]byte, error) {
+	if r.closed.Load() {
+		return nil, pebble.ErrClosed
+	}
+	value, closer, err := r.source.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The value is only valid until the closer is closed.
+	res := make([]byte, len(value))
+	copy(res, value)
+	return res, closer.Close()
+}
+
+func (r pebbleReader) Has(key []byte) (bool, error) {
+	if r.closed.Load() {
+		return false, pebble.ErrClosed
+	}
+	_, closer, err := r.source.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+func (r pebbleReader) NewIterator(keys *KVRange) KVIterator {
+	if r.closed.Load() {
+		return errorIterator{pebble.ErrClosed}
+	}
+	options := &pebble.IterOptions{}
+	if keys != nil {
+		options.LowerBound = keys.Start
+		options.UpperBound = keys.Limit
+	}
+	iter, err := r.source.NewIter(options)
+	if err != nil {
+		return errorIterator{err}
+	}
+	return &pebbleIterator{iter: iter}
+}
+
+// pebbleSnapshot implements the KVSnapshot interface for Pebble snapshots.
+type pebbleSnapshot struct {
+	pebbleReader
+	snap *pebble.Snapshot
+}
+
+func (s pebbleSnapshot) Release() {
+	if s.closed.Load() {
+		return
+	}
+	_ = s.snap.Close()
+}
+
+// pebbleBatch implements the KVBatch interface for Pebble batches.
+type pebbleBatch struct {
+	batch *pebble.Batch
+}
+
+func (b *pebbleBatch) Put(key, value []byte) {
+	// Adding operations only fails for indexed batches, which are not used.
+	_ = b.batch.Set(key, value, nil)
+}
+
+func (b *pebbleBatch) Delete(key []byte) {
+	_ = b.batch.Delete(key, nil)
+}
+
+func (b *pebbleBatch) Reset() {
+	b.batch.Reset()
+}
+
+// pebbleIterator adapts Pebble iterators, which need to be positioned
+// explicitly, to the KVIterator interface.
+type pebbleIterator struct {
+	iter     *pebble.Iterator
+	started  bool
+	released bool
+	err      error
+}
+
+func (i *pebbleIterator) Next() bool {
+	if i.released {
+		return false
+	}
+	if !i.started {
+		i.started = true
+		return i.iter.First()
+	}
+	return i.iter.Next()
+}
+
+func (i *pebbleIterator) Last() bool {
+	if i.released {
+		return false
+	}
+	i.started = true
+	return i.iter.Last()
+}
+
+func (i *pebbleIterator) Key() []byte {
+	if i.released || !i.iter.Valid() {
+		return nil
+	}
+	return i.iter.Key()
+}
+
+func (i *pebbleIterator) Value() []byte {
+	if i.released || !i.iter.Valid() {
+		return nil
+	}
+	return i.iter.Value()
+}
+
+func (i *pebbleIterator) Error() error {
+	if i.released {
+		return i.err
+	}
+	return i.iter.Error()
+}
+
+func (i *pebbleIterator) Release() {
+	if !i.released {
+		i.released = true
+		i.err = i.iter.Close()
+	}
+}
+
+// pebbleLogger drops informational messages of Pebble, in line with LevelDB
+// not logging to the standard output.
+type pebbleLogger struct{}
+
+func (pebbleLogger) Infof(format string, args ...interface{}) {}
+
+func (pebbleLogger) Fatalf(format string, args ...interface{}) {
+	pebble.DefaultLogger.Fatalf(format, args...)
+}
@@ -11,9 +11,11 @@
 package file
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"os"
 	"sync"
 	"unsafe"
@@ -30,6 +32,7 @@ type fileStock[I stock.Index, V any] struct {
 	freelist        *fileBasedStack[I]
 	numValueSlots   I
 	numValuesInFile int64
+	checksums       bool // < true if each value is followed by a checksum in the value file
 	bufferPool      sync.Pool
 }
 
@@ -37,28 +40,46 @@ type fileStock[I stock.Index, V any] struct {
 // data is loaded and verified. A non-existing directory will be implicitly
 // created and an empty directory is a valid target to be initialized as an
 // empty stock.
+//
+// New stocks are created without checksums. Existing stocks are opened using
+// the value layout recorded in their metadata, including stocks created by
+// OpenChecksummedStock.
 func OpenStock[I stock.Index, V any](encoder stock.ValueEncoder[V], directory string) (stock.Stock[I, V], error) {
 	return openStock[I, V](encoder, directory)
 }
 
+// OpenChecksummedStock opens a stock like OpenStock, yet new stocks are
+// created with a checksum stored alongside each value. Checksums are verified
+// whenever a value is loaded, failing with a ChecksumMismatchError if the
+// value got corrupted on disk. Existing stocks retain their value layout.
+func OpenChecksummedStock[I stock.Index, V any](encoder stock.ValueEncoder[V], directory string) (stock.Stock[I, V], error) {
+	return openVerifyStock[I, V](encoder, directory, true, verifyStockInternal[I, V])
+}
+
 func openStock[I stock.Index, V any](encoder stock.ValueEncoder[V], directory string) (*fileStock[I, V], error) {
-	return openVerifyStock[I, V](encoder, directory, verifyStockInternal[I, V])
+	return openVerifyStock[I, V](encoder, directory, false, verifyStockInternal[I, V])
 }
 
 // openVerifyStock opens the stock the same as its public counterpart. This method allows for injecting a custom method to verify the stock.
-func openVerifyStock[I stock.Index, V any](encoder stock.ValueEncoder[V], directory string, verify func(encoder stock.ValueEncoder[V], directory string) (metadata, error)) (*fileStock[I, V], error) {
+func openVerifyStock[I stock.Index, V any](encoder stock.ValueEncoder[V], directory string, checksums bool, verify func(encoder stock.ValueEncoder[V], directory string) (metadata, error)) (*fileStock[I, V], error) {
 	// Create the directory if needed.
 	if err := os.MkdirAll(directory, 0700); err != nil {
 		return nil, err
 	}
 
 	// Verify the content of the stock and get its metadata.
+	metafile, valuefile, freelistfile := getFileNames(directory)
+	isNew := !exists(metafile)
 	meta, err := verify(encoder, directory)
 	if err != nil {
 		return nil, err
 	}
 
-	_, valuefile, freelistfile := getFileNames(directory)
+	// Existing stocks keep the layout they have been created with.
+	if !isNew {
+		checksums = meta.Checksums
+	}
+
 	values, err := utils.OpenBufferedFile(valuefile)
 	if err != nil {
 		return nil, err
@@ -70,7 +91,7 @@ func openVerifyStock[I stock.Index, V any](encoder stock.ValueEncoder[V], direct
 	}
 
 	// Create new files
-	valueSize := encoder.GetEncodedSize()
+	recordSize := getRecordSize(encoder.GetEncodedSize(), checksums)
 	return &fileStock[I, V]{
 		encoder:         encoder,
 		directory:       directory,
@@ -78,14 +99,34 @@ func openVerifyStock[I stock.Index, V any](encoder stock.ValueEncoder[V], direct
 		freelist:        freelist,
 		numValueSlots:   I(meta.ValueListLength),
 		numValuesInFile: meta.NumValuesInFile,
+		checksums:       checksums,
 		bufferPool: sync.Pool{New: func() any {
 			return &buffer[V]{
-				raw: make([]byte, valueSize),
+				raw: make([]byte, recordSize),
 			}
 		}},
 	}, nil
 }
 
+// ChecksumMismatchError is the error reported when loading a value from a
+// checksummed stock whose content does not match its checksum.
+const ChecksumMismatchError = common.ConstError("checksum mismatch")
+
+// checksumSize is the number of bytes occupied by the checksum following
+// each value in the value file of a checksummed stock.
+const checksumSize = 4
+
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// getRecordSize computes the number of bytes occupied by a single value in
+// the value file.
+func getRecordSize(valueSize int, checksums bool) int {
+	if checksums {
+		return valueSize + checksumSize
+	}
+	return valueSize
+}
+
 // buffer combines a raw data and value buffer required in pairs for Get and Set
 // operations. Instances are cached in sync.Pools to avoid allocations for every
 // single use.
@@ -100,6 +141,9 @@ type buffer[V any] struct {
 //   - checking the correct metadata for a stock using the given index and encoder
 //   - checking the value range of elements in the free-list
 //
+// Checksums of individual values are not covered, since they are verified
+// whenever values are loaded.
+//
 // For compatibility with the OpenStock function above, an empty directory is considered a
 // valid stock as well.
 func VerifyStock[I stock.Index, V any](directory string, encoder stock.ValueEncoder[V]) error {
@@ -160,7 +204,7 @@ func verifyStockFilesInternal[I stock.Index, V any](encoder stock.ValueEncoder[V
 		if err != nil {
 			return meta, err
 		}
-		expectedSize := meta.NumValuesInFile * int64(valueSize)
+		expectedSize := meta.NumValuesInFile * int64(getRecordSize(valueSize, meta.Checksums))
 		if got, want := stats.Size(), expectedSize; got < want {
 			return meta, fmt.Errorf("insufficient value file size, got %d, wanted %d", got, want)
 		}
@@ -245,22 +289,50 @@ func (s *fileStock[I, V]) Get(index I) (V, error) {
 	if index >= I(s.numValuesInFile) {
 		return res, nil
 	}
-	// Load value from the file.
-	valueSize := s.encoder.GetEncodedSize()
-	offset := int64(valueSize) * int64(index)
 	buffer := s.bufferPool.Get().(*buffer[V])
 	defer s.bufferPool.Put(buffer)
+
+	// Load value from the file.
+	valueSize := s.encoder.GetEncodedSize()
+	offset := int64(len(buffer.raw)) * int64(index)
 	_, err := s.values.ReadAt(buffer.raw, offset)
 	if err != nil {
 		return res, err
 	}
 
-	if err := s.encoder.Load(buffer.raw, &buffer.value); err != nil {
+	// All records within the file carry a checksum, including those of
+	// values never set, which are written as checksummed zero records.
+	if s.checksums {
+		want := binary.LittleEndian.Uint32(buffer.raw[valueSize:])
+		if got := crc32.Checksum(buffer.raw[:valueSize], checksumTable); got != want {
+			return res, fmt.Errorf("%w for value %d in %s, want %08x, got %08x", ChecksumMismatchError, index, s.directory, want, got)
+		}
+	}
+
+	if err := s.encoder.Load(buffer.raw[:valueSize], &buffer.value); err != nil {
 		return res, err
 	}
 	return buffer.value, nil
 }
 
+// writeZeroRecords fills the records in the range [from, to) with zero
+// values and their checksums, such that no record within the value file lacks
+// a checksum.
+func (s *fileStock[I, V]) writeZeroRecords(from, to int64) error {
+	if from >= to {
+		return nil
+	}
+	valueSize := s.encoder.GetEncodedSize()
+	record := make([]byte, getRecordSize(valueSize, true))
+	binary.LittleEndian.PutUint32(record[valueSize:], crc32.Checksum(record[:valueSize], checksumTable))
+	for i := from; i < to; i++ {
+		if _, err := s.values.WriteAt(record, int64(len(record))*i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *fileStock[I, V]) Set(index I, value V) error {
 	if index >= s.numValueSlots || index < 0 {
 		return fmt.Errorf("index out of range, got %d, range [0,%d)", index, s.numValueSlots)
@@ -271,18 +343,25 @@ func (s *fileStock[I, V]) Set(index I, value V) error {
 	buffer := s.bufferPool.Get().(*buffer[V])
 	defer s.bufferPool.Put(buffer)
 	buffer.value = value
-	if err := s.encoder.Store(buffer.raw, &buffer.value); err != nil {
+	if err := s.encoder.Store(buffer.raw[:valueSize], &buffer.value); err != nil {
 		return err
 	}
 
 	// If the new data is beyond the end of the current file and empty, we can skip
 	// the write operation.
-	if index >= I(s.numValuesInFile) && allZero(buffer.raw) {
+	if index >= I(s.numValuesInFile) && allZero(buffer.raw[:valueSize]) {
 		return nil
 	}
 
+	if s.checksums {
+		binary.LittleEndian.PutUint32(buffer.raw[valueSize:], crc32.Checksum(buffer.raw[:valueSize], checksumTable))
+		if err := s.writeZeroRecords(s.numValuesInFile, int64(index)); err != nil {
+			return err
+		}
+	}
+
 	// Write a serialized form of the value to disk.
-	offset := int64(valueSize) * int64(index)
+	offset := int64(len(buffer.raw)) * int64(index)
 	if _, err := s.values.WriteAt(buffer.raw, offset); err != nil {
 		return err
 	}
@@ -317,6 +396,26 @@ func (s *fileStock[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	return res
 }
 
+func (s *fileStock[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	metafile, valuefile, freelistfile := getFileNames(s.directory)
+	res := common.NewDiskFootprint(0)
+	for name, file := range map[string]string{"meta": metafile, "values": valuefile, "freelist": freelistfile} {
+		footprint, err := common.GetFileDiskFootprint(file)
+		if err != nil {
+			return nil, err
+		}
+		res.AddChild(name, footprint)
+	}
+	// Deleted values beyond the end of the value file occupy no disk space.
+	values := res.GetChild("values")
+	reclaimable := uint64(s.freelist.Size()) * uint64(getRecordSize(s.encoder.GetEncodedSize(), s.checksums))
+	if reclaimable > values.Value() {
+		reclaimable = values.Value()
+	}
+	values.SetReclaimable(reclaimable)
+	return res, nil
+}
+
 func (s *fileStock[I, V]) Flush() error {
 	// Write metadata.
 	var index I
@@ -328,6 +427,7 @@ func (s *fileStock[I, V]) Flush() error {
 		ValueListLength: int(s.numValueSlots),
 		FreeListLength:  s.freelist.Size(),
 		NumValuesInFile: s.numValuesInFile,
+		Checksums:       s.checksums,
 	})
 	if err == nil {
 		if err := os.WriteFile(s.directory+"/meta.json", metadata, 0600); err != nil {
@@ -364,6 +464,7 @@ type metadata struct {
 	ValueListLength int
 	FreeListLength  int
 	NumValuesInFile int64
+	Checksums       bool `json:",omitempty"` // < true if values are followed by checksums
 }
 
 func allZero(data []byte) bool {
@@ -12,6 +12,7 @@ package file
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -33,6 +34,150 @@ func openFileStock(t *testing.T, directory string) (stock.Stock[int, int], error
 	return OpenStock[int, int](stock.IntEncoder{}, directory)
 }
 
+func TestChecksummedFileStock(t *testing.T) {
+	stock.RunStockTests(t, stock.NamedStockFactory{
+		ImplementationName: "checksummed-file",
+		Open: func(t *testing.T, directory string) (stock.Stock[int, int], error) {
+			return OpenChecksummedStock[int, int](stock.IntEncoder{}, directory)
+		},
+	})
+}
+
+func TestFile_ChecksummedStock_DetectsCorruptedValues(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenChecksummedStock[int, int](stock.IntEncoder{}, dir)
+	if err != nil {
+		t.Fatalf("failed to open stock: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		id, err := s.New()
+		if err != nil {
+			t.Fatalf("failed to create value: %v", err)
+		}
+		if err := s.Set(id, i+1); err != nil {
+			t.Fatalf("failed to set value: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("failed to close stock: %v", err)
+	}
+
+	// Flip a bit of the value stored for index 3.
+	_, valuefile, _ := getFileNames(dir)
+	data, err := os.ReadFile(valuefile)
+	if err != nil {
+		t.Fatalf("failed to read value file: %v", err)
+	}
+	data[3*getRecordSize(stock.IntEncoder{}.GetEncodedSize(), true)] ^= 1
+	if err := os.WriteFile(valuefile, data, 0600); err != nil {
+		t.Fatalf("failed to write value file: %v", err)
+	}
+
+	// The layout is retained when re-opening the stock using OpenStock.
+	if err := VerifyStock[int, int](dir, stock.IntEncoder{}); err != nil {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+	s, err = OpenStock[int, int](stock.IntEncoder{}, dir)
+	if err != nil {
+		t.Fatalf("failed to open stock: %v", err)
+	}
+	defer s.Close()
+	for i := 0; i < 10; i++ {
+		value, err := s.Get(i)
+		if i == 3 {
+			if !errors.Is(err, ChecksumMismatchError) {
+				t.Errorf("corrupted value should not be loaded, got %v, %v", value, err)
+			}
+			continue
+		}
+		if err != nil || value != i+1 {
+			t.Errorf("unexpected value for index %d, wanted %d, got %d, %v", i, i+1, value, err)
+		}
+	}
+}
+
+func TestFile_ChecksummedStock_DetectsZeroedAndCorruptedUnsetValues(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenChecksummedStock[int, int](stock.IntEncoder{}, dir)
+	if err != nil {
+		t.Fatalf("failed to open stock: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := s.New(); err != nil {
+			t.Fatalf("failed to create value: %v", err)
+		}
+	}
+	// Only values 2 and 7 are set, leaving the others unset within the file.
+	for _, i := range []int{2, 7} {
+		if err := s.Set(i, i+1); err != nil {
+			t.Fatalf("failed to set value: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("failed to close stock: %v", err)
+	}
+
+	// Zero out the record of value 2 and flip a bit of the unset value 4.
+	_, valuefile, _ := getFileNames(dir)
+	data, err := os.ReadFile(valuefile)
+	if err != nil {
+		t.Fatalf("failed to read value file: %v", err)
+	}
+	recordSize := getRecordSize(stock.IntEncoder{}.GetEncodedSize(), true)
+	for i := 2 * recordSize; i < 3*recordSize; i++ {
+		data[i] = 0
+	}
+	data[4*recordSize] ^= 1
+	if err := os.WriteFile(valuefile, data, 0600); err != nil {
+		t.Fatalf("failed to write value file: %v", err)
+	}
+
+	s, err = OpenStock[int, int](stock.IntEncoder{}, dir)
+	if err != nil {
+		t.Fatalf("failed to open stock: %v", err)
+	}
+	defer s.Close()
+	for i := 0; i < 10; i++ {
+		value, err := s.Get(i)
+		switch i {
+		case 2, 4:
+			if !errors.Is(err, ChecksumMismatchError) {
+				t.Errorf("corrupted value %d should not be loaded, got %v, %v", i, value, err)
+			}
+		case 7:
+			if err != nil || value != 8 {
+				t.Errorf("unexpected value for index %d, wanted %d, got %d, %v", i, 8, value, err)
+			}
+		default:
+			if err != nil || value != 0 {
+				t.Errorf("unexpected value for index %d, wanted 0, got %d, %v", i, value, err)
+			}
+		}
+	}
+}
+
+func TestFile_ExistingStocksRetainTheirLayout(t *testing.T) {
+	for _, checksums := range []bool{false, true} {
+		t.Run(fmt.Sprintf("checksums=%t", checksums), func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := openVerifyStock[int, int](stock.IntEncoder{}, dir, checksums, verifyStockInternal[int, int])
+			if err != nil {
+				t.Fatalf("failed to open stock: %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("failed to close stock: %v", err)
+			}
+			s, err = openVerifyStock[int, int](stock.IntEncoder{}, dir, !checksums, verifyStockInternal[int, int])
+			if err != nil {
+				t.Fatalf("failed to re-open stock: %v", err)
+			}
+			defer s.Close()
+			if want, got := checksums, s.checksums; want != got {
+				t.Errorf("unexpected layout of re-opened stock, wanted checksums %t, got %t", want, got)
+			}
+		})
+	}
+}
 func openInitFileStock(directory string, items int) (*fileStock[int, int], error) {
 	s, err := openStock[int, int](stock.IntEncoder{}, directory)
 	if err != nil {
@@ -146,7 +291,7 @@ func testOpenCorruptedFiles(t *testing.T, filename string) {
 	emptyVerifier := func(encoder stock.ValueEncoder[int], directory string) (meta metadata, err error) {
 		return meta, nil
 	}
-	if _, err := openVerifyStock[int, int](stock.IntEncoder{}, directory, emptyVerifier); err == nil {
+	if _, err := openVerifyStock[int, int](stock.IntEncoder{}, directory, false, emptyVerifier); err == nil {
 		t.Errorf("opening stock should fail")
 	}
 }
@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"unsafe"
 
 	"github.com/Fantom-foundation/Carmen/go/backend/stock"
@@ -187,6 +188,27 @@ func (s *inMemoryStock[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	return res
 }
 
+// GetDiskFootprint reports the disk space occupied by the stock's content
+// written by the last flush.
+func (s *inMemoryStock[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	res := common.NewDiskFootprint(0)
+	for name, file := range map[string]string{"meta": "meta.json", "values": "values.dat", "freelist": "freelist.dat"} {
+		footprint, err := common.GetFileDiskFootprint(filepath.Join(s.directory, file))
+		if err != nil {
+			return nil, err
+		}
+		res.AddChild(name, footprint)
+	}
+	// Deleted values are only reclaimable if they have been written to disk.
+	values := res.GetChild("values")
+	reclaimable := uint64(len(s.freeList)) * uint64(s.encoder.GetEncodedSize())
+	if reclaimable > values.Value() {
+		reclaimable = values.Value()
+	}
+	values.SetReclaimable(reclaimable)
+	return res, nil
+}
+
 func (s *inMemoryStock[I, V]) Flush() error {
 	// Write metadata.
 	var index I
@@ -83,6 +83,21 @@ func (s *shadowStock[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	return res
 }
 
+func (s *shadowStock[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	primary, err := s.primary.GetDiskFootprint()
+	if err != nil {
+		return nil, err
+	}
+	secondary, err := s.secondary.GetDiskFootprint()
+	if err != nil {
+		return nil, err
+	}
+	res := common.NewDiskFootprint(0)
+	res.AddChild("primary", primary)
+	res.AddChild("secondary", secondary)
+	return res, nil
+}
+
 func (s *shadowStock[I, V]) Flush() error {
 	return errors.Join(
 		s.primary.Flush(),
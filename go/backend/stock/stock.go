@@ -64,6 +64,10 @@ type Stock[I Index, V any] interface {
 	// Stocks must provide information on their memory footprint.
 	common.MemoryFootprintProvider
 
+	// Stocks must provide information on their disk footprint, including the
+	// space occupied by deleted values which may be reused.
+	common.DiskFootprintProvider
+
 	// Also, stocks need to be flush and closable.
 	common.FlushAndCloser
 }
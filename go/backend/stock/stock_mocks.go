@@ -106,6 +106,21 @@ func (mr *MockStockMockRecorder[I, V]) Get(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStock[I, V])(nil).Get), arg0)
 }
 
+// GetDiskFootprint mocks base method.
+func (m *MockStock[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDiskFootprint")
+	ret0, _ := ret[0].(*common.DiskFootprint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDiskFootprint indicates an expected call of GetDiskFootprint.
+func (mr *MockStockMockRecorder[I, V]) GetDiskFootprint() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskFootprint", reflect.TypeOf((*MockStock[I, V])(nil).GetDiskFootprint))
+}
+
 // GetIds mocks base method.
 func (m *MockStock[I, V]) GetIds() (IndexSet[I], error) {
 	m.ctrl.T.Helper()
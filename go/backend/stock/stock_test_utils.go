@@ -54,6 +54,7 @@ func RunStockTests(t *testing.T, factory NamedStockFactory) {
 	t.Run("ReusedElementsAreCleared", wrap(testReusedElementsAreCleared))
 	t.Run("LargeNumberOfElements", wrap(testLargeNumberOfElements))
 	t.Run("ProvidesMemoryFootprint", wrap(testProvidesMemoryFootprint))
+	t.Run("ProvidesDiskFootprint", wrap(testProvidesDiskFootprint))
 	t.Run("CreatesMissingDirectories", wrap(testCreatesMissingDirectories))
 	t.Run("CanBeFlushed", wrap(testCanBeFlushed))
 	t.Run("CanBeClosed", wrap(testCanBeClosed))
@@ -220,6 +221,41 @@ func testProvidesMemoryFootprint(t *testing.T, factory NamedStockFactory) {
 	}
 }
 
+func testProvidesDiskFootprint(t *testing.T, factory NamedStockFactory) {
+	stock, err := factory.Open(t, t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create empty stock: %v", err)
+	}
+	defer stock.Close()
+	for i := 0; i < 10; i++ {
+		index, err := stock.New()
+		if err != nil {
+			t.Fatalf("failed to insert element into stock: %v", err)
+		}
+		if err := stock.Set(index, i+1); err != nil {
+			t.Fatalf("failed to update element in stock: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := stock.Delete(i); err != nil {
+			t.Fatalf("failed to delete element from stock: %v", err)
+		}
+	}
+	if err := stock.Flush(); err != nil {
+		t.Fatalf("failed to flush stock: %v", err)
+	}
+	footprint, err := stock.GetDiskFootprint()
+	if err != nil {
+		t.Fatalf("failed to get disk footprint: %v", err)
+	}
+	if footprint.Total() == 0 {
+		t.Errorf("implementation claims zero disk footprint")
+	}
+	if reclaimable := footprint.TotalReclaimable(); reclaimable == 0 || reclaimable > footprint.Total() {
+		t.Errorf("invalid reclaimable disk space, got %d of %d", reclaimable, footprint.Total())
+	}
+}
+
 func testCreatesMissingDirectories(t *testing.T, factory NamedStockFactory) {
 	directory := t.TempDir() + "/some/missing/directory"
 	stock, err := factory.Open(t, directory)
@@ -67,6 +67,12 @@ func (s *syncedStock[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	return s.nested.GetMemoryFootprint()
 }
 
+func (s *syncedStock[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nested.GetDiskFootprint()
+}
+
 func (s *syncedStock[I, V]) Flush() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -88,3 +88,8 @@ func (m *Store[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("cache", m.cache.GetMemoryFootprint(0))
 	return mf
 }
+
+// GetDiskFootprint provides the size of the store on disk in bytes
+func (m *Store[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return m.store.GetDiskFootprint()
+}
@@ -26,6 +26,7 @@ import (
 
 // Store is a filesystem-based store.Store implementation - it stores mapping of ID to value in binary files.
 type Store[I common.Identifier, V any] struct {
+	directory      string
 	file           *os.File
 	hashTree       hashtree.HashTree
 	serializer     common.Serializer[V]
@@ -51,6 +52,7 @@ func NewStore[I common.Identifier, V any](path string, serializer common.Seriali
 
 	itemSize := serializer.Size()
 	s := &Store[I, V]{
+		directory:      path,
 		file:           file,
 		serializer:     serializer,
 		pageSize:       pageSize,
@@ -254,3 +256,8 @@ func (m *Store[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	}
 	return mf
 }
+
+// GetDiskFootprint provides the size of the store on disk in bytes
+func (m *Store[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.GetDirectoryDiskFootprint(m.directory)
+}
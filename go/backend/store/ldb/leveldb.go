@@ -19,13 +19,11 @@ import (
 
 	"github.com/Fantom-foundation/Carmen/go/backend/hashtree"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Store is a database-based store.Store implementation. It stores items in a key-value database.
 type Store[I common.Identifier, V any] struct {
-	db              backend.LevelDB
+	db              backend.KVStore
 	hashTree        hashtree.HashTree
 	valueSerializer common.Serializer[V]
 	indexSerializer common.Serializer[I]
@@ -37,7 +35,7 @@ type Store[I common.Identifier, V any] struct {
 
 // NewStore constructs a new instance of the Store.
 func NewStore[I common.Identifier, V any](
-	db backend.LevelDB,
+	db backend.KVStore,
 	table backend.TableSpace,
 	serializer common.Serializer[V],
 	indexSerializer common.Serializer[I],
@@ -74,13 +72,12 @@ func (m *Store[I, V]) GetPage(page int) (pageData []byte, err error) {
 }
 
 // getPageFromLdbReader provides the hashing page from given LevelDB reader (snapshot or database)
-func (m *Store[I, V]) getPageFromLdbReader(page int, db backend.LevelDBReader) (pageData []byte, err error) {
+func (m *Store[I, V]) getPageFromLdbReader(page int, db backend.KVReader) (pageData []byte, err error) {
 	pageStartKey := page * m.pageSize
 	pageEndKey := pageStartKey + m.pageSize
 	startDbKey := m.convertKey(I(pageStartKey)).ToBytes()
 	endDbKey := m.convertKey(I(pageEndKey)).ToBytes()
-	r := util.Range{Start: startDbKey, Limit: endDbKey}
-	iter := db.NewIterator(&r, nil)
+	iter := db.NewIterator(&backend.KVRange{Start: startDbKey, Limit: endDbKey})
 	defer iter.Release()
 
 	// create the page first
@@ -97,7 +94,7 @@ func (m *Store[I, V]) getPageFromLdbReader(page int, db backend.LevelDBReader) (
 func (m *Store[I, V]) Set(id I, value V) (err error) {
 	// index is mapped in the database directly
 	dbKey := m.convertKey(id).ToBytes()
-	if err = m.db.Put(dbKey, m.valueSerializer.ToBytes(value), nil); err == nil {
+	if err = m.db.Put(dbKey, m.valueSerializer.ToBytes(value)); err == nil {
 		page, _ := m.itemPosition(id)
 		if page >= m.pagesCount {
 			if err := m.setPagesCount(page + 1); err != nil {
@@ -116,8 +113,8 @@ func (m *Store[I, V]) Get(id I) (v V, err error) {
 	// index is mapped in the database directly
 	var val []byte
 	dbKey := m.convertKey(id).ToBytes()
-	if val, err = m.db.Get(dbKey, nil); err != nil {
-		if err == leveldb.ErrNotFound {
+	if val, err = m.db.Get(dbKey); err != nil {
+		if err == backend.ErrNotFound {
 			return v, nil
 		}
 	} else {
@@ -133,8 +130,8 @@ func getPagesCountDbKey(table backend.TableSpace) []byte {
 
 // loadPagesCount loads the amount of pages in the depot into the pagesCount field
 func (m *Store[I, V]) loadPagesCount() error {
-	val, err := m.db.Get(getPagesCountDbKey(m.table), nil)
-	if err == leveldb.ErrNotFound {
+	val, err := m.db.Get(getPagesCountDbKey(m.table))
+	if err == backend.ErrNotFound {
 		m.pagesCount = 0
 		return nil
 	}
@@ -149,7 +146,7 @@ func (m *Store[I, V]) loadPagesCount() error {
 func (m *Store[I, V]) setPagesCount(count int) error {
 	m.pagesCount = count
 	val := binary.LittleEndian.AppendUint32(nil, uint32(count))
-	return m.db.Put(getPagesCountDbKey(m.table), val, nil)
+	return m.db.Put(getPagesCountDbKey(m.table), val)
 }
 
 // GetStateHash computes and returns a cryptographical hash of the stored data
@@ -259,3 +256,10 @@ func (m *Store[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("levelDb", m.db.GetMemoryFootprint())
 	return mf
 }
+
+// GetDiskFootprint provides the size of the store on disk in bytes, which is
+// always zero. The data is kept in a key-value store shared with other
+// components, so the disk usage of the store is reported once by its owner.
+func (m *Store[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
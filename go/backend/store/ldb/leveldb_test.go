@@ -267,7 +267,7 @@ func closeDb[I common.Identifier, K common.Value](db *backend.LevelDbMemoryFootp
 	_ = db.Close()
 }
 
-func createNewStore(t *testing.T, db backend.LevelDB) *Store[uint32, common.Value] {
+func createNewStore(t *testing.T, db backend.KVStore) *Store[uint32, common.Value] {
 	hashTree := htmemory.CreateHashTreeFactory(BranchingFactor)
 	s, err := NewStore[uint32, common.Value](db, backend.ValueStoreKey, common.ValueSerializer{}, common.Identifier32Serializer{}, hashTree, PageSize)
 
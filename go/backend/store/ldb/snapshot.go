@@ -11,15 +11,15 @@
 package ldb
 
 import (
+	"github.com/Fantom-foundation/Carmen/go/backend"
 	"github.com/Fantom-foundation/Carmen/go/backend/hashtree/htldb"
 	"github.com/Fantom-foundation/Carmen/go/common"
-	"github.com/syndtr/goleveldb/leveldb"
 	"unsafe"
 )
 
 // SnapshotSource is backend.StoreSnapshotSource implementation for leveldb store.
 type SnapshotSource[I common.Identifier, V any] struct {
-	snap  *leveldb.Snapshot
+	snap  backend.KVSnapshot
 	store *Store[I, V]
 }
 
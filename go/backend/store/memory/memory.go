@@ -203,3 +203,9 @@ func (m *Store[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	}
 	return mf
 }
+
+// GetDiskFootprint provides the size of the store on disk in bytes, which is
+// zero since all data is kept in memory.
+func (m *Store[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
@@ -24,6 +24,7 @@ import (
 
 // Store is a filesystem-based store.Store implementation - it stores mapping of ID to value in binary files.
 type Store[I common.Identifier, V any] struct {
+	directory    string
 	array        *pagedarray.Array[I, V]
 	serializer   common.Serializer[V]
 	hashTree     hashtree.HashTree
@@ -46,6 +47,7 @@ func NewStore[I common.Identifier, V any](path string, serializer common.Seriali
 	hashTree := hashtreeFactory.Create(arr)
 
 	m := &Store[I, V]{
+		directory:  path,
 		array:      arr,
 		serializer: serializer,
 		hashTree:   hashTree,
@@ -199,3 +201,8 @@ func (m *Store[I, V]) GetMemoryFootprint() *common.MemoryFootprint {
 	}
 	return mf
 }
+
+// GetDiskFootprint provides the size of the store on disk in bytes
+func (m *Store[I, V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.GetDirectoryDiskFootprint(m.directory)
+}
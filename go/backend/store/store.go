@@ -33,6 +33,9 @@ type Store[I common.Identifier, V any] interface {
 	// provides the size of the store in memory in bytes
 	common.MemoryFootprintProvider
 
+	// provides the size of the store on disk in bytes
+	common.DiskFootprintProvider
+
 	// Also, stores need to be flush and closable.
 	common.FlushAndCloser
 
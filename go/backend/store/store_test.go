@@ -108,6 +108,21 @@ func getStoresFactories[V any](tb testing.TB, serializer common.Serializer[V], b
 				return &storeClosingWrapper[V]{cached, []func() error{db.Close}}
 			},
 		},
+		{
+			label: "Pebble",
+			getStore: func(tempDir string) store.Store[uint32, V] {
+				db, err := backend.OpenPebbleDb(tempDir, nil)
+				if err != nil {
+					tb.Fatalf("failed to init pebble store; %s", err)
+				}
+				hashTreeFac := htldb.CreateHashTreeFactory(db, backend.ValueStoreKey, branchingFactor)
+				str, err := ldb.NewStore[uint32, V](db, backend.ValueStoreKey, serializer, common.Identifier32Serializer{}, hashTreeFac, pageSize)
+				if err != nil {
+					tb.Fatalf("failed to init pebble store; %s", err)
+				}
+				return &storeClosingWrapper[V]{str, []func() error{db.Close}}
+			},
+		},
 	}
 }
 
@@ -457,3 +472,29 @@ func TestStorePersistence(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreDiskFootprint(t *testing.T) {
+	serializer := common.KeySerializer{}
+	for _, factory := range getStoresFactories[common.Key](t, serializer, BranchingFactor, PageSize, PoolSize) {
+		t.Run(factory.label, func(t *testing.T) {
+			s := factory.getStore(t.TempDir())
+			defer s.Close()
+
+			if err := s.Set(1, common.Key{0x11, 0x22, 0x33}); err != nil {
+				t.Fatalf("failed to set into a store; %s", err)
+			}
+			if err := s.Flush(); err != nil {
+				t.Fatalf("failed to flush store; %s", err)
+			}
+
+			df, err := s.GetDiskFootprint()
+			if err != nil {
+				t.Fatalf("failed to get disk footprint; %s", err)
+			}
+			persisted := factory.label == "File" || factory.label == "PagedFile"
+			if got := df.Total() > 0; got != persisted {
+				t.Errorf("unexpected disk footprint %d", df.Total())
+			}
+		})
+	}
+}
@@ -11,10 +11,12 @@
 package carmen
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"sync"
 
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 	"github.com/Fantom-foundation/Carmen/go/state"
 )
 
@@ -32,8 +34,10 @@ func (c *commonContext) releaseTxsContext() {
 
 type headBlockContext struct {
 	commonContext
-	block int64
-	state state.StateDB
+	block    int64
+	state    state.StateDB
+	recorder *mpt.WitnessRecorder // < nil if no witness is recorded
+	record   []byte               // < nil if no block record was set
 }
 
 func (c *headBlockContext) BeginTransaction() (TransactionContext, error) {
@@ -59,16 +63,67 @@ func (c *headBlockContext) RunTransaction(run func(TransactionContext) error) er
 	return runTransaction(c, run)
 }
 
+func (c *headBlockContext) GetPendingStateHash() (Hash, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.transactionActive {
+		return Hash{}, errTransactionRunning
+	}
+
+	if c.db == nil {
+		return Hash{}, fmt.Errorf("cannot compute hash of invalid block context")
+	}
+
+	hash, err := c.state.GetPendingHash()
+	return Hash(hash), err
+}
+
+func (c *headBlockContext) SetBlockRecord(record []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.db == nil {
+		return fmt.Errorf("cannot set record of invalid block context")
+	}
+
+	source, ok := state.UnsafeUnwrapSyncedState(c.db.db).(headBlockSource)
+	if !ok {
+		return errHeadBlockNotSupported
+	}
+	if _, _, err := source.GetHeadBlock(); err != nil {
+		return err
+	}
+	c.record = bytes.Clone(record)
+	return nil
+}
+
 func (c *headBlockContext) Commit() error {
+	_, err := c.commit()
+	return err
+}
+
+func (c *headBlockContext) CommitWithWitness() (Witness, error) {
+	witness, err := c.commit()
+	if err != nil {
+		return nil, err
+	}
+	if witness == nil {
+		return nil, fmt.Errorf("no witness recorded for block %d", c.block)
+	}
+	return witness.ToBytes(), nil
+}
+
+func (c *headBlockContext) commit() (*mpt.Witness, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	if c.transactionActive {
-		return errTransactionRunning
+		return nil, errTransactionRunning
 	}
 
 	if c.db == nil {
-		return fmt.Errorf("cannot commit invalid block context")
+		return nil, fmt.Errorf("cannot commit invalid block context")
 	}
 
 	// Obtain exclusive (write) access to the head state.
@@ -81,20 +136,60 @@ func (c *headBlockContext) Commit() error {
 		}
 	}()
 
+	if err := c.setNextBlockRecord(); err != nil {
+		// The block can not be committed, thus the context is aborted to
+		// release the head state.
+		c.db.headStateCommitLock.Unlock()
+		headStateCommitLockReleased = true
+		return nil, errors.Join(err, c.abort())
+	}
 	c.state.EndBlock(uint64(c.block))
 	c.db.headStateCommitLock.Unlock()
 	headStateCommitLockReleased = true
 
-	c.db.moveBlockNumber(c.block)
+	// Subscribers are informed outside the head state lock to not block
+	// queries; the next block can only start once they accepted the update.
+	c.db.publishUpdates()
+
+	var witness *mpt.Witness
+	var err error
+	if c.recorder != nil {
+		witness, err = c.recorder.Finish()
+		c.recorder = nil
+	}
 
-	return c.end() // < invalidates this context
+	err = errors.Join(err, c.db.moveBlockNumber(c.block))
+
+	return witness, errors.Join(err, c.end()) // < invalidates this context
+}
+
+// setNextBlockRecord forwards the record set for this block, if any, to the
+// state to be stored along with the block.
+func (c *headBlockContext) setNextBlockRecord() error {
+	if c.record == nil {
+		return nil
+	}
+	source, ok := state.UnsafeUnwrapSyncedState(c.db.db).(headBlockSource)
+	if !ok {
+		return errHeadBlockNotSupported
+	}
+	return source.SetNextBlockRecord(c.record)
 }
 
 func (c *headBlockContext) Abort() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	return c.abort()
+}
+
+func (c *headBlockContext) abort() error {
 	c.state.ResetBlockContext()
-	return c.end()
+	var err error
+	if c.recorder != nil {
+		_, err = c.recorder.Finish()
+		c.recorder = nil
+	}
+	return errors.Join(err, c.end())
 }
 
 func (c *headBlockContext) end() error {
@@ -305,6 +305,37 @@ func TestDatabase_AbortedBlocksHaveNoEffect(t *testing.T) {
 
 }
 
+func TestBlockContext_CommitsFailingToSetTheBlockRecordReleaseTheHeadState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	stateDB := state.NewMockStateDB(ctrl)
+	stateDB.EXPECT().ResetBlockContext()
+	stateDB.EXPECT().Check().Return(nil)
+	db := &database{
+		db:             state.NewMockState(ctrl), // < does not support block records
+		headStateInUse: true,
+	}
+	context := &headBlockContext{
+		commonContext: commonContext{db: db},
+		state:         stateDB,
+		record:        []byte{1},
+	}
+
+	if err := context.Commit(); !errors.Is(err, errHeadBlockNotSupported) {
+		t.Errorf("unexpected error, wanted %v, got %v", errHeadBlockNotSupported, err)
+	}
+	if db.headStateInUse {
+		t.Errorf("head state was not released by the failed commit")
+	}
+	if !db.headStateCommitLock.TryLock() {
+		t.Errorf("commit lock was not released by the failed commit")
+	} else {
+		db.headStateCommitLock.Unlock()
+	}
+	if err := context.Commit(); err == nil {
+		t.Errorf("failed commit should invalidate the block context")
+	}
+}
+
 func TestBlockContext_PanickingCommitsReleaseQueryLock(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	state := state.NewMockStateDB(ctrl)
@@ -381,3 +412,87 @@ func initHistoricBlockContext(t *testing.T) HistoricBlockContext {
 		state: stateDB,
 	}
 }
+
+func TestHeadBlockContext_GetPendingStateHashReturnsHashOfCommittedBlock(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AddBlock(0, func(context HeadBlockContext) error {
+		return forkTestBlock(0)(context)
+	}); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	before := getHeadStateHash(t, db)
+
+	// Aborted blocks leave the head state untouched.
+	context, err := db.BeginBlock(1)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	if err := forkTestBlock(1)(context); err != nil {
+		t.Fatalf("failed to run block: %v", err)
+	}
+	pending, err := context.GetPendingStateHash()
+	if err != nil {
+		t.Fatalf("failed to get pending state hash: %v", err)
+	}
+	if pending == before {
+		t.Errorf("pending state hash should differ from head state hash")
+	}
+	if got := getHeadStateHash(t, db); got != before {
+		t.Errorf("head state was modified, wanted %x, got %x", before, got)
+	}
+	if err := context.Abort(); err != nil {
+		t.Fatalf("failed to abort block: %v", err)
+	}
+	if got := getHeadStateHash(t, db); got != before {
+		t.Errorf("head state was modified by aborted block, wanted %x, got %x", before, got)
+	}
+
+	// Committed blocks produce the pending state hash.
+	context, err = db.BeginBlock(1)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	if err := forkTestBlock(1)(context); err != nil {
+		t.Fatalf("failed to run block: %v", err)
+	}
+	if got, err := context.GetPendingStateHash(); err != nil || got != pending {
+		t.Errorf("unexpected pending state hash, wanted %x, got %x, err %v", pending, got, err)
+	}
+	if err := context.Commit(); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	if got := getHeadStateHash(t, db); got != pending {
+		t.Errorf("unexpected head state hash, wanted %x, got %x", pending, got)
+	}
+}
+
+func TestHeadBlockContext_GetPendingStateHashFailsWhileTransactionIsRunning(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	context, err := db.BeginBlock(1)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	tx, err := context.BeginTransaction()
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+	if _, err := context.GetPendingStateHash(); !errors.Is(err, errTransactionRunning) {
+		t.Errorf("unexpected error, wanted %v, got %v", errTransactionRunning, err)
+	}
+	if err := tx.Abort(); err != nil {
+		t.Fatalf("failed to abort transaction: %v", err)
+	}
+	if err := context.Abort(); err != nil {
+		t.Fatalf("failed to abort block: %v", err)
+	}
+}
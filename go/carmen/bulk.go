@@ -11,6 +11,7 @@
 package carmen
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/Fantom-foundation/Carmen/go/common"
@@ -59,7 +60,8 @@ func (l *bulkLoad) Finalize() error {
 	}
 
 	err := l.nested.Close()
-	l.db.moveBlockAndReleaseHead(l.block)
+	l.db.publishUpdates()
+	err = errors.Join(err, l.db.moveBlockAndReleaseHead(l.block))
 	l.db = nil
 	return err
 }
@@ -11,6 +11,9 @@
 package carmen
 
 import (
+	"context"
+	"time"
+
 	"github.com/Fantom-foundation/Carmen/go/common"
 	"github.com/Fantom-foundation/Carmen/go/state"
 )
@@ -37,6 +40,33 @@ func OpenDatabase(directory string, implementation Configuration, properties Pro
 	return openDatabase(directory, implementation, properties)
 }
 
+// OpenWitnessDatabase creates an in-memory database based on the given
+// witness. The head state of the resulting database is the state the witness
+// was recorded on, which needs to have the given state root hash. It can be
+// used to re-execute the witness' block without access to the full state.
+// Accessing state information not covered by the witness results in errors.
+// The resulting database has no archive.
+func OpenWitnessDatabase(witness Witness, root Hash) (Database, error) {
+	return openWitnessDatabase(witness, root)
+}
+
+// ImportState creates a new database in the given directory, which must be
+// empty or not exist yet, containing the state provided by the given
+// iterator at the given block. The entries may be provided in any order and
+// their number is not limited; the import is performed in a single pass
+// with bounded memory. If the configuration includes an archive, the
+// archive is initialized with the imported state at the given block and
+// empty states for all preceding blocks. The first block that may be added
+// to the resulting database is block+1.
+//
+// Only file-based schema 5 configurations without archive or with an S5
+// archive are supported. The function returns the hash of the imported
+// state. The content of the target directory is undefined if the import
+// fails.
+func ImportState(directory string, configuration Configuration, block uint64, entries StateIterator) (Hash, error) {
+	return importState(directory, configuration, block, entries)
+}
+
 // Database provides access to the blockchain state.
 // It can query historic state referring to existing blocks
 // and append new blocks with modified state at the head of the chain.
@@ -66,6 +96,14 @@ type Database interface {
 	// either committed or aborted.
 	BeginBlock(block uint64) (HeadBlockContext, error)
 
+	// BeginBlockWithWitness starts a new block context like BeginBlock,
+	// but additionally records a witness of all state information read
+	// while processing and committing the block. The witness is obtained
+	// by committing the block using CommitWithWitness. Witnesses are only
+	// supported by configurations using an MPT based LiveDB storing node
+	// hashes in parent nodes.
+	BeginBlockWithWitness(block uint64) (WitnessBlockContext, error)
+
 	// AddBlock appends a new block to the blockchain.
 	// The input callback function accesses a new block context,
 	// which allows for modification of the state via one or more transactions.
@@ -75,10 +113,31 @@ type Database interface {
 	// error is returned by the AddBlock call. In this case no block is created.
 	AddBlock(block uint64, run func(HeadBlockContext) error) error
 
+	// GetHeadBlock returns the number of the last block committed to the head
+	// state, together with the record provided for it using
+	// HeadBlockContext.SetBlockRecord. The head block is persisted with the
+	// head state and thus also available for databases without an archive.
+	// If no block has been committed yet, the block number is -1. Head block
+	// tracking is only supported by configurations using schema 5.
+	GetHeadBlock() (HeadBlock, error)
+
 	// GetArchiveBlockHeight returns the current last block number of the blockchain.
 	// This value is available only when the archive is enabled.
 	GetArchiveBlockHeight() (int64, error)
 
+	// WaitForArchiveBlock blocks until the archive contains the given block or
+	// the given context is done, in which case the context's error is returned.
+	// Blocks are added to the archive asynchronously after being committed,
+	// such that the archive may lag behind the head state. This method fails
+	// if the archive is not enabled or can not make progress anymore.
+	WaitForArchiveBlock(ctx context.Context, block uint64) error
+
+	// GetArchiveQueueDepth returns the number of committed blocks waiting to
+	// be added to the archive together with the capacity of the queue, which
+	// can be configured using the ArchiveQueueSize property. This is only
+	// supported by Go based configurations with an archive.
+	GetArchiveQueueDepth() (depth int, capacity int, err error)
+
 	// GetHistoricStateHash returns state root hash for the input block number.
 	// This value is available only when the archive is enabled.
 	// Deprecated: use QueryHistoricState
@@ -92,6 +151,14 @@ type Database interface {
 	// be considered invalid.
 	QueryHistoricState(block uint64, query func(QueryContext)) error
 
+	// QueryHistoricStateWithContext is like QueryHistoricState but aborts the
+	// query once the given context is done. In this case, all subsequent reads
+	// of the query fail and the context's error is returned. The database
+	// remains fully operational. Cancellation is best-effort: the context is
+	// checked before each read, while reads in progress, including the loading
+	// of the trie nodes they access, are completed.
+	QueryHistoricStateWithContext(ctx context.Context, block uint64, query func(QueryContext)) error
+
 	// GetHistoricContext returns a block context, which accesses
 	// the world state history as it was for the input block number.
 	// This method lends the context to the caller, and the caller
@@ -100,6 +167,12 @@ type Database interface {
 	// This context is available only when the archive is enabled.
 	GetHistoricContext(block uint64) (HistoricBlockContext, error)
 
+	// GetHistoricContextWithContext is like GetHistoricContext but the reads
+	// of the resulting context fail with the error of the given context once
+	// it is done. Failed reads are reported like other errors by the
+	// transactions of the historic context.
+	GetHistoricContextWithContext(ctx context.Context, block uint64) (HistoricBlockContext, error)
+
 	// QueryBlock accesses a block context that may query the world state
 	// history as it was for the input block number.
 	// The context is provided to the caller via the input callback function.
@@ -108,12 +181,109 @@ type Database interface {
 	// This context is available only when the archive is enabled.
 	QueryBlock(block uint64, run func(HistoricBlockContext) error) error
 
+	// QueryBlockWithContext is like QueryBlock but the reads of the provided
+	// context fail with the error of the given context once it is done.
+	QueryBlockWithContext(ctx context.Context, block uint64, run func(HistoricBlockContext) error) error
+
+	// ForkHistoricBlock creates a new, independent database in the given
+	// directory, which must be empty or not exist. The new database's
+	// state is the state of the given historic block with the transactions
+	// run by the provided callback applied on top of it. These transactions
+	// form block `block+1` of the new database. The new database uses the
+	// given configuration and can be opened using OpenDatabase. If the
+	// configuration includes an archive, the archive contains the state of
+	// the forked block and the new block; all preceding blocks are empty.
+	// Forking is only supported for databases with an S5 archive and
+	// file-based configurations using schema 5. If the callback returns an
+	// error, the fork is aborted and the target directory is left in an
+	// undefined state.
+	ForkHistoricBlock(block uint64, directory string, configuration Configuration, run func(HistoricBlockContext) error) error
+
+	// GetAccountHistory lists the blocks in the range [from, to] in which the
+	// existence, balance, nonce, or code of the given account changed,
+	// together with the account's state before and after each block. The
+	// range must be covered by the archive. History queries are only
+	// supported by configurations using an S5 archive.
+	GetAccountHistory(address Address, from, to uint64) ([]AccountChange, error)
+
+	// GetStorageHistory lists the blocks in the range [from, to] in which the
+	// value of the given storage slot changed, together with the values
+	// before and after each block. Like GetAccountHistory, it is only
+	// supported by configurations using an S5 archive.
+	GetStorageHistory(address Address, key Key, from, to uint64) ([]StorageChange, error)
+
+	// GetStateStats lists the number of accounts, non-zero storage slots, and
+	// code bytes of the state at the end of each block in the range
+	// [from, to]. The statistics are maintained while blocks are added to the
+	// archive, such that no tries need to be walked. They are only supported
+	// by configurations using an S5 archive and only available if the archive
+	// has been tracking them since its first block. Statistics of archives
+	// created before they were introduced are not backfilled; such archives
+	// need to be re-created to provide them.
+	GetStateStats(from, to uint64) ([]StateStats, error)
+
+	// SubscribeUpdates registers a consumer of the state updates of committed
+	// blocks. Updates are delivered in block order through the returned
+	// subscription. Delivery applies back-pressure: if the consumer falls
+	// behind, block commits are blocked until the consumer catches up or the
+	// subscription is closed. Each subscription has a unique name under which
+	// a durable cursor is maintained by acknowledging processed blocks. If the
+	// name has been acknowledged before, delivery resumes with the block
+	// following the cursor, reconstructing missed updates from the archive.
+	// Otherwise, delivery starts with the next committed block. Updates
+	// reconstructed from the archive lead to the same state as the original
+	// updates, yet they may differ in their representation: accounts deleted
+	// and re-created within a block are reported as modified accounts whose
+	// previous storage slots are set to zero, without a deletion. Update
+	// feeds are only supported by Go based configurations; resuming requires
+	// an S5 archive.
+	SubscribeUpdates(name string) (UpdateSubscription, error)
+
 	// Flush persists all committed HeadBlockContexts to the database.
 	// This method blocks until all changes are persisted.
 	// If archive is enabled, this function also waits until
 	// all updates to the archive are persisted.
 	Flush() error
 
+	// GetLastCheckpointBlock returns the last block known to be persisted by
+	// a flush of the database, either triggered explicitly or by the
+	// automatic checkpoint policy configured through the Checkpoint*
+	// properties. The state at opening the database is considered a
+	// checkpoint as well. If no block has been persisted, -1 is returned.
+	// An error is returned if an automatic checkpoint failed.
+	GetLastCheckpointBlock() (int64, error)
+
+	// GetDiskFootprint reports the disk space occupied by this database,
+	// broken down by its components. For each component, the space occupied
+	// by deleted entries which may be reused for new data is reported as
+	// reclaimable. Not all configurations support this report.
+	GetDiskFootprint() (DiskFootprint, error)
+
+	// Scrub checks all nodes of the LiveDB and the archive for corruption.
+	// Nodes are checked against their checksums, which are maintained by
+	// databases created with the StockChecksums property, and the hashes of
+	// archive nodes are verified. At most nodesPerSecond nodes are checked
+	// per second, or an unlimited number if 0. Blocks may be added while a
+	// scrub is in progress, yet the database can not be closed. Detected
+	// bad nodes are listed in the report; an error is only returned if the
+	// scrub could not be completed. This is only supported by Go based
+	// schema 5 configurations.
+	Scrub(ctx context.Context, nodesPerSecond int) (ScrubReport, error)
+
+	// RunArchiveVerification continuously verifies the archive in the
+	// background of a running node. In the given interval, the roots and
+	// nodes added to the archive since the last verification are checked,
+	// at most nodesPerSecond nodes per second, or an unlimited number if 0.
+	// The verified range is recorded in a checkpoint in the archive's
+	// directory, such that verifications resume where they left off after a
+	// restart. The function blocks until the given context is done, in which
+	// case nil is returned, or until a verification fails, in which case the
+	// error describing the issue is returned. Blocks may be added while the
+	// verification is running, yet the context needs to be cancelled before
+	// the database can be closed. This is only supported by Go based
+	// configurations with an S5 archive.
+	RunArchiveVerification(ctx context.Context, interval time.Duration, nodesPerSecond int) error
+
 	// Close flushes and releases this database.
 	// No methods of the database should be called
 	// after it is closed, a new instance must be
@@ -131,6 +301,26 @@ type Database interface {
 	StartBulkLoad(block uint64) (BulkLoad, error)
 }
 
+// UpdateSubscription delivers the state updates of committed blocks to a
+// consumer. See Database.SubscribeUpdates.
+type UpdateSubscription interface {
+	// Updates provides the updates of committed blocks in block order. The
+	// channel is closed when the subscription is closed or fails, in which
+	// case Err reports the reason.
+	Updates() <-chan BlockUpdate
+
+	// Acknowledge durably records that the consumer has processed all blocks
+	// up to and including the given block. Cursors can only be moved forward.
+	Acknowledge(block uint64) error
+
+	// Err returns the error terminating the delivery of updates, if any.
+	Err() error
+
+	// Close ends the subscription, releasing commits blocked by it. The
+	// acknowledged cursor is retained.
+	Close() error
+}
+
 // blockContext is an interface that accesses transactions of a block.
 // This can be used for applying transactions to a new block, which is eventually added
 // to the blockchain, or for querying world state history referred to a block.
@@ -166,6 +356,21 @@ type blockContext interface {
 type HeadBlockContext interface {
 	blockContext
 
+	// GetPendingStateHash computes the state root hash resulting from
+	// committing the changes of this block without committing them. The
+	// changes remain part of this block, which may still be extended,
+	// committed, or aborted afterwards. This method fails if a transaction
+	// is running or if the configuration does not support it.
+	GetPendingStateHash() (Hash, error)
+
+	// SetBlockRecord sets an opaque record describing this block, e.g. its
+	// hash and timestamp, which is persisted with the head state when the
+	// block is committed. It can be retrieved using Database.GetHeadBlock,
+	// for instance to check whether the head state matches other chain
+	// data after a restart. This method fails if the configuration does not
+	// support head block tracking.
+	SetBlockRecord(record []byte) error
+
 	// Commit writes the changes of this block into the database, progressing the
 	// head world state and making it (eventually) visible in the archive state.
 	// It also releases resources bound to this context. This context is invalid
@@ -177,6 +382,17 @@ type HeadBlockContext interface {
 	Abort() error
 }
 
+// WitnessBlockContext is a HeadBlockContext recording a witness for the
+// processed block. Committing the block using Commit discards the witness.
+type WitnessBlockContext interface {
+	HeadBlockContext
+
+	// CommitWithWitness commits the block like Commit and returns the
+	// witness recorded while processing it. This context is invalid
+	// after this call and should be discarded.
+	CommitWithWitness() (Witness, error)
+}
+
 // HistoricBlockContext provides access to the world state of a block of a blockchain.
 // This context allows the caller to open a transaction
 // and query state of the blockchain as it was for this particular block withing the history.
@@ -225,6 +441,15 @@ type TransactionContext interface {
 	// It clears its balance, and marks the account as destructed.
 	SelfDestruct(Address) bool
 
+	// SelfDestruct6780 invalidates the account with the given address
+	// following the semantics of EIP-6780, effective since Cancun: only if
+	// the account was created in the current transaction, its balance is
+	// cleared and the account is marked as destructed, as by SelfDestruct.
+	// Otherwise, the account remains unmodified and transferring its balance
+	// to the beneficiary is up to the caller. The result is true if the
+	// account was marked as destructed.
+	SelfDestruct6780(Address) bool
+
 	// HasSelfDestructed checks if the account with the given address
 	// was destructed.
 	HasSelfDestructed(Address) bool
@@ -444,6 +669,139 @@ type Value common.Value
 // Hash is a 32byte hash.
 type Hash common.Hash
 
+// HeadBlock describes the last block committed to the head state.
+type HeadBlock struct {
+	Number int64  // < the block number, -1 if there is no such block
+	Record []byte // < the record set for the block, nil if there is none
+}
+
+// AccountState summarizes the fields of an account at the end of a block.
+type AccountState struct {
+	Exists   bool
+	Balance  Amount
+	Nonce    uint64
+	CodeHash Hash
+}
+
+// AccountChange describes the modification of an account in a block.
+type AccountChange struct {
+	Block  uint64
+	Before AccountState // < the state at the end of the preceding block
+	After  AccountState // < the state at the end of the block
+}
+
+// StorageChange describes the modification of a storage slot in a block.
+type StorageChange struct {
+	Block  uint64
+	Before Value // < the value at the end of the preceding block
+	After  Value // < the value at the end of the block
+}
+
+// StateStats summarizes the size of the state at the end of a block.
+type StateStats struct {
+	Block       uint64
+	NumAccounts uint64 // < the number of existing accounts
+	NumSlots    uint64 // < the number of storage slots with a non-zero value
+	CodeBytes   uint64 // < the total size of the codes of all accounts
+}
+
+// DiskFootprint describes the disk space occupied by a component of a
+// database and its sub-components.
+type DiskFootprint struct {
+	Size        uint64                   // < bytes occupied by the component, excluding sub-components
+	Reclaimable uint64                   // < part of Size occupied by deleted entries which may be reused
+	Note        string                   // < optional remarks on the component
+	Children    map[string]DiskFootprint // < the sub-components by name
+}
+
+// ScrubReport summarizes the results of a scrub of a database.
+type ScrubReport struct {
+	NumNodes int       // < the number of checked nodes
+	BadNodes []BadNode // < the nodes detected to be corrupted
+}
+
+// BadNode describes a node detected to be corrupted by a scrub.
+type BadNode struct {
+	Archive bool   // < true if the node is part of the archive, false if it is part of the LiveDB
+	Id      string // < the ID of the node, e.g. B-12 for the branch node with index 12
+	Issue   string // < a description of the detected problem
+}
+
+// BlockUpdate describes the state modifications applied by a block. Accounts
+// are deleted before they are created and other modifications are applied.
+type BlockUpdate struct {
+	Block           uint64
+	DeletedAccounts []Address
+	CreatedAccounts []Address
+	Balances        []BalanceUpdate
+	Nonces          []NonceUpdate
+	Codes           []CodeUpdate
+	Slots           []SlotUpdate
+}
+
+// BalanceUpdate describes the new balance of an account.
+type BalanceUpdate struct {
+	Account Address
+	Balance Amount
+}
+
+// NonceUpdate describes the new nonce of an account.
+type NonceUpdate struct {
+	Account Address
+	Nonce   uint64
+}
+
+// CodeUpdate describes the new code of an account.
+type CodeUpdate struct {
+	Account Address
+	Code    []byte
+}
+
+// SlotUpdate describes the new value of a storage slot.
+type SlotUpdate struct {
+	Account Address
+	Key     Key
+	Value   Value
+}
+
+// StateEntryType identifies the kind of information provided by a
+// StateEntry.
+type StateEntryType byte
+
+const (
+	// AccountEntry entries define the balance and nonce of an account.
+	AccountEntry StateEntryType = iota
+	// CodeEntry entries define the code of an account.
+	CodeEntry
+	// StorageEntry entries define the value of a storage slot of an account.
+	StorageEntry
+)
+
+// StateEntry is a single piece of state information imported by ImportState.
+// Depending on the type of the entry, only a subset of the fields is used.
+// Accounts referenced by code or storage entries are implicitly created.
+type StateEntry struct {
+	Type    StateEntryType
+	Address Address
+	Balance Amount // used by AccountEntry entries
+	Nonce   uint64 // used by AccountEntry entries
+	Code    []byte // used by CodeEntry entries
+	Key     Key    // used by StorageEntry entries
+	Value   Value  // used by StorageEntry entries
+}
+
+// StateIterator provides the state entries consumed by ImportState.
+type StateIterator interface {
+	// Next returns the next entry of the iteration. At the end of the
+	// iteration, io.EOF is returned. Any other error aborts the import.
+	Next() (StateEntry, error)
+}
+
+// Witness is a serialized, self-contained collection of the state information
+// read while processing a block. See WitnessBlockContext and
+// OpenWitnessDatabase for its production and consumption.
+type Witness []byte
+
 // Log summarizes a log message recorded during the execution of a contract.
 type Log struct {
 	// -- payload --
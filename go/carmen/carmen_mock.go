@@ -20,7 +20,9 @@
 package carmen
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -77,6 +79,21 @@ func (mr *MockDatabaseMockRecorder) BeginBlock(block any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginBlock", reflect.TypeOf((*MockDatabase)(nil).BeginBlock), block)
 }
 
+// BeginBlockWithWitness mocks base method.
+func (m *MockDatabase) BeginBlockWithWitness(block uint64) (WitnessBlockContext, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginBlockWithWitness", block)
+	ret0, _ := ret[0].(WitnessBlockContext)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginBlockWithWitness indicates an expected call of BeginBlockWithWitness.
+func (mr *MockDatabaseMockRecorder) BeginBlockWithWitness(block any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginBlockWithWitness", reflect.TypeOf((*MockDatabase)(nil).BeginBlockWithWitness), block)
+}
+
 // Close mocks base method.
 func (m *MockDatabase) Close() error {
 	m.ctrl.T.Helper()
@@ -105,6 +122,35 @@ func (mr *MockDatabaseMockRecorder) Flush() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockDatabase)(nil).Flush))
 }
 
+// ForkHistoricBlock mocks base method.
+func (m *MockDatabase) ForkHistoricBlock(block uint64, directory string, configuration Configuration, run func(HistoricBlockContext) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForkHistoricBlock", block, directory, configuration, run)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForkHistoricBlock indicates an expected call of ForkHistoricBlock.
+func (mr *MockDatabaseMockRecorder) ForkHistoricBlock(block, directory, configuration, run any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForkHistoricBlock", reflect.TypeOf((*MockDatabase)(nil).ForkHistoricBlock), block, directory, configuration, run)
+}
+
+// GetAccountHistory mocks base method.
+func (m *MockDatabase) GetAccountHistory(address Address, from, to uint64) ([]AccountChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountHistory", address, from, to)
+	ret0, _ := ret[0].([]AccountChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountHistory indicates an expected call of GetAccountHistory.
+func (mr *MockDatabaseMockRecorder) GetAccountHistory(address, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountHistory", reflect.TypeOf((*MockDatabase)(nil).GetAccountHistory), address, from, to)
+}
+
 // GetArchiveBlockHeight mocks base method.
 func (m *MockDatabase) GetArchiveBlockHeight() (int64, error) {
 	m.ctrl.T.Helper()
@@ -120,6 +166,52 @@ func (mr *MockDatabaseMockRecorder) GetArchiveBlockHeight() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchiveBlockHeight", reflect.TypeOf((*MockDatabase)(nil).GetArchiveBlockHeight))
 }
 
+// GetArchiveQueueDepth mocks base method.
+func (m *MockDatabase) GetArchiveQueueDepth() (int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchiveQueueDepth")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetArchiveQueueDepth indicates an expected call of GetArchiveQueueDepth.
+func (mr *MockDatabaseMockRecorder) GetArchiveQueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchiveQueueDepth", reflect.TypeOf((*MockDatabase)(nil).GetArchiveQueueDepth))
+}
+
+// GetDiskFootprint mocks base method.
+func (m *MockDatabase) GetDiskFootprint() (DiskFootprint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDiskFootprint")
+	ret0, _ := ret[0].(DiskFootprint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDiskFootprint indicates an expected call of GetDiskFootprint.
+func (mr *MockDatabaseMockRecorder) GetDiskFootprint() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskFootprint", reflect.TypeOf((*MockDatabase)(nil).GetDiskFootprint))
+}
+
+// GetHeadBlock mocks base method.
+func (m *MockDatabase) GetHeadBlock() (HeadBlock, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeadBlock")
+	ret0, _ := ret[0].(HeadBlock)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeadBlock indicates an expected call of GetHeadBlock.
+func (mr *MockDatabaseMockRecorder) GetHeadBlock() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeadBlock", reflect.TypeOf((*MockDatabase)(nil).GetHeadBlock))
+}
+
 // GetHistoricContext mocks base method.
 func (m *MockDatabase) GetHistoricContext(block uint64) (HistoricBlockContext, error) {
 	m.ctrl.T.Helper()
@@ -135,6 +227,21 @@ func (mr *MockDatabaseMockRecorder) GetHistoricContext(block any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistoricContext", reflect.TypeOf((*MockDatabase)(nil).GetHistoricContext), block)
 }
 
+// GetHistoricContextWithContext mocks base method.
+func (m *MockDatabase) GetHistoricContextWithContext(ctx context.Context, block uint64) (HistoricBlockContext, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistoricContextWithContext", ctx, block)
+	ret0, _ := ret[0].(HistoricBlockContext)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistoricContextWithContext indicates an expected call of GetHistoricContextWithContext.
+func (mr *MockDatabaseMockRecorder) GetHistoricContextWithContext(ctx, block any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistoricContextWithContext", reflect.TypeOf((*MockDatabase)(nil).GetHistoricContextWithContext), ctx, block)
+}
+
 // GetHistoricStateHash mocks base method.
 func (m *MockDatabase) GetHistoricStateHash(block uint64) (Hash, error) {
 	m.ctrl.T.Helper()
@@ -150,6 +257,51 @@ func (mr *MockDatabaseMockRecorder) GetHistoricStateHash(block any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistoricStateHash", reflect.TypeOf((*MockDatabase)(nil).GetHistoricStateHash), block)
 }
 
+// GetLastCheckpointBlock mocks base method.
+func (m *MockDatabase) GetLastCheckpointBlock() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastCheckpointBlock")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastCheckpointBlock indicates an expected call of GetLastCheckpointBlock.
+func (mr *MockDatabaseMockRecorder) GetLastCheckpointBlock() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastCheckpointBlock", reflect.TypeOf((*MockDatabase)(nil).GetLastCheckpointBlock))
+}
+
+// GetStateStats mocks base method.
+func (m *MockDatabase) GetStateStats(from, to uint64) ([]StateStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStateStats", from, to)
+	ret0, _ := ret[0].([]StateStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStateStats indicates an expected call of GetStateStats.
+func (mr *MockDatabaseMockRecorder) GetStateStats(from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateStats", reflect.TypeOf((*MockDatabase)(nil).GetStateStats), from, to)
+}
+
+// GetStorageHistory mocks base method.
+func (m *MockDatabase) GetStorageHistory(address Address, key Key, from, to uint64) ([]StorageChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStorageHistory", address, key, from, to)
+	ret0, _ := ret[0].([]StorageChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStorageHistory indicates an expected call of GetStorageHistory.
+func (mr *MockDatabaseMockRecorder) GetStorageHistory(address, key, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorageHistory", reflect.TypeOf((*MockDatabase)(nil).GetStorageHistory), address, key, from, to)
+}
+
 // QueryBlock mocks base method.
 func (m *MockDatabase) QueryBlock(block uint64, run func(HistoricBlockContext) error) error {
 	m.ctrl.T.Helper()
@@ -164,6 +316,20 @@ func (mr *MockDatabaseMockRecorder) QueryBlock(block, run any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryBlock", reflect.TypeOf((*MockDatabase)(nil).QueryBlock), block, run)
 }
 
+// QueryBlockWithContext mocks base method.
+func (m *MockDatabase) QueryBlockWithContext(ctx context.Context, block uint64, run func(HistoricBlockContext) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryBlockWithContext", ctx, block, run)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QueryBlockWithContext indicates an expected call of QueryBlockWithContext.
+func (mr *MockDatabaseMockRecorder) QueryBlockWithContext(ctx, block, run any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryBlockWithContext", reflect.TypeOf((*MockDatabase)(nil).QueryBlockWithContext), ctx, block, run)
+}
+
 // QueryHeadState mocks base method.
 func (m *MockDatabase) QueryHeadState(query func(QueryContext)) error {
 	m.ctrl.T.Helper()
@@ -192,6 +358,49 @@ func (mr *MockDatabaseMockRecorder) QueryHistoricState(block, query any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryHistoricState", reflect.TypeOf((*MockDatabase)(nil).QueryHistoricState), block, query)
 }
 
+// QueryHistoricStateWithContext mocks base method.
+func (m *MockDatabase) QueryHistoricStateWithContext(ctx context.Context, block uint64, query func(QueryContext)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryHistoricStateWithContext", ctx, block, query)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QueryHistoricStateWithContext indicates an expected call of QueryHistoricStateWithContext.
+func (mr *MockDatabaseMockRecorder) QueryHistoricStateWithContext(ctx, block, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryHistoricStateWithContext", reflect.TypeOf((*MockDatabase)(nil).QueryHistoricStateWithContext), ctx, block, query)
+}
+
+// RunArchiveVerification mocks base method.
+func (m *MockDatabase) RunArchiveVerification(ctx context.Context, interval time.Duration, nodesPerSecond int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunArchiveVerification", ctx, interval, nodesPerSecond)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunArchiveVerification indicates an expected call of RunArchiveVerification.
+func (mr *MockDatabaseMockRecorder) RunArchiveVerification(ctx, interval, nodesPerSecond any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunArchiveVerification", reflect.TypeOf((*MockDatabase)(nil).RunArchiveVerification), ctx, interval, nodesPerSecond)
+}
+
+// Scrub mocks base method.
+func (m *MockDatabase) Scrub(ctx context.Context, nodesPerSecond int) (ScrubReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scrub", ctx, nodesPerSecond)
+	ret0, _ := ret[0].(ScrubReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Scrub indicates an expected call of Scrub.
+func (mr *MockDatabaseMockRecorder) Scrub(ctx, nodesPerSecond any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scrub", reflect.TypeOf((*MockDatabase)(nil).Scrub), ctx, nodesPerSecond)
+}
+
 // StartBulkLoad mocks base method.
 func (m *MockDatabase) StartBulkLoad(block uint64) (BulkLoad, error) {
 	m.ctrl.T.Helper()
@@ -207,6 +416,114 @@ func (mr *MockDatabaseMockRecorder) StartBulkLoad(block any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartBulkLoad", reflect.TypeOf((*MockDatabase)(nil).StartBulkLoad), block)
 }
 
+// SubscribeUpdates mocks base method.
+func (m *MockDatabase) SubscribeUpdates(name string) (UpdateSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeUpdates", name)
+	ret0, _ := ret[0].(UpdateSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeUpdates indicates an expected call of SubscribeUpdates.
+func (mr *MockDatabaseMockRecorder) SubscribeUpdates(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeUpdates", reflect.TypeOf((*MockDatabase)(nil).SubscribeUpdates), name)
+}
+
+// WaitForArchiveBlock mocks base method.
+func (m *MockDatabase) WaitForArchiveBlock(ctx context.Context, block uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForArchiveBlock", ctx, block)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForArchiveBlock indicates an expected call of WaitForArchiveBlock.
+func (mr *MockDatabaseMockRecorder) WaitForArchiveBlock(ctx, block any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForArchiveBlock", reflect.TypeOf((*MockDatabase)(nil).WaitForArchiveBlock), ctx, block)
+}
+
+// MockUpdateSubscription is a mock of UpdateSubscription interface.
+type MockUpdateSubscription struct {
+	ctrl     *gomock.Controller
+	recorder *MockUpdateSubscriptionMockRecorder
+}
+
+// MockUpdateSubscriptionMockRecorder is the mock recorder for MockUpdateSubscription.
+type MockUpdateSubscriptionMockRecorder struct {
+	mock *MockUpdateSubscription
+}
+
+// NewMockUpdateSubscription creates a new mock instance.
+func NewMockUpdateSubscription(ctrl *gomock.Controller) *MockUpdateSubscription {
+	mock := &MockUpdateSubscription{ctrl: ctrl}
+	mock.recorder = &MockUpdateSubscriptionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUpdateSubscription) EXPECT() *MockUpdateSubscriptionMockRecorder {
+	return m.recorder
+}
+
+// Acknowledge mocks base method.
+func (m *MockUpdateSubscription) Acknowledge(block uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acknowledge", block)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Acknowledge indicates an expected call of Acknowledge.
+func (mr *MockUpdateSubscriptionMockRecorder) Acknowledge(block any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acknowledge", reflect.TypeOf((*MockUpdateSubscription)(nil).Acknowledge), block)
+}
+
+// Close mocks base method.
+func (m *MockUpdateSubscription) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockUpdateSubscriptionMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockUpdateSubscription)(nil).Close))
+}
+
+// Err mocks base method.
+func (m *MockUpdateSubscription) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockUpdateSubscriptionMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockUpdateSubscription)(nil).Err))
+}
+
+// Updates mocks base method.
+func (m *MockUpdateSubscription) Updates() <-chan BlockUpdate {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Updates")
+	ret0, _ := ret[0].(<-chan BlockUpdate)
+	return ret0
+}
+
+// Updates indicates an expected call of Updates.
+func (mr *MockUpdateSubscriptionMockRecorder) Updates() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Updates", reflect.TypeOf((*MockUpdateSubscription)(nil).Updates))
+}
+
 // MockblockContext is a mock of blockContext interface.
 type MockblockContext struct {
 	ctrl     *gomock.Controller
@@ -325,6 +642,21 @@ func (mr *MockHeadBlockContextMockRecorder) Commit() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockHeadBlockContext)(nil).Commit))
 }
 
+// GetPendingStateHash mocks base method.
+func (m *MockHeadBlockContext) GetPendingStateHash() (Hash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPendingStateHash")
+	ret0, _ := ret[0].(Hash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPendingStateHash indicates an expected call of GetPendingStateHash.
+func (mr *MockHeadBlockContextMockRecorder) GetPendingStateHash() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPendingStateHash", reflect.TypeOf((*MockHeadBlockContext)(nil).GetPendingStateHash))
+}
+
 // RunTransaction mocks base method.
 func (m *MockHeadBlockContext) RunTransaction(run func(TransactionContext) error) error {
 	m.ctrl.T.Helper()
@@ -339,6 +671,144 @@ func (mr *MockHeadBlockContextMockRecorder) RunTransaction(run any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunTransaction", reflect.TypeOf((*MockHeadBlockContext)(nil).RunTransaction), run)
 }
 
+// SetBlockRecord mocks base method.
+func (m *MockHeadBlockContext) SetBlockRecord(record []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBlockRecord", record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBlockRecord indicates an expected call of SetBlockRecord.
+func (mr *MockHeadBlockContextMockRecorder) SetBlockRecord(record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBlockRecord", reflect.TypeOf((*MockHeadBlockContext)(nil).SetBlockRecord), record)
+}
+
+// MockWitnessBlockContext is a mock of WitnessBlockContext interface.
+type MockWitnessBlockContext struct {
+	ctrl     *gomock.Controller
+	recorder *MockWitnessBlockContextMockRecorder
+}
+
+// MockWitnessBlockContextMockRecorder is the mock recorder for MockWitnessBlockContext.
+type MockWitnessBlockContextMockRecorder struct {
+	mock *MockWitnessBlockContext
+}
+
+// NewMockWitnessBlockContext creates a new mock instance.
+func NewMockWitnessBlockContext(ctrl *gomock.Controller) *MockWitnessBlockContext {
+	mock := &MockWitnessBlockContext{ctrl: ctrl}
+	mock.recorder = &MockWitnessBlockContextMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWitnessBlockContext) EXPECT() *MockWitnessBlockContextMockRecorder {
+	return m.recorder
+}
+
+// Abort mocks base method.
+func (m *MockWitnessBlockContext) Abort() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abort")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abort indicates an expected call of Abort.
+func (mr *MockWitnessBlockContextMockRecorder) Abort() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abort", reflect.TypeOf((*MockWitnessBlockContext)(nil).Abort))
+}
+
+// BeginTransaction mocks base method.
+func (m *MockWitnessBlockContext) BeginTransaction() (TransactionContext, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTransaction")
+	ret0, _ := ret[0].(TransactionContext)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginTransaction indicates an expected call of BeginTransaction.
+func (mr *MockWitnessBlockContextMockRecorder) BeginTransaction() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTransaction", reflect.TypeOf((*MockWitnessBlockContext)(nil).BeginTransaction))
+}
+
+// Commit mocks base method.
+func (m *MockWitnessBlockContext) Commit() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockWitnessBlockContextMockRecorder) Commit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockWitnessBlockContext)(nil).Commit))
+}
+
+// CommitWithWitness mocks base method.
+func (m *MockWitnessBlockContext) CommitWithWitness() (Witness, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitWithWitness")
+	ret0, _ := ret[0].(Witness)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommitWithWitness indicates an expected call of CommitWithWitness.
+func (mr *MockWitnessBlockContextMockRecorder) CommitWithWitness() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitWithWitness", reflect.TypeOf((*MockWitnessBlockContext)(nil).CommitWithWitness))
+}
+
+// GetPendingStateHash mocks base method.
+func (m *MockWitnessBlockContext) GetPendingStateHash() (Hash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPendingStateHash")
+	ret0, _ := ret[0].(Hash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPendingStateHash indicates an expected call of GetPendingStateHash.
+func (mr *MockWitnessBlockContextMockRecorder) GetPendingStateHash() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPendingStateHash", reflect.TypeOf((*MockWitnessBlockContext)(nil).GetPendingStateHash))
+}
+
+// RunTransaction mocks base method.
+func (m *MockWitnessBlockContext) RunTransaction(run func(TransactionContext) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunTransaction", run)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunTransaction indicates an expected call of RunTransaction.
+func (mr *MockWitnessBlockContextMockRecorder) RunTransaction(run any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunTransaction", reflect.TypeOf((*MockWitnessBlockContext)(nil).RunTransaction), run)
+}
+
+// SetBlockRecord mocks base method.
+func (m *MockWitnessBlockContext) SetBlockRecord(record []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBlockRecord", record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBlockRecord indicates an expected call of SetBlockRecord.
+func (mr *MockWitnessBlockContextMockRecorder) SetBlockRecord(record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBlockRecord", reflect.TypeOf((*MockWitnessBlockContext)(nil).SetBlockRecord), record)
+}
+
 // MockHistoricBlockContext is a mock of HistoricBlockContext interface.
 type MockHistoricBlockContext struct {
 	ctrl     *gomock.Controller
@@ -694,6 +1164,20 @@ func (mr *MockTransactionContextMockRecorder) GetState(arg0, arg1 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetState", reflect.TypeOf((*MockTransactionContext)(nil).GetState), arg0, arg1)
 }
 
+// GetTransientState mocks base method.
+func (m *MockTransactionContext) GetTransientState(arg0 Address, arg1 Key) Value {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransientState", arg0, arg1)
+	ret0, _ := ret[0].(Value)
+	return ret0
+}
+
+// GetTransientState indicates an expected call of GetTransientState.
+func (mr *MockTransactionContextMockRecorder) GetTransientState(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransientState", reflect.TypeOf((*MockTransactionContext)(nil).GetTransientState), arg0, arg1)
+}
+
 // HasSelfDestructed mocks base method.
 func (m *MockTransactionContext) HasSelfDestructed(arg0 Address) bool {
 	m.ctrl.T.Helper()
@@ -763,6 +1247,20 @@ func (mr *MockTransactionContextMockRecorder) SelfDestruct(arg0 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelfDestruct", reflect.TypeOf((*MockTransactionContext)(nil).SelfDestruct), arg0)
 }
 
+// SelfDestruct6780 mocks base method.
+func (m *MockTransactionContext) SelfDestruct6780(arg0 Address) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelfDestruct6780", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SelfDestruct6780 indicates an expected call of SelfDestruct6780.
+func (mr *MockTransactionContextMockRecorder) SelfDestruct6780(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelfDestruct6780", reflect.TypeOf((*MockTransactionContext)(nil).SelfDestruct6780), arg0)
+}
+
 // SetCode mocks base method.
 func (m *MockTransactionContext) SetCode(arg0 Address, arg1 []byte) {
 	m.ctrl.T.Helper()
@@ -799,6 +1297,18 @@ func (mr *MockTransactionContextMockRecorder) SetState(arg0, arg1, arg2 any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetState", reflect.TypeOf((*MockTransactionContext)(nil).SetState), arg0, arg1, arg2)
 }
 
+// SetTransientState mocks base method.
+func (m *MockTransactionContext) SetTransientState(arg0 Address, arg1 Key, arg2 Value) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTransientState", arg0, arg1, arg2)
+}
+
+// SetTransientState indicates an expected call of SetTransientState.
+func (mr *MockTransactionContextMockRecorder) SetTransientState(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTransientState", reflect.TypeOf((*MockTransactionContext)(nil).SetTransientState), arg0, arg1, arg2)
+}
+
 // Snapshot mocks base method.
 func (m *MockTransactionContext) Snapshot() int {
 	m.ctrl.T.Helper()
@@ -1054,3 +1564,41 @@ func (mr *MockBulkLoadMockRecorder) SetState(arg0, arg1, arg2 any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetState", reflect.TypeOf((*MockBulkLoad)(nil).SetState), arg0, arg1, arg2)
 }
+
+// MockStateIterator is a mock of StateIterator interface.
+type MockStateIterator struct {
+	ctrl     *gomock.Controller
+	recorder *MockStateIteratorMockRecorder
+}
+
+// MockStateIteratorMockRecorder is the mock recorder for MockStateIterator.
+type MockStateIteratorMockRecorder struct {
+	mock *MockStateIterator
+}
+
+// NewMockStateIterator creates a new mock instance.
+func NewMockStateIterator(ctrl *gomock.Controller) *MockStateIterator {
+	mock := &MockStateIterator{ctrl: ctrl}
+	mock.recorder = &MockStateIteratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStateIterator) EXPECT() *MockStateIteratorMockRecorder {
+	return m.recorder
+}
+
+// Next mocks base method.
+func (m *MockStateIterator) Next() (StateEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next")
+	ret0, _ := ret[0].(StateEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockStateIteratorMockRecorder) Next() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockStateIterator)(nil).Next))
+}
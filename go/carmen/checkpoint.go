@@ -0,0 +1,232 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// checkpointPolicy defines the conditions triggering automatic background
+// flushes of a database. Conditions with a zero value are disabled.
+type checkpointPolicy struct {
+	blocks     int64         // < the number of blocks after which a checkpoint is conducted
+	period     time.Duration // < the time after which committed blocks are checkpointed
+	dirtyNodes int           // < the number of dirty nodes triggering a checkpoint
+}
+
+func getCheckpointPolicy(properties Properties) (checkpointPolicy, error) {
+	blocks, err := properties.GetInteger(CheckpointBlockInterval, 0)
+	if err != nil {
+		return checkpointPolicy{}, err
+	}
+	seconds, err := properties.GetInteger(CheckpointTimeInterval, 0)
+	if err != nil {
+		return checkpointPolicy{}, err
+	}
+	dirtyNodes, err := properties.GetInteger(CheckpointDirtyNodes, 0)
+	if err != nil {
+		return checkpointPolicy{}, err
+	}
+	for name, value := range map[Property]int{
+		CheckpointBlockInterval: blocks,
+		CheckpointTimeInterval:  seconds,
+		CheckpointDirtyNodes:    dirtyNodes,
+	} {
+		if value < 0 {
+			return checkpointPolicy{}, fmt.Errorf("invalid value for '%s' property: %d", name, value)
+		}
+	}
+	return checkpointPolicy{
+		blocks:     int64(blocks),
+		period:     time.Duration(seconds) * time.Second,
+		dirtyNodes: dirtyNodes,
+	}, nil
+}
+
+func (p checkpointPolicy) enabled() bool {
+	return p.blocks > 0 || p.period > 0 || p.dirtyNodes > 0
+}
+
+// dirtyNodeCounter is implemented by states able to report the number of
+// modified nodes not yet written to disk.
+type dirtyNodeCounter interface {
+	CountDirtyNodes() (int, error)
+}
+
+// checkpointer tracks the last checkpoint of a database and, if enabled by
+// its policy, conducts checkpoints in the background. A checkpoint flushes
+// the state, which is synchronized with block commits by the state itself;
+// thus, commits are only delayed while the actual flush is in progress.
+type checkpointer struct {
+	policy  checkpointPolicy
+	state   state.State
+	counter dirtyNodeCounter // < nil if dirty nodes are not counted
+
+	commits chan struct{} // < signals new commits to the background worker
+	stop    chan struct{} // < closed to stop the background worker
+	done    chan struct{} // < closed when the background worker is done, nil if there is none
+
+	mutex sync.Mutex
+	head  int64 // < the last committed block, -1 if there is none
+	last  int64 // < the last checkpoint block, -1 if there is none
+	err   error // < the error of a failed background checkpoint
+}
+
+func newCheckpointer(policy checkpointPolicy, s state.State, head int64) (*checkpointer, error) {
+	res := &checkpointer{
+		policy: policy,
+		state:  s,
+		head:   head,
+		last:   head,
+	}
+	if policy.dirtyNodes > 0 {
+		if _, ok := state.UnsafeUnwrapSyncedState(s).(dirtyNodeCounter); !ok {
+			return nil, fmt.Errorf("%w: dirty node based checkpoints are not supported by this database", UnsupportedConfiguration)
+		}
+		// Synchronized states obtain the count under the state's lock.
+		counter, ok := s.(dirtyNodeCounter)
+		if !ok {
+			return nil, fmt.Errorf("%w: dirty node based checkpoints are not supported by this database", UnsupportedConfiguration)
+		}
+		res.counter = counter
+	}
+	if policy.enabled() {
+		res.commits = make(chan struct{}, 1)
+		res.stop = make(chan struct{})
+		res.done = make(chan struct{})
+		go res.run()
+	}
+	return res, nil
+}
+
+// committed informs the checkpointer about a newly committed block.
+func (c *checkpointer) committed(block int64) {
+	c.mutex.Lock()
+	c.head = block
+	c.mutex.Unlock()
+	if c.commits == nil {
+		return
+	}
+	// Signals are coalesced if the worker is busy.
+	select {
+	case c.commits <- struct{}{}:
+	default:
+	}
+}
+
+// flushed records a checkpoint conducted by an explicit flush.
+func (c *checkpointer) flushed(block int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if block > c.last {
+		c.last = block
+	}
+}
+
+// getLastCheckpoint returns the last checkpoint block or the error of a failed
+// background checkpoint.
+func (c *checkpointer) getLastCheckpoint() (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.last, c.err
+}
+
+// close stops the background worker, waiting for a running checkpoint to
+// complete.
+func (c *checkpointer) close() {
+	if c.done == nil {
+		return
+	}
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	<-c.done
+}
+
+func (c *checkpointer) run() {
+	defer close(c.done)
+
+	var ticks <-chan time.Time
+	if c.policy.period > 0 {
+		ticker := time.NewTicker(c.policy.period)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		var due bool
+		select {
+		case <-c.stop:
+			return
+		case <-ticks:
+			due = c.hasUncheckpointedBlocks()
+		case <-c.commits:
+			due = c.isDue()
+		}
+		if due && !c.checkpoint() {
+			return
+		}
+	}
+}
+
+func (c *checkpointer) hasUncheckpointedBlocks() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.head > c.last
+}
+
+func (c *checkpointer) isDue() bool {
+	c.mutex.Lock()
+	pending := c.head - c.last
+	c.mutex.Unlock()
+	if pending <= 0 {
+		return false
+	}
+	if c.policy.blocks > 0 && pending >= c.policy.blocks {
+		return true
+	}
+	if c.counter != nil {
+		// The dirty node count is only used as a trigger; if it can not be
+		// obtained, the next flush reports the underlying issue.
+		count, err := c.counter.CountDirtyNodes()
+		return err != nil || count >= c.policy.dirtyNodes
+	}
+	return false
+}
+
+// checkpoint flushes the state and records the checkpoint. It returns false
+// if the flush failed, in which case no further checkpoints are conducted.
+func (c *checkpointer) checkpoint() bool {
+	// Blocks committed after reading the head may be covered by the flush as
+	// well; the recorded checkpoint is thus a lower bound.
+	c.mutex.Lock()
+	head := c.head
+	c.mutex.Unlock()
+
+	err := c.state.Flush()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err != nil {
+		c.err = fmt.Errorf("background checkpoint failed: %w", err)
+		return false
+	}
+	if head > c.last {
+		c.last = head
+	}
+	return true
+}
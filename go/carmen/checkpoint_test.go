@@ -0,0 +1,181 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"go.uber.org/mock/gomock"
+)
+
+// waitForCheckpoint waits until the last checkpoint of the given database
+// reaches the given block.
+func waitForCheckpoint(t *testing.T, db Database, block int64) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		last, err := db.GetLastCheckpointBlock()
+		if err != nil {
+			t.Fatalf("failed to get last checkpoint: %v", err)
+		}
+		if last >= block {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("checkpoint not reached, wanted %d, got %d", block, last)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func openCheckpointTestDatabase(t *testing.T, properties Properties) Database {
+	t.Helper()
+	for name, value := range testProperties {
+		properties[name] = value
+	}
+	db, err := OpenDatabase(t.TempDir(), GetCarmenGoS5WithArchiveConfiguration(), properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	})
+	return db
+}
+
+func TestCheckpoint_ExplicitFlushesAreReportedAsCheckpoints(t *testing.T) {
+	db := openCheckpointTestDatabase(t, Properties{})
+	if last, err := db.GetLastCheckpointBlock(); err != nil || last != -1 {
+		t.Errorf("unexpected checkpoint of empty database, wanted -1, got %d, err %v", last, err)
+	}
+	for i := 0; i < 3; i++ {
+		addBalanceInBlock(t, db, uint64(i), Address{1}, i == 0)
+	}
+	if last, err := db.GetLastCheckpointBlock(); err != nil || last != -1 {
+		t.Errorf("unexpected checkpoint without flush, wanted -1, got %d, err %v", last, err)
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+	if last, err := db.GetLastCheckpointBlock(); err != nil || last != 2 {
+		t.Errorf("unexpected checkpoint after flush, wanted 2, got %d, err %v", last, err)
+	}
+}
+
+func TestCheckpoint_StateAtOpeningIsACheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	config := GetCarmenGoS5WithArchiveConfiguration()
+	db, err := OpenDatabase(dir, config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	addBalanceInBlock(t, db, 4, Address{1}, true)
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	db, err = OpenDatabase(dir, config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	if last, err := db.GetLastCheckpointBlock(); err != nil || last != 4 {
+		t.Errorf("unexpected checkpoint of reopened database, wanted 4, got %d, err %v", last, err)
+	}
+}
+
+func TestCheckpoint_BlockIntervalTriggersCheckpoints(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(CheckpointBlockInterval, 2)
+	db := openCheckpointTestDatabase(t, properties)
+
+	addBalanceInBlock(t, db, 0, Address{1}, true)
+	addBalanceInBlock(t, db, 1, Address{1}, false)
+	waitForCheckpoint(t, db, 1)
+	for i := 2; i < 5; i++ {
+		addBalanceInBlock(t, db, uint64(i), Address{1}, false)
+	}
+	waitForCheckpoint(t, db, 3)
+}
+
+func TestCheckpoint_TimeIntervalTriggersCheckpoints(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(CheckpointTimeInterval, 1)
+	db := openCheckpointTestDatabase(t, properties)
+
+	addBalanceInBlock(t, db, 0, Address{1}, true)
+	addBalanceInBlock(t, db, 1, Address{1}, false)
+	waitForCheckpoint(t, db, 1)
+}
+
+func TestCheckpoint_DirtyNodesTriggerCheckpoints(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(CheckpointDirtyNodes, 1)
+	db := openCheckpointTestDatabase(t, properties)
+
+	addBalanceInBlock(t, db, 0, Address{1}, true)
+	waitForCheckpoint(t, db, 0)
+}
+
+func TestCheckpoint_NegativePropertiesAreRejected(t *testing.T) {
+	for _, property := range []Property{CheckpointBlockInterval, CheckpointTimeInterval, CheckpointDirtyNodes} {
+		properties := Properties{}
+		properties.SetInteger(property, -1)
+		if _, err := OpenDatabase(t.TempDir(), testConfig, properties); err == nil {
+			t.Errorf("negative value for %v should be rejected", property)
+		}
+	}
+}
+
+func TestCheckpoint_DirtyNodesRequireSupportOfState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	st := state.NewMockState(ctrl)
+	if _, err := newCheckpointer(checkpointPolicy{dirtyNodes: 1}, st, -1); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+}
+
+func TestCheckpoint_FailedBackgroundCheckpointIsReported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	st := state.NewMockState(ctrl)
+	injectedErr := errors.New("injected error")
+	st.EXPECT().Flush().Return(injectedErr)
+
+	checkpoints, err := newCheckpointer(checkpointPolicy{blocks: 1}, st, -1)
+	if err != nil {
+		t.Fatalf("failed to create checkpointer: %v", err)
+	}
+	defer checkpoints.close()
+
+	checkpoints.committed(0)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		last, err := checkpoints.getLastCheckpoint()
+		if errors.Is(err, injectedErr) {
+			if last != -1 {
+				t.Errorf("failed checkpoint should not be recorded, got %d", last)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("failed checkpoint was not reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// No further checkpoints are attempted after a failure.
+	checkpoints.committed(1)
+}
@@ -11,11 +11,15 @@
 package carmen
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 	"github.com/Fantom-foundation/Carmen/go/state"
 )
 
@@ -23,6 +27,12 @@ const errDbClosed = common.ConstError("database is already closed")
 const errBlockContextRunning = common.ConstError("block context is running")
 const errTransactionRunning = common.ConstError("transaction is running")
 
+// archivePollPeriod is the period in which the archive height is checked
+// while waiting for the archive of states not supporting notifications.
+const archivePollPeriod = 10 * time.Millisecond
+
+var errHeadBlockNotSupported = fmt.Errorf("%w: head block tracking is not supported by this database", UnsupportedConfiguration)
+
 func openDatabase(
 	directory string,
 	configuration Configuration,
@@ -40,23 +50,70 @@ func openDatabase(
 	if err != nil {
 		return nil, err
 	}
+	archiveQueueSize, err := properties.GetInteger(ArchiveQueueSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	if archiveQueueSize < 0 {
+		return nil, fmt.Errorf("invalid value for '%s' property: %d", ArchiveQueueSize, archiveQueueSize)
+	}
+	checkpoints, err := getCheckpointPolicy(properties)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := properties.GetInteger(MemoryBudget, 0)
+	if err != nil {
+		return nil, err
+	}
+	if budget < 0 {
+		return nil, fmt.Errorf("invalid value for '%s' property: %d", MemoryBudget, budget)
+	}
+	historicViewCache, err := properties.GetInteger(HistoricViewCache, 0)
+	if err != nil {
+		return nil, err
+	}
+	if historicViewCache < 0 {
+		return nil, fmt.Errorf("invalid value for '%s' property: %d", HistoricViewCache, historicViewCache)
+	}
+	stockChecksums, err := properties.GetInteger(StockChecksums, 0)
+	if err != nil {
+		return nil, err
+	}
+	if budget > 0 {
+		// Node caches are created with an even share of the budget unless
+		// limited explicitly, such that the budget is not exceeded before
+		// the first rebalancing assigns their actual size.
+		share := budget / numBudgetedCaches
+		if liveCache <= 0 || liveCache > share {
+			liveCache = share
+		}
+		if archiveCache <= 0 || archiveCache > share {
+			archiveCache = share
+		}
+		if storageCache <= 0 {
+			storageCache = initialBudgetedStorageCacheSize
+		}
+	}
 	params := state.Parameters{
-		Directory:    directory,
-		Variant:      state.Variant(configuration.Variant),
-		Schema:       state.Schema(configuration.Schema),
-		Archive:      state.ArchiveType(configuration.Archive),
-		LiveCache:    int64(liveCache),
-		ArchiveCache: int64(archiveCache),
+		Directory:         directory,
+		Variant:           state.Variant(configuration.Variant),
+		Schema:            state.Schema(configuration.Schema),
+		Archive:           state.ArchiveType(configuration.Archive),
+		LiveCache:         int64(liveCache),
+		ArchiveCache:      int64(archiveCache),
+		ArchiveQueueSize:  archiveQueueSize,
+		HistoricViewCache: historicViewCache,
+		StockChecksums:    stockChecksums != 0,
 	}
 	db, err := state.NewState(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 	statedb := state.CreateCustomStateDBUsing(db, storageCache)
-	return openStateDb(db, statedb)
+	return openStateDb(directory, db, statedb, checkpoints, uint64(budget))
 }
 
-func openStateDb(db state.State, statedb state.StateDB) (Database, error) {
+func openStateDb(directory string, db state.State, statedb state.StateDB, checkpoints checkpointPolicy, budgetSize uint64) (Database, error) {
 	lastBlock, empty, err := statedb.GetArchiveBlockHeight()
 	if err != nil && !errors.Is(err, state.NoArchiveError) {
 		return nil, errors.Join(
@@ -70,16 +127,72 @@ func openStateDb(db state.State, statedb state.StateDB) (Database, error) {
 	if empty || errors.Is(err, state.NoArchiveError) {
 		lastBlockSig = -1
 	}
+
+	// The head block of the LiveDB is persisted independently of the archive,
+	// which may also lag behind the head state.
+	if source, ok := state.UnsafeUnwrapSyncedState(db).(headBlockSource); ok {
+		head, found, err := source.GetHeadBlock()
+		if err != nil && !errors.Is(err, state.UnsupportedConfiguration) {
+			return nil, errors.Join(
+				fmt.Errorf("cannot get head block: %w", err),
+				statedb.Close(),
+				db.Close(),
+			)
+		}
+		if found && int64(head.Number) > lastBlockSig {
+			lastBlockSig = int64(head.Number)
+		}
+	}
+	var budget *memoryBudget
+	if budgetSize > 0 {
+		budget = newMemoryBudget(budgetSize)
+		if err := budget.rebalance(db, statedb); err != nil {
+			// Closing the state DB also closes the underlying state.
+			return nil, errors.Join(err, statedb.Close())
+		}
+	}
+	checkpointer, err := newCheckpointer(checkpoints, db, lastBlockSig)
+	if err != nil {
+		return nil, errors.Join(err, statedb.Close(), db.Close())
+	}
+	var feed *updateFeed
+	if source, ok := state.UnsafeUnwrapSyncedState(db).(updateSource); ok {
+		feed = newUpdateFeed(directory, db, source, lastBlockSig)
+	}
 	return &database{
-		db:        db,
-		state:     statedb,
-		lastBlock: lastBlockSig,
+		db:          db,
+		state:       statedb,
+		feed:        feed,
+		checkpoints: checkpointer,
+		budget:      budget,
+		lastBlock:   lastBlockSig,
 	}, nil
 }
 
+// witnessSource is implemented by states supporting the recording of witnesses.
+type witnessSource interface {
+	StartWitnessRecording() (*mpt.WitnessRecorder, error)
+}
+
+// headBlockSource is implemented by states tracking the last applied block.
+type headBlockSource interface {
+	SetNextBlockRecord(record []byte) error
+	GetHeadBlock() (block mpt.HeadBlock, found bool, err error)
+}
+
+// archiveWaiter is implemented by states able to wait for their archive.
+type archiveWaiter interface {
+	WaitForArchiveBlock(ctx context.Context, block uint64) error
+	GetArchiveQueueDepth() (depth int, capacity int, err error)
+}
+
 type database struct {
 	db    state.State
 	state state.StateDB
+	feed  *updateFeed // < nil if updates can not be subscribed to
+
+	checkpoints *checkpointer // < nil if checkpoints are not tracked
+	budget      *memoryBudget // < nil if cache sizes are not managed
 
 	lock           sync.Mutex
 	headStateInUse bool
@@ -106,6 +219,14 @@ func (db *database) QueryHeadState(query func(QueryContext)) error {
 }
 
 func (db *database) BeginBlock(block uint64) (HeadBlockContext, error) {
+	return db.beginBlock(block, false)
+}
+
+func (db *database) BeginBlockWithWitness(block uint64) (WitnessBlockContext, error) {
+	return db.beginBlock(block, true)
+}
+
+func (db *database) beginBlock(block uint64, recordWitness bool) (*headBlockContext, error) {
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
@@ -121,14 +242,38 @@ func (db *database) BeginBlock(block uint64) (HeadBlockContext, error) {
 		return nil, fmt.Errorf("block is not greater than last block: lastBlock: %d >= block: %d", db.lastBlock, block)
 	}
 
-	db.headStateInUse = true
-	return &headBlockContext{
+	context := &headBlockContext{
 		commonContext: commonContext{
 			db: db,
 		},
 		block: int64(block),
 		state: db.state,
-	}, nil
+	}
+	if recordWitness {
+		source, ok := state.UnsafeUnwrapSyncedState(db.db).(witnessSource)
+		if !ok {
+			return nil, fmt.Errorf("%w: witnesses are not supported by this database", UnsupportedConfiguration)
+		}
+		resetter, ok := db.state.(cacheResetter)
+		if !ok {
+			return nil, fmt.Errorf("%w: the caches of the head state can not be reset", UnsupportedConfiguration)
+		}
+		// Exclusive access to the head state is required to not interfere
+		// with concurrent head state queries.
+		db.headStateCommitLock.Lock()
+		recorder, err := source.StartWitnessRecording()
+		db.headStateCommitLock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		// The state DB caches values read in previous blocks. To make sure
+		// all values read by the block are recorded, its caches are reset.
+		resetter.ResetCaches()
+		context.recorder = recorder
+	}
+
+	db.headStateInUse = true
+	return context, nil
 }
 
 func (db *database) AddBlock(block uint64, run func(HeadBlockContext) error) error {
@@ -145,7 +290,11 @@ func (db *database) AddBlock(block uint64, run func(HeadBlockContext) error) err
 }
 
 func (db *database) QueryBlock(block uint64, run func(HistoricBlockContext) error) error {
-	ctxt, err := db.GetHistoricContext(block)
+	return db.QueryBlockWithContext(context.Background(), block, run)
+}
+
+func (db *database) QueryBlockWithContext(ctx context.Context, block uint64, run func(HistoricBlockContext) error) error {
+	ctxt, err := db.GetHistoricContextWithContext(ctx, block)
 	if err != nil {
 		return fmt.Errorf("failed to start block %d: %w", block, err)
 	}
@@ -156,6 +305,28 @@ func (db *database) QueryBlock(block uint64, run func(HistoricBlockContext) erro
 	)
 }
 
+func (db *database) GetHeadBlock() (HeadBlock, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return HeadBlock{}, errDbClosed
+	}
+
+	source, ok := state.UnsafeUnwrapSyncedState(db.db).(headBlockSource)
+	if !ok {
+		return HeadBlock{}, errHeadBlockNotSupported
+	}
+	head, found, err := source.GetHeadBlock()
+	if err != nil || !found {
+		return HeadBlock{Number: -1}, err
+	}
+	return HeadBlock{
+		Number: int64(head.Number),
+		Record: bytes.Clone(head.Record),
+	}, nil
+}
+
 func (db *database) GetArchiveBlockHeight() (int64, error) {
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -174,6 +345,53 @@ func (db *database) GetArchiveBlockHeight() (int64, error) {
 	return int64(height), err
 }
 
+func (db *database) WaitForArchiveBlock(ctx context.Context, block uint64) error {
+	db.lock.Lock()
+	if db.db == nil {
+		db.lock.Unlock()
+		return errDbClosed
+	}
+	s := db.db
+	db.lock.Unlock()
+	return waitForArchiveBlock(ctx, s, block)
+}
+
+// waitForArchiveBlock blocks until the archive of the given state contains the
+// given block or the context is done.
+func waitForArchiveBlock(ctx context.Context, s state.State, block uint64) error {
+	if waiter, ok := state.UnsafeUnwrapSyncedState(s).(archiveWaiter); ok {
+		return waiter.WaitForArchiveBlock(ctx, block)
+	}
+	for {
+		height, empty, err := s.GetArchiveBlockHeight()
+		if err != nil {
+			return err
+		}
+		if !empty && height >= block {
+			return nil
+		}
+		select {
+		case <-time.After(archivePollPeriod):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (db *database) GetArchiveQueueDepth() (depth int, capacity int, err error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return 0, 0, errDbClosed
+	}
+	waiter, ok := state.UnsafeUnwrapSyncedState(db.db).(archiveWaiter)
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: archive queue depth is not reported by this database", UnsupportedConfiguration)
+	}
+	return waiter.GetArchiveQueueDepth()
+}
+
 func (db *database) GetHistoricStateHash(block uint64) (Hash, error) {
 	var hash Hash
 	err := db.QueryHistoricState(block, func(ctxt QueryContext) {
@@ -183,7 +401,11 @@ func (db *database) GetHistoricStateHash(block uint64) (Hash, error) {
 }
 
 func (db *database) QueryHistoricState(block uint64, query func(QueryContext)) error {
-	return db.QueryBlock(block, func(ctxt HistoricBlockContext) error {
+	return db.QueryHistoricStateWithContext(context.Background(), block, query)
+}
+
+func (db *database) QueryHistoricStateWithContext(ctx context.Context, block uint64, query func(QueryContext)) error {
+	return db.QueryBlockWithContext(ctx, block, func(ctxt HistoricBlockContext) error {
 		return ctxt.RunTransaction(func(ctxt TransactionContext) error {
 			query(ctxt.(*transactionContext))
 			return nil
@@ -192,6 +414,30 @@ func (db *database) QueryHistoricState(block uint64, query func(QueryContext)) e
 }
 
 func (db *database) GetHistoricContext(block uint64) (HistoricBlockContext, error) {
+	return db.GetHistoricContextWithContext(context.Background(), block)
+}
+
+func (db *database) GetHistoricContextWithContext(ctx context.Context, block uint64) (HistoricBlockContext, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := db.getHistoricState(block)
+	if err != nil {
+		return nil, err
+	}
+	s = state.WrapIntoContextState(ctx, s)
+
+	return &archiveBlockContext{
+		commonContext: commonContext{
+			db: db,
+		},
+		state: state.CreateNonCommittableStateDBUsing(s)}, err
+}
+
+// getHistoricState obtains the state of the given historic block. On success,
+// the state is registered as an active archive query, which needs to be
+// released using releaseArchiveQuery.
+func (db *database) getHistoricState(block uint64) (state.State, error) {
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
@@ -203,14 +449,21 @@ func (db *database) GetHistoricContext(block uint64) (HistoricBlockContext, erro
 	if err != nil {
 		return nil, err
 	}
-
 	db.numQueries++
+	return s, nil
+}
 
-	return &archiveBlockContext{
-		commonContext: commonContext{
-			db: db,
-		},
-		state: state.CreateNonCommittableStateDBUsing(s)}, err
+func (db *database) SubscribeUpdates(name string) (UpdateSubscription, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return nil, errDbClosed
+	}
+	if db.feed == nil {
+		return nil, fmt.Errorf("%w: update subscriptions are not supported by this database", UnsupportedConfiguration)
+	}
+	return db.feed.subscribe(name)
 }
 
 func (db *database) StartBulkLoad(block uint64) (BulkLoad, error) {
@@ -250,7 +503,40 @@ func (db *database) flush() error {
 		return errDbClosed
 	}
 
-	return db.state.Flush()
+	if err := db.state.Flush(); err != nil {
+		return err
+	}
+	if db.checkpoints != nil {
+		db.checkpoints.flushed(db.lastBlock)
+	}
+	return nil
+}
+
+func (db *database) GetLastCheckpointBlock() (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return 0, errDbClosed
+	}
+	if db.checkpoints == nil {
+		return -1, nil
+	}
+	return db.checkpoints.getLastCheckpoint()
+}
+
+func (db *database) GetDiskFootprint() (DiskFootprint, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return DiskFootprint{}, errDbClosed
+	}
+	footprint, err := db.db.GetDiskFootprint()
+	if err != nil {
+		return DiskFootprint{}, err
+	}
+	return toDiskFootprint(footprint), nil
 }
 
 func (db *database) Close() error {
@@ -265,6 +551,13 @@ func (db *database) Close() error {
 		return errBlockContextRunning
 	}
 
+	if db.feed != nil {
+		db.feed.close()
+	}
+	if db.checkpoints != nil {
+		db.checkpoints.close()
+	}
+
 	if err := db.flush(); err != nil {
 		return err
 	}
@@ -278,10 +571,41 @@ func (db *database) Close() error {
 	return nil
 }
 
-func (db *database) moveBlockNumber(block int64) {
+func (db *database) moveBlockNumber(block int64) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
 	db.lastBlock = block
+	if db.checkpoints != nil {
+		db.checkpoints.committed(block)
+	}
+	return db.rebalanceMemoryHoldingLock()
+}
+
+// rebalanceMemoryHoldingLock redistributes the memory budget of this database
+// among its caches, if enabled. It is called after committing blocks, when
+// the head state DB is not in use. The caller needs to hold the lock.
+func (db *database) rebalanceMemoryHoldingLock() error {
+	if db.budget == nil {
+		return nil
+	}
+	if err := db.budget.rebalance(db.db, db.state); err != nil {
+		return fmt.Errorf("failed to rebalance memory budget: %w", err)
+	}
+	return nil
+}
+
+// cacheResetter is implemented by state DBs able to drop the values cached
+// from previous blocks.
+type cacheResetter interface {
+	ResetCaches()
+}
+
+// publishUpdates delivers the updates applied by the last commit to the
+// update subscriptions of the database.
+func (db *database) publishUpdates() {
+	if db.feed != nil {
+		db.feed.publish()
+	}
 }
 
 func (db *database) releaseHeadState() {
@@ -296,9 +620,13 @@ func (db *database) releaseArchiveQuery() {
 	db.numQueries--
 }
 
-func (db *database) moveBlockAndReleaseHead(block int64) {
+func (db *database) moveBlockAndReleaseHead(block int64) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
 	db.headStateInUse = false
 	db.lastBlock = block
+	if db.checkpoints != nil {
+		db.checkpoints.committed(block)
+	}
+	return db.rebalanceMemoryHoldingLock()
 }
@@ -11,6 +11,7 @@
 package carmen
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -277,7 +278,7 @@ func TestDatabase_OpeningArchiveFails(t *testing.T) {
 	stateDB.EXPECT().GetArchiveBlockHeight().Return(uint64(0), false, injectedErr)
 	stateDB.EXPECT().Close()
 
-	if _, err := openStateDb(state, stateDB); !errors.Is(err, injectedErr) {
+	if _, err := openStateDb("", state, stateDB, checkpointPolicy{}, 0); !errors.Is(err, injectedErr) {
 		t.Errorf("opening archive should fail")
 	}
 }
@@ -299,6 +300,26 @@ func TestDatabase_OpenFailsForInvalidProperty(t *testing.T) {
 			property: StorageCache,
 			value:    "hello",
 		},
+		"MemoryBudget-not-an-int": {
+			property: MemoryBudget,
+			value:    "hello",
+		},
+		"MemoryBudget-negative": {
+			property: MemoryBudget,
+			value:    "-1",
+		},
+		"MemoryBudget-too-small": {
+			property: MemoryBudget,
+			value:    "1024",
+		},
+		"HistoricViewCache-not-an-int": {
+			property: HistoricViewCache,
+			value:    "hello",
+		},
+		"HistoricViewCache-negative": {
+			property: HistoricViewCache,
+			value:    "-1",
+		},
 	}
 
 	for name, test := range tests {
@@ -1640,3 +1661,304 @@ func TestDatabase_ArchiveCanBeAccessedAsync(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestDatabase_HeadBlockIsPersistedWithoutArchive(t *testing.T) {
+	dir := t.TempDir()
+	config := GetCarmenGoS5WithoutArchiveConfiguration()
+	db, err := OpenDatabase(dir, config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if head, err := db.GetHeadBlock(); err != nil || head.Number != -1 || head.Record != nil {
+		t.Errorf("unexpected head block of empty database: %v, err %v", head, err)
+	}
+
+	ctxt, err := db.BeginBlock(5)
+	if err != nil {
+		t.Fatalf("cannot begin block: %v", err)
+	}
+	if err := ctxt.SetBlockRecord([]byte("block 5")); err != nil {
+		t.Fatalf("cannot set block record: %v", err)
+	}
+	if err := ctxt.Commit(); err != nil {
+		t.Fatalf("cannot commit block: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	db, err = OpenDatabase(dir, config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	head, err := db.GetHeadBlock()
+	if err != nil {
+		t.Fatalf("failed to get head block: %v", err)
+	}
+	if head.Number != 5 || string(head.Record) != "block 5" {
+		t.Errorf("unexpected head block %d with record %q", head.Number, head.Record)
+	}
+
+	// Blocks not exceeding the persisted head block are rejected.
+	for _, block := range []uint64{3, 5} {
+		if _, err := db.BeginBlock(block); err == nil {
+			t.Errorf("beginning block %d should fail", block)
+		}
+	}
+	if err := db.AddBlock(6, func(HeadBlockContext) error { return nil }); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if head, err := db.GetHeadBlock(); err != nil || head.Number != 6 || head.Record != nil {
+		t.Errorf("unexpected head block: %v, err %v", head, err)
+	}
+}
+
+func TestDatabase_HeadBlockIsNotSupportedByAllConfigurations(t *testing.T) {
+	config := Configuration{Variant: "go-file", Schema: 1, Archive: "none"}
+	db, err := OpenDatabase(t.TempDir(), config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetHeadBlock(); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+	ctxt, err := db.BeginBlock(1)
+	if err != nil {
+		t.Fatalf("cannot begin block: %v", err)
+	}
+	defer ctxt.Abort()
+	if err := ctxt.SetBlockRecord([]byte{1}); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+}
+
+func TestDatabase_QueryHistoricStateWithContext_CancellationAbortsQueryWithoutAffectingDatabase(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	addr := Address{1}
+	if err := db.AddBlock(0, func(context HeadBlockContext) error {
+		return context.RunTransaction(func(context TransactionContext) error {
+			context.CreateAccount(addr)
+			context.AddBalance(addr, NewAmount(12))
+			return nil
+		})
+	}); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = db.QueryHistoricStateWithContext(ctx, 0, func(query QueryContext) {
+		if got, want := query.GetBalance(addr), NewAmount(12); got != want {
+			t.Errorf("unexpected balance, wanted %v, got %v", want, got)
+		}
+		cancel()
+		query.GetNonce(addr)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+
+	if err := db.QueryHistoricStateWithContext(ctx, 0, func(QueryContext) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+
+	// The database remains usable.
+	if err := db.QueryHistoricState(0, func(query QueryContext) {
+		if got, want := query.GetBalance(addr), NewAmount(12); got != want {
+			t.Errorf("unexpected balance, wanted %v, got %v", want, got)
+		}
+	}); err != nil {
+		t.Errorf("failed to query historic state: %v", err)
+	}
+	if err := db.AddBlock(1, func(HeadBlockContext) error { return nil }); err != nil {
+		t.Errorf("failed to add block: %v", err)
+	}
+}
+
+func TestDatabase_QueryHistoricStateWithContext_DeadlineIsReported(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.AddBlock(0, func(HeadBlockContext) error { return nil }); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	err = db.QueryBlockWithContext(ctx, 0, func(HistoricBlockContext) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestDatabase_WaitForArchiveBlock_ArchivedBlocksCanBeQueried(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := db.AddBlock(uint64(i), func(HeadBlockContext) error { return nil }); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+		if err := db.WaitForArchiveBlock(context.Background(), uint64(i)); err != nil {
+			t.Fatalf("failed to wait for archive: %v", err)
+		}
+		if err := db.QueryBlock(uint64(i), func(HistoricBlockContext) error { return nil }); err != nil {
+			t.Errorf("failed to query archived block %d: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := db.WaitForArchiveBlock(ctx, 5); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestDatabase_WaitForArchiveBlock_RequiresArchive(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), GetCarmenGoS5WithoutArchiveConfiguration(), testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.WaitForArchiveBlock(context.Background(), 0); !errors.Is(err, state.NoArchiveError) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.NoArchiveError, err)
+	}
+}
+
+func TestDatabase_GetArchiveQueueDepth_ReportsConfiguredCapacity(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(ArchiveQueueSize, 42)
+	db, err := OpenDatabase(t.TempDir(), testConfig, properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	depth, capacity, err := db.GetArchiveQueueDepth()
+	if err != nil {
+		t.Fatalf("failed to get archive queue depth: %v", err)
+	}
+	if depth != 0 || capacity != 42 {
+		t.Errorf("unexpected archive queue state, wanted depth 0 and capacity 42, got %d and %d", depth, capacity)
+	}
+}
+
+func TestDatabase_ArchiveQueueSize_NegativeSizesAreRejected(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(ArchiveQueueSize, -1)
+	if _, err := OpenDatabase(t.TempDir(), testConfig, properties); err == nil {
+		t.Errorf("opening a database with a negative archive queue size should fail")
+	}
+}
+
+func TestDatabase_WaitForArchiveBlock_ClosedDB(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if err := db.WaitForArchiveBlock(context.Background(), 0); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+	if _, _, err := db.GetArchiveQueueDepth(); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+}
+
+func TestDatabase_GetDiskFootprint_CoversLiveDbAndArchive(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	addBalanceInBlock(t, db, 1, Address{1}, true)
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+
+	footprint, err := db.GetDiskFootprint()
+	if err != nil {
+		t.Fatalf("failed to get disk footprint: %v", err)
+	}
+	for _, name := range []string{"live", "archive"} {
+		if child, found := footprint.Children[name]; !found || child.Total() == 0 {
+			t.Errorf("missing disk usage of %s in %v", name, footprint)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if _, err := db.GetDiskFootprint(); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error for closed database: %v", err)
+	}
+}
+
+func TestDatabase_GetDiskFootprint_ForwardsStateErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	state := state.NewMockState(ctrl)
+
+	injectedErr := fmt.Errorf("injectedErr")
+	state.EXPECT().GetDiskFootprint().Return(nil, injectedErr)
+
+	db := &database{db: state}
+	if _, err := db.GetDiskFootprint(); !errors.Is(err, injectedErr) {
+		t.Errorf("unexpected error: %v != %v", err, injectedErr)
+	}
+}
+
+func TestDatabase_HistoricViewCache_QueriesObserveArchivedState(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(HistoricViewCache, 2)
+	db, err := OpenDatabase(t.TempDir(), testConfig, properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	const numBlocks = 5
+	for i := 0; i < numBlocks; i++ {
+		addBalanceInBlock(t, db, uint64(i), Address{1}, i == 0)
+	}
+	if err := db.WaitForArchiveBlock(context.Background(), numBlocks-1); err != nil {
+		t.Fatalf("failed to wait for archive: %v", err)
+	}
+
+	// Repeated queries on the same blocks are served by shared views.
+	for round := 0; round < 3; round++ {
+		for i := 0; i < numBlocks; i++ {
+			err := db.QueryHistoricState(uint64(i), func(context QueryContext) {
+				if want, got := NewAmount(uint64(i+1)), context.GetBalance(Address{1}); want != got {
+					t.Errorf("unexpected balance at block %d, wanted %v, got %v", i, want, got)
+				}
+			})
+			if err != nil {
+				t.Fatalf("failed to query block %d: %v", i, err)
+			}
+		}
+	}
+}
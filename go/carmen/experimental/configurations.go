@@ -14,8 +14,9 @@ import (
 	"github.com/Fantom-foundation/Carmen/go/carmen"
 	"github.com/Fantom-foundation/Carmen/go/state"
 
+	"github.com/Fantom-foundation/Carmen/go/state/gostate"
+
 	_ "github.com/Fantom-foundation/Carmen/go/state/cppstate"
-	_ "github.com/Fantom-foundation/Carmen/go/state/gostate"
 )
 
 // GetDatabaseConfigurations returns a list of experimental database configurations
@@ -36,6 +37,29 @@ func GetDatabaseConfigurations() []carmen.Configuration {
 	return res
 }
 
+// GetBinaryTrieWithoutArchiveConfiguration returns the configuration of the
+// experimental file based binary trie schema following EIP-7864 without
+// Archive features. It is intended for comparing state and proof sizes with
+// the MPT based schemas.
+func GetBinaryTrieWithoutArchiveConfiguration() carmen.Configuration {
+	return carmen.Configuration{
+		Variant: carmen.Variant(gostate.VariantGoFile),
+		Schema:  carmen.Schema(6),
+		Archive: carmen.Archive(state.NoArchive),
+	}
+}
+
+// GetBinaryTrieWithArchiveConfiguration returns the configuration of the
+// experimental file based binary trie schema following EIP-7864 with a binary
+// trie based Archive.
+func GetBinaryTrieWithArchiveConfiguration() carmen.Configuration {
+	return carmen.Configuration{
+		Variant: carmen.Variant(gostate.VariantGoFile),
+		Schema:  carmen.Schema(6),
+		Archive: carmen.Archive(state.S6Archive),
+	}
+}
+
 func init() {
 	for _, config := range GetDatabaseConfigurations() {
 		carmen.RegisterConfiguration(config)
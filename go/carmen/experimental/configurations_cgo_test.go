@@ -0,0 +1,29 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+//go:build cgo && !purego
+
+package experimental_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/carmen/experimental"
+)
+
+func TestConfigurations_ContainCppImplementations(t *testing.T) {
+	for _, config := range experimental.GetDatabaseConfigurations() {
+		if strings.HasPrefix(string(config.Variant), "cpp") {
+			return
+		}
+	}
+	t.Errorf("missing C++ based implementations")
+}
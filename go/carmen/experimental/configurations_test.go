@@ -19,23 +19,16 @@ import (
 	"github.com/Fantom-foundation/Carmen/go/carmen/experimental"
 )
 
-func TestConfigurations_ContainGoAndCppImplementations(t *testing.T) {
+func TestConfigurations_ContainGoImplementations(t *testing.T) {
 	goSeen := false
-	cppSeen := false
 	for _, config := range experimental.GetDatabaseConfigurations() {
 		if strings.HasPrefix(string(config.Variant), "go") {
 			goSeen = true
 		}
-		if strings.HasPrefix(string(config.Variant), "cpp") {
-			cppSeen = true
-		}
 	}
 	if !goSeen {
 		t.Errorf("missing Go based implementations")
 	}
-	if !cppSeen {
-		t.Errorf("missing C++ based implementations")
-	}
 }
 
 func TestConfigurations_ConfigurationsAreRegisteredGlobally(t *testing.T) {
@@ -47,6 +40,19 @@ func TestConfigurations_ConfigurationsAreRegisteredGlobally(t *testing.T) {
 	}
 }
 
+func TestConfigurations_BinaryTrieConfigurationsAreRegistered(t *testing.T) {
+	registeredConfigs := carmen.GetAllConfigurations()
+	configs := []carmen.Configuration{
+		experimental.GetBinaryTrieWithoutArchiveConfiguration(),
+		experimental.GetBinaryTrieWithArchiveConfiguration(),
+	}
+	for _, config := range configs {
+		if !slices.Contains(registeredConfigs, config) {
+			t.Errorf("missing registration of configuration %v", config)
+		}
+	}
+}
+
 func TestConfiguration_RegisteredConfigurationsCanBeUsed(t *testing.T) {
 	for _, config := range carmen.GetAllConfigurations() {
 		config := config
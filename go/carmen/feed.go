@@ -0,0 +1,362 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// updateFeedBufferSize is the number of block updates buffered per
+// subscription before commits are blocked.
+const updateFeedBufferSize = 16
+
+// updateFeedDirectory is the sub-directory of the database directory in
+// which the cursors of update subscriptions are stored.
+const updateFeedDirectory = "feeds"
+
+var subscriptionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// updateSource is implemented by states reporting applied updates.
+type updateSource interface {
+	SetUpdateListener(listener func(block uint64, update *common.Update))
+	GetArchiveUpdate(block uint64) (common.Update, error)
+}
+
+// pendingUpdate is an update applied to the state but not yet published.
+type pendingUpdate struct {
+	block  uint64
+	update *common.Update
+}
+
+// updateFeed distributes the updates applied to the state of a database to
+// its subscriptions. Updates are recorded while being applied by a block
+// commit and published once the head state is released by the commit.
+type updateFeed struct {
+	directory string       // < the database directory
+	state     state.State  // < the state for accessing the archive height
+	source    updateSource // < the state for reconstructing archived updates
+	mutex     sync.Mutex   // < protects the fields below
+	head      int64        // < the last published block, -1 if there is none
+	pending   []pendingUpdate
+	subs      map[string]*updateSubscription
+}
+
+func newUpdateFeed(directory string, db state.State, source updateSource, head int64) *updateFeed {
+	feed := &updateFeed{
+		directory: directory,
+		state:     db,
+		source:    source,
+		head:      head,
+		subs:      map[string]*updateSubscription{},
+	}
+	source.SetUpdateListener(feed.record)
+	return feed
+}
+
+// record is the update listener registered at the state.
+func (f *updateFeed) record(block uint64, update *common.Update) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.pending = append(f.pending, pendingUpdate{block, update})
+}
+
+// publish delivers all recorded updates to the current subscriptions. The
+// call blocks until all subscriptions have accepted the updates.
+func (f *updateFeed) publish() {
+	f.mutex.Lock()
+	pending := f.pending
+	f.pending = nil
+	if len(pending) > 0 {
+		f.head = int64(pending[len(pending)-1].block)
+	}
+	subs := make([]*updateSubscription, 0, len(f.subs))
+	for _, sub := range f.subs {
+		subs = append(subs, sub)
+	}
+	f.mutex.Unlock()
+
+	if len(subs) == 0 {
+		return
+	}
+	for _, cur := range pending {
+		update := toBlockUpdate(cur.block, cur.update)
+		for _, sub := range subs {
+			sub.deliver(update)
+		}
+	}
+}
+
+func (f *updateFeed) subscribe(name string) (*updateSubscription, error) {
+	if !subscriptionNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid subscription name %q", name)
+	}
+	if f.directory == "" {
+		return nil, fmt.Errorf("%w: update subscriptions require a database directory", UnsupportedConfiguration)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, found := f.subs[name]; found {
+		return nil, fmt.Errorf("subscription %q is already active", name)
+	}
+
+	file := filepath.Join(f.directory, updateFeedDirectory, name+".json")
+	cursor, found, err := readCursor(file)
+	if err != nil {
+		return nil, err
+	}
+	from := uint64(f.head + 1)
+	if found {
+		if int64(cursor) > f.head {
+			return nil, fmt.Errorf("cursor of subscription %q at block %d is beyond the head block %d", name, cursor, f.head)
+		}
+		from = cursor + 1
+	}
+	if int64(from) <= f.head {
+		// Missed blocks are reconstructed from the archive.
+		if _, _, err := f.state.GetArchiveBlockHeight(); err != nil {
+			return nil, fmt.Errorf("cannot resume subscription %q: %w", name, err)
+		}
+	}
+
+	sub := &updateSubscription{
+		feed:    f,
+		name:    name,
+		file:    file,
+		cursor:  cursor,
+		hasAck:  found,
+		live:    make(chan BlockUpdate, updateFeedBufferSize),
+		updates: make(chan BlockUpdate),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	f.subs[name] = sub
+	go sub.run(from, f.head)
+	return sub, nil
+}
+
+func (f *updateFeed) unsubscribe(sub *updateSubscription) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.subs[sub.name] == sub {
+		delete(f.subs, sub.name)
+	}
+}
+
+// close ends all subscriptions of this feed.
+func (f *updateFeed) close() {
+	f.mutex.Lock()
+	subs := make([]*updateSubscription, 0, len(f.subs))
+	for _, sub := range f.subs {
+		subs = append(subs, sub)
+	}
+	f.mutex.Unlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+type updateSubscription struct {
+	feed    *updateFeed
+	name    string
+	file    string           // < the file storing the cursor
+	live    chan BlockUpdate // < updates published by commits
+	updates chan BlockUpdate // < updates forwarded to the consumer
+	closed  chan struct{}    // < closed when the subscription is closed
+	done    chan struct{}    // < closed when the delivery routine is done
+	once    sync.Once
+
+	mutex  sync.Mutex // < protects the fields below
+	cursor uint64
+	hasAck bool
+	err    error
+}
+
+func (s *updateSubscription) Updates() <-chan BlockUpdate {
+	return s.updates
+}
+
+func (s *updateSubscription) Acknowledge(block uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.hasAck && block < s.cursor {
+		return fmt.Errorf("cannot move cursor of subscription %q back from block %d to %d", s.name, s.cursor, block)
+	}
+	if err := writeCursor(s.file, block); err != nil {
+		return err
+	}
+	s.cursor = block
+	s.hasAck = true
+	return nil
+}
+
+func (s *updateSubscription) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+func (s *updateSubscription) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+		s.feed.unsubscribe(s)
+	})
+	<-s.done
+	return nil
+}
+
+// deliver hands the given update to the delivery routine of the subscription,
+// blocking while its buffer is full.
+func (s *updateSubscription) deliver(update BlockUpdate) {
+	select {
+	case s.live <- update:
+	case <-s.closed:
+	}
+}
+
+// run is the delivery routine of the subscription, reconstructing updates of
+// the blocks in the range [from, to] from the archive before forwarding
+// published updates.
+func (s *updateSubscription) run(from uint64, to int64) {
+	defer close(s.done)
+	defer close(s.updates)
+
+	if int64(from) <= to {
+		if err := s.replay(from, uint64(to)); err != nil {
+			s.mutex.Lock()
+			s.err = err
+			s.mutex.Unlock()
+			return
+		}
+	}
+
+	for {
+		select {
+		case update := <-s.live:
+			if !s.forward(update) {
+				return
+			}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *updateSubscription) replay(from, to uint64) error {
+	// The archive is updated asynchronously and may lag behind the head state.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := waitForArchiveBlock(ctx, s.feed.state, to); err != nil {
+		if ctx.Err() != nil {
+			return nil // < the subscription got closed
+		}
+		return err
+	}
+	for block := from; block <= to; block++ {
+		update, err := s.feed.source.GetArchiveUpdate(block)
+		if err != nil {
+			return fmt.Errorf("failed to reconstruct update of block %d: %w", block, err)
+		}
+		if !s.forward(toBlockUpdate(block, &update)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// forward hands the given update to the consumer. It returns false if the
+// subscription got closed instead.
+func (s *updateSubscription) forward(update BlockUpdate) bool {
+	select {
+	case s.updates <- update:
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+// cursorData is the persistent format of a subscription cursor.
+type cursorData struct {
+	Block uint64
+}
+
+func readCursor(file string) (block uint64, found bool, err error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	var cursor cursorData
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return 0, false, fmt.Errorf("invalid cursor file %s: %w", file, err)
+	}
+	return cursor.Block, true, nil
+}
+
+func writeCursor(file string, block uint64) error {
+	data, err := json.Marshal(cursorData{Block: block})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	// The cursor is replaced atomically to survive crashes while writing.
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func toBlockUpdate(block uint64, update *common.Update) BlockUpdate {
+	res := BlockUpdate{Block: block}
+	for _, addr := range update.DeletedAccounts {
+		res.DeletedAccounts = append(res.DeletedAccounts, Address(addr))
+	}
+	for _, addr := range update.CreatedAccounts {
+		res.CreatedAccounts = append(res.CreatedAccounts, Address(addr))
+	}
+	for _, cur := range update.Balances {
+		res.Balances = append(res.Balances, BalanceUpdate{Address(cur.Account), NewAmountFromBytes(cur.Balance[:]...)})
+	}
+	for _, cur := range update.Nonces {
+		res.Nonces = append(res.Nonces, NonceUpdate{Address(cur.Account), cur.Nonce.ToUint64()})
+	}
+	for _, cur := range update.Codes {
+		res.Codes = append(res.Codes, CodeUpdate{Address(cur.Account), bytes.Clone(cur.Code)})
+	}
+	for _, cur := range update.Slots {
+		res.Slots = append(res.Slots, SlotUpdate{Address(cur.Account), Key(cur.Key), Value(cur.Value)})
+	}
+	return res
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+func TestDatabase_SubscribeUpdates_DeliversCommittedUpdatesInOrder(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	addr := Address{1}
+	for i := 0; i < 3; i++ {
+		addBalanceInBlock(t, db, uint64(i), addr, i == 0)
+	}
+
+	for i := 0; i < 3; i++ {
+		update := <-sub.Updates()
+		if got, want := update.Block, uint64(i); got != want {
+			t.Errorf("unexpected block, wanted %d, got %d", want, got)
+		}
+		if i == 0 && !reflect.DeepEqual(update.CreatedAccounts, []Address{addr}) {
+			t.Errorf("unexpected created accounts, wanted %v, got %v", []Address{addr}, update.CreatedAccounts)
+		}
+		want := []BalanceUpdate{{Account: addr, Balance: NewAmount(uint64(i + 1))}}
+		if !reflect.DeepEqual(update.Balances, want) {
+			t.Errorf("unexpected balance updates, wanted %v, got %v", want, update.Balances)
+		}
+	}
+}
+
+func TestDatabase_SubscribeUpdates_ResumesFromAcknowledgedCursor(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDatabase(dir, testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	addr := Address{1}
+	for i := 0; i < 4; i++ {
+		addBalanceInBlock(t, db, uint64(i), addr, i == 0)
+	}
+	var live []BlockUpdate
+	for i := 0; i < 4; i++ {
+		live = append(live, <-sub.Updates())
+	}
+	if err := sub.Acknowledge(1); err != nil {
+		t.Fatalf("failed to acknowledge block: %v", err)
+	}
+	if err := sub.Acknowledge(0); err == nil {
+		t.Errorf("moving the cursor backwards should fail")
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("failed to close subscription: %v", err)
+	}
+	if _, open := <-sub.Updates(); open {
+		t.Errorf("updates should be closed after closing the subscription")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	db, err = OpenDatabase(dir, testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	sub, err = db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to resubscribe: %v", err)
+	}
+	defer sub.Close()
+
+	// Blocks 2 and 3 are reconstructed from the archive.
+	for i := 2; i < 4; i++ {
+		if got, want := <-sub.Updates(), live[i]; !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected reconstructed update\nwanted %v\n   got %v", want, got)
+		}
+	}
+
+	// Newly committed blocks are delivered afterwards.
+	addBalanceInBlock(t, db, 4, addr, false)
+	if got, want := (<-sub.Updates()).Block, uint64(4); got != want {
+		t.Errorf("unexpected block, wanted %d, got %d", want, got)
+	}
+}
+
+func TestDatabase_SubscribeUpdates_SlowConsumersBlockCommits(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	numBlocks := updateFeedBufferSize + 2
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < numBlocks; i++ {
+			addBalanceInBlock(t, db, uint64(i), Address{1}, i == 0)
+		}
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("commits should be blocked by the consumer")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for i := 0; i < numBlocks; i++ {
+		if got, want := (<-sub.Updates()).Block, uint64(i); got != want {
+			t.Errorf("unexpected block, wanted %d, got %d", want, got)
+		}
+	}
+	<-done
+}
+
+func TestDatabase_SubscribeUpdates_ClosingSubscriptionReleasesCommits(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < updateFeedBufferSize+2; i++ {
+			addBalanceInBlock(t, db, uint64(i), Address{1}, i == 0)
+		}
+	}()
+	<-sub.Updates()
+	if err := sub.Close(); err != nil {
+		t.Fatalf("failed to close subscription: %v", err)
+	}
+	<-done
+}
+
+func TestDatabase_SubscribeUpdates_InvalidSubscriptionsAreRejected(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDatabase(dir, testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for _, name := range []string{"", "a/b", "../x"} {
+		if _, err := db.SubscribeUpdates(name); err == nil {
+			t.Errorf("subscribing with name %q should fail", name)
+		}
+	}
+
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Close()
+	if _, err := db.SubscribeUpdates("indexer"); err == nil {
+		t.Errorf("subscribing twice with the same name should fail")
+	}
+
+	// A cursor beyond the head block is inconsistent with the database.
+	if err := os.MkdirAll(filepath.Join(dir, updateFeedDirectory), 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := writeCursor(filepath.Join(dir, updateFeedDirectory, "other.json"), 5); err != nil {
+		t.Fatalf("failed to write cursor: %v", err)
+	}
+	if _, err := db.SubscribeUpdates("other"); err == nil {
+		t.Errorf("subscribing with a cursor beyond the head should fail")
+	}
+}
+
+func TestDatabase_SubscribeUpdates_ResumingRequiresArchive(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), GetCarmenGoS5WithoutArchiveConfiguration(), testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	addBalanceInBlock(t, db, 0, Address{1}, true)
+	if err := sub.Acknowledge((<-sub.Updates()).Block); err != nil {
+		t.Fatalf("failed to acknowledge block: %v", err)
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("failed to close subscription: %v", err)
+	}
+
+	// Without missed blocks, no archive is needed.
+	sub, err = db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to resubscribe: %v", err)
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("failed to close subscription: %v", err)
+	}
+
+	addBalanceInBlock(t, db, 1, Address{1}, false)
+	if _, err := db.SubscribeUpdates("indexer"); !errors.Is(err, state.NoArchiveError) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.NoArchiveError, err)
+	}
+}
+
+func TestDatabase_SubscribeUpdates_OnClosedDatabaseFails(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sub, err := db.SubscribeUpdates("indexer")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if _, open := <-sub.Updates(); open {
+		t.Errorf("closing the database should end its subscriptions")
+	}
+	if _, err := db.SubscribeUpdates("indexer"); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+}
+
+func addBalanceInBlock(t *testing.T, db Database, block uint64, addr Address, create bool) {
+	t.Helper()
+	if err := db.AddBlock(block, func(context HeadBlockContext) error {
+		return context.RunTransaction(func(context TransactionContext) error {
+			if create {
+				context.CreateAccount(addr)
+			}
+			context.AddBalance(addr, NewAmount(1))
+			return nil
+		})
+	}); err != nil {
+		t.Errorf("failed to add block %d: %v", block, err)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// Total returns the number of bytes occupied by the component including all
+// its sub-components.
+func (f DiskFootprint) Total() uint64 {
+	total := f.Size
+	for _, child := range f.Children {
+		total += child.Total()
+	}
+	return total
+}
+
+// TotalReclaimable returns the number of reclaimable bytes of the component
+// including all its sub-components.
+func (f DiskFootprint) TotalReclaimable() uint64 {
+	total := f.Reclaimable
+	for _, child := range f.Children {
+		total += child.TotalReclaimable()
+	}
+	return total
+}
+
+// toDiskFootprint converts the given internal footprint into its public
+// representation.
+func toDiskFootprint(footprint *common.DiskFootprint) DiskFootprint {
+	res := DiskFootprint{
+		Size:        footprint.Value(),
+		Reclaimable: footprint.Reclaimable(),
+		Note:        footprint.GetNote(),
+		Children:    map[string]DiskFootprint{},
+	}
+	for _, name := range footprint.GetChildNames() {
+		if child := footprint.GetChild(name); child != nil {
+			res.Children[name] = toDiskFootprint(child)
+		}
+	}
+	return res
+}
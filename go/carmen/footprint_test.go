@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func TestDiskFootprint_TotalsIncludeChildren(t *testing.T) {
+	footprint := DiskFootprint{
+		Size:        10,
+		Reclaimable: 1,
+		Children: map[string]DiskFootprint{
+			"a": {Size: 20, Reclaimable: 2},
+			"b": {Size: 30, Children: map[string]DiskFootprint{
+				"c": {Size: 40, Reclaimable: 4},
+			}},
+		},
+	}
+	if got, want := footprint.Total(), uint64(100); got != want {
+		t.Errorf("unexpected total, wanted %d, got %d", want, got)
+	}
+	if got, want := footprint.TotalReclaimable(), uint64(7); got != want {
+		t.Errorf("unexpected total reclaimable, wanted %d, got %d", want, got)
+	}
+}
+
+func TestDiskFootprint_InternalFootprintIsConverted(t *testing.T) {
+	child := common.NewDiskFootprint(20)
+	child.SetReclaimable(5)
+	child.SetNote("note")
+	internal := common.NewDiskFootprint(10)
+	internal.AddChild("child", child)
+	internal.AddChild("missing", nil)
+
+	footprint := toDiskFootprint(internal)
+	if footprint.Size != 10 || len(footprint.Children) != 1 {
+		t.Fatalf("unexpected conversion result: %v", footprint)
+	}
+	got := footprint.Children["child"]
+	if got.Size != 20 || got.Reclaimable != 5 || got.Note != "note" {
+		t.Errorf("unexpected child: %v", got)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	mptIo "github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"github.com/Fantom-foundation/Carmen/go/state/gostate"
+)
+
+// exportableState is implemented by historic states supporting the export of
+// their content in the format of the mpt/io package.
+type exportableState interface {
+	Export(out io.Writer) error
+}
+
+func (db *database) ForkHistoricBlock(block uint64, directory string, configuration Configuration, run func(HistoricBlockContext) error) error {
+	if configuration.Schema != 5 || (configuration.Archive != Archive(state.NoArchive) && configuration.Archive != Archive(state.S5Archive)) {
+		return fmt.Errorf("%w: forks are only supported for schema 5 without archive or with an S5 archive, got %v", UnsupportedConfiguration, configuration)
+	}
+	if !isFileBasedVariant(configuration.Variant) {
+		return fmt.Errorf("%w: forks are only supported for file-based variants, got %v", UnsupportedConfiguration, configuration)
+	}
+
+	historic, err := db.getHistoricState(block)
+	if err != nil {
+		return err
+	}
+	defer db.releaseArchiveQuery()
+
+	source, ok := historic.(exportableState)
+	if !ok {
+		return fmt.Errorf("%w: forks are not supported by this database", UnsupportedConfiguration)
+	}
+
+	if err := createEmptyDirectory(directory); err != nil {
+		return err
+	}
+
+	// Initialize the LiveDB and Archive of the new database with the state
+	// of the forked block.
+	liveDir := filepath.Join(directory, "live")
+	if err := os.Mkdir(liveDir, 0700); err != nil {
+		return err
+	}
+	if err := transfer(source.Export, func(in io.Reader) error {
+		return mptIo.ImportLiveDb(liveDir, in)
+	}); err != nil {
+		return fmt.Errorf("failed to initialize LiveDB of fork: %w", err)
+	}
+	if configuration.Archive == Archive(state.S5Archive) {
+		archiveDir := filepath.Join(directory, "archive")
+		if err := os.Mkdir(archiveDir, 0700); err != nil {
+			return err
+		}
+		if err := transfer(source.Export, func(in io.Reader) error {
+			return mptIo.InitializeArchive(archiveDir, in, block)
+		}); err != nil {
+			return fmt.Errorf("failed to initialize archive of fork: %w", err)
+		}
+	}
+
+	// Run the forked block on the new database.
+	fork, err := OpenDatabase(directory, configuration, Properties{})
+	if err != nil {
+		return fmt.Errorf("failed to open fork: %w", err)
+	}
+	err = fork.AddBlock(block+1, func(context HeadBlockContext) error {
+		return run(forkBlockContext{context})
+	})
+	return errors.Join(err, fork.Close())
+}
+
+// forkBlockContext is the historic block context provided for running the
+// transactions of a forked block. The block is run on the head state of the
+// fork, which is lifecycle-managed by ForkHistoricBlock.
+type forkBlockContext struct {
+	blockContext
+}
+
+func (forkBlockContext) Close() error {
+	return nil
+}
+
+// isFileBasedVariant returns true if databases of the given variant are opened
+// from the file-based layout produced by the mpt/io package. Other variants,
+// e.g. in-memory variants, do not load such files.
+func isFileBasedVariant(variant Variant) bool {
+	return variant == Variant(gostate.VariantGoFile)
+}
+
+// createEmptyDirectory creates the given directory if it does not exist and
+// makes sure that it is empty.
+func createEmptyDirectory(directory string) error {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fmt.Errorf("directory %s is not empty", directory)
+	}
+	return nil
+}
+
+// transfer pipes the data produced by the given export function into the
+// given import function.
+func transfer(export func(io.Writer) error, importer func(io.Reader) error) error {
+	reader, writer := io.Pipe()
+	exportDone := make(chan error, 1)
+	go func() {
+		err := export(writer)
+		writer.CloseWithError(err)
+		exportDone <- err
+	}()
+	err := importer(reader)
+	// Unblock the exporter in case the import stopped early.
+	reader.Close()
+	exportErr := <-exportDone
+	if err == nil {
+		return exportErr
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/state/gostate"
+)
+
+func forkTestBlock(block int) func(blockContext) error {
+	return func(context blockContext) error {
+		return context.RunTransaction(func(context TransactionContext) error {
+			for i := 0; i < 10; i++ {
+				addr := Address{byte(i)}
+				if block == 0 {
+					context.CreateAccount(addr)
+					context.SetCode(addr, []byte{byte(i), 1, 2, 3})
+				}
+				context.AddBalance(addr, NewAmount(uint64(block+1)))
+				context.SetState(addr, Key{byte(block)}, Value{byte(i + 1)})
+			}
+			return nil
+		})
+	}
+}
+
+// createForkTestSource creates a database with three blocks.
+func createForkTestSource(t *testing.T) Database {
+	t.Helper()
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := db.AddBlock(uint64(i), func(context HeadBlockContext) error {
+			return forkTestBlock(i)(context)
+		}); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+	return db
+}
+
+func TestFork_ForkedDatabaseContainsStateOfHistoricBlockAndForkedBlock(t *testing.T) {
+	for _, config := range []Configuration{GetCarmenGoS5WithArchiveConfiguration(), GetCarmenGoS5WithoutArchiveConfiguration()} {
+		t.Run(config.String(), func(t *testing.T) {
+			db := createForkTestSource(t)
+			defer db.Close()
+
+			// Forking block 1 with the transactions of block 2 must reproduce block 2.
+			dir := filepath.Join(t.TempDir(), "fork")
+			if err := db.ForkHistoricBlock(1, dir, config, func(context HistoricBlockContext) error {
+				return forkTestBlock(2)(context)
+			}); err != nil {
+				t.Fatalf("failed to fork database: %v", err)
+			}
+
+			fork, err := OpenDatabase(dir, config, testProperties)
+			if err != nil {
+				t.Fatalf("failed to open fork: %v", err)
+			}
+			defer fork.Close()
+
+			if got, want := getHeadStateHash(t, fork), getHeadStateHash(t, db); got != want {
+				t.Errorf("unexpected head state hash of fork, wanted %x, got %x", want, got)
+			}
+			if err := fork.QueryHeadState(func(context QueryContext) {
+				if got, want := context.GetCode(Address{1}), []byte{1, 1, 2, 3}; string(got) != string(want) {
+					t.Errorf("unexpected code, wanted %v, got %v", want, got)
+				}
+			}); err != nil {
+				t.Fatalf("failed to query head state: %v", err)
+			}
+
+			height, err := fork.GetArchiveBlockHeight()
+			if config.Archive == GetCarmenGoS5WithoutArchiveConfiguration().Archive {
+				if err == nil {
+					t.Errorf("fork without archive should not report an archive block height")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get archive block height: %v", err)
+			}
+			if got, want := height, int64(2); got != want {
+				t.Errorf("unexpected archive block height, wanted %d, got %d", want, got)
+			}
+			for _, block := range []uint64{1, 2} {
+				want, err := db.GetHistoricStateHash(block)
+				if err != nil {
+					t.Fatalf("failed to get historic state hash: %v", err)
+				}
+				got, err := fork.GetHistoricStateHash(block)
+				if err != nil {
+					t.Fatalf("failed to get historic state hash of fork: %v", err)
+				}
+				if got != want {
+					t.Errorf("unexpected state hash of block %d, wanted %x, got %x", block, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFork_ForkIsIndependentOfSourceDatabase(t *testing.T) {
+	db := createForkTestSource(t)
+	defer db.Close()
+
+	dir := t.TempDir()
+	if err := db.ForkHistoricBlock(0, dir, GetCarmenGoS5WithArchiveConfiguration(), func(context HistoricBlockContext) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to fork database: %v", err)
+	}
+
+	before := getHeadStateHash(t, db)
+	fork, err := OpenDatabase(dir, GetCarmenGoS5WithArchiveConfiguration(), testProperties)
+	if err != nil {
+		t.Fatalf("failed to open fork: %v", err)
+	}
+	defer fork.Close()
+	if err := fork.AddBlock(2, func(context HeadBlockContext) error {
+		return forkTestBlock(5)(context)
+	}); err != nil {
+		t.Fatalf("failed to add block to fork: %v", err)
+	}
+	if got, want := getHeadStateHash(t, db), before; got != want {
+		t.Errorf("source database was modified by fork, wanted %x, got %x", want, got)
+	}
+}
+
+func TestFork_FailingBlockIsReported(t *testing.T) {
+	db := createForkTestSource(t)
+	defer db.Close()
+
+	injectedErr := errors.New("injected error")
+	err := db.ForkHistoricBlock(1, t.TempDir(), GetCarmenGoS5WithArchiveConfiguration(), func(context HistoricBlockContext) error {
+		return injectedErr
+	})
+	if !errors.Is(err, injectedErr) {
+		t.Errorf("unexpected error, wanted %v, got %v", injectedErr, err)
+	}
+}
+
+func TestFork_NonEmptyTargetDirectoryIsRejected(t *testing.T) {
+	db := createForkTestSource(t)
+	defer db.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte{}, 0600); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := db.ForkHistoricBlock(1, dir, GetCarmenGoS5WithArchiveConfiguration(), func(HistoricBlockContext) error {
+		return nil
+	}); err == nil {
+		t.Errorf("forking into a non-empty directory should fail")
+	}
+}
+
+func TestFork_UnsupportedConfigurationsAreRejected(t *testing.T) {
+	db := createForkTestSource(t)
+	defer db.Close()
+
+	config := GetCarmenGoS5WithArchiveConfiguration()
+	config.Schema = 4
+	if err := db.ForkHistoricBlock(1, t.TempDir(), config, func(HistoricBlockContext) error {
+		return nil
+	}); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+}
+
+func TestFork_InMemoryVariantsAreRejected(t *testing.T) {
+	db := createForkTestSource(t)
+	defer db.Close()
+
+	config := GetCarmenGoS5WithArchiveConfiguration()
+	config.Variant = Variant(gostate.VariantGoMemory)
+	dir := t.TempDir()
+	if err := db.ForkHistoricBlock(1, dir, config, func(HistoricBlockContext) error {
+		return nil
+	}); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("rejected fork should not create any files, got %v, err %v", entries, err)
+	}
+}
+
+func TestFork_MissingBlocksAreReported(t *testing.T) {
+	db := createForkTestSource(t)
+	defer db.Close()
+
+	if err := db.ForkHistoricBlock(10, t.TempDir(), GetCarmenGoS5WithArchiveConfiguration(), func(HistoricBlockContext) error {
+		return nil
+	}); err == nil {
+		t.Errorf("forking a non-existing block should fail")
+	}
+}
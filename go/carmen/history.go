@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// historySource is implemented by states supporting history queries.
+type historySource interface {
+	GetAccountHistory(account common.Address, from, to uint64) ([]mpt.AccountChange, error)
+	GetSlotHistory(account common.Address, slot common.Key, from, to uint64) ([]mpt.SlotChange, error)
+}
+
+// runOnHistorySource runs the given operation on the history source of this
+// database. The operation is executed while holding the lock of the state,
+// such that it does not interfere with concurrent updates.
+func (db *database) runOnHistorySource(op func(historySource) error) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.db == nil {
+		return errDbClosed
+	}
+	return state.RunWithUnwrappedState(db.db, func(s state.State) error {
+		source, ok := s.(historySource)
+		if !ok {
+			return fmt.Errorf("%w: history queries are not supported by this configuration", UnsupportedConfiguration)
+		}
+		return op(source)
+	})
+}
+
+func (db *database) GetAccountHistory(address Address, from, to uint64) ([]AccountChange, error) {
+	var changes []mpt.AccountChange
+	err := db.runOnHistorySource(func(source historySource) error {
+		var err error
+		changes, err = source.GetAccountHistory(common.Address(address), from, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]AccountChange, 0, len(changes))
+	for _, change := range changes {
+		res = append(res, AccountChange{
+			Block:  change.Block,
+			Before: toAccountState(&change.Before),
+			After:  toAccountState(&change.After),
+		})
+	}
+	return res, nil
+}
+
+func (db *database) GetStorageHistory(address Address, key Key, from, to uint64) ([]StorageChange, error) {
+	var changes []mpt.SlotChange
+	err := db.runOnHistorySource(func(source historySource) error {
+		var err error
+		changes, err = source.GetSlotHistory(common.Address(address), common.Key(key), from, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]StorageChange, 0, len(changes))
+	for _, change := range changes {
+		res = append(res, StorageChange{
+			Block:  change.Block,
+			Before: Value(change.Before),
+			After:  Value(change.After),
+		})
+	}
+	return res, nil
+}
+
+func toAccountState(state *mpt.AccountState) AccountState {
+	return AccountState{
+		Exists:   state.Exists,
+		Balance:  NewAmountFromBytes(state.Info.Balance[:]...),
+		Nonce:    state.Info.Nonce.ToUint64(),
+		CodeHash: Hash(state.Info.CodeHash),
+	}
+}
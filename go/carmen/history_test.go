@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDatabase_HistoryOfAccountsAndSlotsCanBeQueried(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	addr := Address{1}
+	key := Key{2}
+	for i := 0; i < 5; i++ {
+		if err := db.AddBlock(uint64(i), func(context HeadBlockContext) error {
+			return context.RunTransaction(func(context TransactionContext) error {
+				if i == 0 {
+					context.CreateAccount(addr)
+				}
+				if i%2 == 0 {
+					context.AddBalance(addr, NewAmount(1))
+				}
+				if i == 3 {
+					context.SetState(addr, key, Value{1})
+				}
+				return nil
+			})
+		}); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+
+	changes, err := db.GetAccountHistory(addr, 0, 4)
+	if err != nil {
+		t.Fatalf("failed to get account history: %v", err)
+	}
+	if got, want := len(changes), 3; got != want {
+		t.Fatalf("unexpected number of changes, wanted %d, got %d", want, got)
+	}
+	for i, change := range changes {
+		if got, want := change.Block, uint64(2*i); got != want {
+			t.Errorf("unexpected block of change %d, wanted %d, got %d", i, want, got)
+		}
+		if got, want := change.Before.Balance, NewAmount(uint64(i)); got != want {
+			t.Errorf("unexpected balance before change %d, wanted %v, got %v", i, want, got)
+		}
+		if got, want := change.After.Balance, NewAmount(uint64(i+1)); got != want {
+			t.Errorf("unexpected balance after change %d, wanted %v, got %v", i, want, got)
+		}
+		if !change.After.Exists {
+			t.Errorf("account should exist after change %d", i)
+		}
+	}
+
+	slotChanges, err := db.GetStorageHistory(addr, key, 0, 4)
+	if err != nil {
+		t.Fatalf("failed to get storage history: %v", err)
+	}
+	want := []StorageChange{{Block: 3, Before: Value{}, After: Value{1}}}
+	if len(slotChanges) != 1 || slotChanges[0] != want[0] {
+		t.Errorf("unexpected storage history, wanted %v, got %v", want, slotChanges)
+	}
+}
+
+func TestDatabase_HistoryQueriesRequireArchive(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), GetCarmenGoS5WithoutArchiveConfiguration(), testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetAccountHistory(Address{}, 0, 0); err == nil {
+		t.Errorf("history query without archive should fail")
+	}
+	if _, err := db.GetStorageHistory(Address{}, Key{}, 0, 0); err == nil {
+		t.Errorf("history query without archive should fail")
+	}
+}
+
+func TestDatabase_HistoryQueriesOnClosedDatabaseFail(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if _, err := db.GetAccountHistory(Address{}, 0, 0); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	mptIo "github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+func importState(directory string, configuration Configuration, block uint64, entries StateIterator) (hash Hash, err error) {
+	if configuration.Schema != 5 || (configuration.Archive != Archive(state.NoArchive) && configuration.Archive != Archive(state.S5Archive)) {
+		return hash, fmt.Errorf("%w: state imports are only supported for schema 5 without archive or with an S5 archive, got %v", UnsupportedConfiguration, configuration)
+	}
+	if !isFileBasedVariant(configuration.Variant) {
+		return hash, fmt.Errorf("%w: state imports are only supported for file-based variants, got %v", UnsupportedConfiguration, configuration)
+	}
+	if err := createEmptyDirectory(directory); err != nil {
+		return hash, err
+	}
+
+	// Both the LiveDB and the Archive are built in parallel in a single
+	// pass over the entries.
+	var builders []*mptIo.StateBuilder
+	defer func() {
+		for _, builder := range builders {
+			err = errors.Join(err, builder.Abort())
+		}
+	}()
+
+	liveDir := filepath.Join(directory, "live")
+	if err := os.Mkdir(liveDir, 0700); err != nil {
+		return hash, err
+	}
+	live, err := mptIo.NewLiveDbBuilder(liveDir, block)
+	if err != nil {
+		return hash, fmt.Errorf("failed to initialize LiveDB: %w", err)
+	}
+	builders = append(builders, live)
+
+	if configuration.Archive == Archive(state.S5Archive) {
+		archiveDir := filepath.Join(directory, "archive")
+		if err := os.Mkdir(archiveDir, 0700); err != nil {
+			return hash, err
+		}
+		archive, err := mptIo.NewArchiveBuilder(archiveDir, block)
+		if err != nil {
+			return hash, fmt.Errorf("failed to initialize archive: %w", err)
+		}
+		builders = append(builders, archive)
+	}
+
+	for {
+		entry, err := entries.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return hash, err
+		}
+		for _, builder := range builders {
+			if err := addStateEntry(builder, entry); err != nil {
+				return hash, err
+			}
+		}
+	}
+
+	// Finish all builders and make sure they agree on the resulting state.
+	for len(builders) > 0 {
+		builder := builders[0]
+		builders = builders[1:]
+		got, err := builder.Finish()
+		if err != nil {
+			return hash, err
+		}
+		if hash != (Hash{}) && hash != Hash(got) {
+			return hash, fmt.Errorf("inconsistent import, LiveDB hash %x does not match archive hash %x", hash, got)
+		}
+		hash = Hash(got)
+	}
+	return hash, nil
+}
+
+func addStateEntry(builder *mptIo.StateBuilder, entry StateEntry) error {
+	address := common.Address(entry.Address)
+	switch entry.Type {
+	case AccountEntry:
+		balance := common.Balance(entry.Balance.internal.Bytes32())
+		return builder.SetAccount(address, balance, common.ToNonce(entry.Nonce))
+	case CodeEntry:
+		return builder.SetCode(address, entry.Code)
+	case StorageEntry:
+		return builder.SetStorage(address, common.Key(entry.Key), common.Value(entry.Value))
+	default:
+		return fmt.Errorf("invalid state entry type: %d", entry.Type)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"github.com/Fantom-foundation/Carmen/go/state/gostate"
+)
+
+// sliceIterator is a StateIterator providing the entries of a slice.
+type sliceIterator struct {
+	entries []StateEntry
+	err     error
+}
+
+func (i *sliceIterator) Next() (StateEntry, error) {
+	if len(i.entries) == 0 {
+		if i.err != nil {
+			return StateEntry{}, i.err
+		}
+		return StateEntry{}, io.EOF
+	}
+	res := i.entries[0]
+	i.entries = i.entries[1:]
+	return res, nil
+}
+
+// getImportTestEntries produces the entries of a test state in an order
+// interleaving accounts and listing storage before account information.
+func getImportTestEntries() []StateEntry {
+	var res []StateEntry
+	for i := 9; i >= 0; i-- {
+		addr := Address{byte(i)}
+		res = append(res,
+			StateEntry{Type: StorageEntry, Address: addr, Key: Key{2}, Value: Value{byte(i + 1)}},
+			StateEntry{Type: StorageEntry, Address: Address{byte(9 - i)}, Key: Key{1}, Value: Value{byte(i + 1)}},
+		)
+		if i%2 == 0 {
+			res = append(res, StateEntry{Type: CodeEntry, Address: addr, Code: []byte{byte(i), 1, 2, 3}})
+		}
+		res = append(res, StateEntry{Type: AccountEntry, Address: addr, Balance: NewAmount(uint64(i + 1)), Nonce: uint64(i)})
+	}
+	return res
+}
+
+// createImportTestReference creates a database containing the state of
+// getImportTestEntries by running a regular block.
+func createImportTestReference(t *testing.T, config Configuration) Database {
+	t.Helper()
+	db, err := OpenDatabase(t.TempDir(), config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AddBlock(0, func(context HeadBlockContext) error {
+		return context.RunTransaction(func(context TransactionContext) error {
+			for _, entry := range getImportTestEntries() {
+				context.CreateAccount(entry.Address)
+			}
+			for _, entry := range getImportTestEntries() {
+				switch entry.Type {
+				case AccountEntry:
+					context.SetNonce(entry.Address, entry.Nonce)
+					context.AddBalance(entry.Address, entry.Balance)
+				case CodeEntry:
+					context.SetCode(entry.Address, entry.Code)
+				case StorageEntry:
+					context.SetState(entry.Address, entry.Key, entry.Value)
+				}
+			}
+			return nil
+		})
+	}); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	return db
+}
+
+func TestImportState_ImportedDatabaseContainsImportedState(t *testing.T) {
+	for _, config := range []Configuration{GetCarmenGoS5WithArchiveConfiguration(), GetCarmenGoS5WithoutArchiveConfiguration()} {
+		t.Run(config.String(), func(t *testing.T) {
+			reference := createImportTestReference(t, config)
+			defer reference.Close()
+			want := getHeadStateHash(t, reference)
+
+			const block = 5
+			dir := filepath.Join(t.TempDir(), "db")
+			hash, err := ImportState(dir, config, block, &sliceIterator{entries: getImportTestEntries()})
+			if err != nil {
+				t.Fatalf("failed to import state: %v", err)
+			}
+			if hash != want {
+				t.Errorf("unexpected hash of imported state, wanted %x, got %x", want, hash)
+			}
+
+			db, err := OpenDatabase(dir, config, testProperties)
+			if err != nil {
+				t.Fatalf("failed to open imported database: %v", err)
+			}
+			defer db.Close()
+
+			if got := getHeadStateHash(t, db); got != want {
+				t.Errorf("unexpected head state hash, wanted %x, got %x", want, got)
+			}
+			if err := db.QueryHeadState(func(context QueryContext) {
+				for i := 0; i < 10; i++ {
+					addr := Address{byte(i)}
+					if got, want := context.GetBalance(addr), NewAmount(uint64(i+1)); got != want {
+						t.Errorf("unexpected balance of account %d, wanted %v, got %v", i, want, got)
+					}
+					if got, want := context.GetNonce(addr), uint64(i); got != want {
+						t.Errorf("unexpected nonce of account %d, wanted %d, got %d", i, want, got)
+					}
+					var code []byte
+					if i%2 == 0 {
+						code = []byte{byte(i), 1, 2, 3}
+					}
+					if got := context.GetCode(addr); !bytes.Equal(got, code) {
+						t.Errorf("unexpected code of account %d, wanted %x, got %x", i, code, got)
+					}
+					if got, want := context.GetState(addr, Key{2}), (Value{byte(i + 1)}); got != want {
+						t.Errorf("unexpected storage of account %d, wanted %x, got %x", i, want, got)
+					}
+				}
+			}); err != nil {
+				t.Fatalf("failed to query head state: %v", err)
+			}
+
+			if config.Archive == Archive(state.S5Archive) {
+				if height, err := db.GetArchiveBlockHeight(); err != nil || height != block {
+					t.Errorf("unexpected archive block height, wanted %d, got %d, err %v", block, height, err)
+				}
+				if got, err := db.GetHistoricStateHash(block); err != nil || got != want {
+					t.Errorf("unexpected historic state hash, wanted %x, got %x, err %v", want, got, err)
+				}
+			}
+
+			if err := db.AddBlock(block, func(HeadBlockContext) error { return nil }); err == nil {
+				t.Errorf("adding the imported block again should fail")
+			}
+			if err := db.AddBlock(block+1, func(HeadBlockContext) error { return nil }); err != nil {
+				t.Errorf("failed to add block after imported block: %v", err)
+			}
+		})
+	}
+}
+
+func TestImportState_UnsupportedConfigurationsAreRejected(t *testing.T) {
+	config := GetCarmenGoS5WithArchiveConfiguration()
+	config.Schema = 4
+	_, err := ImportState(t.TempDir(), config, 0, &sliceIterator{})
+	if !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+}
+
+func TestImportState_InMemoryVariantsAreRejected(t *testing.T) {
+	config := GetCarmenGoS5WithArchiveConfiguration()
+	config.Variant = Variant(gostate.VariantGoMemory)
+	dir := t.TempDir()
+	if _, err := ImportState(dir, config, 0, &sliceIterator{entries: getImportTestEntries()}); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("rejected import should not create any files, got %v, err %v", entries, err)
+	}
+}
+
+func TestImportState_NonEmptyDirectoryIsRejected(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte{}, 0600); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, err := ImportState(dir, GetCarmenGoS5WithArchiveConfiguration(), 0, &sliceIterator{}); err == nil {
+		t.Errorf("importing into a non-empty directory should fail")
+	}
+}
+
+func TestImportState_IteratorErrorsAreForwarded(t *testing.T) {
+	injectedError := fmt.Errorf("injected error")
+	iterator := &sliceIterator{entries: getImportTestEntries(), err: injectedError}
+	if _, err := ImportState(t.TempDir(), GetCarmenGoS5WithArchiveConfiguration(), 0, iterator); !errors.Is(err, injectedError) {
+		t.Errorf("unexpected error, wanted %v, got %v", injectedError, err)
+	}
+}
+
+func TestImportState_InvalidEntryTypesAreRejected(t *testing.T) {
+	iterator := &sliceIterator{entries: []StateEntry{{Type: StateEntryType(42)}}}
+	if _, err := ImportState(t.TempDir(), GetCarmenGoS5WithArchiveConfiguration(), 0, iterator); err == nil {
+		t.Errorf("importing an invalid entry should fail")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// memoryBudgetRebalancePeriod is the minimum time between two rebalancing
+// steps of a memory budget.
+const memoryBudgetRebalancePeriod = 5 * time.Second
+
+// memoryBudgetAdaptionRate is the fraction of the memory distribution that
+// is re-assigned based on the misses observed since the last rebalancing.
+const memoryBudgetAdaptionRate = 0.25
+
+// numBudgetedCaches is the maximum number of caches a memory budget is
+// divided among: the LiveDB and archive node caches, the code cache, and the
+// stored-data cache. Node caches are created with a capacity covering an even
+// share of the budget, which limits the size they may grow to.
+const numBudgetedCaches = 4
+
+// initialBudgetedStorageCacheSize is the capacity of the stored-data cache
+// of state DBs created for a database with a memory budget. The capacity is
+// adjusted by the first rebalancing.
+const initialBudgetedStorageCacheSize = 1_000
+
+// memoryBudget distributes a fixed amount of memory among the adaptive
+// caches of a database. Initially, the memory is distributed evenly. Then,
+// in periodic rebalancing steps, memory is shifted toward caches with many
+// misses. Caches not making use of their full capacity are not grown. The
+// memory accounted for is based on the memory footprint estimations of the
+// caches.
+type memoryBudget struct {
+	budget uint64        // < the number of bytes to be distributed
+	period time.Duration // < the minimum time between two rebalancing steps
+
+	lastRebalance time.Time
+	misses        map[string]uint64 // < the number of misses of each cache at the last rebalancing
+}
+
+func newMemoryBudget(budget uint64) *memoryBudget {
+	return &memoryBudget{
+		budget: budget,
+		period: memoryBudgetRebalancePeriod,
+	}
+}
+
+// budgetedCache summarizes the state of a cache managed by a memory budget.
+type budgetedCache struct {
+	name   string
+	stats  common.CacheStatistics
+	misses uint64 // < the number of misses since the last rebalancing
+}
+
+// rebalance redistributes the budget among the adaptive caches of the given
+// state and state DB if the rebalancing period has passed since the last
+// rebalancing. The caller needs to make sure that the state DB is not used
+// concurrently.
+func (b *memoryBudget) rebalance(db state.State, statedb state.StateDB) error {
+	if b.misses != nil && time.Since(b.lastRebalance) < b.period {
+		return nil
+	}
+	return b.forceRebalance(db, statedb)
+}
+
+// forceRebalance redistributes the budget regardless of the time passed since
+// the last rebalancing.
+func (b *memoryBudget) forceRebalance(db state.State, statedb state.StateDB) error {
+	caches := map[string]common.AdaptiveCache{}
+	for _, provider := range []any{db, statedb} {
+		if provider, ok := provider.(state.AdaptiveCacheProvider); ok {
+			for name, cache := range provider.GetAdaptiveCaches() {
+				caches[name] = cache
+			}
+		}
+	}
+
+	// Caches are processed in a fixed order to obtain stable results.
+	names := make([]string, 0, len(caches))
+	for name := range caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	first := b.misses == nil
+	misses := make(map[string]uint64, len(caches))
+	budgeted := make([]budgetedCache, 0, len(caches))
+	for _, name := range names {
+		stats := caches[name].GetCacheStatistics()
+		delta := stats.Misses
+		if last, found := b.misses[name]; found && last <= stats.Misses {
+			delta -= last
+		}
+		misses[name] = stats.Misses
+		budgeted = append(budgeted, budgetedCache{name: name, stats: stats, misses: delta})
+	}
+
+	capacities, err := distributeMemory(b.budget, budgeted, first)
+	if err != nil {
+		return err
+	}
+	for i, cache := range budgeted {
+		caches[cache.name].SetCacheCapacity(capacities[i])
+	}
+	b.misses = misses
+	b.lastRebalance = time.Now()
+	return nil
+}
+
+// distributeMemory computes the capacities of the given caches such that
+// their total memory usage does not exceed the given budget. If even is set,
+// the memory exceeding the minimum capacities is distributed evenly;
+// otherwise, it is distributed based on the current capacities and the
+// observed misses, without growing caches not making use of their full
+// capacity.
+func distributeMemory(budget uint64, caches []budgetedCache, even bool) ([]int, error) {
+	// The overhead and the minimum capacity of each cache are always required.
+	required := uint64(0)
+	for _, cache := range caches {
+		required += cache.stats.Overhead + uint64(cache.stats.MinCapacity)*entrySizeOf(cache)
+	}
+	if required > budget {
+		return nil, fmt.Errorf("memory budget of %d bytes is insufficient, at least %d bytes are required", budget, required)
+	}
+
+	// Each cache is assigned a weight and a limit for the memory it is
+	// assigned in addition to its minimum capacity.
+	weights := make([]float64, len(caches))
+	limits := make([]uint64, len(caches))
+	totalAssigned, totalMisses := uint64(0), uint64(0)
+	for i, cache := range caches {
+		limits[i] = getMemoryLimit(cache, even)
+		totalAssigned += getExtraMemory(cache, cache.stats.Capacity)
+		totalMisses += cache.misses
+	}
+	for i, cache := range caches {
+		if even || totalAssigned == 0 {
+			weights[i] = 1
+			continue
+		}
+		weights[i] = float64(getExtraMemory(cache, cache.stats.Capacity)) / float64(totalAssigned)
+		if totalMisses > 0 {
+			weights[i] = (1-memoryBudgetAdaptionRate)*weights[i] +
+				memoryBudgetAdaptionRate*float64(cache.misses)/float64(totalMisses)
+		}
+	}
+
+	// The remaining memory is distributed according to the weights. Caches
+	// reaching their limit are saturated and the memory not assigned to them
+	// is distributed among the remaining caches.
+	extra := make([]uint64, len(caches))
+	saturated := make([]bool, len(caches))
+	remaining := budget - required
+	for {
+		totalWeight := 0.0
+		for i := range caches {
+			if !saturated[i] {
+				totalWeight += weights[i]
+			}
+		}
+		if totalWeight == 0 {
+			break
+		}
+		progress := false
+		for i := range caches {
+			if saturated[i] {
+				continue
+			}
+			if share := uint64(float64(remaining) * weights[i] / totalWeight); share >= limits[i] {
+				extra[i] = limits[i]
+				saturated[i] = true
+				remaining -= limits[i]
+				progress = true
+			}
+		}
+		if progress {
+			continue
+		}
+		for i := range caches {
+			if !saturated[i] {
+				extra[i] = uint64(float64(remaining) * weights[i] / totalWeight)
+			}
+		}
+		break
+	}
+
+	res := make([]int, len(caches))
+	for i, cache := range caches {
+		res[i] = cache.stats.MinCapacity + int(extra[i]/entrySizeOf(cache))
+	}
+	return res, nil
+}
+
+// getMemoryLimit computes the maximum memory a cache may be assigned in
+// addition to the memory required for its minimum capacity. Unless growing
+// is requested, caches not making use of their full capacity are not grown.
+func getMemoryLimit(cache budgetedCache, grow bool) uint64 {
+	limit := ^uint64(0)
+	if cache.stats.MaxCapacity > 0 {
+		limit = getExtraMemory(cache, cache.stats.MaxCapacity)
+	}
+	if !grow && cache.stats.Size < cache.stats.Capacity {
+		if current := getExtraMemory(cache, cache.stats.Capacity); current < limit {
+			limit = current
+		}
+	}
+	return limit
+}
+
+// getExtraMemory computes the memory required for the given capacity in
+// addition to the memory required for the minimum capacity of the cache.
+func getExtraMemory(cache budgetedCache, capacity int) uint64 {
+	if capacity <= cache.stats.MinCapacity {
+		return 0
+	}
+	return uint64(capacity-cache.stats.MinCapacity) * entrySizeOf(cache)
+}
+
+func entrySizeOf(cache budgetedCache) uint64 {
+	if cache.stats.EntrySize == 0 {
+		return 1
+	}
+	return cache.stats.EntrySize
+}
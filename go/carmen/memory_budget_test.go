@@ -0,0 +1,270 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"go.uber.org/mock/gomock"
+)
+
+// getBudgetedMemory computes the memory used by the given caches if they are
+// assigned the given capacities.
+func getBudgetedMemory(caches []budgetedCache, capacities []int) uint64 {
+	sum := uint64(0)
+	for i, cache := range caches {
+		sum += cache.stats.Overhead + uint64(capacities[i])*cache.stats.EntrySize
+	}
+	return sum
+}
+
+func TestDistributeMemory_InsufficientBudgetIsDetected(t *testing.T) {
+	caches := []budgetedCache{
+		{stats: common.CacheStatistics{MinCapacity: 10, EntrySize: 10, Overhead: 100}},
+		{stats: common.CacheStatistics{MinCapacity: 10, EntrySize: 20}},
+	}
+	if _, err := distributeMemory(399, caches, true); err == nil {
+		t.Errorf("insufficient budget should be detected")
+	}
+	capacities, err := distributeMemory(400, caches, true)
+	if err != nil {
+		t.Fatalf("failed to distribute memory: %v", err)
+	}
+	if want := []int{10, 10}; !reflect.DeepEqual(want, capacities) {
+		t.Errorf("unexpected capacities, wanted %v, got %v", want, capacities)
+	}
+}
+
+func TestDistributeMemory_EvenDistributionRespectsMaximumCapacities(t *testing.T) {
+	caches := []budgetedCache{
+		{stats: common.CacheStatistics{MinCapacity: 10, MaxCapacity: 20, EntrySize: 10}},
+		{stats: common.CacheStatistics{MinCapacity: 0, EntrySize: 10, Overhead: 100}},
+		{stats: common.CacheStatistics{MinCapacity: 0, EntrySize: 20}},
+	}
+	const budget = 2_300
+	capacities, err := distributeMemory(budget, caches, true)
+	if err != nil {
+		t.Fatalf("failed to distribute memory: %v", err)
+	}
+	// The first cache is saturated, the others share the remaining memory.
+	if want := []int{20, 100, 50}; !reflect.DeepEqual(want, capacities) {
+		t.Errorf("unexpected capacities, wanted %v, got %v", want, capacities)
+	}
+	if got := getBudgetedMemory(caches, capacities); got > budget {
+		t.Errorf("budget exceeded, wanted at most %d, got %d", budget, got)
+	}
+}
+
+func TestDistributeMemory_MemoryIsShiftedTowardCachesWithMisses(t *testing.T) {
+	caches := []budgetedCache{
+		{stats: common.CacheStatistics{Capacity: 100, Size: 100, EntrySize: 10}, misses: 0},
+		{stats: common.CacheStatistics{Capacity: 100, Size: 100, EntrySize: 10}, misses: 100},
+	}
+	const budget = 2_000
+	capacities, err := distributeMemory(budget, caches, false)
+	if err != nil {
+		t.Fatalf("failed to distribute memory: %v", err)
+	}
+	if want := []int{75, 125}; !reflect.DeepEqual(want, capacities) {
+		t.Errorf("unexpected capacities, wanted %v, got %v", want, capacities)
+	}
+
+	// Without misses, the distribution is retained.
+	caches[1].misses = 0
+	capacities, err = distributeMemory(budget, caches, false)
+	if err != nil {
+		t.Fatalf("failed to distribute memory: %v", err)
+	}
+	if want := []int{100, 100}; !reflect.DeepEqual(want, capacities) {
+		t.Errorf("unexpected capacities, wanted %v, got %v", want, capacities)
+	}
+}
+
+func TestDistributeMemory_CachesNotUsingTheirCapacityAreNotGrown(t *testing.T) {
+	caches := []budgetedCache{
+		{stats: common.CacheStatistics{Capacity: 100, Size: 50, EntrySize: 10}, misses: 100},
+		{stats: common.CacheStatistics{Capacity: 50, Size: 50, EntrySize: 10}, misses: 0},
+	}
+	const budget = 2_000
+	capacities, err := distributeMemory(budget, caches, false)
+	if err != nil {
+		t.Fatalf("failed to distribute memory: %v", err)
+	}
+	if want := []int{100, 100}; !reflect.DeepEqual(want, capacities) {
+		t.Errorf("unexpected capacities, wanted %v, got %v", want, capacities)
+	}
+}
+
+// adaptiveState is a state providing adaptive caches for testing.
+type adaptiveState struct {
+	*state.MockState
+	*state.MockAdaptiveCacheProvider
+}
+
+// testCache is a simple adaptive cache recording its capacity.
+type testCache struct {
+	stats common.CacheStatistics
+}
+
+func (c *testCache) GetCacheStatistics() common.CacheStatistics {
+	return c.stats
+}
+
+func (c *testCache) SetCacheCapacity(capacity int) {
+	c.stats.Capacity = capacity
+}
+
+func TestMemoryBudget_RebalanceAdjustsCachesOfStateAndStateDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := &testCache{stats: common.CacheStatistics{Capacity: 10, Size: 10, EntrySize: 10}}
+	data := &testCache{stats: common.CacheStatistics{Capacity: 10, Size: 10, EntrySize: 10}}
+
+	db := adaptiveState{state.NewMockState(ctrl), state.NewMockAdaptiveCacheProvider(ctrl)}
+	db.MockAdaptiveCacheProvider.EXPECT().GetAdaptiveCaches().Return(map[string]common.AdaptiveCache{"live": live}).AnyTimes()
+	statedb := struct {
+		*state.MockStateDB
+		*state.MockAdaptiveCacheProvider
+	}{state.NewMockStateDB(ctrl), state.NewMockAdaptiveCacheProvider(ctrl)}
+	statedb.MockAdaptiveCacheProvider.EXPECT().GetAdaptiveCaches().Return(map[string]common.AdaptiveCache{"storedData": data}).AnyTimes()
+
+	budget := newMemoryBudget(1_000)
+	if err := budget.rebalance(db, statedb); err != nil {
+		t.Fatalf("failed to rebalance: %v", err)
+	}
+	if live.stats.Capacity != 50 || data.stats.Capacity != 50 {
+		t.Errorf("memory should be distributed evenly, got %d and %d", live.stats.Capacity, data.stats.Capacity)
+	}
+
+	// Within the rebalancing period, capacities are not changed.
+	live.stats.Size = 50
+	data.stats.Size = 50
+	data.stats.Misses = 100
+	if err := budget.rebalance(db, statedb); err != nil {
+		t.Fatalf("failed to rebalance: %v", err)
+	}
+	if live.stats.Capacity != 50 || data.stats.Capacity != 50 {
+		t.Errorf("capacities should not be changed, got %d and %d", live.stats.Capacity, data.stats.Capacity)
+	}
+
+	budget.period = 0
+	if err := budget.rebalance(db, statedb); err != nil {
+		t.Fatalf("failed to rebalance: %v", err)
+	}
+	if live.stats.Capacity != 37 || data.stats.Capacity != 62 {
+		t.Errorf("memory should be shifted to the cache with misses, got %d and %d", live.stats.Capacity, data.stats.Capacity)
+	}
+
+	// Only misses since the last rebalancing are considered.
+	live.stats.Size = live.stats.Capacity
+	data.stats.Size = data.stats.Capacity
+	if err := budget.rebalance(db, statedb); err != nil {
+		t.Fatalf("failed to rebalance: %v", err)
+	}
+	if live.stats.Capacity != 37 || data.stats.Capacity != 62 {
+		t.Errorf("capacities should be retained, got %d and %d", live.stats.Capacity, data.stats.Capacity)
+	}
+}
+
+func TestMemoryBudget_CacheMemoryOfDatabaseStaysWithinBudget(t *testing.T) {
+	const budget = 64 << 20
+	properties := Properties{}
+	properties.SetInteger(MemoryBudget, budget)
+	db, err := OpenDatabase(t.TempDir(), testConfig, properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	impl := db.(*database)
+	impl.budget.period = 0
+	for i := 0; i < 10; i++ {
+		addBalanceInBlock(t, db, uint64(i), Address{byte(i)}, true)
+	}
+
+	names := []string{}
+	total := uint64(0)
+	for _, provider := range []any{impl.db, impl.state} {
+		for name, cache := range provider.(state.AdaptiveCacheProvider).GetAdaptiveCaches() {
+			stats := cache.GetCacheStatistics()
+			if stats.Capacity < stats.MinCapacity {
+				t.Errorf("capacity of %s cache below minimum: %+v", name, stats)
+			}
+			names = append(names, name)
+			total += stats.Overhead + uint64(stats.Capacity)*stats.EntrySize
+		}
+	}
+	if len(names) != 3 {
+		t.Errorf("unexpected budgeted caches: %v", names)
+	}
+	if total > budget {
+		t.Errorf("caches exceed budget, wanted at most %d, got %d", budget, total)
+	}
+}
+
+func TestMemoryBudget_NodeCachesAreLimitedToAnEvenShareOfTheBudget(t *testing.T) {
+	const budget = 64 << 20
+	properties := Properties{}
+	properties.SetInteger(MemoryBudget, budget)
+	db, err := OpenDatabase(t.TempDir(), testConfig, properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	caches := db.(*database).db.(state.AdaptiveCacheProvider).GetAdaptiveCaches()
+	for _, name := range []string{"live", "archive"} {
+		cache, found := caches[name]
+		if !found {
+			t.Fatalf("missing %s cache", name)
+		}
+		stats := cache.GetCacheStatistics()
+		if got, limit := uint64(stats.Capacity)*stats.EntrySize, uint64(budget/numBudgetedCaches); got > limit {
+			t.Errorf("%s cache exceeds its share of the budget, wanted at most %d, got %d", name, limit, got)
+		}
+	}
+}
+
+func TestMemoryBudget_RebalancingFailuresAreReportedByCommits(t *testing.T) {
+	properties := Properties{}
+	properties.SetInteger(MemoryBudget, 64<<20)
+	db, err := OpenDatabase(t.TempDir(), testConfig, properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// A budget not covering the minimum sizes of the caches fails rebalancing.
+	impl := db.(*database)
+	impl.budget.period = 0
+	impl.budget.budget = 1
+	if err := db.AddBlock(0, func(HeadBlockContext) error { return nil }); err == nil {
+		t.Errorf("failed rebalancing should be reported")
+	}
+
+	bulk, err := db.StartBulkLoad(1)
+	if err != nil {
+		t.Fatalf("failed to start bulk load: %v", err)
+	}
+	if err := bulk.Finalize(); err == nil {
+		t.Errorf("failed rebalancing should be reported")
+	}
+}
@@ -32,6 +32,50 @@ const (
 	// If the storage slot is updated, it is updated in this cache first,
 	// before being flushed into underlying structures later.
 	StorageCache = Property("StorageCache")
+	// ArchiveQueueSize is the number of committed blocks buffered for being
+	// added to the archive asynchronously. If the buffer is full, commits are
+	// blocked until the archive has caught up. By default, 10 blocks are
+	// buffered.
+	ArchiveQueueSize = Property("ArchiveQueueSize")
+	// CheckpointBlockInterval is the number of committed blocks after which
+	// the database is flushed automatically in the background. By default,
+	// or if set to 0, no block-based checkpoints are conducted.
+	CheckpointBlockInterval = Property("CheckpointBlockInterval")
+	// CheckpointTimeInterval is the number of seconds after which committed
+	// blocks are flushed automatically in the background. By default, or if
+	// set to 0, no time-based checkpoints are conducted.
+	CheckpointTimeInterval = Property("CheckpointTimeInterval")
+	// CheckpointDirtyNodes is the number of modified, not yet persisted trie
+	// nodes triggering an automatic flush in the background. By default, or
+	// if set to 0, the number of dirty nodes is not tracked. Only supported
+	// by Go based schema 5 configurations.
+	CheckpointDirtyNodes = Property("CheckpointDirtyNodes")
+	// MemoryBudget is an approximate upper limit for the memory used by the
+	// caches of a database in bytes. If set, the budget is divided among the
+	// LiveDB node cache, the archive node cache, the code cache of schemas
+	// without a trie, and the storage cache of Go configurations, or the code
+	// cache and the storage cache of C++ configurations, and rebalanced at
+	// runtime based on their observed misses. Failing rebalancing steps are
+	// reported by block commits.
+	// The node caches may grow to at most a quarter of the budget, which
+	// may be reduced further by the LiveDBCache and ArchiveCache properties,
+	// and the StorageCache property defines the initial size of the storage
+	// cache. Caches of historic queries are not covered.
+	// By default, or if set to 0, cache sizes are not managed.
+	MemoryBudget = Property("MemoryBudget")
+	// HistoricViewCache is the number of historic blocks for which views are
+	// retained and shared among queries. Each view caches the accounts and
+	// storage slots looked up in its block, speeding up repeated queries on
+	// recent blocks. The hit rates of the cache are reported in the memory
+	// footprint of the state. By default, or if set to 0, views are not
+	// cached. Only supported by Go based configurations.
+	HistoricViewCache = Property("HistoricViewCache")
+	// StockChecksums enables, if set to a non-zero value, the creation of
+	// node stocks storing a checksum with each node, such that nodes
+	// corrupted on disk are detected when loaded or scrubbed. Stocks created
+	// before retain their layout. By default, no checksums are stored. Only
+	// supported by Go based schema 5 configurations.
+	StockChecksums = Property("StockChecksums")
 )
 
 // Properties are optional settings which may influence the
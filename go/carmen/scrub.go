@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// nodeScrubber is implemented by states able to check their nodes for
+// corruption.
+type nodeScrubber interface {
+	PrepareScrub() (func(context.Context, mpt.ScrubConfig) (live, archive mpt.ScrubReport, err error), error)
+}
+
+func (db *database) Scrub(ctx context.Context, nodesPerSecond int) (ScrubReport, error) {
+	if nodesPerSecond < 0 {
+		return ScrubReport{}, fmt.Errorf("invalid number of nodes per second: %d", nodesPerSecond)
+	}
+
+	db.lock.Lock()
+	if db.db == nil {
+		db.lock.Unlock()
+		return ScrubReport{}, errDbClosed
+	}
+	source := db.db
+	if _, ok := state.UnsafeUnwrapSyncedState(source).(nodeScrubber); !ok {
+		db.lock.Unlock()
+		return ScrubReport{}, fmt.Errorf("%w: scrubbing is not supported by this configuration", UnsupportedConfiguration)
+	}
+	// The scrub is registered as an archive query to prevent the database
+	// from being closed while the scrub is in progress.
+	db.numQueries++
+	db.lock.Unlock()
+	defer db.releaseArchiveQuery()
+
+	// The nodes to be scrubbed are collected under the state's lock to
+	// exclude concurrent updates, the scrub itself runs without the lock.
+	var scrub func(context.Context, mpt.ScrubConfig) (live, archive mpt.ScrubReport, err error)
+	err := state.RunWithUnwrappedState(source, func(s state.State) (err error) {
+		scrub, err = s.(nodeScrubber).PrepareScrub()
+		return err
+	})
+	if err != nil {
+		return ScrubReport{}, err
+	}
+	live, archive, err := scrub(ctx, mpt.ScrubConfig{NodesPerSecond: nodesPerSecond})
+	if err != nil {
+		return ScrubReport{}, err
+	}
+	res := ScrubReport{NumNodes: live.NumNodes + archive.NumNodes}
+	for _, node := range live.BadNodes {
+		res.BadNodes = append(res.BadNodes, BadNode{Id: node.Id.String(), Issue: node.Issue.Error()})
+	}
+	for _, node := range archive.BadNodes {
+		res.BadNodes = append(res.BadNodes, BadNode{Archive: true, Id: node.Id.String(), Issue: node.Issue.Error()})
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDatabase_ScrubOfIntactDatabaseFindsNoBadNodes(t *testing.T) {
+	properties := Properties{StockChecksums: "1"}
+	for name, value := range testProperties {
+		properties[name] = value
+	}
+	db, err := OpenDatabase(t.TempDir(), GetCarmenGoS5WithArchiveConfiguration(), properties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := db.AddBlock(uint64(i), func(context HeadBlockContext) error {
+			return context.RunTransaction(func(context TransactionContext) error {
+				context.CreateAccount(Address{byte(i)})
+				context.AddBalance(Address{byte(i)}, NewAmount(uint64(i+1)))
+				context.SetState(Address{byte(i)}, Key{1}, Value{byte(i + 1)})
+				return nil
+			})
+		}); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+
+	report, err := db.Scrub(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("failed to scrub database: %v", err)
+	}
+	if report.NumNodes == 0 {
+		t.Errorf("no nodes have been scrubbed")
+	}
+	if len(report.BadNodes) != 0 {
+		t.Errorf("intact database should have no bad nodes, got %v", report.BadNodes)
+	}
+}
+
+func TestDatabase_ScrubRejectsNegativeRate(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Scrub(context.Background(), -1); err == nil {
+		t.Errorf("negative number of nodes per second should be rejected")
+	}
+}
+
+func TestDatabase_ScrubOnClosedDatabaseFails(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if _, err := db.Scrub(context.Background(), 0); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// stateStatsSource is implemented by states maintaining per-block statistics
+// of the size of the state.
+type stateStatsSource interface {
+	GetStateStats(from, to uint64) ([]mpt.StateStats, error)
+}
+
+func (db *database) GetStateStats(from, to uint64) ([]StateStats, error) {
+	db.lock.Lock()
+	if db.db == nil {
+		db.lock.Unlock()
+		return nil, errDbClosed
+	}
+	source, ok := state.UnsafeUnwrapSyncedState(db.db).(stateStatsSource)
+	db.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: state statistics are not supported by this configuration", UnsupportedConfiguration)
+	}
+	stats, err := source.GetStateStats(from, to)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]StateStats, 0, len(stats))
+	for i, cur := range stats {
+		res = append(res, StateStats{
+			Block:       from + uint64(i),
+			NumAccounts: cur.NumAccounts,
+			NumSlots:    cur.NumSlots,
+			CodeBytes:   cur.CodeBytes,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDatabase_StateStatsCanBeQueried(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 4; i++ {
+		if err := db.AddBlock(uint64(i), func(context HeadBlockContext) error {
+			return context.RunTransaction(func(context TransactionContext) error {
+				addr := Address{byte(i + 1)}
+				context.CreateAccount(addr)
+				context.SetNonce(addr, 1)
+				context.SetCode(addr, make([]byte, 10))
+				context.SetState(addr, Key{1}, Value{1})
+				if i == 3 {
+					context.SelfDestruct(Address{1})
+				}
+				return nil
+			})
+		}); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("failed to flush database: %v", err)
+	}
+
+	stats, err := db.GetStateStats(1, 3)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	want := []StateStats{
+		{Block: 1, NumAccounts: 2, NumSlots: 2, CodeBytes: 20},
+		{Block: 2, NumAccounts: 3, NumSlots: 3, CodeBytes: 30},
+		{Block: 3, NumAccounts: 3, NumSlots: 3, CodeBytes: 30},
+	}
+	if !slices.Equal(want, stats) {
+		t.Errorf("unexpected state statistics, wanted %v, got %v", want, stats)
+	}
+	if _, err := db.GetStateStats(0, 4); err == nil {
+		t.Errorf("querying blocks not covered by the archive should fail")
+	}
+}
+
+func TestDatabase_StateStatsRequireArchive(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), GetCarmenGoS5WithoutArchiveConfiguration(), testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetStateStats(0, 0); err == nil {
+		t.Errorf("state statistics query without archive should fail")
+	}
+}
+
+func TestDatabase_StateStatsOnClosedDatabaseFail(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if _, err := db.GetStateStats(0, 0); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+}
@@ -49,6 +49,13 @@ func (t *transactionContext) SelfDestruct(address Address) bool {
 	return false
 }
 
+func (t *transactionContext) SelfDestruct6780(address Address) bool {
+	if t.state != nil {
+		return t.state.SuicideNewContract(common.Address(address))
+	}
+	return false
+}
+
 func (t *transactionContext) HasSelfDestructed(address Address) bool {
 	if t.state != nil {
 		return t.state.HasSuicided(common.Address(address))
@@ -11,6 +11,7 @@
 package carmen
 
 import (
+	"context"
 	"testing"
 
 	"github.com/Fantom-foundation/Carmen/go/common"
@@ -133,6 +134,7 @@ func TestTransaction_Operations_Passthrough(t *testing.T) {
 	stateDB.EXPECT().Exist(gomock.Any())
 	stateDB.EXPECT().Empty(gomock.Any())
 	stateDB.EXPECT().Suicide(gomock.Any())
+	stateDB.EXPECT().SuicideNewContract(gomock.Any())
 	stateDB.EXPECT().HasSuicided(gomock.Any())
 	stateDB.EXPECT().GetBalance(gomock.Any())
 	stateDB.EXPECT().AddBalance(gomock.Any(), gomock.Any())
@@ -178,6 +180,7 @@ func TestTransaction_Operations_Passthrough(t *testing.T) {
 	tx.Exist(address)
 	tx.Empty(address)
 	tx.SelfDestruct(address)
+	tx.SelfDestruct6780(address)
 	tx.HasSelfDestructed(address)
 	tx.GetBalance(address)
 	tx.AddBalance(address, NewAmount(100))
@@ -237,6 +240,7 @@ func TestTransaction_AfterCommitAllOperationsAreNoops(t *testing.T) {
 	tx.Exist(address)
 	tx.Empty(address)
 	tx.SelfDestruct(address)
+	tx.SelfDestruct6780(address)
 	tx.HasSelfDestructed(address)
 	tx.GetBalance(address)
 	tx.AddBalance(address, NewAmount(100))
@@ -265,3 +269,87 @@ func TestTransaction_AfterCommitAllOperationsAreNoops(t *testing.T) {
 	tx.Snapshot()
 	tx.RevertToSnapshot(10)
 }
+
+func TestTransaction_SelfDestruct6780_LiveAndArchiveAreConsistent(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	existing, created, legacy := Address{1}, Address{2}, Address{3}
+	runBlock := func(block uint64, run func(TransactionContext)) {
+		t.Helper()
+		if err := db.AddBlock(block, func(context HeadBlockContext) error {
+			return context.RunTransaction(func(context TransactionContext) error {
+				run(context)
+				return nil
+			})
+		}); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+
+	runBlock(0, func(tx TransactionContext) {
+		for _, addr := range []Address{existing, legacy} {
+			tx.CreateAccount(addr)
+			tx.AddBalance(addr, NewAmount(10))
+			tx.SetState(addr, Key{1}, Value{1})
+		}
+	})
+	runBlock(1, func(tx TransactionContext) {
+		// Accounts not created in this transaction are retained.
+		if tx.SelfDestruct6780(existing) {
+			t.Errorf("existing account should not be destructed")
+		}
+		tx.CreateAccount(created)
+		tx.AddBalance(created, NewAmount(10))
+		tx.SetState(created, Key{1}, Value{1})
+		if !tx.SelfDestruct6780(created) {
+			t.Errorf("account created in the transaction should be destructed")
+		}
+		if !tx.SelfDestruct(legacy) {
+			t.Errorf("legacy self-destruct should destruct existing account")
+		}
+	})
+
+	check := func(context QueryContext) {
+		for _, addr := range []Address{created, legacy} {
+			if got := context.GetBalance(addr); got != NewAmount() {
+				t.Errorf("unexpected balance of deleted account %v, wanted 0, got %v", addr, got)
+			}
+			if got := context.GetState(addr, Key{1}); got != (Value{}) {
+				t.Errorf("unexpected storage of deleted account %v, wanted zero, got %v", addr, got)
+			}
+		}
+		if got := context.GetBalance(existing); got != NewAmount(10) {
+			t.Errorf("unexpected balance of retained account, wanted 10, got %v", got)
+		}
+		if got := context.GetState(existing, Key{1}); got != (Value{1}) {
+			t.Errorf("unexpected storage of retained account, wanted %v, got %v", Value{1}, got)
+		}
+	}
+
+	var headHash Hash
+	if err := db.QueryHeadState(func(context QueryContext) {
+		check(context)
+		headHash = context.GetStateHash()
+	}); err != nil {
+		t.Fatalf("failed to query head state: %v", err)
+	}
+	if err := db.WaitForArchiveBlock(context.Background(), 1); err != nil {
+		t.Fatalf("failed to wait for archive: %v", err)
+	}
+	if err := db.QueryHistoricState(1, func(context QueryContext) {
+		check(context)
+		if got := context.GetStateHash(); got != headHash {
+			t.Errorf("archive and LiveDB are inconsistent, wanted hash %v, got %v", headHash, got)
+		}
+	}); err != nil {
+		t.Fatalf("failed to query historic state: %v", err)
+	}
+}
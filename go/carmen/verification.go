@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// backgroundVerifier is implemented by states able to verify their archive
+// incrementally while blocks are added.
+type backgroundVerifier interface {
+	RunBackgroundVerification(ctx context.Context, config mpt.BackgroundVerificationConfig, observer mpt.VerificationObserver) error
+}
+
+func (db *database) RunArchiveVerification(ctx context.Context, interval time.Duration, nodesPerSecond int) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid verification interval: %v", interval)
+	}
+	if nodesPerSecond < 0 {
+		return fmt.Errorf("invalid number of nodes per second: %d", nodesPerSecond)
+	}
+
+	db.lock.Lock()
+	if db.db == nil {
+		db.lock.Unlock()
+		return errDbClosed
+	}
+	verifier, ok := state.UnsafeUnwrapSyncedState(db.db).(backgroundVerifier)
+	if !ok {
+		db.lock.Unlock()
+		return fmt.Errorf("%w: archive verification is not supported by this configuration", UnsupportedConfiguration)
+	}
+	// The verification is registered as an archive query to prevent the
+	// database from being closed while the verification is running.
+	db.numQueries++
+	db.lock.Unlock()
+	defer db.releaseArchiveQuery()
+
+	return verifier.RunBackgroundVerification(ctx, mpt.BackgroundVerificationConfig{
+		Interval:       interval,
+		NodesPerSecond: nodesPerSecond,
+	}, nil)
+}
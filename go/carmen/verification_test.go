@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDatabase_RunArchiveVerification_VerifiesArchiveUntilStopped(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDatabase(dir, testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := db.AddBlock(uint64(i), func(context HeadBlockContext) error {
+			return context.RunTransaction(func(context TransactionContext) error {
+				context.CreateAccount(Address{byte(i)})
+				context.AddBalance(Address{byte(i)}, NewAmount(uint64(i+1)))
+				return nil
+			})
+		}); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	if err := db.WaitForArchiveBlock(context.Background(), 4); err != nil {
+		t.Fatalf("failed to wait for archive: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- db.RunArchiveVerification(ctx, 10*time.Millisecond, 0)
+	}()
+
+	checkpoint := filepath.Join(dir, "archive", "verification.json")
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		if _, err := os.Stat(checkpoint); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no verification checkpoint has been written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The database can not be closed while the verification is running.
+	if err := db.Close(); !errors.Is(err, errBlockContextRunning) {
+		t.Errorf("unexpected error, wanted %v, got %v", errBlockContextRunning, err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("stopped verification should not report an error, got %v", err)
+	}
+}
+
+func TestDatabase_RunArchiveVerification_RejectsInvalidParameters(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.RunArchiveVerification(context.Background(), -time.Second, 0); err == nil {
+		t.Errorf("negative interval should be rejected")
+	}
+	if err := db.RunArchiveVerification(context.Background(), time.Second, -1); err == nil {
+		t.Errorf("negative number of nodes per second should be rejected")
+	}
+}
+
+func TestDatabase_RunArchiveVerification_RequiresArchive(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testNonArchiveConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := db.RunArchiveVerification(context.Background(), time.Second, 0); err == nil {
+		t.Errorf("verification without archive should fail")
+	}
+}
+
+func TestDatabase_RunArchiveVerification_OnClosedDatabaseFails(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if err := db.RunArchiveVerification(context.Background(), time.Second, 0); !errors.Is(err, errDbClosed) {
+		t.Errorf("unexpected error, wanted %v, got %v", errDbClosed, err)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// witnessStorageCacheSize is the size of the storage cache used for witness
+// databases. Witnesses only cover the storage accessed by a single block,
+// thus a small cache is sufficient.
+const witnessStorageCacheSize = 1024
+
+func openWitnessDatabase(witness Witness, root Hash) (Database, error) {
+	parsed, err := mpt.WitnessFromBytes(witness)
+	if err != nil {
+		return nil, err
+	}
+	db, err := mpt.OpenWitnessState(parsed, common.Hash(root))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open witness database: %w", err)
+	}
+	statedb := state.CreateCustomStateDBUsing(db, witnessStorageCacheSize)
+	return openStateDb("", db, statedb, checkpointPolicy{}, 0)
+}
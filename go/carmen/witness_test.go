@@ -0,0 +1,280 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package carmen
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"github.com/Fantom-foundation/Carmen/go/state/gostate"
+)
+
+func witnessTestBlock(block int) func(HeadBlockContext) error {
+	return func(context HeadBlockContext) error {
+		return context.RunTransaction(func(context TransactionContext) error {
+			for i := 0; i < 20; i++ {
+				addr := Address{byte(i)}
+				if block == 0 {
+					context.CreateAccount(addr)
+					context.SetCode(addr, []byte{byte(i), 1, 2, 3})
+				}
+				context.AddBalance(addr, NewAmount(uint64(block*i+1)))
+				context.SetState(addr, Key{byte(block)}, Value{byte(i + 1)})
+				if got, want := len(context.GetCode(addr)), 4; got != want {
+					return fmt.Errorf("unexpected code size of account %x, wanted %d, got %d", addr, want, got)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func getHeadStateHash(t *testing.T, db Database) Hash {
+	t.Helper()
+	var hash Hash
+	if err := db.QueryHeadState(func(context QueryContext) {
+		hash = context.GetStateHash()
+	}); err != nil {
+		t.Fatalf("failed to query head state: %v", err)
+	}
+	return hash
+}
+
+func TestWitness_BlockCanBeReExecutedOnWitnessDatabase(t *testing.T) {
+	for _, config := range []Configuration{testConfig, testNonArchiveConfig} {
+		t.Run(string(config.Variant), func(t *testing.T) {
+			db, err := OpenDatabase(t.TempDir(), config, testProperties)
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			defer db.Close()
+
+			if err := db.AddBlock(0, witnessTestBlock(0)); err != nil {
+				t.Fatalf("failed to add block: %v", err)
+			}
+			preHash := getHeadStateHash(t, db)
+
+			context, err := db.BeginBlockWithWitness(1)
+			if err != nil {
+				t.Fatalf("failed to start block: %v", err)
+			}
+			if err := witnessTestBlock(1)(context); err != nil {
+				t.Fatalf("failed to run block: %v", err)
+			}
+			witness, err := context.CommitWithWitness()
+			if err != nil {
+				t.Fatalf("failed to commit block: %v", err)
+			}
+			postHash := getHeadStateHash(t, db)
+
+			witnessDb, err := OpenWitnessDatabase(witness, preHash)
+			if err != nil {
+				t.Fatalf("failed to open witness database: %v", err)
+			}
+			defer witnessDb.Close()
+
+			if got, want := getHeadStateHash(t, witnessDb), preHash; got != want {
+				t.Errorf("unexpected pre-state hash, wanted %x, got %x", want, got)
+			}
+			if err := witnessDb.AddBlock(1, witnessTestBlock(1)); err != nil {
+				t.Fatalf("failed to re-execute block: %v", err)
+			}
+			if got, want := getHeadStateHash(t, witnessDb), postHash; got != want {
+				t.Errorf("unexpected post-state hash, wanted %x, got %x", want, got)
+			}
+		})
+	}
+}
+
+func TestWitness_ValuesReadInEarlierBlocksAreRecorded(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AddBlock(0, witnessTestBlock(0)); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	// Block 1 fills the storage cache of the database's state DB.
+	if err := db.AddBlock(1, witnessTestBlock(1)); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	preHash := getHeadStateHash(t, db)
+
+	read := func(context HeadBlockContext) error {
+		return context.RunTransaction(func(context TransactionContext) error {
+			for i := 0; i < 20; i++ {
+				context.AddBalance(Address{byte(i)}, NewAmount(1))
+				context.GetState(Address{byte(i)}, Key{1})
+			}
+			return nil
+		})
+	}
+
+	context, err := db.BeginBlockWithWitness(2)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	if err := read(context); err != nil {
+		t.Fatalf("failed to run block: %v", err)
+	}
+	witness, err := context.CommitWithWitness()
+	if err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+
+	witnessDb, err := OpenWitnessDatabase(witness, preHash)
+	if err != nil {
+		t.Fatalf("failed to open witness database: %v", err)
+	}
+	defer witnessDb.Close()
+	if err := witnessDb.AddBlock(2, read); err != nil {
+		t.Fatalf("failed to re-execute block: %v", err)
+	}
+	if got, want := getHeadStateHash(t, witnessDb), getHeadStateHash(t, db); got != want {
+		t.Errorf("unexpected post-state hash, wanted %x, got %x", want, got)
+	}
+}
+
+// cacheResetTrackingStateDB is a state DB recording whether its caches got
+// reset.
+type cacheResetTrackingStateDB struct {
+	state.StateDB
+	reset bool
+}
+
+func (s *cacheResetTrackingStateDB) ResetCaches() {
+	s.reset = true
+}
+
+func TestWitness_CachesOfHeadStateDBAreResetInsteadOfReplacingIt(t *testing.T) {
+	db, err := OpenDatabase(t.TempDir(), testConfig, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	impl := db.(*database)
+	head := &cacheResetTrackingStateDB{StateDB: impl.state}
+	impl.state = head
+
+	context, err := db.BeginBlockWithWitness(0)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	if !head.reset {
+		t.Errorf("caches of the head state DB should have been reset")
+	}
+	if err := witnessTestBlock(0)(context); err != nil {
+		t.Fatalf("failed to run block: %v", err)
+	}
+	if _, err := context.CommitWithWitness(); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	if impl.state != head {
+		t.Errorf("head state DB should have been retained")
+	}
+}
+
+func TestWitness_AccessingStateNotCoveredByWitnessFails(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AddBlock(0, witnessTestBlock(0)); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	preHash := getHeadStateHash(t, db)
+
+	context, err := db.BeginBlockWithWitness(1)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	witness, err := context.CommitWithWitness()
+	if err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+
+	witnessDb, err := OpenWitnessDatabase(witness, preHash)
+	if err != nil {
+		t.Fatalf("failed to open witness database: %v", err)
+	}
+	defer witnessDb.Close()
+
+	err = witnessDb.QueryHeadState(func(context QueryContext) {
+		context.GetBalance(Address{1})
+	})
+	if !errors.Is(err, mpt.MissingWitnessDataError) {
+		t.Errorf("unexpected error, wanted %v, got %v", mpt.MissingWitnessDataError, err)
+	}
+}
+
+func TestWitness_AbortedBlockLeavesDatabaseUsable(t *testing.T) {
+	db, err := openTestDatabase(t)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	context, err := db.BeginBlockWithWitness(1)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	if err := context.Abort(); err != nil {
+		t.Fatalf("failed to abort block: %v", err)
+	}
+	if err := db.AddBlock(1, witnessTestBlock(0)); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	context, err = db.BeginBlockWithWitness(2)
+	if err != nil {
+		t.Fatalf("failed to start block: %v", err)
+	}
+	if err := context.Commit(); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+}
+
+func TestWitness_UnsupportedConfigurationsAreDetected(t *testing.T) {
+	config := Configuration{
+		Variant: Variant(gostate.VariantGoMemory),
+		Schema:  1,
+		Archive: Archive(state.NoArchive),
+	}
+	db, err := OpenDatabase(t.TempDir(), config, testProperties)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.BeginBlockWithWitness(1); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+	context, err := db.BeginBlock(1)
+	if err != nil {
+		t.Fatalf("failed to start block after failed witness block: %v", err)
+	}
+	if err := context.Abort(); err != nil {
+		t.Fatalf("failed to abort block: %v", err)
+	}
+}
+
+func TestWitness_InvalidWitnessesAreRejected(t *testing.T) {
+	if _, err := OpenWitnessDatabase(Witness{1, 2, 3}, Hash{}); err == nil {
+		t.Errorf("opening an invalid witness should fail")
+	}
+}
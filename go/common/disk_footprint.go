@@ -0,0 +1,187 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package common
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiskFootprint describes the disk space occupied by a database structure.
+// Like the MemoryFootprint, it forms a tree of the structure's components.
+type DiskFootprint struct {
+	value       uint64
+	reclaimable uint64
+	children    map[string]*DiskFootprint
+	note        string
+}
+
+// NewDiskFootprint creates a new DiskFootprint instance for a database
+// structure occupying the given number of bytes on disk.
+func NewDiskFootprint(value uint64) *DiskFootprint {
+	return &DiskFootprint{
+		value:    value,
+		children: make(map[string]*DiskFootprint),
+	}
+}
+
+// GetFileDiskFootprint creates a DiskFootprint covering the file with the
+// given path. A missing file occupies no disk space.
+func GetFileDiskFootprint(path string) (*DiskFootprint, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewDiskFootprint(0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewDiskFootprint(uint64(info.Size())), nil
+}
+
+// GetDirectoryDiskFootprint creates a DiskFootprint covering all files in
+// the given directory and its sub-directories. A missing directory occupies
+// no disk space.
+func GetDirectoryDiskFootprint(directory string) (*DiskFootprint, error) {
+	size := uint64(0)
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewDiskFootprint(0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewDiskFootprint(size), nil
+}
+
+// SetNote allows to attach a string comment to the disk report
+func (df *DiskFootprint) SetNote(note string) {
+	df.note = note
+}
+
+// GetNote returns the comment attached to the disk report
+func (df *DiskFootprint) GetNote() string {
+	return df.note
+}
+
+// SetReclaimable sets the number of bytes of this structure (excluding its
+// subcomponents) occupied by freed entries, which may be reused.
+func (df *DiskFootprint) SetReclaimable(reclaimable uint64) {
+	df.reclaimable = reclaimable
+}
+
+// AddChild allows to attach a DiskFootprint of the database structure subcomponent
+func (df *DiskFootprint) AddChild(name string, child *DiskFootprint) {
+	df.children[name] = child
+}
+
+// GetChild returns a child of the disk footprint with the given name
+func (df *DiskFootprint) GetChild(name string) *DiskFootprint {
+	return df.children[name]
+}
+
+// GetChildNames returns the names of all children of the disk footprint in
+// sorted order.
+func (df *DiskFootprint) GetChildNames() []string {
+	names := make([]string, 0, len(df.children))
+	for name := range df.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Value provides the amount of bytes occupied by the database structure (excluding its subcomponents)
+func (df *DiskFootprint) Value() uint64 {
+	return df.value
+}
+
+// Reclaimable provides the amount of bytes occupied by freed entries of the database structure (excluding its subcomponents)
+func (df *DiskFootprint) Reclaimable() uint64 {
+	return df.reclaimable
+}
+
+// Total provides the amount of bytes occupied by the database structure including all its subcomponents
+func (df *DiskFootprint) Total() uint64 {
+	if df == nil {
+		return 0
+	}
+	total := df.value
+	for _, child := range df.children {
+		total += child.Total()
+	}
+	return total
+}
+
+// TotalReclaimable provides the amount of bytes occupied by freed entries of the database structure including all its subcomponents
+func (df *DiskFootprint) TotalReclaimable() uint64 {
+	if df == nil {
+		return 0
+	}
+	total := df.reclaimable
+	for _, child := range df.children {
+		total += child.TotalReclaimable()
+	}
+	return total
+}
+
+// ToString provides the disk footprint as a tree summary in a string
+// The name param allows to give a name to the root of the tree.
+func (df *DiskFootprint) ToString(name string) string {
+	var sb strings.Builder
+	df.toStringBuilder(&sb, name)
+	return sb.String()
+}
+
+// Allow disk footprints to be used in format strings.
+func (df *DiskFootprint) String() string {
+	return df.ToString(".")
+}
+
+func (df *DiskFootprint) toStringBuilder(sb *strings.Builder, path string) {
+	if df == nil {
+		return
+	}
+	// Print children in order for simpler comparison.
+	for _, name := range df.GetChildNames() {
+		df.children[name].toStringBuilder(sb, path+"/"+name)
+	}
+
+	// Show sum at the bottom.
+	memoryAmountToString(sb, df.Total())
+	sb.WriteRune(' ')
+	sb.WriteString(path)
+	if reclaimable := df.TotalReclaimable(); reclaimable > 0 {
+		sb.WriteString(" (reclaimable: ")
+		memoryAmountToString(sb, reclaimable)
+		sb.WriteRune(')')
+	}
+	if len(df.note) != 0 {
+		sb.WriteRune(' ')
+		sb.WriteString(df.note)
+	}
+	sb.WriteRune('\n')
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskFootprintIsFormatable(t *testing.T) {
+	fp := NewDiskFootprint(12)
+	left := NewDiskFootprint(50 * 1024)
+	left.SetReclaimable(10 * 1024)
+	fp.AddChild("left", left)
+	fp.AddChild("right", NewDiskFootprint(10*1024*1024+200*1024))
+
+	print := fmt.Sprintf("%v", fp)
+	expectSubstr(t, print, "10.2 MB . (reclaimable:   10.0 KB)")
+	expectSubstr(t, print, "50.0 KB ./left (reclaimable:   10.0 KB)")
+	expectSubstr(t, print, "10.2 MB ./right\n")
+}
+
+func TestDiskFootprintContainsNote(t *testing.T) {
+	fp := NewDiskFootprint(12)
+	fp.SetNote("Hello")
+
+	if !strings.Contains(fp.String(), "Hello") {
+		t.Errorf("note not printed")
+	}
+}
+
+func TestDiskFootprint_TotalsIncludeChildren(t *testing.T) {
+	fp := NewDiskFootprint(12)
+	child := NewDiskFootprint(30)
+	child.SetReclaimable(20)
+	fp.AddChild("x", child)
+	fp.AddChild("y", nil)
+	fp.SetReclaimable(2)
+
+	if got, want := fp.Value(), uint64(12); got != want {
+		t.Errorf("value does not match: %d != %d", got, want)
+	}
+	if got, want := fp.Total(), uint64(42); got != want {
+		t.Errorf("total does not match: %d != %d", got, want)
+	}
+	if got, want := fp.Reclaimable(), uint64(2); got != want {
+		t.Errorf("reclaimable does not match: %d != %d", got, want)
+	}
+	if got, want := fp.TotalReclaimable(), uint64(22); got != want {
+		t.Errorf("total reclaimable does not match: %d != %d", got, want)
+	}
+	if got := fp.GetChild("x"); got != child {
+		t.Errorf("unexpected child: %v", got)
+	}
+	if got, want := fmt.Sprintf("%v", fp.GetChildNames()), "[x y]"; got != want {
+		t.Errorf("unexpected child names: %s != %s", got, want)
+	}
+}
+
+func TestDiskFootprint_FilesAndDirectoriesAreMeasured(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), make([]byte, 12), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 30), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if fp, err := GetFileDiskFootprint(filepath.Join(dir, "a")); err != nil || fp.Total() != 12 {
+		t.Errorf("unexpected file footprint: %v, err %v", fp, err)
+	}
+	if fp, err := GetFileDiskFootprint(filepath.Join(dir, "missing")); err != nil || fp.Total() != 0 {
+		t.Errorf("unexpected footprint of missing file: %v, err %v", fp, err)
+	}
+	if fp, err := GetDirectoryDiskFootprint(dir); err != nil || fp.Total() != 42 {
+		t.Errorf("unexpected directory footprint: %v, err %v", fp, err)
+	}
+	if fp, err := GetDirectoryDiskFootprint(filepath.Join(dir, "missing")); err != nil || fp.Total() != 0 {
+		t.Errorf("unexpected footprint of missing directory: %v, err %v", fp, err)
+	}
+}
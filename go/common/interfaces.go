@@ -29,6 +29,39 @@ type MemoryFootprintProvider interface {
 	GetMemoryFootprint() *MemoryFootprint
 }
 
+// DiskFootprintProvider is implemented by data structures retaining data on
+// disk and able to report a breakdown of the occupied disk space.
+type DiskFootprintProvider interface {
+	GetDiskFootprint() (*DiskFootprint, error)
+}
+
+// AdaptiveCache is implemented by caches whose capacity may be adjusted while
+// they are in use. Unless stated otherwise by the provider of a cache, its
+// methods must not be called concurrently to other operations on the owner
+// of the cache.
+type AdaptiveCache interface {
+	// GetCacheStatistics returns a summary of the current usage of the cache.
+	GetCacheStatistics() CacheStatistics
+	// SetCacheCapacity changes the maximum number of entries retained by the
+	// cache. The capacity is clamped to the range supported by the cache.
+	// Implementations may defer the application of the new capacity, which
+	// is reflected by the statistics once it is in effect.
+	SetCacheCapacity(capacity int)
+}
+
+// CacheStatistics summarizes the usage of an AdaptiveCache.
+type CacheStatistics struct {
+	Capacity    int    // < the maximum number of entries currently retained
+	MinCapacity int    // < the minimum capacity required for correct operation
+	MaxCapacity int    // < the maximum capacity supported by the cache, 0 if unbounded
+	Size        int    // < the number of entries currently retained
+	EntrySize   uint64 // < the estimated number of bytes occupied per entry
+	Overhead    uint64 // < bytes occupied independently of the number of entries
+	Memory      uint64 // < bytes occupied in total as reported by the memory footprint
+	Hits        uint64 // < the total number of lookups served by the cache
+	Misses      uint64 // < the total number of lookups not served by the cache
+}
+
 type Hasher[K any] interface {
 	Hash(*K) uint64
 }
@@ -0,0 +1,25 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+//go:build !cgo || purego
+
+package common
+
+func Keccak256(data []byte) Hash {
+	return keccak256_Go(data)
+}
+
+func Keccak256ForAddress(addr Address) Hash {
+	return keccak256_Go(addr[:])
+}
+
+func Keccak256ForKey(key Key) Hash {
+	return keccak256_Go(key[:])
+}
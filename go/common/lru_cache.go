@@ -21,6 +21,8 @@ type LruCache[K comparable, V any] struct {
 	capacity int
 	head     *entry[K, V]
 	tail     *entry[K, V]
+	hits     uint64 // number of successful lookups
+	misses   uint64 // number of failed lookups
 }
 
 // NewLruCache returns a new instance
@@ -56,6 +58,9 @@ func (c *LruCache[K, V]) Get(key K) (V, bool) {
 	if exists {
 		val = item.val
 		c.touch(item)
+		c.hits++
+	} else {
+		c.misses++
 	}
 
 	return val, exists
@@ -146,6 +151,44 @@ func (c *LruCache[K, V]) Remove(key K) (original V, exists bool) {
 	return
 }
 
+// SetCapacity changes the maximum number of entries retained by this cache.
+// If the cache contains more entries, the least recently used are dropped.
+// The capacity is at least 1.
+func (c *LruCache[K, V]) SetCapacity(capacity int) {
+	if capacity < 1 {
+		capacity = 1
+	}
+	for len(c.cache) > capacity {
+		c.dropLast()
+	}
+	if capacity < c.capacity {
+		// Maps do not shrink, the remaining entries are moved to a new map
+		// to release the memory reserved for the previous capacity.
+		cache := make(map[K]*entry[K, V], capacity)
+		for key, item := range c.cache {
+			cache[key] = item
+		}
+		c.cache = cache
+	}
+	c.capacity = capacity
+}
+
+// Capacity returns the maximum number of entries retained by this cache.
+func (c *LruCache[K, V]) Capacity() int {
+	return c.capacity
+}
+
+// Size returns the number of entries currently retained by this cache.
+func (c *LruCache[K, V]) Size() int {
+	return len(c.cache)
+}
+
+// GetLookupCounts returns the number of lookups served and not served by this
+// cache since its creation.
+func (c *LruCache[K, V]) GetLookupCounts() (hits, misses uint64) {
+	return c.hits, c.misses
+}
+
 func (c *LruCache[K, V]) Clear() {
 	if len(c.cache) > 0 {
 		c.cache = make(map[K]*entry[K, V], c.capacity)
@@ -209,6 +252,53 @@ func (c *LruCache[K, V]) GetDynamicMemoryFootprint(valueSizeProvider func(V) uin
 	return mf
 }
 
+// NewAdaptiveLruCache wraps the given cache into an AdaptiveCache. The memory
+// referenced by values is obtained from the given provider, which may be nil
+// if values do not reference any memory. Like the cache itself, the result is
+// not thread safe.
+func NewAdaptiveLruCache[K comparable, V any](cache *LruCache[K, V], valueSizeProvider func(V) uintptr) AdaptiveCache {
+	return &adaptiveLruCache[K, V]{cache: cache, valueSizeProvider: valueSizeProvider}
+}
+
+type adaptiveLruCache[K comparable, V any] struct {
+	cache             *LruCache[K, V]
+	valueSizeProvider func(V) uintptr // < nil if values do not reference memory
+}
+
+func (c *adaptiveLruCache[K, V]) GetCacheStatistics() CacheStatistics {
+	// Footprints account for a pointer per entry of the capacity and the
+	// actual entries, including the memory referenced by their values.
+	overhead := unsafe.Sizeof(*c.cache)
+	pointerSize := unsafe.Sizeof(&entry[K, V]{})
+	entrySize := pointerSize + unsafe.Sizeof(entry[K, V]{})
+	var memory uintptr
+	if c.valueSizeProvider == nil {
+		memory = c.cache.GetMemoryFootprint(0).Value()
+	} else {
+		memory = c.cache.GetDynamicMemoryFootprint(c.valueSizeProvider).Value()
+		size := uintptr(len(c.cache.cache))
+		slots := overhead + uintptr(c.cache.capacity)*pointerSize
+		if size > 0 && memory > slots {
+			entrySize = pointerSize + (memory-slots)/size
+		}
+	}
+	hits, misses := c.cache.GetLookupCounts()
+	return CacheStatistics{
+		Capacity:    c.cache.capacity,
+		MinCapacity: 1,
+		Size:        len(c.cache.cache),
+		EntrySize:   uint64(entrySize),
+		Overhead:    uint64(overhead),
+		Memory:      uint64(memory),
+		Hits:        hits,
+		Misses:      misses,
+	}
+}
+
+func (c *adaptiveLruCache[K, V]) SetCacheCapacity(capacity int) {
+	c.cache.SetCapacity(capacity)
+}
+
 // entry is a cache item wrapping an index, a key and references to previous and next elements.
 type entry[K comparable, V any] struct {
 	key  K
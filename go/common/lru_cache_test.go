@@ -40,6 +40,56 @@ func TestLruExceedCapacity(t *testing.T) {
 	}
 }
 
+func TestLruCache_SetCapacity_DropsLeastRecentlyUsedEntries(t *testing.T) {
+	c := NewLruCache[int, int](4)
+	for i := 1; i <= 4; i++ {
+		c.Set(i, i*11)
+	}
+	c.Get(1) // 2 is now the least recently used entry
+
+	c.SetCapacity(2)
+	if got, want := c.Capacity(), 2; got != want {
+		t.Errorf("unexpected capacity, wanted %d, got %d", want, got)
+	}
+	if got, want := c.Size(), 2; got != want {
+		t.Errorf("unexpected size, wanted %d, got %d", want, got)
+	}
+	for _, key := range []int{2, 3} {
+		if _, exists := c.Get(key); exists {
+			t.Errorf("item %d should be evicted", key)
+		}
+	}
+	for _, key := range []int{1, 4} {
+		if _, exists := c.Get(key); !exists {
+			t.Errorf("item %d should be retained", key)
+		}
+	}
+
+	c.SetCapacity(3)
+	if _, _, evicted := c.Set(5, 55); evicted {
+		t.Errorf("no item should be evicted after increasing the capacity")
+	}
+
+	c.SetCapacity(0)
+	if got, want := c.Capacity(), 1; got != want {
+		t.Errorf("unexpected capacity, wanted %d, got %d", want, got)
+	}
+	if _, exists := c.Get(5); !exists {
+		t.Errorf("most recently used item should be retained")
+	}
+}
+
+func TestLruCache_GetLookupCounts_CountsHitsAndMisses(t *testing.T) {
+	c := NewLruCache[int, int](2)
+	c.Set(1, 11)
+	c.Get(1)
+	c.Get(1)
+	c.Get(2)
+	if hits, misses := c.GetLookupCounts(); hits != 2 || misses != 1 {
+		t.Errorf("unexpected lookup counts, wanted 2/1, got %d/%d", hits, misses)
+	}
+}
+
 // TestLRUOrder test correct ordering of the keys
 func TestLRUOrder(t *testing.T) {
 	c := NewLruCache[int, int](3)
@@ -108,3 +158,34 @@ func TestCache_Entry_String(t *testing.T) {
 		t.Errorf("provided string does not match: %s != %s", got, want)
 	}
 }
+
+func TestAdaptiveLruCache_StatisticsReflectCacheUsage(t *testing.T) {
+	c := NewLruCache[int, []byte](10)
+	adaptive := NewAdaptiveLruCache(c, func(value []byte) uintptr {
+		return uintptr(len(value))
+	})
+	c.Set(1, make([]byte, 100))
+	c.Set(2, make([]byte, 300))
+	c.Get(1)
+	c.Get(3)
+
+	stats := adaptive.GetCacheStatistics()
+	if stats.Capacity != 10 || stats.MinCapacity != 1 || stats.MaxCapacity != 0 || stats.Size != 2 {
+		t.Errorf("unexpected capacity statistics: %+v", stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected lookup counts: %+v", stats)
+	}
+	if want, got := uint64(c.GetDynamicMemoryFootprint(func(value []byte) uintptr { return uintptr(len(value)) }).Value()), stats.Memory; want != got {
+		t.Errorf("unexpected memory, wanted %d, got %d", want, got)
+	}
+	fixed := NewAdaptiveLruCache(NewLruCache[int, []byte](10), nil).GetCacheStatistics()
+	if want, got := fixed.EntrySize+200, stats.EntrySize; want != got {
+		t.Errorf("entry size should reflect the average value size, wanted %d, got %d", want, got)
+	}
+
+	adaptive.SetCacheCapacity(1)
+	if got := adaptive.GetCacheStatistics(); got.Capacity != 1 || got.Size != 1 {
+		t.Errorf("capacity should be reduced, got %+v", got)
+	}
+}
@@ -101,7 +101,7 @@ func (mf *MemoryFootprint) toStringBuilder(sb *strings.Builder, path string) {
 	}
 
 	// Show sum at the bottom.
-	memoryAmountToString(sb, mf.Total())
+	memoryAmountToString(sb, uint64(mf.Total()))
 	sb.WriteRune(' ')
 	sb.WriteString(path)
 	if len(mf.note) != 0 {
@@ -111,7 +111,7 @@ func (mf *MemoryFootprint) toStringBuilder(sb *strings.Builder, path string) {
 	sb.WriteRune('\n')
 }
 
-func memoryAmountToString(sb *strings.Builder, bytes uintptr) {
+func memoryAmountToString(sb *strings.Builder, bytes uint64) {
 	const unit = 1024
 	const prefixes = " KMGTPE"
 	div, exp := 1, 0
@@ -0,0 +1,310 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/archive"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+)
+
+// ArchiveTrie is an archive implementation retaining the state of every
+// block in a binary trie. Consecutive blocks share unmodified nodes, only
+// nodes modified by a block are copied.
+type ArchiveTrie struct {
+	directory string
+	lock      common.LockFile
+	mutex     sync.Mutex
+	store     *nodeStore
+	head      trie
+	roots     []root
+	err       error // < an error encountered while adding blocks, making the archive unusable
+}
+
+// root is the root of the trie of a single block and its hash.
+type root struct {
+	id   NodeId
+	hash common.Hash
+}
+
+var _ archive.Archive = (*ArchiveTrie)(nil)
+
+// OpenArchiveTrie opens a file based archive in the given directory, keeping
+// the given number of nodes cached in memory.
+func OpenArchiveTrie(directory string, cacheCapacity int) (*ArchiveTrie, error) {
+	lock, err := mpt.LockDirectory(directory)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := readRoots(filepath.Join(directory, rootsFileName))
+	if err != nil {
+		return nil, errors.Join(err, lock.Release())
+	}
+	store, err := openNodeStore(directory, FileStock, cacheCapacity)
+	if err != nil {
+		return nil, errors.Join(err, lock.Release())
+	}
+	head := EmptyId
+	if len(roots) > 0 {
+		head = roots[len(roots)-1].id
+	}
+	return &ArchiveTrie{
+		directory: directory,
+		lock:      lock,
+		store:     store,
+		head:      trie{forest: newForest(store, true), root: head},
+		roots:     roots,
+	}, nil
+}
+
+func (a *ArchiveTrie) Add(block uint64, update common.Update, _ any) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	if uint64(len(a.roots)) > block {
+		return fmt.Errorf("block %d already present", block)
+	}
+
+	// Mark skipped blocks as having no changes.
+	for uint64(len(a.roots)) < block {
+		hash, err := a.head.GetHash()
+		if err != nil {
+			return a.addError(err)
+		}
+		a.roots = append(a.roots, root{a.head.root, hash})
+	}
+
+	if err := update.ApplyTo(&a.head); err != nil {
+		return a.addError(err)
+	}
+	hash, err := a.head.forest.freeze(a.head.root)
+	if err != nil {
+		return a.addError(err)
+	}
+	a.roots = append(a.roots, root{a.head.root, hash})
+	return nil
+}
+
+func (a *ArchiveTrie) addError(err error) error {
+	a.err = errors.Join(a.err, err)
+	return a.err
+}
+
+func (a *ArchiveTrie) GetBlockHeight() (uint64, bool, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.roots) == 0 {
+		return 0, true, a.err
+	}
+	return uint64(len(a.roots) - 1), false, a.err
+}
+
+// getView returns a trie for the state of the given block. The result may
+// only be used while holding the archive's mutex.
+func (a *ArchiveTrie) getView(block uint64) (*trie, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	if block >= uint64(len(a.roots)) {
+		return nil, fmt.Errorf("invalid block: %d >= %d", block, len(a.roots))
+	}
+	return &trie{forest: a.head.forest, root: a.roots[block].id}, nil
+}
+
+func (a *ArchiveTrie) Exists(block uint64, account common.Address) (bool, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return false, err
+	}
+	return view.Exists(account)
+}
+
+func (a *ArchiveTrie) GetBalance(block uint64, account common.Address) (common.Balance, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return common.Balance{}, err
+	}
+	return view.GetBalance(account)
+}
+
+func (a *ArchiveTrie) GetCode(block uint64, account common.Address) ([]byte, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return nil, err
+	}
+	return view.GetCode(account)
+}
+
+func (a *ArchiveTrie) GetNonce(block uint64, account common.Address) (common.Nonce, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return common.Nonce{}, err
+	}
+	return view.GetNonce(account)
+}
+
+func (a *ArchiveTrie) GetStorage(block uint64, account common.Address, slot common.Key) (common.Value, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return common.Value{}, err
+	}
+	return view.GetStorage(account, slot)
+}
+
+func (a *ArchiveTrie) GetAccountHash(block uint64, account common.Address) (common.Hash, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return view.GetAccountHash(account)
+}
+
+func (a *ArchiveTrie) GetHash(block uint64) (common.Hash, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if _, err := a.getView(block); err != nil {
+		return common.Hash{}, err
+	}
+	return a.roots[block].hash, nil
+}
+
+// CreateProof creates a proof for the basic data and code hash of the given
+// account and the given storage slots of the account at the given block.
+func (a *ArchiveTrie) CreateProof(block uint64, address common.Address, keys ...common.Key) (*Proof, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	view, err := a.getView(block)
+	if err != nil {
+		return nil, err
+	}
+	return createAccountProof(view, address, keys)
+}
+
+// Check verifies the structural invariants of the trie of each block.
+func (a *ArchiveTrie) Check() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for block, root := range a.roots {
+		if err := a.head.forest.check(root.id); err != nil {
+			return fmt.Errorf("invalid trie of block %d: %w", block, err)
+		}
+	}
+	return nil
+}
+
+func (a *ArchiveTrie) GetMemoryFootprint() *common.MemoryFootprint {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	mf := common.NewMemoryFootprint(unsafe.Sizeof(*a))
+	mf.AddChild("nodes", a.store.GetMemoryFootprint())
+	mf.AddChild("roots", common.NewMemoryFootprint(uintptr(len(a.roots))*unsafe.Sizeof(root{})))
+	return mf
+}
+
+func (a *ArchiveTrie) GetDiskFootprint() (*common.DiskFootprint, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return getDiskFootprint(a.store, filepath.Join(a.directory, rootsFileName))
+}
+
+func (a *ArchiveTrie) Flush() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.flush()
+}
+
+func (a *ArchiveTrie) flush() error {
+	return errors.Join(
+		a.err,
+		a.store.Flush(),
+		writeRoots(filepath.Join(a.directory, rootsFileName), a.roots),
+	)
+}
+
+func (a *ArchiveTrie) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return errors.Join(
+		a.flush(),
+		a.store.Close(),
+		a.lock.Release(),
+	)
+}
+
+const rootsFileName = "roots.dat"
+
+func readRoots(filename string) ([]root, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	res := []root{}
+	reader := bufio.NewReader(file)
+	var entry [8 + 32]byte
+	for {
+		if _, err := io.ReadFull(reader, entry[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return res, nil
+			}
+			return nil, fmt.Errorf("invalid roots file %s: %w", filename, err)
+		}
+		var cur root
+		cur.id = NodeId(binary.BigEndian.Uint64(entry[:8]))
+		copy(cur.hash[:], entry[8:])
+		res = append(res, cur)
+	}
+}
+
+func writeRoots(filename string, roots []root) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, cur := range roots {
+		var entry [8 + 32]byte
+		binary.BigEndian.PutUint64(entry[:8], uint64(cur.id))
+		copy(entry[8:], cur.hash[:])
+		if _, err := writer.Write(entry[:]); err != nil {
+			return errors.Join(err, file.Close())
+		}
+	}
+	return errors.Join(writer.Flush(), file.Close())
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func TestArchiveTrie_EmptyArchiveHasNoBlocks(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	if _, empty, err := archive.GetBlockHeight(); err != nil || !empty {
+		t.Errorf("new archive should be empty, err %v", err)
+	}
+	if _, err := archive.GetHash(0); err == nil {
+		t.Errorf("fetching the hash of a missing block should fail")
+	}
+}
+
+func TestArchiveTrie_HistoricValuesCanBeRetrieved(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	address := common.Address{1}
+	key := common.Key{1}
+	for block := uint64(0); block < 10; block++ {
+		update := common.Update{
+			Balances: []common.BalanceUpdate{{Account: address, Balance: common.Balance{31: byte(block)}}},
+			Slots:    []common.SlotUpdate{{Account: address, Key: key, Value: common.Value{31: byte(block)}}},
+		}
+		if block == 0 {
+			update.CreatedAccounts = []common.Address{address}
+		}
+		if err := archive.Add(block, update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+
+	for block := uint64(0); block < 10; block++ {
+		if got, err := archive.GetBalance(block, address); err != nil || got != (common.Balance{31: byte(block)}) {
+			t.Errorf("unexpected balance at block %d: %x, err %v", block, got, err)
+		}
+		if got, err := archive.GetStorage(block, address, key); err != nil || got != (common.Value{31: byte(block)}) {
+			t.Errorf("unexpected value at block %d: %x, err %v", block, got, err)
+		}
+	}
+	if err := archive.Check(); err != nil {
+		t.Errorf("invalid archive: %v", err)
+	}
+}
+
+func TestArchiveTrie_HashesMatchLiveState(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	live, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer live.Close()
+
+	r := rand.New(rand.NewSource(0))
+	for block := uint64(0); block < 5; block++ {
+		update := getRandomUpdate(r, 10, 10)
+		if _, err := live.Apply(block, update); err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+		if err := archive.Add(block, update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+		want, err := live.GetHash()
+		if err != nil {
+			t.Fatalf("failed to get live hash: %v", err)
+		}
+		if got, err := archive.GetHash(block); err != nil || got != want {
+			t.Errorf("unexpected hash of block %d, wanted %x, got %x, err %v", block, want, got, err)
+		}
+	}
+}
+
+func TestArchiveTrie_SkippedBlocksShareState(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	address := common.Address{1}
+	if err := archive.Add(2, common.Update{
+		CreatedAccounts: []common.Address{address},
+		Nonces:          []common.NonceUpdate{{Account: address, Nonce: common.ToNonce(1)}},
+	}, nil); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := archive.Add(5, common.Update{
+		Nonces: []common.NonceUpdate{{Account: address, Nonce: common.ToNonce(2)}},
+	}, nil); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := archive.Add(4, common.Update{}, nil); err == nil {
+		t.Errorf("adding a block out of order should fail")
+	}
+
+	if height, empty, err := archive.GetBlockHeight(); err != nil || empty || height != 5 {
+		t.Errorf("unexpected block height %d, empty %t, err %v", height, empty, err)
+	}
+	wants := []uint64{0, 0, 1, 1, 1, 2}
+	for block, want := range wants {
+		if got, err := archive.GetNonce(uint64(block), address); err != nil || got != common.ToNonce(want) {
+			t.Errorf("unexpected nonce at block %d, wanted %d, got %x, err %v", block, want, got, err)
+		}
+	}
+}
+
+func TestArchiveTrie_ContentIsPersistent(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	updates := []common.Update{}
+	hashes := []common.Hash{}
+	for block := uint64(0); block < 5; block++ {
+		update := getRandomUpdate(r, 10, 10)
+		if err := archive.Add(block, update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+		hash, err := archive.GetHash(block)
+		if err != nil {
+			t.Fatalf("failed to get hash: %v", err)
+		}
+		updates = append(updates, update)
+		hashes = append(hashes, hash)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	archive, err = OpenArchiveTrie(dir, MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to reopen archive: %v", err)
+	}
+	defer archive.Close()
+	for block, want := range hashes {
+		if got, err := archive.GetHash(uint64(block)); err != nil || got != want {
+			t.Errorf("unexpected hash of block %d, wanted %x, got %x, err %v", block, want, got, err)
+		}
+		for _, change := range updates[block].Slots {
+			if got, err := archive.GetStorage(uint64(block), change.Account, change.Key); err != nil || got != change.Value {
+				t.Errorf("unexpected value of slot %x/%x at block %d, got %x, err %v", change.Account, change.Key, block, got, err)
+			}
+		}
+	}
+	if err := archive.Check(); err != nil {
+		t.Errorf("invalid archive: %v", err)
+	}
+}
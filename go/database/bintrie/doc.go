@@ -0,0 +1,35 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+/*
+Package bintrie provides an experimental state representation based on the
+unified binary trie proposed by EIP-7864. It is intended for comparing state
+and proof sizes with the MPT based schemas and is exposed as schema 6.
+
+All account information is stored in a single binary trie. Keys are 32 bytes
+long, composed of a 31-byte stem derived from the account address using
+SHA-256, and a one-byte sub-index. Leaves sharing a stem are grouped in stem
+nodes, which are located in the trie by the bits of their stems. Internal
+nodes have exactly two children. All hashes are computed using SHA-256.
+
+Unlike the MPT configurations in package mpt, which vary hashing and path
+options of a common hexary node layout, this package uses its own node
+types, stocks, and hasher. The following deviations from EIP-7864 are needed
+to cover Carmen's state model:
+
+  - balances exceeding 16 bytes store their upper bytes in an extra leaf of
+    the account header
+  - the storage of accounts can be cleared; to that end, a reincarnation
+    counter is kept in the account header and mixed into the keys of storage
+    slots outside the account header
+
+Both are only visible for accounts with huge balances or cleared storage.
+*/
+package bintrie
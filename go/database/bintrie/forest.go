@@ -0,0 +1,484 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// forest implements the node-level operations on binary tries sharing a
+// common node store. In mutable mode, nodes are updated in-place, thus only a
+// single trie is retained. In immutable mode, nodes are frozen by calling
+// freeze and modifications of frozen nodes create modified copies, allowing
+// multiple versions of the trie to share unmodified nodes.
+type forest struct {
+	store     *nodeStore
+	immutable bool
+	fresh     map[NodeId]struct{} // < nodes created since the last freeze, only used in immutable mode
+}
+
+func newForest(store *nodeStore, immutable bool) *forest {
+	return &forest{
+		store:     store,
+		immutable: immutable,
+		fresh:     map[NodeId]struct{}{},
+	}
+}
+
+// leafUpdate describes the modification of a single leaf of a stem node.
+type leafUpdate struct {
+	index   byte
+	value   common.Value
+	present bool // < false if the leaf should be removed
+}
+
+// getStem locates the stem node with the given stem in the trie with the given
+// root. If there is no such node, nil is returned.
+func (f *forest) getStem(root NodeId, stem *Stem) (*stemNode, error) {
+	id := root
+	for depth := 0; ; depth++ {
+		if id.IsEmpty() {
+			return nil, nil
+		}
+		if id.IsStem() {
+			node, err := f.store.getStem(id)
+			if err != nil || node.stem != *stem {
+				return nil, err
+			}
+			return node, nil
+		}
+		node, err := f.store.getInternal(id)
+		if err != nil {
+			return nil, err
+		}
+		id = node.children[stem.getBit(depth)]
+	}
+}
+
+// getValue fetches the value of the leaf with the given key in the trie with
+// the given root. The present flag is false if there is no such leaf.
+func (f *forest) getValue(root NodeId, key TreeKey) (value common.Value, present bool, err error) {
+	stem := key.Stem()
+	node, err := f.getStem(root, &stem)
+	if node == nil || err != nil {
+		return common.Value{}, false, err
+	}
+	return f.getStemValue(node, key.SubIndex())
+}
+
+// getStemValue fetches the value stored at the given index of the given stem
+// node. The present flag is false if there is no such value.
+func (f *forest) getStemValue(node *stemNode, index byte) (common.Value, bool, error) {
+	id := node.values[index]
+	if id.IsEmpty() {
+		return common.Value{}, false, nil
+	}
+	value, err := f.store.getValue(id)
+	if err != nil {
+		return common.Value{}, false, err
+	}
+	return value.value, true, nil
+}
+
+// getValueHashes computes the hashes of all leaves of the given stem node.
+// Hashes of absent leaves are zero.
+func (f *forest) getValueHashes(node *stemNode) (*[stemSubtreeWidth]common.Hash, error) {
+	res := &[stemSubtreeWidth]common.Hash{}
+	for i, id := range node.values {
+		if id.IsEmpty() {
+			continue
+		}
+		value, err := f.store.getValue(id)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = sha256.Sum256(value.value[:])
+	}
+	return res, nil
+}
+
+// setValue updates a single leaf in the trie with the given root and returns
+// the root of the resulting trie.
+func (f *forest) setValue(root NodeId, key TreeKey, value common.Value, present bool) (NodeId, error) {
+	return f.update(root, key.Stem(), []leafUpdate{{
+		index:   key.SubIndex(),
+		value:   value,
+		present: present,
+	}})
+}
+
+// update applies the given leaf updates to the stem node with the given stem
+// in the trie with the given root and returns the root of the resulting trie.
+// Stem nodes are created and removed as needed.
+func (f *forest) update(root NodeId, stem Stem, updates []leafUpdate) (NodeId, error) {
+	// Filter out updates not changing anything to avoid unnecessary copies.
+	current, err := f.getStem(root, &stem)
+	if err != nil {
+		return root, err
+	}
+	effective := make([]leafUpdate, 0, len(updates))
+	for _, update := range updates {
+		present := false
+		var value common.Value
+		if current != nil {
+			value, present, err = f.getStemValue(current, update.index)
+			if err != nil {
+				return root, err
+			}
+		}
+		if present != update.present || value != update.value {
+			effective = append(effective, update)
+		}
+	}
+	if len(effective) == 0 {
+		return root, nil
+	}
+	return f.updateNode(root, 0, &stem, effective)
+}
+
+func (f *forest) updateNode(id NodeId, depth int, stem *Stem, updates []leafUpdate) (NodeId, error) {
+	if id.IsEmpty() {
+		return f.createStem(stem, updates)
+	}
+	if id.IsStem() {
+		return f.updateStem(id, depth, stem, updates)
+	}
+
+	id, node, err := f.getMutableInternal(id)
+	if err != nil {
+		return id, err
+	}
+	bit := stem.getBit(depth)
+	child, err := f.updateNode(node.children[bit], depth+1, stem, updates)
+	if err != nil {
+		return id, err
+	}
+	node.children[bit] = child
+	node.markChanged()
+	if err := f.store.update(id, node); err != nil {
+		return id, err
+	}
+
+	// Internal nodes with less than two children are collapsed, unless the
+	// remaining child is an internal node.
+	other := node.children[1-bit]
+	if child.IsEmpty() && (other.IsEmpty() || other.IsStem()) {
+		return other, f.releaseNode(id)
+	}
+	if other.IsEmpty() && child.IsStem() {
+		return child, f.releaseNode(id)
+	}
+	return id, nil
+}
+
+func (f *forest) updateStem(id NodeId, depth int, stem *Stem, updates []leafUpdate) (NodeId, error) {
+	node, err := f.store.getStem(id)
+	if err != nil {
+		return id, err
+	}
+
+	// If the stem differs, the stem node is split by introducing internal
+	// nodes covering the common prefix of the stems.
+	if node.stem != *stem {
+		newId, err := f.createStem(stem, updates)
+		if err != nil || newId.IsEmpty() {
+			return id, err
+		}
+		return f.split(depth, id, &node.stem, newId, stem)
+	}
+
+	id, node, err = f.getMutableStem(id)
+	if err != nil {
+		return id, err
+	}
+	if err := f.setStemValues(node, updates); err != nil {
+		return id, err
+	}
+	if err := f.store.update(id, node); err != nil {
+		return id, err
+	}
+	if node.isEmpty() {
+		return EmptyId, f.releaseNode(id)
+	}
+	return id, nil
+}
+
+// split creates a chain of internal nodes starting at the given depth
+// distinguishing the two given stem nodes.
+func (f *forest) split(depth int, idA NodeId, stemA *Stem, idB NodeId, stemB *Stem) (NodeId, error) {
+	id, node, err := f.createInternal()
+	if err != nil {
+		return id, err
+	}
+	bitA, bitB := stemA.getBit(depth), stemB.getBit(depth)
+	if bitA != bitB {
+		node.children[bitA] = idA
+		node.children[bitB] = idB
+	} else {
+		child, err := f.split(depth+1, idA, stemA, idB, stemB)
+		if err != nil {
+			return id, err
+		}
+		node.children[bitA] = child
+	}
+	node.markChanged()
+	return id, f.store.update(id, node)
+}
+
+// createStem creates a new stem node with the values of the given updates.
+// If no value is present, no node is created and the empty ID is returned.
+func (f *forest) createStem(stem *Stem, updates []leafUpdate) (NodeId, error) {
+	needed := false
+	for _, update := range updates {
+		needed = needed || update.present
+	}
+	if !needed {
+		return EmptyId, nil
+	}
+	id, node, err := f.store.createStem(*stem)
+	if err != nil {
+		return id, err
+	}
+	f.markFresh(id)
+	if err := f.setStemValues(node, updates); err != nil {
+		return id, err
+	}
+	return id, f.store.update(id, node)
+}
+
+// setStemValues applies the given updates to the given mutable stem node.
+// Since value nodes are immutable, each modified leaf gets a new value node.
+func (f *forest) setStemValues(node *stemNode, updates []leafUpdate) error {
+	for _, update := range updates {
+		if old := node.values[update.index]; !old.IsEmpty() {
+			if err := f.releaseNode(old); err != nil {
+				return err
+			}
+			node.values[update.index] = EmptyId
+		}
+		if update.present {
+			id, err := f.store.createValue(update.value)
+			if err != nil {
+				return err
+			}
+			f.markFresh(id)
+			node.values[update.index] = id
+		}
+	}
+	node.markChanged()
+	return nil
+}
+
+func (f *forest) createInternal() (NodeId, *internalNode, error) {
+	id, node, err := f.store.createInternal()
+	if err != nil {
+		return id, nil, err
+	}
+	f.markFresh(id)
+	return id, node, nil
+}
+
+// getMutableInternal fetches the internal node with the given ID for an
+// update. If the node is frozen, a copy is created and returned instead.
+func (f *forest) getMutableInternal(id NodeId) (NodeId, *internalNode, error) {
+	node, err := f.store.getInternal(id)
+	if err != nil || f.isMutable(id) {
+		return id, node, err
+	}
+	newId, res, err := f.createInternal()
+	if err != nil {
+		return id, nil, err
+	}
+	res.children = node.children
+	return newId, res, nil
+}
+
+// getMutableStem fetches the stem node with the given ID for an update. If the
+// node is frozen, a copy is created and returned instead.
+func (f *forest) getMutableStem(id NodeId) (NodeId, *stemNode, error) {
+	node, err := f.store.getStem(id)
+	if err != nil || f.isMutable(id) {
+		return id, node, err
+	}
+	newId, res, err := f.store.createStem(node.stem)
+	if err != nil {
+		return id, nil, err
+	}
+	f.markFresh(newId)
+	// Value nodes are immutable and may thus be shared among stem nodes.
+	res.values = node.values
+	return newId, res, nil
+}
+
+func (f *forest) isMutable(id NodeId) bool {
+	if !f.immutable {
+		return true
+	}
+	_, found := f.fresh[id]
+	return found
+}
+
+func (f *forest) markFresh(id NodeId) {
+	if f.immutable {
+		f.fresh[id] = struct{}{}
+	}
+}
+
+// releaseNode frees the given node unless it is frozen.
+func (f *forest) releaseNode(id NodeId) error {
+	if !f.isMutable(id) {
+		return nil
+	}
+	delete(f.fresh, id)
+	return f.store.release(id)
+}
+
+// freeze makes all nodes created so far immutable. Hashes of frozen nodes
+// need to be up-to-date, thus the hash of the given root is refreshed before
+// nodes are frozen.
+func (f *forest) freeze(root NodeId) (common.Hash, error) {
+	hash, err := f.getHash(root)
+	if err != nil {
+		return hash, err
+	}
+	f.fresh = map[NodeId]struct{}{}
+	return hash, nil
+}
+
+// getHash computes the hash of the node with the given ID, refreshing the
+// hashes of all modified nodes in its sub-tree.
+func (f *forest) getHash(id NodeId) (common.Hash, error) {
+	if id.IsEmpty() {
+		return common.Hash{}, nil
+	}
+	if id.IsStem() {
+		node, err := f.store.getStem(id)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if !node.hashDirty {
+			return node.hash, nil
+		}
+		hashes, err := f.getValueHashes(node)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		valuesRoot, _ := merkleize(hashes[:], 0, nil)
+		// The node may have been evicted from the cache while loading its
+		// values, so it is re-fetched for the update.
+		node, err = f.store.getStem(id)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		node.hash = hashStem(&node.stem, &valuesRoot)
+		node.hashDirty = false
+		return node.hash, f.store.update(id, node)
+	}
+
+	node, err := f.store.getInternal(id)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !node.hashDirty {
+		return node.hash, nil
+	}
+	left, err := f.getHash(node.children[0])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	right, err := f.getHash(node.children[1])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// The node may have been evicted from the cache while hashing its
+	// children, so it is re-fetched for the update.
+	node, err = f.store.getInternal(id)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	node.hash = hashPair(&left, &right)
+	node.hashDirty = false
+	return node.hash, f.store.update(id, node)
+}
+
+// visit traverses all nodes of the trie with the given root in pre-order.
+func (f *forest) visit(id NodeId, depth int, visitor func(NodeId, int, node) error) error {
+	if id.IsEmpty() {
+		return nil
+	}
+	if id.IsStem() {
+		node, err := f.store.getStem(id)
+		if err != nil {
+			return err
+		}
+		return visitor(id, depth, node)
+	}
+	node, err := f.store.getInternal(id)
+	if err != nil {
+		return err
+	}
+	children := node.children
+	if err := visitor(id, depth, node); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := f.visit(child, depth+1, visitor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check verifies the structural invariants of the trie with the given root.
+func (f *forest) check(root NodeId) error {
+	return f.checkNode(root, 0, nil)
+}
+
+func (f *forest) checkNode(id NodeId, depth int, prefix []byte) error {
+	if id.IsEmpty() {
+		return nil
+	}
+	if id.IsStem() {
+		node, err := f.store.getStem(id)
+		if err != nil {
+			return err
+		}
+		for i, bit := range prefix {
+			if node.stem.getBit(i) != bit {
+				return fmt.Errorf("stem node %v with stem %x is located at invalid position", id, node.stem)
+			}
+		}
+		if node.isEmpty() {
+			return fmt.Errorf("stem node %v contains no values", id)
+		}
+		return nil
+	}
+	node, err := f.store.getInternal(id)
+	if err != nil {
+		return err
+	}
+	if depth >= StemSize*8 {
+		return fmt.Errorf("internal node %v exceeds maximum depth", id)
+	}
+	children := node.children
+	if (children[0].IsEmpty() || children[1].IsEmpty()) && !children[0].IsInternal() && !children[1].IsInternal() {
+		return fmt.Errorf("internal node %v should have been collapsed", id)
+	}
+	for i, child := range children {
+		if err := f.checkNode(child, depth+1, append(prefix, byte(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// StemSize is the number of bytes of a stem, the common prefix of the keys of
+// all leaves grouped in a single stem node.
+const StemSize = 31
+
+// Stem is the 31-byte prefix of a tree key addressing a stem node.
+type Stem [StemSize]byte
+
+// TreeKey is a key addressing a single 32-byte leaf in the binary trie. It
+// is composed of a stem and a one-byte sub-index selecting one of the 256
+// leaves of the stem.
+type TreeKey [StemSize + 1]byte
+
+// Stem returns the stem of the stem node containing the leaf of this key.
+func (k TreeKey) Stem() Stem {
+	var res Stem
+	copy(res[:], k[:StemSize])
+	return res
+}
+
+// SubIndex returns the position of the leaf addressed by this key in its
+// stem node.
+func (k TreeKey) SubIndex() byte {
+	return k[StemSize]
+}
+
+// getBit returns the bit at the given position of the stem, starting with
+// the most significant bit of the first byte.
+func (s *Stem) getBit(pos int) byte {
+	return (s[pos/8] >> (7 - pos%8)) & 1
+}
+
+// The following constants define the layout of account data in the tree as
+// specified by EIP-7864. The account header stem, addressed by tree index 0,
+// contains the basic account data, the code hash, the first storage slots and
+// the first chunks of the code.
+const (
+	basicDataLeafKey     = 0
+	codeHashLeafKey      = 1
+	headerStorageOffset  = 64
+	codeOffset           = 128
+	stemSubtreeWidth     = 256
+	codeChunkSize        = 31
+	numHeaderStorageKeys = codeOffset - headerStorageOffset
+)
+
+// The following leaves of the account header are not defined by EIP-7864 but
+// occupy indexes reserved for future use. They are needed for covering
+// Carmen's state model.
+const (
+	// reincarnationLeafKey addresses a counter incremented whenever the
+	// storage of an account is cleared. It is mixed into the keys of storage
+	// slots outside the header, invalidating all of them at once.
+	reincarnationLeafKey = 2
+	// balanceHighLeafKey stores the upper 16 bytes of balances exceeding the
+	// 16 bytes reserved in the basic data leaf. It is absent for all balances
+	// representable in 16 bytes.
+	balanceHighLeafKey = 3
+)
+
+// getTreeKey computes the tree key of the leaf with the given sub-index in the
+// stem identified by the given address and the 32-byte little-endian tree
+// index. The reincarnation counter occupies otherwise zero padding bytes of
+// the address. Thus, for the initial reincarnation, the key is the one
+// defined by EIP-7864.
+func getTreeKey(address common.Address, reincarnation uint32, treeIndex *[32]byte, subIndex byte) TreeKey {
+	var data [64]byte
+	binary.BigEndian.PutUint32(data[0:4], reincarnation)
+	copy(data[12:32], address[:])
+	copy(data[32:], treeIndex[:])
+	hash := sha256.Sum256(data[:])
+	var res TreeKey
+	copy(res[:], hash[:StemSize])
+	res[StemSize] = subIndex
+	return res
+}
+
+// getHeaderKey computes the tree key of a leaf in the header stem of the
+// given account.
+func getHeaderKey(address common.Address, subIndex byte) TreeKey {
+	return getTreeKey(address, 0, &[32]byte{}, subIndex)
+}
+
+// isHeaderStorageKey determines whether the given storage slot is located in
+// the header stem of its account.
+func isHeaderStorageKey(key common.Key) bool {
+	for _, cur := range key[:len(key)-1] {
+		if cur != 0 {
+			return false
+		}
+	}
+	return key[len(key)-1] < numHeaderStorageKeys
+}
+
+// getStorageKey computes the tree key of the given storage slot of the given
+// account. Slots outside the header depend on the reincarnation counter of
+// the account.
+func getStorageKey(address common.Address, reincarnation uint32, key common.Key) TreeKey {
+	if isHeaderStorageKey(key) {
+		return getHeaderKey(address, headerStorageOffset+key[len(key)-1])
+	}
+	// The tree index is 256^30 + key / 256, in little-endian order.
+	var treeIndex [32]byte
+	for i := 0; i < len(key)-1; i++ {
+		treeIndex[i] = key[len(key)-2-i]
+	}
+	treeIndex[30]++
+	if treeIndex[30] == 0 {
+		treeIndex[31] = 1
+	}
+	return getTreeKey(address, reincarnation, &treeIndex, key[len(key)-1])
+}
+
+// getCodeChunkKey computes the tree key of the code chunk with the given
+// index of the given account.
+func getCodeChunkKey(address common.Address, chunk int) TreeKey {
+	pos := uint64(codeOffset + chunk)
+	var treeIndex [32]byte
+	binary.LittleEndian.PutUint64(treeIndex[:], pos/stemSubtreeWidth)
+	return getTreeKey(address, 0, &treeIndex, byte(pos%stemSubtreeWidth))
+}
+
+// basicData is the content of the basic data leaf of an account. Its
+// encoding is defined by EIP-7864 as
+//
+//	version (1 byte) | reserved (4 bytes) | code size (3 bytes) | nonce (8 bytes) | balance (16 bytes)
+type basicData struct {
+	codeSize int
+	nonce    common.Nonce
+	balance  [16]byte
+}
+
+func (d *basicData) encode() common.Value {
+	var res common.Value
+	res[5] = byte(d.codeSize >> 16)
+	res[6] = byte(d.codeSize >> 8)
+	res[7] = byte(d.codeSize)
+	copy(res[8:16], d.nonce[:])
+	copy(res[16:32], d.balance[:])
+	return res
+}
+
+func (d *basicData) decode(value common.Value) {
+	d.codeSize = int(value[5])<<16 | int(value[6])<<8 | int(value[7])
+	copy(d.nonce[:], value[8:16])
+	copy(d.balance[:], value[16:32])
+}
+
+// maxCodeSize is the maximum code size that can be encoded in the basic data
+// leaf of an account.
+const maxCodeSize = 1<<24 - 1
+
+// chunkify splits the given code into 31-byte chunks, each prefixed by the
+// number of leading bytes being push data of an instruction in a previous
+// chunk, as defined by EIP-7864.
+func chunkify(code []byte) []common.Value {
+	const push1, push32 = 0x60, 0x7f
+	numChunks := (len(code) + codeChunkSize - 1) / codeChunkSize
+	res := make([]common.Value, numChunks)
+
+	// Compute for each chunk the number of leading push data bytes.
+	pos := 0
+	for pos < len(code) {
+		op := code[pos]
+		pos++
+		if op < push1 || op > push32 {
+			continue
+		}
+		dataEnd := pos + int(op-push1) + 1
+		first := (pos + codeChunkSize - 1) / codeChunkSize
+		for chunk := first; chunk < numChunks && chunk*codeChunkSize < dataEnd; chunk++ {
+			leading := dataEnd - chunk*codeChunkSize
+			if leading > codeChunkSize {
+				leading = codeChunkSize
+			}
+			res[chunk][0] = byte(leading)
+		}
+		pos = dataEnd
+	}
+
+	for i := range res {
+		copy(res[i][1:], code[i*codeChunkSize:])
+	}
+	return res
+}
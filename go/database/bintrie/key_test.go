@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func TestTreeKey_AccountHeaderLeavesShareStem(t *testing.T) {
+	address := common.Address{1, 2, 3}
+	stem := getHeaderKey(address, basicDataLeafKey).Stem()
+	keys := []TreeKey{
+		getHeaderKey(address, codeHashLeafKey),
+		getStorageKey(address, 0, common.Key{31: 0}),
+		getStorageKey(address, 0, common.Key{31: 63}),
+		getStorageKey(address, 7, common.Key{31: 63}),
+		getCodeChunkKey(address, 0),
+		getCodeChunkKey(address, 127),
+	}
+	for _, key := range keys {
+		if got := key.Stem(); got != stem {
+			t.Errorf("unexpected stem for key %x, wanted %x, got %x", key, stem, got)
+		}
+	}
+	if got, want := getStorageKey(address, 0, common.Key{31: 5}).SubIndex(), byte(headerStorageOffset+5); got != want {
+		t.Errorf("unexpected sub-index of header storage slot, wanted %d, got %d", want, got)
+	}
+	if got, want := getCodeChunkKey(address, 5).SubIndex(), byte(codeOffset+5); got != want {
+		t.Errorf("unexpected sub-index of code chunk, wanted %d, got %d", want, got)
+	}
+}
+
+func TestTreeKey_MainStorageAndCodeLeavesAreOutsideOfHeader(t *testing.T) {
+	address := common.Address{1, 2, 3}
+	stem := getHeaderKey(address, basicDataLeafKey).Stem()
+	keys := []TreeKey{
+		getStorageKey(address, 0, common.Key{31: 64}),
+		getStorageKey(address, 0, common.Key{0: 1}),
+		getStorageKey(address, 0, common.Key{0: 0xff, 31: 0xff}),
+		getCodeChunkKey(address, 128),
+	}
+	for _, key := range keys {
+		if key.Stem() == stem {
+			t.Errorf("key %x should not be in the account header", key)
+		}
+	}
+}
+
+func TestTreeKey_SlotsWithinTheSameGroupOf256ShareStem(t *testing.T) {
+	address := common.Address{1}
+	a := getStorageKey(address, 0, common.Key{0: 1, 31: 0})
+	b := getStorageKey(address, 0, common.Key{0: 1, 31: 0xff})
+	c := getStorageKey(address, 0, common.Key{0: 1, 30: 1})
+	if a.Stem() != b.Stem() {
+		t.Errorf("slots of the same group should share a stem")
+	}
+	if a.Stem() == c.Stem() {
+		t.Errorf("slots of different groups should not share a stem")
+	}
+	if a.SubIndex() != 0 || b.SubIndex() != 0xff {
+		t.Errorf("unexpected sub-indexes %d and %d", a.SubIndex(), b.SubIndex())
+	}
+}
+
+func TestTreeKey_ReincarnationOnlyAffectsMainStorage(t *testing.T) {
+	address := common.Address{1}
+	header := common.Key{31: 1}
+	main := common.Key{0: 1}
+	if getStorageKey(address, 0, header) != getStorageKey(address, 1, header) {
+		t.Errorf("reincarnation should not affect header slots")
+	}
+	if getStorageKey(address, 0, main) == getStorageKey(address, 1, main) {
+		t.Errorf("reincarnation should affect main storage slots")
+	}
+}
+
+func TestBasicData_EncodingFollowsEip7864Layout(t *testing.T) {
+	data := basicData{
+		codeSize: 0x010203,
+		nonce:    common.ToNonce(0x0405),
+		balance:  [16]byte{15: 0x06},
+	}
+	got := data.encode()
+	want := common.Value{5: 0x01, 6: 0x02, 7: 0x03, 14: 0x04, 15: 0x05, 31: 0x06}
+	if got != want {
+		t.Errorf("unexpected encoding, wanted %x, got %x", want, got)
+	}
+	var restored basicData
+	restored.decode(got)
+	if restored != data {
+		t.Errorf("unexpected decoded data, wanted %v, got %v", data, restored)
+	}
+}
+
+func TestChunkify_SplitsCodeIntoChunksWithPushDataPrefix(t *testing.T) {
+	const push1, push32 = 0x60, 0x7f
+	code := make([]byte, 70)
+	code[29] = push32 // < push data covering bytes 30-61
+	code[62] = push1  // < push data covering byte 63
+
+	chunks := chunkify(code)
+	if got, want := len(chunks), 3; got != want {
+		t.Fatalf("unexpected number of chunks, wanted %d, got %d", want, got)
+	}
+	if got, want := chunks[0][0], byte(0); got != want {
+		t.Errorf("unexpected prefix of chunk 0, wanted %d, got %d", want, got)
+	}
+	if got, want := chunks[1][0], byte(31); got != want {
+		t.Errorf("unexpected prefix of chunk 1, wanted %d, got %d", want, got)
+	}
+	if got, want := chunks[2][0], byte(0); got != want {
+		t.Errorf("unexpected prefix of chunk 2, wanted %d, got %d", want, got)
+	}
+
+	restored := []byte{}
+	for _, chunk := range chunks {
+		restored = append(restored, chunk[1:]...)
+	}
+	if !bytes.Equal(restored[:len(code)], code) {
+		t.Errorf("chunks do not contain the code")
+	}
+}
+
+func TestChunkify_PushDataSpanningChunkStartIsCounted(t *testing.T) {
+	const push4 = 0x63
+	code := make([]byte, 40)
+	code[29] = push4 // < push data covering bytes 30-33
+	chunks := chunkify(code)
+	if got, want := chunks[1][0], byte(3); got != want {
+		t.Errorf("unexpected prefix of chunk 1, wanted %d, got %d", want, got)
+	}
+}
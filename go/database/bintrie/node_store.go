@@ -0,0 +1,305 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/stock"
+	"github.com/Fantom-foundation/Carmen/go/backend/stock/file"
+	"github.com/Fantom-foundation/Carmen/go/backend/stock/memory"
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// StockType selects the stock implementation used for persisting nodes.
+type StockType int
+
+const (
+	// MemoryStock keeps all nodes in memory and persists them on Flush.
+	MemoryStock StockType = iota
+	// FileStock keeps nodes in files, loading them on demand.
+	FileStock
+)
+
+// DefaultCacheCapacity is the default number of nodes retained in the node
+// cache of a binary trie.
+const DefaultCacheCapacity = 1 << 16
+
+// MinCacheCapacity is the minimum number of nodes retained in the node cache.
+// It needs to be large enough to hold all nodes accessed by a single update
+// operation.
+const MinCacheCapacity = 1 << 10
+
+// EstimatePerNodeMemoryUsage returns an upper bound estimate of the memory
+// used by a single cached node. It may be used to derive cache capacities
+// from memory budgets.
+func EstimatePerNodeMemoryUsage() int {
+	return int(unsafe.Sizeof(stemNode{}))
+}
+
+// nodeStore manages the nodes of a binary trie. Nodes are persisted in one
+// stock per node type, while recently used nodes are retained in an LRU cache
+// in their decoded form. Modified nodes are written back to their stock when
+// evicted from the cache or when the store is flushed. Node stores are not
+// thread safe.
+type nodeStore struct {
+	internals stock.Stock[uint64, internalNode]
+	stems     stock.Stock[uint64, stemNode]
+	values    stock.Stock[uint64, valueNode]
+	cache     *common.LruCache[NodeId, node]
+}
+
+func openNodeStore(directory string, stockType StockType, cacheCapacity int) (*nodeStore, error) {
+	if stockType != MemoryStock && stockType != FileStock {
+		return nil, fmt.Errorf("unknown stock type: %d", stockType)
+	}
+	internals, err := openStock[internalNode](filepath.Join(directory, "internals"), stockType, internalNodeEncoder{})
+	if err != nil {
+		return nil, err
+	}
+	stems, err := openStock[stemNode](filepath.Join(directory, "stems"), stockType, stemNodeEncoder{})
+	if err != nil {
+		return nil, errors.Join(err, internals.Close())
+	}
+	values, err := openStock[valueNode](filepath.Join(directory, "values"), stockType, valueNodeEncoder{})
+	if err != nil {
+		return nil, errors.Join(err, internals.Close(), stems.Close())
+	}
+	if cacheCapacity < MinCacheCapacity {
+		cacheCapacity = MinCacheCapacity
+	}
+	return &nodeStore{
+		internals: internals,
+		stems:     stems,
+		values:    values,
+		cache:     common.NewLruCache[NodeId, node](cacheCapacity),
+	}, nil
+}
+
+func openStock[V any](directory string, stockType StockType, encoder stock.ValueEncoder[V]) (stock.Stock[uint64, V], error) {
+	if stockType == MemoryStock {
+		return memory.OpenStock[uint64, V](encoder, directory)
+	}
+	return file.OpenStock[uint64, V](encoder, directory)
+}
+
+// getInternal fetches the internal node with the given ID. The resulting node
+// may be modified, but modifications need to be registered using update.
+func (s *nodeStore) getInternal(id NodeId) (*internalNode, error) {
+	if !id.IsInternal() {
+		return nil, fmt.Errorf("node %v is not an internal node", id)
+	}
+	if res, found := s.cache.Get(id); found {
+		return res.(*internalNode), nil
+	}
+	node, err := s.internals.Get(id.Index())
+	if err != nil {
+		return nil, err
+	}
+	res := &node
+	return res, s.add(id, res)
+}
+
+// getStem fetches the stem node with the given ID. The resulting node may be
+// modified, but modifications need to be registered using update.
+func (s *nodeStore) getStem(id NodeId) (*stemNode, error) {
+	if !id.IsStem() {
+		return nil, fmt.Errorf("node %v is not a stem node", id)
+	}
+	if res, found := s.cache.Get(id); found {
+		return res.(*stemNode), nil
+	}
+	node, err := s.stems.Get(id.Index())
+	if err != nil {
+		return nil, err
+	}
+	res := &node
+	return res, s.add(id, res)
+}
+
+// getValue fetches the value node with the given ID. Value nodes are
+// immutable and must not be modified.
+func (s *nodeStore) getValue(id NodeId) (*valueNode, error) {
+	if !id.IsValue() {
+		return nil, fmt.Errorf("node %v is not a value node", id)
+	}
+	if res, found := s.cache.Get(id); found {
+		return res.(*valueNode), nil
+	}
+	node, err := s.values.Get(id.Index())
+	if err != nil {
+		return nil, err
+	}
+	res := &node
+	return res, s.add(id, res)
+}
+
+// createInternal allocates a new, empty internal node.
+func (s *nodeStore) createInternal() (NodeId, *internalNode, error) {
+	index, err := s.internals.New()
+	if err != nil {
+		return EmptyId, nil, err
+	}
+	id := newInternalId(index)
+	res := &internalNode{}
+	res.markChanged()
+	return id, res, s.add(id, res)
+}
+
+// createStem allocates a new stem node with the given stem and no values.
+func (s *nodeStore) createStem(stem Stem) (NodeId, *stemNode, error) {
+	index, err := s.stems.New()
+	if err != nil {
+		return EmptyId, nil, err
+	}
+	id := newStemId(index)
+	res := &stemNode{stem: stem}
+	res.markChanged()
+	return id, res, s.add(id, res)
+}
+
+// createValue allocates a new value node holding the given value.
+func (s *nodeStore) createValue(value common.Value) (NodeId, error) {
+	index, err := s.values.New()
+	if err != nil {
+		return EmptyId, err
+	}
+	id := newValueId(index)
+	return id, s.add(id, &valueNode{value: value, dirty: true})
+}
+
+// update registers a modification of the given node, which must have been
+// obtained from this store using the given ID.
+func (s *nodeStore) update(id NodeId, node node) error {
+	node.setDirty(true)
+	return s.add(id, node)
+}
+
+// release frees the node with the given ID. The ID may be re-used by future
+// nodes.
+func (s *nodeStore) release(id NodeId) error {
+	s.cache.Remove(id)
+	switch {
+	case id.IsStem():
+		return s.stems.Delete(id.Index())
+	case id.IsValue():
+		return s.values.Delete(id.Index())
+	}
+	return s.internals.Delete(id.Index())
+}
+
+// add inserts the given node into the cache, writing back evicted nodes.
+func (s *nodeStore) add(id NodeId, node node) error {
+	evictedId, evicted, wasEvicted := s.cache.Set(id, node)
+	if !wasEvicted {
+		return nil
+	}
+	return s.write(evictedId, evicted)
+}
+
+// write stores the given node in its stock if it is dirty.
+func (s *nodeStore) write(id NodeId, node node) error {
+	if !node.isDirty() {
+		return nil
+	}
+	var err error
+	switch n := node.(type) {
+	case *internalNode:
+		err = s.internals.Set(id.Index(), *n)
+	case *stemNode:
+		err = s.stems.Set(id.Index(), *n)
+	case *valueNode:
+		err = s.values.Set(id.Index(), *n)
+	}
+	if err != nil {
+		return err
+	}
+	node.setDirty(false)
+	return nil
+}
+
+func (s *nodeStore) Flush() error {
+	var errs []error
+	s.cache.Iterate(func(id NodeId, node node) bool {
+		if err := s.write(id, node); err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+	return errors.Join(
+		errors.Join(errs...),
+		s.internals.Flush(),
+		s.stems.Flush(),
+		s.values.Flush(),
+	)
+}
+
+func (s *nodeStore) Close() error {
+	return errors.Join(
+		s.Flush(),
+		s.internals.Close(),
+		s.stems.Close(),
+		s.values.Close(),
+	)
+}
+
+func (s *nodeStore) GetMemoryFootprint() *common.MemoryFootprint {
+	mf := common.NewMemoryFootprint(unsafe.Sizeof(*s))
+	mf.AddChild("internals", s.internals.GetMemoryFootprint())
+	mf.AddChild("stems", s.stems.GetMemoryFootprint())
+	mf.AddChild("values", s.values.GetMemoryFootprint())
+	mf.AddChild("cache", s.cache.GetDynamicMemoryFootprint(func(n node) uintptr {
+		switch n.(type) {
+		case *stemNode:
+			return unsafe.Sizeof(stemNode{})
+		case *valueNode:
+			return unsafe.Sizeof(valueNode{})
+		}
+		return unsafe.Sizeof(internalNode{})
+	}))
+	return mf
+}
+
+func (s *nodeStore) GetDiskFootprint() (*common.DiskFootprint, error) {
+	df := common.NewDiskFootprint(0)
+	for name, stock := range map[string]common.DiskFootprintProvider{
+		"internals": s.internals,
+		"stems":     s.stems,
+		"values":    s.values,
+	} {
+		child, err := stock.GetDiskFootprint()
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild(name, child)
+	}
+	return df, nil
+}
+
+// getDiskFootprint reports the disk usage of the given node store and the
+// file listing the roots of the tries it contains.
+func getDiskFootprint(store *nodeStore, rootsFile string) (*common.DiskFootprint, error) {
+	nodes, err := store.GetDiskFootprint()
+	if err != nil {
+		return nil, err
+	}
+	roots, err := common.GetFileDiskFootprint(rootsFile)
+	if err != nil {
+		return nil, err
+	}
+	df := common.NewDiskFootprint(0)
+	df.AddChild("nodes", nodes)
+	df.AddChild("roots", roots)
+	return df, nil
+}
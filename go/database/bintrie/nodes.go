@@ -0,0 +1,277 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// NodeId identifies a node in the binary trie. The zero value is the empty
+// node. The lowest two bits distinguish internal, stem, and value nodes, the
+// remaining bits encode the index of the node in the stock of its type.
+type NodeId uint64
+
+// EmptyId is the ID of the empty node.
+const EmptyId = NodeId(0)
+
+const (
+	internalNodeKind = 0
+	stemNodeKind     = 1
+	valueNodeKind    = 2
+)
+
+func newInternalId(index uint64) NodeId {
+	return NodeId((index+1)<<2 | internalNodeKind)
+}
+
+func newStemId(index uint64) NodeId {
+	return NodeId((index+1)<<2 | stemNodeKind)
+}
+
+func newValueId(index uint64) NodeId {
+	return NodeId((index+1)<<2 | valueNodeKind)
+}
+
+func (i NodeId) IsEmpty() bool {
+	return i == EmptyId
+}
+
+func (i NodeId) IsInternal() bool {
+	return !i.IsEmpty() && i&3 == internalNodeKind
+}
+
+func (i NodeId) IsStem() bool {
+	return i&3 == stemNodeKind
+}
+
+func (i NodeId) IsValue() bool {
+	return i&3 == valueNodeKind
+}
+
+// Index returns the position of the node in the stock of its type.
+func (i NodeId) Index() uint64 {
+	return uint64(i>>2) - 1
+}
+
+func (i NodeId) String() string {
+	switch {
+	case i.IsEmpty():
+		return "E"
+	case i.IsStem():
+		return fmt.Sprintf("S-%d", i.Index())
+	case i.IsValue():
+		return fmt.Sprintf("V-%d", i.Index())
+	default:
+		return fmt.Sprintf("I-%d", i.Index())
+	}
+}
+
+// node is the common interface of the node types maintained in a node cache.
+type node interface {
+	isDirty() bool
+	setDirty(bool)
+}
+
+// nodeBase contains the fields shared by all node types.
+type nodeBase struct {
+	hash      common.Hash // < the cached hash of the node, valid if not hashDirty
+	hashDirty bool        // < true if hash needs to be re-computed
+	dirty     bool        // < true if the node needs to be written to its stock
+}
+
+func (n *nodeBase) isDirty() bool {
+	return n.dirty
+}
+
+func (n *nodeBase) setDirty(dirty bool) {
+	n.dirty = dirty
+}
+
+// markChanged records a modification of the node's content.
+func (n *nodeBase) markChanged() {
+	n.hashDirty = true
+	n.dirty = true
+}
+
+// internalNode is a node with two children, the left child covering all keys
+// with a 0 bit at the node's depth, the right child those with a 1 bit.
+type internalNode struct {
+	nodeBase
+	children [2]NodeId
+}
+
+// stemNode is a leaf-level node referencing up to 256 value nodes sharing a
+// common stem. Absent values are represented by empty IDs, distinguishing
+// them from zero values.
+type stemNode struct {
+	nodeBase
+	stem   Stem
+	values [stemSubtreeWidth]NodeId
+}
+
+func (n *stemNode) isEmpty() bool {
+	for _, cur := range n.values {
+		if !cur.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// valueNode holds the value of a single leaf. Value nodes are immutable, so
+// they may be shared by multiple versions of a stem node.
+type valueNode struct {
+	value common.Value
+	dirty bool
+}
+
+func (n *valueNode) isDirty() bool {
+	return n.dirty
+}
+
+func (n *valueNode) setDirty(dirty bool) {
+	n.dirty = dirty
+}
+
+// ----------------------------------------------------------------------------
+//                               Hashing
+// ----------------------------------------------------------------------------
+
+// hashPair computes the SHA-256 hash of the concatenation of the given hashes.
+// Following EIP-7864, the hash of two zero hashes is the zero hash, making
+// the hash of empty sub-trees independent of their depth.
+func hashPair(left, right *common.Hash) common.Hash {
+	if *left == (common.Hash{}) && *right == (common.Hash{}) {
+		return common.Hash{}
+	}
+	var data [64]byte
+	copy(data[:32], left[:])
+	copy(data[32:], right[:])
+	return sha256.Sum256(data[:])
+}
+
+// merkleize reduces the given level of the value tree of a stem node to its
+// root hash. If siblings is not nil, the sibling hashes along the path to the
+// leaf with the given index are appended, starting at the leaf level.
+func merkleize(level []common.Hash, index int, siblings []common.Hash) (common.Hash, []common.Hash) {
+	for len(level) > 1 {
+		if siblings != nil {
+			siblings = append(siblings, level[index^1])
+		}
+		next := make([]common.Hash, len(level)/2)
+		for i := range next {
+			next[i] = hashPair(&level[2*i], &level[2*i+1])
+		}
+		level = next
+		index /= 2
+	}
+	return level[0], siblings
+}
+
+// hashStem computes the hash of a stem node from its stem and the root of the
+// Merkle tree of its values.
+func hashStem(stem *Stem, valuesRoot *common.Hash) common.Hash {
+	var data [64]byte
+	copy(data[:StemSize], stem[:])
+	copy(data[StemSize+1:], valuesRoot[:])
+	return sha256.Sum256(data[:])
+}
+
+// ----------------------------------------------------------------------------
+//                               Encoders
+// ----------------------------------------------------------------------------
+
+// internalNodeEncoder is a stock encoder for internal nodes. Hashes are
+// stored with the nodes.
+type internalNodeEncoder struct{}
+
+func (internalNodeEncoder) GetEncodedSize() int {
+	return 2*8 + len(common.Hash{}) + 1
+}
+
+func (internalNodeEncoder) Store(dst []byte, node *internalNode) error {
+	binary.BigEndian.PutUint64(dst[0:], uint64(node.children[0]))
+	binary.BigEndian.PutUint64(dst[8:], uint64(node.children[1]))
+	copy(dst[16:], node.hash[:])
+	dst[48] = encodeBool(node.hashDirty)
+	return nil
+}
+
+func (internalNodeEncoder) Load(src []byte, node *internalNode) error {
+	node.children[0] = NodeId(binary.BigEndian.Uint64(src[0:]))
+	node.children[1] = NodeId(binary.BigEndian.Uint64(src[8:]))
+	copy(node.hash[:], src[16:])
+	node.hashDirty = src[48] != 0
+	node.dirty = false
+	return nil
+}
+
+// stemNodeEncoder is a stock encoder for stem nodes. Hashes are stored with
+// the nodes.
+type stemNodeEncoder struct{}
+
+func (stemNodeEncoder) GetEncodedSize() int {
+	return StemSize + stemSubtreeWidth*8 + len(common.Hash{}) + 1
+}
+
+func (stemNodeEncoder) Store(dst []byte, node *stemNode) error {
+	copy(dst, node.stem[:])
+	dst = dst[StemSize:]
+	for _, cur := range node.values {
+		binary.BigEndian.PutUint64(dst, uint64(cur))
+		dst = dst[8:]
+	}
+	copy(dst, node.hash[:])
+	dst[len(common.Hash{})] = encodeBool(node.hashDirty)
+	return nil
+}
+
+func (stemNodeEncoder) Load(src []byte, node *stemNode) error {
+	copy(node.stem[:], src)
+	src = src[StemSize:]
+	for i := range node.values {
+		node.values[i] = NodeId(binary.BigEndian.Uint64(src))
+		src = src[8:]
+	}
+	copy(node.hash[:], src)
+	node.hashDirty = src[len(common.Hash{})] != 0
+	node.dirty = false
+	return nil
+}
+
+// valueNodeEncoder is a stock encoder for value nodes.
+type valueNodeEncoder struct{}
+
+func (valueNodeEncoder) GetEncodedSize() int {
+	return len(common.Value{})
+}
+
+func (valueNodeEncoder) Store(dst []byte, node *valueNode) error {
+	copy(dst, node.value[:])
+	return nil
+}
+
+func (valueNodeEncoder) Load(src []byte, node *valueNode) error {
+	copy(node.value[:], src)
+	node.dirty = false
+	return nil
+}
+
+func encodeBool(value bool) byte {
+	if value {
+		return 1
+	}
+	return 0
+}
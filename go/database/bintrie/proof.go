@@ -0,0 +1,211 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// Proof is a Merkle proof for the values of a set of leaves in a binary trie.
+// It may be used to prove the presence or absence of values for a given state
+// root hash.
+type Proof struct {
+	Leaves []LeafProof
+}
+
+// LeafProof proves the value of a single leaf, or its absence.
+type LeafProof struct {
+	// Key is the key of the proven leaf.
+	Key TreeKey
+	// Value is the value of the leaf, nil if the leaf is absent.
+	Value *common.Value
+	// Stem is the stem of the stem node at the end of the path to the leaf,
+	// nil if the path ends at an empty node. It differs from the stem of the
+	// key when proving the absence of a leaf.
+	Stem *Stem
+	// ValueSiblings are the sibling hashes on the path from the leaf to the
+	// root of the value tree of the stem node, starting at the leaf level.
+	// If the stem differs from the stem of the key, it only contains the root
+	// of the value tree of the stem node.
+	ValueSiblings []common.Hash
+	// PathSiblings are the sibling hashes on the path from the root of the
+	// trie to the stem node, starting at the root.
+	PathSiblings []common.Hash
+}
+
+// GetSize returns the number of bytes required for representing the proof,
+// counting keys, values, stems, and hashes.
+func (p *Proof) GetSize() int {
+	res := 0
+	for _, leaf := range p.Leaves {
+		res += len(leaf.Key)
+		if leaf.Value != nil {
+			res += len(leaf.Value)
+		}
+		if leaf.Stem != nil {
+			res += len(leaf.Stem)
+		}
+		res += (len(leaf.ValueSiblings) + len(leaf.PathSiblings)) * len(common.Hash{})
+	}
+	return res
+}
+
+// Verify checks that all leaf proofs are consistent with the given root hash.
+func (p *Proof) Verify(root common.Hash) error {
+	for _, leaf := range p.Leaves {
+		if err := leaf.Verify(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks that this leaf proof is consistent with the given root hash.
+func (l *LeafProof) Verify(root common.Hash) error {
+	keyStem := l.Key.Stem()
+	if len(l.PathSiblings) >= StemSize*8 {
+		return fmt.Errorf("invalid proof for key %x, path too long", l.Key)
+	}
+
+	var hash common.Hash
+	switch {
+	case l.Stem == nil:
+		if l.Value != nil {
+			return fmt.Errorf("invalid proof for key %x, value without stem", l.Key)
+		}
+	case *l.Stem == keyStem:
+		const valueTreeDepth = 8
+		if len(l.ValueSiblings) != valueTreeDepth {
+			return fmt.Errorf("invalid proof for key %x, expected %d value siblings, got %d", l.Key, valueTreeDepth, len(l.ValueSiblings))
+		}
+		var valueHash common.Hash
+		if l.Value != nil {
+			valueHash = sha256.Sum256(l.Value[:])
+		}
+		index := int(l.Key.SubIndex())
+		for _, sibling := range l.ValueSiblings {
+			sibling := sibling
+			if index%2 == 0 {
+				valueHash = hashPair(&valueHash, &sibling)
+			} else {
+				valueHash = hashPair(&sibling, &valueHash)
+			}
+			index /= 2
+		}
+		hash = hashStem(l.Stem, &valueHash)
+	default:
+		if l.Value != nil {
+			return fmt.Errorf("invalid proof for key %x, value in foreign stem", l.Key)
+		}
+		if len(l.ValueSiblings) != 1 {
+			return fmt.Errorf("invalid proof for key %x, missing value root of foreign stem", l.Key)
+		}
+		for i := range l.PathSiblings {
+			if l.Stem.getBit(i) != keyStem.getBit(i) {
+				return fmt.Errorf("invalid proof for key %x, foreign stem not on path", l.Key)
+			}
+		}
+		hash = hashStem(l.Stem, &l.ValueSiblings[0])
+	}
+
+	for depth := len(l.PathSiblings) - 1; depth >= 0; depth-- {
+		sibling := l.PathSiblings[depth]
+		if keyStem.getBit(depth) == 0 {
+			hash = hashPair(&hash, &sibling)
+		} else {
+			hash = hashPair(&sibling, &hash)
+		}
+	}
+	if hash != root {
+		return fmt.Errorf("invalid proof for key %x, root hash mismatch, wanted %x, got %x", l.Key, root, hash)
+	}
+	return nil
+}
+
+// createAccountProof creates a proof for the basic data and code hash of the
+// given account and the given storage slots in the given trie.
+func createAccountProof(t *trie, address common.Address, keys []common.Key) (*Proof, error) {
+	// Hashes need to be up-to-date for collecting sibling hashes.
+	if _, err := t.GetHash(); err != nil {
+		return nil, err
+	}
+	reincarnation, err := t.getReincarnation(address)
+	if err != nil {
+		return nil, err
+	}
+	treeKeys := []TreeKey{
+		getHeaderKey(address, basicDataLeafKey),
+		getHeaderKey(address, codeHashLeafKey),
+	}
+	for _, key := range keys {
+		treeKeys = append(treeKeys, getStorageKey(address, reincarnation, key))
+	}
+	res := &Proof{}
+	for _, key := range treeKeys {
+		leaf, err := createLeafProof(t, key)
+		if err != nil {
+			return nil, err
+		}
+		res.Leaves = append(res.Leaves, leaf)
+	}
+	return res, nil
+}
+
+func createLeafProof(t *trie, key TreeKey) (LeafProof, error) {
+	res := LeafProof{Key: key}
+	stem := key.Stem()
+	id := t.root
+	for depth := 0; id.IsInternal(); depth++ {
+		node, err := t.forest.store.getInternal(id)
+		if err != nil {
+			return res, err
+		}
+		bit := stem.getBit(depth)
+		next, sibling := node.children[bit], node.children[1-bit]
+		hash, err := t.forest.getHash(sibling)
+		if err != nil {
+			return res, err
+		}
+		res.PathSiblings = append(res.PathSiblings, hash)
+		id = next
+	}
+	if id.IsEmpty() {
+		return res, nil
+	}
+
+	node, err := t.forest.store.getStem(id)
+	if err != nil {
+		return res, err
+	}
+	res.Stem = new(Stem)
+	*res.Stem = node.stem
+	hashes, err := t.forest.getValueHashes(node)
+	if err != nil {
+		return res, err
+	}
+	if node.stem != stem {
+		valuesRoot, _ := merkleize(hashes[:], 0, nil)
+		res.ValueSiblings = []common.Hash{valuesRoot}
+		return res, nil
+	}
+	value, found, err := t.forest.getStemValue(node, key.SubIndex())
+	if err != nil {
+		return res, err
+	}
+	if found {
+		res.Value = &value
+	}
+	_, res.ValueSiblings = merkleize(hashes[:], int(key.SubIndex()), []common.Hash{})
+	return res, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func TestProof_ProofsOfPresentAndAbsentValuesAreValid(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	update := getRandomUpdate(rand.New(rand.NewSource(0)), 20, 10)
+	if _, err := state.Apply(1, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	root, err := state.GetHash()
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+
+	for _, change := range update.Slots {
+		proof, err := state.CreateProof(change.Account, change.Key)
+		if err != nil {
+			t.Fatalf("failed to create proof: %v", err)
+		}
+		if err := proof.Verify(root); err != nil {
+			t.Errorf("invalid proof: %v", err)
+		}
+		last := proof.Leaves[len(proof.Leaves)-1]
+		if last.Value == nil || *last.Value != change.Value {
+			t.Errorf("proof does not contain value %x of slot %x/%x", change.Value, change.Account, change.Key)
+		}
+	}
+
+	missing := []common.Address{{}, {1}, {2, 3}}
+	for _, address := range missing {
+		proof, err := state.CreateProof(address, common.Key{1}, common.Key{31: 1})
+		if err != nil {
+			t.Fatalf("failed to create proof: %v", err)
+		}
+		if err := proof.Verify(root); err != nil {
+			t.Errorf("invalid proof of absence: %v", err)
+		}
+		for _, leaf := range proof.Leaves {
+			if leaf.Value != nil {
+				t.Errorf("proof of absence contains value for key %x", leaf.Key)
+			}
+		}
+	}
+}
+
+func TestProof_TamperedProofsAreDetected(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	update := getRandomUpdate(rand.New(rand.NewSource(1)), 20, 10)
+	if _, err := state.Apply(1, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	root, err := state.GetHash()
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+	change := update.Slots[0]
+	proof, err := state.CreateProof(change.Account, change.Key)
+	if err != nil {
+		t.Fatalf("failed to create proof: %v", err)
+	}
+
+	leaf := proof.Leaves[len(proof.Leaves)-1]
+	value := *leaf.Value
+	value[0]++
+	leaf.Value = &value
+	if err := leaf.Verify(root); err == nil {
+		t.Errorf("modified value should be detected")
+	}
+
+	leaf = proof.Leaves[len(proof.Leaves)-1]
+	leaf.Value = nil
+	if err := leaf.Verify(root); err == nil {
+		t.Errorf("removed value should be detected")
+	}
+
+	if err := proof.Verify(common.Hash{1}); err == nil {
+		t.Errorf("wrong root hash should be detected")
+	}
+}
+
+func TestProof_ArchiveProofsAreValidForHistoricBlocks(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	address := common.Address{1}
+	key := common.Key{1}
+	for block := uint64(0); block < 3; block++ {
+		if err := archive.Add(block, common.Update{
+			CreatedAccounts: []common.Address{address},
+			Slots:           []common.SlotUpdate{{Account: address, Key: key, Value: common.Value{byte(block + 1)}}},
+		}, nil); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+
+	for block := uint64(0); block < 3; block++ {
+		root, err := archive.GetHash(block)
+		if err != nil {
+			t.Fatalf("failed to get hash: %v", err)
+		}
+		proof, err := archive.CreateProof(block, address, key)
+		if err != nil {
+			t.Fatalf("failed to create proof: %v", err)
+		}
+		if err := proof.Verify(root); err != nil {
+			t.Errorf("invalid proof for block %d: %v", block, err)
+		}
+		last := proof.Leaves[len(proof.Leaves)-1]
+		if last.Value == nil || *last.Value != (common.Value{byte(block + 1)}) {
+			t.Errorf("unexpected value in proof for block %d", block)
+		}
+		if proof.GetSize() == 0 {
+			t.Errorf("proof should not be empty")
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/backend"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// State is a LiveDB implementation retaining the current world state in a
+// binary trie. Nodes are updated in-place.
+type State struct {
+	directory string
+	lock      common.LockFile
+	mutex     sync.Mutex
+	store     *nodeStore
+	trie      trie
+}
+
+var _ state.LiveDB = (*State)(nil)
+
+// OpenGoMemoryState opens a binary trie state retained entirely in memory,
+// persisted in the given directory on Flush and Close.
+func OpenGoMemoryState(directory string, cacheCapacity int) (*State, error) {
+	return openState(directory, MemoryStock, cacheCapacity)
+}
+
+// OpenGoFileState opens a binary trie state retained in files in the given
+// directory, keeping the given number of nodes cached in memory.
+func OpenGoFileState(directory string, cacheCapacity int) (*State, error) {
+	return openState(directory, FileStock, cacheCapacity)
+}
+
+func openState(directory string, stockType StockType, cacheCapacity int) (*State, error) {
+	lock, err := mpt.LockDirectory(directory)
+	if err != nil {
+		return nil, err
+	}
+	root, err := readRoot(filepath.Join(directory, rootFileName))
+	if err != nil {
+		return nil, errors.Join(err, lock.Release())
+	}
+	store, err := openNodeStore(directory, stockType, cacheCapacity)
+	if err != nil {
+		return nil, errors.Join(err, lock.Release())
+	}
+	return &State{
+		directory: directory,
+		lock:      lock,
+		store:     store,
+		trie:      trie{forest: newForest(store, false), root: root},
+	}, nil
+}
+
+func (s *State) Exists(address common.Address) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.Exists(address)
+}
+
+func (s *State) GetBalance(address common.Address) (common.Balance, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetBalance(address)
+}
+
+func (s *State) GetNonce(address common.Address) (common.Nonce, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetNonce(address)
+}
+
+func (s *State) GetStorage(address common.Address, key common.Key) (common.Value, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetStorage(address, key)
+}
+
+func (s *State) GetCode(address common.Address) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetCode(address)
+}
+
+func (s *State) GetCodeSize(address common.Address) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetCodeSize(address)
+}
+
+func (s *State) GetCodeHash(address common.Address) (common.Hash, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetCodeHash(address)
+}
+
+func (s *State) GetHash() (common.Hash, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.GetHash()
+}
+
+// CreateProof creates a proof for the basic data and code hash of the given
+// account and the given storage slots of the account.
+func (s *State) CreateProof(address common.Address, keys ...common.Key) (*Proof, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return createAccountProof(&s.trie, address, keys)
+}
+
+func (s *State) Apply(block uint64, update common.Update) (common.Releaser, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := update.ApplyTo(&s.trie); err != nil {
+		return nil, err
+	}
+	_, err := s.trie.GetHash()
+	return nil, err
+}
+
+// Check verifies the structural invariants of the trie and its hashes.
+func (s *State) Check() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trie.forest.check(s.trie.root)
+}
+
+func (s *State) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.flush()
+}
+
+func (s *State) flush() error {
+	return errors.Join(
+		s.store.Flush(),
+		writeRoot(filepath.Join(s.directory, rootFileName), s.trie.root),
+	)
+}
+
+func (s *State) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return errors.Join(
+		s.flush(),
+		s.store.Close(),
+		s.lock.Release(),
+	)
+}
+
+func (s *State) GetMemoryFootprint() *common.MemoryFootprint {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	mf := common.NewMemoryFootprint(unsafe.Sizeof(*s))
+	mf.AddChild("nodes", s.store.GetMemoryFootprint())
+	return mf
+}
+
+func (s *State) GetDiskFootprint() (*common.DiskFootprint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return getDiskFootprint(s.store, filepath.Join(s.directory, rootFileName))
+}
+
+func (s *State) GetSnapshotableComponents() []backend.Snapshotable {
+	return nil
+}
+
+func (s *State) RunPostRestoreTasks() error {
+	return nil
+}
+
+const rootFileName = "root.dat"
+
+// readRoot loads the root ID stored in the given file. If the file does not
+// exist, the empty ID is returned.
+func readRoot(filename string) (NodeId, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return EmptyId, nil
+	}
+	if err != nil {
+		return EmptyId, err
+	}
+	if len(data) != 8 {
+		return EmptyId, fmt.Errorf("invalid root file %s, expected 8 bytes, got %d", filename, len(data))
+	}
+	return NodeId(binary.BigEndian.Uint64(data)), nil
+}
+
+func writeRoot(filename string, root NodeId) error {
+	return os.WriteFile(filename, binary.BigEndian.AppendUint64(nil, uint64(root)), 0600)
+}
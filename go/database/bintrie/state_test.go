@@ -0,0 +1,357 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+var stateFactories = map[string]func(string) (*State, error){
+	"memory": func(dir string) (*State, error) { return OpenGoMemoryState(dir, MinCacheCapacity) },
+	"file":   func(dir string) (*State, error) { return OpenGoFileState(dir, MinCacheCapacity) },
+}
+
+func TestState_EmptyStateHasZeroHash(t *testing.T) {
+	for name, open := range stateFactories {
+		t.Run(name, func(t *testing.T) {
+			state, err := open(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			defer state.Close()
+			hash, err := state.GetHash()
+			if err != nil {
+				t.Fatalf("failed to get hash: %v", err)
+			}
+			if hash != (common.Hash{}) {
+				t.Errorf("unexpected hash of empty state: %x", hash)
+			}
+		})
+	}
+}
+
+func TestState_AccountDataCanBeUpdatedAndRead(t *testing.T) {
+	for name, open := range stateFactories {
+		t.Run(name, func(t *testing.T) {
+			state, err := open(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			defer state.Close()
+
+			address := common.Address{1}
+			balance := common.Balance{0: 1, 31: 2}
+			nonce := common.ToNonce(12)
+			code := make([]byte, 1000)
+			for i := range code {
+				code[i] = byte(i)
+			}
+			update := common.Update{
+				CreatedAccounts: []common.Address{address},
+				Balances:        []common.BalanceUpdate{{Account: address, Balance: balance}},
+				Nonces:          []common.NonceUpdate{{Account: address, Nonce: nonce}},
+				Codes:           []common.CodeUpdate{{Account: address, Code: code}},
+				Slots: []common.SlotUpdate{
+					{Account: address, Key: common.Key{31: 1}, Value: common.Value{1}},
+					{Account: address, Key: common.Key{0: 1}, Value: common.Value{2}},
+				},
+			}
+			if _, err := state.Apply(1, update); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+
+			if exists, err := state.Exists(address); err != nil || !exists {
+				t.Errorf("account should exist, err %v", err)
+			}
+			if got, err := state.GetBalance(address); err != nil || got != balance {
+				t.Errorf("unexpected balance, wanted %x, got %x, err %v", balance, got, err)
+			}
+			if got, err := state.GetNonce(address); err != nil || got != nonce {
+				t.Errorf("unexpected nonce, wanted %x, got %x, err %v", nonce, got, err)
+			}
+			if got, err := state.GetCode(address); err != nil || !bytes.Equal(got, code) {
+				t.Errorf("unexpected code, wanted %x, got %x, err %v", code, got, err)
+			}
+			if got, err := state.GetCodeSize(address); err != nil || got != len(code) {
+				t.Errorf("unexpected code size, wanted %d, got %d, err %v", len(code), got, err)
+			}
+			if got, err := state.GetCodeHash(address); err != nil || got != common.Keccak256(code) {
+				t.Errorf("unexpected code hash, got %x, err %v", got, err)
+			}
+			if got, err := state.GetStorage(address, common.Key{31: 1}); err != nil || got != (common.Value{1}) {
+				t.Errorf("unexpected header slot value, got %x, err %v", got, err)
+			}
+			if got, err := state.GetStorage(address, common.Key{0: 1}); err != nil || got != (common.Value{2}) {
+				t.Errorf("unexpected main slot value, got %x, err %v", got, err)
+			}
+			if err := state.Check(); err != nil {
+				t.Errorf("invalid state: %v", err)
+			}
+		})
+	}
+}
+
+func TestState_CodeCanBeShrunk(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	address := common.Address{1}
+	if _, err := state.Apply(1, common.Update{
+		Codes: []common.CodeUpdate{{Account: address, Code: make([]byte, 10_000)}},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if _, err := state.Apply(2, common.Update{
+		Codes: []common.CodeUpdate{{Account: address, Code: []byte{1, 2, 3}}},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if got, err := state.GetCode(address); err != nil || !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("unexpected code, got %x, err %v", got, err)
+	}
+
+	// Removing the code again should restore the initial state hash.
+	want := getHashOf(t, common.Update{Nonces: []common.NonceUpdate{{Account: address}}})
+	if _, err := state.Apply(3, common.Update{
+		Codes: []common.CodeUpdate{{Account: address, Code: []byte{}}},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if got, err := state.GetHash(); err != nil || got != want {
+		t.Errorf("unexpected hash, wanted %x, got %x, err %v", want, got, err)
+	}
+	if err := state.Check(); err != nil {
+		t.Errorf("invalid state: %v", err)
+	}
+}
+
+func TestState_DeletedAccountsLoseAllTheirData(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	address := common.Address{1}
+	if _, err := state.Apply(1, common.Update{
+		CreatedAccounts: []common.Address{address},
+		Balances:        []common.BalanceUpdate{{Account: address, Balance: common.Balance{1}}},
+		Codes:           []common.CodeUpdate{{Account: address, Code: make([]byte, 5_000)}},
+		Slots: []common.SlotUpdate{
+			{Account: address, Key: common.Key{31: 1}, Value: common.Value{1}},
+			{Account: address, Key: common.Key{0: 1}, Value: common.Value{2}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if _, err := state.Apply(2, common.Update{
+		DeletedAccounts: []common.Address{address},
+		CreatedAccounts: []common.Address{address},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+
+	if exists, err := state.Exists(address); err != nil || !exists {
+		t.Errorf("re-created account should exist, err %v", err)
+	}
+	if got, err := state.GetBalance(address); err != nil || got != (common.Balance{}) {
+		t.Errorf("unexpected balance %x, err %v", got, err)
+	}
+	if got, err := state.GetCode(address); err != nil || len(got) != 0 {
+		t.Errorf("unexpected code %x, err %v", got, err)
+	}
+	for _, key := range []common.Key{{31: 1}, {0: 1}} {
+		if got, err := state.GetStorage(address, key); err != nil || got != (common.Value{}) {
+			t.Errorf("unexpected value of slot %x: %x, err %v", key, got, err)
+		}
+	}
+	if err := state.Check(); err != nil {
+		t.Errorf("invalid state: %v", err)
+	}
+}
+
+func TestState_HashIsIndependentOfInsertionOrder(t *testing.T) {
+	update := getRandomUpdate(rand.New(rand.NewSource(0)), 100, 10)
+	want := getHashOf(t, update)
+
+	reversed := update
+	reversed.Slots = nil
+	for i := len(update.Slots) - 1; i >= 0; i-- {
+		reversed.Slots = append(reversed.Slots, update.Slots[i])
+	}
+	got := getHashOf(t, reversed)
+	if want != got {
+		t.Errorf("hash depends on insertion order, wanted %x, got %x", want, got)
+	}
+}
+
+func TestState_RemovingAllValuesProducesEmptyTrie(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	update := getRandomUpdate(rand.New(rand.NewSource(1)), 50, 20)
+	if _, err := state.Apply(1, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if err := state.Check(); err != nil {
+		t.Fatalf("invalid state: %v", err)
+	}
+
+	clear := common.Update{}
+	for _, change := range update.Slots {
+		clear.Slots = append(clear.Slots, common.SlotUpdate{Account: change.Account, Key: change.Key})
+	}
+	if _, err := state.Apply(2, clear); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if err := state.Check(); err != nil {
+		t.Fatalf("invalid state: %v", err)
+	}
+
+	// Only the accounts should remain.
+	want := getHashOf(t, common.Update{CreatedAccounts: update.CreatedAccounts})
+	if got, err := state.GetHash(); err != nil || got != want {
+		t.Errorf("unexpected hash, wanted %x, got %x, err %v", want, got, err)
+	}
+}
+
+func TestState_ContentIsPersistent(t *testing.T) {
+	for name, open := range stateFactories {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			state, err := open(dir)
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			update := getRandomUpdate(rand.New(rand.NewSource(2)), 100, 50)
+			if _, err := state.Apply(1, update); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			want, err := state.GetHash()
+			if err != nil {
+				t.Fatalf("failed to get hash: %v", err)
+			}
+			if err := state.Close(); err != nil {
+				t.Fatalf("failed to close state: %v", err)
+			}
+
+			state, err = open(dir)
+			if err != nil {
+				t.Fatalf("failed to reopen state: %v", err)
+			}
+			defer state.Close()
+			if got, err := state.GetHash(); err != nil || got != want {
+				t.Errorf("unexpected hash after reopening, wanted %x, got %x, err %v", want, got, err)
+			}
+			for _, change := range update.Slots {
+				if got, err := state.GetStorage(change.Account, change.Key); err != nil || got != change.Value {
+					t.Errorf("unexpected value of slot %x/%x, wanted %x, got %x, err %v", change.Account, change.Key, change.Value, got, err)
+				}
+			}
+			if err := state.Check(); err != nil {
+				t.Errorf("invalid state: %v", err)
+			}
+		})
+	}
+}
+
+func TestState_DiskFootprintCoversNodesAndRoot(t *testing.T) {
+	for name, open := range stateFactories {
+		t.Run(name, func(t *testing.T) {
+			state, err := open(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			defer state.Close()
+			update := getRandomUpdate(rand.New(rand.NewSource(3)), 10, 5)
+			if _, err := state.Apply(1, update); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			if err := state.Flush(); err != nil {
+				t.Fatalf("failed to flush state: %v", err)
+			}
+
+			footprint, err := state.GetDiskFootprint()
+			if err != nil {
+				t.Fatalf("failed to get disk footprint: %v", err)
+			}
+			for _, name := range []string{"nodes", "roots"} {
+				if footprint.GetChild(name).Total() == 0 {
+					t.Errorf("no disk usage reported for %s:\n%v", name, footprint)
+				}
+			}
+		})
+	}
+}
+
+func TestState_CannotBeOpenedTwice(t *testing.T) {
+	dir := t.TempDir()
+	state, err := OpenGoFileState(dir, MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	if _, err := OpenGoFileState(dir, MinCacheCapacity); err == nil {
+		t.Errorf("opening a state twice should fail")
+	}
+}
+
+// getHashOf computes the hash of the state resulting from applying the given
+// update to an empty state.
+func getHashOf(t *testing.T, update common.Update) common.Hash {
+	t.Helper()
+	state, err := OpenGoMemoryState(t.TempDir(), MinCacheCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	if _, err := state.Apply(0, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	hash, err := state.GetHash()
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+	return hash
+}
+
+// getRandomUpdate creates an update creating the given number of accounts,
+// each with the given number of random storage slots.
+func getRandomUpdate(r *rand.Rand, numAccounts, numSlots int) common.Update {
+	update := common.Update{}
+	for i := 0; i < numAccounts; i++ {
+		var address common.Address
+		r.Read(address[:])
+		update.CreatedAccounts = append(update.CreatedAccounts, address)
+		for j := 0; j < numSlots; j++ {
+			var key common.Key
+			var value common.Value
+			r.Read(key[:])
+			if j%4 == 0 {
+				key = common.Key{31: byte(j)}
+			}
+			value[r.Intn(len(value))] = byte(r.Intn(255) + 1)
+			update.Slots = append(update.Slots, common.SlotUpdate{Account: address, Key: key, Value: value})
+		}
+	}
+	update.Normalize()
+	return update
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package bintrie
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+var emptyCodeHash = common.Keccak256([]byte{})
+
+// trie maps Carmen's account model onto a binary trie with the given root.
+// It implements the read operations of the state interfaces as well as the
+// common.UpdateTarget interface, updating its root on each modification.
+type trie struct {
+	forest *forest
+	root   NodeId
+}
+
+func (t *trie) get(key TreeKey) (common.Value, bool, error) {
+	return t.forest.getValue(t.root, key)
+}
+
+func (t *trie) set(key TreeKey, value common.Value, present bool) error {
+	root, err := t.forest.setValue(t.root, key, value, present)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+func (t *trie) getBasicData(address common.Address) (basicData, bool, error) {
+	var res basicData
+	value, exists, err := t.get(getHeaderKey(address, basicDataLeafKey))
+	if err != nil || !exists {
+		return res, false, err
+	}
+	res.decode(value)
+	return res, true, nil
+}
+
+func (t *trie) setBasicData(address common.Address, data basicData) error {
+	return t.set(getHeaderKey(address, basicDataLeafKey), data.encode(), true)
+}
+
+func (t *trie) getReincarnation(address common.Address) (uint32, error) {
+	value, _, err := t.get(getHeaderKey(address, reincarnationLeafKey))
+	return binary.BigEndian.Uint32(value[len(value)-4:]), err
+}
+
+func (t *trie) Exists(address common.Address) (bool, error) {
+	_, exists, err := t.getBasicData(address)
+	return exists, err
+}
+
+func (t *trie) GetBalance(address common.Address) (common.Balance, error) {
+	var res common.Balance
+	data, exists, err := t.getBasicData(address)
+	if err != nil || !exists {
+		return res, err
+	}
+	high, _, err := t.get(getHeaderKey(address, balanceHighLeafKey))
+	if err != nil {
+		return res, err
+	}
+	copy(res[:16], high[16:])
+	copy(res[16:], data.balance[:])
+	return res, nil
+}
+
+func (t *trie) GetNonce(address common.Address) (common.Nonce, error) {
+	data, _, err := t.getBasicData(address)
+	return data.nonce, err
+}
+
+func (t *trie) GetStorage(address common.Address, key common.Key) (common.Value, error) {
+	reincarnation, err := t.getReincarnation(address)
+	if err != nil {
+		return common.Value{}, err
+	}
+	value, _, err := t.get(getStorageKey(address, reincarnation, key))
+	return value, err
+}
+
+func (t *trie) GetCode(address common.Address) ([]byte, error) {
+	data, exists, err := t.getBasicData(address)
+	if err != nil || !exists || data.codeSize == 0 {
+		return nil, err
+	}
+	res := make([]byte, 0, data.codeSize+codeChunkSize)
+	var node *stemNode
+	for i := 0; len(res) < data.codeSize; i++ {
+		key := getCodeChunkKey(address, i)
+		if i == 0 || key.SubIndex() == 0 {
+			stem := key.Stem()
+			if node, err = t.forest.getStem(t.root, &stem); err != nil {
+				return nil, err
+			}
+			if node == nil {
+				return nil, fmt.Errorf("missing code chunk %d of account %x", i, address)
+			}
+		}
+		chunk, found, err := t.forest.getStemValue(node, key.SubIndex())
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("missing code chunk %d of account %x", i, address)
+		}
+		res = append(res, chunk[1:]...)
+	}
+	return res[:data.codeSize], nil
+}
+
+func (t *trie) GetCodeSize(address common.Address) (int, error) {
+	data, _, err := t.getBasicData(address)
+	return data.codeSize, err
+}
+
+func (t *trie) GetCodeHash(address common.Address) (common.Hash, error) {
+	value, exists, err := t.get(getHeaderKey(address, codeHashLeafKey))
+	if err != nil || !exists {
+		return emptyCodeHash, err
+	}
+	return common.Hash(value), nil
+}
+
+// GetAccountHash returns the hash of the stem node containing the header of
+// the given account. The binary trie has no dedicated account sub-trie, so
+// this hash covers the basic account data but not the full storage.
+func (t *trie) GetAccountHash(address common.Address) (common.Hash, error) {
+	stem := getHeaderKey(address, 0).Stem()
+	id := t.root
+	for depth := 0; id.IsInternal(); depth++ {
+		node, err := t.forest.store.getInternal(id)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		id = node.children[stem.getBit(depth)]
+	}
+	if id.IsEmpty() {
+		return common.Hash{}, nil
+	}
+	node, err := t.forest.store.getStem(id)
+	if err != nil || node.stem != stem {
+		return common.Hash{}, err
+	}
+	return t.forest.getHash(id)
+}
+
+func (t *trie) GetHash() (common.Hash, error) {
+	return t.forest.getHash(t.root)
+}
+
+// -- common.UpdateTarget --
+
+func (t *trie) CreateAccount(address common.Address) error {
+	data, exists, err := t.getBasicData(address)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := t.setBasicData(address, data); err != nil {
+			return err
+		}
+	}
+	return t.clearStorage(address)
+}
+
+func (t *trie) DeleteAccount(address common.Address) error {
+	data, exists, err := t.getBasicData(address)
+	if err != nil || !exists {
+		return err
+	}
+	if err := t.setCode(address, data, nil); err != nil {
+		return err
+	}
+	updates := []leafUpdate{
+		{index: basicDataLeafKey},
+		{index: codeHashLeafKey},
+		{index: balanceHighLeafKey},
+	}
+	if err := t.update(getHeaderKey(address, 0).Stem(), updates); err != nil {
+		return err
+	}
+	return t.clearStorage(address)
+}
+
+// clearStorage removes all storage slots of the given account. Slots in the
+// account header are removed explicitly, while all other slots are
+// invalidated by incrementing the reincarnation counter of the account.
+func (t *trie) clearStorage(address common.Address) error {
+	reincarnation, err := t.getReincarnation(address)
+	if err != nil {
+		return err
+	}
+	var counter common.Value
+	binary.BigEndian.PutUint32(counter[len(counter)-4:], reincarnation+1)
+	updates := []leafUpdate{{index: reincarnationLeafKey, value: counter, present: true}}
+	for i := 0; i < numHeaderStorageKeys; i++ {
+		updates = append(updates, leafUpdate{index: byte(headerStorageOffset + i)})
+	}
+	return t.update(getHeaderKey(address, 0).Stem(), updates)
+}
+
+func (t *trie) SetBalance(address common.Address, balance common.Balance) error {
+	data, _, err := t.getBasicData(address)
+	if err != nil {
+		return err
+	}
+	copy(data.balance[:], balance[16:])
+	var high common.Value
+	copy(high[16:], balance[:16])
+	if err := t.set(getHeaderKey(address, balanceHighLeafKey), high, high != common.Value{}); err != nil {
+		return err
+	}
+	return t.setBasicData(address, data)
+}
+
+func (t *trie) SetNonce(address common.Address, nonce common.Nonce) error {
+	data, _, err := t.getBasicData(address)
+	if err != nil {
+		return err
+	}
+	data.nonce = nonce
+	return t.setBasicData(address, data)
+}
+
+func (t *trie) SetCode(address common.Address, code []byte) error {
+	if len(code) > maxCodeSize {
+		return fmt.Errorf("code of account %x exceeds maximum size: %d > %d", address, len(code), maxCodeSize)
+	}
+	data, _, err := t.getBasicData(address)
+	if err != nil {
+		return err
+	}
+	if err := t.setCode(address, data, code); err != nil {
+		return err
+	}
+	hash := common.Keccak256(code)
+	if err := t.set(getHeaderKey(address, codeHashLeafKey), common.Value(hash), len(code) > 0); err != nil {
+		return err
+	}
+	data.codeSize = len(code)
+	return t.setBasicData(address, data)
+}
+
+// setCode replaces the code chunks of the given account, removing chunks of
+// the previous code not covered by the new code.
+func (t *trie) setCode(address common.Address, data basicData, code []byte) error {
+	chunks := chunkify(code)
+	numChunks := (data.codeSize + codeChunkSize - 1) / codeChunkSize
+	if len(chunks) > numChunks {
+		numChunks = len(chunks)
+	}
+
+	// Chunks are grouped by their stem to update each stem node only once.
+	var updates []leafUpdate
+	var stem Stem
+	for i := 0; i < numChunks; i++ {
+		key := getCodeChunkKey(address, i)
+		if i > 0 && key.SubIndex() == 0 {
+			if err := t.update(stem, updates); err != nil {
+				return err
+			}
+			updates = updates[:0]
+		}
+		stem = key.Stem()
+		update := leafUpdate{index: key.SubIndex()}
+		if i < len(chunks) {
+			update.value = chunks[i]
+			update.present = true
+		}
+		updates = append(updates, update)
+	}
+	if len(updates) > 0 {
+		return t.update(stem, updates)
+	}
+	return nil
+}
+
+func (t *trie) update(stem Stem, updates []leafUpdate) error {
+	root, err := t.forest.update(t.root, stem, updates)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+func (t *trie) SetStorage(address common.Address, key common.Key, value common.Value) error {
+	// Like in the MPT based schemas, storage of non-existing accounts is
+	// ignored.
+	_, exists, err := t.getBasicData(address)
+	if err != nil || !exists {
+		return err
+	}
+	reincarnation, err := t.getReincarnation(address)
+	if err != nil {
+		return err
+	}
+	return t.set(getStorageKey(address, reincarnation, key), value, value != common.Value{})
+}
@@ -12,13 +12,16 @@ package mpt
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"unsafe"
 
+	"github.com/Fantom-foundation/Carmen/go/backend/archive"
 	"github.com/Fantom-foundation/Carmen/go/common"
 )
 
@@ -33,10 +36,17 @@ type ArchiveTrie struct {
 	head         LiveState // the current head-state
 	forest       Database  // global forest with all versions of LiveState
 	nodeSource   NodeSource
-	roots        []Root     // the roots of individual blocks indexed by block height
-	rootsMutex   sync.Mutex // protecting access to the roots list
-	rootFile     string     // the file storing the list of roots
-	addMutex     sync.Mutex // a mutex to make sure that at any time only one thread is adding new blocks
+	roots        []Root            // the roots of individual blocks indexed by block height
+	rootsMutex   sync.Mutex        // protecting access to the roots list
+	rootFile     string            // the file storing the list of roots
+	stats        []StateStats      // the state statistics of individual blocks, nil if not tracked
+	statsFile    string            // the file storing the list of state statistics
+	statsWorker  *stateStatsWorker // completing the statistics of added blocks, nil if not tracked
+	sizeCounter  stateSizeCounter  // counting the accounts and values inserted into the forest
+	directory    string            // the directory storing the archive
+	changes      *changeIndex      // the blocks in which accounts were modified
+	indexed      bool              // true if the change index covers all blocks
+	addMutex     sync.Mutex        // a mutex to make sure that at any time only one thread is adding new blocks
 	errorMutex   sync.RWMutex
 	archiveError error // a non-nil error will be stored here should it occur during any archive operation
 }
@@ -51,6 +61,20 @@ func OpenArchiveTrie(directory string, config MptConfig, cacheCapacity int) (*Ar
 	if err != nil {
 		return nil, err
 	}
+	statsFile := filepath.Join(directory, stateStatsFileName)
+	stats, err := loadStateStats(statsFile)
+	if err != nil {
+		return nil, err
+	}
+	// Statistics can only be maintained if they cover all blocks, which is not
+	// the case for archives created before statistics were introduced. Since
+	// statistics are stored before the roots, entries of blocks beyond the
+	// roots may be present after a crash; those are dropped.
+	if len(stats) < len(roots) {
+		stats = nil
+	} else {
+		stats = stats[:len(roots)]
+	}
 	forestConfig := ForestConfig{Mode: Immutable, CacheCapacity: cacheCapacity}
 	forest, err := OpenFileForest(directory, config, forestConfig)
 	if err != nil {
@@ -61,18 +85,34 @@ func OpenArchiveTrie(directory string, config MptConfig, cacheCapacity int) (*Ar
 		forest.Close()
 		return nil, err
 	}
+	changes, err := openChangeIndex(filepath.Join(directory, changeIndexDirectoryName))
+	if err != nil {
+		head.Close()
+		return nil, err
+	}
 	state, err := newMptState(directory, lock, head)
 	if err != nil {
 		head.Close()
+		changes.Close()
 		return nil, err
 	}
-	return &ArchiveTrie{
-		head:       state,
-		forest:     forest,
-		nodeSource: forest,
-		roots:      roots,
-		rootFile:   rootfile,
-	}, nil
+	res := &ArchiveTrie{
+		head:        state,
+		forest:      forest,
+		nodeSource:  forest,
+		roots:       roots,
+		rootFile:    rootfile,
+		stats:       stats,
+		statsFile:   statsFile,
+		sizeCounter: forest,
+		directory:   directory,
+		changes:     changes,
+		indexed:     changes.getNumBlocks() == uint64(len(roots)),
+	}
+	if stats != nil {
+		res.statsWorker = startStateStatsWorker(res)
+	}
+	return res, nil
 }
 
 func VerifyArchive(directory string, config MptConfig, observer VerificationObserver) error {
@@ -86,6 +126,116 @@ func VerifyArchive(directory string, config MptConfig, observer VerificationObse
 	return VerifyFileForest(directory, config, roots, observer)
 }
 
+// InitializeArchiveMetadata creates the metadata maintained by archives next
+// to their blocks for an archive in the given directory whose forest and
+// roots have been written directly, as done when sealing an imported state.
+// All blocks of the archive but the last one must be empty. The metadata
+// covers the state statistics and the change index of all blocks. Metadata
+// already present is kept.
+func InitializeArchiveMetadata(directory string, config MptConfig) error {
+	archive, err := OpenArchiveTrie(directory, config, DefaultMptStateCapacity)
+	if err != nil {
+		return err
+	}
+	return errors.Join(archive.initializeMetadata(), archive.Close())
+}
+
+func (a *ArchiveTrie) initializeMetadata() error {
+	height, empty, err := a.GetBlockHeight()
+	if err != nil || empty {
+		return err
+	}
+	trackStats := a.statsWorker == nil
+	buildIndex := a.changes.getNumBlocks() == 0
+	if !trackStats && !buildIndex {
+		return nil
+	}
+	a.rootsMutex.Lock()
+	for block := uint64(0); block < height; block++ {
+		if !a.roots[block].NodeRef.Id().IsEmpty() {
+			a.rootsMutex.Unlock()
+			return fmt.Errorf("block %d of the archive is not empty", block)
+		}
+	}
+	a.rootsMutex.Unlock()
+
+	// All accounts of the last block are created in this block.
+	codes, err := a.GetCodes()
+	if err != nil {
+		return err
+	}
+	stats := StateStats{}
+	accounts := []common.Address{}
+	err = a.VisitTrie(height, MakeVisitor(func(node Node, _ NodeInfo) VisitResponse {
+		switch n := node.(type) {
+		case *AccountNode:
+			stats.NumAccounts++
+			stats.CodeBytes += uint64(len(codes[n.info.CodeHash]))
+			if buildIndex {
+				accounts = append(accounts, n.address)
+			}
+		case *ValueNode:
+			stats.NumSlots++
+		}
+		return VisitResponseContinue
+	}))
+	if err != nil {
+		return err
+	}
+
+	if trackStats {
+		a.rootsMutex.Lock()
+		a.stats = make([]StateStats, height+1)
+		a.stats[height] = stats
+		a.rootsMutex.Unlock()
+		a.statsWorker = startStateStatsWorker(a)
+	}
+	if buildIndex {
+		if err := a.changes.add(height, accounts); err != nil {
+			return err
+		}
+		a.addMutex.Lock()
+		a.indexed = true
+		a.addMutex.Unlock()
+	}
+	return nil
+}
+
+// RebuildChangeIndex re-creates the index of modified accounts of the archive
+// in the given directory, which is required for querying the history of
+// accounts and storage slots. It is needed for archives created before the
+// index was introduced or if the index got out of sync with the archive.
+func RebuildChangeIndex(directory string, config MptConfig) error {
+	if err := os.RemoveAll(filepath.Join(directory, changeIndexDirectoryName)); err != nil {
+		return err
+	}
+	archive, err := OpenArchiveTrie(directory, config, DefaultMptStateCapacity)
+	if err != nil {
+		return err
+	}
+	return errors.Join(archive.rebuildChangeIndex(), archive.Close())
+}
+
+func (a *ArchiveTrie) rebuildChangeIndex() error {
+	height, empty, err := a.GetBlockHeight()
+	if err != nil || empty {
+		return err
+	}
+	for block := uint64(0); block <= height; block++ {
+		diff, err := a.GetDiffForBlock(block)
+		if err != nil {
+			return err
+		}
+		if err := a.changes.add(block, sortedAccounts(diff)); err != nil {
+			return err
+		}
+	}
+	a.addMutex.Lock()
+	a.indexed = true
+	a.addMutex.Unlock()
+	return nil
+}
+
 func (a *ArchiveTrie) Add(block uint64, update common.Update, hint any) error {
 	if err := a.CheckErrors(); err != nil {
 		return err
@@ -103,6 +253,7 @@ func (a *ArchiveTrie) Add(block uint64, update common.Update, hint any) error {
 	}
 
 	// Mark skipped blocks as having no changes.
+	skipped := 0
 	if uint64(len(a.roots)) < block {
 		lastHash, err := a.head.GetHash()
 		if err != nil {
@@ -111,12 +262,29 @@ func (a *ArchiveTrie) Add(block uint64, update common.Update, hint any) error {
 		}
 		for uint64(len(a.roots)) < block {
 			a.roots = append(a.roots, Root{a.head.Root(), lastHash})
+			skipped++
 		}
 	}
+	before := emptyNodeReference
+	if len(a.roots) > 0 {
+		before = a.roots[len(a.roots)-1].NodeRef
+	}
 	a.rootsMutex.Unlock()
 
-	// Apply all the changes of the update.
-	if err := update.ApplyTo(a.head); err != nil {
+	// Apply all the changes of the update, keeping track of the state size
+	// if the statistics are maintained.
+	var tracker *stateStatsTracker
+	if a.statsWorker != nil {
+		for i := 0; i < skipped; i++ {
+			a.statsWorker.add(stateStatsJob{before: before})
+		}
+		tracker = newStateStatsTracker(a.head, a.sizeCounter)
+	}
+	if tracker != nil {
+		if err := update.ApplyTo(tracker); err != nil {
+			return a.addError(err)
+		}
+	} else if err := update.ApplyTo(a.head); err != nil {
 		return a.addError(err)
 	}
 
@@ -145,13 +313,33 @@ func (a *ArchiveTrie) Add(block uint64, update common.Update, hint any) error {
 		return a.addError(err)
 	}
 
+	// Record modified accounts. An incomplete index is not extended, since
+	// it needs to be rebuilt anyway.
+	if a.indexed {
+		if err := a.changes.add(block, getModifiedAccounts(&update)); err != nil {
+			return a.addError(err)
+		}
+	}
+
 	// Save new root node.
 	a.rootsMutex.Lock()
 	a.roots = append(a.roots, Root{a.head.Root(), hash})
 	a.rootsMutex.Unlock()
+	if tracker != nil {
+		a.statsWorker.add(tracker.getJob(before))
+	}
 	return nil
 }
 
+// getCurrentStateStats returns the statistics of the last block, or empty
+// statistics if there is none. The roots mutex must be held by the caller.
+func (a *ArchiveTrie) getCurrentStateStats() StateStats {
+	if len(a.stats) == 0 {
+		return StateStats{}
+	}
+	return a.stats[len(a.stats)-1]
+}
+
 func (a *ArchiveTrie) GetBlockHeight() (block uint64, empty bool, err error) {
 	a.rootsMutex.Lock()
 	length := uint64(len(a.roots))
@@ -240,6 +428,12 @@ func (a *ArchiveTrie) GetHash(block uint64) (hash common.Hash, err error) {
 
 // GetDiff computes the difference between the given source and target blocks.
 func (a *ArchiveTrie) GetDiff(srcBlock, trgBlock uint64) (Diff, error) {
+	return a.GetDiffWithContext(context.Background(), srcBlock, trgBlock)
+}
+
+// GetDiffWithContext is like GetDiff but aborts the diff computation with the
+// context's error once the given context is done.
+func (a *ArchiveTrie) GetDiffWithContext(ctx context.Context, srcBlock, trgBlock uint64) (Diff, error) {
 	a.rootsMutex.Lock()
 	if srcBlock >= uint64(len(a.roots)) {
 		a.rootsMutex.Unlock()
@@ -252,34 +446,330 @@ func (a *ArchiveTrie) GetDiff(srcBlock, trgBlock uint64) (Diff, error) {
 	before := a.roots[srcBlock].NodeRef
 	after := a.roots[trgBlock].NodeRef
 	a.rootsMutex.Unlock()
-	return GetDiff(a.nodeSource, &before, &after)
+	return GetDiffWithContext(ctx, a.nodeSource, &before, &after)
 }
 
 // GetDiffForBlock computes the diff introduced by the given block compared to its
 // predecessor. Note that this enables access to the changes introduced by block 0.
 func (a *ArchiveTrie) GetDiffForBlock(block uint64) (Diff, error) {
-	if block == 0 {
-		a.rootsMutex.Lock()
+	return a.GetDiffForBlockWithContext(context.Background(), block)
+}
+
+// GetDiffForBlockWithContext is like GetDiffForBlock but aborts the diff
+// computation with the context's error once the given context is done.
+func (a *ArchiveTrie) GetDiffForBlockWithContext(ctx context.Context, block uint64) (Diff, error) {
+	before, after, err := a.getRootsOfBlock(block)
+	if err != nil {
+		return Diff{}, err
+	}
+	return GetDiffWithContext(ctx, a.nodeSource, &before, &after)
+}
+
+// GetAccountDiffForBlockWithContext is like GetDiffForBlockWithContext but
+// restricts the diff to the given accounts. Parts of the trie not leading to
+// any of those accounts are skipped. See GetAccountDiffWithContext.
+func (a *ArchiveTrie) GetAccountDiffForBlockWithContext(ctx context.Context, block uint64, accounts []common.Address) (Diff, error) {
+	before, after, err := a.getRootsOfBlock(block)
+	if err != nil {
+		return Diff{}, err
+	}
+	return GetAccountDiffWithContext(ctx, a.nodeSource, &before, &after, accounts)
+}
+
+// getRootsOfBlock obtains the roots of the tries before and after the given
+// block. The trie before block 0 is the empty trie.
+func (a *ArchiveTrie) getRootsOfBlock(block uint64) (before, after NodeReference, err error) {
+	a.rootsMutex.Lock()
+	defer a.rootsMutex.Unlock()
+	if block >= uint64(len(a.roots)) {
 		if len(a.roots) == 0 {
-			a.rootsMutex.Unlock()
-			return Diff{}, fmt.Errorf("archive is empty, no diff present for block 0")
+			return before, after, fmt.Errorf("archive is empty, no diff present for block %d", block)
 		}
-		after := a.roots[0].NodeRef
-		a.rootsMutex.Unlock()
-		return GetDiff(a.nodeSource, &emptyNodeReference, &after)
+		return before, after, fmt.Errorf("block %d not present in archive, highest block is %d", block, len(a.roots)-1)
+	}
+	before = emptyNodeReference
+	if block > 0 {
+		before = a.roots[block-1].NodeRef
+	}
+	return before, a.roots[block].NodeRef, nil
+}
+
+// GetUpdateForBlock reconstructs an update equivalent to the one applied by
+// the given block from the diff of the block. Deleted accounts are reported
+// as deleted and accounts not present before the block as created. Since the
+// archive only retains the state at the end of each block, accounts deleted
+// and re-created within the block can not be distinguished from modified
+// accounts. They are thus reported by their modified fields only, with all
+// storage slots cleared by the deletion being explicitly set to zero. The
+// resulting update leads to the same state, yet it lacks the deletion of the
+// account. The resulting update is normalized.
+func (a *ArchiveTrie) GetUpdateForBlock(block uint64) (common.Update, error) {
+	diff, err := a.GetDiffForBlock(block)
+	if err != nil {
+		return common.Update{}, err
+	}
+	update := common.Update{}
+	for _, account := range sortedAccounts(diff) {
+		accountDiff := diff[account]
+		if accountDiff.Reset {
+			update.AppendDeleteAccount(account)
+			continue
+		}
+		existed := false
+		if block > 0 {
+			existed, err = a.Exists(block-1, account)
+			if err != nil {
+				return common.Update{}, err
+			}
+		}
+		if !existed {
+			update.AppendCreateAccount(account)
+		}
+		if accountDiff.Balance != nil {
+			update.AppendBalanceUpdate(account, *accountDiff.Balance)
+		}
+		if accountDiff.Nonce != nil {
+			update.AppendNonceUpdate(account, *accountDiff.Nonce)
+		}
+		// New accounts are reported with the hash of the empty code.
+		if accountDiff.Code != nil && (existed || *accountDiff.Code != emptyCodeHash) {
+			update.AppendCodeUpdate(account, a.head.GetCodeForHash(*accountDiff.Code))
+		}
+		for key, value := range accountDiff.Storage {
+			update.AppendSlotUpdate(account, key, value)
+		}
+	}
+	if err := update.Normalize(); err != nil {
+		return common.Update{}, err
+	}
+	return update, nil
+}
+
+// GetUpdates reconstructs the updates of the blocks in the range [from, to]
+// covered by the archive using GetUpdateForBlock. Deleted accounts are
+// complemented by resets of their balance, nonce, and code, such that
+// archives not clearing the fields of deleted accounts implicitly, like the
+// flat LevelDB and SQLite archives, end up in the same state. It implements
+// the archive.HistorySource interface.
+func (a *ArchiveTrie) GetUpdates(from, to uint64) ([]archive.BlockUpdate, error) {
+	height, empty, err := a.GetBlockHeight()
+	if err != nil || empty || from > height {
+		return nil, err
+	}
+	if to > height {
+		to = height
+	}
+	res := make([]archive.BlockUpdate, 0, to-from+1)
+	for block := from; ; block++ {
+		update, err := a.GetUpdateForBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		// Deleted accounts are reported without any further field updates.
+		for _, account := range update.DeletedAccounts {
+			update.AppendBalanceUpdate(account, common.Balance{})
+			update.AppendNonceUpdate(account, common.Nonce{})
+			update.AppendCodeUpdate(account, []byte{})
+		}
+		if err := update.Normalize(); err != nil {
+			return nil, err
+		}
+		res = append(res, archive.BlockUpdate{Block: block, Update: update})
+		if block == to {
+			break
+		}
+	}
+	return res, nil
+}
+
+// AccountState summarizes the fields of an account at some block.
+type AccountState struct {
+	Exists bool
+	Info   AccountInfo
+}
+
+// AccountChange describes the modification of an account's fields in a block.
+type AccountChange struct {
+	Block  uint64
+	Before AccountState // < the state at the end of the preceding block
+	After  AccountState // < the state at the end of the given block
+}
+
+// SlotChange describes the modification of a storage slot in a block.
+type SlotChange struct {
+	Block  uint64
+	Before common.Value // < the value at the end of the preceding block
+	After  common.Value // < the value at the end of the given block
+}
+
+// GetAccountHistory lists the blocks in the range [from, to] in which the
+// existence, balance, nonce, or code of the given account changed, including
+// the old and new values of the account. Changes in block 0 are relative to
+// an empty state. The range must be covered by the archive.
+func (a *ArchiveTrie) GetAccountHistory(account common.Address, from, to uint64) ([]AccountChange, error) {
+	blocks, err := a.getModifyingBlocks(account, from, to)
+	if err != nil {
+		return nil, err
+	}
+	res := []AccountChange{}
+	for _, block := range blocks {
+		var before, after AccountState
+		if block > 0 {
+			if before, err = a.getAccountState(block-1, account); err != nil {
+				return nil, err
+			}
+		}
+		if after, err = a.getAccountState(block, account); err != nil {
+			return nil, err
+		}
+		if before != after {
+			res = append(res, AccountChange{Block: block, Before: before, After: after})
+		}
+	}
+	return res, nil
+}
+
+// GetSlotHistory lists the blocks in the range [from, to] in which the value
+// of the given storage slot changed, including the old and new values. Changes
+// in block 0 are relative to an empty state. The range must be covered by the
+// archive.
+func (a *ArchiveTrie) GetSlotHistory(account common.Address, slot common.Key, from, to uint64) ([]SlotChange, error) {
+	blocks, err := a.getModifyingBlocks(account, from, to)
+	if err != nil {
+		return nil, err
+	}
+	res := []SlotChange{}
+	for _, block := range blocks {
+		var before, after common.Value
+		if block > 0 {
+			if before, err = a.GetStorage(block-1, account, slot); err != nil {
+				return nil, err
+			}
+		}
+		if after, err = a.GetStorage(block, account, slot); err != nil {
+			return nil, err
+		}
+		if before != after {
+			res = append(res, SlotChange{Block: block, Before: before, After: after})
+		}
+	}
+	return res, nil
+}
+
+// getModifyingBlocks lists the blocks in the range [from, to] in which the
+// given account or its storage may have been modified.
+func (a *ArchiveTrie) getModifyingBlocks(account common.Address, from, to uint64) ([]uint64, error) {
+	if err := a.CheckErrors(); err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", from, to)
 	}
-	return a.GetDiff(block-1, block)
+	a.addMutex.Lock()
+	indexed := a.indexed
+	a.addMutex.Unlock()
+	if !indexed {
+		return nil, fmt.Errorf("change index of archive is incomplete, it needs to be rebuilt using the rebuild-change-index command of the mpt tool")
+	}
+	a.rootsMutex.Lock()
+	length := uint64(len(a.roots))
+	a.rootsMutex.Unlock()
+	if to >= length {
+		return nil, fmt.Errorf("block %d not present in archive, block height is %d", to, length)
+	}
+	return a.changes.getBlocks(account, from, to)
+}
+
+func (a *ArchiveTrie) getAccountState(block uint64, account common.Address) (AccountState, error) {
+	view, err := a.getView(block)
+	if err != nil {
+		return AccountState{}, err
+	}
+	info, exists, err := view.GetAccountInfo(account)
+	if err != nil {
+		return AccountState{}, a.addError(err)
+	}
+	return AccountState{Exists: exists, Info: info}, nil
+}
+
+// VisitTrieWithContext is like VisitTrie but aborts the visit with the
+// context's error once the given context is done.
+func (a *ArchiveTrie) VisitTrieWithContext(ctx context.Context, block uint64, visitor NodeVisitor) error {
+	return visitWithContext(ctx, visitor, func(visitor NodeVisitor) error {
+		return a.VisitTrie(block, visitor)
+	})
+}
+
+// VisitTrie runs the given visitor on all nodes of the trie of the given block.
+func (a *ArchiveTrie) VisitTrie(block uint64, visitor NodeVisitor) error {
+	view, err := a.getView(block)
+	if err != nil {
+		return err
+	}
+	return a.addError(view.VisitTrie(visitor))
 }
 
 func (a *ArchiveTrie) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(unsafe.Sizeof(*a))
 	mf.AddChild("head", a.head.GetMemoryFootprint())
+	mf.AddChild("changes", a.changes.GetMemoryFootprint())
 	a.rootsMutex.Lock()
 	mf.AddChild("roots", common.NewMemoryFootprint(uintptr(len(a.roots))*unsafe.Sizeof(NodeId(0))))
+	mf.AddChild("stats", common.NewMemoryFootprint(uintptr(len(a.stats))*unsafe.Sizeof(StateStats{})))
 	a.rootsMutex.Unlock()
 	return mf
 }
 
+// GetDiskFootprint provides sizes of individual components of the archive on
+// disk. The forest shared by all blocks is reported as part of the head state.
+func (a *ArchiveTrie) GetDiskFootprint() (*common.DiskFootprint, error) {
+	df := common.NewDiskFootprint(0)
+	if provider, ok := a.head.(common.DiskFootprintProvider); ok {
+		head, err := provider.GetDiskFootprint()
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild("head", head)
+	}
+	files := []struct {
+		name string
+		path string
+	}{
+		{"roots", a.rootFile},
+		{"stats", a.statsFile},
+	}
+	for _, file := range files {
+		child, err := common.GetFileDiskFootprint(file.path)
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild(file.name, child)
+	}
+	changes, err := common.GetDirectoryDiskFootprint(a.changes.directory)
+	if err != nil {
+		return nil, err
+	}
+	df.AddChild("changes", changes)
+	return df, nil
+}
+
+// CountDirtyNodes returns the number of modified nodes of this archive that
+// have not been written to disk yet. See Forest.CountDirtyNodes.
+func (a *ArchiveTrie) CountDirtyNodes() int {
+	return countDirtyNodes(a.forest)
+}
+
+// GetCacheStatistics summarizes the usage of the node cache of this archive.
+func (a *ArchiveTrie) GetCacheStatistics() common.CacheStatistics {
+	return getCacheStatistics(a.forest)
+}
+
+// SetCacheCapacity changes the number of nodes retained in the node cache of
+// this archive. The capacity is at least MinMptStateCapacity.
+func (a *ArchiveTrie) SetCacheCapacity(capacity int) {
+	setCacheCapacity(a.forest, capacity)
+}
+
 func (a *ArchiveTrie) Check() error {
 	roots := make([]*NodeReference, len(a.roots))
 	for i := 0; i < len(a.roots); i++ {
@@ -302,19 +792,43 @@ func (a *ArchiveTrie) Dump() {
 }
 
 func (a *ArchiveTrie) Flush() error {
+	if a.statsWorker != nil {
+		a.statsWorker.sync()
+	}
 	a.rootsMutex.Lock()
 	defer a.rootsMutex.Unlock()
+	// The statistics are stored before the roots, such that the stored
+	// statistics cover at least all stored roots. Roots of blocks whose
+	// statistics are not yet complete are not stored.
+	roots := a.roots
+	var statsErr error
+	if a.stats != nil {
+		if len(a.stats) < len(roots) {
+			roots = roots[:len(a.stats)]
+		}
+		statsErr = storeStateStats(a.statsFile, a.stats)
+	}
+	if statsErr != nil {
+		return errors.Join(a.CheckErrors(), statsErr)
+	}
 	return errors.Join(
 		a.CheckErrors(),
 		a.head.Flush(),
-		StoreRoots(a.rootFile, a.roots),
+		a.changes.Flush(),
+		StoreRoots(a.rootFile, roots),
 	)
 }
 
 func (a *ArchiveTrie) Close() error {
+	err := a.CheckErrors()
+	flushErr := a.Flush()
+	if a.statsWorker != nil {
+		a.statsWorker.close()
+	}
 	return errors.Join(
-		a.CheckErrors(),
-		a.head.closeWithError(a.Flush()))
+		err,
+		a.head.closeWithError(flushErr),
+		a.changes.Close())
 }
 
 func (a *ArchiveTrie) getView(block uint64) (*LiveTrie, error) {
@@ -394,15 +908,29 @@ func loadRootsFrom(reader io.Reader) ([]Root, error) {
 }
 
 func StoreRoots(filename string, roots []Root) error {
-	f, err := os.Create(filename)
+	return writeFileAtomically(filename, func(writer io.Writer) error {
+		return storeRootsTo(writer, roots)
+	})
+}
+
+// writeFileAtomically replaces the content of the given file by the content
+// produced by the given write function. The content is written to a temporary
+// file first, such that a crash while writing does not leave a truncated file
+// behind.
+func writeFileAtomically(filename string, write func(io.Writer) error) error {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 	if err != nil {
 		return err
 	}
 	writer := bufio.NewWriter(f)
-	return errors.Join(
-		storeRootsTo(writer, roots),
-		writer.Flush(),
-		f.Close())
+	if err := errors.Join(write(writer), writer.Flush(), f.Sync()); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
 }
 
 func storeRootsTo(writer io.Writer, roots []Root) error {
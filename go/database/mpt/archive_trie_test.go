@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -598,6 +599,7 @@ func TestArchiveTrie_Add_LiveStateFailsCreateAccount(t *testing.T) {
 			var injectedError = errors.New("injectedError")
 			ctrl := gomock.NewController(t)
 			live := NewMockLiveState(ctrl)
+			live.EXPECT().Root().Return(NewNodeReference(EmptyId())).AnyTimes()
 			live.EXPECT().CreateAccount(gomock.Any()).Return(injectedError)
 			archive.head = live
 
@@ -1457,7 +1459,9 @@ func TestArchiveTrie_FailingLiveStateUpdate_InvalidatesArchive(t *testing.T) {
 			liveState := NewMockLiveState(ctrl)
 			liveState.EXPECT().Flush().AnyTimes()
 			liveState.EXPECT().closeWithError(gomock.Any())
-			liveState.EXPECT().Root().AnyTimes()
+			// the state statistics tracker reads code sizes and storage tries
+			liveState.EXPECT().Root().Return(NewNodeReference(EmptyId())).AnyTimes()
+			liveState.EXPECT().GetCodeSize(gomock.Any()).AnyTimes()
 
 			db := NewMockDatabase(ctrl)
 			db.EXPECT().Freeze(gomock.Any()).AnyTimes()
@@ -1538,3 +1542,293 @@ var archiveGetters = map[string]func(archive archive.Archive) error{
 		return err
 	},
 }
+
+func TestArchiveTrie_AccountHistoryListsChangedBlocks(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open empty archive: %v", err)
+	}
+	defer archive.Close()
+
+	addr := common.Address{1}
+	other := common.Address{2}
+	updates := map[uint64]common.Update{
+		1: {
+			CreatedAccounts: []common.Address{addr},
+			Balances:        []common.BalanceUpdate{{Account: addr, Balance: common.Balance{1}}},
+		},
+		2: {Balances: []common.BalanceUpdate{{Account: other, Balance: common.Balance{1}}}},
+		// The balance is touched without being modified.
+		3: {Balances: []common.BalanceUpdate{{Account: addr, Balance: common.Balance{1}}}},
+		5: {Nonces: []common.NonceUpdate{{Account: addr, Nonce: common.ToNonce(1)}}},
+		6: {Slots: []common.SlotUpdate{{Account: addr, Key: common.Key{1}, Value: common.Value{1}}}},
+		7: {DeletedAccounts: []common.Address{addr}},
+	}
+	for block := uint64(0); block <= 7; block++ {
+		if update, found := updates[block]; found {
+			if err := archive.Add(block, update, nil); err != nil {
+				t.Fatalf("failed to add block %d: %v", block, err)
+			}
+		}
+	}
+
+	changes, err := archive.GetAccountHistory(addr, 0, 7)
+	if err != nil {
+		t.Fatalf("failed to get account history: %v", err)
+	}
+	created := AccountState{Exists: true, Info: AccountInfo{Balance: common.Balance{1}, CodeHash: emptyCodeHash}}
+	withNonce := AccountState{Exists: true, Info: AccountInfo{Balance: common.Balance{1}, Nonce: common.ToNonce(1), CodeHash: emptyCodeHash}}
+	want := []AccountChange{
+		{Block: 1, Before: AccountState{}, After: created},
+		{Block: 5, Before: created, After: withNonce},
+		{Block: 7, Before: withNonce, After: AccountState{}},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("unexpected account history\nwanted %v\n   got %v", want, changes)
+	}
+
+	changes, err = archive.GetAccountHistory(addr, 2, 6)
+	if err != nil {
+		t.Fatalf("failed to get account history: %v", err)
+	}
+	if !slices.Equal(changes, want[1:2]) {
+		t.Errorf("unexpected account history\nwanted %v\n   got %v", want[1:2], changes)
+	}
+
+	slotChanges, err := archive.GetSlotHistory(addr, common.Key{1}, 0, 7)
+	if err != nil {
+		t.Fatalf("failed to get slot history: %v", err)
+	}
+	wantSlots := []SlotChange{
+		{Block: 6, Before: common.Value{}, After: common.Value{1}},
+		{Block: 7, Before: common.Value{1}, After: common.Value{}},
+	}
+	if !slices.Equal(slotChanges, wantSlots) {
+		t.Errorf("unexpected slot history\nwanted %v\n   got %v", wantSlots, slotChanges)
+	}
+}
+
+func TestArchiveTrie_HistoryQueriesOutsideOfArchiveFail(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open empty archive: %v", err)
+	}
+	defer archive.Close()
+	if err := archive.Add(0, common.Update{}, nil); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if _, err := archive.GetAccountHistory(common.Address{}, 0, 1); err == nil {
+		t.Errorf("querying blocks beyond the archive height should fail")
+	}
+	if _, err := archive.GetSlotHistory(common.Address{}, common.Key{}, 1, 0); err == nil {
+		t.Errorf("querying an invalid range should fail")
+	}
+}
+
+func TestArchiveTrie_ChangeIndexCanBeRebuilt(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open empty archive: %v", err)
+	}
+	addr := common.Address{1}
+	for block := uint64(0); block < 5; block++ {
+		if err := archive.Add(block, common.Update{
+			CreatedAccounts: []common.Address{addr},
+			Balances:        []common.BalanceUpdate{{Account: addr, Balance: common.Balance{byte(block / 2)}}},
+		}, nil); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	want, err := archive.GetAccountHistory(addr, 0, 4)
+	if err != nil {
+		t.Fatalf("failed to get account history: %v", err)
+	}
+	if len(want) != 3 {
+		t.Errorf("unexpected number of changes, wanted 3, got %d", len(want))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	// Without an index, history queries fail.
+	if err := os.RemoveAll(filepath.Join(dir, changeIndexDirectoryName)); err != nil {
+		t.Fatalf("failed to remove index: %v", err)
+	}
+	archive, err = OpenArchiveTrie(dir, S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to reopen archive: %v", err)
+	}
+	if _, err := archive.GetAccountHistory(addr, 0, 4); err == nil {
+		t.Errorf("history query without index should fail")
+	}
+	if err := archive.Add(5, common.Update{}, nil); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	if err := RebuildChangeIndex(dir, S5ArchiveConfig); err != nil {
+		t.Fatalf("failed to rebuild index: %v", err)
+	}
+	archive, err = OpenArchiveTrie(dir, S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to reopen archive: %v", err)
+	}
+	defer archive.Close()
+	got, err := archive.GetAccountHistory(addr, 0, 5)
+	if err != nil {
+		t.Fatalf("failed to get account history: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected history after rebuild\nwanted %v\n   got %v", want, got)
+	}
+}
+
+func TestArchiveTrie_GetUpdateForBlockReconstructsAppliedUpdates(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open empty archive: %v", err)
+	}
+	defer archive.Close()
+
+	addr := common.Address{1}
+	other := common.Address{2}
+	updates := []common.Update{
+		{
+			CreatedAccounts: []common.Address{other},
+			Nonces:          []common.NonceUpdate{{Account: other, Nonce: common.ToNonce(1)}},
+		},
+		{
+			CreatedAccounts: []common.Address{addr},
+			Balances:        []common.BalanceUpdate{{Account: addr, Balance: common.Balance{1}}},
+			Codes:           []common.CodeUpdate{{Account: addr, Code: []byte{1, 2, 3}}},
+			Slots:           []common.SlotUpdate{{Account: addr, Key: common.Key{1}, Value: common.Value{1}}},
+		},
+		{
+			Nonces: []common.NonceUpdate{{Account: addr, Nonce: common.ToNonce(2)}},
+			Slots: []common.SlotUpdate{
+				{Account: addr, Key: common.Key{1}, Value: common.Value{2}},
+				{Account: addr, Key: common.Key{2}, Value: common.Value{3}},
+			},
+		},
+		{DeletedAccounts: []common.Address{addr}},
+	}
+	for block, update := range updates {
+		if err := archive.Add(uint64(block), update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+
+	for block, want := range updates {
+		got, err := archive.GetUpdateForBlock(uint64(block))
+		if err != nil {
+			t.Fatalf("failed to get update of block %d: %v", block, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("unexpected update for block %d\nwanted %v\n   got %v", block, want, got)
+		}
+	}
+
+	if _, err := archive.GetUpdateForBlock(uint64(len(updates))); err == nil {
+		t.Errorf("getting the update of a block beyond the archive height should fail")
+	}
+}
+
+func TestArchiveTrie_GetUpdateForBlockReportsStorageClearOfRecreatedAccounts(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open empty archive: %v", err)
+	}
+	defer archive.Close()
+
+	addr := common.Address{1}
+	updates := []common.Update{
+		{
+			CreatedAccounts: []common.Address{addr},
+			Nonces:          []common.NonceUpdate{{Account: addr, Nonce: common.ToNonce(1)}},
+			Slots: []common.SlotUpdate{
+				{Account: addr, Key: common.Key{1}, Value: common.Value{1}},
+				{Account: addr, Key: common.Key{2}, Value: common.Value{2}},
+			},
+		},
+		{
+			DeletedAccounts: []common.Address{addr},
+			CreatedAccounts: []common.Address{addr},
+			Nonces:          []common.NonceUpdate{{Account: addr, Nonce: common.ToNonce(1)}},
+			Slots:           []common.SlotUpdate{{Account: addr, Key: common.Key{2}, Value: common.Value{3}}},
+		},
+	}
+	for block, update := range updates {
+		if err := archive.Add(uint64(block), update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+
+	// The deletion can not be reconstructed, the cleared slot is reset instead.
+	want := common.Update{
+		Slots: []common.SlotUpdate{
+			{Account: addr, Key: common.Key{1}, Value: common.Value{}},
+			{Account: addr, Key: common.Key{2}, Value: common.Value{3}},
+		},
+	}
+	got, err := archive.GetUpdateForBlock(1)
+	if err != nil {
+		t.Fatalf("failed to get update of block 1: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected update\nwanted %v\n   got %v", want, got)
+	}
+
+	// Applying the reconstructed updates leads to the same state.
+	replica, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open empty archive: %v", err)
+	}
+	defer replica.Close()
+	for block := range updates {
+		update, err := archive.GetUpdateForBlock(uint64(block))
+		if err != nil {
+			t.Fatalf("failed to get update of block %d: %v", block, err)
+		}
+		if err := replica.Add(uint64(block), update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+	wantHash, err := archive.GetHash(1)
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+	if gotHash, err := replica.GetHash(1); err != nil || gotHash != wantHash {
+		t.Errorf("unexpected hash of replicated state, wanted %x, got %x, err %v", wantHash, gotHash, err)
+	}
+}
+
+func TestArchiveTrie_GetDiskFootprint_CoversHeadRootsAndChanges(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	if err := archive.Add(1, common.Update{
+		CreatedAccounts: []common.Address{{1}},
+		Nonces:          []common.NonceUpdate{{Account: common.Address{1}, Nonce: common.ToNonce(1)}},
+	}, nil); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := archive.Flush(); err != nil {
+		t.Fatalf("failed to flush archive: %v", err)
+	}
+
+	footprint, err := archive.GetDiskFootprint()
+	if err != nil {
+		t.Fatalf("failed to get disk footprint: %v", err)
+	}
+	for _, name := range []string{"head", "roots", "changes"} {
+		if footprint.GetChild(name).Total() == 0 {
+			t.Errorf("no disk usage reported for %s:\n%v", name, footprint)
+		}
+	}
+}
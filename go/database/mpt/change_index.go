@@ -0,0 +1,211 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/backend"
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// changeIndex records for each account the blocks in which the account or its
+// storage was modified. It is maintained by the ArchiveTrie and enables the
+// efficient lookup of the history of individual accounts and storage slots.
+//
+// The index is persisted in a LevelDB instance holding one empty entry per
+// modified account and block, keyed by the address followed by the block
+// number. Thus, the blocks modifying an account are listed by a range scan
+// and only the queried part of the index is loaded into memory. Blocks added
+// since the last flush are kept in a pending batch and an in-memory overlay
+// until they are written by the next flush.
+type changeIndex struct {
+	db            *backend.LevelDbMemoryFootprintWrapper
+	directory     string
+	numBlocks     uint64                      // < the number of blocks covered by the index
+	pending       backend.KVBatch             // < entries not yet written to the database
+	pendingBlocks map[common.Address][]uint64 // < blocks of pending entries per account
+	mutex         sync.Mutex
+}
+
+// changeIndexDirectoryName is the name of the directory storing the change
+// index of an archive within the archive's directory.
+const changeIndexDirectoryName = "changes"
+
+const (
+	changeIndexNumBlocksKey   = byte(0) // < key of the number of covered blocks
+	changeIndexEntryKeyPrefix = byte(1) // < prefix of the keys of account/block entries
+)
+
+// openChangeIndex opens the change index stored in the given directory. If
+// the directory does not exist, an empty index is created.
+func openChangeIndex(directory string) (*changeIndex, error) {
+	db, err := backend.OpenLevelDb(directory, nil)
+	if err != nil {
+		return nil, err
+	}
+	var numBlocks uint64
+	value, err := db.Get([]byte{changeIndexNumBlocksKey})
+	if err == nil {
+		if len(value) != 8 {
+			return nil, errors.Join(
+				fmt.Errorf("invalid change index format: invalid number of blocks entry of length %d", len(value)),
+				db.Close(),
+			)
+		}
+		numBlocks = binary.BigEndian.Uint64(value)
+	} else if !errors.Is(err, backend.ErrNotFound) {
+		return nil, errors.Join(err, db.Close())
+	}
+	return &changeIndex{
+		db:            db,
+		directory:     directory,
+		numBlocks:     numBlocks,
+		pending:       db.NewBatch(),
+		pendingBlocks: map[common.Address][]uint64{},
+	}, nil
+}
+
+func getChangeIndexEntryKey(account common.Address, block uint64) []byte {
+	key := make([]byte, 0, 1+len(account)+8)
+	key = append(key, changeIndexEntryKeyPrefix)
+	key = append(key, account[:]...)
+	return binary.BigEndian.AppendUint64(key, block)
+}
+
+// add records the given accounts as being modified in the given block. Blocks
+// need to be added in increasing order.
+func (i *changeIndex) add(block uint64, accounts []common.Address) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if block < i.numBlocks {
+		return fmt.Errorf("block %d already covered by change index", block)
+	}
+	for _, account := range accounts {
+		i.pending.Put(getChangeIndexEntryKey(account, block), nil)
+		i.pendingBlocks[account] = append(i.pendingBlocks[account], block)
+	}
+	i.numBlocks = block + 1
+	return nil
+}
+
+// getNumBlocks returns the number of blocks covered by this index.
+func (i *changeIndex) getNumBlocks() uint64 {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.numBlocks
+}
+
+// getBlocks returns the blocks in the range [from, to] in which the given
+// account was modified.
+func (i *changeIndex) getBlocks(account common.Address, from, to uint64) ([]uint64, error) {
+	if from > to {
+		return nil, nil
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	keys := &backend.KVRange{Start: getChangeIndexEntryKey(account, from)}
+	if to < math.MaxUint64 {
+		keys.Limit = getChangeIndexEntryKey(account, to+1)
+	} else {
+		keys.Limit = backend.KVPrefix(append([]byte{changeIndexEntryKeyPrefix}, account[:]...)).Limit
+	}
+	var res []uint64
+	iter := i.db.NewIterator(keys)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		res = append(res, binary.BigEndian.Uint64(key[len(key)-8:]))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	// Pending blocks are newer than all blocks written to the database.
+	blocks := i.pendingBlocks[account]
+	begin := sort.Search(len(blocks), func(j int) bool { return blocks[j] >= from })
+	end := sort.Search(len(blocks), func(j int) bool { return blocks[j] > to })
+	if begin < end {
+		res = append(res, blocks[begin:end]...)
+	}
+	return res, nil
+}
+
+func (i *changeIndex) Flush() error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.pending.Put([]byte{changeIndexNumBlocksKey}, binary.BigEndian.AppendUint64(nil, i.numBlocks))
+	if err := i.db.Write(i.pending); err != nil {
+		return err
+	}
+	i.pending.Reset()
+	i.pendingBlocks = map[common.Address][]uint64{}
+	return nil
+}
+
+func (i *changeIndex) Close() error {
+	return errors.Join(i.Flush(), i.db.Close())
+}
+
+func (i *changeIndex) GetMemoryFootprint() *common.MemoryFootprint {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	size := unsafe.Sizeof(*i)
+	for _, blocks := range i.pendingBlocks {
+		size += unsafe.Sizeof(common.Address{}) + unsafe.Sizeof(blocks) + uintptr(cap(blocks))*unsafe.Sizeof(uint64(0))
+	}
+	mf := common.NewMemoryFootprint(size)
+	mf.AddChild("db", i.db.GetMemoryFootprint())
+	return mf
+}
+
+// getModifiedAccounts returns the sorted list of accounts touched by the
+// given update.
+func getModifiedAccounts(update *common.Update) []common.Address {
+	set := map[common.Address]struct{}{}
+	for _, account := range update.DeletedAccounts {
+		set[account] = struct{}{}
+	}
+	for _, account := range update.CreatedAccounts {
+		set[account] = struct{}{}
+	}
+	for _, change := range update.Balances {
+		set[change.Account] = struct{}{}
+	}
+	for _, change := range update.Nonces {
+		set[change.Account] = struct{}{}
+	}
+	for _, change := range update.Codes {
+		set[change.Account] = struct{}{}
+	}
+	for _, change := range update.Slots {
+		set[change.Account] = struct{}{}
+	}
+	return sortedAccounts(set)
+}
+
+func sortedAccounts[V any](accounts map[common.Address]V) []common.Address {
+	res := make([]common.Address, 0, len(accounts))
+	for account := range accounts {
+		res = append(res, account)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Compare(&res[j]) < 0
+	})
+	return res
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func TestChangeIndex_MissingDirectoryProducesEmptyIndex(t *testing.T) {
+	index, err := openChangeIndex(filepath.Join(t.TempDir(), changeIndexDirectoryName))
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	defer index.Close()
+	if got := index.getNumBlocks(); got != 0 {
+		t.Errorf("unexpected number of blocks, wanted 0, got %d", got)
+	}
+	if got, err := index.getBlocks(common.Address{1}, 0, 100); err != nil || len(got) != 0 {
+		t.Errorf("unexpected blocks %v, err %v", got, err)
+	}
+}
+
+func TestChangeIndex_BlocksCanBeQueriedByRange(t *testing.T) {
+	index, err := openChangeIndex(filepath.Join(t.TempDir(), changeIndexDirectoryName))
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	defer index.Close()
+	addr1 := common.Address{1}
+	addr2 := common.Address{2}
+	for _, block := range []uint64{1, 3, 5, 7} {
+		if err := index.add(block, []common.Address{addr1}); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+		// Blocks 1 and 3 are written to the database, 5 and 7 remain pending.
+		if block == 3 {
+			if err := index.Flush(); err != nil {
+				t.Fatalf("failed to flush index: %v", err)
+			}
+		}
+	}
+	if err := index.add(8, []common.Address{addr2}); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	tests := []struct {
+		from, to uint64
+		want     []uint64
+	}{
+		{0, 10, []uint64{1, 3, 5, 7}},
+		{3, 5, []uint64{3, 5}},
+		{2, 4, []uint64{3}},
+		{4, 4, nil},
+		{8, 10, nil},
+		{10, 8, nil},
+		{2, math.MaxUint64, []uint64{3, 5, 7}},
+	}
+	for _, test := range tests {
+		got, err := index.getBlocks(addr1, test.from, test.to)
+		if err != nil {
+			t.Fatalf("failed to get blocks: %v", err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("unexpected blocks in range [%d,%d], wanted %v, got %v", test.from, test.to, test.want, got)
+		}
+	}
+	if got, want := index.getNumBlocks(), uint64(9); got != want {
+		t.Errorf("unexpected number of blocks, wanted %d, got %d", want, got)
+	}
+	if err := index.add(8, nil); err == nil {
+		t.Errorf("adding a covered block should fail")
+	}
+}
+
+func TestChangeIndex_ContentIsPersistent(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), changeIndexDirectoryName)
+	index, err := openChangeIndex(directory)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	addr := common.Address{1}
+	for block := uint64(0); block < 4; block++ {
+		accounts := []common.Address{}
+		if block%2 == 0 {
+			accounts = append(accounts, addr)
+		}
+		if err := index.add(block, accounts); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+		// Flushing in between should add entries.
+		if err := index.Flush(); err != nil {
+			t.Fatalf("failed to flush index: %v", err)
+		}
+	}
+	if err := index.Close(); err != nil {
+		t.Fatalf("failed to close index: %v", err)
+	}
+
+	restored, err := openChangeIndex(directory)
+	if err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	defer restored.Close()
+	if got, want := restored.getNumBlocks(), uint64(4); got != want {
+		t.Errorf("unexpected number of blocks, wanted %d, got %d", want, got)
+	}
+	if got, err := restored.getBlocks(addr, 0, 10); err != nil || !slices.Equal(got, []uint64{0, 2}) {
+		t.Errorf("unexpected blocks, wanted %v, got %v, err %v", []uint64{0, 2}, got, err)
+	}
+}
+
+func TestChangeIndex_CloseWritesPendingBlocks(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), changeIndexDirectoryName)
+	index, err := openChangeIndex(directory)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	addr := common.Address{1}
+	if err := index.add(2, []common.Address{addr}); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatalf("failed to close index: %v", err)
+	}
+
+	restored, err := openChangeIndex(directory)
+	if err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	defer restored.Close()
+	if got, want := restored.getNumBlocks(), uint64(3); got != want {
+		t.Errorf("unexpected number of blocks, wanted %d, got %d", want, got)
+	}
+	if got, err := restored.getBlocks(addr, 0, 10); err != nil || !slices.Equal(got, []uint64{2}) {
+		t.Errorf("unexpected blocks, wanted %v, got %v, err %v", []uint64{2}, got, err)
+	}
+}
+
+func TestChangeIndex_CorruptedDirectoryIsDetected(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), changeIndexDirectoryName)
+	if err := os.WriteFile(directory, []byte("Hello, World!"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := openChangeIndex(directory); err == nil {
+		t.Errorf("opening corrupted index should fail")
+	}
+}
+
+func TestGetModifiedAccounts_CoversAllAccountsOfUpdate(t *testing.T) {
+	update := common.Update{
+		DeletedAccounts: []common.Address{{5}},
+		CreatedAccounts: []common.Address{{4}},
+		Balances:        []common.BalanceUpdate{{Account: common.Address{3}}},
+		Nonces:          []common.NonceUpdate{{Account: common.Address{2}}},
+		Codes:           []common.CodeUpdate{{Account: common.Address{1}}},
+		Slots:           []common.SlotUpdate{{Account: common.Address{1}}, {Account: common.Address{6}}},
+	}
+	want := []common.Address{{1}, {2}, {3}, {4}, {5}, {6}}
+	if got := getModifiedAccounts(&update); !slices.Equal(got, want) {
+		t.Errorf("unexpected accounts, wanted %v, got %v", want, got)
+	}
+}
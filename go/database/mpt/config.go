@@ -35,6 +35,12 @@ type MptConfig struct {
 
 	// Determines whether hashes are stored with nodes or with the parents.
 	HashStorageLocation HashStorageLocation
+
+	// If enabled, new node stocks are created with a checksum stored for
+	// each node, such that nodes corrupted on disk are detected when being
+	// loaded or scrubbed. Existing stocks retain their layout, independent
+	// of this option.
+	UseStockChecksums bool
 }
 
 var S4LiveConfig = MptConfig{
@@ -11,6 +11,7 @@
 package mpt
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sort"
@@ -82,8 +83,20 @@ func GetDiff(
 	source NodeSource,
 	before *NodeReference,
 	after *NodeReference,
+) (Diff, error) {
+	return GetDiffWithContext(context.Background(), source, before, after)
+}
+
+// GetDiffWithContext is like GetDiff but aborts the diff computation with the
+// context's error once the given context is done.
+func GetDiffWithContext(
+	ctx context.Context,
+	source NodeSource,
+	before *NodeReference,
+	after *NodeReference,
 ) (Diff, error) {
 	context := &diffContext{
+		ctx:    ctx,
 		source: source,
 		result: Diff{},
 	}
@@ -98,12 +111,57 @@ func GetDiff(
 	return context.result, nil
 }
 
+// GetAccountDiffWithContext is like GetDiffWithContext but restricts the
+// resulting diff to the given accounts. Sub-tries of the account trie not
+// leading to any of the given accounts are skipped, such that the costs of
+// the diff computation depend on the number of the given accounts instead of
+// the number of all modified accounts.
+func GetAccountDiffWithContext(
+	ctx context.Context,
+	source NodeSource,
+	before *NodeReference,
+	after *NodeReference,
+	accounts []common.Address,
+) (Diff, error) {
+	context := &diffContext{
+		ctx:    ctx,
+		source: source,
+		result: Diff{},
+	}
+
+	if before.Id() == after.Id() {
+		return context.result, nil
+	}
+
+	filter := make([][]Nibble, 0, len(accounts))
+	for _, account := range accounts {
+		filter = append(filter, AddressToNibblePath(account, source))
+	}
+	if err := collectDiff(context, triePosition{ref: *before, filter: filter}, triePosition{ref: *after, filter: filter}); err != nil {
+		return nil, err
+	}
+
+	// Accounts sharing a path prefix with one of the given accounts may be
+	// part of the result and are removed.
+	selected := make(map[common.Address]bool, len(accounts))
+	for _, account := range accounts {
+		selected[account] = true
+	}
+	for account := range context.result {
+		if !selected[account] {
+			delete(context.result, account)
+		}
+	}
+	return context.result, nil
+}
+
 // -----
 
 type triePosition struct {
 	ref    NodeReference
-	depth  int // the distance from the root node
-	offset int // number of Nibbles consumed of Extension Node paths
+	depth  int        // the distance from the root node
+	offset int        // number of Nibbles consumed of Extension Node paths
+	filter [][]Nibble // if not nil, only the sub-tries on these paths are considered
 }
 
 func (p *triePosition) id() NodeId {
@@ -177,6 +235,7 @@ func (p *triePosition) getChild(source NodeSource, nibble Nibble) (triePosition,
 // ------
 
 type diffContext struct {
+	ctx            context.Context
 	source         NodeSource
 	currentAccount *common.Address
 	result         Diff
@@ -193,11 +252,32 @@ func collectDiff(
 		return nil
 	}
 
+	if err := context.ctx.Err(); err != nil {
+		return err
+	}
+
 	if before.isLeaf() && after.isLeaf() {
 		return collectDiffFromLeafs(context, before, after)
 	}
 
 	for i := Nibble(0); i < Nibble(16); i++ {
+		var filter [][]Nibble
+		if before.filter != nil {
+			// Empty positions do not track their depth.
+			depth := before.depth
+			if before.id().IsEmpty() {
+				depth = after.depth
+			}
+			filter = make([][]Nibble, 0, len(before.filter))
+			for _, path := range before.filter {
+				if path[depth] == i {
+					filter = append(filter, path)
+				}
+			}
+			if len(filter) == 0 {
+				continue
+			}
+		}
 		lhs, err := before.getChild(context.source, i)
 		if err != nil {
 			return err
@@ -206,6 +286,7 @@ func collectDiff(
 		if err != nil {
 			return err
 		}
+		lhs.filter, rhs.filter = filter, filter
 		if err := collectDiff(context, lhs, rhs); err != nil {
 			return err
 		}
@@ -11,6 +11,7 @@
 package mpt
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -437,6 +438,78 @@ func TestDiff_DiffScenariosProduceCorrectDiff(t *testing.T) {
 	}
 }
 
+func TestDiff_AccountDiffsAreRestrictedToSelectedAccounts(t *testing.T) {
+	for name, test := range getDiffScenarios() {
+		if test.err != nil {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ctxt := newNodeContext(t, ctrl)
+
+			before, _ := ctxt.Build(test.before)
+			after, _ := ctxt.Build(test.after)
+
+			for account, accountDiff := range test.diff {
+				want := Diff{account: accountDiff}
+				got, err := GetAccountDiffWithContext(context.Background(), ctxt, &before, &after, []common.Address{account})
+				if err != nil {
+					t.Fatalf("failed to get account diff: %v", err)
+				}
+				if !got.Equal(want) {
+					t.Errorf("unexpected diff of account %x, wanted: %s\ngot: %s", account, want, got)
+				}
+			}
+
+			got, err := GetAccountDiffWithContext(context.Background(), ctxt, &before, &after, []common.Address{{0xAB}})
+			if err != nil {
+				t.Fatalf("failed to get account diff: %v", err)
+			}
+			if len(got) != 0 {
+				t.Errorf("diff of unmodified account should be empty, got %s", got)
+			}
+		})
+	}
+}
+
+func TestDiff_AccountDiffsSkipUnrelatedSubTries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctxt := newNodeContext(t, ctrl)
+
+	before, _ := ctxt.Build(Empty{})
+	after, _ := ctxt.Build(&Branch{
+		children: Children{
+			0x1: &Account{address: common.Address{0x10}, info: AccountInfo{Nonce: common.Nonce{1}}},
+			0x2: &Account{address: common.Address{0x20}, info: AccountInfo{Nonce: common.Nonce{1}}},
+			0x3: &Account{address: common.Address{0x30}, info: AccountInfo{Nonce: common.Nonce{1}}},
+		},
+	})
+
+	numReads := func(get func(NodeSource) (Diff, error)) int {
+		counter := NewMockNodeSource(ctrl)
+		reads := 0
+		counter.EXPECT().getReadAccess(gomock.Any()).AnyTimes().DoAndReturn(func(ref *NodeReference) (shared.ReadHandle[Node], error) {
+			reads++
+			return ctxt.getReadAccess(ref)
+		})
+		counter.EXPECT().getConfig().AnyTimes().Return(ctxt.getConfig())
+		if _, err := get(counter); err != nil {
+			t.Fatalf("failed to get diff: %v", err)
+		}
+		return reads
+	}
+
+	full := numReads(func(source NodeSource) (Diff, error) {
+		return GetDiff(source, &before, &after)
+	})
+	restricted := numReads(func(source NodeSource) (Diff, error) {
+		return GetAccountDiffWithContext(context.Background(), source, &before, &after, []common.Address{{0x20}})
+	})
+	if restricted >= full {
+		t.Errorf("restricted diff should read fewer nodes than the full diff, got %d and %d", restricted, full)
+	}
+}
+
 func TestDiff_ErrorsArePropagated(t *testing.T) {
 	for name, test := range getDiffScenarios() {
 		t.Run(name, func(t *testing.T) {
@@ -483,6 +556,28 @@ func TestDiff_ErrorsArePropagated(t *testing.T) {
 	}
 }
 
+func TestDiff_CancellationAbortsDiff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, test := range getDiffScenarios() {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ctxt := newNodeContext(t, ctrl)
+
+			before, _ := ctxt.Build(test.before)
+			after, _ := ctxt.Build(test.after)
+			if before.Id() == after.Id() {
+				return
+			}
+
+			_, err := GetDiffWithContext(ctx, ctxt, &before, &after)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+			}
+		})
+	}
+}
+
 func TestDiff_DiffsCanBePrinted(t *testing.T) {
 	for name, test := range getDiffScenarios() {
 		t.Run(name, func(t *testing.T) {
@@ -17,6 +17,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -78,6 +79,9 @@ type ForestConfig struct {
 type Forest struct {
 	config MptConfig
 
+	// The directory the forest is stored in, empty if not backed by disk.
+	directory string
+
 	// The stock containers managing individual node types.
 	branches   stock.Stock[uint64, BranchNode]
 	extensions stock.Stock[uint64, ExtensionNode]
@@ -119,6 +123,19 @@ type Forest struct {
 
 	// A flag indicating whether the forest is closed.
 	closed atomic.Bool
+
+	// An optional recorder informed about every node accessed, used for
+	// collecting witnesses. Nil if no recording is active.
+	witness atomic.Pointer[WitnessRecorder]
+
+	// The number of nodes modified since they were last written to disk,
+	// maintained incrementally as nodes are marked dirty and clean.
+	dirtyNodes atomic.Int64
+
+	// The net numbers of accounts and storage values inserted into the tries
+	// of this forest since it was opened, used for tracking state statistics.
+	insertedAccounts atomic.Int64
+	insertedValues   atomic.Int64
 }
 
 func OpenInMemoryForest(directory string, mptConfig MptConfig, forestConfig ForestConfig) (*Forest, error) {
@@ -181,25 +198,25 @@ func OpenFileForest(directory string, mptConfig MptConfig, forestConfig ForestCo
 	}()
 
 	accountEncoder, branchEncoder, extensionEncoder, valueEncoder := getEncoder(mptConfig)
-	branches, err := file.OpenStock[uint64, BranchNode](branchEncoder, directory+"/branches")
+	branches, err := openFileStock(branchEncoder, directory+"/branches", mptConfig.UseStockChecksums)
 	if err != nil {
 		return nil, err
 	}
 	closers = append(closers, branches)
 
-	extensions, err := file.OpenStock[uint64, ExtensionNode](extensionEncoder, directory+"/extensions")
+	extensions, err := openFileStock(extensionEncoder, directory+"/extensions", mptConfig.UseStockChecksums)
 	if err != nil {
 		return nil, err
 	}
 	closers = append(closers, extensions)
 
-	accounts, err := file.OpenStock[uint64, AccountNode](accountEncoder, directory+"/accounts")
+	accounts, err := openFileStock(accountEncoder, directory+"/accounts", mptConfig.UseStockChecksums)
 	if err != nil {
 		return nil, err
 	}
 	closers = append(closers, accounts)
 
-	values, err := file.OpenStock[uint64, ValueNode](valueEncoder, directory+"/values")
+	values, err := openFileStock(valueEncoder, directory+"/values", mptConfig.UseStockChecksums)
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +226,15 @@ func OpenFileForest(directory string, mptConfig MptConfig, forestConfig ForestCo
 	return makeForest(mptConfig, directory, branches, extensions, accounts, values, forestConfig)
 }
 
+// openFileStock opens a file based stock for nodes, creating new stocks with
+// or without checksums as requested.
+func openFileStock[V any](encoder stock.ValueEncoder[V], directory string, checksums bool) (stock.Stock[uint64, V], error) {
+	if checksums {
+		return file.OpenChecksummedStock[uint64, V](encoder, directory)
+	}
+	return file.OpenStock[uint64, V](encoder, directory)
+}
+
 // closers is a shortcut for the list of io.Closer.
 type closers []io.Closer
 
@@ -266,6 +292,7 @@ func makeForest(
 
 	res := &Forest{
 		config:        mptConfig,
+		directory:     directory,
 		branches:      synced.Sync(branches),
 		extensions:    synced.Sync(extensions),
 		accounts:      synced.Sync(accounts),
@@ -477,6 +504,26 @@ func (f *Forest) Freeze(ref *NodeReference) error {
 	return err
 }
 
+// setWitnessRecorder installs the given recorder to be informed about all
+// nodes accessed in this forest. A nil recorder stops an ongoing recording.
+// Only a single recorder may be active at a time.
+func (s *Forest) setWitnessRecorder(recorder *WitnessRecorder) error {
+	// Wait for the releaser to finish its current tasks such that pending
+	// releases are not attributed to the new recorder.
+	s.releaseQueue <- EmptyId() // signals a sync request
+	<-s.releaseSync
+
+	if recorder == nil {
+		s.witness.Store(nil)
+		return nil
+	}
+	recorder.config = s.config
+	if !s.witness.CompareAndSwap(nil, recorder) {
+		return errWitnessRecordingActive
+	}
+	return nil
+}
+
 // CheckErrors returns an error that might have been
 // encountered on this forest in the past.
 // If the result is not empty, this
@@ -485,6 +532,36 @@ func (s *Forest) CheckErrors() error {
 	return errors.Join(s.errors...)
 }
 
+// getNodeIds returns the IDs of all nodes present in this forest. Pending
+// asynchronous releases of nodes are completed before the IDs are collected.
+func (s *Forest) getNodeIds() (nodeIdSets, error) {
+	s.releaseQueue <- EmptyId() // signals a sync request
+	<-s.releaseSync
+
+	accounts, err := s.accounts.GetIds()
+	if err != nil {
+		return nodeIdSets{}, err
+	}
+	branches, err := s.branches.GetIds()
+	if err != nil {
+		return nodeIdSets{}, err
+	}
+	extensions, err := s.extensions.GetIds()
+	if err != nil {
+		return nodeIdSets{}, err
+	}
+	values, err := s.values.GetIds()
+	if err != nil {
+		return nodeIdSets{}, err
+	}
+	return nodeIdSets{
+		accounts:   accounts,
+		branches:   branches,
+		extensions: extensions,
+		values:     values,
+	}, nil
+}
+
 func (s *Forest) Flush() error {
 	// Wait for releaser to finish its current tasks.
 	s.releaseQueue <- EmptyId() // signals a sync request
@@ -519,6 +596,69 @@ func (s *Forest) Flush() error {
 	)
 }
 
+// CountDirtyNodes returns the number of nodes that have been modified since
+// they were last written to disk, including dirty nodes evicted from the node
+// cache but not yet written by the write buffer. The count is maintained
+// incrementally and may be outdated by concurrent modifications.
+func (s *Forest) CountDirtyNodes() int {
+	return int(s.dirtyNodes.Load())
+}
+
+func (s *Forest) nodeMarkedDirty() {
+	s.dirtyNodes.Add(1)
+}
+
+func (s *Forest) nodeMarkedClean() {
+	s.dirtyNodes.Add(-1)
+}
+
+func (s *Forest) stateSizeChanged(accounts, values int) {
+	s.insertedAccounts.Add(int64(accounts))
+	s.insertedValues.Add(int64(values))
+}
+
+// getInsertedStateSize returns the net numbers of accounts and storage values
+// inserted into the tries of this forest since it was opened. Storage tries
+// discarded as a whole by deleting or re-creating accounts are not covered.
+func (s *Forest) getInsertedStateSize() (accounts, values int64) {
+	return s.insertedAccounts.Load(), s.insertedValues.Load()
+}
+
+// resizableNodeCache is implemented by node caches whose capacity may be
+// changed at runtime.
+type resizableNodeCache interface {
+	getStatistics() common.CacheStatistics
+	setLimit(limit int) []evictedNode
+}
+
+// GetCacheStatistics summarizes the usage of the forest's node cache. If the
+// cache does not support resizing, empty statistics are returned.
+func (s *Forest) GetCacheStatistics() common.CacheStatistics {
+	cache, ok := s.nodeCache.(resizableNodeCache)
+	if !ok {
+		return common.CacheStatistics{}
+	}
+	stats := cache.getStatistics()
+	stats.EntrySize = uint64(EstimatePerNodeMemoryUsage())
+	stats.Memory = uint64(s.nodeCache.GetMemoryFootprint().Total())
+	return stats
+}
+
+// SetCacheCapacity changes the number of nodes retained in the forest's node
+// cache. The capacity can not exceed the capacity the forest was opened with.
+// Dirty nodes evicted by lowering the capacity are written to disk.
+func (s *Forest) SetCacheCapacity(capacity int) {
+	cache, ok := s.nodeCache.(resizableNodeCache)
+	if !ok {
+		return
+	}
+	s.nodeTransferMutex.Lock()
+	defer s.nodeTransferMutex.Unlock()
+	for _, evicted := range cache.setLimit(capacity) {
+		s.writeEvictedNodeHoldingTransferMutex(evicted.id, evicted.node)
+	}
+}
+
 func (s *Forest) flushDirtyIds(ids []NodeId) error {
 	var errs []error
 	// Flush dirty keys in order (to avoid excessive seeking).
@@ -531,6 +671,10 @@ func (s *Forest) flushDirtyIds(ids []NodeId) error {
 			node := handle.Get()
 			err := s.flushNode(id, node)
 			if err == nil {
+				// The node may have been released in the meantime.
+				if node.IsDirty() {
+					s.nodeMarkedClean()
+				}
 				node.MarkClean()
 			} else {
 				errs = append(errs, err)
@@ -602,6 +746,35 @@ func (s *Forest) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// GetDiskFootprint provides sizes of individual components of the forest on disk.
+func (s *Forest) GetDiskFootprint() (*common.DiskFootprint, error) {
+	df := common.NewDiskFootprint(0)
+	stocks := []struct {
+		name  string
+		stock common.DiskFootprintProvider
+	}{
+		{"accounts", s.accounts},
+		{"branches", s.branches},
+		{"extensions", s.extensions},
+		{"values", s.values},
+	}
+	for _, cur := range stocks {
+		child, err := cur.stock.GetDiskFootprint()
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild(cur.name, child)
+	}
+	if s.directory != "" {
+		metadata, err := common.GetFileDiskFootprint(filepath.Join(s.directory, "forest.json"))
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild("metadata", metadata)
+	}
+	return df, nil
+}
+
 // Dump prints the content of the Trie to the console. Mainly intended for debugging.
 func (s *Forest) Dump(rootRef *NodeReference) {
 	root, err := s.getViewAccess(rootRef)
@@ -711,6 +884,11 @@ func getAccess[H any](
 	if err != nil {
 		return def, err
 	}
+	if recorder := f.witness.Load(); recorder != nil {
+		if err := recorder.recordNode(f, ref, instance); err != nil {
+			return def, err
+		}
+	}
 	for {
 		// Obtain needed access and make sure the instance access was obtained
 		// for is still valid (by re-fetching the instance and check that it
@@ -835,22 +1013,27 @@ func (s *Forest) addToCacheHoldingTransferMutex(ref *NodeReference, node *shared
 		// would be. Methods like createBranch depend on this to be covered here.
 		s.nodeCache.Touch(ref)
 	}
-	if !evicted {
-		return current, present
+	if evicted {
+		s.writeEvictedNodeHoldingTransferMutex(evictedId, evictedNode)
 	}
+	return current, present
+}
 
+// writeEvictedNodeHoldingTransferMutex makes sure that a node evicted from the
+// node cache gets written to disk if it is dirty. The caller needs to hold the
+// nodeTransferMutex.
+func (s *Forest) writeEvictedNodeHoldingTransferMutex(id NodeId, node *shared.Shared[Node]) {
 	// Clean nodes can be ignored, dirty nodes need to be written.
-	if handle, ok := evictedNode.TryGetViewHandle(); ok {
+	if handle, ok := node.TryGetViewHandle(); ok {
 		dirty := handle.Get().IsDirty()
 		handle.Release()
 		if !dirty {
-			return current, present
+			return
 		}
 	}
 
 	// Enqueue evicted node for asynchronous write to file.
-	s.writeBuffer.Add(evictedId, evictedNode)
-	return current, present
+	s.writeBuffer.Add(id, node)
 }
 
 func (s *Forest) flushNode(id NodeId, node Node) error {
@@ -883,13 +1066,19 @@ func (s *Forest) createAccount() (NodeReference, shared.WriteHandle[Node], error
 		return NodeReference{}, shared.WriteHandle[Node]{}, err
 	}
 	ref := NewNodeReference(AccountId(i))
+	s.registerCreatedNode(ref.Id())
 	node := new(AccountNode)
 	instance, present := s.addToCache(&ref, shared.MakeShared[Node](node))
 	if present {
 		write := instance.GetWriteHandle()
+		if write.Get().IsDirty() {
+			s.nodeMarkedClean()
+		}
 		*write.Get().(*AccountNode) = *node
 		write.Release()
 	}
+	// New nodes are dirty until they are written to disk.
+	s.nodeMarkedDirty()
 	return ref, instance.GetWriteHandle(), err
 }
 
@@ -899,13 +1088,19 @@ func (s *Forest) createBranch() (NodeReference, shared.WriteHandle[Node], error)
 		return NodeReference{}, shared.WriteHandle[Node]{}, err
 	}
 	ref := NewNodeReference(BranchId(i))
+	s.registerCreatedNode(ref.Id())
 	node := new(BranchNode)
 	instance, present := s.addToCache(&ref, shared.MakeShared[Node](node))
 	if present {
 		write := instance.GetWriteHandle()
+		if write.Get().IsDirty() {
+			s.nodeMarkedClean()
+		}
 		*write.Get().(*BranchNode) = *node
 		write.Release()
 	}
+	// New nodes are dirty until they are written to disk.
+	s.nodeMarkedDirty()
 	return ref, instance.GetWriteHandle(), err
 }
 
@@ -915,13 +1110,19 @@ func (s *Forest) createExtension() (NodeReference, shared.WriteHandle[Node], err
 		return NodeReference{}, shared.WriteHandle[Node]{}, err
 	}
 	ref := NewNodeReference(ExtensionId(i))
+	s.registerCreatedNode(ref.Id())
 	node := new(ExtensionNode)
 	instance, present := s.addToCache(&ref, shared.MakeShared[Node](node))
 	if present {
 		write := instance.GetWriteHandle()
+		if write.Get().IsDirty() {
+			s.nodeMarkedClean()
+		}
 		*write.Get().(*ExtensionNode) = *node
 		write.Release()
 	}
+	// New nodes are dirty until they are written to disk.
+	s.nodeMarkedDirty()
 	return ref, instance.GetWriteHandle(), err
 }
 
@@ -931,16 +1132,30 @@ func (s *Forest) createValue() (NodeReference, shared.WriteHandle[Node], error)
 		return NodeReference{}, shared.WriteHandle[Node]{}, err
 	}
 	ref := NewNodeReference(ValueId(i))
+	s.registerCreatedNode(ref.Id())
 	node := new(ValueNode)
 	instance, present := s.addToCache(&ref, shared.MakeShared[Node](node))
 	if present {
 		write := instance.GetWriteHandle()
+		if write.Get().IsDirty() {
+			s.nodeMarkedClean()
+		}
 		*write.Get().(*ValueNode) = *node
 		write.Release()
 	}
+	// New nodes are dirty until they are written to disk.
+	s.nodeMarkedDirty()
 	return ref, instance.GetWriteHandle(), err
 }
 
+// registerCreatedNode informs an active witness recorder about a newly created
+// node which is thus not part of the state the witness is recorded for.
+func (s *Forest) registerCreatedNode(id NodeId) {
+	if recorder := s.witness.Load(); recorder != nil {
+		recorder.markCreated(id)
+	}
+}
+
 func (s *Forest) release(ref *NodeReference) error {
 	// Released nodes will not be needed,
 	// so they are moved in the cache to the least priority.
@@ -1015,7 +1230,12 @@ type writeBufferSink struct {
 }
 
 func (s writeBufferSink) Write(id NodeId, handle shared.ViewHandle[Node]) error {
-	return s.forest.flushNode(id, handle.Get())
+	// Successfully written nodes are marked clean by the write buffer.
+	err := s.forest.flushNode(id, handle.Get())
+	if err == nil {
+		s.forest.nodeMarkedClean()
+	}
+	return err
 }
 
 // -- Forest metadata --
@@ -686,6 +686,216 @@ func TestForest_Flush_Makes_Node_Clean(t *testing.T) {
 	}
 }
 
+func TestForest_CountDirtyNodes_CountsModifiedNodesUntilFlush(t *testing.T) {
+	for _, variant := range fileAndMemVariants {
+		for forestConfigName, forestConfig := range forestConfigs {
+			t.Run(fmt.Sprintf("%s-%s", variant.name, forestConfigName), func(t *testing.T) {
+				forest, err := variant.factory(t.TempDir(), S5LiveConfig, forestConfig)
+				if err != nil {
+					t.Fatalf("failed to open forest: %v", err)
+				}
+				defer func() {
+					if err := forest.Close(); err != nil {
+						t.Fatalf("cannot close forest: %v", err)
+					}
+				}()
+
+				if got := forest.CountDirtyNodes(); got != 0 {
+					t.Errorf("unexpected number of dirty nodes in empty forest, wanted 0, got %d", got)
+				}
+
+				root := NewNodeReference(EmptyId())
+				for _, address := range getTestAddresses(10) {
+					root, err = forest.SetAccountInfo(&root, address, AccountInfo{Balance: common.Balance{0x1}})
+					if err != nil {
+						t.Fatalf("cannot update account: %v", err)
+					}
+				}
+				if _, _, err := forest.updateHashesFor(&root); err != nil {
+					t.Fatalf("cannot update hashes: %v", err)
+				}
+
+				// 10 accounts need at least 10 account nodes and a branch node.
+				if got := forest.CountDirtyNodes(); got < 11 {
+					t.Errorf("unexpected number of dirty nodes, wanted at least 11, got %d", got)
+				}
+
+				if err := forest.Flush(); err != nil {
+					t.Fatalf("cannot flush: %v", err)
+				}
+				if got := forest.CountDirtyNodes(); got != 0 {
+					t.Errorf("unexpected number of dirty nodes after flush, wanted 0, got %d", got)
+				}
+			})
+		}
+	}
+}
+
+func TestForest_CountDirtyNodes_MatchesDirtyNodesInCache(t *testing.T) {
+	for _, variant := range fileAndMemVariants {
+		t.Run(variant.name, func(t *testing.T) {
+			forest, err := variant.factory(t.TempDir(), S5LiveConfig, ForestConfig{Mode: Mutable, CacheCapacity: 128 * 1024})
+			if err != nil {
+				t.Fatalf("failed to open forest: %v", err)
+			}
+			defer forest.Close()
+
+			countDirtyNodesInCache := func() int {
+				count := 0
+				forest.nodeCache.ForEach(func(id NodeId, node *shared.Shared[Node]) {
+					handle := node.GetViewHandle()
+					if handle.Get().IsDirty() {
+						count++
+					}
+					handle.Release()
+				})
+				return count
+			}
+
+			root := NewNodeReference(EmptyId())
+			addresses := getTestAddresses(20)
+			for round := 0; round < 3; round++ {
+				for i, address := range addresses {
+					root, err = forest.SetAccountInfo(&root, address, AccountInfo{Nonce: common.ToNonce(uint64(round + 1))})
+					if err != nil {
+						t.Fatalf("cannot update account: %v", err)
+					}
+					for j := 0; j < i%4; j++ {
+						root, err = forest.SetValue(&root, address, common.Key{byte(j)}, common.Value{byte(round + 1)})
+						if err != nil {
+							t.Fatalf("cannot update slot: %v", err)
+						}
+					}
+				}
+				// Some accounts are deleted, releasing their nodes.
+				for _, address := range addresses[round*5 : round*5+3] {
+					root, err = forest.SetAccountInfo(&root, address, AccountInfo{})
+					if err != nil {
+						t.Fatalf("cannot delete account: %v", err)
+					}
+				}
+				if _, _, err := forest.updateHashesFor(&root); err != nil {
+					t.Fatalf("cannot update hashes: %v", err)
+				}
+				// Wait for the asynchronous release of deleted nodes.
+				forest.releaseQueue <- EmptyId()
+				<-forest.releaseSync
+
+				if want, got := countDirtyNodesInCache(), forest.CountDirtyNodes(); want != got {
+					t.Errorf("unexpected number of dirty nodes in round %d, wanted %d, got %d", round, want, got)
+				}
+				if round == 1 {
+					if err := forest.Flush(); err != nil {
+						t.Fatalf("cannot flush: %v", err)
+					}
+					if got := forest.CountDirtyNodes(); got != 0 {
+						t.Errorf("unexpected number of dirty nodes after flush, wanted 0, got %d", got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestForest_CountDirtyNodes_EvictedNodesAreCountedUntilWritten(t *testing.T) {
+	for _, variant := range fileAndMemVariants {
+		t.Run(variant.name, func(t *testing.T) {
+			forest, err := variant.factory(t.TempDir(), S5LiveConfig, ForestConfig{Mode: Mutable, CacheCapacity: 64})
+			if err != nil {
+				t.Fatalf("failed to open forest: %v", err)
+			}
+			defer forest.Close()
+
+			root := NewNodeReference(EmptyId())
+			for _, address := range getTestAddresses(200) {
+				root, err = forest.SetAccountInfo(&root, address, AccountInfo{Nonce: common.ToNonce(1)})
+				if err != nil {
+					t.Fatalf("cannot update account: %v", err)
+				}
+			}
+			if got := forest.CountDirtyNodes(); got <= 0 {
+				t.Errorf("modified nodes should be counted, got %d", got)
+			}
+			if _, _, err := forest.updateHashesFor(&root); err != nil {
+				t.Fatalf("cannot update hashes: %v", err)
+			}
+			if err := forest.Flush(); err != nil {
+				t.Fatalf("cannot flush: %v", err)
+			}
+			if got := forest.CountDirtyNodes(); got != 0 {
+				t.Errorf("unexpected number of dirty nodes after flush, wanted 0, got %d", got)
+			}
+		})
+	}
+}
+
+func TestForest_getInsertedStateSize_CountsInsertedAndDeletedAccountsAndValues(t *testing.T) {
+	for _, mode := range []StorageMode{Mutable, Immutable} {
+		t.Run(mode.String(), func(t *testing.T) {
+			forest, err := OpenInMemoryForest(t.TempDir(), S5LiveConfig, ForestConfig{Mode: mode, CacheCapacity: 1024})
+			if err != nil {
+				t.Fatalf("failed to open forest: %v", err)
+			}
+			defer forest.Close()
+
+			root := NewNodeReference(EmptyId())
+			addresses := getTestAddresses(5)
+			for _, address := range addresses {
+				root, err = forest.SetAccountInfo(&root, address, AccountInfo{Nonce: common.ToNonce(1)})
+				if err != nil {
+					t.Fatalf("cannot update account: %v", err)
+				}
+				for i := 0; i < 3; i++ {
+					root, err = forest.SetValue(&root, address, common.Key{byte(i)}, common.Value{1})
+					if err != nil {
+						t.Fatalf("cannot update slot: %v", err)
+					}
+				}
+			}
+			if _, _, err := forest.updateHashesFor(&root); err != nil {
+				t.Fatalf("cannot update hashes: %v", err)
+			}
+			if mode == Immutable {
+				if err := forest.Freeze(&root); err != nil {
+					t.Fatalf("cannot freeze trie: %v", err)
+				}
+			}
+
+			// Updates of existing entries and deletions of missing ones are not counted.
+			root, err = forest.SetAccountInfo(&root, addresses[0], AccountInfo{Nonce: common.ToNonce(2)})
+			if err != nil {
+				t.Fatalf("cannot update account: %v", err)
+			}
+			root, err = forest.SetValue(&root, addresses[0], common.Key{0}, common.Value{2})
+			if err != nil {
+				t.Fatalf("cannot update slot: %v", err)
+			}
+			root, err = forest.SetValue(&root, addresses[0], common.Key{9}, common.Value{})
+			if err != nil {
+				t.Fatalf("cannot update slot: %v", err)
+			}
+			root, err = forest.SetValue(&root, addresses[1], common.Key{1}, common.Value{})
+			if err != nil {
+				t.Fatalf("cannot delete slot: %v", err)
+			}
+			root, err = forest.SetAccountInfo(&root, addresses[2], AccountInfo{})
+			if err != nil {
+				t.Fatalf("cannot delete account: %v", err)
+			}
+
+			if _, _, err := forest.updateHashesFor(&root); err != nil {
+				t.Fatalf("cannot update hashes: %v", err)
+			}
+
+			// The storage of the deleted account is discarded as a whole.
+			accounts, values := forest.getInsertedStateSize()
+			if accounts != 4 || values != 14 {
+				t.Errorf("unexpected inserted state size, wanted 4 accounts and 14 values, got %d and %d", accounts, values)
+			}
+		})
+	}
+}
+
 func TestForest_flushNode_EmptyId(t *testing.T) {
 	for _, variant := range variants {
 		for _, config := range allMptConfigs {
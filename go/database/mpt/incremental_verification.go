@@ -0,0 +1,561 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/stock"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt/shared"
+)
+
+// This file provides an incremental verification of archives. Since nodes of
+// an archive are frozen, nodes and roots verified once do not need to be
+// checked again. A checkpoint stored in the archive's directory records the
+// verified blocks and nodes such that subsequent verifications only need to
+// check the blocks and nodes added since then.
+
+const verificationCheckpointFileName = "verification.json"
+
+// verificationCheckpoint records the blocks and nodes of an archive covered
+// by previous verifications.
+type verificationCheckpoint struct {
+	Blocks     uint64 // < the number of verified blocks
+	Accounts   verifiedNodeRange
+	Branches   verifiedNodeRange
+	Extensions verifiedNodeRange
+	Values     verifiedNodeRange
+}
+
+// verifiedNodeRange describes the verified nodes of a single node type. All
+// nodes with an index below the high-water mark have been verified, except
+// for those whose indexes were not in use at the time of the verification.
+type verifiedNodeRange struct {
+	HighWaterMark uint64
+	Unused        []uint64 `json:",omitempty"`
+}
+
+// nodeIdSets are the IDs of the nodes present in a forest.
+type nodeIdSets struct {
+	accounts, branches, extensions, values stock.IndexSet[uint64]
+}
+
+func (s *nodeIdSets) isValid(id NodeId) bool {
+	switch {
+	case id.IsEmpty():
+		return true
+	case id.IsAccount():
+		return s.accounts.Contains(id.Index())
+	case id.IsBranch():
+		return s.branches.Contains(id.Index())
+	case id.IsExtension():
+		return s.extensions.Contains(id.Index())
+	case id.IsValue():
+		return s.values.Contains(id.Index())
+	}
+	return false
+}
+
+// VerifyArchiveIncrementally verifies the blocks and nodes added to the
+// archive in the given directory since the last verification and records the
+// verified state in a checkpoint. If there is no checkpoint yet, the complete
+// archive is verified like by VerifyArchive.
+func VerifyArchiveIncrementally(directory string, config MptConfig, observer VerificationObserver) (res error) {
+	if observer == nil {
+		observer = NilVerificationObserver{}
+	}
+	roots, err := loadRoots(filepath.Join(directory, "roots.dat"))
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+
+	checkpoint, found, err := readVerificationCheckpoint(directory)
+	if err != nil {
+		return err
+	}
+	if !found {
+		observer.Progress("No verification checkpoint found, verifying complete archive ...")
+		if err := VerifyFileForest(directory, config, roots, observer); err != nil {
+			return err
+		}
+	} else {
+		observer.StartVerification()
+		defer func() {
+			observer.EndVerification(res)
+		}()
+	}
+
+	source, err := openVerificationNodeSource(directory, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		res = errors.Join(res, source.Close())
+	}()
+	ids := nodeIdSets{
+		accounts:   source.accountIds,
+		branches:   source.branchIds,
+		extensions: source.extensionIds,
+		values:     source.valueIds,
+	}
+
+	if found {
+		checkpoint, err = verifyIncrementally(context.Background(), source, ids, roots, checkpoint, nil, observer)
+		if err != nil {
+			return err
+		}
+	} else {
+		checkpoint = getCheckpointOfCompleteVerification(uint64(len(roots)), ids)
+	}
+	return writeVerificationCheckpoint(directory, checkpoint)
+}
+
+// VerifyIncrementally verifies the blocks and nodes added to this archive
+// since the last verification and records the verified state in a checkpoint
+// in the archive's directory. Unlike VerifyArchiveIncrementally, it may be
+// used while blocks are added to the archive. If there is no checkpoint yet,
+// the complete archive is verified incrementally.
+func (a *ArchiveTrie) VerifyIncrementally(ctx context.Context, observer VerificationObserver) error {
+	return a.verifyIncrementally(ctx, nil, observer)
+}
+
+// BackgroundVerificationConfig configures the continuous verification of an
+// archive by RunBackgroundVerification.
+type BackgroundVerificationConfig struct {
+	Interval       time.Duration // < the pause between two incremental verifications
+	NodesPerSecond int           // < the maximum number of verified nodes per second, unlimited if 0
+}
+
+// RunBackgroundVerification incrementally verifies this archive in regular
+// intervals until the given context is done or a verification fails. The
+// results of each verification are reported to the given observer. The
+// function returns nil if it got stopped by the context, and the error of
+// the failed verification otherwise. It may be run concurrently to blocks
+// being added to this archive.
+func (a *ArchiveTrie) RunBackgroundVerification(ctx context.Context, config BackgroundVerificationConfig, observer VerificationObserver) error {
+	for {
+		limiter := newNodeRateLimiter(config.NodesPerSecond)
+		if err := a.verifyIncrementally(ctx, limiter, observer); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(config.Interval):
+		}
+	}
+}
+
+func (a *ArchiveTrie) verifyIncrementally(ctx context.Context, limiter *nodeRateLimiter, observer VerificationObserver) (res error) {
+	if observer == nil {
+		observer = NilVerificationObserver{}
+	}
+	forest, ok := a.forest.(*Forest)
+	if !ok {
+		return fmt.Errorf("unsupported forest implementation %T", a.forest)
+	}
+	if err := a.CheckErrors(); err != nil {
+		return err
+	}
+
+	observer.StartVerification()
+	defer func() {
+		observer.EndVerification(res)
+	}()
+
+	checkpoint, _, err := readVerificationCheckpoint(a.directory)
+	if err != nil {
+		return err
+	}
+
+	// Nodes present at the end of a block are frozen and not modified by
+	// subsequent blocks. Thus, the set of nodes to be checked and the roots
+	// need to be collected between two blocks.
+	a.addMutex.Lock()
+	ids, err := forest.getNodeIds()
+	a.rootsMutex.Lock()
+	roots := make([]Root, len(a.roots))
+	if checkpoint.Blocks <= uint64(len(a.roots)) {
+		copy(roots[checkpoint.Blocks:], a.roots[checkpoint.Blocks:])
+	}
+	a.rootsMutex.Unlock()
+	a.addMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err = verifyIncrementally(ctx, forest, ids, roots, checkpoint, limiter, observer)
+	if err != nil {
+		return err
+	}
+	return writeVerificationCheckpoint(a.directory, checkpoint)
+}
+
+// verifyIncrementally checks the roots and nodes not covered by the given
+// checkpoint and returns a checkpoint covering all given roots and nodes.
+// Roots of blocks covered by the checkpoint are not accessed.
+func verifyIncrementally(
+	ctx context.Context,
+	source NodeSource,
+	ids nodeIdSets,
+	roots []Root,
+	checkpoint verificationCheckpoint,
+	limiter *nodeRateLimiter,
+	observer VerificationObserver,
+) (verificationCheckpoint, error) {
+	config := source.getConfig()
+	if config.HashStorageLocation != HashStoredWithNode {
+		return checkpoint, fmt.Errorf("incremental verification is not supported for configuration %s", config.Name)
+	}
+	if checkpoint.Blocks > uint64(len(roots)) {
+		return checkpoint, fmt.Errorf("verification checkpoint covers %d blocks, archive has %d blocks", checkpoint.Blocks, len(roots))
+	}
+	verifier, err := newNodeVerifier(source, ids)
+	if err != nil {
+		return checkpoint, err
+	}
+
+	// Check the roots of new blocks.
+	observer.Progress(fmt.Sprintf("Checking %d new root hashes ...", uint64(len(roots))-checkpoint.Blocks))
+	for block := checkpoint.Blocks; block < uint64(len(roots)); block++ {
+		if err := verifier.checkRoot(roots[block]); err != nil {
+			return checkpoint, fmt.Errorf("invalid root of block %d: %w", block, err)
+		}
+	}
+
+	// Check nodes added since the last verification.
+	res := verificationCheckpoint{Blocks: uint64(len(roots))}
+	types := []struct {
+		name     string
+		ids      stock.IndexSet[uint64]
+		toId     func(uint64) NodeId
+		verified verifiedNodeRange
+		result   *verifiedNodeRange
+	}{
+		{"account", ids.accounts, AccountId, checkpoint.Accounts, &res.Accounts},
+		{"branch", ids.branches, BranchId, checkpoint.Branches, &res.Branches},
+		{"extension", ids.extensions, ExtensionId, checkpoint.Extensions, &res.Extensions},
+		{"value", ids.values, ValueId, checkpoint.Values, &res.Values},
+	}
+	for _, cur := range types {
+		added, covered := getUnverifiedNodes(cur.verified, cur.ids)
+		observer.Progress(fmt.Sprintf("Checking %d new %ss ...", len(added), cur.name))
+		for _, index := range added {
+			if err := limiter.wait(ctx); err != nil {
+				return checkpoint, err
+			}
+			if err := verifier.checkNode(cur.toId(index)); err != nil {
+				return checkpoint, err
+			}
+		}
+		*cur.result = covered
+	}
+	return res, nil
+}
+
+// getUnverifiedNodes lists the indexes of the given set not covered by the
+// given verified range and provides the range covering all nodes of the set.
+func getUnverifiedNodes(verified verifiedNodeRange, ids stock.IndexSet[uint64]) ([]uint64, verifiedNodeRange) {
+	res := []uint64{}
+	covered := verifiedNodeRange{HighWaterMark: verified.HighWaterMark}
+	for _, index := range verified.Unused {
+		if ids.Contains(index) {
+			res = append(res, index)
+		} else {
+			covered.Unused = append(covered.Unused, index)
+		}
+	}
+	for index := verified.HighWaterMark; index < ids.GetUpperBound(); index++ {
+		if ids.Contains(index) {
+			res = append(res, index)
+		} else {
+			covered.Unused = append(covered.Unused, index)
+		}
+	}
+	if ids.GetUpperBound() > covered.HighWaterMark {
+		covered.HighWaterMark = ids.GetUpperBound()
+	}
+	return res, covered
+}
+
+// getCheckpointOfCompleteVerification creates a checkpoint covering the given
+// number of blocks and all nodes in the given sets.
+func getCheckpointOfCompleteVerification(blocks uint64, ids nodeIdSets) verificationCheckpoint {
+	covered := func(ids stock.IndexSet[uint64]) verifiedNodeRange {
+		_, res := getUnverifiedNodes(verifiedNodeRange{}, ids)
+		return res
+	}
+	return verificationCheckpoint{
+		Blocks:     blocks,
+		Accounts:   covered(ids.accounts),
+		Branches:   covered(ids.branches),
+		Extensions: covered(ids.extensions),
+		Values:     covered(ids.values),
+	}
+}
+
+// nodeVerifier checks the consistency of individual nodes of a forest storing
+// hashes with nodes.
+type nodeVerifier struct {
+	source        NodeSource
+	ids           nodeIdSets
+	hasher        hasher
+	emptyNodeHash common.Hash
+}
+
+func newNodeVerifier(source NodeSource, ids nodeIdSets) (*nodeVerifier, error) {
+	res := &nodeVerifier{
+		source: source,
+		ids:    ids,
+		hasher: source.getConfig().Hashing.createHasher(),
+	}
+	var err error
+	if res.emptyNodeHash, err = res.hash(EmptyId(), EmptyNode{}); err != nil {
+		return nil, fmt.Errorf("failed to hash empty node: %w", err)
+	}
+	return res, nil
+}
+
+func (v *nodeVerifier) checkRoot(root Root) error {
+	if !v.ids.isValid(root.NodeRef.Id()) {
+		return fmt.Errorf("contains invalid reference to node %v", root.NodeRef.Id())
+	}
+	hash, _, err := v.getStoredHash(root.NodeRef.Id())
+	if err != nil {
+		return err
+	}
+	if hash != root.Hash {
+		return fmt.Errorf("inconsistent hash for root node %v, want %v, got %v", root.NodeRef.Id(), hash, root.Hash)
+	}
+	return nil
+}
+
+// checkNode checks that the node with the given ID only references existing
+// nodes and that its stored hash is consistent with its content and the
+// hashes stored in the referenced nodes.
+func (v *nodeVerifier) checkNode(id NodeId) error {
+	ref := NewNodeReference(id)
+	handle, err := v.source.getViewAccess(&ref)
+	if err != nil {
+		return err
+	}
+	var node Node
+	switch n := handle.Get().(type) {
+	case *AccountNode:
+		clone := *n
+		node = &clone
+	case *BranchNode:
+		clone := *n
+		node = &clone
+	case *ExtensionNode:
+		clone := *n
+		node = &clone
+	case *ValueNode:
+		clone := *n
+		node = &clone
+	default:
+		handle.Release()
+		return fmt.Errorf("unexpected node type %T for node %v", n, id)
+	}
+	handle.Release()
+
+	// Fill in the hashes of the referenced nodes.
+	switch n := node.(type) {
+	case *AccountNode:
+		hash, _, err := v.getChildHash(id, n.storage.Id())
+		if err != nil {
+			return err
+		}
+		n.storageHash = hash
+		n.storageHashDirty = false
+	case *BranchNode:
+		for i := 0; i < len(n.children); i++ {
+			child := n.children[i].Id()
+			if child.IsEmpty() {
+				continue
+			}
+			hash, embedded, err := v.getChildHash(id, child)
+			if err != nil {
+				return err
+			}
+			if embedded {
+				n.setEmbedded(byte(i), true)
+			}
+			n.hashes[i] = hash
+		}
+		n.dirtyHashes = 0
+	case *ExtensionNode:
+		hash, embedded, err := v.getChildHash(id, n.next.Id())
+		if err != nil {
+			return err
+		}
+		n.nextHash = hash
+		n.nextHashDirty = false
+		n.nextIsEmbedded = embedded
+	}
+
+	want, err := v.hash(id, node)
+	if err != nil {
+		return err
+	}
+	got, dirty := node.GetHash()
+	if dirty {
+		return fmt.Errorf("encountered dirty hash for node %v", id)
+	}
+	if got != want {
+		return fmt.Errorf("invalid hash stored for node %v, want %v, got %v", id, want, got)
+	}
+	return nil
+}
+
+// getChildHash checks that the given child of the given node is present and
+// provides its stored hash and whether it is embedded in its parent.
+func (v *nodeVerifier) getChildHash(parent, child NodeId) (common.Hash, bool, error) {
+	if !v.ids.isValid(child) {
+		return common.Hash{}, false, fmt.Errorf("node %v contains invalid reference to node %v", parent, child)
+	}
+	return v.getStoredHash(child)
+}
+
+// getStoredHash provides the hash stored in the given node and whether the
+// node is embedded in its parent.
+func (v *nodeVerifier) getStoredHash(id NodeId) (common.Hash, bool, error) {
+	if id.IsEmpty() {
+		return v.emptyNodeHash, false, nil
+	}
+	ref := NewNodeReference(id)
+	handle, err := v.source.getViewAccess(&ref)
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	defer handle.Release()
+	hash, dirty := handle.Get().GetHash()
+	if dirty {
+		return common.Hash{}, false, fmt.Errorf("encountered dirty hash for node %v", id)
+	}
+	embedded, err := v.hasher.isEmbedded(handle.Get(), v.source)
+	return hash, embedded, err
+}
+
+// hash computes the hash of the given node, which is resolved for the given
+// ID instead of the node stored in the source.
+func (v *nodeVerifier) hash(id NodeId, node Node) (common.Hash, error) {
+	ref := NewNodeReference(id)
+	return v.hasher.getHash(&ref, &nodeOverrideSource{NodeSource: v.source, id: id, node: node})
+}
+
+// nodeOverrideSource is a NodeSource resolving a single ID to a custom node.
+type nodeOverrideSource struct {
+	NodeSource
+	id   NodeId
+	node Node
+}
+
+func (s *nodeOverrideSource) getViewAccess(ref *NodeReference) (shared.ViewHandle[Node], error) {
+	if ref.Id() == s.id {
+		return shared.MakeShared[Node](s.node).GetViewHandle(), nil
+	}
+	return s.NodeSource.getViewAccess(ref)
+}
+
+func (s *nodeOverrideSource) getReadAccess(ref *NodeReference) (shared.ReadHandle[Node], error) {
+	if ref.Id() == s.id {
+		return shared.MakeShared[Node](s.node).GetReadHandle(), nil
+	}
+	return s.NodeSource.getReadAccess(ref)
+}
+
+// nodeRateLimiter limits the number of verified nodes per second. A nil
+// limiter does not limit the rate.
+type nodeRateLimiter struct {
+	nodesPerSecond int
+	start          time.Time
+	count          int
+}
+
+func newNodeRateLimiter(nodesPerSecond int) *nodeRateLimiter {
+	if nodesPerSecond <= 0 {
+		return nil
+	}
+	return &nodeRateLimiter{nodesPerSecond: nodesPerSecond, start: time.Now()}
+}
+
+// wait blocks until the next node may be verified or the context is done.
+func (l *nodeRateLimiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil || l == nil {
+		return err
+	}
+	l.count++
+	due := l.start.Add(time.Duration(l.count) * time.Second / time.Duration(l.nodesPerSecond))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+func readVerificationCheckpoint(directory string) (verificationCheckpoint, bool, error) {
+	data, err := os.ReadFile(filepath.Join(directory, verificationCheckpointFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return verificationCheckpoint{}, false, nil
+	}
+	if err != nil {
+		return verificationCheckpoint{}, false, err
+	}
+	var res verificationCheckpoint
+	if err := json.Unmarshal(data, &res); err != nil {
+		return verificationCheckpoint{}, false, fmt.Errorf("invalid verification checkpoint: %w", err)
+	}
+	return res, true, nil
+}
+
+func writeVerificationCheckpoint(directory string, checkpoint verificationCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	// The checkpoint is replaced atomically such that a crash while writing
+	// does not leave a truncated checkpoint behind.
+	file := filepath.Join(directory, verificationCheckpointFileName)
+	tmp := file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
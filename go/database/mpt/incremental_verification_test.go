@@ -0,0 +1,355 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/stock"
+	"github.com/Fantom-foundation/Carmen/go/backend/stock/file"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"go.uber.org/mock/gomock"
+)
+
+// addIncrementalVerificationTestBlocks adds blocks modifying accounts and
+// storage slots to the given archive, starting at the given block.
+func addIncrementalVerificationTestBlocks(t *testing.T, archive *ArchiveTrie, from, to uint64) {
+	t.Helper()
+	for block := from; block < to; block++ {
+		update := common.Update{}
+		for i := 0; i < 10; i++ {
+			address := common.Address{byte(block), byte(i)}
+			update.CreatedAccounts = append(update.CreatedAccounts, address)
+			update.Balances = append(update.Balances, common.BalanceUpdate{Account: address, Balance: common.Balance{31: byte(block + 1)}})
+			update.Slots = append(update.Slots, common.SlotUpdate{Account: address, Key: common.Key{byte(i)}, Value: common.Value{byte(block + 1)}})
+		}
+		if block > 0 {
+			update.DeletedAccounts = []common.Address{{byte(block - 1), 0}}
+		}
+		if err := update.Normalize(); err != nil {
+			t.Fatalf("failed to normalize update: %v", err)
+		}
+		if err := archive.Add(block, update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+}
+
+func createIncrementalVerificationTestArchive(t *testing.T, dir string, config MptConfig, from, to uint64) {
+	t.Helper()
+	archive, err := OpenArchiveTrie(dir, config, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	addIncrementalVerificationTestBlocks(t, archive, from, to)
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+}
+
+func readTestCheckpoint(t *testing.T, dir string) verificationCheckpoint {
+	t.Helper()
+	checkpoint, found, err := readVerificationCheckpoint(dir)
+	if err != nil || !found {
+		t.Fatalf("failed to read verification checkpoint, found %t, err %v", found, err)
+	}
+	return checkpoint
+}
+
+func TestVerifyArchiveIncrementally_CheckpointIsCreatedAndExtended(t *testing.T) {
+	for _, config := range []MptConfig{S4ArchiveConfig, S5ArchiveConfig} {
+		t.Run(config.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			createIncrementalVerificationTestArchive(t, dir, config, 0, 5)
+			if err := VerifyArchiveIncrementally(dir, config, nil); err != nil {
+				t.Fatalf("failed to verify archive: %v", err)
+			}
+			first := readTestCheckpoint(t, dir)
+			if first.Blocks != 5 {
+				t.Errorf("unexpected number of verified blocks, wanted 5, got %d", first.Blocks)
+			}
+
+			createIncrementalVerificationTestArchive(t, dir, config, 5, 8)
+			if err := VerifyArchiveIncrementally(dir, config, nil); err != nil {
+				t.Fatalf("failed to verify archive: %v", err)
+			}
+			second := readTestCheckpoint(t, dir)
+			if second.Blocks != 8 {
+				t.Errorf("unexpected number of verified blocks, wanted 8, got %d", second.Blocks)
+			}
+			if second.Accounts.HighWaterMark <= first.Accounts.HighWaterMark || second.Values.HighWaterMark <= first.Values.HighWaterMark {
+				t.Errorf("high-water marks should grow, before %v, after %v", first, second)
+			}
+		})
+	}
+}
+
+func TestVerifyArchiveIncrementally_ModifiedNewNodeIsDetected(t *testing.T) {
+	dir := t.TempDir()
+	config := S5ArchiveConfig
+	createIncrementalVerificationTestArchive(t, dir, config, 0, 3)
+	if err := VerifyArchiveIncrementally(dir, config, nil); err != nil {
+		t.Fatalf("failed to verify archive: %v", err)
+	}
+	createIncrementalVerificationTestArchive(t, dir, config, 3, 5)
+
+	encoder, _, _, _ := getEncoder(config)
+	modifyLastNode(t, dir+"/accounts", encoder, func(node *AccountNode) {
+		node.info.Balance[0]++
+	})
+	err := VerifyArchiveIncrementally(dir, config, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid hash stored for node") {
+		t.Errorf("modification of new node should be detected, got %v", err)
+	}
+	if got := readTestCheckpoint(t, dir).Blocks; got != 3 {
+		t.Errorf("failed verification should not update the checkpoint, got %d verified blocks", got)
+	}
+}
+
+func TestVerifyArchiveIncrementally_VerifiedNodesAreNotCheckedAgain(t *testing.T) {
+	dir := t.TempDir()
+	config := S5ArchiveConfig
+	createIncrementalVerificationTestArchive(t, dir, config, 0, 3)
+	if err := VerifyArchiveIncrementally(dir, config, nil); err != nil {
+		t.Fatalf("failed to verify archive: %v", err)
+	}
+
+	// A modification of an already verified node is only detected by a full verification.
+	encoder, _, _, _ := getEncoder(config)
+	modifyNode(t, dir+"/accounts", encoder, func(node *AccountNode) {
+		node.info.Balance[0]++
+	})
+	if err := VerifyArchiveIncrementally(dir, config, nil); err != nil {
+		t.Errorf("verified nodes should not be checked again, got %v", err)
+	}
+	if err := VerifyArchive(dir, config, nil); err == nil {
+		t.Errorf("full verification should detect the modification")
+	}
+}
+
+func TestVerifyArchiveIncrementally_ObserverIsNotified(t *testing.T) {
+	dir := t.TempDir()
+	config := S5ArchiveConfig
+	createIncrementalVerificationTestArchive(t, dir, config, 0, 3)
+	if err := VerifyArchiveIncrementally(dir, config, nil); err != nil {
+		t.Fatalf("failed to verify archive: %v", err)
+	}
+	createIncrementalVerificationTestArchive(t, dir, config, 3, 4)
+
+	ctrl := gomock.NewController(t)
+	observer := NewMockVerificationObserver(ctrl)
+	gomock.InOrder(
+		observer.EXPECT().StartVerification(),
+		observer.EXPECT().Progress("Checking 1 new root hashes ..."),
+		observer.EXPECT().Progress(gomock.Any()).Times(4),
+		observer.EXPECT().EndVerification(nil),
+	)
+	if err := VerifyArchiveIncrementally(dir, config, observer); err != nil {
+		t.Fatalf("failed to verify archive: %v", err)
+	}
+}
+
+func TestVerifyIncrementally_ConfigurationsStoringHashesWithParentsAreRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := NewMockNodeSource(ctrl)
+	source.EXPECT().getConfig().Return(S5LiveConfig)
+	_, err := verifyIncrementally(context.Background(), source, nodeIdSets{}, nil, verificationCheckpoint{}, nil, NilVerificationObserver{})
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("configurations storing hashes with parents should be rejected, got %v", err)
+	}
+}
+
+func TestArchiveTrie_VerifyIncrementally_BlocksAddedSinceLastVerificationAreVerified(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+
+	for i, blocks := range []uint64{3, 6, 6, 10} {
+		addIncrementalVerificationTestBlocks(t, archive, []uint64{0, 3, 6, 6}[i], blocks)
+		if err := archive.VerifyIncrementally(context.Background(), nil); err != nil {
+			t.Fatalf("failed to verify archive: %v", err)
+		}
+		if got := readTestCheckpoint(t, dir).Blocks; got != blocks {
+			t.Errorf("unexpected number of verified blocks, wanted %d, got %d", blocks, got)
+		}
+	}
+
+	// The checkpoint created by the online verification is valid for offline verifications.
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	if err := VerifyArchiveIncrementally(dir, S5ArchiveConfig, nil); err != nil {
+		t.Errorf("failed to verify archive: %v", err)
+	}
+}
+
+func TestArchiveTrie_VerifyIncrementally_CheckpointBeyondArchiveIsRejected(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	addIncrementalVerificationTestBlocks(t, archive, 0, 2)
+	if err := writeVerificationCheckpoint(dir, verificationCheckpoint{Blocks: 5}); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+	if err := archive.VerifyIncrementally(context.Background(), nil); err == nil {
+		t.Errorf("checkpoint covering more blocks than present should be rejected")
+	}
+}
+
+func TestArchiveTrie_RunBackgroundVerification_VerifiesNewBlocksUntilStopped(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		config := BackgroundVerificationConfig{Interval: time.Millisecond, NodesPerSecond: 100_000}
+		done <- archive.RunBackgroundVerification(ctx, config, nil)
+	}()
+
+	addIncrementalVerificationTestBlocks(t, archive, 0, 20)
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(time.Millisecond) {
+		if checkpoint, found, _ := readVerificationCheckpoint(dir); found && checkpoint.Blocks == 20 {
+			break
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("background verification failed: %v", err)
+	}
+	if got := readTestCheckpoint(t, dir).Blocks; got != 20 {
+		t.Errorf("unexpected number of verified blocks, wanted 20, got %d", got)
+	}
+}
+
+func TestWriteVerificationCheckpoint_ExistingCheckpointIsReplaced(t *testing.T) {
+	dir := t.TempDir()
+	for _, blocks := range []uint64{5, 7} {
+		if err := writeVerificationCheckpoint(dir, verificationCheckpoint{Blocks: blocks}); err != nil {
+			t.Fatalf("failed to write checkpoint: %v", err)
+		}
+		checkpoint, found, err := readVerificationCheckpoint(dir)
+		if err != nil || !found {
+			t.Fatalf("failed to read checkpoint, found %t, err %v", found, err)
+		}
+		if checkpoint.Blocks != blocks {
+			t.Errorf("unexpected number of blocks, wanted %d, got %d", blocks, checkpoint.Blocks)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != verificationCheckpointFileName {
+		t.Errorf("unexpected directory content: %v", entries)
+	}
+}
+
+func TestWriteVerificationCheckpoint_FailingWriteRetainsPreviousCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeVerificationCheckpoint(dir, verificationCheckpoint{Blocks: 5}); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+	// A directory in place of the temporary file makes the write fail.
+	if err := os.Mkdir(filepath.Join(dir, verificationCheckpointFileName+".tmp"), 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := writeVerificationCheckpoint(dir, verificationCheckpoint{Blocks: 7}); err == nil {
+		t.Errorf("writing checkpoint should fail")
+	}
+	checkpoint, found, err := readVerificationCheckpoint(dir)
+	if err != nil || !found || checkpoint.Blocks != 5 {
+		t.Errorf("previous checkpoint not retained, got %v, found %t, err %v", checkpoint, found, err)
+	}
+}
+
+func TestGetUnverifiedNodes_NewAndReusedIndexesAreListed(t *testing.T) {
+	ids := stock.MakeComplementSet[uint64](0, 10)
+	ids.Remove(3)
+	ids.Remove(7)
+
+	verified := verifiedNodeRange{HighWaterMark: 6, Unused: []uint64{2, 4}}
+	added, covered := getUnverifiedNodes(verified, ids)
+	if want := []uint64{2, 4, 6, 8, 9}; !reflect.DeepEqual(want, added) {
+		t.Errorf("unexpected unverified nodes, wanted %v, got %v", want, added)
+	}
+	if want := (verifiedNodeRange{HighWaterMark: 10, Unused: []uint64{7}}); !reflect.DeepEqual(want, covered) {
+		t.Errorf("unexpected covered range, wanted %v, got %v", want, covered)
+	}
+}
+
+func TestNodeRateLimiter_RateIsLimited(t *testing.T) {
+	limiter := newNodeRateLimiter(1000)
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatalf("failed to wait: %v", err)
+		}
+	}
+	if got := time.Since(start); got < 50*time.Millisecond {
+		t.Errorf("rate should be limited, 50 nodes took %v", got)
+	}
+
+	var unlimited *nodeRateLimiter
+	if err := unlimited.wait(context.Background()); err != nil {
+		t.Errorf("unlimited limiter should not fail: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newNodeRateLimiter(1).wait(ctx); err != context.Canceled {
+		t.Errorf("waiting with cancelled context should fail, got %v", err)
+	}
+}
+
+// modifyLastNode is like modifyNode but modifies the node with the highest
+// index, which is one of the most recently added nodes of the stock.
+func modifyLastNode[N any](t *testing.T, directory string, encoder stock.ValueEncoder[N], modify func(n *N)) {
+	t.Helper()
+	stock, err := file.OpenStock[uint64](encoder, directory)
+	if err != nil {
+		t.Fatalf("failed to open stock")
+	}
+	ids, err := stock.GetIds()
+	if err != nil {
+		t.Fatalf("failed to get stock ids: %v", err)
+	}
+	idx := ids.GetUpperBound() - 1
+	for !ids.Contains(idx) {
+		idx--
+	}
+	node, err := stock.Get(idx)
+	if err != nil {
+		t.Fatalf("failed to load node from stock: %v", err)
+	}
+	modify(&node)
+	if err := stock.Set(idx, node); err != nil {
+		t.Fatalf("failed to update node: %v", err)
+	}
+	if err := stock.Close(); err != nil {
+		t.Fatalf("failed to close stock: %v", err)
+	}
+}
@@ -12,6 +12,7 @@ package io
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -55,6 +56,12 @@ var archiveMagicNumber []byte = []byte("Fantom-Archive-State")
 const archiveFormatVersion = byte(1)
 
 func ExportArchive(directory string, out io.Writer) error {
+	return ExportArchiveWithContext(context.Background(), directory, out)
+}
+
+// ExportArchiveWithContext is like ExportArchive but aborts the export with
+// the context's error once the given context is done.
+func ExportArchiveWithContext(ctx context.Context, directory string, out io.Writer) error {
 
 	info, err := CheckMptDirectoryAndGetInfo(directory)
 	if err != nil {
@@ -100,7 +107,10 @@ func ExportArchive(directory string, out io.Writer) error {
 
 	// Encode diff of each individual block.
 	for block := uint64(0); block <= maxBlock; block++ {
-		diff, err := archive.GetDiffForBlock(block)
+		diff, err := archive.GetDiffForBlockWithContext(ctx, block)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return errors.Join(ctxErr, archive.Close())
+		}
 		if err != nil {
 			return fmt.Errorf("failed to get diff for block %d: %w", block, err)
 		}
@@ -197,28 +207,42 @@ func ExportArchive(directory string, out io.Writer) error {
 }
 
 func ImportArchive(directory string, in io.Reader) error {
+	return ImportArchiveWithContext(context.Background(), directory, in)
+}
+
+// ImportArchiveWithContext is like ImportArchive but aborts the import with
+// the context's error once the given context is done. The content of the
+// target directory is undefined after an aborted import.
+func ImportArchiveWithContext(ctx context.Context, directory string, in io.Reader) error {
 	// check that the destination directory is an empty directory
 	if err := checkEmptyDirectory(directory); err != nil {
 		return err
 	}
 	liveDbDir := path.Join(directory, "tmp-live-db")
 	return errors.Join(
-		importArchive(liveDbDir, directory, in),
+		importArchive(ctx, liveDbDir, directory, in),
 		os.RemoveAll(liveDbDir), // live db is deleted at the end
 	)
 }
 
 func ImportLiveAndArchive(directory string, in io.Reader) error {
+	return ImportLiveAndArchiveWithContext(context.Background(), directory, in)
+}
+
+// ImportLiveAndArchiveWithContext is like ImportLiveAndArchive but aborts the
+// import with the context's error once the given context is done. The content
+// of the target directory is undefined after an aborted import.
+func ImportLiveAndArchiveWithContext(ctx context.Context, directory string, in io.Reader) error {
 	// check that the destination directory is an empty directory
 	if err := checkEmptyDirectory(directory); err != nil {
 		return err
 	}
 	liveDbDir := path.Join(directory, "live")
 	archiveDbDir := path.Join(directory, "archive")
-	return importArchive(liveDbDir, archiveDbDir, in)
+	return importArchive(ctx, liveDbDir, archiveDbDir, in)
 }
 
-func importArchive(liveDbDir, archiveDbDir string, in io.Reader) (err error) {
+func importArchive(ctx context.Context, liveDbDir, archiveDbDir string, in io.Reader) (err error) {
 	// Start by checking the magic number.
 	buffer := make([]byte, len(archiveMagicNumber))
 	if _, err := io.ReadFull(in, buffer); err != nil {
@@ -258,6 +282,10 @@ func importArchive(liveDbDir, archiveDbDir string, in io.Reader) (err error) {
 	// Restore the archive from the input file.
 	context := newImportContext()
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Read prefix determining the next input marker.
 		if _, err := io.ReadFull(in, buffer[0:1]); err != nil {
 			if err == io.EOF {
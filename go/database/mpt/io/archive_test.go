@@ -12,6 +12,8 @@ package io
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"path"
 	"testing"
 
@@ -216,3 +218,31 @@ func fillTestBlocksIntoArchive(t *testing.T, archive *mpt.ArchiveTrie) (blockHei
 
 	return 7
 }
+
+func TestIO_Archive_CancelledExportAndImportFail(t *testing.T) {
+	sourceDir := t.TempDir()
+	source, err := mpt.OpenArchiveTrie(sourceDir, mpt.S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	fillTestBlocksIntoArchive(t, source)
+	if err := source.Close(); err != nil {
+		t.Fatalf("failed to close source archive: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	buffer := new(bytes.Buffer)
+	if err := ExportArchiveWithContext(ctx, sourceDir, buffer); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+
+	buffer.Reset()
+	if err := ExportArchive(sourceDir, buffer); err != nil {
+		t.Fatalf("failed to export Archive: %v", err)
+	}
+	if err := ImportArchiveWithContext(ctx, t.TempDir(), buffer); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+}
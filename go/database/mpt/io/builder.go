@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package io
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+)
+
+// hashUpdatePeriod is the number of modifications after which the builder
+// refreshes the hashes of its trie to release nodes with dirty hashes.
+const hashUpdatePeriod = 100_000
+
+// StateBuilder constructs a fresh LiveDB or Archive from a stream of account,
+// code, and storage entries. Entries may be provided in any order and the
+// number of entries is only bounded by the available disk space; the memory
+// used by the builder is bounded by the node cache of the underlying trie.
+type StateBuilder struct {
+	directory string
+	block     uint64
+	archive   bool
+	db        *mpt.MptState
+	counter   int
+}
+
+// NewLiveDbBuilder creates a builder for a LiveDB in the given directory,
+// which must be an existing, empty directory. The resulting LiveDB has the
+// given block recorded as its head block.
+func NewLiveDbBuilder(directory string, block uint64) (*StateBuilder, error) {
+	return newStateBuilder(directory, block, mpt.S5LiveConfig, false)
+}
+
+// NewArchiveBuilder creates a builder for an Archive in the given directory,
+// which must be an existing, empty directory. The resulting Archive contains
+// the built state at the given block. All states before the given block are
+// empty.
+func NewArchiveBuilder(directory string, block uint64) (*StateBuilder, error) {
+	return newStateBuilder(directory, block, mpt.S5ArchiveConfig, true)
+}
+
+func newStateBuilder(directory string, block uint64, config mpt.MptConfig, archive bool) (*StateBuilder, error) {
+	if err := checkEmptyDirectory(directory); err != nil {
+		return nil, err
+	}
+	db, err := mpt.OpenGoFileState(directory, config, mpt.DefaultMptStateCapacity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create empty state: %w", err)
+	}
+	return &StateBuilder{
+		directory: directory,
+		block:     block,
+		archive:   archive,
+		db:        db,
+	}, nil
+}
+
+// SetAccount creates the given account, if it does not exist yet, and sets
+// its balance and nonce.
+func (b *StateBuilder) SetAccount(address common.Address, balance common.Balance, nonce common.Nonce) error {
+	if err := b.ensureAccount(address); err != nil {
+		return err
+	}
+	if err := b.db.SetBalance(address, balance); err != nil {
+		return err
+	}
+	if err := b.db.SetNonce(address, nonce); err != nil {
+		return err
+	}
+	return b.modified()
+}
+
+// SetCode creates the given account, if it does not exist yet, and sets its
+// code.
+func (b *StateBuilder) SetCode(address common.Address, code []byte) error {
+	if err := b.ensureAccount(address); err != nil {
+		return err
+	}
+	if err := b.db.SetCode(address, code); err != nil {
+		return err
+	}
+	return b.modified()
+}
+
+// SetStorage creates the given account, if it does not exist yet, and sets
+// the value of the given storage slot.
+func (b *StateBuilder) SetStorage(address common.Address, key common.Key, value common.Value) error {
+	if err := b.ensureAccount(address); err != nil {
+		return err
+	}
+	if err := b.db.SetStorage(address, key, value); err != nil {
+		return err
+	}
+	return b.modified()
+}
+
+// Finish completes the construction, closes the builder, and returns the
+// hash of the built state.
+func (b *StateBuilder) Finish() (hash common.Hash, err error) {
+	if b.db == nil {
+		return hash, fmt.Errorf("builder is closed")
+	}
+	db := b.db
+	b.db = nil
+
+	var root mpt.NodeId
+	if b.archive {
+		hash, err = db.GetHash()
+		root = db.GetRootId()
+	} else {
+		// Record the head block of the LiveDB.
+		var hints common.Releaser
+		hints, err = db.Apply(b.block, common.Update{})
+		if hints != nil {
+			hints.Release()
+		}
+		if err == nil {
+			hash, err = db.GetHash()
+		}
+	}
+	if err := errors.Join(err, db.Close()); err != nil {
+		return hash, err
+	}
+	if b.archive {
+		if err := sealArchive(b.directory, root, hash, b.block); err != nil {
+			return hash, err
+		}
+	}
+	return hash, nil
+}
+
+// Abort closes the builder without completing the construction. The content
+// of the target directory is undefined afterwards.
+func (b *StateBuilder) Abort() error {
+	if b.db == nil {
+		return nil
+	}
+	db := b.db
+	b.db = nil
+	return db.Close()
+}
+
+func (b *StateBuilder) ensureAccount(address common.Address) error {
+	if b.db == nil {
+		return fmt.Errorf("builder is closed")
+	}
+	exists, err := b.db.Exists(address)
+	if err != nil || exists {
+		return err
+	}
+	return b.db.CreateAccount(address)
+}
+
+// modified updates hashes periodically to avoid running out of memory for
+// nodes with dirty hashes.
+func (b *StateBuilder) modified() error {
+	b.counter++
+	if b.counter%hashUpdatePeriod == 0 {
+		if _, err := b.db.GetHash(); err != nil {
+			return fmt.Errorf("failed to update hashes: %w", err)
+		}
+	}
+	return nil
+}
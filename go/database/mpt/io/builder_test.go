@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package io
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+)
+
+func TestStateBuilder_UnsortedEntriesReproduceExportedState(t *testing.T) {
+	_, want := exportExampleState(t)
+
+	for _, archive := range []bool{false, true} {
+		dir := t.TempDir()
+		block := uint64(12)
+		var builder *StateBuilder
+		var err error
+		if archive {
+			builder, err = NewArchiveBuilder(dir, block)
+		} else {
+			builder, err = NewLiveDbBuilder(dir, block)
+		}
+		if err != nil {
+			t.Fatalf("failed to create builder: %v", err)
+		}
+
+		// Same content as the example state, provided in a scrambled order.
+		addr1 := common.Address{1}
+		addr2 := common.Address{2}
+		steps := []func() error{
+			func() error { return builder.SetStorage(addr2, common.Key{2}, common.Value{2}) },
+			func() error { return builder.SetStorage(addr1, common.Key{1}, common.Value{1}) },
+			func() error { return builder.SetCode(addr1, []byte("some_code")) },
+			func() error { return builder.SetAccount(addr2, common.Balance{14}, common.ToNonce(2)) },
+			func() error { return builder.SetStorage(addr2, common.Key{1}, common.Value{1}) },
+			func() error { return builder.SetAccount(addr1, common.Balance{12}, common.ToNonce(1)) },
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				t.Fatalf("failed to add entry: %v", err)
+			}
+		}
+
+		got, err := builder.Finish()
+		if err != nil {
+			t.Fatalf("failed to finish build: %v", err)
+		}
+		if got != want {
+			t.Errorf("unexpected hash, wanted %x, got %x", want, got)
+		}
+
+		if archive {
+			if err := mpt.VerifyArchive(dir, mpt.S5ArchiveConfig, nil); err != nil {
+				t.Fatalf("verification of built archive failed: %v", err)
+			}
+			db, err := mpt.OpenArchiveTrie(dir, mpt.S5ArchiveConfig, 1024)
+			if err != nil {
+				t.Fatalf("failed to open built archive: %v", err)
+			}
+			if height, empty, err := db.GetBlockHeight(); err != nil || empty || height != block {
+				t.Errorf("invalid block height, wanted %d, got %d, empty %t, err %v", block, height, empty, err)
+			}
+			if hash, err := db.GetHash(block); err != nil || hash != want {
+				t.Errorf("invalid archive hash, wanted %x, got %x, err %v", want, hash, err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatalf("failed to close archive: %v", err)
+			}
+		} else {
+			if err := mpt.VerifyFileLiveTrie(dir, mpt.S5LiveConfig, nil); err != nil {
+				t.Fatalf("verification of built LiveDB failed: %v", err)
+			}
+			db, err := mpt.OpenGoFileState(dir, mpt.S5LiveConfig, 1024)
+			if err != nil {
+				t.Fatalf("failed to open built LiveDB: %v", err)
+			}
+			if head, found := db.GetHeadBlock(); !found || head.Number != block {
+				t.Errorf("invalid head block, wanted %d, got %d, found %t", block, head.Number, found)
+			}
+			if code, err := db.GetCode(addr1); err != nil || !bytes.Equal(code, []byte("some_code")) {
+				t.Errorf("invalid code, got %v, err %v", code, err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatalf("failed to close LiveDB: %v", err)
+			}
+		}
+	}
+}
+
+func TestStateBuilder_NonEmptyTargetDirectoryFails(t *testing.T) {
+	dir := t.TempDir()
+	builder, err := NewLiveDbBuilder(dir, 0)
+	if err != nil {
+		t.Fatalf("failed to create builder: %v", err)
+	}
+	if err := builder.Abort(); err != nil {
+		t.Fatalf("failed to abort builder: %v", err)
+	}
+	if _, err := NewLiveDbBuilder(dir, 0); err == nil {
+		t.Errorf("creating a builder in a non-empty directory should fail")
+	}
+	if _, err := NewArchiveBuilder(dir, 0); err == nil {
+		t.Errorf("creating a builder in a non-empty directory should fail")
+	}
+}
+
+func TestStateBuilder_ClosedBuilderCanNotBeUsed(t *testing.T) {
+	builder, err := NewLiveDbBuilder(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("failed to create builder: %v", err)
+	}
+	if _, err := builder.Finish(); err != nil {
+		t.Fatalf("failed to finish build: %v", err)
+	}
+	if err := builder.SetAccount(common.Address{1}, common.Balance{1}, common.Nonce{}); err == nil {
+		t.Errorf("modifying a finished builder should fail")
+	}
+	if _, err := builder.Finish(); err == nil {
+		t.Errorf("finishing a builder twice should fail")
+	}
+	if err := builder.Abort(); err != nil {
+		t.Errorf("aborting a finished builder should be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+)
+
+// This file provides an export of the history of an archive as a stream of
+// flat, self-describing records intended for analytical tools. Unlike the
+// binary export format, it is not intended to be imported again.
+
+// ChangeField names the part of the state modified by a Change.
+type ChangeField string
+
+const (
+	FieldExists  ChangeField = "exists"  // < values are true or false
+	FieldBalance ChangeField = "balance" // < values are decimal numbers
+	FieldNonce   ChangeField = "nonce"   // < values are decimal numbers
+	FieldCode    ChangeField = "code"    // < values are hex-encoded code hashes
+	FieldStorage ChangeField = "storage" // < values are hex-encoded slot values
+)
+
+// Change is a single modification of an account or storage slot introduced
+// by a block. Old values are the values at the end of the preceding block,
+// or the values of an empty state for block 0.
+type Change struct {
+	Block   uint64
+	Address common.Address
+	Field   ChangeField
+	Key     *common.Key // < only set for storage changes
+	Old     string
+	New     string
+}
+
+// ChangeWriter writes changes in a specific format to an output stream.
+type ChangeWriter interface {
+	Write(change Change) error
+	// Flush writes buffered data to the underlying output stream.
+	Flush() error
+}
+
+// ChangeExportConfig defines the range and filter of an export of changes.
+type ChangeExportConfig struct {
+	From, To  uint64           // < the inclusive range of exported blocks
+	Addresses []common.Address // < if not empty, only changes of those accounts are exported
+}
+
+// ExportChanges writes all changes introduced by the blocks in the configured
+// range of the given archive to the given writer, ordered by block, address,
+// field, and slot key. The changes of each block are derived from the diff of
+// the block. If the export is restricted to a set of addresses, the diff only
+// covers the parts of the trie leading to those accounts, such that the costs
+// of the export grow with the number of selected accounts rather than with the
+// size of the blocks. Storage slots cleared by the deletion of an account are
+// not reported individually. Since the archive is not modified, multiple
+// exports of disjoint block ranges of the same archive may run in parallel.
+func ExportChanges(ctx context.Context, archive *mpt.ArchiveTrie, config ChangeExportConfig, out ChangeWriter) error {
+	if config.From > config.To {
+		return fmt.Errorf("invalid block range [%d, %d]", config.From, config.To)
+	}
+	height, empty, err := archive.GetBlockHeight()
+	if err != nil {
+		return err
+	}
+	if empty || config.To > height {
+		return fmt.Errorf("block %d not present in archive, block height is %d, empty %t", config.To, height, empty)
+	}
+
+	for block := config.From; block <= config.To; block++ {
+		var diff mpt.Diff
+		if len(config.Addresses) == 0 {
+			diff, err = archive.GetDiffForBlockWithContext(ctx, block)
+		} else {
+			diff, err = archive.GetAccountDiffForBlockWithContext(ctx, block, config.Addresses)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get diff of block %d: %w", block, err)
+		}
+		addresses := make([]common.Address, 0, len(diff))
+		for address := range diff {
+			addresses = append(addresses, address)
+		}
+		sort.Slice(addresses, func(i, j int) bool {
+			return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+		})
+		for _, address := range addresses {
+			changes, err := getAccountChanges(archive, block, address, diff[address])
+			if err != nil {
+				return fmt.Errorf("failed to get changes of account %x in block %d: %w", address, block, err)
+			}
+			for _, change := range changes {
+				if err := out.Write(change); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return out.Flush()
+}
+
+// getAccountChanges converts the diff of an account in the given block into
+// changes by looking up the old values in the preceding block.
+func getAccountChanges(archive *mpt.ArchiveTrie, block uint64, address common.Address, diff *mpt.AccountDiff) ([]Change, error) {
+	var (
+		existed    bool
+		oldBalance common.Balance
+		oldNonce   common.Nonce
+		oldCode    []byte
+		err        error
+	)
+	if block > 0 {
+		if existed, err = archive.Exists(block-1, address); err != nil {
+			return nil, err
+		}
+		if oldBalance, err = archive.GetBalance(block-1, address); err != nil {
+			return nil, err
+		}
+		if oldNonce, err = archive.GetNonce(block-1, address); err != nil {
+			return nil, err
+		}
+	}
+
+	newBalance, newNonce := oldBalance, oldNonce
+	if diff.Reset {
+		newBalance, newNonce = common.Balance{}, common.Nonce{}
+	}
+	if diff.Balance != nil {
+		newBalance = *diff.Balance
+	}
+	if diff.Nonce != nil {
+		newNonce = *diff.Nonce
+	}
+
+	res := []Change{}
+	add := func(field ChangeField, key *common.Key, before, after string) {
+		if before != after {
+			res = append(res, Change{Block: block, Address: address, Field: field, Key: key, Old: before, New: after})
+		}
+	}
+	add(FieldExists, nil, strconv.FormatBool(existed), strconv.FormatBool(!diff.Reset))
+	add(FieldBalance, nil, formatBalance(oldBalance), formatBalance(newBalance))
+	add(FieldNonce, nil, strconv.FormatUint(oldNonce.ToUint64(), 10), strconv.FormatUint(newNonce.ToUint64(), 10))
+
+	if diff.Reset || diff.Code != nil {
+		if block > 0 {
+			if oldCode, err = archive.GetCode(block-1, address); err != nil {
+				return nil, err
+			}
+		}
+		oldHash := common.Keccak256(oldCode)
+		newHash := common.Keccak256(nil)
+		if diff.Code != nil {
+			newHash = *diff.Code
+		}
+		add(FieldCode, nil, formatHex(oldHash[:]), formatHex(newHash[:]))
+	}
+
+	keys := make([]common.Key, 0, len(diff.Storage))
+	for key := range diff.Storage {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+	for _, key := range keys {
+		var old common.Value
+		if block > 0 {
+			if old, err = archive.GetStorage(block-1, address, key); err != nil {
+				return nil, err
+			}
+		}
+		value := diff.Storage[key]
+		key := key
+		add(FieldStorage, &key, formatHex(old[:]), formatHex(value[:]))
+	}
+	return res, nil
+}
+
+func formatBalance(balance common.Balance) string {
+	return balance.ToBigInt().String()
+}
+
+func formatHex(data []byte) string {
+	return fmt.Sprintf("0x%x", data)
+}
+
+// csvChangeWriter writes changes as comma separated values, starting with a
+// header line naming the columns.
+type csvChangeWriter struct {
+	out           *csv.Writer
+	headerWritten bool
+}
+
+// NewCsvChangeWriter creates a writer producing CSV records with the columns
+// block, address, field, key, old, and new. The key column is empty for all
+// but storage changes.
+func NewCsvChangeWriter(out io.Writer) ChangeWriter {
+	return &csvChangeWriter{out: csv.NewWriter(out)}
+}
+
+func (w *csvChangeWriter) Write(change Change) error {
+	if !w.headerWritten {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	key := ""
+	if change.Key != nil {
+		key = formatHex(change.Key[:])
+	}
+	return w.out.Write([]string{
+		strconv.FormatUint(change.Block, 10),
+		formatHex(change.Address[:]),
+		string(change.Field),
+		key,
+		change.Old,
+		change.New,
+	})
+}
+
+func (w *csvChangeWriter) Flush() error {
+	if !w.headerWritten {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	w.out.Flush()
+	return w.out.Error()
+}
+
+func (w *csvChangeWriter) writeHeader() error {
+	w.headerWritten = true
+	return w.out.Write([]string{"block", "address", "field", "key", "old", "new"})
+}
+
+// jsonChangeWriter writes changes as JSON Lines, one object per change.
+type jsonChangeWriter struct {
+	out *json.Encoder
+}
+
+// jsonChange is the JSON representation of a change.
+type jsonChange struct {
+	Block   uint64 `json:"block"`
+	Address string `json:"address"`
+	Field   string `json:"field"`
+	Key     string `json:"key,omitempty"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+// NewJsonChangeWriter creates a writer producing one JSON object per line
+// with the properties block, address, field, key, old, and new. The key
+// property is only present for storage changes.
+func NewJsonChangeWriter(out io.Writer) ChangeWriter {
+	return &jsonChangeWriter{out: json.NewEncoder(out)}
+}
+
+func (w *jsonChangeWriter) Write(change Change) error {
+	record := jsonChange{
+		Block:   change.Block,
+		Address: formatHex(change.Address[:]),
+		Field:   string(change.Field),
+		Old:     change.Old,
+		New:     change.New,
+	}
+	if change.Key != nil {
+		record.Key = formatHex(change.Key[:])
+	}
+	return w.out.Encode(&record)
+}
+
+func (w *jsonChangeWriter) Flush() error {
+	return nil
+}
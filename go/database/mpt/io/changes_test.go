@@ -0,0 +1,212 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+)
+
+func createArchiveWithChanges(t *testing.T) *mpt.ArchiveTrie {
+	t.Helper()
+	archive, err := mpt.OpenArchiveTrie(t.TempDir(), mpt.S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	t.Cleanup(func() { archive.Close() })
+
+	updates := []common.Update{
+		{
+			CreatedAccounts: []common.Address{{1}, {2}},
+			Balances:        []common.BalanceUpdate{{Account: common.Address{1}, Balance: common.Balance{31: 10}}},
+			Nonces:          []common.NonceUpdate{{Account: common.Address{2}, Nonce: common.ToNonce(3)}},
+			Codes:           []common.CodeUpdate{{Account: common.Address{2}, Code: []byte{1, 2}}},
+			Slots:           []common.SlotUpdate{{Account: common.Address{2}, Key: common.Key{7}, Value: common.Value{31: 1}}},
+		},
+		{
+			Balances: []common.BalanceUpdate{{Account: common.Address{1}, Balance: common.Balance{31: 12}}},
+			Slots:    []common.SlotUpdate{{Account: common.Address{2}, Key: common.Key{7}, Value: common.Value{31: 2}}},
+		},
+		{
+			DeletedAccounts: []common.Address{{2}},
+		},
+	}
+	for i, update := range updates {
+		if err := archive.Add(uint64(i), update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", i, err)
+		}
+	}
+	return archive
+}
+
+// changeCollector is a ChangeWriter collecting all written changes.
+type changeCollector struct {
+	changes []string
+	flushed bool
+}
+
+func (c *changeCollector) Write(change Change) error {
+	key := ""
+	if change.Key != nil {
+		key = fmt.Sprintf("%02x", change.Key[0])
+	}
+	c.changes = append(c.changes, fmt.Sprintf("%d %02x %s %s %s->%s", change.Block, change.Address[0], change.Field, key, change.Old, change.New))
+	return nil
+}
+
+func (c *changeCollector) Flush() error {
+	c.flushed = true
+	return nil
+}
+
+func TestExportChanges_ChangesOfAllBlocksAreReported(t *testing.T) {
+	archive := createArchiveWithChanges(t)
+	emptyCode := "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"
+	code := fmt.Sprintf("0x%x", common.Keccak256([]byte{1, 2}))
+
+	out := &changeCollector{}
+	if err := ExportChanges(context.Background(), archive, ChangeExportConfig{From: 0, To: 2}, out); err != nil {
+		t.Fatalf("failed to export changes: %v", err)
+	}
+	want := []string{
+		"0 01 exists  false->true",
+		"0 01 balance  0->10",
+		"0 02 exists  false->true",
+		"0 02 nonce  0->3",
+		"0 02 code  " + emptyCode + "->" + code,
+		"0 02 storage 07 0x" + strings.Repeat("0", 64) + "->0x" + strings.Repeat("0", 63) + "1",
+		"1 01 balance  10->12",
+		"1 02 storage 07 0x" + strings.Repeat("0", 63) + "1->0x" + strings.Repeat("0", 63) + "2",
+		"2 02 exists  true->false",
+		"2 02 nonce  3->0",
+		"2 02 code  " + code + "->" + emptyCode,
+	}
+	if got := strings.Join(out.changes, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected changes, wanted\n%s\ngot\n%s", strings.Join(want, "\n"), got)
+	}
+	if !out.flushed {
+		t.Errorf("writer should be flushed")
+	}
+}
+
+func TestExportChanges_BlockRangeAndAddressesAreFiltered(t *testing.T) {
+	archive := createArchiveWithChanges(t)
+
+	out := &changeCollector{}
+	config := ChangeExportConfig{From: 1, To: 2, Addresses: []common.Address{{2}}}
+	if err := ExportChanges(context.Background(), archive, config, out); err != nil {
+		t.Fatalf("failed to export changes: %v", err)
+	}
+	if len(out.changes) != 4 {
+		t.Fatalf("unexpected number of changes, wanted 4, got %d: %v", len(out.changes), out.changes)
+	}
+	for _, change := range out.changes {
+		if !strings.HasPrefix(change, "1 02") && !strings.HasPrefix(change, "2 02") {
+			t.Errorf("change should have been filtered: %s", change)
+		}
+	}
+}
+
+func TestExportChanges_InvalidRangesAreRejected(t *testing.T) {
+	archive := createArchiveWithChanges(t)
+	for _, config := range []ChangeExportConfig{{From: 2, To: 1}, {From: 0, To: 3}} {
+		if err := ExportChanges(context.Background(), archive, config, &changeCollector{}); err == nil {
+			t.Errorf("range [%d, %d] should be rejected", config.From, config.To)
+		}
+	}
+}
+
+func TestExportChanges_CancelledExportFails(t *testing.T) {
+	archive := createArchiveWithChanges(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ExportChanges(ctx, archive, ChangeExportConfig{From: 0, To: 2}, &changeCollector{}); err == nil {
+		t.Errorf("cancelled export should fail")
+	}
+}
+
+func TestCsvChangeWriter_WritesHeaderAndRecords(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	writer := NewCsvChangeWriter(buffer)
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	if want, got := "block,address,field,key,old,new\n", buffer.String(); want != got {
+		t.Errorf("empty output should contain header, wanted %q, got %q", want, got)
+	}
+
+	buffer.Reset()
+	writer = NewCsvChangeWriter(buffer)
+	key := common.Key{31: 1}
+	changes := []Change{
+		{Block: 5, Address: common.Address{19: 1}, Field: FieldBalance, Old: "1", New: "2"},
+		{Block: 6, Address: common.Address{19: 2}, Field: FieldStorage, Key: &key, Old: "0x00", New: "0x01"},
+	}
+	for _, change := range changes {
+		if err := writer.Write(change); err != nil {
+			t.Fatalf("failed to write change: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	want := "block,address,field,key,old,new\n" +
+		"5,0x0000000000000000000000000000000000000001,balance,,1,2\n" +
+		"6,0x0000000000000000000000000000000000000002,storage,0x0000000000000000000000000000000000000000000000000000000000000001,0x00,0x01\n"
+	if got := buffer.String(); want != got {
+		t.Errorf("unexpected output, wanted\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestJsonChangeWriter_WritesOneObjectPerLine(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	writer := NewJsonChangeWriter(buffer)
+	key := common.Key{31: 1}
+	changes := []Change{
+		{Block: 5, Address: common.Address{19: 1}, Field: FieldNonce, Old: "1", New: "2"},
+		{Block: 6, Address: common.Address{19: 2}, Field: FieldStorage, Key: &key, Old: "0x00", New: "0x01"},
+	}
+	for _, change := range changes {
+		if err := writer.Write(change); err != nil {
+			t.Fatalf("failed to write change: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected number of lines, wanted 2, got %d", len(lines))
+	}
+	for i, line := range lines {
+		record := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("failed to parse line %q: %v", line, err)
+		}
+		if got, want := record["block"], float64(changes[i].Block); got != want {
+			t.Errorf("unexpected block, wanted %v, got %v", want, got)
+		}
+		if got, want := record["field"], string(changes[i].Field); got != want {
+			t.Errorf("unexpected field, wanted %v, got %v", want, got)
+		}
+		if _, found := record["key"]; found != (changes[i].Key != nil) {
+			t.Errorf("key should only be present for storage changes: %s", line)
+		}
+	}
+}
@@ -12,6 +12,7 @@ package io
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -56,6 +57,12 @@ const (
 // information required by the Import function below to reconstruct the full
 // state of the LiveDB.
 func Export(directory string, out io.Writer) error {
+	return ExportWithContext(context.Background(), directory, out)
+}
+
+// ExportWithContext is like Export but aborts the export with the context's
+// error once the given context is done.
+func ExportWithContext(ctx context.Context, directory string, out io.Writer) error {
 
 	info, err := CheckMptDirectoryAndGetInfo(directory)
 	if err != nil {
@@ -72,6 +79,31 @@ func Export(directory string, out io.Writer) error {
 	}
 	defer db.Close()
 
+	return export(ctx, db, out)
+}
+
+// ExportBlockFromArchive writes the state of the given block retained in the
+// given archive to the given output writer. The result has the same format as
+// the output of the Export function and can thus be used to initialize
+// LiveDB and Archive instances using ImportLiveDb and InitializeArchive.
+func ExportBlockFromArchive(archive *mpt.ArchiveTrie, block uint64, out io.Writer) error {
+	return ExportBlockFromArchiveWithContext(context.Background(), archive, block, out)
+}
+
+// ExportBlockFromArchiveWithContext is like ExportBlockFromArchive but aborts
+// the export with the context's error once the given context is done.
+func ExportBlockFromArchiveWithContext(ctx context.Context, archive *mpt.ArchiveTrie, block uint64, out io.Writer) error {
+	return export(ctx, archiveBlock{archive, block}, out)
+}
+
+// exportSource is the state information required for exporting a state.
+type exportSource interface {
+	GetHash() (common.Hash, error)
+	GetCodes() (map[common.Hash][]byte, error)
+	VisitWithContext(context.Context, mpt.NodeVisitor) error
+}
+
+func export(ctx context.Context, db exportSource, out io.Writer) error {
 	// Start with the magic number.
 	if _, err := out.Write(stateMagicNumber); err != nil {
 		return err
@@ -105,17 +137,43 @@ func Export(directory string, out io.Writer) error {
 
 	// Write out all accounts and values.
 	visitor := exportVisitor{out: out}
-	if err := db.Visit(&visitor); err != nil || visitor.err != nil {
-		return fmt.Errorf("failed exporting content: %v", errors.Join(err, visitor.err))
+	if err := db.VisitWithContext(ctx, &visitor); err != nil || visitor.err != nil {
+		return fmt.Errorf("failed exporting content: %w", errors.Join(err, visitor.err))
 	}
 
 	return nil
 }
 
+// archiveBlock is an adapter exporting the state of a single block of an
+// archive.
+type archiveBlock struct {
+	archive *mpt.ArchiveTrie
+	block   uint64
+}
+
+func (a archiveBlock) GetHash() (common.Hash, error) {
+	return a.archive.GetHash(a.block)
+}
+
+func (a archiveBlock) GetCodes() (map[common.Hash][]byte, error) {
+	return a.archive.GetCodes()
+}
+
+func (a archiveBlock) VisitWithContext(ctx context.Context, visitor mpt.NodeVisitor) error {
+	return a.archive.VisitTrieWithContext(ctx, a.block, visitor)
+}
+
 // ImportLiveDb creates a fresh StateDB in the given directory and fills it
 // with the content read from the given reader.
 func ImportLiveDb(directory string, in io.Reader) error {
-	_, _, err := runImport(directory, in, mpt.S5LiveConfig)
+	return ImportLiveDbWithContext(context.Background(), directory, in)
+}
+
+// ImportLiveDbWithContext is like ImportLiveDb but aborts the import with the
+// context's error once the given context is done. The content of the target
+// directory is undefined after an aborted import.
+func ImportLiveDbWithContext(ctx context.Context, directory string, in io.Reader) error {
+	_, _, err := runImport(ctx, directory, in, mpt.S5LiveConfig)
 	return err
 }
 
@@ -123,12 +181,27 @@ func ImportLiveDb(directory string, in io.Reader) error {
 // the state read from the input stream at the given block. All states before
 // the given block are empty.
 func InitializeArchive(directory string, in io.Reader, block uint64) (err error) {
+	return InitializeArchiveWithContext(context.Background(), directory, in, block)
+}
+
+// InitializeArchiveWithContext is like InitializeArchive but aborts the
+// initialization with the context's error once the given context is done.
+// The content of the target directory is undefined after an aborted import.
+func InitializeArchiveWithContext(ctx context.Context, directory string, in io.Reader, block uint64) (err error) {
 	// The import creates a live-DB state that initializes the Archive.
-	root, hash, err := runImport(directory, in, mpt.S5ArchiveConfig)
+	root, hash, err := runImport(ctx, directory, in, mpt.S5ArchiveConfig)
 	if err != nil {
 		return err
 	}
 
+	return sealArchive(directory, root, hash, block)
+}
+
+// sealArchive converts the LiveDB-like state in the given directory into an
+// immutable archive with the given root as the state of the given block. All
+// states before the given block are empty. The metadata of the archive, like
+// its state statistics and change index, is created for the sealed blocks.
+func sealArchive(directory string, root mpt.NodeId, hash common.Hash, block uint64) error {
 	// Seal the data by marking the content as immutable.
 	forestFile := directory + string(os.PathSeparator) + "forest.json"
 	metaData, err := os.ReadFile(forestFile)
@@ -155,10 +228,10 @@ func InitializeArchive(directory string, in io.Reader, block uint64) (err error)
 	if err := mpt.StoreRoots(directory+string(os.PathSeparator)+"roots.dat", roots); err != nil {
 		return err
 	}
-	return nil
+	return mpt.InitializeArchiveMetadata(directory, mpt.S5ArchiveConfig)
 }
 
-func runImport(directory string, in io.Reader, config mpt.MptConfig) (root mpt.NodeId, hash common.Hash, err error) {
+func runImport(ctx context.Context, directory string, in io.Reader, config mpt.MptConfig) (root mpt.NodeId, hash common.Hash, err error) {
 	// check that the destination directory is an empty directory
 	if err := checkEmptyDirectory(directory); err != nil {
 		return root, hash, err
@@ -207,6 +280,10 @@ func runImport(directory string, in io.Reader, config mpt.MptConfig) (root mpt.N
 	hashFound := false
 	var stateHash common.Hash
 	for {
+		if err := ctx.Err(); err != nil {
+			return root, hash, err
+		}
+
 		// Update hashes periodically to avoid running out of memory
 		// for nodes with dirty hashes.
 		counter++
@@ -12,8 +12,10 @@ package io
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -93,6 +95,25 @@ func TestIO_ExportAndImportAsArchive(t *testing.T) {
 			t.Fatalf("invalid hash for pre-genesis block %d\nwanted %x\n   got %x\n   err %v", i, mpt.EmptyNodeEthereumHash, got, err)
 		}
 	}
+
+	stats, err := db.GetStateStats(genesisBlock-1, genesisBlock)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	want := []mpt.StateStats{{}, {NumAccounts: 2, NumSlots: 3, CodeBytes: uint64(len("some_code"))}}
+	if !slices.Equal(want, stats) {
+		t.Errorf("unexpected state statistics, wanted %v, got %v", want, stats)
+	}
+
+	for _, address := range []common.Address{{1}, {2}} {
+		history, err := db.GetAccountHistory(address, 0, genesisBlock)
+		if err != nil {
+			t.Fatalf("failed to get account history: %v", err)
+		}
+		if len(history) != 1 || history[0].Block != genesisBlock {
+			t.Errorf("unexpected history of account %v, wanted a single change in block %d, got %v", address, genesisBlock, history)
+		}
+	}
 }
 
 func exportExampleState(t *testing.T) ([]byte, common.Hash) {
@@ -211,3 +232,75 @@ func TestCheckEmptyDirectory_FailsIfDirectoryContainsADirectory(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestIO_ExportBlockFromArchiveAndImportAsLiveDb(t *testing.T) {
+	archive, err := mpt.OpenArchiveTrie(t.TempDir(), mpt.S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	fillTestBlocksIntoArchive(t, archive)
+
+	for _, block := range []uint64{0, 3, 5, 7} {
+		want, err := archive.GetHash(block)
+		if err != nil {
+			t.Fatalf("failed to get hash of block %d: %v", block, err)
+		}
+
+		buffer := new(bytes.Buffer)
+		if err := ExportBlockFromArchive(archive, block, buffer); err != nil {
+			t.Fatalf("failed to export block %d: %v", block, err)
+		}
+
+		targetDir := t.TempDir()
+		if err := ImportLiveDb(targetDir, buffer); err != nil {
+			t.Fatalf("failed to import block %d: %v", block, err)
+		}
+
+		db, err := mpt.OpenGoFileState(targetDir, mpt.S5LiveConfig, 1024)
+		if err != nil {
+			t.Fatalf("failed to open imported DB: %v", err)
+		}
+		if got, err := db.GetHash(); err != nil || got != want {
+			t.Errorf("imported DB of block %d failed to reproduce hash\nwanted %x\n   got %x\n   err %v", block, want, got, err)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatalf("failed to close imported DB: %v", err)
+		}
+	}
+}
+
+func TestIO_ExportBlockFromArchive_MissingBlockIsReported(t *testing.T) {
+	archive, err := mpt.OpenArchiveTrie(t.TempDir(), mpt.S5ArchiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	fillTestBlocksIntoArchive(t, archive)
+
+	if err := ExportBlockFromArchive(archive, 8, new(bytes.Buffer)); err == nil {
+		t.Errorf("exporting a missing block should fail")
+	}
+}
+
+func TestIO_CancelledExportAndImportFail(t *testing.T) {
+	genesis, _ := exportExampleState(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := ImportLiveDbWithContext(ctx, t.TempDir(), bytes.NewBuffer(genesis)); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+	if err := InitializeArchiveWithContext(ctx, t.TempDir(), bytes.NewBuffer(genesis), 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+
+	sourceDir := t.TempDir()
+	if err := ImportLiveDb(sourceDir, bytes.NewBuffer(genesis)); err != nil {
+		t.Fatalf("failed to import DB: %v", err)
+	}
+	if err := ExportWithContext(ctx, sourceDir, new(bytes.Buffer)); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+}
@@ -11,10 +11,12 @@
 package mpt
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"unsafe"
 
 	"github.com/Fantom-foundation/Carmen/go/common"
@@ -33,6 +35,15 @@ type LiveTrie struct {
 	root NodeReference
 	// The file name for storing trie metadata.
 	metadatafile string
+	// The last block applied to the trie, nil if unknown.
+	headBlock *HeadBlock
+	headMutex sync.Mutex
+}
+
+// HeadBlock describes the last block applied to a LiveTrie.
+type HeadBlock struct {
+	Number uint64
+	Record []byte // < an opaque, user-provided description of the block
 }
 
 // OpenInMemoryLiveTrie loads trie information from the given directory and
@@ -94,6 +105,7 @@ func makeTrie(
 		root:         NewNodeReference(metadata.RootNode),
 		metadatafile: metadatafile,
 		forest:       forest,
+		headBlock:    metadata.HeadBlock,
 	}, nil
 }
 
@@ -148,10 +160,35 @@ func (s *LiveTrie) setHashes(hashes *NodeHashes) error {
 	return s.forest.setHashesFor(&s.root, hashes)
 }
 
+// GetHeadBlock returns the last block applied to this trie. The found flag is
+// false if no block was recorded yet.
+func (s *LiveTrie) GetHeadBlock() (block HeadBlock, found bool) {
+	s.headMutex.Lock()
+	defer s.headMutex.Unlock()
+	if s.headBlock == nil {
+		return HeadBlock{}, false
+	}
+	return *s.headBlock, true
+}
+
+// setHeadBlock records the last block applied to this trie. It is persisted
+// with the trie's metadata on the next flush.
+func (s *LiveTrie) setHeadBlock(block HeadBlock) {
+	s.headMutex.Lock()
+	defer s.headMutex.Unlock()
+	s.headBlock = &block
+}
+
 func (s *LiveTrie) VisitTrie(visitor NodeVisitor) error {
 	return s.forest.VisitTrie(&s.root, visitor)
 }
 
+// VisitTrieWithContext is like VisitTrie but aborts the visit with the
+// context's error once the given context is done.
+func (s *LiveTrie) VisitTrieWithContext(ctx context.Context, visitor NodeVisitor) error {
+	return visitWithContext(ctx, visitor, s.VisitTrie)
+}
+
 func (s *LiveTrie) Flush() error {
 	// Update hashes to eliminate dirty hashes before flushing.
 	hash, _, err := s.UpdateHashes()
@@ -160,10 +197,13 @@ func (s *LiveTrie) Flush() error {
 	}
 
 	// Update on-disk meta-data.
+	s.headMutex.Lock()
 	metadata, err := json.Marshal(metadata{
-		RootNode: s.root.Id(),
-		RootHash: hash,
+		RootNode:  s.root.Id(),
+		RootHash:  hash,
+		HeadBlock: s.headBlock,
 	})
+	s.headMutex.Unlock()
 
 	if err == nil {
 		if err := os.WriteFile(s.metadatafile, metadata, 0600); err != nil {
@@ -188,6 +228,22 @@ func (s *LiveTrie) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// GetDiskFootprint provides sizes of individual components of the trie on disk.
+func (s *LiveTrie) GetDiskFootprint() (*common.DiskFootprint, error) {
+	forest, err := getDiskFootprint(s.forest)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := common.GetFileDiskFootprint(s.metadatafile)
+	if err != nil {
+		return nil, err
+	}
+	df := common.NewDiskFootprint(0)
+	df.AddChild("forest", forest)
+	df.AddChild("metadata", metadata)
+	return df, nil
+}
+
 // Dump prints the content of the Trie to the console. Mainly intended for debugging.
 func (s *LiveTrie) Dump() {
 	s.forest.Dump(&s.root)
@@ -205,8 +261,9 @@ func (s *LiveTrie) Check() error {
 
 // metadata is the helper type to read and write metadata from/to the disk.
 type metadata struct {
-	RootNode NodeId
-	RootHash common.Hash
+	RootNode  NodeId
+	RootHash  common.Hash
+	HeadBlock *HeadBlock `json:",omitempty"`
 }
 
 // readMetadata parses the content of the given file if it exists or returns
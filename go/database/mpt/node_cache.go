@@ -99,10 +99,14 @@ type NodeCache interface {
 type nodeCache struct {
 	owners     []nodeOwner              // fixed length list of all owned nodes
 	index      map[NodeId]ownerPosition // an index on the owned nodes
+	limit      int                      // the maximum number of owned nodes, at most len(owners)
+	free       []ownerPosition          // owners released by lowering the limit
 	tagCounter uint64                   // a counter to generate fresh tags
 	head       ownerPosition            // head of the LRU list of owners
 	tail       ownerPosition            // tail of the LRU list of owners
 	mutex      sync.Mutex               // for everything except the owner list
+	hits       atomic.Uint64            // number of successful lookups
+	misses     atomic.Uint64            // number of failed lookups
 }
 
 func NewNodeCache(capacity int) NodeCache {
@@ -116,6 +120,7 @@ func newNodeCache(capacity int) *nodeCache {
 	return &nodeCache{
 		owners: make([]nodeOwner, capacity),
 		index:  make(map[NodeId]ownerPosition, capacity),
+		limit:  capacity,
 	}
 }
 
@@ -137,6 +142,7 @@ func (c *nodeCache) Get(r *NodeReference) (*shared.Shared[Node], bool) {
 			position, found := c.index[r.id]
 			if !found {
 				c.mutex.Unlock()
+				c.misses.Add(1)
 				return nil, false
 			}
 			pos = uint32(position)
@@ -150,6 +156,7 @@ func (c *nodeCache) Get(r *NodeReference) (*shared.Shared[Node], bool) {
 		res := owner.Node()
 		// Check that the tag is still correct and the fetched result is valid.
 		if owner.tag.Load() == tag {
+			c.hits.Add(1)
 			return res, true
 		}
 		// If the tag has changed the position is out-dated and the true owner
@@ -181,7 +188,7 @@ func (c *nodeCache) GetOrSet(
 	// If not present, the capacity needs to be checked.
 	var pos ownerPosition
 	var target *nodeOwner
-	if len(c.index) >= len(c.owners) {
+	if len(c.index) >= c.limit {
 		// an element needs to be evicted
 		pos = c.tail
 
@@ -194,9 +201,15 @@ func (c *nodeCache) GetOrSet(
 		evictedNode = target.Node()
 		evicted = true
 
+	} else if len(c.free) > 0 {
+		// re-use an owner released by lowering the limit
+		pos = c.free[len(c.free)-1]
+		c.free = c.free[:len(c.free)-1]
+		target = &c.owners[pos]
 	} else {
-		// start using a new node from the owner list
-		pos = ownerPosition(len(c.index))
+		// start using a new node from the owner list; all owners below
+		// this position are either in use or in the free list
+		pos = ownerPosition(len(c.index) + len(c.free))
 		target = &c.owners[pos]
 	}
 
@@ -231,7 +244,8 @@ func (c *nodeCache) Touch(r *NodeReference) {
 	}
 	target := &c.owners[pos]
 	c.mutex.Lock()
-	if c.head == pos {
+	if c.head == pos || target.tag.Load() == 0 {
+		// The owner is already at the head or has been released.
 		c.mutex.Unlock()
 		return
 	}
@@ -261,7 +275,8 @@ func (c *nodeCache) Release(r *NodeReference) {
 	}
 	target := &c.owners[pos]
 	c.mutex.Lock()
-	if c.tail == pos {
+	if c.tail == pos || target.tag.Load() == 0 {
+		// The owner is already at the tail or has been released.
 		c.mutex.Unlock()
 		return
 	}
@@ -335,6 +350,58 @@ func (c *nodeCache) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// setLimit changes the maximum number of nodes retained by this cache. The
+// limit is clamped to the range between 1 and the capacity the cache was
+// created with. Nodes evicted due to a lowered limit are returned.
+func (c *nodeCache) setLimit(limit int) []evictedNode {
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > len(c.owners) {
+		limit = len(c.owners)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.limit = limit
+	var evicted []evictedNode
+	for len(c.index) > limit {
+		pos := c.tail
+		target := &c.owners[pos]
+		delete(c.index, target.Id())
+		c.tail = target.prev
+		evicted = append(evicted, evictedNode{id: target.Id(), node: target.Node()})
+
+		// The tag needs to be invalidated first to make concurrent lookups
+		// aware of the modification before the owner is cleared.
+		target.tag.Store(0)
+		target.id.Store(0)
+		target.node.Store(nil)
+		c.free = append(c.free, pos)
+	}
+	return evicted
+}
+
+// getStatistics summarizes the usage of this cache. Memory related
+// properties are left to be filled in by the caller.
+func (c *nodeCache) getStatistics() common.CacheStatistics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return common.CacheStatistics{
+		Capacity:    c.limit,
+		MaxCapacity: len(c.owners),
+		Size:        len(c.index),
+		Overhead:    uint64(unsafe.Sizeof(*c) + unsafe.Sizeof(nodeOwner{})*uintptr(len(c.owners))),
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+	}
+}
+
+// evictedNode is a node removed from a node cache.
+type evictedNode struct {
+	id   NodeId
+	node *shared.Shared[Node]
+}
+
 func (c *nodeCache) getIdsInReverseEvictionOrder() []NodeId {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -425,3 +425,111 @@ func TestNodeCache_GetAndSetThreadSafety(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestNodeCache_SetLimit_EvictsLeastRecentlyUsedNodes(t *testing.T) {
+	cache := newNodeCache(4)
+
+	refs := []NodeReference{}
+	nodes := []*shared.Shared[Node]{}
+	for i := 0; i < 4; i++ {
+		refs = append(refs, NewNodeReference(ValueId(uint64(i))))
+		nodes = append(nodes, shared.MakeShared[Node](EmptyNode{}))
+		cache.GetOrSet(&refs[i], nodes[i])
+	}
+
+	evicted := cache.setLimit(2)
+	if len(evicted) != 2 {
+		t.Fatalf("unexpected number of evicted nodes, wanted 2, got %d", len(evicted))
+	}
+	for i, cur := range evicted {
+		if cur.id != refs[i].Id() || cur.node != nodes[i] {
+			t.Errorf("unexpected evicted node, wanted %v, got %v", refs[i].Id(), cur.id)
+		}
+	}
+	if want, got := "[V-3 V-2]", fmt.Sprintf("%v", cache.getIdsInReverseEvictionOrder()); want != got {
+		t.Errorf("unexpected eviction order, wanted %s, got %s", want, got)
+	}
+	for i := 0; i < 2; i++ {
+		if _, found := cache.Get(&refs[i]); found {
+			t.Errorf("node %v should be evicted", refs[i].Id())
+		}
+	}
+
+	// The limit is respected by new insertions.
+	if _, _, evictedId, _, evicted := cache.GetOrSet(&refs[0], nodes[0]); !evicted || evictedId != refs[2].Id() {
+		t.Errorf("insertion should evict %v, evicted %t, got %v", refs[2].Id(), evicted, evictedId)
+	}
+
+	// Raising the limit again re-uses released owners.
+	cache.setLimit(10)
+	for i := 1; i < 4; i++ {
+		if _, _, _, _, evicted := cache.GetOrSet(&refs[i], nodes[i]); evicted {
+			t.Errorf("no node should be evicted after raising the limit")
+		}
+	}
+	if want, got := "[V-2 V-1 V-0 V-3]", fmt.Sprintf("%v", cache.getIdsInReverseEvictionOrder()); want != got {
+		t.Errorf("unexpected eviction order, wanted %s, got %s", want, got)
+	}
+	for i := range refs {
+		if got, found := cache.Get(&refs[i]); !found || got != nodes[i] {
+			t.Errorf("failed to retrieve node %v", refs[i].Id())
+		}
+	}
+}
+
+func TestNodeCache_SetLimit_IsClampedToCapacity(t *testing.T) {
+	cache := newNodeCache(4)
+	for limit, want := range map[int]int{-1: 1, 0: 1, 1: 1, 3: 3, 4: 4, 5: 4} {
+		cache.setLimit(limit)
+		if got := cache.getStatistics().Capacity; got != want {
+			t.Errorf("unexpected capacity for limit %d, wanted %d, got %d", limit, want, got)
+		}
+	}
+}
+
+func TestNodeCache_GetStatistics_CountsHitsAndMisses(t *testing.T) {
+	cache := newNodeCache(4)
+	ref := NewNodeReference(ValueId(1))
+	cache.Get(&ref)
+	cache.GetOrSet(&ref, shared.MakeShared[Node](EmptyNode{}))
+	cache.Get(&ref)
+	cache.Get(&ref)
+
+	stats := cache.getStatistics()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("unexpected lookup counts, wanted 2/1, got %d/%d", stats.Hits, stats.Misses)
+	}
+	if stats.Size != 1 || stats.Capacity != 4 || stats.MaxCapacity != 4 {
+		t.Errorf("unexpected statistics: %+v", stats)
+	}
+}
+
+func TestNodeCache_SetLimitThreadSafety(t *testing.T) {
+	cache := newNodeCache(16)
+	N := 20
+	var wg sync.WaitGroup
+	wg.Add(N + 1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cache.setLimit(1 + i%16)
+		}
+	}()
+	for i := 0; i < N; i++ {
+		go func(i int) {
+			defer wg.Done()
+			id := ValueId(uint64(i))
+			node := shared.MakeShared[Node](EmptyNode{})
+			for j := 0; j < 1000; j++ {
+				ref := NewNodeReference(id)
+				got, _, _, _, _ := cache.GetOrSet(&ref, node)
+				if got != node {
+					t.Errorf("Invalid element in cache, wanted %p, got %p for ID %v", node, got, id)
+				}
+				cache.Touch(&ref)
+				cache.Release(&ref)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -243,6 +243,27 @@ type NodeManager interface {
 	releaseTrieAsynchronous(NodeReference)
 }
 
+// dirtyNodeTracker is an optional extension of a NodeManager counting the
+// nodes modified since they were last written to disk. Nodes report their
+// transitions between the clean and the dirty state to their manager.
+type dirtyNodeTracker interface {
+	nodeMarkedDirty()
+	nodeMarkedClean()
+}
+
+// stateSizeObserver is an optional extension of a NodeManager informed about
+// accounts and storage values inserted into or deleted from tries. Storage
+// tries discarded as a whole are not reported value by value.
+type stateSizeObserver interface {
+	stateSizeChanged(accounts, values int)
+}
+
+func reportStateSizeChange(manager NodeManager, accounts, values int) {
+	if observer, ok := manager.(stateSizeObserver); ok {
+		observer.stateSizeChanged(accounts, values)
+	}
+}
+
 // ----------------------------------------------------------------------------
 //                               Utilities
 // ----------------------------------------------------------------------------
@@ -529,13 +550,23 @@ func (n *nodeBase) MarkClean() {
 	n.clean = true
 }
 
-func (n *nodeBase) markDirty() {
+func (n *nodeBase) markDirty(manager NodeManager) {
+	if n.clean {
+		if tracker, ok := manager.(dirtyNodeTracker); ok {
+			tracker.nodeMarkedDirty()
+		}
+	}
 	n.clean = false
 	n.hashStatus = hashStatusDirty
 }
 
-func (n *nodeBase) Release() {
+func (n *nodeBase) Release(manager NodeManager) {
 	// The node is disconnected from the disk version and thus clean.
+	if !n.clean {
+		if tracker, ok := manager.(dirtyNodeTracker); ok {
+			tracker.nodeMarkedClean()
+		}
+	}
 	n.clean = true
 	n.hashStatus = hashStatusClean
 }
@@ -582,10 +613,11 @@ func (e EmptyNode) SetAccount(manager NodeManager, thisRef *NodeReference, this
 	}
 	defer handle.Release()
 	res := handle.Get().(*AccountNode)
-	res.markDirty()
+	res.markDirty(manager)
 	res.address = address
 	res.info = info
 	res.pathLength = byte(len(path))
+	reportStateSizeChange(manager, 1, 0)
 	return ref, false, nil
 }
 
@@ -601,8 +633,9 @@ func (e EmptyNode) SetValue(manager NodeManager, thisRef *NodeReference, this sh
 	res := handle.Get().(*ValueNode)
 	res.key = key
 	res.value = value
-	res.markDirty()
+	res.markDirty(manager)
 	res.pathLength = byte(len(path))
+	reportStateSizeChange(manager, 0, 1)
 	return ref, true, nil
 }
 
@@ -739,7 +772,7 @@ func (n *BranchNode) setNextNode(
 
 	if newRoot.Id() == child.Id() {
 		if hasChanged {
-			n.markDirty()
+			n.markDirty(manager)
 			n.markChildHashDirty(byte(path[0]))
 		}
 		return *thisRef, hasChanged, nil
@@ -755,7 +788,7 @@ func (n *BranchNode) setNextNode(
 		defer handle.Release()
 		newNode := handle.Get().(*BranchNode)
 		*newNode = *n
-		newNode.markDirty()
+		newNode.markDirty(manager)
 		newNode.markMutable()
 		n = newNode
 		thisRef = &newRef
@@ -811,7 +844,7 @@ func (n *BranchNode) setNextNode(
 				}
 
 				extensionNode.path.Prepend(remainingPos)
-				extensionNode.markDirty()
+				extensionNode.markDirty(manager)
 			} else if remaining.Id().IsBranch() {
 				// An extension needs to replace this branch.
 				extensionRef, handle, err := manager.createExtension()
@@ -827,7 +860,7 @@ func (n *BranchNode) setNextNode(
 					extension.nextIsEmbedded = n.isEmbedded(byte(remainingPos))
 					extension.nextHash = n.hashes[byte(remainingPos)]
 				}
-				extension.markDirty()
+				extension.markDirty(manager)
 				newRoot = extensionRef
 			} else if manager.getConfig().TrackSuffixLengthsInLeafNodes {
 				// If suffix lengths need to be tracked, leaf nodes require an update.
@@ -853,12 +886,12 @@ func (n *BranchNode) setNextNode(
 					}
 				}
 			}
-			n.nodeBase.Release()
+			n.nodeBase.Release(manager)
 			return newRoot, !isClone, manager.release(thisRef)
 		}
 	}
 
-	n.markDirty()
+	n.markDirty(manager)
 	return *thisRef, !isClone, err
 }
 
@@ -898,7 +931,7 @@ func (n *BranchNode) Release(manager NodeManager, thisRef *NodeReference, this s
 	if n.IsFrozen() {
 		return nil
 	}
-	n.nodeBase.Release()
+	n.nodeBase.Release(manager)
 	for _, cur := range n.children {
 		if !cur.Id().IsEmpty() {
 			handle, err := manager.getWriteAccess(&cur)
@@ -1175,7 +1208,7 @@ func (n *ExtensionNode) setNextNode(
 				defer handle.Release()
 				newNode := handle.Get().(*ExtensionNode)
 				*newNode = *n
-				newNode.markDirty()
+				newNode.markDirty(manager)
 				newNode.markMutable()
 				thisRef, n = &newRef, newNode
 				isClone = true
@@ -1199,18 +1232,18 @@ func (n *ExtensionNode) setNextNode(
 					n.nextHash = extension.nextHash
 					n.nextIsEmbedded = extension.nextIsEmbedded
 				}
-				n.markDirty()
-				extension.nodeBase.Release()
+				n.markDirty(manager)
+				extension.nodeBase.Release(manager)
 				if err := manager.release(&newRoot); err != nil {
 					return NodeReference{}, false, err
 				}
 			} else if newRoot.Id().IsBranch() {
 				n.next = newRoot
 				n.nextHashDirty = true
-				n.markDirty()
+				n.markDirty(manager)
 			} else {
 				// If the next node is anything but a branch or extension, remove this extension.
-				n.nodeBase.Release()
+				n.nodeBase.Release(manager)
 				if err := manager.release(thisRef); err != nil {
 					return NodeReference{}, false, err
 				}
@@ -1237,7 +1270,7 @@ func (n *ExtensionNode) setNextNode(
 				return newRoot, !isClone, nil
 			}
 		} else if hasChanged {
-			n.markDirty()
+			n.markDirty(manager)
 			n.nextHashDirty = true
 		}
 		return *thisRef, hasChanged, err
@@ -1258,7 +1291,7 @@ func (n *ExtensionNode) setNextNode(
 		defer handle.Release()
 		newNode := handle.Get().(*ExtensionNode)
 		*newNode = *n
-		newNode.markDirty()
+		newNode.markDirty(manager)
 		newNode.markMutable()
 		thisRef, n = &newRef, newNode
 		isClone = true
@@ -1288,7 +1321,7 @@ func (n *ExtensionNode) setNextNode(
 		branch.children[n.path.Get(commonPrefixLength)] = *thisRef
 		branch.markChildHashDirty(byte(n.path.Get(commonPrefixLength)))
 		n.path.ShiftLeft(commonPrefixLength + 1)
-		n.markDirty()
+		n.markDirty(manager)
 		thisNodeWasReused = true
 	} else {
 		pos := byte(n.path.Get(commonPrefixLength))
@@ -1322,7 +1355,7 @@ func (n *ExtensionNode) setNextNode(
 		extension.path = CreatePathFromNibbles(path[0:commonPrefixLength])
 		extension.next = branchRef
 		extension.nextHashDirty = true
-		extension.markDirty()
+		extension.markDirty(manager)
 		newRoot = extensionRef
 	}
 
@@ -1334,7 +1367,7 @@ func (n *ExtensionNode) setNextNode(
 
 	// If this node was not needed any more, we can discard it.
 	if !thisNodeWasReused {
-		n.nodeBase.Release()
+		n.nodeBase.Release(manager)
 		return newRoot, false, manager.release(thisRef)
 	}
 
@@ -1377,7 +1410,7 @@ func (n *ExtensionNode) Release(manager NodeManager, thisRef *NodeReference, thi
 	if n.IsFrozen() {
 		return nil
 	}
-	n.nodeBase.Release()
+	n.nodeBase.Release(manager)
 	handle, err := manager.getWriteAccess(&n.next)
 	if err != nil {
 		return err
@@ -1544,6 +1577,7 @@ func (n *AccountNode) SetAccount(manager NodeManager, thisRef *NodeReference, th
 			return *thisRef, false, nil
 		}
 		if info.IsEmpty() {
+			reportStateSizeChange(manager, -1, 0)
 			if n.IsFrozen() {
 				return NewNodeReference(EmptyId()), false, nil
 			}
@@ -1552,7 +1586,7 @@ func (n *AccountNode) SetAccount(manager NodeManager, thisRef *NodeReference, th
 				manager.releaseTrieAsynchronous(n.storage)
 			}
 			// Release this account node and remove it from the trie.
-			n.nodeBase.Release()
+			n.nodeBase.Release(manager)
 			return NewNodeReference(EmptyId()), false, manager.release(thisRef)
 		}
 
@@ -1566,14 +1600,14 @@ func (n *AccountNode) SetAccount(manager NodeManager, thisRef *NodeReference, th
 			defer handle.Release()
 			newNode := handle.Get().(*AccountNode)
 			*newNode = *n
-			newNode.markDirty()
+			newNode.markDirty(manager)
 			newNode.markMutable()
 			newNode.info = info
 			return newRef, false, nil
 		}
 
 		n.info = info
-		n.markDirty()
+		n.markDirty(manager)
 		return *thisRef, true, nil
 	}
 
@@ -1591,7 +1625,8 @@ func (n *AccountNode) SetAccount(manager NodeManager, thisRef *NodeReference, th
 	sibling := handle.Get().(*AccountNode)
 	sibling.address = address
 	sibling.info = info
-	sibling.markDirty()
+	sibling.markDirty(manager)
+	reportStateSizeChange(manager, 1, 0)
 
 	thisPath := AddressToNibblePath(n.address, manager)
 	newRoot, err := splitLeafNode(manager, thisRef, thisPath[:], n, this, path, &siblingRef, sibling, handle)
@@ -1642,7 +1677,7 @@ func splitLeafNode(
 		extension.path = CreatePathFromNibbles(siblingPath[0:commonPrefixLength])
 		extension.next = branchRef
 		extension.nextHashDirty = true
-		extension.markDirty()
+		extension.markDirty(manager)
 	}
 
 	// If enabled, keep track of the suffix length of leaf values.
@@ -1664,7 +1699,7 @@ func splitLeafNode(
 	branch.children[partialPath[commonPrefixLength]] = *thisRef
 	branch.children[siblingPath[commonPrefixLength]] = *siblingRef
 	branch.markChildHashDirty(byte(siblingPath[commonPrefixLength]))
-	branch.markDirty()
+	branch.markDirty(manager)
 
 	// Update hash if present.
 	if hash, dirty := this.GetHash(); thisModified || dirty {
@@ -1720,7 +1755,7 @@ func (n *AccountNode) SetSlot(manager NodeManager, thisRef *NodeReference, this
 			defer newHandle.Release()
 			newNode := newHandle.Get().(*AccountNode)
 			*newNode = *n
-			newNode.markDirty()
+			newNode.markDirty(manager)
 			newNode.markMutable()
 			newNode.storage = root
 			newNode.storageHashDirty = true
@@ -1728,11 +1763,11 @@ func (n *AccountNode) SetSlot(manager NodeManager, thisRef *NodeReference, this
 		}
 		n.storage = root
 		n.storageHashDirty = true
-		n.markDirty()
+		n.markDirty(manager)
 		hasChanged = true
 	} else if hasChanged {
 		n.storageHashDirty = true
-		n.markDirty()
+		n.markDirty(manager)
 	}
 	return *thisRef, hasChanged, nil
 }
@@ -1752,7 +1787,7 @@ func (n *AccountNode) ClearStorage(manager NodeManager, thisRef *NodeReference,
 		defer newHandle.Release()
 		newNode := newHandle.Get().(*AccountNode)
 		*newNode = *n
-		newNode.markDirty()
+		newNode.markDirty(manager)
 		newNode.markMutable()
 		newNode.storage = NewNodeReference(EmptyId())
 		newNode.storageHashDirty = true
@@ -1764,7 +1799,7 @@ func (n *AccountNode) ClearStorage(manager NodeManager, thisRef *NodeReference,
 	}
 
 	n.storage = NewNodeReference(EmptyId())
-	n.markDirty()
+	n.markDirty(manager)
 	n.storageHashDirty = true
 	return *thisRef, true, err
 }
@@ -1773,7 +1808,7 @@ func (n *AccountNode) Release(manager NodeManager, thisRef *NodeReference, this
 	if n.IsFrozen() {
 		return nil
 	}
-	n.nodeBase.Release()
+	n.nodeBase.Release(manager)
 	if !n.storage.Id().IsEmpty() {
 		rootHandle, err := manager.getWriteAccess(&n.storage)
 		if err != nil {
@@ -1800,14 +1835,14 @@ func (n *AccountNode) setPathLength(manager NodeManager, thisRef *NodeReference,
 		defer newHandle.Release()
 		newNode := newHandle.Get().(*AccountNode)
 		*newNode = *n
-		newNode.markDirty()
+		newNode.markDirty(manager)
 		newNode.markMutable()
 		newNode.pathLength = length
 		return newRef, false, nil
 	}
 
 	n.pathLength = length
-	n.markDirty()
+	n.markDirty(manager)
 	return *thisRef, true, nil
 }
 
@@ -1964,8 +1999,9 @@ func (n *ValueNode) SetValue(manager NodeManager, thisRef *NodeReference, this s
 			return *thisRef, false, nil
 		}
 		if value == (common.Value{}) {
+			reportStateSizeChange(manager, 0, -1)
 			if !n.IsFrozen() {
-				n.nodeBase.Release()
+				n.nodeBase.Release(manager)
 				if err := manager.release(thisRef); err != nil {
 					return NodeReference{}, false, err
 				}
@@ -1981,12 +2017,12 @@ func (n *ValueNode) SetValue(manager NodeManager, thisRef *NodeReference, this s
 			newNode := newHandle.Get().(*ValueNode)
 			newNode.key = n.key
 			newNode.value = value
-			newNode.markDirty()
+			newNode.markDirty(manager)
 			newNode.pathLength = n.pathLength
 			return newRef, false, nil
 		}
 		n.value = value
-		n.markDirty()
+		n.markDirty(manager)
 		return *thisRef, true, nil
 	}
 
@@ -2004,7 +2040,8 @@ func (n *ValueNode) SetValue(manager NodeManager, thisRef *NodeReference, this s
 	sibling := siblingHandle.Get().(*ValueNode)
 	sibling.key = key
 	sibling.value = value
-	sibling.markDirty()
+	sibling.markDirty(manager)
+	reportStateSizeChange(manager, 0, 1)
 
 	thisPath := KeyToNibblePath(n.key, manager)
 	newRootId, err := splitLeafNode(manager, thisRef, thisPath[:], n, this, path, &siblingRef, sibling, siblingHandle)
@@ -2023,7 +2060,7 @@ func (n *ValueNode) Release(manager NodeManager, thisRef *NodeReference, this sh
 	if n.IsFrozen() {
 		return nil
 	}
-	n.nodeBase.Release()
+	n.nodeBase.Release(manager)
 	return manager.release(thisRef)
 }
 
@@ -2040,13 +2077,13 @@ func (n *ValueNode) setPathLength(manager NodeManager, thisRef *NodeReference, t
 		newNode := newHandle.Get().(*ValueNode)
 		newNode.key = n.key
 		newNode.value = n.value
-		newNode.markDirty()
+		newNode.markDirty(manager)
 		newNode.pathLength = length
 		return newRef, false, nil
 	}
 
 	n.pathLength = length
-	n.markDirty()
+	n.markDirty(manager)
 	return *thisRef, true, nil
 }
 
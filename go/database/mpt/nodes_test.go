@@ -6501,13 +6501,13 @@ func markModifiedAsDirty(t *testing.T, ctxt *nodeContext, before, after NodeRefe
 		if !isReused(n) {
 			switch n := n.(type) {
 			case (*AccountNode):
-				n.markDirty()
+				n.markDirty(nil)
 			case (*BranchNode):
-				n.markDirty()
+				n.markDirty(nil)
 			case (*ExtensionNode):
-				n.markDirty()
+				n.markDirty(nil)
 			case (*ValueNode):
-				n.markDirty()
+				n.markDirty(nil)
 			}
 		}
 		// Also update the dirty child-hash markers in the nodes.
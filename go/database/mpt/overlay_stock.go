@@ -0,0 +1,159 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/stock"
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// overlayStock is an in-memory stock of nodes optionally layered on top of a
+// node source. Nodes not present in the stock are copied from the source when
+// accessed for the first time, while all modifications are retained in the
+// stock only. Thus, the source is never modified. If there is no source,
+// accessing nodes not present in the stock results in errors, which allows
+// to detect accesses to nodes missing in a witness. New nodes are allocated
+// starting at the given index, which must exceed all indexes used by the
+// source.
+type overlayStock[V any] struct {
+	source  NodeSource // < nil if there is no underlying source
+	toId    func(uint64) NodeId
+	encoder stock.ValueEncoder[V]
+	values  map[uint64]V
+	deleted map[uint64]bool
+	next    uint64
+}
+
+// overlayIndexOffset is the first index used for new nodes in overlays on top
+// of a node source. It exceeds the number of nodes of any realistic trie.
+const overlayIndexOffset = uint64(1) << 48
+
+func newOverlayStock[V any](source NodeSource, toId func(uint64) NodeId, encoder stock.ValueEncoder[V], next uint64) *overlayStock[V] {
+	return &overlayStock[V]{
+		source:  source,
+		toId:    toId,
+		encoder: encoder,
+		values:  map[uint64]V{},
+		deleted: map[uint64]bool{},
+		next:    next,
+	}
+}
+
+// add registers the encoded node at the given index in this stock.
+func (s *overlayStock[V]) add(index uint64, data []byte) error {
+	if len(data) != s.encoder.GetEncodedSize() {
+		return fmt.Errorf("invalid encoding size, wanted %d, got %d", s.encoder.GetEncodedSize(), len(data))
+	}
+	var value V
+	if err := s.encoder.Load(data, &value); err != nil {
+		return err
+	}
+	s.values[index] = value
+	if index >= s.next {
+		s.next = index + 1
+	}
+	return nil
+}
+
+func (s *overlayStock[V]) New() (uint64, error) {
+	res := s.next
+	s.next++
+	return res, nil
+}
+
+func (s *overlayStock[V]) Get(index uint64) (V, error) {
+	value, found := s.values[index]
+	if found {
+		return value, nil
+	}
+	if s.source == nil {
+		return value, fmt.Errorf("%w: node %v", MissingWitnessDataError, s.toId(index))
+	}
+	if s.deleted[index] {
+		return value, fmt.Errorf("node %v has been deleted", s.toId(index))
+	}
+	return s.load(index)
+}
+
+// load copies the node with the given index from the source. The copy is
+// obtained by encoding and decoding the node, which resets all in-memory
+// node status information.
+func (s *overlayStock[V]) load(index uint64) (V, error) {
+	var res V
+	ref := NewNodeReference(s.toId(index))
+	handle, err := s.source.getViewAccess(&ref)
+	if err != nil {
+		return res, err
+	}
+	defer handle.Release()
+	node, ok := any(handle.Get()).(*V)
+	if !ok {
+		return res, fmt.Errorf("invalid node type %T of node %v", handle.Get(), ref.Id())
+	}
+	data := make([]byte, s.encoder.GetEncodedSize())
+	if err := s.encoder.Store(data, node); err != nil {
+		return res, err
+	}
+	err = s.encoder.Load(data, &res)
+	return res, err
+}
+
+func (s *overlayStock[V]) Set(index uint64, value V) error {
+	s.values[index] = value
+	delete(s.deleted, index)
+	return nil
+}
+
+func (s *overlayStock[V]) Delete(index uint64) error {
+	delete(s.values, index)
+	if s.source != nil {
+		s.deleted[index] = true
+	}
+	return nil
+}
+
+// GetIds returns the indexes of the nodes retained in this stock. Nodes of
+// the source not accessed through this stock are not included.
+func (s *overlayStock[V]) GetIds() (stock.IndexSet[uint64], error) {
+	if s.source != nil {
+		return nil, fmt.Errorf("listing IDs is not supported for overlays")
+	}
+	res := stock.MakeComplementSet[uint64](0, s.next)
+	for i := uint64(0); i < s.next; i++ {
+		if _, found := s.values[i]; !found {
+			res.Remove(i)
+		}
+	}
+	return res, nil
+}
+
+func (s *overlayStock[V]) GetMemoryFootprint() *common.MemoryFootprint {
+	var value V
+	size := unsafe.Sizeof(*s) + uintptr(len(s.values))*(unsafe.Sizeof(value)+8) + uintptr(len(s.deleted))*9
+	return common.NewMemoryFootprint(size)
+}
+
+// GetDiskFootprint reports no disk usage since overlays are retained in
+// memory only.
+func (s *overlayStock[V]) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return common.NewDiskFootprint(0), nil
+}
+
+func (s *overlayStock[V]) Flush() error {
+	return nil
+}
+
+func (s *overlayStock[V]) Close() error {
+	return nil
+}
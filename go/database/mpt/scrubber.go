@@ -0,0 +1,229 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/stock"
+	"github.com/Fantom-foundation/Carmen/go/backend/stock/file"
+)
+
+// This file provides a scrubber walking all nodes of a forest to detect
+// corrupted nodes. Each node is loaded from its stock, which verifies the
+// node's checksum if the stock has been created with checksums. For forests
+// storing hashes with nodes, the hash of each node is checked as well, based
+// on the nodes stored in the stocks. Scrubs are prepared while updates are
+// excluded and may then be run while the LiveDB or archive is in use.
+
+// ScrubConfig configures a scrub of the nodes of a LiveDB or an archive.
+type ScrubConfig struct {
+	NodesPerSecond int // < the maximum number of scrubbed nodes per second, unlimited if 0
+}
+
+// ScrubReport summarizes the results of a scrub.
+type ScrubReport struct {
+	NumNodes int       // < the number of scrubbed nodes
+	BadNodes []BadNode // < the nodes failing a check, grouped by node type
+}
+
+// BadNode is a node detected to be corrupted by a scrub.
+type BadNode struct {
+	Id    NodeId
+	Issue error
+}
+
+// PreparedScrub is a scrub of the nodes stored in the stocks of a forest at
+// the time the scrub was prepared. Running a prepared scrub does not require
+// exclusive access to the LiveDB or archive it was prepared for.
+type PreparedScrub struct {
+	forest     *Forest
+	hashSource NodeSource
+	ids        nodeIdSets
+}
+
+// Run checks the nodes covered by this scrub for corruption. Errors are only
+// returned if the scrub could not be completed, bad nodes are listed in the
+// report.
+func (p PreparedScrub) Run(ctx context.Context, config ScrubConfig, observer VerificationObserver) (ScrubReport, error) {
+	return scrub(ctx, p.forest, p.hashSource, p.ids, newNodeRateLimiter(config.NodesPerSecond), observer)
+}
+
+// PrepareScrub writes all modified nodes of this trie to the stocks and
+// collects the nodes to be checked by the resulting scrub. Since the hashes of
+// the nodes of a LiveTrie are stored in their parents and may not be
+// up-to-date, only the checksums of the nodes are verified. This method must
+// not be called concurrently to updates of the trie.
+func (t *LiveTrie) PrepareScrub() (PreparedScrub, error) {
+	forest, ok := t.forest.(*Forest)
+	if !ok {
+		return PreparedScrub{}, fmt.Errorf("unsupported forest implementation %T", t.forest)
+	}
+	if err := forest.Flush(); err != nil {
+		return PreparedScrub{}, err
+	}
+	ids, err := forest.getNodeIds()
+	if err != nil {
+		return PreparedScrub{}, err
+	}
+	return PreparedScrub{forest: forest, ids: ids}, nil
+}
+
+// Scrub checks all nodes of this trie for corruption. See PrepareScrub and
+// PreparedScrub.Run. This method must not be called concurrently to updates
+// of the trie.
+func (t *LiveTrie) Scrub(ctx context.Context, config ScrubConfig, observer VerificationObserver) (ScrubReport, error) {
+	prepared, err := t.PrepareScrub()
+	if err != nil {
+		return ScrubReport{}, err
+	}
+	return prepared.Run(ctx, config, observer)
+}
+
+// PrepareScrub prepares a scrub of all nodes of this state. See
+// LiveTrie.PrepareScrub.
+func (s *MptState) PrepareScrub() (PreparedScrub, error) {
+	return s.trie.PrepareScrub()
+}
+
+// Scrub checks all nodes of this state for corruption. See LiveTrie.Scrub.
+func (s *MptState) Scrub(ctx context.Context, config ScrubConfig, observer VerificationObserver) (ScrubReport, error) {
+	return s.trie.Scrub(ctx, config, observer)
+}
+
+// PrepareScrub writes all modified nodes of this archive to the stocks and
+// collects the nodes to be checked by the resulting scrub. Besides the
+// checksums of the nodes, the hashes stored with the nodes are verified. All
+// checks are based on the nodes stored in the stocks, bypassing the node
+// cache. It may be called while blocks are added to the archive, in which case
+// nodes added after the preparation are not covered.
+func (a *ArchiveTrie) PrepareScrub() (PreparedScrub, error) {
+	forest, ok := a.forest.(*Forest)
+	if !ok {
+		return PreparedScrub{}, fmt.Errorf("unsupported forest implementation %T", a.forest)
+	}
+
+	// Nodes present at the end of a block are frozen. Thus, the set of nodes
+	// to be checked needs to be collected and written to the stocks between
+	// two blocks. Afterwards, the stored nodes are not modified anymore.
+	a.addMutex.Lock()
+	err := forest.Flush()
+	ids, idErr := forest.getNodeIds()
+	a.addMutex.Unlock()
+	if err = errors.Join(err, idErr); err != nil {
+		return PreparedScrub{}, err
+	}
+	var hashSource NodeSource
+	if forest.getConfig().HashStorageLocation == HashStoredWithNode {
+		hashSource = newStockNodeSource(forest)
+	}
+	return PreparedScrub{forest: forest, hashSource: hashSource, ids: ids}, nil
+}
+
+// Scrub checks all nodes of this archive for corruption. See PrepareScrub and
+// PreparedScrub.Run. It may be used while blocks are added to the archive.
+func (a *ArchiveTrie) Scrub(ctx context.Context, config ScrubConfig, observer VerificationObserver) (ScrubReport, error) {
+	prepared, err := a.PrepareScrub()
+	if err != nil {
+		return ScrubReport{}, err
+	}
+	return prepared.Run(ctx, config, observer)
+}
+
+// scrub loads all nodes of the given sets from the stocks of the given forest
+// and, if a hash source is given, checks their stored hashes using the nodes
+// provided by this source. Nodes failing any of these checks are listed in
+// the resulting report.
+func scrub(
+	ctx context.Context,
+	forest *Forest,
+	hashSource NodeSource,
+	ids nodeIdSets,
+	limiter *nodeRateLimiter,
+	observer VerificationObserver,
+) (res ScrubReport, err error) {
+	if observer == nil {
+		observer = NilVerificationObserver{}
+	}
+	observer.StartVerification()
+	defer func() {
+		observer.EndVerification(err)
+	}()
+
+	var verifier *nodeVerifier
+	if hashSource != nil {
+		if verifier, err = newNodeVerifier(hashSource, ids); err != nil {
+			return res, err
+		}
+	}
+
+	types := []struct {
+		name string
+		ids  stock.IndexSet[uint64]
+		toId func(uint64) NodeId
+		load func(uint64) error
+	}{
+		{"account", ids.accounts, AccountId, getLoader(forest.accounts)},
+		{"branch", ids.branches, BranchId, getLoader(forest.branches)},
+		{"extension", ids.extensions, ExtensionId, getLoader(forest.extensions)},
+		{"value", ids.values, ValueId, getLoader(forest.values)},
+	}
+	for _, cur := range types {
+		observer.Progress(fmt.Sprintf("Scrubbing %ss ...", cur.name))
+		for index := cur.ids.GetLowerBound(); index < cur.ids.GetUpperBound(); index++ {
+			if !cur.ids.Contains(index) {
+				continue
+			}
+			if err := limiter.wait(ctx); err != nil {
+				return res, err
+			}
+			res.NumNodes++
+			id := cur.toId(index)
+			issue := cur.load(index)
+			if issue == nil && verifier != nil {
+				issue = verifier.checkNode(id)
+				// Corrupted referenced nodes are reported on their own.
+				if errors.Is(issue, file.ChecksumMismatchError) {
+					issue = nil
+				}
+			}
+			if issue != nil {
+				res.BadNodes = append(res.BadNodes, BadNode{Id: id, Issue: issue})
+			}
+		}
+	}
+	observer.Progress(fmt.Sprintf("Scrubbed %d nodes, found %d bad nodes", res.NumNodes, len(res.BadNodes)))
+	return res, nil
+}
+
+// getLoader creates a function loading values from the given stock, bypassing
+// any caches such that stored values are checked.
+func getLoader[V any](stock stock.Stock[uint64, V]) func(uint64) error {
+	return func(index uint64) error {
+		_, err := stock.Get(index)
+		return err
+	}
+}
+
+// newStockNodeSource creates a read-only NodeSource loading the nodes directly
+// from the stocks of the given forest, bypassing its node cache. The stocks
+// remain owned by the forest, so the resulting source must not be closed.
+func newStockNodeSource(forest *Forest) NodeSource {
+	return &verificationNodeSource{
+		config:     forest.getConfig(),
+		accounts:   forest.accounts,
+		branches:   forest.branches,
+		extensions: forest.extensions,
+		values:     forest.values,
+	}
+}
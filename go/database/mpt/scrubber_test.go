@@ -0,0 +1,251 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/stock"
+	"github.com/Fantom-foundation/Carmen/go/backend/stock/file"
+)
+
+// corruptLastNode flips a bit of the node with the highest index in the value
+// file of the checksummed stock in the given directory and returns its index.
+func corruptLastNode[N any](t *testing.T, directory string, encoder stock.ValueEncoder[N]) uint64 {
+	t.Helper()
+	stock, err := file.OpenStock[uint64](encoder, directory)
+	if err != nil {
+		t.Fatalf("failed to open stock")
+	}
+	ids, err := stock.GetIds()
+	if err != nil {
+		t.Fatalf("failed to get stock ids: %v", err)
+	}
+	if err := stock.Close(); err != nil {
+		t.Fatalf("failed to close stock: %v", err)
+	}
+	idx := ids.GetUpperBound() - 1
+	for !ids.Contains(idx) {
+		idx--
+	}
+
+	// Each value is followed by a 4-byte checksum.
+	recordSize := int64(encoder.GetEncodedSize() + 4)
+	data, err := os.ReadFile(directory + "/values.dat")
+	if err != nil {
+		t.Fatalf("failed to read value file: %v", err)
+	}
+	data[int64(idx)*recordSize] ^= 1
+	if err := os.WriteFile(directory+"/values.dat", data, 0600); err != nil {
+		t.Fatalf("failed to write value file: %v", err)
+	}
+	return idx
+}
+
+func TestScrub_IntactArchivesHaveNoBadNodes(t *testing.T) {
+	for _, config := range []MptConfig{S4ArchiveConfig, S5ArchiveConfig} {
+		config.UseStockChecksums = true
+		t.Run(config.Name, func(t *testing.T) {
+			archive, err := OpenArchiveTrie(t.TempDir(), config, DefaultMptStateCapacity)
+			if err != nil {
+				t.Fatalf("failed to open archive: %v", err)
+			}
+			defer archive.Close()
+			addIncrementalVerificationTestBlocks(t, archive, 0, 5)
+
+			report, err := archive.Scrub(context.Background(), ScrubConfig{}, nil)
+			if err != nil {
+				t.Fatalf("failed to scrub archive: %v", err)
+			}
+			if report.NumNodes == 0 {
+				t.Errorf("no nodes have been scrubbed")
+			}
+			if len(report.BadNodes) != 0 {
+				t.Errorf("intact archive should have no bad nodes, got %v", report.BadNodes)
+			}
+		})
+	}
+}
+
+func TestScrub_NodesFailingTheirChecksumAreReported(t *testing.T) {
+	dir := t.TempDir()
+	config := S5ArchiveConfig
+	config.UseStockChecksums = true
+	createIncrementalVerificationTestArchive(t, dir, config, 0, 5)
+	_, _, _, encoder := getEncoder(config)
+	idx := corruptLastNode(t, dir+"/values", encoder)
+
+	// The archive is opened without requesting checksums, yet the existing
+	// stocks retain them.
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	report, err := archive.Scrub(context.Background(), ScrubConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to scrub archive: %v", err)
+	}
+	if len(report.BadNodes) != 1 {
+		t.Fatalf("unexpected bad nodes, wanted 1, got %v", report.BadNodes)
+	}
+	if want, got := ValueId(idx), report.BadNodes[0].Id; want != got {
+		t.Errorf("unexpected bad node, wanted %v, got %v", want, got)
+	}
+	if issue := report.BadNodes[0].Issue; !errors.Is(issue, file.ChecksumMismatchError) {
+		t.Errorf("unexpected issue, got %v", issue)
+	}
+}
+
+func TestScrub_NodesWithInvalidHashesAreReportedForArchives(t *testing.T) {
+	dir := t.TempDir()
+	config := S5ArchiveConfig
+	createIncrementalVerificationTestArchive(t, dir, config, 0, 5)
+	encoder, _, _, _ := getEncoder(config)
+	modifyLastNode(t, dir+"/accounts", encoder, func(node *AccountNode) {
+		node.info.Balance[0]++
+	})
+
+	archive, err := OpenArchiveTrie(dir, config, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	report, err := archive.Scrub(context.Background(), ScrubConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to scrub archive: %v", err)
+	}
+	if len(report.BadNodes) != 1 {
+		t.Fatalf("unexpected bad nodes, wanted 1, got %v", report.BadNodes)
+	}
+	if bad := report.BadNodes[0]; !bad.Id.IsAccount() || !strings.Contains(bad.Issue.Error(), "invalid hash stored for node") {
+		t.Errorf("unexpected bad node, got %v: %v", bad.Id, bad.Issue)
+	}
+}
+
+func TestScrub_ArchiveHashesAreCheckedOnStoredNodesInsteadOfCachedNodes(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	addIncrementalVerificationTestBlocks(t, archive, 0, 5)
+	if err := archive.Flush(); err != nil {
+		t.Fatalf("failed to flush archive: %v", err)
+	}
+
+	// Load the last account into the node cache and modify its stored copy.
+	forest := archive.forest.(*Forest)
+	ids, err := forest.accounts.GetIds()
+	if err != nil {
+		t.Fatalf("failed to get account ids: %v", err)
+	}
+	idx := ids.GetUpperBound() - 1
+	ref := NewNodeReference(AccountId(idx))
+	handle, err := forest.getViewAccess(&ref)
+	if err != nil {
+		t.Fatalf("failed to load node: %v", err)
+	}
+	handle.Release()
+	node, err := forest.accounts.Get(idx)
+	if err != nil {
+		t.Fatalf("failed to get node: %v", err)
+	}
+	node.info.Balance[0]++
+	if err := forest.accounts.Set(idx, node); err != nil {
+		t.Fatalf("failed to set node: %v", err)
+	}
+
+	report, err := archive.Scrub(context.Background(), ScrubConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to scrub archive: %v", err)
+	}
+	if len(report.BadNodes) != 1 {
+		t.Fatalf("unexpected bad nodes, wanted 1, got %v", report.BadNodes)
+	}
+	if bad := report.BadNodes[0]; bad.Id != AccountId(idx) || !strings.Contains(bad.Issue.Error(), "invalid hash stored for node") {
+		t.Errorf("unexpected bad node, got %v: %v", bad.Id, bad.Issue)
+	}
+}
+
+func TestScrub_LiveTrieNodesFailingTheirChecksumAreReported(t *testing.T) {
+	dir := t.TempDir()
+	config := S5LiveConfig
+	config.UseStockChecksums = true
+	state, err := OpenGoFileState(dir, config, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	applyTrieDiffTestUpdates(t, state, getTrieDiffTestUpdate())
+	if err := state.Close(); err != nil {
+		t.Fatalf("failed to close state: %v", err)
+	}
+	encoder, _, _, _ := getEncoder(config)
+	idx := corruptLastNode(t, dir+"/accounts", encoder)
+
+	state, err = OpenGoFileState(dir, config, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	report, err := state.Scrub(context.Background(), ScrubConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to scrub state: %v", err)
+	}
+	if len(report.BadNodes) != 1 || report.BadNodes[0].Id != AccountId(idx) {
+		t.Errorf("unexpected bad nodes, wanted account %d, got %v", idx, report.BadNodes)
+	}
+}
+
+func TestScrub_ModifiedNodesOfLiveDbAreWrittenBeforeTheScrub(t *testing.T) {
+	config := S5LiveConfig
+	config.UseStockChecksums = true
+	state, err := OpenGoFileState(t.TempDir(), config, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	applyTrieDiffTestUpdates(t, state, getTrieDiffTestUpdate())
+
+	prepared, err := state.PrepareScrub()
+	if err != nil {
+		t.Fatalf("failed to prepare scrub: %v", err)
+	}
+	if got := state.CountDirtyNodes(); got != 0 {
+		t.Errorf("modified nodes should be written when preparing the scrub, got %d dirty nodes", got)
+	}
+	report, err := prepared.Run(context.Background(), ScrubConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to scrub state: %v", err)
+	}
+	if report.NumNodes == 0 || len(report.BadNodes) != 0 {
+		t.Errorf("all nodes should be scrubbed without issues, got %d nodes and bad nodes %v", report.NumNodes, report.BadNodes)
+	}
+}
+
+func TestScrub_CancelledScrubFails(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	addIncrementalVerificationTestBlocks(t, archive, 0, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := archive.Scrub(ctx, ScrubConfig{}, nil); err != context.Canceled {
+		t.Errorf("cancelled scrub should fail with %v, got %v", context.Canceled, err)
+	}
+}
@@ -12,6 +12,8 @@ package mpt
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -20,6 +22,7 @@ import (
 	"maps"
 	"os"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/Fantom-foundation/Carmen/go/database/mpt/shared"
@@ -92,6 +95,7 @@ type Database interface {
 
 	updateHashesFor(ref *NodeReference) (common.Hash, *NodeHashes, error)
 	setHashesFor(root *NodeReference, hashes *NodeHashes) error
+	setWitnessRecorder(recorder *WitnessRecorder) error
 }
 
 // LiveState represents a single  Merkle-Patricia-Trie (MPT) view to the Database
@@ -141,6 +145,10 @@ type MptState struct {
 	codeMutex sync.Mutex
 	codefile  string
 	hasher    hash.Hash
+	witness   atomic.Pointer[WitnessRecorder] // < an optional recorder for collecting read codes
+
+	nextRecord      []byte     // < the record of the block applied next, see SetNextBlockRecord
+	nextRecordMutex sync.Mutex // < protecting the next record
 }
 
 // The capacity of an MPT's node cache must be at least as large as the maximum
@@ -306,16 +314,16 @@ func (s *MptState) GetCode(address common.Address) (value []byte, err error) {
 	if !exists {
 		return nil, nil
 	}
-	s.codeMutex.Lock()
-	res := s.code[info.CodeHash]
-	s.codeMutex.Unlock()
-	return res, nil
+	return s.GetCodeForHash(info.CodeHash), nil
 }
 
 func (s *MptState) GetCodeForHash(hash common.Hash) []byte {
 	s.codeMutex.Lock()
 	res := s.code[hash]
 	s.codeMutex.Unlock()
+	if recorder := s.witness.Load(); recorder != nil {
+		recorder.recordCode(hash, res)
+	}
 	return res
 }
 
@@ -372,18 +380,155 @@ func (s *MptState) GetHash() (hash common.Hash, err error) {
 	return hash, err
 }
 
+// Apply applies the given update to this state and records the given block
+// as the head block of this state. The head block is persisted with the trie
+// on the next flush, keeping it consistent with the persisted state.
 func (s *MptState) Apply(block uint64, update common.Update) (archiveUpdateHints common.Releaser, err error) {
+	// The record is only intended for this block, even if the update fails.
+	defer func() {
+		s.nextRecordMutex.Lock()
+		s.nextRecord = nil
+		s.nextRecordMutex.Unlock()
+	}()
 	if err := update.ApplyTo(s); err != nil {
 		return nil, err
 	}
+	s.nextRecordMutex.Lock()
+	s.trie.setHeadBlock(HeadBlock{Number: block, Record: s.nextRecord})
+	s.nextRecordMutex.Unlock()
 	_, hints, err := s.trie.UpdateHashes()
 	return hints, err
 }
 
+// SetNextBlockRecord registers an opaque record, e.g. the hash and time of a
+// block, to be stored with the head block information by the next call to
+// Apply. The record is reset after each Apply.
+func (s *MptState) SetNextBlockRecord(record []byte) {
+	s.nextRecordMutex.Lock()
+	defer s.nextRecordMutex.Unlock()
+	s.nextRecord = bytes.Clone(record)
+}
+
+// GetHeadBlock returns the last block applied to this state. The found flag
+// is false if no block was applied to this state yet.
+func (s *MptState) GetHeadBlock() (block HeadBlock, found bool) {
+	return s.trie.GetHeadBlock()
+}
+
+// CountDirtyNodes returns the number of modified nodes of this state that
+// have not been written to disk yet. See Forest.CountDirtyNodes.
+func (s *MptState) CountDirtyNodes() int {
+	return countDirtyNodes(s.trie.forest)
+}
+
+// dirtyNodeCounter is implemented by databases able to count their dirty
+// nodes.
+type dirtyNodeCounter interface {
+	CountDirtyNodes() int
+}
+
+func countDirtyNodes(db Database) int {
+	if counter, ok := db.(dirtyNodeCounter); ok {
+		return counter.CountDirtyNodes()
+	}
+	return 0
+}
+
+// GetCacheStatistics summarizes the usage of the node cache of this state.
+func (s *MptState) GetCacheStatistics() common.CacheStatistics {
+	return getCacheStatistics(s.trie.forest)
+}
+
+// SetCacheCapacity changes the number of nodes retained in the node cache of
+// this state. The capacity is at least MinMptStateCapacity.
+func (s *MptState) SetCacheCapacity(capacity int) {
+	setCacheCapacity(s.trie.forest, capacity)
+}
+
+// getCacheStatistics summarizes the usage of the node cache of the given
+// database, accounting for the minimum capacity required by MPT states.
+func getCacheStatistics(db Database) common.CacheStatistics {
+	cache, ok := db.(common.AdaptiveCache)
+	if !ok {
+		return common.CacheStatistics{}
+	}
+	stats := cache.GetCacheStatistics()
+	stats.MinCapacity = MinMptStateCapacity
+	if stats.MinCapacity > stats.MaxCapacity {
+		stats.MinCapacity = stats.MaxCapacity
+	}
+	return stats
+}
+
+func setCacheCapacity(db Database, capacity int) {
+	if capacity < MinMptStateCapacity {
+		capacity = MinMptStateCapacity
+	}
+	if cache, ok := db.(common.AdaptiveCache); ok {
+		cache.SetCacheCapacity(capacity)
+	}
+}
+
+// getDiskFootprint reports the disk usage of the given database, which is
+// considered to occupy no disk space if it is unable to report it.
+func getDiskFootprint(db Database) (*common.DiskFootprint, error) {
+	if provider, ok := db.(common.DiskFootprintProvider); ok {
+		return provider.GetDiskFootprint()
+	}
+	return common.NewDiskFootprint(0), nil
+}
+
+// GetHashAfter computes the hash of the state resulting from applying the
+// given update to this state without modifying it. The update is applied on
+// a temporary copy-on-write overlay of this state's trie, which only copies
+// the nodes touched by the update.
+func (s *MptState) GetHashAfter(update common.Update) (common.Hash, error) {
+	source, ok := s.trie.forest.(NodeSource)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("unsupported forest implementation %T", s.trie.forest)
+	}
+
+	// Nodes copied into the overlay need to have up-to-date hashes.
+	if _, err := s.GetHash(); err != nil {
+		return common.Hash{}, err
+	}
+
+	config := source.getConfig()
+	accountEncoder, branchEncoder, extensionEncoder, valueEncoder := getEncoder(config)
+	overlay, err := makeForest(
+		config,
+		"",
+		newOverlayStock[BranchNode](source, BranchId, branchEncoder, overlayIndexOffset),
+		newOverlayStock[ExtensionNode](source, ExtensionId, extensionEncoder, overlayIndexOffset),
+		newOverlayStock[AccountNode](source, AccountId, accountEncoder, overlayIndexOffset),
+		newOverlayStock[ValueNode](source, ValueId, valueEncoder, overlayIndexOffset),
+		ForestConfig{Mode: Mutable, CacheCapacity: MinMptStateCapacity},
+	)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	preview := &MptState{
+		trie: getTrieView(NewNodeReference(s.trie.root.Id()), overlay),
+		code: map[common.Hash][]byte{},
+	}
+	hash := common.Hash{}
+	if err = update.ApplyTo(preview); err == nil {
+		hash, err = preview.GetHash()
+	}
+	return hash, errors.Join(err, overlay.Close())
+}
+
 func (s *MptState) Visit(visitor NodeVisitor) error {
 	return s.trie.VisitTrie(visitor)
 }
 
+// VisitWithContext is like Visit but aborts the visit with the context's
+// error once the given context is done.
+func (s *MptState) VisitWithContext(ctx context.Context, visitor NodeVisitor) error {
+	return s.trie.VisitTrieWithContext(ctx, visitor)
+}
+
 func (s *MptState) GetCodes() (map[common.Hash][]byte, error) {
 	s.codeMutex.Lock()
 	res := maps.Clone(s.code)
@@ -455,6 +600,24 @@ func (s *MptState) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// GetDiskFootprint provides sizes of individual components of the state on disk
+func (s *MptState) GetDiskFootprint() (*common.DiskFootprint, error) {
+	trie, err := s.trie.GetDiskFootprint()
+	if err != nil {
+		return nil, err
+	}
+	df := common.NewDiskFootprint(0)
+	df.AddChild("trie", trie)
+	if s.codefile != "" {
+		codes, err := common.GetFileDiskFootprint(s.codefile)
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild("codes", codes)
+	}
+	return df, nil
+}
+
 func (s *MptState) UpdateHashes() (common.Hash, *NodeHashes, error) {
 	return s.trie.UpdateHashes()
 }
@@ -0,0 +1,327 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// This file provides an analysis of the storage tries of all accounts of a
+// state, listing the accounts consuming the most space. The account trie is
+// walked by a single goroutine, while the storage tries of the located
+// accounts are analyzed by a pool of workers in parallel.
+
+// StateConsumersConfig configures the analysis of the storage tries of a state.
+type StateConsumersConfig struct {
+	TopN       int // < the number of accounts with the largest storage tries to be reported
+	NumWorkers int // < the number of storage tries analyzed in parallel, the number of CPUs if 0
+}
+
+// AccountStorageStatistic summarizes the storage trie of a single account.
+type AccountStorageStatistic struct {
+	Address  common.Address
+	NumSlots int    // < the number of storage slots with a non-zero value
+	NumNodes int    // < the number of nodes of the storage trie
+	Depth    int    // < the number of levels of the storage trie, 0 if empty
+	Size     uint64 // < the encoded size of all nodes of the storage trie in bytes
+}
+
+// StateConsumersReport summarizes the storage tries of all accounts of a state.
+type StateConsumersReport struct {
+	NumAccounts int
+	NumSlots    int
+	NumNodes    int
+	Size        uint64
+	// Top lists the accounts with the largest storage tries by size, the
+	// largest first. Ties are broken by the number of slots and addresses.
+	Top []AccountStorageStatistic
+	// SlotHistogram lists the number of accounts per range of slot counts.
+	// Entry 0 covers accounts without slots, entry i > 0 accounts with a
+	// slot count in the range [2^(i-1), 2^i).
+	SlotHistogram []int
+}
+
+func (r *StateConsumersReport) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("Accounts: %d, slots: %d, storage nodes: %d, storage size: %d bytes\n", r.NumAccounts, r.NumSlots, r.NumNodes, r.Size))
+
+	builder.WriteString(fmt.Sprintf("Top %d accounts by storage size:\n", len(r.Top)))
+	builder.WriteString("Rank, Address, Slots, Nodes, Depth, Bytes\n")
+	for i, cur := range r.Top {
+		builder.WriteString(fmt.Sprintf("%d, %x, %d, %d, %d, %d\n", i+1, cur.Address[:], cur.NumSlots, cur.NumNodes, cur.Depth, cur.Size))
+	}
+
+	builder.WriteString("Slot count distribution:\n")
+	for i, count := range r.SlotHistogram {
+		switch i {
+		case 0:
+			builder.WriteString(fmt.Sprintf("0, %d\n", count))
+		case 1:
+			builder.WriteString(fmt.Sprintf("1, %d\n", count))
+		default:
+			builder.WriteString(fmt.Sprintf("%d-%d, %d\n", 1<<(i-1), 1<<i-1, count))
+		}
+	}
+	return builder.String()
+}
+
+// GetStateConsumers analyzes the storage tries of all accounts of the current
+// state of this LiveDB. The state must not be modified during the analysis.
+func (s *MptState) GetStateConsumers(ctx context.Context, config StateConsumersConfig) (StateConsumersReport, error) {
+	source, ok := s.trie.forest.(NodeSource)
+	if !ok {
+		return StateConsumersReport{}, fmt.Errorf("unsupported forest implementation %T", s.trie.forest)
+	}
+	return getStateConsumers(ctx, source, NewNodeReference(s.trie.root.Id()), config)
+}
+
+// GetStateConsumers analyzes the storage tries of all accounts of the state
+// of the given block.
+func (a *ArchiveTrie) GetStateConsumers(ctx context.Context, block uint64, config StateConsumersConfig) (StateConsumersReport, error) {
+	a.rootsMutex.Lock()
+	if block >= uint64(len(a.roots)) {
+		a.rootsMutex.Unlock()
+		return StateConsumersReport{}, fmt.Errorf("block %d not present in archive, number of blocks is %d", block, len(a.roots))
+	}
+	root := a.roots[block].NodeRef
+	a.rootsMutex.Unlock()
+	return getStateConsumers(ctx, a.nodeSource, root, config)
+}
+
+// storageRoot is the storage trie of an account to be analyzed.
+type storageRoot struct {
+	address common.Address
+	root    NodeReference
+}
+
+func getStateConsumers(ctx context.Context, source NodeSource, root NodeReference, config StateConsumersConfig) (StateConsumersReport, error) {
+	if config.TopN < 0 {
+		return StateConsumersReport{}, fmt.Errorf("invalid number of top accounts: %d", config.TopN)
+	}
+	numWorkers := config.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	// Any failure aborts all workers and the walk of the account trie.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	_, branchEncoder, extensionEncoder, valueEncoder := getEncoder(source.getConfig())
+	sizes := storageNodeSizes{
+		branch:    uint64(branchEncoder.GetEncodedSize()),
+		extension: uint64(extensionEncoder.GetEncodedSize()),
+		value:     uint64(valueEncoder.GetEncodedSize()),
+	}
+
+	roots := make(chan storageRoot, 16*numWorkers)
+	results := make([]*stateConsumersCollector, numWorkers)
+	errs := make([]error, numWorkers+1)
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		results[i] = newStateConsumersCollector(config.TopN)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for cur := range roots {
+				stats, err := getAccountStorageStatistic(ctx, source, cur, sizes)
+				if err != nil {
+					errs[i] = err
+					cancel()
+					return
+				}
+				results[i].add(stats)
+			}
+		}(i)
+	}
+
+	// Accounts are located by a single walk of the account trie.
+	errs[numWorkers] = visitWithContext(ctx, MakeVisitor(func(node Node, _ NodeInfo) VisitResponse {
+		account, ok := node.(*AccountNode)
+		if !ok {
+			return VisitResponseContinue
+		}
+		select {
+		case roots <- storageRoot{address: account.address, root: NewNodeReference(account.storage.Id())}:
+			return VisitResponsePrune
+		case <-ctx.Done():
+			return VisitResponseAbort
+		}
+	}), func(visitor NodeVisitor) error {
+		return visitSubtrie(source, &root, visitor)
+	})
+	close(roots)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return StateConsumersReport{}, err
+	}
+	res := newStateConsumersCollector(config.TopN)
+	for _, cur := range results {
+		res.merge(cur)
+	}
+	return res.getReport(), nil
+}
+
+// storageNodeSizes are the encoded sizes of the node types of storage tries.
+type storageNodeSizes struct {
+	branch, extension, value uint64
+}
+
+// getAccountStorageStatistic walks the storage trie of a single account.
+func getAccountStorageStatistic(ctx context.Context, source NodeSource, account storageRoot, sizes storageNodeSizes) (AccountStorageStatistic, error) {
+	res := AccountStorageStatistic{Address: account.address}
+	err := visitWithContext(ctx, MakeVisitor(func(node Node, info NodeInfo) VisitResponse {
+		switch node.(type) {
+		case *BranchNode:
+			res.Size += sizes.branch
+		case *ExtensionNode:
+			res.Size += sizes.extension
+		case *ValueNode:
+			res.Size += sizes.value
+			res.NumSlots++
+		default:
+			return VisitResponseContinue
+		}
+		res.NumNodes++
+		if *info.Depth+1 > res.Depth {
+			res.Depth = *info.Depth + 1
+		}
+		return VisitResponseContinue
+	}), func(visitor NodeVisitor) error {
+		return visitSubtrie(source, &account.root, visitor)
+	})
+	return res, err
+}
+
+// visitSubtrie runs the given visitor on all nodes of the trie rooted by the
+// given node. The depth of the root node is 0.
+func visitSubtrie(source NodeSource, root *NodeReference, visitor NodeVisitor) error {
+	handle, err := source.getViewAccess(root)
+	if err != nil {
+		return err
+	}
+	defer handle.Release()
+	_, err = handle.Get().Visit(source, root, 0, visitor)
+	return err
+}
+
+// stateConsumersCollector aggregates the statistics of individual accounts,
+// retaining the largest accounts only.
+type stateConsumersCollector struct {
+	report StateConsumersReport
+	topN   int
+	top    accountStorageStatisticHeap
+}
+
+func newStateConsumersCollector(topN int) *stateConsumersCollector {
+	return &stateConsumersCollector{topN: topN}
+}
+
+func (c *stateConsumersCollector) add(stats AccountStorageStatistic) {
+	c.report.NumAccounts++
+	c.report.NumSlots += stats.NumSlots
+	c.report.NumNodes += stats.NumNodes
+	c.report.Size += stats.Size
+
+	bucket := bits.Len(uint(stats.NumSlots))
+	for len(c.report.SlotHistogram) <= bucket {
+		c.report.SlotHistogram = append(c.report.SlotHistogram, 0)
+	}
+	c.report.SlotHistogram[bucket]++
+
+	if c.topN == 0 {
+		return
+	}
+	if len(c.top) < c.topN {
+		heap.Push(&c.top, stats)
+	} else if isLargerStorage(stats, c.top[0]) {
+		c.top[0] = stats
+		heap.Fix(&c.top, 0)
+	}
+}
+
+func (c *stateConsumersCollector) merge(other *stateConsumersCollector) {
+	c.report.NumAccounts += other.report.NumAccounts
+	c.report.NumSlots += other.report.NumSlots
+	c.report.NumNodes += other.report.NumNodes
+	c.report.Size += other.report.Size
+	for len(c.report.SlotHistogram) < len(other.report.SlotHistogram) {
+		c.report.SlotHistogram = append(c.report.SlotHistogram, 0)
+	}
+	for i, count := range other.report.SlotHistogram {
+		c.report.SlotHistogram[i] += count
+	}
+
+	// Accounts are only counted once, top entries are merged separately.
+	for _, cur := range other.top {
+		if len(c.top) < c.topN {
+			heap.Push(&c.top, cur)
+		} else if isLargerStorage(cur, c.top[0]) {
+			c.top[0] = cur
+			heap.Fix(&c.top, 0)
+		}
+	}
+}
+
+func (c *stateConsumersCollector) getReport() StateConsumersReport {
+	res := c.report
+	res.Top = make([]AccountStorageStatistic, len(c.top))
+	copy(res.Top, c.top)
+	sort.Slice(res.Top, func(i, j int) bool {
+		return isLargerStorage(res.Top[i], res.Top[j])
+	})
+	return res
+}
+
+// isLargerStorage defines the order of accounts in the top N list.
+func isLargerStorage(a, b AccountStorageStatistic) bool {
+	if a.Size != b.Size {
+		return a.Size > b.Size
+	}
+	if a.NumSlots != b.NumSlots {
+		return a.NumSlots > b.NumSlots
+	}
+	return a.Address.Compare(&b.Address) < 0
+}
+
+// accountStorageStatisticHeap is a min-heap of account statistics, keeping
+// the smallest of the retained top accounts at its root.
+type accountStorageStatisticHeap []AccountStorageStatistic
+
+func (h accountStorageStatisticHeap) Len() int { return len(h) }
+
+func (h accountStorageStatisticHeap) Less(i, j int) bool {
+	return isLargerStorage(h[j], h[i])
+}
+
+func (h accountStorageStatisticHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *accountStorageStatisticHeap) Push(x any) {
+	*h = append(*h, x.(AccountStorageStatistic))
+}
+
+func (h *accountStorageStatisticHeap) Pop() any {
+	old := *h
+	res := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return res
+}
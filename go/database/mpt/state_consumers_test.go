@@ -0,0 +1,210 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// getStateConsumersTestUpdate creates accounts 1..numAccounts where account i
+// has i storage slots.
+func getStateConsumersTestUpdate(numAccounts int) common.Update {
+	update := common.Update{}
+	for i := 1; i <= numAccounts; i++ {
+		address := common.Address{byte(i)}
+		update.CreatedAccounts = append(update.CreatedAccounts, address)
+		update.Nonces = append(update.Nonces, common.NonceUpdate{Account: address, Nonce: common.ToNonce(1)})
+		for j := 0; j < i; j++ {
+			update.Slots = append(update.Slots, common.SlotUpdate{Account: address, Key: common.Key{byte(j)}, Value: common.Value{byte(j + 1)}})
+		}
+	}
+	return update
+}
+
+func TestGetStateConsumers_LiveDbReportsLargestAccounts(t *testing.T) {
+	for _, config := range []MptConfig{S4LiveConfig, S5LiveConfig} {
+		t.Run(config.Name, func(t *testing.T) {
+			state, err := OpenGoMemoryState(t.TempDir(), config, DefaultMptStateCapacity)
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			defer state.Close()
+			applyTrieDiffTestUpdates(t, state, getStateConsumersTestUpdate(10))
+
+			for _, workers := range []int{0, 1, 3} {
+				report, err := state.GetStateConsumers(context.Background(), StateConsumersConfig{TopN: 3, NumWorkers: workers})
+				if err != nil {
+					t.Fatalf("failed to get state consumers: %v", err)
+				}
+				if want, got := 10, report.NumAccounts; want != got {
+					t.Errorf("unexpected number of accounts, wanted %d, got %d", want, got)
+				}
+				if want, got := 55, report.NumSlots; want != got {
+					t.Errorf("unexpected number of slots, wanted %d, got %d", want, got)
+				}
+				if report.NumNodes <= report.NumSlots || report.Size == 0 {
+					t.Errorf("storage nodes and sizes are not covered, got %d nodes, %d bytes", report.NumNodes, report.Size)
+				}
+
+				if len(report.Top) != 3 {
+					t.Fatalf("unexpected number of top accounts, got %v", report.Top)
+				}
+				for i, cur := range report.Top {
+					if want := (common.Address{byte(10 - i)}); cur.Address != want {
+						t.Errorf("unexpected account at rank %d, wanted %x, got %x", i+1, want, cur.Address)
+					}
+					if want := 10 - i; cur.NumSlots != want {
+						t.Errorf("unexpected number of slots at rank %d, wanted %d, got %d", i+1, want, cur.NumSlots)
+					}
+					if cur.Depth < 2 || cur.NumNodes <= cur.NumSlots {
+						t.Errorf("unexpected trie shape at rank %d, got %+v", i+1, cur)
+					}
+				}
+
+				// 1 account with 1 slot, 2 with 2-3, 4 with 4-7, 3 with 8-15
+				if want, got := []int{0, 1, 2, 4, 3}, report.SlotHistogram; !slices.Equal(want, got) {
+					t.Errorf("unexpected slot histogram, wanted %v, got %v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetStateConsumers_AccountsWithoutStorageAreCovered(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	applyTrieDiffTestUpdates(t, state, common.Update{
+		CreatedAccounts: []common.Address{{1}, {2}},
+		Nonces: []common.NonceUpdate{
+			{Account: common.Address{1}, Nonce: common.ToNonce(1)},
+			{Account: common.Address{2}, Nonce: common.ToNonce(1)},
+		},
+		Slots: []common.SlotUpdate{{Account: common.Address{2}, Key: common.Key{1}, Value: common.Value{1}}},
+	})
+
+	report, err := state.GetStateConsumers(context.Background(), StateConsumersConfig{TopN: 5})
+	if err != nil {
+		t.Fatalf("failed to get state consumers: %v", err)
+	}
+	want := []AccountStorageStatistic{
+		{Address: common.Address{2}, NumSlots: 1, NumNodes: 1, Depth: 1, Size: report.Top[0].Size},
+		{Address: common.Address{1}},
+	}
+	if !slices.Equal(want, report.Top) {
+		t.Errorf("unexpected top accounts, wanted %v, got %v", want, report.Top)
+	}
+	if want, got := []int{1, 1}, report.SlotHistogram; !slices.Equal(want, got) {
+		t.Errorf("unexpected slot histogram, wanted %v, got %v", want, got)
+	}
+}
+
+func TestGetStateConsumers_EmptyStateHasNoAccounts(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	report, err := state.GetStateConsumers(context.Background(), StateConsumersConfig{TopN: 5})
+	if err != nil {
+		t.Fatalf("failed to get state consumers: %v", err)
+	}
+	if report.NumAccounts != 0 || len(report.Top) != 0 || len(report.SlotHistogram) != 0 {
+		t.Errorf("empty state should have no accounts, got %+v", report)
+	}
+}
+
+func TestGetStateConsumers_ArchiveBlocksCanBeAnalyzed(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	for i, numAccounts := range []int{2, 5} {
+		update := getStateConsumersTestUpdate(numAccounts)
+		if err := update.Normalize(); err != nil {
+			t.Fatalf("failed to normalize update: %v", err)
+		}
+		if err := archive.Add(uint64(i), update, nil); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+
+	for block, want := range []int{2, 5} {
+		report, err := archive.GetStateConsumers(context.Background(), uint64(block), StateConsumersConfig{TopN: 1})
+		if err != nil {
+			t.Fatalf("failed to get state consumers of block %d: %v", block, err)
+		}
+		if report.NumAccounts != want {
+			t.Errorf("unexpected number of accounts in block %d, wanted %d, got %d", block, want, report.NumAccounts)
+		}
+		if len(report.Top) != 1 || report.Top[0].Address != (common.Address{byte(want)}) {
+			t.Errorf("unexpected top account in block %d, got %v", block, report.Top)
+		}
+	}
+
+	if _, err := archive.GetStateConsumers(context.Background(), 2, StateConsumersConfig{}); err == nil {
+		t.Errorf("analyzing a missing block should fail")
+	}
+}
+
+func TestGetStateConsumers_InvalidConfigurationIsRejected(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	if _, err := state.GetStateConsumers(context.Background(), StateConsumersConfig{TopN: -1}); err == nil {
+		t.Errorf("negative number of top accounts should be rejected")
+	}
+}
+
+func TestGetStateConsumers_CanceledContextAbortsAnalysis(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+	applyTrieDiffTestUpdates(t, state, getStateConsumersTestUpdate(10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := state.GetStateConsumers(ctx, StateConsumersConfig{TopN: 3}); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestStateConsumersReport_StringListsTopAccountsAndHistogram(t *testing.T) {
+	report := StateConsumersReport{
+		NumAccounts:   3,
+		NumSlots:      5,
+		Top:           []AccountStorageStatistic{{Address: common.Address{1}, NumSlots: 5, NumNodes: 7, Depth: 3, Size: 100}},
+		SlotHistogram: []int{2, 0, 0, 1},
+	}
+	text := report.String()
+	for _, want := range []string{
+		"Accounts: 3, slots: 5",
+		"1, 0100000000000000000000000000000000000000, 5, 7, 3, 100",
+		"0, 2\n1, 0\n2-3, 0\n4-7, 1\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in report:\n%s", want, text)
+		}
+	}
+}
@@ -264,6 +264,20 @@ func (mr *MockDatabaseMockRecorder) setHashesFor(root, hashes any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setHashesFor", reflect.TypeOf((*MockDatabase)(nil).setHashesFor), root, hashes)
 }
 
+// setWitnessRecorder mocks base method.
+func (m *MockDatabase) setWitnessRecorder(recorder *WitnessRecorder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "setWitnessRecorder", recorder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// setWitnessRecorder indicates an expected call of setWitnessRecorder.
+func (mr *MockDatabaseMockRecorder) setWitnessRecorder(recorder any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setWitnessRecorder", reflect.TypeOf((*MockDatabase)(nil).setWitnessRecorder), recorder)
+}
+
 // updateHashesFor mocks base method.
 func (m *MockDatabase) updateHashesFor(ref *NodeReference) (common.Hash, *NodeHashes, error) {
 	m.ctrl.T.Helper()
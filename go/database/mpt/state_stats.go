@@ -0,0 +1,344 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// StateStats summarizes the size of the state at the end of a block.
+type StateStats struct {
+	NumAccounts uint64 // < the number of existing accounts
+	NumSlots    uint64 // < the number of storage slots with a non-zero value
+	CodeBytes   uint64 // < the total size of the codes of all accounts
+}
+
+// stateStatsFileName is the name of the file storing the per-block state
+// statistics of an archive within the archive's directory.
+const stateStatsFileName = "stats.dat"
+
+// stateSizeCounter is implemented by node managers counting the accounts and
+// storage values inserted into and deleted from their tries.
+type stateSizeCounter interface {
+	getInsertedStateSize() (accounts, values int64)
+}
+
+// stateStatsTracker is an update target forwarding all modifications to a
+// LiveState while tracking the changes of the statistics of the resulting
+// state. Accounts and slots inserted or deleted by the modifications are
+// counted by the nodes of the modified tries, such that only code sizes need
+// to be looked up. The storage tries of deleted and re-created accounts and
+// the codes of deleted accounts are discarded as a whole; they are only
+// recorded here and accounted for by a stateStatsWorker.
+type stateStatsTracker struct {
+	head    LiveState
+	counter stateSizeCounter
+	// the counts of the counter when the tracking started
+	accounts, values int64
+	// the change of the total code size caused by code updates
+	codeBytes int64
+	// the accounts whose storage is discarded
+	discarded []common.Address
+	// the accounts whose code is discarded
+	deleted []common.Address
+}
+
+func newStateStatsTracker(head LiveState, counter stateSizeCounter) *stateStatsTracker {
+	res := &stateStatsTracker{head: head, counter: counter}
+	res.accounts, res.values = counter.getInsertedStateSize()
+	return res
+}
+
+// getJob returns a job completing the statistics of a block in which all
+// forwarded modifications have been applied to the state of the block with
+// the given root.
+func (t *stateStatsTracker) getJob(before NodeReference) stateStatsJob {
+	accounts, values := t.counter.getInsertedStateSize()
+	return stateStatsJob{
+		before:    before,
+		accounts:  accounts - t.accounts,
+		values:    values - t.values,
+		codeBytes: t.codeBytes,
+		discarded: t.discarded,
+		deleted:   t.deleted,
+	}
+}
+
+func (t *stateStatsTracker) CreateAccount(address common.Address) error {
+	// Re-creating an existing account discards its storage.
+	if err := t.head.CreateAccount(address); err != nil {
+		return err
+	}
+	t.discarded = append(t.discarded, address)
+	return nil
+}
+
+func (t *stateStatsTracker) DeleteAccount(address common.Address) error {
+	if err := t.head.DeleteAccount(address); err != nil {
+		return err
+	}
+	t.discarded = append(t.discarded, address)
+	t.deleted = append(t.deleted, address)
+	return nil
+}
+
+func (t *stateStatsTracker) SetBalance(address common.Address, balance common.Balance) error {
+	return t.head.SetBalance(address, balance)
+}
+
+func (t *stateStatsTracker) SetNonce(address common.Address, nonce common.Nonce) error {
+	return t.head.SetNonce(address, nonce)
+}
+
+func (t *stateStatsTracker) SetCode(address common.Address, code []byte) error {
+	size, err := t.head.GetCodeSize(address)
+	if err != nil {
+		return err
+	}
+	if err := t.head.SetCode(address, code); err != nil {
+		return err
+	}
+	// Setting an empty code for a missing account is a no-op, any other
+	// update results in an account with the given code.
+	t.codeBytes += int64(len(code)) - int64(size)
+	return nil
+}
+
+func (t *stateStatsTracker) SetStorage(address common.Address, key common.Key, value common.Value) error {
+	return t.head.SetStorage(address, key, value)
+}
+
+// stateStatsJob describes the statistics of a block relative to the
+// statistics of its predecessor, except for the discarded storage and codes.
+type stateStatsJob struct {
+	before    NodeReference    // < the root of the state of the preceding block
+	accounts  int64            // < the change of the number of accounts
+	values    int64            // < the change of the number of slots, excluding discarded slots
+	codeBytes int64            // < the change of the code size, excluding discarded codes
+	discarded []common.Address // < accounts of which the storage is discarded
+	deleted   []common.Address // < accounts of which the code is discarded
+	done      chan struct{}    // < if not nil, the job is a sync request closing this channel
+}
+
+// stateStatsWorker completes the statistics of blocks added to an archive in
+// the background. Counting the slots of discarded storage tries requires a
+// walk over those tries, which must not delay the addition of blocks. The
+// statistics of the blocks are appended to the archive in block order.
+type stateStatsWorker struct {
+	archive  *ArchiveTrie
+	jobs     chan stateStatsJob
+	finished chan struct{}
+}
+
+// stateStatsQueueSize is the maximum number of blocks of which the statistics
+// may be pending. Adding further blocks blocks until the worker caught up.
+const stateStatsQueueSize = 1024
+
+func startStateStatsWorker(archive *ArchiveTrie) *stateStatsWorker {
+	worker := &stateStatsWorker{
+		archive:  archive,
+		jobs:     make(chan stateStatsJob, stateStatsQueueSize),
+		finished: make(chan struct{}),
+	}
+	go worker.run()
+	return worker
+}
+
+// add schedules the completion of the statistics of the next block.
+func (w *stateStatsWorker) add(job stateStatsJob) {
+	w.jobs <- job
+}
+
+// sync waits until the statistics of all scheduled blocks are completed.
+func (w *stateStatsWorker) sync() {
+	done := make(chan struct{})
+	w.jobs <- stateStatsJob{done: done}
+	<-done
+}
+
+// close stops the worker after completing all scheduled blocks.
+func (w *stateStatsWorker) close() {
+	close(w.jobs)
+	<-w.finished
+}
+
+func (w *stateStatsWorker) run() {
+	defer close(w.finished)
+	for job := range w.jobs {
+		if job.done != nil {
+			close(job.done)
+			continue
+		}
+		// After a failure, the statistics of further blocks are unknown.
+		if w.archive.CheckErrors() != nil {
+			continue
+		}
+		discarded, err := getDiscardedStateSize(w.archive, job)
+		if err != nil {
+			w.archive.addError(fmt.Errorf("failed to compute state statistics: %w", err))
+			continue
+		}
+		a := w.archive
+		a.rootsMutex.Lock()
+		stats := a.getCurrentStateStats()
+		stats.NumAccounts = uint64(int64(stats.NumAccounts) + job.accounts)
+		stats.NumSlots = uint64(int64(stats.NumSlots)+job.values) - discarded.NumSlots
+		stats.CodeBytes = uint64(int64(stats.CodeBytes)+job.codeBytes) - discarded.CodeBytes
+		a.stats = append(a.stats, stats)
+		a.rootsMutex.Unlock()
+	}
+}
+
+// getDiscardedStateSize counts the slots and code bytes discarded by the
+// given job in the state it has been applied to.
+func getDiscardedStateSize(archive *ArchiveTrie, job stateStatsJob) (StateStats, error) {
+	res := StateStats{}
+	seen := make(map[common.Address]bool, len(job.discarded))
+	for _, address := range job.discarded {
+		// Accounts deleted and re-created in a block discard their storage once.
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		account, err := findAccount(archive.nodeSource, job.before, address)
+		if err != nil || account == nil || account.storage.Id().IsEmpty() {
+			if err != nil {
+				return res, err
+			}
+			continue
+		}
+		stats, err := getAccountStorageStatistic(context.Background(), archive.nodeSource, storageRoot{address: address, root: account.storage}, storageNodeSizes{})
+		if err != nil {
+			return res, err
+		}
+		res.NumSlots += uint64(stats.NumSlots)
+	}
+	for _, address := range job.deleted {
+		account, err := findAccount(archive.nodeSource, job.before, address)
+		if err != nil {
+			return res, err
+		}
+		if account != nil {
+			res.CodeBytes += uint64(len(archive.head.GetCodeForHash(account.info.CodeHash)))
+		}
+	}
+	return res, nil
+}
+
+// findAccount locates the given account in the trie with the given root. The
+// result is nil if the account does not exist.
+func findAccount(source NodeSource, root NodeReference, address common.Address) (*accountLookup, error) {
+	var res *accountLookup
+	_, err := VisitPathToAccount(source, &root, address, MakeVisitor(func(node Node, _ NodeInfo) VisitResponse {
+		if account, ok := node.(*AccountNode); ok && account.address == address {
+			res = &accountLookup{info: account.info, storage: NewNodeReference(account.storage.Id())}
+		}
+		return VisitResponseContinue
+	}))
+	return res, err
+}
+
+// accountLookup is the information of an account located by findAccount.
+type accountLookup struct {
+	info    AccountInfo
+	storage NodeReference
+}
+
+// GetStateStats provides the statistics of the states of the blocks in the
+// range [from, to], the i-th entry describing block from+i. The statistics
+// are maintained while blocks are added and are thus only available if the
+// archive has been tracking them since its first block. Archives created
+// before the statistics were introduced are not backfilled and thus never
+// provide statistics; they need to be re-created, e.g. by a migration.
+func (a *ArchiveTrie) GetStateStats(from, to uint64) ([]StateStats, error) {
+	if a.statsWorker != nil {
+		a.statsWorker.sync()
+	}
+	if err := a.CheckErrors(); err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", from, to)
+	}
+	a.rootsMutex.Lock()
+	defer a.rootsMutex.Unlock()
+	if a.stats == nil {
+		return nil, fmt.Errorf("state statistics are not available, the archive has been created without tracking them")
+	}
+	if to >= uint64(len(a.roots)) {
+		return nil, fmt.Errorf("block %d not present in archive, number of blocks is %d", to, len(a.roots))
+	}
+	res := make([]StateStats, to-from+1)
+	copy(res, a.stats[from:to+1])
+	return res, nil
+}
+
+// ---- Reading and Writing State Statistics ----
+
+// loadStateStats loads the per-block statistics stored in the given file.
+// If the file does not exist, an empty list is returned.
+func loadStateStats(filename string) ([]StateStats, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return []StateStats{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return loadStateStatsFrom(bufio.NewReader(f))
+}
+
+func loadStateStatsFrom(reader io.Reader) ([]StateStats, error) {
+	res := []StateStats{}
+	var buffer [24]byte
+	for {
+		if _, err := io.ReadFull(reader, buffer[:]); err != nil {
+			if err == io.EOF {
+				return res, nil
+			}
+			return nil, fmt.Errorf("invalid state statistics file format: %v", err)
+		}
+		res = append(res, StateStats{
+			NumAccounts: binary.BigEndian.Uint64(buffer[0:8]),
+			NumSlots:    binary.BigEndian.Uint64(buffer[8:16]),
+			CodeBytes:   binary.BigEndian.Uint64(buffer[16:24]),
+		})
+	}
+}
+
+func storeStateStats(filename string, stats []StateStats) error {
+	return writeFileAtomically(filename, func(writer io.Writer) error {
+		return storeStateStatsTo(writer, stats)
+	})
+}
+
+func storeStateStatsTo(writer io.Writer, stats []StateStats) error {
+	// Simple file format: [<accounts><slots><code-bytes>]*
+	var buffer [24]byte
+	for _, cur := range stats {
+		binary.BigEndian.PutUint64(buffer[0:8], cur.NumAccounts)
+		binary.BigEndian.PutUint64(buffer[8:16], cur.NumSlots)
+		binary.BigEndian.PutUint64(buffer[16:24], cur.CodeBytes)
+		if _, err := writer.Write(buffer[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
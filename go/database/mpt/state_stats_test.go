@@ -0,0 +1,340 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"go.uber.org/mock/gomock"
+)
+
+func addStateStatsTestBlocks(t *testing.T, archive *ArchiveTrie, blocks map[uint64]common.Update) {
+	t.Helper()
+	numbers := make([]uint64, 0, len(blocks))
+	for block := range blocks {
+		numbers = append(numbers, block)
+	}
+	slices.Sort(numbers)
+	for _, block := range numbers {
+		update := blocks[block]
+		if err := update.Normalize(); err != nil {
+			t.Fatalf("failed to normalize update: %v", err)
+		}
+		if err := archive.Add(block, update, nil); err != nil {
+			t.Fatalf("failed to add block %d: %v", block, err)
+		}
+	}
+}
+
+func TestArchiveTrie_StateStatsAreMaintainedIncrementally(t *testing.T) {
+	addr1 := common.Address{1}
+	addr2 := common.Address{2}
+	addr3 := common.Address{3}
+
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{
+		0: {
+			CreatedAccounts: []common.Address{addr1, addr2},
+			Codes:           []common.CodeUpdate{{Account: addr1, Code: []byte{1, 2, 3}}},
+			Slots: []common.SlotUpdate{
+				{Account: addr1, Key: common.Key{1}, Value: common.Value{1}},
+				{Account: addr1, Key: common.Key{2}, Value: common.Value{2}},
+				{Account: addr2, Key: common.Key{1}, Value: common.Value{1}},
+			},
+		},
+		// accounts are created implicitly, slots are overwritten and cleared, codes replaced
+		1: {
+			Balances: []common.BalanceUpdate{{Account: addr3, Balance: common.Balance{1}}},
+			Codes:    []common.CodeUpdate{{Account: addr1, Code: []byte{1, 2, 3, 4, 5}}},
+			Slots: []common.SlotUpdate{
+				{Account: addr1, Key: common.Key{1}, Value: common.Value{7}},
+				{Account: addr1, Key: common.Key{2}, Value: common.Value{}},
+				{Account: addr2, Key: common.Key{9}, Value: common.Value{}},
+			},
+		},
+		// deleting an account drops its storage and code, re-creating clears storage
+		2: {
+			DeletedAccounts: []common.Address{addr1, {0xAA}},
+			CreatedAccounts: []common.Address{addr2},
+		},
+		// block 3 is skipped, no-op updates have no effect
+		4: {
+			Balances: []common.BalanceUpdate{{Account: addr3, Balance: common.Balance{1}}},
+			Slots:    []common.SlotUpdate{{Account: addr3, Key: common.Key{1}, Value: common.Value{1}}},
+		},
+	})
+
+	want := []StateStats{
+		{NumAccounts: 2, NumSlots: 3, CodeBytes: 3},
+		{NumAccounts: 3, NumSlots: 2, CodeBytes: 5},
+		{NumAccounts: 2, NumSlots: 0, CodeBytes: 0},
+		{NumAccounts: 2, NumSlots: 0, CodeBytes: 0},
+		{NumAccounts: 2, NumSlots: 1, CodeBytes: 0},
+	}
+	got, err := archive.GetStateStats(0, 4)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	if !slices.Equal(want, got) {
+		t.Errorf("unexpected state statistics, wanted %v, got %v", want, got)
+	}
+
+	got, err = archive.GetStateStats(1, 2)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	if !slices.Equal(want[1:3], got) {
+		t.Errorf("unexpected state statistics, wanted %v, got %v", want[1:3], got)
+	}
+}
+
+func TestArchiveTrie_StateStatsMatchTheContentOfTheTries(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	blocks := map[uint64]common.Update{0: getStateConsumersTestUpdate(20)}
+	for i := 1; i < 10; i++ {
+		update := common.Update{}
+		for j := 0; j < 20; j += i + 1 {
+			address := common.Address{byte(j)}
+			switch (i + j) % 3 {
+			case 0:
+				update.DeletedAccounts = append(update.DeletedAccounts, address)
+			case 1:
+				update.CreatedAccounts = append(update.CreatedAccounts, address)
+			}
+			update.Slots = append(update.Slots, common.SlotUpdate{Account: address, Key: common.Key{byte(i)}, Value: common.Value{byte(j % 2)}})
+			update.Codes = append(update.Codes, common.CodeUpdate{Account: address, Code: bytes.Repeat([]byte{1}, i+j)})
+		}
+		blocks[uint64(i)] = update
+	}
+	addStateStatsTestBlocks(t, archive, blocks)
+
+	stats, err := archive.GetStateStats(0, 9)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	for block, cur := range stats {
+		report, err := archive.GetStateConsumers(context.Background(), uint64(block), StateConsumersConfig{})
+		if err != nil {
+			t.Fatalf("failed to analyze block %d: %v", block, err)
+		}
+		if cur.NumAccounts != uint64(report.NumAccounts) || cur.NumSlots != uint64(report.NumSlots) {
+			t.Errorf("statistics of block %d do not match the trie, got %v, trie has %d accounts and %d slots", block, cur, report.NumAccounts, report.NumSlots)
+		}
+		codeBytes := uint64(0)
+		for i := 0; i < 256; i++ {
+			code, err := archive.GetCode(uint64(block), common.Address{byte(i)})
+			if err != nil {
+				t.Fatalf("failed to get code: %v", err)
+			}
+			codeBytes += uint64(len(code))
+		}
+		if cur.CodeBytes != codeBytes {
+			t.Errorf("unexpected code bytes of block %d, wanted %d, got %d", block, codeBytes, cur.CodeBytes)
+		}
+	}
+}
+
+func TestArchiveTrie_StateStatsArePersisted(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{0: getStateConsumersTestUpdate(3)})
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	archive, err = OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to reopen archive: %v", err)
+	}
+	defer archive.Close()
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{1: getStateConsumersTestUpdate(4)})
+
+	want := []StateStats{{NumAccounts: 3, NumSlots: 6}, {NumAccounts: 4, NumSlots: 10}}
+	got, err := archive.GetStateStats(0, 1)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	if !slices.Equal(want, got) {
+		t.Errorf("unexpected state statistics, wanted %v, got %v", want, got)
+	}
+}
+
+func TestArchiveTrie_StateStatsAreUnavailableIfNotCoveringAllBlocks(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{0: getStateConsumersTestUpdate(3)})
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, stateStatsFileName)); err != nil {
+		t.Fatalf("failed to remove statistics: %v", err)
+	}
+
+	archive, err = OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to reopen archive: %v", err)
+	}
+	defer archive.Close()
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{1: getStateConsumersTestUpdate(4)})
+	if _, err := archive.GetStateStats(0, 1); err == nil {
+		t.Errorf("statistics of archives not tracking them from the start should be unavailable")
+	}
+	if _, err := os.Stat(filepath.Join(dir, stateStatsFileName)); err == nil {
+		t.Errorf("statistics should not be stored if they are not tracked")
+	}
+}
+
+func TestArchiveTrie_StateStatsOfInvalidRangesAreRejected(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{0: getStateConsumersTestUpdate(3)})
+
+	if _, err := archive.GetStateStats(1, 0); err == nil {
+		t.Errorf("inverted range should be rejected")
+	}
+	if _, err := archive.GetStateStats(0, 1); err == nil {
+		t.Errorf("range exceeding the archive should be rejected")
+	}
+}
+
+func TestStateStats_CanBeStoredAndLoaded(t *testing.T) {
+	file := filepath.Join(t.TempDir(), stateStatsFileName)
+	want := []StateStats{{1, 2, 3}, {4, 5, 6}, {0, 0, 1 << 40}}
+	if err := storeStateStats(file, want); err != nil {
+		t.Fatalf("failed to store statistics: %v", err)
+	}
+	got, err := loadStateStats(file)
+	if err != nil {
+		t.Fatalf("failed to load statistics: %v", err)
+	}
+	if !slices.Equal(want, got) {
+		t.Errorf("unexpected statistics, wanted %v, got %v", want, got)
+	}
+
+	if err := os.WriteFile(file, make([]byte, 30), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := loadStateStats(file); err == nil {
+		t.Errorf("loading truncated statistics should fail")
+	}
+}
+
+func TestArchiveTrie_StateStatsFailingReadsInvalidateArchive(t *testing.T) {
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	head := archive.head
+	defer head.Close()
+
+	injectedErr := errors.New("injected error")
+	ctrl := gomock.NewController(t)
+	live := NewMockLiveState(ctrl)
+	live.EXPECT().GetCodeSize(gomock.Any()).Return(0, injectedErr)
+	archive.head = live
+
+	update := common.Update{Codes: []common.CodeUpdate{{Account: common.Address{1}, Code: []byte{1}}}}
+	if err := archive.Add(0, update, nil); !errors.Is(err, injectedErr) {
+		t.Errorf("unexpected error, wanted %v, got %v", injectedErr, err)
+	}
+	if _, err := archive.GetStateStats(0, 0); !errors.Is(err, injectedErr) {
+		t.Errorf("unexpected error, wanted %v, got %v", injectedErr, err)
+	}
+}
+
+func TestArchiveTrie_StateStatsAreStoredBeforeTheRoots(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{0: getStateConsumersTestUpdate(3)})
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	// A crash between storing the statistics and the roots leaves
+	// statistics of additional blocks behind, which are ignored.
+	stats, err := loadStateStats(filepath.Join(dir, stateStatsFileName))
+	if err != nil {
+		t.Fatalf("failed to load statistics: %v", err)
+	}
+	stats = append(stats, StateStats{NumAccounts: 7})
+	if err := storeStateStats(filepath.Join(dir, stateStatsFileName), stats); err != nil {
+		t.Fatalf("failed to store statistics: %v", err)
+	}
+
+	archive, err = OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to reopen archive: %v", err)
+	}
+	defer archive.Close()
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{1: {}})
+
+	want := []StateStats{{NumAccounts: 3, NumSlots: 6}, {NumAccounts: 3, NumSlots: 6}}
+	got, err := archive.GetStateStats(0, 1)
+	if err != nil {
+		t.Fatalf("failed to get state statistics: %v", err)
+	}
+	if !slices.Equal(want, got) {
+		t.Errorf("unexpected state statistics, wanted %v, got %v", want, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, stateStatsFileName+".tmp")); err == nil {
+		t.Errorf("temporary statistics file should have been renamed")
+	}
+}
+
+func TestInitializeArchiveMetadata_NonEmptyEarlierBlocksAreRejected(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := OpenArchiveTrie(dir, S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	addStateStatsTestBlocks(t, archive, map[uint64]common.Update{
+		0: getStateConsumersTestUpdate(3),
+		1: getStateConsumersTestUpdate(4),
+	})
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, stateStatsFileName)); err != nil {
+		t.Fatalf("failed to remove statistics: %v", err)
+	}
+	if err := InitializeArchiveMetadata(dir, S5ArchiveConfig); err == nil {
+		t.Errorf("initializing the metadata of archives with non-empty earlier blocks should fail")
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
@@ -782,3 +783,272 @@ func BenchmarkMptState_ArchiveFlush(b *testing.B) {
 func BenchmarkMptState_ArchiveFlushForcedDirty(b *testing.B) {
 	runFlushBenchmark(b, S5ArchiveConfig, true)
 }
+
+func TestState_GetHashAfter_ProducesHashOfUpdatedStateWithoutModifyingState(t *testing.T) {
+	for _, config := range []MptConfig{S4LiveConfig, S5LiveConfig} {
+		t.Run(config.Name, func(t *testing.T) {
+			state, err := OpenGoMemoryState(t.TempDir(), config, 1024)
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			defer state.Close()
+
+			witnessTestPreState(t, state)
+			before, err := state.GetHash()
+			if err != nil {
+				t.Fatalf("failed to get hash: %v", err)
+			}
+
+			update, _ := witnessTestBlock(t, state)
+			preview, err := state.GetHashAfter(update)
+			if err != nil {
+				t.Fatalf("failed to compute hash preview: %v", err)
+			}
+
+			if got, err := state.GetHash(); err != nil || got != before {
+				t.Fatalf("state was modified by preview, wanted %x, got %x, err %v", before, got, err)
+			}
+			if exists, err := state.Exists(common.Address{0xFF}); err != nil || exists {
+				t.Errorf("account created by preview should not exist, exists %t, err %v", exists, err)
+			}
+			if err := state.trie.Check(); err != nil {
+				t.Errorf("state is inconsistent after preview: %v", err)
+			}
+
+			if _, err := state.Apply(1, update); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			if got, err := state.GetHash(); err != nil || got != preview {
+				t.Errorf("unexpected hash after update, wanted %x, got %x, err %v", preview, got, err)
+			}
+		})
+	}
+}
+
+func TestState_GetHashAfter_LargeUpdatesExceedingOverlayCacheAreSupported(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 10*MinMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	witnessTestPreState(t, state)
+	update := common.Update{}
+	for i := 0; i < 2*MinMptStateCapacity; i++ {
+		addr := common.Address{0xAB, byte(i), byte(i >> 8)}
+		update.AppendCreateAccount(addr)
+		update.AppendBalanceUpdate(addr, common.Balance{1})
+	}
+	if err := update.Normalize(); err != nil {
+		t.Fatalf("failed to normalize update: %v", err)
+	}
+
+	preview, err := state.GetHashAfter(update)
+	if err != nil {
+		t.Fatalf("failed to compute hash preview: %v", err)
+	}
+	if _, err := state.Apply(1, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if got, err := state.GetHash(); err != nil || got != preview {
+		t.Errorf("unexpected hash after update, wanted %x, got %x, err %v", preview, got, err)
+	}
+}
+
+func TestState_HeadBlockIsRecordedByApplyAndPersisted(t *testing.T) {
+	for name, open := range mptStateFactories {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			state, err := open(dir)
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			if _, found := state.GetHeadBlock(); found {
+				t.Errorf("fresh state should have no head block")
+			}
+
+			state.SetNextBlockRecord([]byte{1, 2, 3})
+			if _, err := state.Apply(5, common.Update{}); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			want := HeadBlock{Number: 5, Record: []byte{1, 2, 3}}
+			if got, found := state.GetHeadBlock(); !found || !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected head block, wanted %v, got %v, found %t", want, got, found)
+			}
+
+			// The record is only used for a single block.
+			if _, err := state.Apply(7, common.Update{}); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			want = HeadBlock{Number: 7}
+			if got, found := state.GetHeadBlock(); !found || !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected head block, wanted %v, got %v, found %t", want, got, found)
+			}
+
+			state.SetNextBlockRecord([]byte{4})
+			if _, err := state.Apply(8, common.Update{}); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			if err := state.Close(); err != nil {
+				t.Fatalf("failed to close state: %v", err)
+			}
+
+			state, err = open(dir)
+			if err != nil {
+				t.Fatalf("failed to reopen state: %v", err)
+			}
+			defer state.Close()
+			want = HeadBlock{Number: 8, Record: []byte{4}}
+			if got, found := state.GetHeadBlock(); !found || !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected head block after reopening, wanted %v, got %v, found %t", want, got, found)
+			}
+		})
+	}
+}
+
+func TestState_BlockRecordIsResetByFailingApply(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	injectedErr := errors.New("injectedError")
+	ctrl := gomock.NewController(t)
+	db := NewMockDatabase(ctrl)
+	db.EXPECT().GetAccountInfo(gomock.Any(), gomock.Any()).Return(AccountInfo{}, false, injectedErr)
+	forest := state.trie.forest
+	state.trie.forest = db
+
+	state.SetNextBlockRecord([]byte{1, 2, 3})
+	update := common.Update{Balances: []common.BalanceUpdate{{Account: common.Address{1}, Balance: common.Balance{1}}}}
+	if _, err := state.Apply(5, update); !errors.Is(err, injectedErr) {
+		t.Fatalf("unexpected error, wanted %v, got %v", injectedErr, err)
+	}
+
+	// The record of the failed block is not used for the next block.
+	state.trie.forest = forest
+	if _, err := state.Apply(6, common.Update{}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	want := HeadBlock{Number: 6}
+	if got, found := state.GetHeadBlock(); !found || !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected head block, wanted %v, got %v, found %t", want, got, found)
+	}
+}
+
+func TestMptState_GetDiskFootprint_CoversTrieAndCodes(t *testing.T) {
+	state, err := OpenGoFileState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open test state: %v", err)
+	}
+	defer state.Close()
+
+	addr1, addr2 := common.Address{1}, common.Address{2}
+	if _, err := state.Apply(1, common.Update{
+		CreatedAccounts: []common.Address{addr1, addr2},
+		Nonces:          []common.NonceUpdate{{Account: addr1, Nonce: common.ToNonce(1)}, {Account: addr2, Nonce: common.ToNonce(1)}},
+		Codes:           []common.CodeUpdate{{Account: addr1, Code: []byte{1, 2, 3}}},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if err := state.Flush(); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+
+	footprint, err := state.GetDiskFootprint()
+	if err != nil {
+		t.Fatalf("failed to get disk footprint: %v", err)
+	}
+	trie := footprint.GetChild("trie")
+	forest := trie.GetChild("forest")
+	for name, component := range map[string]*common.DiskFootprint{
+		"codes":           footprint.GetChild("codes"),
+		"trie metadata":   trie.GetChild("metadata"),
+		"forest metadata": forest.GetChild("metadata"),
+		"accounts":        forest.GetChild("accounts"),
+		"branches":        forest.GetChild("branches"),
+	} {
+		if component.Total() == 0 {
+			t.Errorf("no disk usage reported for %s:\n%v", name, footprint)
+		}
+	}
+	if got := footprint.TotalReclaimable(); got != 0 {
+		t.Errorf("unexpected reclaimable space before deletions: %d", got)
+	}
+
+	if _, err := state.Apply(2, common.Update{
+		DeletedAccounts: []common.Address{addr2},
+	}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if err := state.Flush(); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	footprint, err = state.GetDiskFootprint()
+	if err != nil {
+		t.Fatalf("failed to get disk footprint: %v", err)
+	}
+	if got := footprint.TotalReclaimable(); got == 0 {
+		t.Errorf("deleted nodes should be reclaimable:\n%v", footprint)
+	}
+}
+
+func TestState_SetCacheCapacity_ReducedCacheRetainsModifiedData(t *testing.T) {
+	dir := t.TempDir()
+	state, err := OpenGoFileState(dir, S5LiveConfig, 10*MinMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+
+	const N = 2 * MinMptStateCapacity
+	update := common.Update{}
+	for i := 0; i < N; i++ {
+		addr := common.Address{0xAB, byte(i), byte(i >> 8)}
+		update.AppendCreateAccount(addr)
+		update.AppendBalanceUpdate(addr, common.Balance{1})
+	}
+	if err := update.Normalize(); err != nil {
+		t.Fatalf("failed to normalize update: %v", err)
+	}
+	if _, err := state.Apply(1, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	want, err := state.GetHash()
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+
+	stats := state.GetCacheStatistics()
+	if stats.MinCapacity != MinMptStateCapacity || stats.MaxCapacity != 10*MinMptStateCapacity {
+		t.Errorf("unexpected capacity range: %+v", stats)
+	}
+	if stats.Size <= MinMptStateCapacity {
+		t.Errorf("cache should contain more than %d nodes, got %d", MinMptStateCapacity, stats.Size)
+	}
+
+	state.SetCacheCapacity(0)
+	stats = state.GetCacheStatistics()
+	if stats.Capacity != MinMptStateCapacity || stats.Size != MinMptStateCapacity {
+		t.Errorf("cache should be reduced to its minimum capacity, got %+v", stats)
+	}
+
+	for i := 0; i < N; i++ {
+		addr := common.Address{0xAB, byte(i), byte(i >> 8)}
+		if balance, err := state.GetBalance(addr); err != nil || balance != (common.Balance{1}) {
+			t.Fatalf("unexpected balance of %v: %v, err %v", addr, balance, err)
+		}
+	}
+	if err := state.Close(); err != nil {
+		t.Fatalf("failed to close state: %v", err)
+	}
+
+	state, err = OpenGoFileState(dir, S5LiveConfig, 10*MinMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to re-open state: %v", err)
+	}
+	defer state.Close()
+	if got, err := state.GetHash(); err != nil || got != want {
+		t.Errorf("unexpected hash after re-opening, wanted %x, got %x, err %v", want, got, err)
+	}
+}
@@ -11,6 +11,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -71,7 +72,11 @@ func checkLiveDB(dir string, info io.MptInfo) error {
 		return err
 	}
 	defer live.Close()
-	return live.Check()
+	if err := live.Check(); err != nil {
+		return err
+	}
+	fmt.Printf("Scrubbing nodes ...\n")
+	return checkScrubReport(live.Scrub(context.Background(), mpt.ScrubConfig{}, nil))
 }
 
 func checkArchive(dir string, info io.MptInfo) error {
@@ -80,5 +85,24 @@ func checkArchive(dir string, info io.MptInfo) error {
 		return err
 	}
 	defer archive.Close()
-	return archive.Check()
+	if err := archive.Check(); err != nil {
+		return err
+	}
+	fmt.Printf("Scrubbing nodes ...\n")
+	return checkScrubReport(archive.Scrub(context.Background(), mpt.ScrubConfig{}, nil))
+}
+
+// checkScrubReport lists the bad nodes of the given scrub report and fails if
+// there are any.
+func checkScrubReport(report mpt.ScrubReport, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, node := range report.BadNodes {
+		fmt.Printf("Bad node %v: %v\n", node.Id, node.Issue)
+	}
+	if len(report.BadNodes) > 0 {
+		return fmt.Errorf("scrubbing found %d bad nodes out of %d", len(report.BadNodes), report.NumNodes)
+	}
+	return nil
 }
@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+	"github.com/urfave/cli/v2"
+)
+
+var DiffCmd = cli.Command{
+	Action:    doDiff,
+	Name:      "diff",
+	Usage:     "lists the accounts, fields, and storage slots differing between two LiveDBs or Archive blocks",
+	ArgsUsage: "<director A> <director B>",
+	Flags: []cli.Flag{
+		&blockAFlag,
+		&blockBFlag,
+	},
+}
+
+var (
+	blockAFlag = cli.Int64Flag{
+		Name:  "block-a",
+		Usage: "the block to be compared if directory A contains an archive, the last block if negative",
+		Value: -1,
+	}
+	blockBFlag = cli.Int64Flag{
+		Name:  "block-b",
+		Usage: "the block to be compared if directory B contains an archive, the last block if negative",
+		Value: -1,
+	}
+)
+
+func doDiff(context *cli.Context) error {
+	if context.Args().Len() != 2 {
+		return fmt.Errorf("missing directory A and/or B parameter")
+	}
+
+	a, closeA, err := openDiffInput(context.Args().Get(0), context.Int64(blockAFlag.Name))
+	if err != nil {
+		return err
+	}
+	b, closeB, err := openDiffInput(context.Args().Get(1), context.Int64(blockBFlag.Name))
+	if err != nil {
+		return errors.Join(err, closeA())
+	}
+
+	// An interrupt aborts the comparison.
+	ctx, stop := signal.NotifyContext(context.Context, os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	logFromStart(start, "comparison started")
+	diff, err := mpt.GetTrieDiff(ctx, a, b)
+	if err == nil {
+		logFromStart(start, fmt.Sprintf("comparison done, %d accounts differ", len(diff)))
+		if len(diff) > 0 {
+			fmt.Println(diff)
+		}
+	}
+	return errors.Join(err, closeA(), closeB())
+}
+
+// openDiffInput opens the LiveDB or Archive in the given directory and
+// provides its state or the state of the given block for a comparison.
+func openDiffInput(dir string, block int64) (mpt.TrieDiffInput, func() error, error) {
+	info, err := io.CheckMptDirectoryAndGetInfo(dir)
+	if err != nil {
+		return mpt.TrieDiffInput{}, nil, err
+	}
+
+	if info.Mode == mpt.Mutable {
+		state, err := mpt.OpenGoFileState(dir, info.Config, mpt.DefaultMptStateCapacity)
+		if err != nil {
+			return mpt.TrieDiffInput{}, nil, err
+		}
+		input, err := state.GetTrieDiffInput()
+		if err != nil {
+			return mpt.TrieDiffInput{}, nil, errors.Join(err, state.Close())
+		}
+		fmt.Printf("Comparing LiveDB in %s\n", dir)
+		return input, state.Close, nil
+	}
+
+	archive, err := mpt.OpenArchiveTrie(dir, info.Config, mpt.DefaultMptStateCapacity)
+	if err != nil {
+		return mpt.TrieDiffInput{}, nil, err
+	}
+	if block < 0 {
+		height, empty, err := archive.GetBlockHeight()
+		if err != nil {
+			return mpt.TrieDiffInput{}, nil, errors.Join(err, archive.Close())
+		}
+		if empty {
+			return mpt.TrieDiffInput{}, nil, errors.Join(fmt.Errorf("archive in %s is empty", dir), archive.Close())
+		}
+		block = int64(height)
+	}
+	input, err := archive.GetTrieDiffInput(uint64(block))
+	if err != nil {
+		return mpt.TrieDiffInput{}, nil, errors.Join(err, archive.Close())
+	}
+	hash, err := archive.GetHash(uint64(block))
+	if err != nil {
+		return mpt.TrieDiffInput{}, nil, errors.Join(err, archive.Close())
+	}
+	fmt.Printf("Comparing block %d of archive in %s with hash %x\n", block, dir, hash)
+	return input, archive.Close, nil
+}
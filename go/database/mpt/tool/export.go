@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
@@ -57,11 +58,15 @@ func doExport(context *cli.Context) error {
 		return err
 	}
 
-	export := io.Export
+	export := io.ExportWithContext
 	if mptInfo.Mode == mpt.Immutable {
-		export = io.ExportArchive
+		export = io.ExportArchiveWithContext
 	}
 
+	// An interrupt aborts the export.
+	ctx, stop := signal.NotifyContext(context.Context, os.Interrupt)
+	defer stop()
+
 	start := time.Now()
 	logFromStart(start, "export started")
 	file, err := os.Create(trg)
@@ -74,7 +79,7 @@ func doExport(context *cli.Context) error {
 		logFromStart(start, "export done")
 	}()
 	return errors.Join(
-		export(dir, out),
+		export(ctx, dir, out),
 		out.Close(),
 		bufferedWriter.Flush(),
 		file.Close(),
@@ -0,0 +1,207 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+	"github.com/urfave/cli/v2"
+)
+
+var ExportChangesCmd = cli.Command{
+	Action:    doExportChanges,
+	Name:      "export-changes",
+	Usage:     "exports the per-block changes of an Archive as CSV or JSON Lines records for analytical tools",
+	ArgsUsage: "<archive director> <target-file>",
+	Flags: []cli.Flag{
+		&changeFormatFlag,
+		&fromBlockFlag,
+		&toBlockFlag,
+		&addressFlag,
+		&shardsFlag,
+	},
+}
+
+var (
+	changeFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "the format of the exported records: csv or jsonl",
+		Value: "csv",
+	}
+	fromBlockFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "the first block to be exported",
+	}
+	toBlockFlag = cli.Int64Flag{
+		Name:  "to",
+		Usage: "the last block to be exported, the last block of the archive if negative",
+		Value: -1,
+	}
+	addressFlag = cli.StringSliceFlag{
+		Name:  "address",
+		Usage: "an address whose changes should be exported, all addresses are exported if not set; may be repeated",
+	}
+	shardsFlag = cli.IntFlag{
+		Name:  "shards",
+		Usage: "the number of block ranges exported in parallel into separate files",
+		Value: 1,
+	}
+)
+
+func doExportChanges(context *cli.Context) error {
+	if context.Args().Len() != 2 {
+		return fmt.Errorf("missing archive directory and/or target file parameter")
+	}
+	dir := context.Args().Get(0)
+	trg := context.Args().Get(1)
+
+	format := context.String(changeFormatFlag.Name)
+	if format != "csv" && format != "jsonl" {
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	shards := context.Int(shardsFlag.Name)
+	if shards < 1 {
+		return fmt.Errorf("invalid number of shards: %d", shards)
+	}
+	addresses := []common.Address{}
+	for _, cur := range context.StringSlice(addressFlag.Name) {
+		address, err := parseAddress(cur)
+		if err != nil {
+			return err
+		}
+		addresses = append(addresses, address)
+	}
+
+	info, err := io.CheckMptDirectoryAndGetInfo(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode != mpt.Immutable {
+		return fmt.Errorf("directory %s does not contain an archive", dir)
+	}
+	archive, err := mpt.OpenArchiveTrie(dir, info.Config, mpt.DefaultMptStateCapacity)
+	if err != nil {
+		return err
+	}
+
+	from := context.Uint64(fromBlockFlag.Name)
+	to := uint64(context.Int64(toBlockFlag.Name))
+	if context.Int64(toBlockFlag.Name) < 0 {
+		height, empty, err := archive.GetBlockHeight()
+		if err != nil {
+			return errors.Join(err, archive.Close())
+		}
+		if empty {
+			return errors.Join(fmt.Errorf("archive is empty"), archive.Close())
+		}
+		to = height
+	}
+	if from > to {
+		return errors.Join(fmt.Errorf("invalid block range [%d, %d]", from, to), archive.Close())
+	}
+
+	// An interrupt aborts the export.
+	ctx, stop := signal.NotifyContext(context.Context, os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	logFromStart(start, "export started")
+	ranges := splitBlockRange(from, to, shards)
+	errs := make([]error, len(ranges))
+	var wg sync.WaitGroup
+	for i, cur := range ranges {
+		file := trg
+		if len(ranges) > 1 {
+			file = getShardFileName(trg, i)
+		}
+		config := io.ChangeExportConfig{From: cur[0], To: cur[1], Addresses: addresses}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = exportChangesToFile(ctx, archive, config, format, file)
+			if errs[i] == nil {
+				logFromStart(start, fmt.Sprintf("exported blocks %d-%d into %s", config.From, config.To, file))
+			}
+		}(i)
+	}
+	wg.Wait()
+	logFromStart(start, "export done")
+	return errors.Join(errors.Join(errs...), archive.Close())
+}
+
+func exportChangesToFile(ctx context.Context, archive *mpt.ArchiveTrie, config io.ChangeExportConfig, format string, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bufferedWriter := bufio.NewWriter(file)
+	writer := io.NewCsvChangeWriter(bufferedWriter)
+	if format == "jsonl" {
+		writer = io.NewJsonChangeWriter(bufferedWriter)
+	}
+	return errors.Join(
+		io.ExportChanges(ctx, archive, config, writer),
+		bufferedWriter.Flush(),
+		file.Close(),
+	)
+}
+
+// splitBlockRange splits the block range [from, to] into at most the given
+// number of contiguous ranges of similar size.
+func splitBlockRange(from, to uint64, parts int) [][2]uint64 {
+	res := [][2]uint64{}
+	length := to - from + 1
+	size := length / uint64(parts)
+	if length%uint64(parts) != 0 {
+		size++
+	}
+	for cur := from; cur <= to; cur += size {
+		end := cur + size - 1
+		if end > to || end < cur {
+			end = to
+		}
+		res = append(res, [2]uint64{cur, end})
+		if end == to {
+			break
+		}
+	}
+	return res
+}
+
+// getShardFileName inserts the index of a shard before the extension of the
+// given file name, e.g. changes.csv becomes changes-001.csv.
+func getShardFileName(file string, shard int) string {
+	ext := filepath.Ext(file)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(file, ext), shard, ext)
+}
+
+func parseAddress(value string) (common.Address, error) {
+	var address common.Address
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(data) != len(address) {
+		return address, fmt.Errorf("invalid address: %s", value)
+	}
+	copy(address[:], data)
+	return address, nil
+}
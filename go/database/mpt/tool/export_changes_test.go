@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func TestSplitBlockRange_RangesCoverAllBlocks(t *testing.T) {
+	tests := []struct {
+		from, to uint64
+		parts    int
+		want     [][2]uint64
+	}{
+		{0, 9, 1, [][2]uint64{{0, 9}}},
+		{0, 9, 2, [][2]uint64{{0, 4}, {5, 9}}},
+		{0, 9, 3, [][2]uint64{{0, 3}, {4, 7}, {8, 9}}},
+		{5, 6, 4, [][2]uint64{{5, 5}, {6, 6}}},
+		{7, 7, 2, [][2]uint64{{7, 7}}},
+	}
+	for _, test := range tests {
+		if got := splitBlockRange(test.from, test.to, test.parts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected ranges of [%d, %d] in %d parts, wanted %v, got %v", test.from, test.to, test.parts, test.want, got)
+		}
+	}
+}
+
+func TestGetShardFileName_IndexIsInsertedBeforeExtension(t *testing.T) {
+	if want, got := "out/changes-002.csv", getShardFileName("out/changes.csv", 2); want != got {
+		t.Errorf("unexpected file name, wanted %s, got %s", want, got)
+	}
+	if want, got := "changes-010", getShardFileName("changes", 10); want != got {
+		t.Errorf("unexpected file name, wanted %s, got %s", want, got)
+	}
+}
+
+func TestParseAddress_ValidAndInvalidAddresses(t *testing.T) {
+	want := common.Address{0x12, 19: 0x34}
+	for _, input := range []string{"0x1200000000000000000000000000000000000034", "1200000000000000000000000000000000000034"} {
+		if got, err := parseAddress(input); err != nil || got != want {
+			t.Errorf("failed to parse %s, got %x, err %v", input, got, err)
+		}
+	}
+	for _, input := range []string{"", "0x12", "0xzz00000000000000000000000000000000000034"} {
+		if _, err := parseAddress(input); err == nil {
+			t.Errorf("parsing %s should fail", input)
+		}
+	}
+}
@@ -13,6 +13,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/Fantom-foundation/Carmen/go/common"
 	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 	"github.com/Fantom-foundation/Carmen/go/database/mpt/io"
 	"github.com/urfave/cli/v2"
@@ -24,6 +25,7 @@ var Info = cli.Command{
 	Usage:  "lists information about a Carmen MTP state repository",
 	Flags: []cli.Flag{
 		&statsFlag,
+		&diskUsageFlag,
 	},
 	ArgsUsage: "<director>",
 }
@@ -33,6 +35,10 @@ var (
 		Name:  "stats",
 		Usage: "Compute and print node statistics",
 	}
+	diskUsageFlag = cli.BoolFlag{
+		Name:  "disk-usage",
+		Usage: "Print the disk usage of individual components",
+	}
 )
 
 func info(context *cli.Context) error {
@@ -43,6 +49,7 @@ func info(context *cli.Context) error {
 	dir := context.Args().Get(0)
 
 	withStats := context.Bool(statsFlag.Name)
+	withDiskUsage := context.Bool(diskUsageFlag.Name)
 
 	// try to obtain information of the contained MPT
 	mptInfo, err := io.CheckMptDirectoryAndGetInfo(dir)
@@ -64,6 +71,10 @@ func info(context *cli.Context) error {
 			fmt.Printf("\tCan be opened:     Yes\n")
 		}
 
+		if err := printDiskUsage(trie, withDiskUsage); err != nil {
+			return err
+		}
+
 		if withStats {
 			fmt.Printf("\nCollecting Node Statistics ...\n")
 			stats, err := mpt.GetTrieNodeStatistics(trie)
@@ -95,6 +106,10 @@ func info(context *cli.Context) error {
 			fmt.Printf("\tBlock height:      %d\n", height)
 		}
 
+		if err := printDiskUsage(archive, withDiskUsage); err != nil {
+			return err
+		}
+
 		if err := archive.Close(); err != nil {
 			return fmt.Errorf("error closing forest: %v", err)
 		}
@@ -113,3 +128,16 @@ func info(context *cli.Context) error {
 
 	return nil
 }
+
+func printDiskUsage(provider common.DiskFootprintProvider, withDetails bool) error {
+	footprint, err := provider.GetDiskFootprint()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\tDisk usage:        %d bytes (reclaimable: %d bytes)\n", footprint.Total(), footprint.TotalReclaimable())
+	if withDetails {
+		fmt.Print("\n--- Disk Usage ---\n")
+		fmt.Println(footprint.String())
+	}
+	return nil
+}
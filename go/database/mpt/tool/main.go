@@ -37,6 +37,12 @@ func main() {
 			&Verify,
 			&Benchmark,
 			&Block,
+			&RebuildChangeIndex,
+			&MigrateArchiveCmd,
+			&MigrateLiveDbCmd,
+			&ExportChangesCmd,
+			&DiffCmd,
+			&StateConsumersCmd,
 		},
 	}
 
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/backend"
+	"github.com/Fantom-foundation/Carmen/go/backend/archive"
+	"github.com/Fantom-foundation/Carmen/go/backend/archive/ldb"
+	"github.com/Fantom-foundation/Carmen/go/backend/archive/sqlite"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/urfave/cli/v2"
+)
+
+var MigrateArchiveCmd = cli.Command{
+	Action:    doArchiveMigration,
+	Name:      "migrate-archive",
+	Usage:     "replays the history of an archive into an archive of another type",
+	ArgsUsage: "<source director> <target director>",
+	Flags: []cli.Flag{
+		&sourceArchiveTypeFlag,
+		&targetArchiveTypeFlag,
+		&migrationBatchSizeFlag,
+	},
+}
+
+var (
+	sourceArchiveTypeFlag = cli.StringFlag{
+		Name:  "source-type",
+		Usage: "the type of the source archive: ldb, sqlite, or s5",
+		Value: "ldb",
+	}
+	targetArchiveTypeFlag = cli.StringFlag{
+		Name:  "target-type",
+		Usage: "the type of the target archive: ldb, sqlite, or s5",
+		Value: "s5",
+	}
+	migrationBatchSizeFlag = cli.IntFlag{
+		Name:  "batch-size",
+		Usage: "the number of blocks reconstructed from the source at once",
+		Value: archive.DefaultMigrationBatchSize,
+	}
+)
+
+// migrationReportInterval is the number of blocks between two progress
+// reports of a migration.
+const migrationReportInterval = 100_000
+
+func doArchiveMigration(context *cli.Context) error {
+	if context.Args().Len() != 2 {
+		return fmt.Errorf("missing source and/or target directory parameter")
+	}
+	sourceDir := context.Args().Get(0)
+	targetDir := context.Args().Get(1)
+	sourceType := context.String(sourceArchiveTypeFlag.Name)
+	targetType := context.String(targetArchiveTypeFlag.Name)
+
+	source, err := openArchiveOfType(sourceType, sourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to open source archive: %w", err)
+	}
+	target, err := openArchiveOfType(targetType, targetDir)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to open target archive: %w", err), source.Close())
+	}
+
+	// LevelDB and SQLite archives share the same hash definition, MPT based
+	// archives are only compatible with archives of the same type.
+	verify := sourceType == targetType || (sourceType != "s5" && targetType != "s5")
+	if !verify {
+		fmt.Printf("Hashes of %s and %s archives are not comparable, skipping hash verification.\n", sourceType, targetType)
+	}
+
+	start := time.Now()
+	config := archive.MigrationConfig{
+		BatchSize:    context.Int(migrationBatchSizeFlag.Name),
+		VerifyHashes: verify,
+		Progress: func(block uint64) {
+			if block%migrationReportInterval == 0 {
+				t := uint64(time.Since(start).Seconds())
+				fmt.Printf("%s [t=%4d:%02d] - Migrated block %d\n", time.Now().Format("15:04:05"), t/60, t%60, block)
+			}
+		},
+	}
+	fmt.Printf("Migrating %s archive in %s to %s archive in %s ...\n", sourceType, sourceDir, targetType, targetDir)
+	if err := archive.Migrate(source, target, config); err != nil {
+		return errors.Join(err, source.Close(), target.Close())
+	}
+	if err := errors.Join(source.Close(), target.Close()); err != nil {
+		return err
+	}
+	fmt.Printf("Archive migrated successfully!\n")
+	return nil
+}
+
+// openArchiveOfType opens the archive of the given type stored in the given
+// directory, using the same layout as the archives of Go states.
+func openArchiveOfType(archiveType string, dir string) (archive.Archive, error) {
+	switch archiveType {
+	case "ldb":
+		db, err := backend.OpenLevelDb(dir, nil)
+		if err != nil {
+			return nil, err
+		}
+		arch, err := ldb.NewArchive(db)
+		if err != nil {
+			return nil, errors.Join(err, db.Close())
+		}
+		return &levelDbArchive{arch, db}, nil
+	case "sqlite":
+		return sqlite.NewArchive(filepath.Join(dir, "archive.sqlite"))
+	case "s5":
+		return mpt.OpenArchiveTrie(dir, mpt.S5ArchiveConfig, mpt.DefaultMptStateCapacity)
+	}
+	return nil, fmt.Errorf("unknown archive type: %s", archiveType)
+}
+
+// levelDbArchive closes the LevelDB instance of an archive when the archive
+// is closed.
+type levelDbArchive struct {
+	*ldb.Archive
+	db *backend.LevelDbMemoryFootprintWrapper
+}
+
+func (a *levelDbArchive) Close() error {
+	return errors.Join(a.Archive.Close(), a.db.Close())
+}
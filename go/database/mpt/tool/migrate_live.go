@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"github.com/Fantom-foundation/Carmen/go/state/gostate"
+	"github.com/urfave/cli/v2"
+)
+
+var MigrateLiveDbCmd = cli.Command{
+	Action:    doLiveDbMigration,
+	Name:      "migrate-live",
+	Usage:     "copies the LiveDB of a state of schema 1-5 into a fresh S5 LiveDB",
+	ArgsUsage: "<source state director> <target director>",
+	Flags: []cli.Flag{
+		&sourceVariantFlag,
+		&sourceSchemaFlag,
+		&migrationBlockFlag,
+	},
+}
+
+var (
+	sourceVariantFlag = cli.StringFlag{
+		Name:  "variant",
+		Usage: "the variant of the source state, e.g. go-file or go-ldb",
+		Value: string(gostate.VariantGoFile),
+	}
+	sourceSchemaFlag = cli.IntFlag{
+		Name:  "schema",
+		Usage: "the schema of the source state",
+		Value: 3,
+	}
+	migrationBlockFlag = cli.Uint64Flag{
+		Name:  "block",
+		Usage: "the block to be recorded as the head block of the new LiveDB",
+	}
+)
+
+func doLiveDbMigration(context *cli.Context) error {
+	if context.Args().Len() != 2 {
+		return fmt.Errorf("missing source and/or target directory parameter")
+	}
+	sourceDir := context.Args().Get(0)
+	targetDir := context.Args().Get(1)
+
+	source, err := state.NewState(state.Parameters{
+		Directory: sourceDir,
+		Variant:   state.Variant(context.String(sourceVariantFlag.Name)),
+		Schema:    state.Schema(context.Int(sourceSchemaFlag.Name)),
+		Archive:   state.NoArchive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open source state: %w", err)
+	}
+
+	start := time.Now()
+	fmt.Printf("Migrating LiveDB of state in %s to S5 LiveDB in %s ...\n", sourceDir, targetDir)
+	hash, err := gostate.MigrateLiveDbToS5(source, targetDir, context.Uint64(migrationBlockFlag.Name))
+	if err != nil {
+		return errors.Join(err, source.Close())
+	}
+	if err := source.Close(); err != nil {
+		return err
+	}
+	t := uint64(time.Since(start).Seconds())
+	fmt.Printf("%s [t=%4d:%02d] - LiveDB migrated and verified successfully, hash %x\n", time.Now().Format("15:04:05"), t/60, t%60, hash)
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+	"github.com/urfave/cli/v2"
+)
+
+var RebuildChangeIndex = cli.Command{
+	Action:    rebuildChangeIndex,
+	Name:      "rebuild-change-index",
+	Usage:     "rebuilds the index of modified accounts required for history queries on an archive",
+	ArgsUsage: "<director>",
+}
+
+func rebuildChangeIndex(context *cli.Context) error {
+	// parse the directory argument
+	if context.Args().Len() != 1 {
+		return fmt.Errorf("missing directory storing archive")
+	}
+	dir := context.Args().Get(0)
+
+	// try to obtain information of the contained MPT
+	info, err := io.CheckMptDirectoryAndGetInfo(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode != mpt.Immutable {
+		return fmt.Errorf("directory %s does not contain an archive", dir)
+	}
+	fmt.Printf("Rebuilding change index of archive in %s ...\n", dir)
+	if err := mpt.RebuildChangeIndex(dir, info.Config); err != nil {
+		return err
+	}
+	fmt.Printf("Change index rebuilt successfully!\n")
+	return nil
+}
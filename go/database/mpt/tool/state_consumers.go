@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+	"github.com/urfave/cli/v2"
+)
+
+var StateConsumersCmd = cli.Command{
+	Action:    doStateConsumers,
+	Name:      "state-consumers",
+	Usage:     "lists the accounts with the largest storage tries of a LiveDB or an Archive block",
+	ArgsUsage: "<director>",
+	Flags: []cli.Flag{
+		&stateConsumersBlockFlag,
+		&stateConsumersTopFlag,
+		&stateConsumersWorkersFlag,
+	},
+}
+
+var (
+	stateConsumersBlockFlag = cli.Int64Flag{
+		Name:  "block",
+		Usage: "the block to be analyzed if the directory contains an archive, the last block if negative",
+		Value: -1,
+	}
+	stateConsumersTopFlag = cli.IntFlag{
+		Name:  "top",
+		Usage: "the number of accounts with the largest storage tries to be listed",
+		Value: 20,
+	}
+	stateConsumersWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "the number of storage tries analyzed in parallel, the number of CPUs if 0",
+	}
+)
+
+func doStateConsumers(context *cli.Context) error {
+	if context.Args().Len() != 1 {
+		return fmt.Errorf("missing directory storing state")
+	}
+	dir := context.Args().Get(0)
+	config := mpt.StateConsumersConfig{
+		TopN:       context.Int(stateConsumersTopFlag.Name),
+		NumWorkers: context.Int(stateConsumersWorkersFlag.Name),
+	}
+
+	// An interrupt aborts the analysis.
+	ctx, stop := signal.NotifyContext(context.Context, os.Interrupt)
+	defer stop()
+
+	info, err := io.CheckMptDirectoryAndGetInfo(dir)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var report mpt.StateConsumersReport
+	if info.Mode == mpt.Mutable {
+		state, err := mpt.OpenGoFileState(dir, info.Config, mpt.DefaultMptStateCapacity)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Analyzing LiveDB in %s\n", dir)
+		logFromStart(start, "analysis started")
+		report, err = state.GetStateConsumers(ctx, config)
+		if err = errors.Join(err, state.Close()); err != nil {
+			return err
+		}
+	} else {
+		archive, err := mpt.OpenArchiveTrie(dir, info.Config, mpt.DefaultMptStateCapacity)
+		if err != nil {
+			return err
+		}
+		block := context.Int64(stateConsumersBlockFlag.Name)
+		if block < 0 {
+			height, empty, err := archive.GetBlockHeight()
+			if err != nil {
+				return errors.Join(err, archive.Close())
+			}
+			if empty {
+				return errors.Join(fmt.Errorf("archive in %s is empty", dir), archive.Close())
+			}
+			block = int64(height)
+		}
+		fmt.Printf("Analyzing block %d of archive in %s\n", block, dir)
+		logFromStart(start, "analysis started")
+		report, err = archive.GetStateConsumers(ctx, uint64(block), config)
+		if err = errors.Join(err, archive.Close()); err != nil {
+			return err
+		}
+	}
+	logFromStart(start, "analysis done")
+	fmt.Print(report.String())
+	return nil
+}
@@ -27,9 +27,15 @@ var Verify = cli.Command{
 	ArgsUsage: "<director>",
 	Flags: []cli.Flag{
 		&cpuProfileFlag,
+		&incrementalFlag,
 	},
 }
 
+var incrementalFlag = cli.BoolFlag{
+	Name:  "incremental",
+	Usage: "only verifies the blocks and nodes of an archive added since the last incremental verification",
+}
+
 func verify(context *cli.Context) error {
 	// parse the directory argument
 	if context.Args().Len() != 1 {
@@ -56,6 +62,12 @@ func verify(context *cli.Context) error {
 	// run forest verification
 	observer := &verificationObserver{}
 
+	if context.Bool(incrementalFlag.Name) {
+		if info.Mode != mpt.Immutable {
+			return fmt.Errorf("incremental verification is only supported for archives")
+		}
+		return mpt.VerifyArchiveIncrementally(dir, info.Config, observer)
+	}
 	if info.Mode == mpt.Immutable {
 		return mpt.VerifyArchive(dir, info.Config, observer)
 	}
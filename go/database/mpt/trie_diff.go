@@ -0,0 +1,308 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"golang.org/x/exp/maps"
+)
+
+// This file provides a structural comparison of two tries which, unlike the
+// Diff computed by GetDiff, may be located in different forests using
+// different configurations. This enables, for instance, the comparison of
+// the LiveDBs of two nodes disagreeing on a state root or the comparison of
+// a LiveDB with a block of an archive.
+
+// TrieDiff lists the accounts whose existence, fields, or storage differ
+// between two states A and B.
+type TrieDiff map[common.Address]*AccountDifference
+
+// AccountDifference describes how an account differs between two states A
+// and B. The account information of a state is zero if the account does not
+// exist in this state. Storage slots not present in a state are reported
+// with a zero value.
+type AccountDifference struct {
+	ExistsA, ExistsB bool
+	A, B             AccountInfo
+	Storage          map[common.Key]SlotDifference
+}
+
+// SlotDifference describes the values of a storage slot in states A and B.
+type SlotDifference struct {
+	A, B common.Value
+}
+
+func (d TrieDiff) String() string {
+	addresses := maps.Keys(d)
+	sort.Slice(addresses, func(i, j int) bool {
+		return string(addresses[i][:]) < string(addresses[j][:])
+	})
+
+	builder := strings.Builder{}
+	builder.WriteString("TrieDiff {\n")
+	for _, address := range addresses {
+		builder.WriteString(fmt.Sprintf("\t%x: \n", address[:]))
+		diff := d[address]
+		if diff.ExistsA != diff.ExistsB {
+			builder.WriteString(fmt.Sprintf("\t\tExists:  %t | %t\n", diff.ExistsA, diff.ExistsB))
+		}
+		if diff.A.Balance != diff.B.Balance {
+			builder.WriteString(fmt.Sprintf("\t\tBalance: %x | %x\n", diff.A.Balance, diff.B.Balance))
+		}
+		if diff.A.Nonce != diff.B.Nonce {
+			builder.WriteString(fmt.Sprintf("\t\tNonce:   %x | %x\n", diff.A.Nonce, diff.B.Nonce))
+		}
+		if diff.A.CodeHash != diff.B.CodeHash {
+			builder.WriteString(fmt.Sprintf("\t\tCode:    %x | %x\n", diff.A.CodeHash, diff.B.CodeHash))
+		}
+
+		if len(diff.Storage) > 0 {
+			keys := maps.Keys(diff.Storage)
+			sort.Slice(keys, func(i, j int) bool {
+				return string(keys[i][:]) < string(keys[j][:])
+			})
+			for _, key := range keys {
+				value := diff.Storage[key]
+				builder.WriteString(fmt.Sprintf("\t\t\t%x: %x | %x\n", key[:], value.A[:], value.B[:]))
+			}
+		}
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+// TrieDiffInput is the root of a trie to be compared using GetTrieDiff. It
+// can be obtained from a LiveDB or for a block of an archive.
+type TrieDiffInput struct {
+	source NodeSource
+	root   NodeReference
+}
+
+// GetTrieDiffInput provides the current state of this LiveDB as an input for
+// GetTrieDiff. The hashes of the state are updated for the comparison. The
+// state must not be modified while the input is in use.
+func (s *MptState) GetTrieDiffInput() (TrieDiffInput, error) {
+	source, ok := s.trie.forest.(NodeSource)
+	if !ok {
+		return TrieDiffInput{}, fmt.Errorf("unsupported forest implementation %T", s.trie.forest)
+	}
+	if _, err := s.GetHash(); err != nil {
+		return TrieDiffInput{}, err
+	}
+	return TrieDiffInput{source: source, root: NewNodeReference(s.trie.root.Id())}, nil
+}
+
+// GetTrieDiffInput provides the state of the given block as an input for
+// GetTrieDiff.
+func (a *ArchiveTrie) GetTrieDiffInput(block uint64) (TrieDiffInput, error) {
+	a.rootsMutex.Lock()
+	defer a.rootsMutex.Unlock()
+	if block >= uint64(len(a.roots)) {
+		return TrieDiffInput{}, fmt.Errorf("block %d not present in archive, number of blocks is %d", block, len(a.roots))
+	}
+	return TrieDiffInput{source: a.nodeSource, root: a.roots[block].NodeRef}, nil
+}
+
+// GetTrieDiff computes the differences between the states A and B. Both
+// tries are traversed in parallel, skipping sub-tries with equal hashes if
+// both use the same hashing algorithm. Otherwise, all nodes of both tries
+// are compared. The tries must use the same kind of paths, i.e. either both
+// or none of them must hash addresses and keys.
+func GetTrieDiff(ctx context.Context, a, b TrieDiffInput) (TrieDiff, error) {
+	configA := a.source.getConfig()
+	configB := b.source.getConfig()
+	if configA.UseHashedPaths != configB.UseHashedPaths {
+		return nil, fmt.Errorf("unable to compare tries with different path layouts, configurations %s and %s", configA.Name, configB.Name)
+	}
+	context := &trieDiffContext{
+		ctx:           ctx,
+		sourceA:       a.source,
+		sourceB:       b.source,
+		compareHashes: configA.Hashing.Name == configB.Hashing.Name,
+		result:        TrieDiff{},
+	}
+	if err := collectTrieDiff(context, triePosition{ref: a.root}, triePosition{ref: b.root}); err != nil {
+		return nil, err
+	}
+	return context.result, nil
+}
+
+// ------
+
+type trieDiffContext struct {
+	ctx              context.Context
+	sourceA, sourceB NodeSource
+	compareHashes    bool               // < true if equal hashes imply equal sub-tries
+	current          *AccountDifference // < the account whose storage is compared
+	result           TrieDiff
+}
+
+func collectTrieDiff(context *trieDiffContext, a, b triePosition) error {
+	if a.id().IsEmpty() && b.id().IsEmpty() {
+		return nil
+	}
+
+	if err := context.ctx.Err(); err != nil {
+		return err
+	}
+
+	equal, err := haveEqualHashes(context, a, b)
+	if err != nil || equal {
+		return err
+	}
+
+	if a.isLeaf() && b.isLeaf() {
+		return collectTrieDiffFromLeafs(context, a, b)
+	}
+
+	for i := Nibble(0); i < Nibble(16); i++ {
+		lhs, err := a.getChild(context.sourceA, i)
+		if err != nil {
+			return err
+		}
+		rhs, err := b.getChild(context.sourceB, i)
+		if err != nil {
+			return err
+		}
+		if err := collectTrieDiff(context, lhs, rhs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// haveEqualHashes checks whether the sub-tries at the given positions are
+// known to be equal due to their hashes. Positions within the path of an
+// extension node and embedded nodes, which have no hash, are never equal.
+func haveEqualHashes(context *trieDiffContext, a, b triePosition) (bool, error) {
+	if !context.compareHashes || a.offset != 0 || b.offset != 0 {
+		return false, nil
+	}
+	hashA, err := context.sourceA.getHashFor(&a.ref)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := context.sourceB.getHashFor(&b.ref)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB && hashA != (common.Hash{}), nil
+}
+
+func collectTrieDiffFromLeafs(context *trieDiffContext, a, b triePosition) error {
+	lhs, rhs := a.id(), b.id()
+
+	if lhs.IsAccount() || rhs.IsAccount() {
+		var accountA, accountB *AccountNode
+		if lhs.IsAccount() {
+			handle, err := a.getReadAccess(context.sourceA)
+			if err != nil {
+				return err
+			}
+			defer handle.Release()
+			accountA = handle.Get().(*AccountNode)
+		}
+		if rhs.IsAccount() {
+			handle, err := b.getReadAccess(context.sourceB)
+			if err != nil {
+				return err
+			}
+			defer handle.Release()
+			accountB = handle.Get().(*AccountNode)
+		}
+		if accountA != nil && accountB != nil && accountA.address == accountB.address {
+			return collectAccountDiff(context, accountA, accountB)
+		}
+		if accountA != nil {
+			if err := collectAccountDiff(context, accountA, nil); err != nil {
+				return err
+			}
+		}
+		if accountB != nil {
+			return collectAccountDiff(context, nil, accountB)
+		}
+		return nil
+	}
+
+	var valueA, valueB *ValueNode
+	if lhs.IsValue() {
+		handle, err := a.getReadAccess(context.sourceA)
+		if err != nil {
+			return err
+		}
+		defer handle.Release()
+		valueA = handle.Get().(*ValueNode)
+	}
+	if rhs.IsValue() {
+		handle, err := b.getReadAccess(context.sourceB)
+		if err != nil {
+			return err
+		}
+		defer handle.Release()
+		valueB = handle.Get().(*ValueNode)
+	}
+	if valueA != nil && valueB != nil && valueA.key == valueB.key {
+		if valueA.value != valueB.value {
+			recordSlotDifference(context, valueA.key, SlotDifference{A: valueA.value, B: valueB.value})
+		}
+		return nil
+	}
+	if valueA != nil {
+		recordSlotDifference(context, valueA.key, SlotDifference{A: valueA.value})
+	}
+	if valueB != nil {
+		recordSlotDifference(context, valueB.key, SlotDifference{B: valueB.value})
+	}
+	return nil
+}
+
+// collectAccountDiff compares the given accounts with the same address, where
+// a nil account is not present in the respective state.
+func collectAccountDiff(context *trieDiffContext, a, b *AccountNode) error {
+	diff := &AccountDifference{}
+	var address common.Address
+	storageA, storageB := emptyNodeReference, emptyNodeReference
+	if a != nil {
+		address = a.address
+		diff.ExistsA = true
+		diff.A = a.info
+		storageA = a.storage
+	}
+	if b != nil {
+		address = b.address
+		diff.ExistsB = true
+		diff.B = b.info
+		storageB = b.storage
+	}
+
+	context.current = diff
+	if err := collectTrieDiff(context, triePosition{ref: storageA}, triePosition{ref: storageB}); err != nil {
+		return err
+	}
+	context.current = nil
+
+	if diff.ExistsA != diff.ExistsB || diff.A != diff.B || len(diff.Storage) > 0 {
+		context.result[address] = diff
+	}
+	return nil
+}
+
+func recordSlotDifference(context *trieDiffContext, key common.Key, diff SlotDifference) {
+	if context.current.Storage == nil {
+		context.current.Storage = map[common.Key]SlotDifference{}
+	}
+	context.current.Storage[key] = diff
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// getTrieDiffTestUpdate creates an update with enough accounts and slots to
+// form tries with branch and extension nodes.
+func getTrieDiffTestUpdate() common.Update {
+	update := common.Update{}
+	for i := 0; i < 50; i++ {
+		address := common.Address{byte(i), byte(i * 7)}
+		update.CreatedAccounts = append(update.CreatedAccounts, address)
+		update.Nonces = append(update.Nonces, common.NonceUpdate{Account: address, Nonce: common.ToNonce(uint64(i + 1))})
+		for j := 0; j < i%5; j++ {
+			update.Slots = append(update.Slots, common.SlotUpdate{Account: address, Key: common.Key{byte(j)}, Value: common.Value{byte(i), byte(j + 1)}})
+		}
+	}
+	return update
+}
+
+func applyTrieDiffTestUpdates(t *testing.T, state *MptState, updates ...common.Update) {
+	t.Helper()
+	for i, update := range updates {
+		if err := update.Normalize(); err != nil {
+			t.Fatalf("failed to normalize update: %v", err)
+		}
+		hints, err := state.Apply(uint64(i), update)
+		if err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+		if hints != nil {
+			hints.Release()
+		}
+	}
+}
+
+func getTrieDiffTestInput(t *testing.T, config MptConfig, updates ...common.Update) TrieDiffInput {
+	t.Helper()
+	state, err := OpenGoMemoryState(t.TempDir(), config, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	t.Cleanup(func() { state.Close() })
+	applyTrieDiffTestUpdates(t, state, updates...)
+	input, err := state.GetTrieDiffInput()
+	if err != nil {
+		t.Fatalf("failed to get diff input: %v", err)
+	}
+	return input
+}
+
+func TestGetTrieDiff_EqualStatesHaveNoDifferences(t *testing.T) {
+	for _, config := range []MptConfig{S4LiveConfig, S5LiveConfig} {
+		t.Run(config.Name, func(t *testing.T) {
+			a := getTrieDiffTestInput(t, config, getTrieDiffTestUpdate())
+			b := getTrieDiffTestInput(t, config, getTrieDiffTestUpdate())
+			diff, err := GetTrieDiff(context.Background(), a, b)
+			if err != nil {
+				t.Fatalf("failed to compute diff: %v", err)
+			}
+			if len(diff) != 0 {
+				t.Errorf("equal states should have no differences, got %v", diff)
+			}
+		})
+	}
+}
+
+func TestGetTrieDiff_DifferencesAreReported(t *testing.T) {
+	address1 := common.Address{1, 7}
+	address2 := common.Address{2, 14}
+	address3 := common.Address{3, 21}
+	address4 := common.Address{4, 28}
+	newAddress := common.Address{0xAB}
+
+	changes := common.Update{
+		DeletedAccounts: []common.Address{address1},
+		CreatedAccounts: []common.Address{newAddress},
+		Balances:        []common.BalanceUpdate{{Account: address2, Balance: common.Balance{31: 5}}},
+		Nonces:          []common.NonceUpdate{{Account: newAddress, Nonce: common.ToNonce(1)}},
+		Codes:           []common.CodeUpdate{{Account: address3, Code: []byte{1, 2, 3}}},
+		Slots: []common.SlotUpdate{
+			{Account: address3, Key: common.Key{0}, Value: common.Value{1}},
+			{Account: address4, Key: common.Key{1}, Value: common.Value{}},
+			{Account: address4, Key: common.Key{9}, Value: common.Value{9}},
+		},
+	}
+
+	want := TrieDiff{
+		address1: &AccountDifference{
+			ExistsA: true,
+			A:       AccountInfo{Nonce: common.ToNonce(2), CodeHash: emptyCodeHash},
+			Storage: map[common.Key]SlotDifference{{0}: {A: common.Value{1, 1}}},
+		},
+		newAddress: &AccountDifference{
+			ExistsB: true,
+			B:       AccountInfo{Nonce: common.ToNonce(1), CodeHash: emptyCodeHash},
+		},
+		address2: &AccountDifference{
+			ExistsA: true,
+			ExistsB: true,
+			A:       AccountInfo{Nonce: common.ToNonce(3), CodeHash: emptyCodeHash},
+			B:       AccountInfo{Nonce: common.ToNonce(3), Balance: common.Balance{31: 5}, CodeHash: emptyCodeHash},
+		},
+		address3: &AccountDifference{
+			ExistsA: true,
+			ExistsB: true,
+			A:       AccountInfo{Nonce: common.ToNonce(4), CodeHash: emptyCodeHash},
+			B:       AccountInfo{Nonce: common.ToNonce(4), CodeHash: common.Keccak256([]byte{1, 2, 3})},
+			Storage: map[common.Key]SlotDifference{{0}: {A: common.Value{3, 1}, B: common.Value{1}}},
+		},
+		address4: &AccountDifference{
+			ExistsA: true,
+			ExistsB: true,
+			A:       AccountInfo{Nonce: common.ToNonce(5), CodeHash: emptyCodeHash},
+			B:       AccountInfo{Nonce: common.ToNonce(5), CodeHash: emptyCodeHash},
+			Storage: map[common.Key]SlotDifference{
+				{1}: {A: common.Value{4, 2}},
+				{9}: {B: common.Value{9}},
+			},
+		},
+	}
+
+	for _, config := range []MptConfig{S4LiveConfig, S5LiveConfig} {
+		t.Run(config.Name, func(t *testing.T) {
+			a := getTrieDiffTestInput(t, config, getTrieDiffTestUpdate())
+			b := getTrieDiffTestInput(t, config, getTrieDiffTestUpdate(), changes)
+			diff, err := GetTrieDiff(context.Background(), a, b)
+			if err != nil {
+				t.Fatalf("failed to compute diff: %v", err)
+			}
+			if !reflect.DeepEqual(want, diff) {
+				t.Errorf("unexpected diff, wanted %v, got %v", want, diff)
+			}
+
+			// The comparison in the opposite direction swaps A and B.
+			diff, err = GetTrieDiff(context.Background(), b, a)
+			if err != nil {
+				t.Fatalf("failed to compute diff: %v", err)
+			}
+			if len(diff) != len(want) {
+				t.Fatalf("unexpected number of differences, wanted %d, got %d", len(want), len(diff))
+			}
+			for address, cur := range diff {
+				if cur.ExistsA != want[address].ExistsB || cur.A != want[address].B {
+					t.Errorf("unexpected difference of account %x, got %v", address, cur)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTrieDiff_LiveDbCanBeComparedWithArchiveBlocks(t *testing.T) {
+	changes := common.Update{
+		Balances: []common.BalanceUpdate{{Account: common.Address{2, 14}, Balance: common.Balance{31: 5}}},
+	}
+
+	archive, err := OpenArchiveTrie(t.TempDir(), S5ArchiveConfig, DefaultMptStateCapacity)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	for i, update := range []common.Update{getTrieDiffTestUpdate(), changes} {
+		if err := update.Normalize(); err != nil {
+			t.Fatalf("failed to normalize update: %v", err)
+		}
+		if err := archive.Add(uint64(i), update, nil); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	live := getTrieDiffTestInput(t, S5LiveConfig, getTrieDiffTestUpdate())
+
+	for block, want := range []int{0, 1} {
+		input, err := archive.GetTrieDiffInput(uint64(block))
+		if err != nil {
+			t.Fatalf("failed to get diff input: %v", err)
+		}
+		diff, err := GetTrieDiff(context.Background(), live, input)
+		if err != nil {
+			t.Fatalf("failed to compute diff: %v", err)
+		}
+		if len(diff) != want {
+			t.Errorf("unexpected number of differences to block %d, wanted %d, got %v", block, want, diff)
+		}
+	}
+
+	if _, err := archive.GetTrieDiffInput(2); err == nil {
+		t.Errorf("missing block should not be accepted")
+	}
+}
+
+func TestGetTrieDiff_TriesWithDifferentHashingAreCompared(t *testing.T) {
+	config := S5LiveConfig
+	config.Name = "S5-Live-DirectHashing"
+	config.Hashing = DirectHashing
+
+	changes := common.Update{
+		Slots: []common.SlotUpdate{{Account: common.Address{4, 28}, Key: common.Key{1}, Value: common.Value{1}}},
+	}
+	a := getTrieDiffTestInput(t, S5LiveConfig, getTrieDiffTestUpdate())
+	b := getTrieDiffTestInput(t, config, getTrieDiffTestUpdate(), changes)
+	diff, err := GetTrieDiff(context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("failed to compute diff: %v", err)
+	}
+	want := TrieDiff{
+		common.Address{4, 28}: &AccountDifference{
+			ExistsA: true,
+			ExistsB: true,
+			A:       AccountInfo{Nonce: common.ToNonce(5), CodeHash: emptyCodeHash},
+			B:       AccountInfo{Nonce: common.ToNonce(5), CodeHash: emptyCodeHash},
+			Storage: map[common.Key]SlotDifference{{1}: {A: common.Value{4, 2}, B: common.Value{1}}},
+		},
+	}
+	if !reflect.DeepEqual(want, diff) {
+		t.Errorf("unexpected diff, wanted %v, got %v", want, diff)
+	}
+}
+
+func TestGetTrieDiff_TriesWithDifferentPathsAreRejected(t *testing.T) {
+	a := getTrieDiffTestInput(t, S4LiveConfig)
+	b := getTrieDiffTestInput(t, S5LiveConfig)
+	if _, err := GetTrieDiff(context.Background(), a, b); err == nil || !strings.Contains(err.Error(), "different path layouts") {
+		t.Errorf("comparison of tries with different paths should fail, got %v", err)
+	}
+}
+
+func TestGetTrieDiff_CancelledComparisonFails(t *testing.T) {
+	a := getTrieDiffTestInput(t, S5LiveConfig, getTrieDiffTestUpdate())
+	b := getTrieDiffTestInput(t, S5LiveConfig)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetTrieDiff(ctx, a, b); err != context.Canceled {
+		t.Errorf("cancelled comparison should fail with %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestTrieDiff_StringListsDifferences(t *testing.T) {
+	diff := TrieDiff{
+		common.Address{1}: &AccountDifference{
+			ExistsA: true,
+			A:       AccountInfo{Nonce: common.ToNonce(1)},
+			Storage: map[common.Key]SlotDifference{{2}: {A: common.Value{3}}},
+		},
+	}
+	got := diff.String()
+	for _, want := range []string{"Exists:  true | false", "Nonce:   0000000000000001 | 0000000000000000", "02000000"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %s", want, got)
+		}
+	}
+	if strings.Contains(got, "Balance") {
+		t.Errorf("equal balances should not be listed: %s", got)
+	}
+}
@@ -13,6 +13,7 @@ package mpt
 //go:generate mockgen -source visitor.go -destination visitor_mocks.go -package mpt
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -63,6 +64,40 @@ func VisitForestNodes(directory string, config MptConfig, visitor NodeVisitor) e
 	})
 }
 
+// ----------------------------------------------------------------------------
+//                          Context Visitor
+// ----------------------------------------------------------------------------
+
+// visitWithContext runs the given visit using a visitor forwarding nodes to
+// the given visitor until the given context is done. In the latter case, the
+// visit is aborted and the context's error is returned. Since the context is
+// checked for each visited node, this also bounds the time spent on loading
+// nodes during the visit.
+func visitWithContext(ctx context.Context, visitor NodeVisitor, visit func(NodeVisitor) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	wrapper := &contextVisitor{ctx: ctx, visitor: visitor}
+	if err := visit(wrapper); err != nil {
+		return err
+	}
+	return wrapper.err
+}
+
+type contextVisitor struct {
+	ctx     context.Context
+	visitor NodeVisitor
+	err     error // < the context's error if the visit got aborted by it
+}
+
+func (v *contextVisitor) Visit(n Node, i NodeInfo) VisitResponse {
+	if err := v.ctx.Err(); err != nil {
+		v.err = err
+		return VisitResponseAbort
+	}
+	return v.visitor.Visit(n, i)
+}
+
 // ----------------------------------------------------------------------------
 //                          Lambda Visitor
 // ----------------------------------------------------------------------------
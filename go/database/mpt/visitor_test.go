@@ -11,6 +11,8 @@
 package mpt
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/Fantom-foundation/Carmen/go/common"
@@ -90,3 +92,46 @@ func TestNodeStatistics_CollectForestStatisticsWorks(t *testing.T) {
 		t.Errorf("invalid stats for archive: %v", &stats)
 	}
 }
+
+func TestVisitTrieWithContext_CancellationAbortsVisit(t *testing.T) {
+	trie, err := OpenInMemoryLiveTrie(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to create empty trie: %v", err)
+	}
+	defer trie.Close()
+	for i := 0; i < 10; i++ {
+		trie.SetAccountInfo(common.Address{byte(i)}, AccountInfo{Nonce: common.ToNonce(1)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	visited := 0
+	err = trie.VisitTrieWithContext(ctx, MakeVisitor(func(Node, NodeInfo) VisitResponse {
+		visited++
+		cancel()
+		return VisitResponseContinue
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.Canceled, err)
+	}
+	if visited != 1 {
+		t.Errorf("visit should be aborted after the first node, visited %d nodes", visited)
+	}
+
+	// A visit with an active context visits all nodes.
+	counter := func(count *int) NodeVisitor {
+		return MakeVisitor(func(Node, NodeInfo) VisitResponse {
+			*count++
+			return VisitResponseContinue
+		})
+	}
+	want, got := 0, 0
+	if err := trie.VisitTrie(counter(&want)); err != nil {
+		t.Fatalf("failed to visit trie: %v", err)
+	}
+	if err := trie.VisitTrieWithContext(context.Background(), counter(&got)); err != nil {
+		t.Errorf("failed to visit trie: %v", err)
+	}
+	if want != got {
+		t.Errorf("unexpected number of visited nodes, wanted %d, got %d", want, got)
+	}
+}
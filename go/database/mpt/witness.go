@@ -0,0 +1,340 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt/shared"
+	"golang.org/x/exp/maps"
+)
+
+// Witness is a self-contained collection of the state information read while
+// processing a block on an MPT based state. It contains the pre-block version
+// of every trie node accessed while running and committing the block as well
+// as all contract codes read during its execution. Together with the state
+// root hash of the pre-block state, it is sufficient to re-execute the
+// block without having access to the full state. See OpenWitnessState for
+// creating a state based on a witness.
+type Witness struct {
+	// Config is the MPT configuration of the state the witness was recorded on.
+	Config MptConfig
+	// Root is the ID of the root node of the pre-block state.
+	Root NodeId
+	// Nodes maps the IDs of recorded nodes to their encoded pre-block content.
+	Nodes map[NodeId][]byte
+	// Codes contains all contract codes read, indexed by their hash.
+	Codes map[common.Hash][]byte
+}
+
+const witnessEncodingVersion byte = 0
+
+// ToBytes serializes this witness into a byte sequence that can be parsed
+// by WitnessFromBytes. The encoding is deterministic.
+func (w *Witness) ToBytes() []byte {
+	size := 1 + 2 + len(w.Config.Name) + 8 + 4 + 4
+	for _, data := range w.Nodes {
+		size += 8 + 4 + len(data)
+	}
+	for _, code := range w.Codes {
+		size += len(common.Hash{}) + 4 + len(code)
+	}
+
+	res := make([]byte, 0, size)
+	res = append(res, witnessEncodingVersion)
+	res = binary.BigEndian.AppendUint16(res, uint16(len(w.Config.Name)))
+	res = append(res, w.Config.Name...)
+	res = binary.BigEndian.AppendUint64(res, uint64(w.Root))
+
+	ids := maps.Keys(w.Nodes)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	res = binary.BigEndian.AppendUint32(res, uint32(len(ids)))
+	for _, id := range ids {
+		data := w.Nodes[id]
+		res = binary.BigEndian.AppendUint64(res, uint64(id))
+		res = binary.BigEndian.AppendUint32(res, uint32(len(data)))
+		res = append(res, data...)
+	}
+
+	hashes := maps.Keys(w.Codes)
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].Compare(&hashes[j]) < 0 })
+	res = binary.BigEndian.AppendUint32(res, uint32(len(hashes)))
+	for _, hash := range hashes {
+		code := w.Codes[hash]
+		res = append(res, hash[:]...)
+		res = binary.BigEndian.AppendUint32(res, uint32(len(code)))
+		res = append(res, code...)
+	}
+	return res
+}
+
+// WitnessFromBytes parses a witness previously serialized using ToBytes.
+func WitnessFromBytes(data []byte) (*Witness, error) {
+	if len(data) < 1+2 {
+		return nil, fmt.Errorf("invalid witness encoding, too few bytes")
+	}
+	if data[0] != witnessEncodingVersion {
+		return nil, fmt.Errorf("unknown witness encoding version: %d", data[0])
+	}
+	data = data[1:]
+
+	nameLength := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < nameLength+8+4 {
+		return nil, fmt.Errorf("invalid witness encoding, truncated header")
+	}
+	config, found := GetConfigByName(string(data[:nameLength]))
+	if !found {
+		return nil, fmt.Errorf("invalid witness encoding, unknown MPT configuration %q", string(data[:nameLength]))
+	}
+	data = data[nameLength:]
+
+	res := &Witness{
+		Config: config,
+		Root:   NodeId(binary.BigEndian.Uint64(data)),
+		Nodes:  map[NodeId][]byte{},
+		Codes:  map[common.Hash][]byte{},
+	}
+	data = data[8:]
+
+	numNodes := int(binary.BigEndian.Uint32(data))
+	data = data[4:]
+	for i := 0; i < numNodes; i++ {
+		if len(data) < 8+4 {
+			return nil, fmt.Errorf("invalid witness encoding, truncated node list")
+		}
+		id := NodeId(binary.BigEndian.Uint64(data))
+		length := int(binary.BigEndian.Uint32(data[8:]))
+		data = data[8+4:]
+		if len(data) < length {
+			return nil, fmt.Errorf("invalid witness encoding, truncated node %v", id)
+		}
+		res.Nodes[id] = data[:length:length]
+		data = data[length:]
+	}
+
+	if len(data) < 4 {
+		return nil, fmt.Errorf("invalid witness encoding, missing code list")
+	}
+	numCodes := int(binary.BigEndian.Uint32(data))
+	data = data[4:]
+	for i := 0; i < numCodes; i++ {
+		if len(data) < len(common.Hash{})+4 {
+			return nil, fmt.Errorf("invalid witness encoding, truncated code list")
+		}
+		var hash common.Hash
+		copy(hash[:], data)
+		length := int(binary.BigEndian.Uint32(data[len(hash):]))
+		data = data[len(hash)+4:]
+		if len(data) < length {
+			return nil, fmt.Errorf("invalid witness encoding, truncated code %x", hash)
+		}
+		res.Codes[hash] = data[:length:length]
+		data = data[length:]
+	}
+
+	if len(data) != 0 {
+		return nil, fmt.Errorf("invalid witness encoding, %d trailing bytes", len(data))
+	}
+	return res, nil
+}
+
+// WitnessRecorder collects the nodes and codes accessed on an MptState while
+// recording is active. Recordings are started using
+// MptState.StartWitnessRecording and completed by calling Finish.
+type WitnessRecorder struct {
+	state  *MptState
+	config MptConfig // < set by the forest when the recorder is registered
+	root   NodeId
+
+	mutex   sync.Mutex
+	nodes   map[NodeId][]byte // < nil values mark nodes currently being recorded
+	created map[NodeId]bool   // < nodes created while recording, not part of the pre-state
+	codes   map[common.Hash][]byte
+	done    bool
+}
+
+func newWitnessRecorder(state *MptState, root NodeId) *WitnessRecorder {
+	return &WitnessRecorder{
+		state:   state,
+		root:    root,
+		nodes:   map[NodeId][]byte{},
+		created: map[NodeId]bool{},
+		codes:   map[common.Hash][]byte{},
+	}
+}
+
+// Finish stops the recording and returns the collected witness. After this
+// call, the recorder should be discarded.
+func (r *WitnessRecorder) Finish() (*Witness, error) {
+	r.mutex.Lock()
+	if r.done {
+		r.mutex.Unlock()
+		return nil, fmt.Errorf("witness recording already finished")
+	}
+	r.done = true
+	r.mutex.Unlock()
+
+	// Unregistering the recorder waits for pending node releases, which may
+	// still be reading nodes of the pre-block state.
+	err := r.state.trie.forest.setWitnessRecorder(nil)
+	r.state.witness.CompareAndSwap(r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	nodes := make(map[NodeId][]byte, len(r.nodes))
+	for id, data := range r.nodes {
+		if data == nil {
+			return nil, fmt.Errorf("incomplete recording of node %v", id)
+		}
+		nodes[id] = data
+	}
+	return &Witness{
+		Config: r.config,
+		Root:   r.root,
+		Nodes:  nodes,
+		Codes:  maps.Clone(r.codes),
+	}, nil
+}
+
+// markCreated registers a node created while recording. Such nodes are not
+// part of the pre-block state and thus excluded from the witness.
+func (r *WitnessRecorder) markCreated(id NodeId) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, recorded := r.nodes[id]; !recorded {
+		r.created[id] = true
+	}
+}
+
+// recordCode registers a code read while recording.
+func (r *WitnessRecorder) recordCode(hash common.Hash, code []byte) {
+	if code == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.codes[hash] = code
+}
+
+// recordNode registers a node accessed in the given forest while recording.
+// Only the first access to a node is recorded, since it is the one observing
+// the node's pre-block content. Embedded child nodes are recorded alongside
+// with their parents since they are required for computing the parent's hash.
+func (r *WitnessRecorder) recordNode(forest *Forest, ref *NodeReference, instance *shared.Shared[Node]) error {
+	id := ref.Id()
+	if id.IsEmpty() {
+		return nil
+	}
+
+	r.mutex.Lock()
+	_, recorded := r.nodes[id]
+	if recorded || r.created[id] {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.nodes[id] = nil // < reserves the entry to record it only once
+	r.mutex.Unlock()
+
+	handle := instance.GetViewHandle()
+	data, embedded, err := r.encode(handle.Get())
+	handle.Release()
+	if err != nil {
+		return fmt.Errorf("failed to record witness node %v: %w", id, err)
+	}
+
+	r.mutex.Lock()
+	r.nodes[id] = data
+	r.mutex.Unlock()
+
+	for _, child := range embedded {
+		handle, err := forest.getViewAccess(&child)
+		if err != nil {
+			return err
+		}
+		handle.Release()
+	}
+	return nil
+}
+
+// encode serializes the given node using the stock encoding of the recorded
+// state's configuration and lists the node's embedded children.
+func (r *WitnessRecorder) encode(node Node) ([]byte, []NodeReference, error) {
+	accountEncoder, branchEncoder, extensionEncoder, valueEncoder := getEncoder(r.config)
+	var data []byte
+	var embedded []NodeReference
+	var err error
+	switch n := node.(type) {
+	case *AccountNode:
+		data = make([]byte, accountEncoder.GetEncodedSize())
+		err = accountEncoder.Store(data, n)
+	case *BranchNode:
+		data = make([]byte, branchEncoder.GetEncodedSize())
+		err = branchEncoder.Store(data, n)
+		for i := 0; i < len(n.children); i++ {
+			if !n.children[i].Id().IsEmpty() && n.isEmbedded(byte(i)) {
+				embedded = append(embedded, n.children[i])
+			}
+		}
+	case *ExtensionNode:
+		data = make([]byte, extensionEncoder.GetEncodedSize())
+		err = extensionEncoder.Store(data, n)
+		if n.nextIsEmbedded {
+			embedded = append(embedded, n.next)
+		}
+	case *ValueNode:
+		data = make([]byte, valueEncoder.GetEncodedSize())
+		err = valueEncoder.Store(data, n)
+	default:
+		err = fmt.Errorf("unsupported node type %T", node)
+	}
+	return data, embedded, err
+}
+
+// errWitnessRecordingActive is returned when starting a witness recording
+// while another recording is in progress.
+const errWitnessRecordingActive = common.ConstError("witness recording already in progress")
+
+// StartWitnessRecording starts the collection of all trie nodes and codes read
+// from this state until the returned recorder is finished. Only one recording
+// may be active at any time.
+func (s *MptState) StartWitnessRecording() (*WitnessRecorder, error) {
+	// Witnesses capture nodes including the hashes of their children. Thus,
+	// all hashes need to be up-to-date when starting a recording.
+	if _, err := s.GetHash(); err != nil {
+		return nil, err
+	}
+
+	recorder := newWitnessRecorder(s, s.trie.root.Id())
+	if !s.witness.CompareAndSwap(nil, recorder) {
+		return nil, errWitnessRecordingActive
+	}
+	if err := s.trie.forest.setWitnessRecorder(recorder); err != nil {
+		s.witness.Store(nil)
+		return nil, err
+	}
+
+	// Make sure the root node is part of the witness, even if no other
+	// information is read while recording.
+	if _, err := s.GetHash(); err != nil {
+		_, finishErr := recorder.Finish()
+		return nil, errors.Join(err, finishErr)
+	}
+	return recorder, nil
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/backend"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"golang.org/x/crypto/sha3"
+)
+
+// MissingWitnessDataError is the error produced when accessing state
+// information in a witness-based state that is not covered by the witness.
+const MissingWitnessDataError = common.ConstError("missing witness data")
+
+// OpenWitnessState creates an in-memory state based on the nodes and codes
+// recorded in the given witness. The resulting state represents the pre-block
+// state the witness was recorded for and can be used to re-execute the block.
+// Accessing information not covered by the witness results in errors wrapping
+// MissingWitnessDataError.
+//
+// Before the state is created, the content of the witness is verified to be
+// consistent with the given root hash of the pre-block state. Witnesses can
+// only be used for configurations storing node hashes in parent nodes.
+func OpenWitnessState(witness *Witness, root common.Hash) (state.State, error) {
+	config := witness.Config
+	if config.HashStorageLocation != HashStoredWithParent {
+		return nil, fmt.Errorf("witnesses are not supported for MPT configuration %v", config.Name)
+	}
+
+	accountEncoder, branchEncoder, extensionEncoder, valueEncoder := getEncoder(config)
+	accounts := newOverlayStock[AccountNode](nil, AccountId, accountEncoder, 0)
+	branches := newOverlayStock[BranchNode](nil, BranchId, branchEncoder, 0)
+	extensions := newOverlayStock[ExtensionNode](nil, ExtensionId, extensionEncoder, 0)
+	values := newOverlayStock[ValueNode](nil, ValueId, valueEncoder, 0)
+	for id, data := range witness.Nodes {
+		var err error
+		if id.IsAccount() {
+			err = accounts.add(id.Index(), data)
+		} else if id.IsBranch() {
+			err = branches.add(id.Index(), data)
+		} else if id.IsExtension() {
+			err = extensions.add(id.Index(), data)
+		} else if id.IsValue() {
+			err = values.add(id.Index(), data)
+		} else {
+			err = fmt.Errorf("invalid node ID")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid witness node %v: %w", id, err)
+		}
+	}
+
+	forestConfig := ForestConfig{
+		Mode:          Mutable,
+		CacheCapacity: MinMptStateCapacity + len(witness.Nodes),
+	}
+	forest, err := makeForest(config, "", branches, extensions, accounts, values, forestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &witnessState{
+		MptState: &MptState{
+			trie: getTrieView(NewNodeReference(witness.Root), forest),
+			code: map[common.Hash][]byte{},
+		},
+		forest: forest,
+	}
+	for hash, code := range witness.Codes {
+		res.code[hash] = code
+	}
+
+	if err := res.verify(witness, root); err != nil {
+		return nil, errors.Join(err, forest.Close())
+	}
+	return state.WrapIntoSyncedState(res), nil
+}
+
+// witnessState is a state.State implementation backed by a forest only
+// containing the nodes of a witness.
+type witnessState struct {
+	*MptState
+	forest *Forest
+}
+
+// verify checks that the nodes and codes in the given witness are consistent
+// with each other and the given root hash.
+func (s *witnessState) verify(witness *Witness, root common.Hash) error {
+	hash, err := s.GetHash()
+	if err != nil {
+		return err
+	}
+	if hash != root {
+		return fmt.Errorf("witness does not match state root, wanted %x, got %x", root, hash)
+	}
+
+	// The hash of the root node covers the hashes of all child nodes. Thus,
+	// verifying that each node present in the witness matches the hash
+	// recorded in its parent node establishes the integrity of all nodes
+	// reachable from the root.
+	expected := map[NodeId]common.Hash{}
+	for id := range witness.Nodes {
+		ref := NewNodeReference(id)
+		handle, err := s.forest.getViewAccess(&ref)
+		if err != nil {
+			return err
+		}
+		switch node := handle.Get().(type) {
+		case *BranchNode:
+			for i := 0; i < len(node.children); i++ {
+				if !node.children[i].Id().IsEmpty() && !node.isEmbedded(byte(i)) {
+					expected[node.children[i].Id()] = node.hashes[i]
+				}
+			}
+		case *ExtensionNode:
+			if !node.nextIsEmbedded {
+				expected[node.next.Id()] = node.nextHash
+			}
+		case *AccountNode:
+			if !node.storage.Id().IsEmpty() {
+				expected[node.storage.Id()] = node.storageHash
+			}
+		}
+		handle.Release()
+	}
+	for id, want := range expected {
+		if _, found := witness.Nodes[id]; !found {
+			continue
+		}
+		ref := NewNodeReference(id)
+		got, err := s.forest.getHashFor(&ref)
+		if err != nil {
+			return err
+		}
+		if want != got {
+			return fmt.Errorf("invalid witness node %v, hash does not match parent, wanted %x, got %x", id, want, got)
+		}
+	}
+
+	hasher := sha3.NewLegacyKeccak256()
+	for hash, code := range witness.Codes {
+		if got := common.GetHash(hasher, code); got != hash {
+			return fmt.Errorf("invalid witness code, wanted hash %x, got %x", hash, got)
+		}
+	}
+	return nil
+}
+
+func (s *witnessState) GetCode(address common.Address) ([]byte, error) {
+	info, exists, err := s.trie.GetAccountInfo(address)
+	if err != nil || !exists {
+		return nil, err
+	}
+	if info.CodeHash == emptyCodeHash {
+		return nil, nil
+	}
+	s.codeMutex.Lock()
+	code, found := s.code[info.CodeHash]
+	s.codeMutex.Unlock()
+	if !found {
+		return nil, fmt.Errorf("%w: code with hash %x of account %x", MissingWitnessDataError, info.CodeHash, address)
+	}
+	return code, nil
+}
+
+func (s *witnessState) GetCodeSize(address common.Address) (int, error) {
+	code, err := s.GetCode(address)
+	return len(code), err
+}
+
+func (s *witnessState) Apply(block uint64, update common.Update) error {
+	hints, err := s.MptState.Apply(block, update)
+	if hints != nil {
+		hints.Release()
+	}
+	return err
+}
+
+func (s *witnessState) Flush() error {
+	// Witness states are not persisted.
+	return s.forest.CheckErrors()
+}
+
+func (s *witnessState) Close() error {
+	return s.forest.Close()
+}
+
+func (s *witnessState) GetArchiveState(block uint64) (state.State, error) {
+	return nil, state.NoArchiveError
+}
+
+func (s *witnessState) GetArchiveBlockHeight() (uint64, bool, error) {
+	return 0, false, state.NoArchiveError
+}
+
+func (s *witnessState) Check() error {
+	return s.forest.CheckErrors()
+}
+
+func (s *witnessState) GetProof() (backend.Proof, error) {
+	return nil, backend.ErrSnapshotNotSupported
+}
+
+func (s *witnessState) CreateSnapshot() (backend.Snapshot, error) {
+	return nil, backend.ErrSnapshotNotSupported
+}
+
+func (s *witnessState) Restore(backend.SnapshotData) error {
+	return backend.ErrSnapshotNotSupported
+}
+
+func (s *witnessState) GetSnapshotVerifier([]byte) (backend.SnapshotVerifier, error) {
+	return nil, backend.ErrSnapshotNotSupported
+}
@@ -0,0 +1,340 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package mpt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+func witnessTestPreState(t *testing.T, state *MptState) {
+	t.Helper()
+	update := common.Update{}
+	for i := 0; i < 100; i++ {
+		addr := common.Address{byte(i), byte(i * 7)}
+		update.AppendCreateAccount(addr)
+		update.AppendBalanceUpdate(addr, common.Balance{byte(i + 1)})
+		update.AppendNonceUpdate(addr, common.Nonce{byte(i)})
+		if i%10 == 0 {
+			update.AppendCodeUpdate(addr, []byte{byte(i), 1, 2, 3})
+			for j := 0; j < 20; j++ {
+				update.AppendSlotUpdate(addr, common.Key{byte(j)}, common.Value{byte(i), byte(j)})
+			}
+		}
+	}
+	if err := update.Normalize(); err != nil {
+		t.Fatalf("failed to normalize update: %v", err)
+	}
+	if _, err := state.Apply(0, update); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+}
+
+// witnessTestBlock reads and modifies some data of the pre-state.
+func witnessTestBlock(t *testing.T, state interface {
+	GetBalance(common.Address) (common.Balance, error)
+	GetStorage(common.Address, common.Key) (common.Value, error)
+	GetCode(common.Address) ([]byte, error)
+}) (common.Update, []any) {
+	t.Helper()
+	var reads []any
+	for _, i := range []byte{3, 10, 20} {
+		balance, err := state.GetBalance(common.Address{i, i * 7})
+		if err != nil {
+			t.Fatalf("failed to read balance: %v", err)
+		}
+		value, err := state.GetStorage(common.Address{i, i * 7}, common.Key{5})
+		if err != nil {
+			t.Fatalf("failed to read storage: %v", err)
+		}
+		code, err := state.GetCode(common.Address{i, i * 7})
+		if err != nil {
+			t.Fatalf("failed to read code: %v", err)
+		}
+		reads = append(reads, balance, value, code)
+	}
+
+	update := common.Update{}
+	update.AppendDeleteAccount(common.Address{20, 20 * 7})
+	update.AppendCreateAccount(common.Address{0xFF})
+	update.AppendBalanceUpdate(common.Address{0xFF}, common.Balance{12})
+	update.AppendBalanceUpdate(common.Address{3, 3 * 7}, common.Balance{1})
+	update.AppendSlotUpdate(common.Address{10, 10 * 7}, common.Key{5}, common.Value{})
+	update.AppendSlotUpdate(common.Address{10, 10 * 7}, common.Key{0xAA}, common.Value{1})
+	update.AppendCodeUpdate(common.Address{0xFF}, []byte{1, 2, 3, 4, 5})
+	if err := update.Normalize(); err != nil {
+		t.Fatalf("failed to normalize update: %v", err)
+	}
+	return update, reads
+}
+
+func recordWitnessTestBlock(t *testing.T, state *MptState) (*Witness, common.Hash, common.Hash) {
+	t.Helper()
+	witnessTestPreState(t, state)
+	preHash, err := state.GetHash()
+	if err != nil {
+		t.Fatalf("failed to get pre-state hash: %v", err)
+	}
+
+	recorder, err := state.StartWitnessRecording()
+	if err != nil {
+		t.Fatalf("failed to start recording: %v", err)
+	}
+	update, _ := witnessTestBlock(t, state)
+	if _, err := state.Apply(1, update); err != nil {
+		t.Fatalf("failed to apply block: %v", err)
+	}
+	witness, err := recorder.Finish()
+	if err != nil {
+		t.Fatalf("failed to finish recording: %v", err)
+	}
+
+	postHash, err := state.GetHash()
+	if err != nil {
+		t.Fatalf("failed to get post-state hash: %v", err)
+	}
+	return witness, preHash, postHash
+}
+
+func TestWitness_ReExecutionOnWitnessStateProducesSamePostStateHash(t *testing.T) {
+	for name, open := range mptStateFactories {
+		t.Run(name, func(t *testing.T) {
+			state, err := open(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to open state: %v", err)
+			}
+			defer state.Close()
+
+			witness, preHash, postHash := recordWitnessTestBlock(t, state)
+
+			witness, err = WitnessFromBytes(witness.ToBytes())
+			if err != nil {
+				t.Fatalf("failed to parse witness: %v", err)
+			}
+
+			witnessState, err := OpenWitnessState(witness, preHash)
+			if err != nil {
+				t.Fatalf("failed to open witness state: %v", err)
+			}
+			defer witnessState.Close()
+
+			if got, err := witnessState.GetHash(); err != nil || got != preHash {
+				t.Errorf("unexpected pre-state hash, wanted %x, got %x, err %v", preHash, got, err)
+			}
+
+			update, reads := witnessTestBlock(t, witnessState)
+			if want, got := []any{
+				common.Balance{4}, common.Value{}, []byte(nil),
+				common.Balance{11}, common.Value{10, 5}, []byte{10, 1, 2, 3},
+				common.Balance{21}, common.Value{20, 5}, []byte{20, 1, 2, 3},
+			}, reads; len(want) != len(got) {
+				t.Fatalf("unexpected number of reads, wanted %d, got %d", len(want), len(got))
+			} else {
+				for i := range want {
+					if wantCode, ok := want[i].([]byte); ok {
+						if !bytes.Equal(wantCode, got[i].([]byte)) {
+							t.Errorf("unexpected code, wanted %v, got %v", wantCode, got[i])
+						}
+					} else if want[i] != got[i] {
+						t.Errorf("unexpected value, wanted %v, got %v", want[i], got[i])
+					}
+				}
+			}
+
+			if err := witnessState.Apply(1, update); err != nil {
+				t.Fatalf("failed to re-execute block: %v", err)
+			}
+			if got, err := witnessState.GetHash(); err != nil || got != postHash {
+				t.Errorf("unexpected post-state hash, wanted %x, got %x, err %v", postHash, got, err)
+			}
+			if err := witnessState.Check(); err != nil {
+				t.Errorf("unexpected error in witness state: %v", err)
+			}
+		})
+	}
+}
+
+func TestWitness_WitnessStateReportsMissingNodes(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	witness, preHash, _ := recordWitnessTestBlock(t, state)
+	witnessState, err := OpenWitnessState(witness, preHash)
+	if err != nil {
+		t.Fatalf("failed to open witness state: %v", err)
+	}
+	defer witnessState.Close()
+
+	// Account 30 has not been accessed while recording the witness.
+	if _, err := witnessState.GetBalance(common.Address{30, 30 * 7}); !errors.Is(err, MissingWitnessDataError) {
+		t.Errorf("expected missing witness data error, got %v", err)
+	}
+}
+
+func TestWitness_WitnessStateReportsMissingCodes(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	witness, preHash, _ := recordWitnessTestBlock(t, state)
+	for hash := range witness.Codes {
+		delete(witness.Codes, hash)
+	}
+	witnessState, err := OpenWitnessState(witness, preHash)
+	if err != nil {
+		t.Fatalf("failed to open witness state: %v", err)
+	}
+	defer witnessState.Close()
+
+	if _, err := witnessState.GetCode(common.Address{10, 10 * 7}); !errors.Is(err, MissingWitnessDataError) {
+		t.Errorf("expected missing witness data error, got %v", err)
+	}
+}
+
+func TestWitness_OpeningWitnessStateWithWrongRootFails(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	witness, _, postHash := recordWitnessTestBlock(t, state)
+	if _, err := OpenWitnessState(witness, postHash); err == nil {
+		t.Errorf("opening a witness state with a wrong root hash should fail")
+	}
+}
+
+func TestWitness_ManipulatedNodesAreDetected(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	witness, preHash, _ := recordWitnessTestBlock(t, state)
+
+	// Modify the balance of a recorded account.
+	manipulated := false
+	encoder, _, _, _ := getEncoder(witness.Config)
+	for id, data := range witness.Nodes {
+		if !id.IsAccount() {
+			continue
+		}
+		var node AccountNode
+		if err := encoder.Load(data, &node); err != nil {
+			t.Fatalf("failed to decode account node: %v", err)
+		}
+		node.info.Balance = common.Balance{0xFF}
+		modified := make([]byte, len(data))
+		if err := encoder.Store(modified, &node); err != nil {
+			t.Fatalf("failed to encode account node: %v", err)
+		}
+		witness.Nodes[id] = modified
+		manipulated = true
+		break
+	}
+	if !manipulated {
+		t.Fatalf("witness does not contain any account")
+	}
+
+	if _, err := OpenWitnessState(witness, preHash); err == nil {
+		t.Errorf("manipulated witness should be detected")
+	}
+}
+
+func TestWitness_WitnessesAreNotSupportedForHashesStoredWithNodes(t *testing.T) {
+	witness := &Witness{Config: S5ArchiveConfig}
+	if _, err := OpenWitnessState(witness, common.Hash{}); err == nil {
+		t.Errorf("opening witness state for archive config should fail")
+	}
+}
+
+func TestWitness_EmptyStateCanBeRecorded(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	recorder, err := state.StartWitnessRecording()
+	if err != nil {
+		t.Fatalf("failed to start recording: %v", err)
+	}
+	witness, err := recorder.Finish()
+	if err != nil {
+		t.Fatalf("failed to finish recording: %v", err)
+	}
+	witnessState, err := OpenWitnessState(witness, EmptyNodeEthereumHash)
+	if err != nil {
+		t.Fatalf("failed to open witness state: %v", err)
+	}
+	if err := witnessState.Close(); err != nil {
+		t.Errorf("failed to close witness state: %v", err)
+	}
+}
+
+func TestWitness_OnlyOneRecordingCanBeActive(t *testing.T) {
+	state, err := OpenGoMemoryState(t.TempDir(), S5LiveConfig, 1024)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	defer state.Close()
+
+	recorder, err := state.StartWitnessRecording()
+	if err != nil {
+		t.Fatalf("failed to start recording: %v", err)
+	}
+	if _, err := state.StartWitnessRecording(); !errors.Is(err, errWitnessRecordingActive) {
+		t.Errorf("unexpected error starting second recording: %v", err)
+	}
+	if _, err := recorder.Finish(); err != nil {
+		t.Fatalf("failed to finish recording: %v", err)
+	}
+	if _, err := recorder.Finish(); err == nil {
+		t.Errorf("finishing a recording twice should fail")
+	}
+	recorder, err = state.StartWitnessRecording()
+	if err != nil {
+		t.Fatalf("failed to start new recording: %v", err)
+	}
+	if _, err := recorder.Finish(); err != nil {
+		t.Fatalf("failed to finish recording: %v", err)
+	}
+}
+
+func TestWitnessFromBytes_DetectsInvalidEncodings(t *testing.T) {
+	witness := &Witness{
+		Config: S5LiveConfig,
+		Root:   BranchId(12),
+		Nodes:  map[NodeId][]byte{BranchId(12): {1, 2, 3}, ValueId(1): {4}},
+		Codes:  map[common.Hash][]byte{{1}: {1, 2}},
+	}
+	data := witness.ToBytes()
+	if _, err := WitnessFromBytes(data); err != nil {
+		t.Fatalf("failed to parse valid encoding: %v", err)
+	}
+	for i := 0; i < len(data); i++ {
+		if _, err := WitnessFromBytes(data[:i]); err == nil {
+			t.Errorf("truncated encoding of length %d should fail", i)
+		}
+	}
+	if _, err := WitnessFromBytes(append(data, 0)); err == nil {
+		t.Errorf("encoding with trailing bytes should fail")
+	}
+}
@@ -29,6 +29,10 @@ type Parameters struct {
 	Directory    string
 	LiveCache    int64 // bytes, approximate, supported only by S5 now
 	ArchiveCache int64 // bytes, approximate, supported only by S5 now
+
+	ArchiveQueueSize  int  // number of blocks buffered for the asynchronous archive writer, 0 for the default, supported only by Go states
+	HistoricViewCache int  // number of historic blocks with cached account and slot lookups, 0 to disable, supported only by Go states
+	StockChecksums    bool // whether new node stocks are created with checksums, supported only by Go S5 states
 }
 
 // UnsupportedConfiguration is the error returned if unsupported configuration
@@ -89,6 +93,7 @@ const (
 	SqliteArchive  ArchiveType = "sql"
 	S4Archive      ArchiveType = "s4"
 	S5Archive      ArchiveType = "s5"
+	S6Archive      ArchiveType = "s6"
 )
 
 type StateFactory func(params Parameters) (State, error)
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package state
+
+import (
+	"context"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// contextState wraps a state implementation such that read operations fail
+// with the context's error once the given context is done. It is intended for
+// bounding the duration of read-only queries on historic states.
+type contextState struct {
+	State
+	ctx context.Context
+}
+
+// WrapIntoContextState wraps the given state into a state failing all read
+// operations with the error of the given context once it is done. Failed
+// operations do not reach the underlying state, which is thus not put into
+// an error state by a cancellation. Cancellation is best-effort: operations
+// already running on the underlying state when the context is done are not
+// interrupted.
+func WrapIntoContextState(ctx context.Context, state State) State {
+	return &contextState{
+		State: state,
+		ctx:   ctx,
+	}
+}
+
+func (s *contextState) Exists(address common.Address) (bool, error) {
+	if err := s.ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.State.Exists(address)
+}
+
+func (s *contextState) GetBalance(address common.Address) (common.Balance, error) {
+	if err := s.ctx.Err(); err != nil {
+		return common.Balance{}, err
+	}
+	return s.State.GetBalance(address)
+}
+
+func (s *contextState) GetNonce(address common.Address) (common.Nonce, error) {
+	if err := s.ctx.Err(); err != nil {
+		return common.Nonce{}, err
+	}
+	return s.State.GetNonce(address)
+}
+
+func (s *contextState) GetStorage(address common.Address, key common.Key) (common.Value, error) {
+	if err := s.ctx.Err(); err != nil {
+		return common.Value{}, err
+	}
+	return s.State.GetStorage(address, key)
+}
+
+func (s *contextState) GetCode(address common.Address) ([]byte, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.State.GetCode(address)
+}
+
+func (s *contextState) GetCodeSize(address common.Address) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.State.GetCodeSize(address)
+}
+
+func (s *contextState) GetCodeHash(address common.Address) (common.Hash, error) {
+	if err := s.ctx.Err(); err != nil {
+		return common.Hash{}, err
+	}
+	return s.State.GetCodeHash(address)
+}
+
+func (s *contextState) GetHash() (common.Hash, error) {
+	if err := s.ctx.Err(); err != nil {
+		return common.Hash{}, err
+	}
+	return s.State.GetHash()
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"go.uber.org/mock/gomock"
+)
+
+func TestContextState_ReadsAreForwardedWhileContextIsActive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	mock.EXPECT().GetBalance(common.Address{1}).Return(common.Balance{2}, nil)
+
+	state := WrapIntoContextState(context.Background(), mock)
+	balance, err := state.GetBalance(common.Address{1})
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+	if want, got := (common.Balance{2}), balance; want != got {
+		t.Errorf("unexpected balance, wanted %v, got %v", want, got)
+	}
+}
+
+func TestContextState_ReadsFailWithoutReachingStateOnceContextIsDone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	state := WrapIntoContextState(ctx, mock)
+
+	reads := map[string]func() error{
+		"exists":   func() error { _, err := state.Exists(common.Address{}); return err },
+		"balance":  func() error { _, err := state.GetBalance(common.Address{}); return err },
+		"nonce":    func() error { _, err := state.GetNonce(common.Address{}); return err },
+		"storage":  func() error { _, err := state.GetStorage(common.Address{}, common.Key{}); return err },
+		"code":     func() error { _, err := state.GetCode(common.Address{}); return err },
+		"codeSize": func() error { _, err := state.GetCodeSize(common.Address{}); return err },
+		"codeHash": func() error { _, err := state.GetCodeHash(common.Address{}); return err },
+		"hash":     func() error { _, err := state.GetHash(); return err },
+	}
+	for name, read := range reads {
+		if err := read(); !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error of %s, wanted %v, got %v", name, context.Canceled, err)
+		}
+	}
+}
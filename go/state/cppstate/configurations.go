@@ -8,6 +8,10 @@
 // On the date above, in accordance with the Business Source License, use of
 // this software will be governed by the GNU Lesser General Public License v3.
 
+// Package cppstate provides state implementations backed by the C++ library of
+// Carmen. The implementations are only available in builds with cgo enabled
+// and without the purego build tag. Otherwise, this package does not register
+// any configuration.
 package cppstate
 
 import "github.com/Fantom-foundation/Carmen/go/state"
@@ -17,32 +21,3 @@ const (
 	VariantCppFile    state.Variant = "cpp-file"
 	VariantCppLevelDb state.Variant = "cpp-ldb"
 )
-
-func init() {
-	supportedArchives := []state.ArchiveType{
-		state.NoArchive,
-		state.LevelDbArchive,
-		state.SqliteArchive,
-	}
-
-	// Register all configuration options supported by the C++ implementation.
-	for schema := state.Schema(1); schema <= state.Schema(3); schema++ {
-		for _, archive := range supportedArchives {
-			state.RegisterStateFactory(state.Configuration{
-				Variant: VariantCppMemory,
-				Schema:  schema,
-				Archive: archive,
-			}, newInMemoryState)
-			state.RegisterStateFactory(state.Configuration{
-				Variant: VariantCppFile,
-				Schema:  schema,
-				Archive: archive,
-			}, newFileBasedState)
-			state.RegisterStateFactory(state.Configuration{
-				Variant: VariantCppLevelDb,
-				Schema:  schema,
-				Archive: archive,
-			}, newLevelDbBasedState)
-		}
-	}
-}
@@ -8,6 +8,8 @@
 // On the date above, in accordance with the Business Source License, use of
 // this software will be governed by the GNU Lesser General Public License v3.
 
+//go:build cgo && !purego
+
 package cppstate
 
 //go:generate sh ../../lib/build_libcarmen.sh
@@ -21,8 +23,11 @@ package cppstate
 */
 import "C"
 import (
+	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"unsafe"
@@ -234,6 +239,10 @@ func (s *CppState) GetSnapshotVerifier(metadata []byte) (backend.SnapshotVerifie
 	return nil, backend.ErrSnapshotNotSupported
 }
 
+func (cs *CppState) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return nil, fmt.Errorf("%w: disk footprint is not reported by C++ states", state.UnsupportedConfiguration)
+}
+
 func (cs *CppState) GetMemoryFootprint() *common.MemoryFootprint {
 	if cs.state == nil {
 		return common.NewMemoryFootprint(unsafe.Sizeof(*cs))
@@ -263,6 +272,15 @@ func (cs *CppState) GetMemoryFootprint() *common.MemoryFootprint {
 	return res
 }
 
+// GetAdaptiveCaches lists the Go-side code cache of this state.
+func (cs *CppState) GetAdaptiveCaches() map[string]common.AdaptiveCache {
+	return map[string]common.AdaptiveCache{
+		"code": common.NewAdaptiveLruCache(cs.codeCache, func(code []byte) uintptr {
+			return uintptr(cap(code)) // memory consumed by the code slice
+		}),
+	}
+}
+
 func (cs *CppState) GetArchiveState(block uint64) (state.State, error) {
 	return &CppState{
 		state:     C.Carmen_GetArchiveState(cs.state, C.uint64_t(block)),
@@ -279,6 +297,90 @@ func (cs *CppState) Check() error {
 	return nil
 }
 
+// VisitContent reports all existing accounts and their non-zero storage slots
+// to the given callbacks. The content is exported by the C++ state into a
+// temporary file, which is parsed afterwards. The order of the reported
+// entries is undefined. The enumeration is aborted by the first error returned
+// by a callback. Archive states do not support the enumeration of their
+// content.
+func (cs *CppState) VisitContent(
+	visitAccount func(common.Address, common.Balance, common.Nonce, []byte) error,
+	visitSlot func(common.Address, common.Key, common.Value) error,
+) (err error) {
+	file, err := os.CreateTemp("", "carmen-content-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close(), os.Remove(file.Name()))
+	}()
+
+	name := C.CString(file.Name())
+	defer C.free(unsafe.Pointer(name))
+	if C.Carmen_ExportContent(cs.state, name, C.int(len(file.Name()))) == 0 {
+		return fmt.Errorf("failed to export content of C++ state")
+	}
+	return parseContent(bufio.NewReader(file), visitAccount, visitSlot)
+}
+
+// parseContent decodes the content records produced by Carmen_ExportContent
+// and forwards them to the given callbacks.
+func parseContent(
+	reader *bufio.Reader,
+	visitAccount func(common.Address, common.Balance, common.Nonce, []byte) error,
+	visitSlot func(common.Address, common.Key, common.Value) error,
+) error {
+	for {
+		kind, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var address common.Address
+		if _, err := io.ReadFull(reader, address[:]); err != nil {
+			return err
+		}
+		switch kind {
+		case 'A':
+			var balance common.Balance
+			var nonce common.Nonce
+			var length [4]byte
+			if _, err := io.ReadFull(reader, balance[:]); err != nil {
+				return err
+			}
+			if _, err := io.ReadFull(reader, nonce[:]); err != nil {
+				return err
+			}
+			if _, err := io.ReadFull(reader, length[:]); err != nil {
+				return err
+			}
+			code := make([]byte, binary.LittleEndian.Uint32(length[:]))
+			if _, err := io.ReadFull(reader, code); err != nil {
+				return err
+			}
+			if err := visitAccount(address, balance, nonce, code); err != nil {
+				return err
+			}
+		case 'S':
+			var key common.Key
+			var value common.Value
+			if _, err := io.ReadFull(reader, key[:]); err != nil {
+				return err
+			}
+			if _, err := io.ReadFull(reader, value[:]); err != nil {
+				return err
+			}
+			if err := visitSlot(address, key, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid content record type: %d", kind)
+		}
+	}
+}
+
 type objectId struct {
 	obj_loc, obj_type uint64
 }
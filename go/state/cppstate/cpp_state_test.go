@@ -8,6 +8,8 @@
 // On the date above, in accordance with the Business Source License, use of
 // this software will be governed by the GNU Lesser General Public License v3.
 
+//go:build cgo && !purego
+
 package cppstate
 
 import (
@@ -214,6 +216,56 @@ func TestSetAndGetCodeHash(t *testing.T) {
 	})
 }
 
+func TestContentCanBeVisited(t *testing.T) {
+	runForEachCppConfig(t, func(t *testing.T, s state.State) {
+		address2 := common.Address{0x02}
+		code := []byte{1, 2, 3}
+		err := s.Apply(1, common.Update{
+			CreatedAccounts: []common.Address{address1, address2},
+			Balances:        []common.BalanceUpdate{{Account: address1, Balance: balance1}},
+			Nonces:          []common.NonceUpdate{{Account: address1, Nonce: nonce1}},
+			Codes:           []common.CodeUpdate{{Account: address1, Code: code}},
+			Slots: []common.SlotUpdate{
+				{Account: address1, Key: key1, Value: val1},
+				{Account: address2, Key: key1, Value: val1},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+		// Deleted accounts and their slots are not reported.
+		if err := s.Apply(2, common.Update{DeletedAccounts: []common.Address{address2}}); err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+
+		accounts, slots := 0, 0
+		err = state.RunWithUnwrappedState(s, func(s state.State) error {
+			return s.(*CppState).VisitContent(
+				func(address common.Address, balance common.Balance, nonce common.Nonce, got []byte) error {
+					accounts++
+					if address != address1 || balance != balance1 || nonce != nonce1 || !bytes.Equal(got, code) {
+						t.Errorf("unexpected account %x, balance %x, nonce %x, code %x", address, balance, nonce, got)
+					}
+					return nil
+				},
+				func(address common.Address, key common.Key, value common.Value) error {
+					slots++
+					if address != address1 || key != key1 || value != val1 {
+						t.Errorf("unexpected slot %x of account %x with value %x", key, address, value)
+					}
+					return nil
+				},
+			)
+		})
+		if err != nil {
+			t.Fatalf("failed to visit content: %v", err)
+		}
+		if accounts != 1 || slots != 1 {
+			t.Errorf("unexpected number of reported entries, wanted 1 account and 1 slot, got %d and %d", accounts, slots)
+		}
+	})
+}
+
 func runForEachCppConfig(t *testing.T, test func(*testing.T, state.State)) {
 	for config, factory := range state.GetAllRegisteredStateFactories() {
 		config := config
@@ -13,11 +13,14 @@ package gostate
 import (
 	"errors"
 	"fmt"
+	"io"
 	"unsafe"
 
 	"github.com/Fantom-foundation/Carmen/go/backend"
 	"github.com/Fantom-foundation/Carmen/go/backend/archive"
 	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	mptIo "github.com/Fantom-foundation/Carmen/go/database/mpt/io"
 	"github.com/Fantom-foundation/Carmen/go/state"
 	"golang.org/x/crypto/sha3"
 )
@@ -25,16 +28,26 @@ import (
 // ArchiveState represents a historical State. Loads data from the Archive.
 type ArchiveState struct {
 	archive      archive.Archive
+	view         *historicView // < nil if historic views are not cached
 	block        uint64
 	archiveError error
 }
 
+// getReader obtains the source for looking up accounts and storage slots,
+// which is the cached view on the block if present.
+func (s *ArchiveState) getReader() archiveReader {
+	if s.view != nil {
+		return s.view
+	}
+	return s.archive
+}
+
 func (s *ArchiveState) Exists(address common.Address) (bool, error) {
 	if err := s.archiveError; err != nil {
 		return false, err
 	}
 
-	exists, err := s.archive.Exists(s.block, address)
+	exists, err := s.getReader().Exists(s.block, address)
 	if err != nil {
 		s.archiveError = errors.Join(s.archiveError, err)
 	}
@@ -46,7 +59,7 @@ func (s *ArchiveState) GetBalance(address common.Address) (common.Balance, error
 		return common.Balance{}, err
 	}
 
-	balance, err := s.archive.GetBalance(s.block, address)
+	balance, err := s.getReader().GetBalance(s.block, address)
 	if err != nil {
 		s.archiveError = errors.Join(s.archiveError, err)
 	}
@@ -58,7 +71,7 @@ func (s *ArchiveState) GetNonce(address common.Address) (common.Nonce, error) {
 		return common.Nonce{}, err
 	}
 
-	nonce, err := s.archive.GetNonce(s.block, address)
+	nonce, err := s.getReader().GetNonce(s.block, address)
 	if err != nil {
 		s.archiveError = errors.Join(s.archiveError, err)
 	}
@@ -70,7 +83,7 @@ func (s *ArchiveState) GetStorage(address common.Address, key common.Key) (commo
 		return common.Value{}, err
 	}
 
-	storage, err := s.archive.GetStorage(s.block, address, key)
+	storage, err := s.getReader().GetStorage(s.block, address, key)
 	if err != nil {
 		s.archiveError = errors.Join(s.archiveError, err)
 	}
@@ -82,7 +95,7 @@ func (s *ArchiveState) GetCode(address common.Address) ([]byte, error) {
 		return []byte{}, err
 	}
 
-	code, err := s.archive.GetCode(s.block, address)
+	code, err := s.getReader().GetCode(s.block, address)
 	if err != nil {
 		s.archiveError = errors.Join(s.archiveError, err)
 	}
@@ -94,7 +107,7 @@ func (s *ArchiveState) GetCodeSize(address common.Address) (size int, err error)
 		return 0, err
 	}
 
-	code, err := s.archive.GetCode(s.block, address)
+	code, err := s.getReader().GetCode(s.block, address)
 	if err != nil {
 		s.archiveError = errors.Join(s.archiveError, err)
 		return 0, s.archiveError
@@ -107,7 +120,7 @@ func (s *ArchiveState) GetCodeHash(address common.Address) (hash common.Hash, er
 		return common.Hash{}, err
 	}
 
-	code, err := s.archive.GetCode(s.block, address)
+	code, err := s.getReader().GetCode(s.block, address)
 	if err != nil || len(code) == 0 {
 		s.archiveError = errors.Join(s.archiveError, err)
 		return emptyCodeHash, s.archiveError
@@ -138,6 +151,10 @@ func (s *ArchiveState) GetMemoryFootprint() *common.MemoryFootprint {
 	return common.NewMemoryFootprint(unsafe.Sizeof(*s))
 }
 
+func (s *ArchiveState) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return nil, fmt.Errorf("%w: disk footprint is not reported for historic states", state.UnsupportedConfiguration)
+}
+
 func (s *ArchiveState) Flush() error {
 	panic("ArchiveState does not support Flush operation")
 }
@@ -199,3 +216,17 @@ func (s *ArchiveState) GetArchiveBlockHeight() (uint64, bool, error) {
 func (s *ArchiveState) Check() error {
 	return s.archiveError
 }
+
+// Export writes the state of the block represented by this archive state to
+// the given writer using the format of the mpt/io package. Exporting states
+// is only supported for S5 archives.
+func (s *ArchiveState) Export(out io.Writer) error {
+	if err := s.archiveError; err != nil {
+		return err
+	}
+	trie, ok := s.archive.(*mpt.ArchiveTrie)
+	if !ok {
+		return fmt.Errorf("%w: exporting blocks is only supported for S5 archives", state.UnsupportedConfiguration)
+	}
+	return mptIo.ExportBlockFromArchive(trie, s.block, out)
+}
@@ -14,6 +14,7 @@ import (
 	"fmt"
 
 	"github.com/Fantom-foundation/Carmen/go/backend"
+	"github.com/Fantom-foundation/Carmen/go/database/bintrie"
 	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 
 	"io"
@@ -70,13 +71,17 @@ const (
 	VariantGoFileNoCache    state.Variant = "go-file-nocache"
 	VariantGoLevelDb        state.Variant = "go-ldb"
 	VariantGoLevelDbNoCache state.Variant = "go-ldb-nocache"
+	VariantGoPebble         state.Variant = "go-pebble"
+	VariantGoPebbleNoCache  state.Variant = "go-pebble-nocache"
 )
 
 func init() {
 	generallySupportedArchives := []state.ArchiveType{
 		state.NoArchive,
 		state.LevelDbArchive,
-		state.SqliteArchive,
+	}
+	if sqliteArchiveSupported {
+		generallySupportedArchives = append(generallySupportedArchives, state.SqliteArchive)
 	}
 
 	// Register all configuration options supported by the Go implementation.
@@ -104,12 +109,22 @@ func init() {
 					Variant: VariantGoLevelDb,
 					Schema:  schema,
 					Archive: archive,
-				}, newGoCachedLeveLIndexAndStoreState)
+				}, withKVStore(newGoCachedKVIndexAndStoreState, openLevelDbStore))
 				state.RegisterStateFactory(state.Configuration{
 					Variant: VariantGoLevelDbNoCache,
 					Schema:  schema,
 					Archive: archive,
-				}, newGoLeveLIndexAndStoreState)
+				}, withKVStore(newGoKVIndexAndStoreState, openLevelDbStore))
+				state.RegisterStateFactory(state.Configuration{
+					Variant: VariantGoPebble,
+					Schema:  schema,
+					Archive: archive,
+				}, withKVStore(newGoCachedKVIndexAndStoreState, openPebbleStore))
+				state.RegisterStateFactory(state.Configuration{
+					Variant: VariantGoPebbleNoCache,
+					Schema:  schema,
+					Archive: archive,
+				}, withKVStore(newGoKVIndexAndStoreState, openPebbleStore))
 			}
 		}
 	}
@@ -136,6 +151,20 @@ func init() {
 		}, newGoFileState)
 	}
 
+	// Schema 6 is an experimental binary trie based schema.
+	for _, archive := range []state.ArchiveType{state.NoArchive, state.S6Archive} {
+		state.RegisterStateFactory(state.Configuration{
+			Variant: VariantGoMemory,
+			Schema:  6,
+			Archive: archive,
+		}, newGoMemoryState)
+
+		state.RegisterStateFactory(state.Configuration{
+			Variant: VariantGoFile,
+			Schema:  6,
+			Archive: archive,
+		}, newGoFileState)
+	}
 }
 
 // newGoMemoryState creates in memory implementation
@@ -154,6 +183,9 @@ func newGoMemoryState(params state.Parameters) (state.State, error) {
 	if params.Schema == 5 {
 		return newGoMemoryS5State(params)
 	}
+	if params.Schema == 6 {
+		return newGoMemoryS6State(params)
+	}
 
 	addressIndex := indexmem.NewIndex[common.Address, uint32](common.AddressSerializer{})
 	accountsStore, err := storemem.NewStore[uint32, common.AccountState](common.AccountStateSerializer{}, common.PageSize, htmemory.CreateHashTreeFactory(HashTreeFactor))
@@ -245,7 +277,7 @@ func newGoMemoryState(params state.Parameters) (state.State, error) {
 			nil,
 		}
 	default:
-		return nil, fmt.Errorf("%w: the go implementation only supports schemas 1-5, got %d", state.UnsupportedConfiguration, params.Schema)
+		return nil, fmt.Errorf("%w: the go implementation only supports schemas 1-6, got %d", state.UnsupportedConfiguration, params.Schema)
 	}
 
 	arch, archiveCleanup, err := openArchive(params)
@@ -253,7 +285,7 @@ func newGoMemoryState(params state.Parameters) (state.State, error) {
 		return nil, err
 	}
 
-	state := newGoState(live, arch, []func(){archiveCleanup})
+	state := newGoState(live, arch, []func(){archiveCleanup}, params)
 	return state, nil
 }
 
@@ -272,6 +304,9 @@ func newGoFileState(params state.Parameters) (state.State, error) {
 	if params.Schema == 5 {
 		return newGoFileS5State(params)
 	}
+	if params.Schema == 6 {
+		return newGoFileS6State(params)
+	}
 
 	indexPath, storePath, err := createSubDirs(path)
 	if err != nil {
@@ -426,7 +461,7 @@ func newGoFileState(params state.Parameters) (state.State, error) {
 			nil,
 		}
 	default:
-		return nil, fmt.Errorf("%w: the go implementation only supports schemas 1-5, got %d", state.UnsupportedConfiguration, params.Schema)
+		return nil, fmt.Errorf("%w: the go implementation only supports schemas 1-6, got %d", state.UnsupportedConfiguration, params.Schema)
 	}
 
 	arch, archiveCleanup, err := openArchive(params)
@@ -434,7 +469,7 @@ func newGoFileState(params state.Parameters) (state.State, error) {
 		return nil, err
 	}
 
-	state := newGoState(live, arch, []func(){archiveCleanup})
+	state := newGoState(live, arch, []func(){archiveCleanup}, params)
 	return state, nil
 }
 
@@ -453,6 +488,9 @@ func newGoCachedFileState(params state.Parameters) (state.State, error) {
 	if params.Schema == 5 {
 		return newGoFileS5State(params)
 	}
+	if params.Schema == 6 {
+		return newGoFileS6State(params)
+	}
 
 	indexPath, storePath, err := createSubDirs(path)
 	if err != nil {
@@ -603,7 +641,7 @@ func newGoCachedFileState(params state.Parameters) (state.State, error) {
 			nil,
 		}
 	default:
-		return nil, fmt.Errorf("%w: the go implementation only supports schemas 1-5, got %d", state.UnsupportedConfiguration, params.Schema)
+		return nil, fmt.Errorf("%w: the go implementation only supports schemas 1-6, got %d", state.UnsupportedConfiguration, params.Schema)
 	}
 
 	arch, archiveCleanup, err := openArchive(params)
@@ -611,16 +649,51 @@ func newGoCachedFileState(params state.Parameters) (state.State, error) {
 		return nil, err
 	}
 
-	state := newGoState(live, arch, []func(){archiveCleanup})
+	state := newGoState(live, arch, []func(){archiveCleanup}, params)
 	return state, nil
 }
 
-// newGoLeveLIndexAndStoreState creates Index and Store both backed up by the leveldb
-func newGoLeveLIndexAndStoreState(params state.Parameters) (state.State, error) {
+// kvStore is a key-value store backing the Index and Store instances of a state.
+type kvStore interface {
+	backend.KVStore
+	io.Closer
+}
+
+// kvStoreOpener opens the key-value store in the given directory.
+type kvStoreOpener func(path string) (kvStore, error)
+
+// openLevelDbStore opens a LevelDB key-value store.
+func openLevelDbStore(path string) (kvStore, error) {
+	db, err := backend.OpenLevelDb(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// openPebbleStore opens a Pebble key-value store.
+func openPebbleStore(path string) (kvStore, error) {
+	db, err := backend.OpenPebbleDb(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// withKVStore binds a state factory based on a key-value store to the engine
+// opened by the given opener.
+func withKVStore(factory func(state.Parameters, kvStoreOpener) (state.State, error), open kvStoreOpener) state.StateFactory {
+	return func(params state.Parameters) (state.State, error) {
+		return factory(params, open)
+	}
+}
+
+// newGoKVIndexAndStoreState creates Index and Store both backed up by a key-value store
+func newGoKVIndexAndStoreState(params state.Parameters, open kvStoreOpener) (state.State, error) {
 	if params.Schema == 0 {
 		params.Schema = defaultSchema
 	}
-	db, err := backend.OpenLevelDb(params.Directory+string(filepath.Separator)+"live", nil)
+	db, err := open(params.Directory + string(filepath.Separator) + "live")
 	if err != nil {
 		return nil, err
 	}
@@ -740,16 +813,16 @@ func newGoLeveLIndexAndStoreState(params state.Parameters) (state.State, error)
 		return nil, err
 	}
 
-	state := newGoState(live, arch, []func(){archiveCleanup, cleanUpByClosing(db)})
+	state := newGoState(live, arch, []func(){archiveCleanup, cleanUpByClosing(db)}, params)
 	return state, nil
 }
 
-// newGoCachedLeveLIndexAndStoreState creates Index and Store both backed up by the leveldb
-func newGoCachedLeveLIndexAndStoreState(params state.Parameters) (state.State, error) {
+// newGoCachedKVIndexAndStoreState creates Index and Store both backed up by a key-value store
+func newGoCachedKVIndexAndStoreState(params state.Parameters, open kvStoreOpener) (state.State, error) {
 	if params.Schema == 0 {
 		params.Schema = defaultSchema
 	}
-	db, err := backend.OpenLevelDb(params.Directory+string(filepath.Separator)+"live", nil)
+	db, err := open(params.Directory + string(filepath.Separator) + "live")
 	if err != nil {
 		return nil, err
 	}
@@ -869,7 +942,7 @@ func newGoCachedLeveLIndexAndStoreState(params state.Parameters) (state.State, e
 		return nil, err
 	}
 
-	state := newGoState(live, arch, []func(){archiveCleanup, cleanUpByClosing(db)})
+	state := newGoState(live, arch, []func(){archiveCleanup, cleanUpByClosing(db)}, params)
 	return state, nil
 }
 
@@ -945,7 +1018,17 @@ func openArchive(params state.Parameters) (archive archive.Archive, cleanup func
 		if err != nil {
 			return nil, nil, err
 		}
-		arch, err := mpt.OpenArchiveTrie(path, mpt.S5ArchiveConfig, mptStateCapacity(params.ArchiveCache))
+		config := mpt.S5ArchiveConfig
+		config.UseStockChecksums = params.StockChecksums
+		arch, err := mpt.OpenArchiveTrie(path, config, mptStateCapacity(params.ArchiveCache))
+		return arch, nil, err
+
+	case state.S6Archive:
+		path, err := getArchivePath(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		arch, err := bintrie.OpenArchiveTrie(path, binaryTrieCacheCapacity(params.ArchiveCache))
 		return arch, nil, err
 	}
 	return nil, nil, fmt.Errorf("unknown archive type: %v", params.Archive)
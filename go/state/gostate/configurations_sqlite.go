@@ -0,0 +1,17 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+//go:build cgo && !purego
+
+package gostate
+
+// sqliteArchiveSupported is true if the SQLite based archive is available.
+// The SQLite driver requires cgo; in builds without cgo it is only a stub.
+const sqliteArchiveSupported = true
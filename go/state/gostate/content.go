@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package gostate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/depot"
+	"github.com/Fantom-foundation/Carmen/go/backend/index"
+	"github.com/Fantom-foundation/Carmen/go/backend/store"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// contentVisitor is notified about the accounts and storage slots of a
+// LiveDB enumerated by a contentSource.
+type contentVisitor interface {
+	// visitAccount is called once for each existing account.
+	visitAccount(address common.Address, balance common.Balance, nonce common.Nonce, code []byte) error
+	// visitSlot is called once for each non-zero storage slot of an existing
+	// account. Slots may be reported before or after their account.
+	visitSlot(address common.Address, key common.Key, value common.Value) error
+}
+
+// contentSource is implemented by LiveDBs able to enumerate their content.
+// The legacy schemas have no trie to be traversed, thus their content is
+// enumerated using the indexes and stores of the respective schema.
+type contentSource interface {
+	// visitContent reports all existing accounts and their non-zero storage
+	// slots to the given visitor. The order of the reported entries is
+	// undefined. The enumeration is aborted by the first error returned by
+	// the visitor. The LiveDB must not be modified during the enumeration.
+	visitContent(visitor contentVisitor) error
+}
+
+// visitContent reports the content of the LiveDB of this state to the given
+// visitor. It is only supported by the LiveDB implementations of schemas 1-5.
+func (s *GoState) visitContent(visitor contentVisitor) error {
+	if err := s.stateError; err != nil {
+		return err
+	}
+	source, ok := s.live.(contentSource)
+	if !ok {
+		return fmt.Errorf("%w: the LiveDB of this state does not support the enumeration of its content", state.UnsupportedConfiguration)
+	}
+	return source.visitContent(visitor)
+}
+
+// visitIndexedAccounts reports all existing accounts of a legacy schema
+// registered in the given address index to the visitor. It returns the
+// addresses of the existing accounts by their identifier, which is needed to
+// resolve the owners of storage slots.
+func visitIndexedAccounts(
+	addressIndex index.Index[common.Address, uint32],
+	accountsStore store.Store[uint32, common.AccountState],
+	balancesStore store.Store[uint32, common.Balance],
+	noncesStore store.Store[uint32, common.Nonce],
+	codesDepot depot.Depot[uint32],
+	visitor contentVisitor,
+) (map[uint32]common.Address, error) {
+	accounts := map[uint32]common.Address{}
+	err := index.ForEach(addressIndex, func(address common.Address, idx uint32) error {
+		accountState, err := accountsStore.Get(idx)
+		if err != nil || accountState != common.Exists {
+			return err
+		}
+		balance, err := balancesStore.Get(idx)
+		if err != nil {
+			return err
+		}
+		nonce, err := noncesStore.Get(idx)
+		if err != nil {
+			return err
+		}
+		code, err := codesDepot.Get(idx)
+		if err != nil {
+			return err
+		}
+		accounts[idx] = address
+		return visitor.visitAccount(address, balance, nonce, code)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// visitMptContent reports the content of the given MPT state to the visitor.
+// Accounts are reported before their storage slots.
+func visitMptContent(mptState *mpt.MptState, visitor contentVisitor) error {
+	codes, err := mptState.GetCodes()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve codes: %w", err)
+	}
+	v := mptContentVisitor{codes: codes, visitor: visitor}
+	if err := mptState.Visit(&v); err != nil || v.err != nil {
+		return fmt.Errorf("failed to enumerate content: %w", errors.Join(err, v.err))
+	}
+	return nil
+}
+
+// mptContentVisitor forwards the account and value nodes of an MPT to a
+// contentVisitor. Nodes are visited in depth-first order, thus the value
+// nodes of the storage trie of an account follow the account node.
+type mptContentVisitor struct {
+	codes   map[common.Hash][]byte
+	visitor contentVisitor
+	account common.Address
+	err     error
+}
+
+func (v *mptContentVisitor) Visit(node mpt.Node, _ mpt.NodeInfo) mpt.VisitResponse {
+	switch n := node.(type) {
+	case *mpt.AccountNode:
+		v.account = n.Address()
+		info := n.Info()
+		v.err = v.visitor.visitAccount(v.account, info.Balance, info.Nonce, v.codes[info.CodeHash])
+	case *mpt.ValueNode:
+		v.err = v.visitor.visitSlot(v.account, n.Key(), n.Value())
+	}
+	if v.err != nil {
+		return mpt.VisitResponseAbort
+	}
+	return mpt.VisitResponseContinue
+}
@@ -12,6 +12,7 @@ package gostate
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"hash"
 	"io"
 
@@ -345,6 +346,11 @@ func (s *GoSchema1) RunPostRestoreTasks() error {
 	return backend.ErrSnapshotNotSupported
 }
 
+// getCodesDepot provides the depot storing the codes of accounts.
+func (s *GoSchema1) getCodesDepot() depot.Depot[uint32] {
+	return s.codesDepot
+}
+
 // GetMemoryFootprint provides sizes of individual components of the state in the memory
 func (s *GoSchema1) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(0)
@@ -360,3 +366,49 @@ func (s *GoSchema1) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("addressToSlots", s.addressToSlots.GetMemoryFootprint())
 	return mf
 }
+
+// GetDiskFootprint provides sizes of individual components of the state on disk. The
+// addressToSlots multimap has no files of its own and is thus not covered.
+func (s *GoSchema1) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return getDiskFootprintOf(map[string]common.DiskFootprintProvider{
+		"addressIndex":    s.addressIndex,
+		"keyIndex":        s.keyIndex,
+		"slotIndex":       s.slotIndex,
+		"accountsStore":   s.accountsStore,
+		"noncesStore":     s.noncesStore,
+		"balancesStore":   s.balancesStore,
+		"valuesStore":     s.valuesStore,
+		"codesDepot":      s.codesDepot,
+		"codeHashesStore": s.codeHashesStore,
+	})
+}
+
+func (s *GoSchema1) visitContent(visitor contentVisitor) error {
+	accounts, err := visitIndexedAccounts(s.addressIndex, s.accountsStore, s.balancesStore, s.noncesStore, s.codesDepot, visitor)
+	if err != nil {
+		return err
+	}
+	keys := make([]common.Key, s.keyIndex.Size())
+	err = index.ForEach(s.keyIndex, func(key common.Key, idx uint32) error {
+		keys[idx] = key
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enumerate keys: %w", err)
+	}
+	err = index.ForEach(s.slotIndex, func(slot common.SlotIdx[uint32], idx uint32) error {
+		address, exists := accounts[slot.AddressIdx]
+		if !exists {
+			return nil
+		}
+		value, err := s.valuesStore.Get(idx)
+		if err != nil || value == (common.Value{}) {
+			return err
+		}
+		return visitor.visitSlot(address, keys[slot.KeyIdx], value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enumerate slots: %w", err)
+	}
+	return nil
+}
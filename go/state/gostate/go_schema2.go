@@ -12,6 +12,7 @@ package gostate
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"hash"
 	"io"
 
@@ -329,6 +330,11 @@ func (s *GoSchema2) RunPostRestoreTasks() error {
 	return backend.ErrSnapshotNotSupported
 }
 
+// getCodesDepot provides the depot storing the codes of accounts.
+func (s *GoSchema2) getCodesDepot() depot.Depot[uint32] {
+	return s.codesDepot
+}
+
 // GetMemoryFootprint provides sizes of individual components of the state in the memory
 func (s *GoSchema2) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(0)
@@ -343,3 +349,40 @@ func (s *GoSchema2) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("addressToSlots", s.addressToSlots.GetMemoryFootprint())
 	return mf
 }
+
+// GetDiskFootprint provides sizes of individual components of the state on disk. The
+// addressToSlots multimap has no files of its own and is thus not covered.
+func (s *GoSchema2) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return getDiskFootprintOf(map[string]common.DiskFootprintProvider{
+		"addressIndex":    s.addressIndex,
+		"slotIndex":       s.slotIndex,
+		"accountsStore":   s.accountsStore,
+		"noncesStore":     s.noncesStore,
+		"balancesStore":   s.balancesStore,
+		"valuesStore":     s.valuesStore,
+		"codesDepot":      s.codesDepot,
+		"codeHashesStore": s.codeHashesStore,
+	})
+}
+
+func (s *GoSchema2) visitContent(visitor contentVisitor) error {
+	accounts, err := visitIndexedAccounts(s.addressIndex, s.accountsStore, s.balancesStore, s.noncesStore, s.codesDepot, visitor)
+	if err != nil {
+		return err
+	}
+	err = index.ForEach(s.slotIndex, func(slot common.SlotIdxKey[uint32], idx uint32) error {
+		address, exists := accounts[slot.AddressIdx]
+		if !exists {
+			return nil
+		}
+		value, err := s.valuesStore.Get(idx)
+		if err != nil || value == (common.Value{}) {
+			return err
+		}
+		return visitor.visitSlot(address, slot.Key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enumerate slots: %w", err)
+	}
+	return nil
+}
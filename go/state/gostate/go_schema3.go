@@ -12,6 +12,7 @@ package gostate
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"hash"
 	"io"
 
@@ -378,6 +379,11 @@ func (s *GoSchema3) RunPostRestoreTasks() error {
 	return s.codeHashesStore.Restore(snapshot.GetData())
 }
 
+// getCodesDepot provides the depot storing the codes of accounts.
+func (s *GoSchema3) getCodesDepot() depot.Depot[uint32] {
+	return s.codesDepot
+}
+
 // GetMemoryFootprint provides sizes of individual components of the state in the memory
 func (s *GoSchema3) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(0)
@@ -392,3 +398,45 @@ func (s *GoSchema3) GetMemoryFootprint() *common.MemoryFootprint {
 	mf.AddChild("codeHashesStore", s.codeHashesStore.GetMemoryFootprint())
 	return mf
 }
+
+// GetDiskFootprint provides sizes of individual components of the state on disk
+func (s *GoSchema3) GetDiskFootprint() (*common.DiskFootprint, error) {
+	return getDiskFootprintOf(map[string]common.DiskFootprintProvider{
+		"addressIndex":        s.addressIndex,
+		"slotIndex":           s.slotIndex,
+		"accountsStore":       s.accountsStore,
+		"noncesStore":         s.noncesStore,
+		"reincarnationsStore": s.reincarnationsStore,
+		"balancesStore":       s.balancesStore,
+		"valuesStore":         s.valuesStore,
+		"codesDepot":          s.codesDepot,
+		"codeHashesStore":     s.codeHashesStore,
+	})
+}
+
+func (s *GoSchema3) visitContent(visitor contentVisitor) error {
+	accounts, err := visitIndexedAccounts(s.addressIndex, s.accountsStore, s.balancesStore, s.noncesStore, s.codesDepot, visitor)
+	if err != nil {
+		return err
+	}
+	err = index.ForEach(s.slotIndex, func(slot common.SlotIdxKey[uint32], idx uint32) error {
+		address, exists := accounts[slot.AddressIdx]
+		if !exists {
+			return nil
+		}
+		reincarnation, err := s.reincarnationsStore.Get(slot.AddressIdx)
+		if err != nil {
+			return err
+		}
+		// Values of previous incarnations of the account are outdated.
+		value, err := s.valuesStore.Get(idx)
+		if err != nil || value.Reincarnation != reincarnation || value.Value == (common.Value{}) {
+			return err
+		}
+		return visitor.visitSlot(address, slot.Key, value.Value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enumerate slots: %w", err)
+	}
+	return nil
+}
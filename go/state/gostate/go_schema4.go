@@ -38,7 +38,7 @@ func newS4State(params state.Parameters, mptState *mpt.MptState) (state.State, e
 	}
 	return newGoState(&goSchema4{
 		MptState: mptState,
-	}, arch, []func(){archiveCleanup}), nil
+	}, arch, []func(){archiveCleanup}, params), nil
 }
 
 func newGoMemoryS4State(params state.Parameters) (state.State, error) {
@@ -56,3 +56,7 @@ func newGoFileS4State(params state.Parameters) (state.State, error) {
 	}
 	return newS4State(params, state)
 }
+
+func (s *goSchema4) visitContent(visitor contentVisitor) error {
+	return visitMptContent(s.MptState, visitor)
+}
@@ -74,7 +74,7 @@ func newS5State(params state.Parameters, mptState *mpt.MptState) (state.State, e
 
 	return newGoState(&goSchema5{
 		MptState: mptState,
-	}, arch, []func(){archiveCleanup}), nil
+	}, arch, []func(){archiveCleanup}, params), nil
 }
 
 func mptStateCapacity(param int64) int {
@@ -88,8 +88,16 @@ func mptStateCapacity(param int64) int {
 	return capacity
 }
 
+// s5LiveConfig provides the MPT configuration of S5 LiveDBs for the given
+// parameters.
+func s5LiveConfig(params state.Parameters) mpt.MptConfig {
+	config := mpt.S5LiveConfig
+	config.UseStockChecksums = params.StockChecksums
+	return config
+}
+
 func newGoMemoryS5State(params state.Parameters) (state.State, error) {
-	state, err := mpt.OpenGoMemoryState(filepath.Join(params.Directory, "live"), mpt.S5LiveConfig, mptStateCapacity(params.LiveCache))
+	state, err := mpt.OpenGoMemoryState(filepath.Join(params.Directory, "live"), s5LiveConfig(params), mptStateCapacity(params.LiveCache))
 	if err != nil {
 		return nil, err
 	}
@@ -97,9 +105,13 @@ func newGoMemoryS5State(params state.Parameters) (state.State, error) {
 }
 
 func newGoFileS5State(params state.Parameters) (state.State, error) {
-	state, err := mpt.OpenGoFileState(filepath.Join(params.Directory, "live"), mpt.S5LiveConfig, mptStateCapacity(params.LiveCache))
+	state, err := mpt.OpenGoFileState(filepath.Join(params.Directory, "live"), s5LiveConfig(params), mptStateCapacity(params.LiveCache))
 	if err != nil {
 		return nil, err
 	}
 	return newS5State(params, state)
 }
+
+func (s *goSchema5) visitContent(visitor contentVisitor) error {
+	return visitMptContent(s.MptState, visitor)
+}
@@ -11,9 +11,15 @@
 package gostate
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
 	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 	"github.com/Fantom-foundation/Carmen/go/state"
-	"testing"
 )
 
 func TestScheme5_Archive_And_Live_Must_Be_InSync(t *testing.T) {
@@ -129,3 +135,100 @@ func TestCarmen_Empty_Archive_And_Live_Must_Be_InSync(t *testing.T) {
 		t.Errorf("opening database should fail")
 	}
 }
+
+func TestScheme5_CountDirtyNodes_ReportsUnflushedModifications(t *testing.T) {
+	for _, archive := range []state.ArchiveType{state.NoArchive, state.S5Archive} {
+		t.Run(string(archive), func(t *testing.T) {
+			config := namedStateConfig{
+				config: state.Configuration{
+					Variant: VariantGoFile,
+					Schema:  5,
+					Archive: archive,
+				},
+				factory: newGoFileState,
+			}
+			db, err := config.createState(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			defer db.Close()
+			goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+
+			update := common.Update{
+				CreatedAccounts: []common.Address{{1}, {2}},
+				Balances:        []common.BalanceUpdate{{Account: common.Address{1}, Balance: common.Balance{1}}, {Account: common.Address{2}, Balance: common.Balance{2}}},
+			}
+			if err := db.Apply(0, update); err != nil {
+				t.Fatalf("cannot add block: %v", err)
+			}
+			if err := db.Flush(); err != nil {
+				t.Fatalf("cannot flush database: %v", err)
+			}
+			if got, err := goState.CountDirtyNodes(); err != nil || got != 0 {
+				t.Errorf("unexpected number of dirty nodes after flush, wanted 0, got %d, err %v", got, err)
+			}
+
+			update = common.Update{
+				Balances: []common.BalanceUpdate{{Account: common.Address{1}, Balance: common.Balance{3}}},
+			}
+			if err := db.Apply(1, update); err != nil {
+				t.Fatalf("cannot add block: %v", err)
+			}
+			if got, err := goState.CountDirtyNodes(); err != nil || got == 0 {
+				t.Errorf("modified nodes should be reported as dirty, got %d, err %v", got, err)
+			}
+		})
+	}
+}
+
+func TestScheme5_StockChecksums_AreMaintainedAndScrubbedIfRequested(t *testing.T) {
+	dir := t.TempDir()
+	db, err := newGoFileState(state.Parameters{
+		Variant:        VariantGoFile,
+		Schema:         5,
+		Archive:        state.S5Archive,
+		Directory:      dir,
+		StockChecksums: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+
+	update := common.Update{
+		CreatedAccounts: []common.Address{{1}, {2}},
+		Balances:        []common.BalanceUpdate{{Account: common.Address{1}, Balance: common.Balance{1}}, {Account: common.Address{2}, Balance: common.Balance{2}}},
+	}
+	if err := db.Apply(0, update); err != nil {
+		t.Fatalf("cannot add block: %v", err)
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatalf("cannot flush database: %v", err)
+	}
+
+	for _, component := range []string{"live", "archive"} {
+		meta, err := os.ReadFile(filepath.Join(dir, component, "accounts", "meta.json"))
+		if err != nil {
+			t.Fatalf("failed to read stock metadata: %v", err)
+		}
+		if !strings.Contains(string(meta), `"Checksums":true`) {
+			t.Errorf("accounts of %s should be stored with checksums, got metadata %s", component, meta)
+		}
+	}
+
+	scrub, err := goState.PrepareScrub()
+	if err != nil {
+		t.Fatalf("failed to prepare scrub: %v", err)
+	}
+	live, archive, err := scrub(context.Background(), mpt.ScrubConfig{})
+	if err != nil {
+		t.Fatalf("failed to scrub state: %v", err)
+	}
+	if live.NumNodes == 0 || archive.NumNodes == 0 {
+		t.Errorf("nodes of LiveDB and archive should be scrubbed, got %d and %d", live.NumNodes, archive.NumNodes)
+	}
+	if len(live.BadNodes) != 0 || len(archive.BadNodes) != 0 {
+		t.Errorf("unexpected bad nodes, got %v and %v", live.BadNodes, archive.BadNodes)
+	}
+}
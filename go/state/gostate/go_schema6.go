@@ -0,0 +1,96 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package gostate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/bintrie"
+	"github.com/Fantom-foundation/Carmen/go/state"
+)
+
+// goSchema6 implements an experimental state utilizing a binary trie in the
+// style of EIP-7864. It is not binary compatible with any Ethereum state.
+type goSchema6 struct {
+	*bintrie.State
+}
+
+func newS6State(params state.Parameters, liveState *bintrie.State) (state.State, error) {
+	if params.Archive == state.S4Archive || params.Archive == state.S5Archive {
+		return nil, errors.Join(
+			fmt.Errorf("%w: cannot use archive %v with schema 6", state.UnsupportedConfiguration, params.Archive),
+			liveState.Close(),
+		)
+	}
+	arch, archiveCleanup, err := openArchive(params)
+	if err != nil {
+		return nil, errors.Join(err, liveState.Close())
+	}
+
+	if params.Archive == state.S6Archive {
+		archiveBlockHeight, empty, err := arch.GetBlockHeight()
+		if err != nil {
+			return nil, errors.Join(err, arch.Close(), liveState.Close())
+		}
+		liveHash, err := liveState.GetHash()
+		if err != nil {
+			return nil, errors.Join(err, arch.Close(), liveState.Close())
+		}
+		// The hash of an empty binary trie is the zero hash.
+		var archiveHash common.Hash
+		if !empty {
+			archiveHash, err = arch.GetHash(archiveBlockHeight)
+			if err != nil {
+				return nil, errors.Join(err, arch.Close(), liveState.Close())
+			}
+		}
+		if archiveHash != liveHash {
+			return nil, errors.Join(
+				fmt.Errorf("archive and live state hashes do not match: archive: 0x%x != live: 0x%x", archiveHash, liveHash),
+				arch.Close(),
+				liveState.Close())
+		}
+	}
+
+	return newGoState(&goSchema6{
+		State: liveState,
+	}, arch, []func(){archiveCleanup}, params), nil
+}
+
+func binaryTrieCacheCapacity(param int64) int {
+	if param <= 0 {
+		return bintrie.DefaultCacheCapacity
+	}
+	capacity := int(param / int64(bintrie.EstimatePerNodeMemoryUsage()))
+	if capacity < bintrie.MinCacheCapacity {
+		capacity = bintrie.MinCacheCapacity
+	}
+	return capacity
+}
+
+func newGoMemoryS6State(params state.Parameters) (state.State, error) {
+	state, err := bintrie.OpenGoMemoryState(filepath.Join(params.Directory, "live"), binaryTrieCacheCapacity(params.LiveCache))
+	if err != nil {
+		return nil, err
+	}
+	return newS6State(params, state)
+}
+
+func newGoFileS6State(params state.Parameters) (state.State, error) {
+	state, err := bintrie.OpenGoFileState(filepath.Join(params.Directory, "live"), binaryTrieCacheCapacity(params.LiveCache))
+	if err != nil {
+		return nil, err
+	}
+	return newS6State(params, state)
+}
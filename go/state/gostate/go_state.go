@@ -11,13 +11,18 @@
 package gostate
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"runtime"
+	"sync"
 
 	"github.com/Fantom-foundation/Carmen/go/backend"
 	"github.com/Fantom-foundation/Carmen/go/backend/archive"
+	"github.com/Fantom-foundation/Carmen/go/backend/depot"
 	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 	"github.com/Fantom-foundation/Carmen/go/state"
 	"golang.org/x/crypto/sha3"
 )
@@ -29,35 +34,69 @@ type GoState struct {
 	archive archive.Archive
 	cleanup []func()
 
+	// Directories of key-value stores owned by this state, shared by all
+	// components of the LiveDB or the archive respectively. Their disk usage
+	// is reported as a whole, since it can not be attributed to individual
+	// components. Empty if the components own their files.
+	liveStoreDirectory    string
+	archiveStoreDirectory string
+
 	stateError error // collect errors occurred during operation
 
+	updateListener func(block uint64, update *common.Update) // < nil if no listener is registered
+
+	historicViews *historicViewCache // < nil if views on historic blocks are not cached
+
 	// Channels are only present if archive is enabled.
 	archiveWriter          chan<- archiveUpdate
 	archiveWriterFlushDone <-chan bool
 	archiveWriterDone      <-chan bool
 	archiveWriterError     <-chan error
+	archiveQueue           chan<- archiveUpdate // < same as archiveWriter, but retained after closing
+	archiveProgress        *archiveProgress
 }
 
-func newGoState(live state.LiveDB, archive archive.Archive, cleanup []func()) state.State {
+// defaultArchiveQueueSize is the number of updates buffered for the archive
+// writer if no queue size is configured.
+const defaultArchiveQueueSize = 10
+
+func newGoState(live state.LiveDB, archive archive.Archive, cleanup []func(), params state.Parameters) state.State {
 
 	res := &GoState{
 		live:    live,
 		archive: archive,
 		cleanup: cleanup,
 	}
+	switch params.Variant {
+	case VariantGoLevelDb, VariantGoLevelDbNoCache, VariantGoPebble, VariantGoPebbleNoCache:
+		res.liveStoreDirectory = filepath.Join(params.Directory, "live")
+	}
+	if archive != nil {
+		switch params.Archive {
+		case state.LevelDbArchive, state.SqliteArchive:
+			res.archiveStoreDirectory = filepath.Join(params.Directory, "archive")
+		}
+	}
 
 	// If there is an archive, start an asynchronous archive writer routine.
 	if archive != nil {
-		in := make(chan archiveUpdate, 10)
+		archiveQueueSize := params.ArchiveQueueSize
+		if archiveQueueSize <= 0 {
+			archiveQueueSize = defaultArchiveQueueSize
+		}
+		in := make(chan archiveUpdate, archiveQueueSize)
 		flush := make(chan bool)
 		done := make(chan bool)
 		err := make(chan error, 10)
 
+		progress := newArchiveProgress()
+
 		go func() {
 			runtime.LockOSThread()
 			defer runtime.UnlockOSThread()
 			defer close(flush)
 			defer close(done)
+			defer progress.stop()
 			// Process all incoming updates, no not stop on errors.
 			for update := range in {
 				// If there is no update, the state is asking for a flush signal.
@@ -71,6 +110,9 @@ func newGoState(live state.LiveDB, archive archive.Archive, cleanup []func()) st
 					issue := res.archive.Add(update.block, *update.update, update.updateHints)
 					if issue != nil {
 						err <- issue
+						progress.fail(issue)
+					} else {
+						progress.ingested(update.block)
 					}
 					if update.updateHints != nil {
 						update.updateHints.Release()
@@ -83,6 +125,12 @@ func newGoState(live state.LiveDB, archive archive.Archive, cleanup []func()) st
 		res.archiveWriterDone = done
 		res.archiveWriterFlushDone = flush
 		res.archiveWriterError = err
+		res.archiveQueue = in
+		res.archiveProgress = progress
+
+		if params.HistoricViewCache > 0 {
+			res.historicViews = newHistoricViewCache(params.HistoricViewCache)
+		}
 	}
 
 	return state.WrapIntoSyncedState(res)
@@ -204,6 +252,10 @@ func (s *GoState) Apply(block uint64, update common.Update) error {
 		return s.stateError
 	}
 
+	if s.updateListener != nil {
+		s.updateListener(block, &update)
+	}
+
 	if s.archive != nil {
 		// Send the update to the writer to be processed asynchronously.
 		s.archiveWriter <- archiveUpdate{block, &update, archiveUpdateHints}
@@ -229,6 +281,17 @@ func (s *GoState) Apply(block uint64, update common.Update) error {
 	return nil
 }
 
+func (s *GoState) GetHashAfter(update common.Update) (common.Hash, error) {
+	if err := s.stateError; err != nil {
+		return common.Hash{}, err
+	}
+	previewer, ok := s.live.(state.HashPreviewer)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("%w: hash previews are not supported by the LiveDB of this state", state.UnsupportedConfiguration)
+	}
+	return previewer.GetHashAfter(update)
+}
+
 // GetMemoryFootprint provides sizes of individual components of the state in the memory
 func (s *GoState) GetMemoryFootprint() *common.MemoryFootprint {
 	mf := common.NewMemoryFootprint(0)
@@ -236,9 +299,81 @@ func (s *GoState) GetMemoryFootprint() *common.MemoryFootprint {
 	if s.archive != nil {
 		mf.AddChild("archive", s.archive.GetMemoryFootprint())
 	}
+	if s.historicViews != nil {
+		mf.AddChild("historicViews", s.historicViews.GetMemoryFootprint())
+	}
 	return mf
 }
 
+// GetDiskFootprint provides sizes of individual components of the state on disk
+func (s *GoState) GetDiskFootprint() (*common.DiskFootprint, error) {
+	liveFootprint, err := getComponentDiskFootprint("LiveDB", s.live, s.liveStoreDirectory)
+	if err != nil {
+		return nil, err
+	}
+	df := common.NewDiskFootprint(0)
+	df.AddChild("live", liveFootprint)
+	if s.archive != nil {
+		archiveFootprint, err := getComponentDiskFootprint("archive", s.archive, s.archiveStoreDirectory)
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild("archive", archiveFootprint)
+	}
+	return df, nil
+}
+
+// getComponentDiskFootprint obtains the disk footprint of the given component
+// of the state. If the component is backed by a key-value store owned by the
+// state, the size of the directory of the store is reported.
+func getComponentDiskFootprint(name string, component any, storeDirectory string) (*common.DiskFootprint, error) {
+	if storeDirectory != "" {
+		return common.GetDirectoryDiskFootprint(storeDirectory)
+	}
+	provider, ok := component.(common.DiskFootprintProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: disk footprint is not reported by the %s of this state", state.UnsupportedConfiguration, name)
+	}
+	return provider.GetDiskFootprint()
+}
+
+// codesDepotProvider is implemented by LiveDBs storing codes in a depot.
+type codesDepotProvider interface {
+	getCodesDepot() depot.Depot[uint32]
+}
+
+// GetAdaptiveCaches lists the adjustable node caches of the LiveDB and the
+// archive of this state, as well as the code cache of LiveDBs keeping codes
+// in a cached depot. The node caches are thread safe, the code cache is not.
+func (s *GoState) GetAdaptiveCaches() map[string]common.AdaptiveCache {
+	res := map[string]common.AdaptiveCache{}
+	if live, ok := s.live.(common.AdaptiveCache); ok {
+		res["live"] = live
+	}
+	if live, ok := s.live.(codesDepotProvider); ok {
+		if code, ok := live.getCodesDepot().(common.AdaptiveCache); ok {
+			res["code"] = code
+		}
+	}
+	if archive, ok := s.archive.(common.AdaptiveCache); ok {
+		res["archive"] = archive
+	}
+	return res
+}
+
+// getDiskFootprintOf aggregates the disk footprints of the given components.
+func getDiskFootprintOf(components map[string]common.DiskFootprintProvider) (*common.DiskFootprint, error) {
+	df := common.NewDiskFootprint(0)
+	for name, component := range components {
+		child, err := component.GetDiskFootprint()
+		if err != nil {
+			return nil, err
+		}
+		df.AddChild(name, child)
+	}
+	return df, nil
+}
+
 func (s *GoState) Flush() error {
 	if s.archiveWriter != nil {
 		// Signal to the archive worker that a flush should be conducted.
@@ -311,8 +446,13 @@ func (s *GoState) GetArchiveState(block uint64) (as state.State, err error) {
 	if block > lastBlock {
 		return nil, fmt.Errorf("block %d is not present in the archive (non-empty archive, last block %d)", block, lastBlock)
 	}
+	var view *historicView
+	if s.historicViews != nil {
+		view = s.historicViews.getView(s.archive, block)
+	}
 	return &ArchiveState{
 		archive: s.archive,
+		view:    view,
 		block:   block,
 	}, nil
 }
@@ -424,3 +564,329 @@ func (s *GoState) GetSnapshotVerifier(metadata []byte) (backend.SnapshotVerifier
 	}
 	return backend.NewComposedSnapshotVerifier(verifiers, partCounts), nil
 }
+
+// StartWitnessRecording starts recording a witness of all state information
+// accessed on the LiveDB of this state. Witnesses are only supported by MPT
+// based LiveDB implementations.
+func (s *GoState) StartWitnessRecording() (*mpt.WitnessRecorder, error) {
+	live, ok := s.live.(interface {
+		StartWitnessRecording() (*mpt.WitnessRecorder, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: witnesses are not supported by the LiveDB of this state", state.UnsupportedConfiguration)
+	}
+	return live.StartWitnessRecording()
+}
+
+// historyProvider is implemented by archives supporting the lookup of the
+// modification history of accounts and storage slots.
+type historyProvider interface {
+	GetAccountHistory(account common.Address, from, to uint64) ([]mpt.AccountChange, error)
+	GetSlotHistory(account common.Address, slot common.Key, from, to uint64) ([]mpt.SlotChange, error)
+}
+
+func (s *GoState) getHistoryProvider() (historyProvider, error) {
+	if s.archive == nil {
+		return nil, state.NoArchiveError
+	}
+	if err := s.stateError; err != nil {
+		return nil, err
+	}
+	provider, ok := s.archive.(historyProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: history queries are only supported for S5 archives", state.UnsupportedConfiguration)
+	}
+	return provider, nil
+}
+
+// GetAccountHistory lists the changes of the given account in the block
+// range [from, to] of the archive. See mpt.ArchiveTrie.GetAccountHistory.
+func (s *GoState) GetAccountHistory(account common.Address, from, to uint64) ([]mpt.AccountChange, error) {
+	provider, err := s.getHistoryProvider()
+	if err != nil {
+		return nil, err
+	}
+	return provider.GetAccountHistory(account, from, to)
+}
+
+// GetSlotHistory lists the changes of the given storage slot in the block
+// range [from, to] of the archive. See mpt.ArchiveTrie.GetSlotHistory.
+func (s *GoState) GetSlotHistory(account common.Address, slot common.Key, from, to uint64) ([]mpt.SlotChange, error) {
+	provider, err := s.getHistoryProvider()
+	if err != nil {
+		return nil, err
+	}
+	return provider.GetSlotHistory(account, slot, from, to)
+}
+
+// stateStatsProvider is implemented by archives maintaining per-block
+// statistics of the size of the state.
+type stateStatsProvider interface {
+	GetStateStats(from, to uint64) ([]mpt.StateStats, error)
+}
+
+// GetStateStats provides the statistics of the state of the blocks in the
+// range [from, to] of the archive. See mpt.ArchiveTrie.GetStateStats.
+func (s *GoState) GetStateStats(from, to uint64) ([]mpt.StateStats, error) {
+	if s.archive == nil {
+		return nil, state.NoArchiveError
+	}
+	if err := s.stateError; err != nil {
+		return nil, err
+	}
+	provider, ok := s.archive.(stateStatsProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: state statistics are only supported for S5 archives", state.UnsupportedConfiguration)
+	}
+	return provider.GetStateStats(from, to)
+}
+
+// headBlockTracker is implemented by LiveDBs tracking the last applied block.
+type headBlockTracker interface {
+	SetNextBlockRecord(record []byte)
+	GetHeadBlock() (block mpt.HeadBlock, found bool)
+}
+
+func (s *GoState) getHeadBlockTracker() (headBlockTracker, error) {
+	if err := s.stateError; err != nil {
+		return nil, err
+	}
+	tracker, ok := s.live.(headBlockTracker)
+	if !ok {
+		return nil, fmt.Errorf("%w: head block tracking is not supported by the LiveDB of this state", state.UnsupportedConfiguration)
+	}
+	return tracker, nil
+}
+
+// SetNextBlockRecord registers an opaque record to be persisted with the head
+// block information of the next block applied to this state. Head blocks are
+// only tracked by MPT based LiveDB implementations.
+func (s *GoState) SetNextBlockRecord(record []byte) error {
+	tracker, err := s.getHeadBlockTracker()
+	if err != nil {
+		return err
+	}
+	tracker.SetNextBlockRecord(record)
+	return nil
+}
+
+// GetHeadBlock returns the last block applied to the LiveDB of this state.
+// The found flag is false if no block was applied yet.
+func (s *GoState) GetHeadBlock() (block mpt.HeadBlock, found bool, err error) {
+	tracker, err := s.getHeadBlockTracker()
+	if err != nil {
+		return mpt.HeadBlock{}, false, err
+	}
+	block, found = tracker.GetHeadBlock()
+	return block, found, nil
+}
+
+// SetUpdateListener registers a listener to be informed about each update
+// successfully applied to the LiveDB of this state. The listener is called
+// synchronously within Apply and must not modify the update, which is not
+// modified by the state either once it got applied.
+// Only a single listener is supported, and it must be registered before the
+// first update is applied.
+func (s *GoState) SetUpdateListener(listener func(block uint64, update *common.Update)) {
+	s.updateListener = listener
+}
+
+// archiveUpdateProvider is implemented by archives able to reconstruct the
+// update applied by a block.
+type archiveUpdateProvider interface {
+	GetUpdateForBlock(block uint64) (common.Update, error)
+}
+
+// GetArchiveUpdate reconstructs the update of the given block from the
+// archive. See mpt.ArchiveTrie.GetUpdateForBlock. The archive is accessed
+// directly, which makes this method safe to be called concurrently to Apply.
+func (s *GoState) GetArchiveUpdate(block uint64) (common.Update, error) {
+	if s.archive == nil {
+		return common.Update{}, state.NoArchiveError
+	}
+	provider, ok := s.archive.(archiveUpdateProvider)
+	if !ok {
+		return common.Update{}, fmt.Errorf("%w: update reconstruction is only supported for S5 archives", state.UnsupportedConfiguration)
+	}
+	return provider.GetUpdateForBlock(block)
+}
+
+// WaitForArchiveBlock blocks until the archive has ingested the given block,
+// the archive writer failed, or the given context is done. Since updates are
+// added to the archive asynchronously, the archive may lag behind the LiveDB.
+// This method may be called concurrently to other operations on the state.
+func (s *GoState) WaitForArchiveBlock(ctx context.Context, block uint64) error {
+	if s.archiveProgress == nil {
+		return state.NoArchiveError
+	}
+	return s.archiveProgress.waitFor(ctx, block, s.archive.GetBlockHeight)
+}
+
+// GetArchiveQueueDepth returns the number of updates buffered for the archive
+// writer and the capacity of the buffer. If the buffer is full, Apply blocks
+// until the archive has caught up.
+func (s *GoState) GetArchiveQueueDepth() (depth int, capacity int, err error) {
+	if s.archiveQueue == nil {
+		return 0, 0, state.NoArchiveError
+	}
+	return len(s.archiveQueue), cap(s.archiveQueue), nil
+}
+
+// dirtyNodeCounter is implemented by LiveDBs and archives able to report the
+// number of their modified nodes not yet written to disk.
+type dirtyNodeCounter interface {
+	CountDirtyNodes() int
+}
+
+// CountDirtyNodes returns the number of nodes of the LiveDB and the archive
+// that have been modified since they were last written to disk. This method
+// may be called concurrently to other operations on the state.
+func (s *GoState) CountDirtyNodes() (int, error) {
+	live, ok := s.live.(dirtyNodeCounter)
+	if !ok {
+		return 0, fmt.Errorf("%w: dirty nodes are not counted by the LiveDB of this state", state.UnsupportedConfiguration)
+	}
+	count := live.CountDirtyNodes()
+	if archive, ok := s.archive.(dirtyNodeCounter); ok {
+		count += archive.CountDirtyNodes()
+	}
+	return count, nil
+}
+
+// nodeScrubber is implemented by LiveDBs and archives able to check their
+// nodes for corruption.
+type nodeScrubber interface {
+	PrepareScrub() (mpt.PreparedScrub, error)
+}
+
+// PrepareScrub writes the modified nodes of the LiveDB and of the archive of
+// this state to disk and collects the nodes to be checked by the returned
+// scrub function. See mpt.MptState.PrepareScrub and
+// mpt.ArchiveTrie.PrepareScrub. Archives not supporting scrubbing are skipped.
+// This method must not be called concurrently to updates of the state, while
+// the returned function may be run concurrently to other operations on the
+// state.
+func (s *GoState) PrepareScrub() (func(context.Context, mpt.ScrubConfig) (live, archive mpt.ScrubReport, err error), error) {
+	liveScrubber, ok := s.live.(nodeScrubber)
+	if !ok {
+		return nil, fmt.Errorf("%w: scrubbing is not supported by the LiveDB of this state", state.UnsupportedConfiguration)
+	}
+	liveScrub, err := liveScrubber.PrepareScrub()
+	if err != nil {
+		return nil, err
+	}
+	var archiveScrub *mpt.PreparedScrub
+	if archiveScrubber, ok := s.archive.(nodeScrubber); ok {
+		prepared, err := archiveScrubber.PrepareScrub()
+		if err != nil {
+			return nil, err
+		}
+		archiveScrub = &prepared
+	}
+	return func(ctx context.Context, config mpt.ScrubConfig) (live, archive mpt.ScrubReport, err error) {
+		if live, err = liveScrub.Run(ctx, config, nil); err != nil {
+			return live, archive, err
+		}
+		if archiveScrub != nil {
+			archive, err = archiveScrub.Run(ctx, config, nil)
+		}
+		return live, archive, err
+	}, nil
+}
+
+// backgroundVerifier is implemented by archives able to verify themselves
+// incrementally while blocks are added.
+type backgroundVerifier interface {
+	RunBackgroundVerification(ctx context.Context, config mpt.BackgroundVerificationConfig, observer mpt.VerificationObserver) error
+}
+
+// RunBackgroundVerification incrementally verifies the archive of this state
+// until the given context is done or a verification fails. See
+// mpt.ArchiveTrie.RunBackgroundVerification. This method may be called
+// concurrently to other operations on the state.
+func (s *GoState) RunBackgroundVerification(ctx context.Context, config mpt.BackgroundVerificationConfig, observer mpt.VerificationObserver) error {
+	if s.archive == nil {
+		return state.NoArchiveError
+	}
+	verifier, ok := s.archive.(backgroundVerifier)
+	if !ok {
+		return fmt.Errorf("%w: background verification is only supported for S5 archives", state.UnsupportedConfiguration)
+	}
+	return verifier.RunBackgroundVerification(ctx, config, observer)
+}
+
+// archiveProgress tracks the blocks ingested by the archive writer, enabling
+// callers to wait for the archive to reach some block.
+type archiveProgress struct {
+	mutex   sync.Mutex
+	height  int64         // < the last block ingested by the writer, -1 if there is none
+	err     error         // < the reason for the writer to not make progress anymore
+	updated chan struct{} // < closed and replaced whenever the fields above change
+}
+
+func newArchiveProgress() *archiveProgress {
+	return &archiveProgress{
+		height:  -1,
+		updated: make(chan struct{}),
+	}
+}
+
+func (p *archiveProgress) ingested(block uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.height = int64(block)
+	p.notify()
+}
+
+func (p *archiveProgress) fail(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err == nil {
+		p.err = fmt.Errorf("archive writer failed: %w", err)
+	}
+	p.notify()
+}
+
+func (p *archiveProgress) stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err == nil {
+		p.err = fmt.Errorf("archive writer is shut down")
+	}
+	p.notify()
+}
+
+// notify wakes up all waiting callers, the mutex must be held.
+func (p *archiveProgress) notify() {
+	close(p.updated)
+	p.updated = make(chan struct{})
+}
+
+// waitFor blocks until the given block got ingested. Blocks ingested before
+// the writer was started are covered by consulting the given archive height.
+func (p *archiveProgress) waitFor(ctx context.Context, block uint64, getArchiveHeight func() (uint64, bool, error)) error {
+	for {
+		p.mutex.Lock()
+		height, err, updated := p.height, p.err, p.updated
+		p.mutex.Unlock()
+		if height >= 0 && uint64(height) >= block {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// The archive may contain the block from an earlier session.
+		archived, empty, err := getArchiveHeight()
+		if err != nil {
+			return err
+		}
+		if !empty && archived >= block {
+			return nil
+		}
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
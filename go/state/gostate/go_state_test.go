@@ -12,16 +12,19 @@ package gostate
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Fantom-foundation/Carmen/go/backend/archive"
 	"github.com/Fantom-foundation/Carmen/go/backend/index"
 	"github.com/Fantom-foundation/Carmen/go/backend/store"
 	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
 	"github.com/Fantom-foundation/Carmen/go/state"
 	"go.uber.org/mock/gomock"
 )
@@ -331,7 +334,7 @@ func TestRecreatingAccountsPreservesEverythingButTheStorage(t *testing.T) {
 }
 
 func TestHashing(t *testing.T) {
-	var hashes = [][]common.Hash{nil, nil, nil, nil, nil, nil}
+	var hashes = [][]common.Hash{nil, nil, nil, nil, nil, nil, nil}
 	for _, config := range initGoStates() {
 		t.Run(config.name(), func(t *testing.T) {
 			state, err := config.createState(t.TempDir())
@@ -503,7 +506,7 @@ func TestGoState_FlushFlushesLiveDbAndArchive(t *testing.T) {
 	live.EXPECT().Flush()
 	archive.EXPECT().Flush()
 
-	state := newGoState(live, archive, nil)
+	state := newGoState(live, archive, nil, state.Parameters{})
 	state.Flush()
 }
 
@@ -521,7 +524,7 @@ func TestGoState_CloseClosesLiveDbAndArchive(t *testing.T) {
 		archive.EXPECT().Close(),
 	)
 
-	state := newGoState(live, archive, nil)
+	state := newGoState(live, archive, nil, state.Parameters{})
 	state.Close()
 }
 
@@ -540,7 +543,7 @@ func TestStateDB_AddBlock_Errors_Propagated_MultipleStateInstances(t *testing.T)
 	// state is already corrupted.
 	liveDB.EXPECT().Apply(gomock.Any(), gomock.Any()).Return(nil, injectedErr)
 
-	db := newGoState(liveDB, nil, []func(){})
+	db := newGoState(liveDB, nil, []func(){}, state.Parameters{})
 
 	stateA := state.CreateStateDBUsing(db)
 	runAddBlock(0, stateA)
@@ -574,7 +577,7 @@ func TestStateDB_AddBlock_Errors_Propagated_From_Archive_MultipleStateInstances(
 	// state is already corrupted.
 	archiveDB.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).Return(injectedErr)
 
-	db := newGoState(liveDB, archiveDB, []func(){})
+	db := newGoState(liveDB, archiveDB, []func(){}, state.Parameters{})
 	flush := func() {
 		state.UnsafeUnwrapSyncedState(db).(*GoState).archiveWriter <- archiveUpdate{}
 		<-state.UnsafeUnwrapSyncedState(db).(*GoState).archiveWriterFlushDone
@@ -607,7 +610,7 @@ func TestStateDB_AddBlock_CannotCallRepeatedly_OnError(t *testing.T) {
 	// will be called only once as repeated calls will not get triggered.
 	liveDB.EXPECT().Apply(gomock.Any(), gomock.Any()).Return(nil, injectedErr)
 
-	db := newGoState(liveDB, nil, []func(){})
+	db := newGoState(liveDB, nil, []func(){}, state.Parameters{})
 
 	stateDB := state.CreateStateDBUsing(db)
 	for i := 0; i < 10; i++ {
@@ -630,7 +633,7 @@ func TestState_Flush_Or_Close_Corrupted_State_Detected(t *testing.T) {
 	// will be called only once as repeated calls will not get triggered.
 	liveDB.EXPECT().Apply(gomock.Any(), gomock.Any()).Return(nil, injectedErr)
 
-	db := newGoState(liveDB, nil, []func(){})
+	db := newGoState(liveDB, nil, []func(){}, state.Parameters{})
 
 	update := common.Update{
 		CreatedAccounts: []common.Address{{0xA}},
@@ -665,7 +668,7 @@ func TestState_Flush_Or_Close_Corrupted_Archive_Detected(t *testing.T) {
 	archiveDB.EXPECT().Flush().AnyTimes()
 	archiveDB.EXPECT().Close().Return(injectedErr).AnyTimes()
 
-	db := newGoState(liveDB, archiveDB, []func(){})
+	db := newGoState(liveDB, archiveDB, []func(){}, state.Parameters{})
 
 	// the same result many times
 	for i := 0; i < 10; i++ {
@@ -692,7 +695,7 @@ func TestState_Apply_CannotCallRepeatedly_OnError(t *testing.T) {
 	// will be called only once as repeated calls will not get triggered.
 	liveDB.EXPECT().Apply(gomock.Any(), gomock.Any()).Return(nil, injectedErr)
 
-	db := newGoState(liveDB, nil, []func(){})
+	db := newGoState(liveDB, nil, []func(){}, state.Parameters{})
 
 	for i := 0; i < 10; i++ {
 		update := common.Update{
@@ -735,7 +738,7 @@ func TestState_All_Live_Operations_May_Cause_Failure(t *testing.T) {
 			liveDB.EXPECT().Flush().Return(results[8]).AnyTimes()
 			liveDB.EXPECT().Close().Return(results[9]).AnyTimes()
 
-			db := newGoState(liveDB, nil, []func(){})
+			db := newGoState(liveDB, nil, []func(){}, state.Parameters{})
 			// calls must succeed until the first failure,
 			// repeated calls must all fail
 			var shouldFail bool
@@ -796,7 +799,7 @@ func TestState_All_Archive_Operations_May_Cause_Failure(t *testing.T) {
 	archiveDB.EXPECT().GetBlockHeight().Return(uint64(0), false, injectedErr).Times(2)
 	archiveDB.EXPECT().Flush().AnyTimes()
 
-	db := newGoState(liveDB, archiveDB, []func(){})
+	db := newGoState(liveDB, archiveDB, []func(){}, state.Parameters{})
 	// repeated calls must all fail
 	for i := 0; i < 2; i++ {
 		if _, err := db.GetArchiveState(0); !errors.Is(err, injectedErr) {
@@ -807,7 +810,7 @@ func TestState_All_Archive_Operations_May_Cause_Failure(t *testing.T) {
 		}
 	}
 	// swap calls
-	db = newGoState(liveDB, archiveDB, []func(){})
+	db = newGoState(liveDB, archiveDB, []func(){}, state.Parameters{})
 	for i := 0; i < 2; i++ {
 		if _, _, err := db.GetArchiveBlockHeight(); !errors.Is(err, injectedErr) {
 			t.Errorf("calling archive should fail")
@@ -835,3 +838,304 @@ func runAddBlock(block uint64, stateDB state.StateDB) {
 	stateDB.EndTransaction()
 	stateDB.EndBlock(block)
 }
+
+func TestGoState_WaitForArchiveBlock_WaitsForArchiveWriter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+	archive := archive.NewMockArchive(ctrl)
+
+	release := make(chan struct{})
+	live.EXPECT().Apply(uint64(1), gomock.Any())
+	archive.EXPECT().GetBlockHeight().Return(uint64(0), true, nil).AnyTimes()
+	archive.EXPECT().Add(uint64(1), gomock.Any(), gomock.Any()).DoAndReturn(func(uint64, common.Update, any) error {
+		<-release
+		return nil
+	})
+	live.EXPECT().Flush().AnyTimes()
+	live.EXPECT().Close()
+	archive.EXPECT().Flush().AnyTimes()
+	archive.EXPECT().Close()
+
+	db := newGoState(live, archive, nil, state.Parameters{})
+	defer db.Close()
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+
+	if err := db.Apply(1, common.Update{}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := goState.WaitForArchiveBlock(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error, wanted %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := goState.WaitForArchiveBlock(context.Background(), 1); err != nil {
+		t.Errorf("failed to wait for archive: %v", err)
+	}
+}
+
+func TestGoState_WaitForArchiveBlock_BlocksArchivedBeforeAreCovered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+	archive := archive.NewMockArchive(ctrl)
+	archive.EXPECT().GetBlockHeight().Return(uint64(5), false, nil)
+	live.EXPECT().Flush()
+	live.EXPECT().Close()
+	archive.EXPECT().Flush()
+	archive.EXPECT().Close()
+
+	db := newGoState(live, archive, nil, state.Parameters{})
+	defer db.Close()
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if err := goState.WaitForArchiveBlock(context.Background(), 3); err != nil {
+		t.Errorf("failed to wait for archive: %v", err)
+	}
+}
+
+func TestGoState_WaitForArchiveBlock_ArchiveErrorsAreReported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+	archive := archive.NewMockArchive(ctrl)
+
+	injectedErr := errors.New("injected error")
+	live.EXPECT().Apply(uint64(1), gomock.Any())
+	archive.EXPECT().GetBlockHeight().Return(uint64(0), true, nil).AnyTimes()
+	archive.EXPECT().Add(uint64(1), gomock.Any(), gomock.Any()).Return(injectedErr)
+	live.EXPECT().Flush()
+	archive.EXPECT().Flush()
+
+	db := newGoState(live, archive, nil, state.Parameters{})
+	defer db.Close()
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if err := db.Apply(1, common.Update{}); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if err := goState.WaitForArchiveBlock(context.Background(), 1); !errors.Is(err, injectedErr) {
+		t.Errorf("unexpected error, wanted %v, got %v", injectedErr, err)
+	}
+}
+
+func TestGoState_WaitForArchiveBlock_RequiresArchive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+
+	db := newGoState(live, nil, nil, state.Parameters{})
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if err := goState.WaitForArchiveBlock(context.Background(), 0); !errors.Is(err, state.NoArchiveError) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.NoArchiveError, err)
+	}
+	if _, _, err := goState.GetArchiveQueueDepth(); !errors.Is(err, state.NoArchiveError) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.NoArchiveError, err)
+	}
+}
+
+func TestGoState_CountDirtyNodes_RequiresSupportOfLiveDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+
+	db := newGoState(live, nil, nil, state.Parameters{})
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if _, err := goState.CountDirtyNodes(); !errors.Is(err, state.UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.UnsupportedConfiguration, err)
+	}
+}
+
+func TestGoState_PrepareScrub_RequiresSupportOfLiveDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+
+	db := newGoState(live, nil, nil, state.Parameters{})
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if _, err := goState.PrepareScrub(); !errors.Is(err, state.UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.UnsupportedConfiguration, err)
+	}
+}
+
+func TestGoState_RunBackgroundVerification_RequiresArchive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+
+	db := newGoState(live, nil, nil, state.Parameters{})
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if err := goState.RunBackgroundVerification(context.Background(), mpt.BackgroundVerificationConfig{}, nil); !errors.Is(err, state.NoArchiveError) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.NoArchiveError, err)
+	}
+}
+
+func TestGoState_GetDiskFootprint_RequiresSupportOfLiveDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+
+	db := newGoState(live, nil, nil, state.Parameters{})
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+	if _, err := goState.GetDiskFootprint(); !errors.Is(err, state.UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", state.UnsupportedConfiguration, err)
+	}
+}
+
+func TestGoState_GetDiskFootprint_ReportsFilesOfLiveDB(t *testing.T) {
+	for _, config := range initGoStates() {
+		t.Run(config.name(), func(t *testing.T) {
+			db, err := config.createState(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to initialize state %s; %s", config.name(), err)
+			}
+			defer db.Close()
+
+			update := common.Update{
+				CreatedAccounts: []common.Address{address1},
+				Balances:        []common.BalanceUpdate{{Account: address1, Balance: balance1}},
+				Slots:           []common.SlotUpdate{{Account: address1, Key: key1, Value: val1}},
+			}
+			if err := db.Apply(1, update); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			if err := db.Flush(); err != nil {
+				t.Fatalf("failed to flush state: %v", err)
+			}
+
+			footprint, err := db.GetDiskFootprint()
+			if err != nil {
+				t.Fatalf("failed to get disk footprint: %v", err)
+			}
+			live := footprint.GetChild("live")
+			if live == nil {
+				t.Fatalf("missing LiveDB in disk footprint:\n%v", footprint)
+			}
+			if config.config.Variant != VariantGoMemory && live.Total() == 0 {
+				t.Errorf("no disk usage reported for persistent LiveDB:\n%v", footprint)
+			}
+			if config.config.Archive != state.NoArchive {
+				archive := footprint.GetChild("archive")
+				if archive == nil {
+					t.Fatalf("missing archive in disk footprint:\n%v", footprint)
+				}
+				if archive.Total() == 0 {
+					t.Errorf("no disk usage reported for archive:\n%v", footprint)
+				}
+			}
+		})
+	}
+}
+
+func TestGoState_GetArchiveQueueDepth_ReportsQueuedUpdates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+	archive := archive.NewMockArchive(ctrl)
+
+	release := make(chan struct{})
+	live.EXPECT().Apply(gomock.Any(), gomock.Any()).Times(3)
+	archive.EXPECT().Add(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(uint64, common.Update, any) error {
+		<-release
+		return nil
+	}).Times(3)
+	live.EXPECT().Flush()
+	live.EXPECT().Close()
+	archive.EXPECT().Flush()
+	archive.EXPECT().Close()
+
+	db := newGoState(live, archive, nil, state.Parameters{ArchiveQueueSize: 4})
+	defer db.Close()
+	goState := state.UnsafeUnwrapSyncedState(db).(*GoState)
+
+	for i := 0; i < 3; i++ {
+		if err := db.Apply(uint64(i), common.Update{}); err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+	}
+	// The first update is taken by the writer, the others are queued.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		depth, capacity, err := goState.GetArchiveQueueDepth()
+		if err != nil {
+			t.Fatalf("failed to get queue depth: %v", err)
+		}
+		if capacity != 4 {
+			t.Errorf("unexpected queue capacity, wanted 4, got %d", capacity)
+		}
+		if depth == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected queue depth, wanted 2, got %d", depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+}
+
+func TestGoState_GetAdaptiveCaches_ListsNodeCachesOfSchema5(t *testing.T) {
+	for _, config := range initGoStates() {
+		if config.config.Schema != 5 {
+			continue
+		}
+		t.Run(config.name(), func(t *testing.T) {
+			db, err := config.createState(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to initialize state %s; %s", config.name(), err)
+			}
+			defer db.Close()
+
+			caches := db.(state.AdaptiveCacheProvider).GetAdaptiveCaches()
+			names := []string{"live"}
+			if config.config.Archive == state.S5Archive {
+				names = append(names, "archive")
+			}
+			if len(caches) != len(names) {
+				t.Errorf("unexpected caches, wanted %v, got %v", names, caches)
+			}
+			for _, name := range names {
+				cache, found := caches[name]
+				if !found {
+					t.Fatalf("missing cache %s", name)
+				}
+				stats := cache.GetCacheStatistics()
+				if stats.MinCapacity != mpt.MinMptStateCapacity || stats.Capacity < stats.MinCapacity || stats.EntrySize == 0 {
+					t.Errorf("unexpected statistics of %s cache: %+v", name, stats)
+				}
+				cache.SetCacheCapacity(stats.MinCapacity)
+				if got := cache.GetCacheStatistics().Capacity; got != stats.MinCapacity {
+					t.Errorf("unexpected capacity of %s cache, wanted %d, got %d", name, stats.MinCapacity, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGoState_GetAdaptiveCaches_ListsCodeCachesOfCachedDepots(t *testing.T) {
+	for _, config := range initGoStates() {
+		if config.config.Schema > 3 {
+			continue
+		}
+		t.Run(config.name(), func(t *testing.T) {
+			db, err := config.createState(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to initialize state %s; %s", config.name(), err)
+			}
+			defer db.Close()
+
+			variant := config.config.Variant
+			cached := variant == VariantGoFile || variant == VariantGoLevelDb || variant == VariantGoPebble
+			cache, found := db.(state.AdaptiveCacheProvider).GetAdaptiveCaches()["code"]
+			if found != cached {
+				t.Fatalf("unexpected listing of code cache, wanted %t, got %t", cached, found)
+			}
+			if !found {
+				return
+			}
+			if err := db.Apply(1, common.Update{Codes: []common.CodeUpdate{{Account: address1, Code: []byte{1, 2, 3}}}}); err != nil {
+				t.Fatalf("failed to apply update: %v", err)
+			}
+			stats := cache.GetCacheStatistics()
+			if stats.Size != 1 || stats.EntrySize == 0 {
+				t.Errorf("unexpected statistics of code cache: %+v", stats)
+			}
+			cache.SetCacheCapacity(stats.MinCapacity)
+			if got := cache.GetCacheStatistics().Capacity; got != stats.MinCapacity {
+				t.Errorf("unexpected capacity of code cache, wanted %d, got %d", stats.MinCapacity, got)
+			}
+		})
+	}
+}
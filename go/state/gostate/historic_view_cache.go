@@ -0,0 +1,234 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package gostate
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+)
+
+// historicViewAccountCapacity is the number of entries retained by each of
+// the account-level caches of a historic view.
+const historicViewAccountCapacity = 1 << 10
+
+// historicViewSlotCapacity is the number of storage slots retained by a
+// historic view.
+const historicViewSlotCapacity = 1 << 12
+
+// archiveReader is the subset of the archive interface used by archive
+// states for looking up accounts and storage slots of historic blocks.
+type archiveReader interface {
+	Exists(block uint64, account common.Address) (exists bool, err error)
+	GetBalance(block uint64, account common.Address) (balance common.Balance, err error)
+	GetCode(block uint64, account common.Address) (code []byte, err error)
+	GetNonce(block uint64, account common.Address) (nonce common.Nonce, err error)
+	GetStorage(block uint64, account common.Address, slot common.Key) (value common.Value, err error)
+}
+
+// historicViewCache is a size-bounded cache of views on historic blocks
+// shared among all archive states of a GoState. Each view caches the account
+// and storage lookups of its block, such that queries targeting the same
+// block do not need to resolve the same paths in the archive repeatedly.
+// Blocks in the archive are immutable, thus cached lookups remain valid
+// until the view is evicted. Only the pruning of the archive, which is not
+// supported yet, would require views to be invalidated. This type is thread
+// safe.
+type historicViewCache struct {
+	mutex sync.Mutex
+	views *common.LruCache[uint64, *historicView]
+
+	viewHits     atomic.Uint64
+	viewMisses   atomic.Uint64
+	lookupHits   atomic.Uint64
+	lookupMisses atomic.Uint64
+}
+
+// newHistoricViewCache creates a cache retaining the views of up to the
+// given number of blocks.
+func newHistoricViewCache(capacity int) *historicViewCache {
+	return &historicViewCache{
+		views: common.NewLruCache[uint64, *historicView](capacity),
+	}
+}
+
+// getView obtains the view on the given block of the given archive. If the
+// view is not present, it is created, potentially evicting the view of the
+// least recently used block.
+func (c *historicViewCache) getView(source archiveReader, block uint64) *historicView {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if view, found := c.views.Get(block); found {
+		c.viewHits.Add(1)
+		return view
+	}
+	c.viewMisses.Add(1)
+	view := newHistoricView(c, source, block)
+	c.views.Set(block, view)
+	return view
+}
+
+// GetMemoryFootprint provides the size of the cached views in memory and
+// reports the hit rates of the cache in its note.
+func (c *historicViewCache) GetMemoryFootprint() *common.MemoryFootprint {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	mf := common.NewMemoryFootprint(unsafe.Sizeof(*c))
+	mf.AddChild("index", c.views.GetMemoryFootprint(0))
+	size := uintptr(0)
+	c.views.Iterate(func(_ uint64, view *historicView) bool {
+		size += view.GetMemoryFootprint().Total()
+		return true
+	})
+	mf.AddChild("views", common.NewMemoryFootprint(size))
+
+	viewHits, viewMisses := c.viewHits.Load(), c.viewMisses.Load()
+	lookupHits, lookupMisses := c.lookupHits.Load(), c.lookupMisses.Load()
+	mf.SetNote(fmt.Sprintf("(historic views, view hits %d, misses %d, hit ratio %f, lookup hits %d, misses %d, hit ratio %f)",
+		viewHits, viewMisses, getHitRatio(viewHits, viewMisses),
+		lookupHits, lookupMisses, getHitRatio(lookupHits, lookupMisses),
+	))
+	return mf
+}
+
+// getHitRatio computes the fraction of hits among all lookups, which is
+// reported as 0 if there have been no lookups yet.
+func getHitRatio(hits, misses uint64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// historicView caches the account and storage lookups of a single block of
+// an archive. Lookups for other blocks are forwarded to the archive. This
+// type is thread safe.
+type historicView struct {
+	owner  *historicViewCache
+	source archiveReader
+	block  uint64
+
+	mutex    sync.Mutex
+	exists   *common.LruCache[common.Address, bool]
+	balances *common.LruCache[common.Address, common.Balance]
+	nonces   *common.LruCache[common.Address, common.Nonce]
+	codes    *common.LruCache[common.Address, []byte]
+	slots    *common.LruCache[slotId, common.Value]
+}
+
+// slotId identifies a storage slot of an account.
+type slotId struct {
+	address common.Address
+	key     common.Key
+}
+
+func newHistoricView(owner *historicViewCache, source archiveReader, block uint64) *historicView {
+	return &historicView{
+		owner:    owner,
+		source:   source,
+		block:    block,
+		exists:   common.NewLruCache[common.Address, bool](historicViewAccountCapacity),
+		balances: common.NewLruCache[common.Address, common.Balance](historicViewAccountCapacity),
+		nonces:   common.NewLruCache[common.Address, common.Nonce](historicViewAccountCapacity),
+		codes:    common.NewLruCache[common.Address, []byte](historicViewAccountCapacity),
+		slots:    common.NewLruCache[slotId, common.Value](historicViewSlotCapacity),
+	}
+}
+
+func (v *historicView) Exists(block uint64, account common.Address) (bool, error) {
+	if block != v.block {
+		return v.source.Exists(block, account)
+	}
+	return lookupInView(v, v.exists, account, func() (bool, error) {
+		return v.source.Exists(block, account)
+	})
+}
+
+func (v *historicView) GetBalance(block uint64, account common.Address) (common.Balance, error) {
+	if block != v.block {
+		return v.source.GetBalance(block, account)
+	}
+	return lookupInView(v, v.balances, account, func() (common.Balance, error) {
+		return v.source.GetBalance(block, account)
+	})
+}
+
+func (v *historicView) GetCode(block uint64, account common.Address) ([]byte, error) {
+	if block != v.block {
+		return v.source.GetCode(block, account)
+	}
+	code, err := lookupInView(v, v.codes, account, func() ([]byte, error) {
+		return v.source.GetCode(block, account)
+	})
+	// Cached codes are shared by all queries on the view, thus each caller
+	// obtains its own copy.
+	return bytes.Clone(code), err
+}
+
+func (v *historicView) GetNonce(block uint64, account common.Address) (common.Nonce, error) {
+	if block != v.block {
+		return v.source.GetNonce(block, account)
+	}
+	return lookupInView(v, v.nonces, account, func() (common.Nonce, error) {
+		return v.source.GetNonce(block, account)
+	})
+}
+
+func (v *historicView) GetStorage(block uint64, account common.Address, slot common.Key) (common.Value, error) {
+	if block != v.block {
+		return v.source.GetStorage(block, account, slot)
+	}
+	return lookupInView(v, v.slots, slotId{account, slot}, func() (common.Value, error) {
+		return v.source.GetStorage(block, account, slot)
+	})
+}
+
+// GetMemoryFootprint provides the size of the view in memory.
+func (v *historicView) GetMemoryFootprint() *common.MemoryFootprint {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	mf := common.NewMemoryFootprint(unsafe.Sizeof(*v))
+	mf.AddChild("exists", v.exists.GetMemoryFootprint(0))
+	mf.AddChild("balances", v.balances.GetMemoryFootprint(0))
+	mf.AddChild("nonces", v.nonces.GetMemoryFootprint(0))
+	mf.AddChild("codes", v.codes.GetDynamicMemoryFootprint(func(code []byte) uintptr {
+		return uintptr(cap(code))
+	}))
+	mf.AddChild("slots", v.slots.GetMemoryFootprint(0))
+	return mf
+}
+
+// lookupInView obtains the value of the given key from the given cache of a
+// view. If it is not present, it is loaded using the given function. Errors
+// are not cached. The archive is accessed without holding the lock of the
+// view, such that concurrent queries on the same block are not serialized.
+func lookupInView[K comparable, V any](view *historicView, cache *common.LruCache[K, V], key K, load func() (V, error)) (V, error) {
+	view.mutex.Lock()
+	value, found := cache.Get(key)
+	view.mutex.Unlock()
+	if found {
+		view.owner.lookupHits.Add(1)
+		return value, nil
+	}
+	view.owner.lookupMisses.Add(1)
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	view.mutex.Lock()
+	cache.Set(key, value)
+	view.mutex.Unlock()
+	return value, nil
+}
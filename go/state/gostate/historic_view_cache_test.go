@@ -0,0 +1,209 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package gostate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/backend/archive"
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHistoricViewCache_ViewsAreSharedPerBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	cache := newHistoricViewCache(2)
+
+	view1 := cache.getView(source, 1)
+	if want, got := view1, cache.getView(source, 1); want != got {
+		t.Errorf("views of the same block should be shared")
+	}
+	view2 := cache.getView(source, 2)
+	if view1 == view2 {
+		t.Errorf("views of different blocks should be different")
+	}
+
+	// Adding a third block evicts the least recently used view.
+	cache.getView(source, 3)
+	if got := cache.getView(source, 1); got == view1 {
+		t.Errorf("view of block 1 should have been evicted")
+	}
+	if hits, misses := cache.viewHits.Load(), cache.viewMisses.Load(); hits != 1 || misses != 4 {
+		t.Errorf("unexpected view hits and misses, wanted 1 and 4, got %d and %d", hits, misses)
+	}
+}
+
+func TestHistoricView_LookupsAreCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	address := common.Address{1}
+	key := common.Key{2}
+
+	source.EXPECT().Exists(uint64(5), address).Return(true, nil)
+	source.EXPECT().GetBalance(uint64(5), address).Return(common.Balance{31: 1}, nil)
+	source.EXPECT().GetNonce(uint64(5), address).Return(common.Nonce{7: 2}, nil)
+	source.EXPECT().GetCode(uint64(5), address).Return([]byte{3}, nil)
+	source.EXPECT().GetStorage(uint64(5), address, key).Return(common.Value{31: 4}, nil)
+
+	view := newHistoricViewCache(1).getView(source, 5)
+	for i := 0; i < 3; i++ {
+		if exists, err := view.Exists(5, address); err != nil || !exists {
+			t.Errorf("unexpected existence, got %t, err %v", exists, err)
+		}
+		if balance, err := view.GetBalance(5, address); err != nil || balance != (common.Balance{31: 1}) {
+			t.Errorf("unexpected balance, got %v, err %v", balance, err)
+		}
+		if nonce, err := view.GetNonce(5, address); err != nil || nonce != (common.Nonce{7: 2}) {
+			t.Errorf("unexpected nonce, got %v, err %v", nonce, err)
+		}
+		if code, err := view.GetCode(5, address); err != nil || len(code) != 1 || code[0] != 3 {
+			t.Errorf("unexpected code, got %v, err %v", code, err)
+		}
+		if value, err := view.GetStorage(5, address, key); err != nil || value != (common.Value{31: 4}) {
+			t.Errorf("unexpected value, got %v, err %v", value, err)
+		}
+	}
+	if hits, misses := view.owner.lookupHits.Load(), view.owner.lookupMisses.Load(); hits != 10 || misses != 5 {
+		t.Errorf("unexpected lookup hits and misses, wanted 10 and 5, got %d and %d", hits, misses)
+	}
+}
+
+func TestHistoricView_LookupsOfOtherBlocksAreForwarded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	address := common.Address{1}
+
+	source.EXPECT().GetBalance(uint64(4), address).Return(common.Balance{31: 1}, nil).Times(2)
+
+	view := newHistoricViewCache(1).getView(source, 5)
+	for i := 0; i < 2; i++ {
+		if _, err := view.GetBalance(4, address); err != nil {
+			t.Fatalf("failed to get balance: %v", err)
+		}
+	}
+}
+
+func TestHistoricView_ErrorsAreNotCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	address := common.Address{1}
+	injectedErr := fmt.Errorf("injected error")
+
+	gomock.InOrder(
+		source.EXPECT().GetNonce(uint64(5), address).Return(common.Nonce{}, injectedErr),
+		source.EXPECT().GetNonce(uint64(5), address).Return(common.Nonce{7: 1}, nil),
+	)
+
+	view := newHistoricViewCache(1).getView(source, 5)
+	if _, err := view.GetNonce(5, address); err != injectedErr {
+		t.Errorf("unexpected error, wanted %v, got %v", injectedErr, err)
+	}
+	if nonce, err := view.GetNonce(5, address); err != nil || nonce != (common.Nonce{7: 1}) {
+		t.Errorf("unexpected nonce, got %v, err %v", nonce, err)
+	}
+}
+
+func TestHistoricView_CodesAreCopiedForEachCaller(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	address := common.Address{1}
+
+	source.EXPECT().GetCode(uint64(5), address).Return([]byte{1, 2, 3}, nil)
+
+	view := newHistoricViewCache(1).getView(source, 5)
+	for i := 0; i < 2; i++ {
+		code, err := view.GetCode(5, address)
+		if err != nil || !bytes.Equal(code, []byte{1, 2, 3}) {
+			t.Fatalf("unexpected code, got %v, err %v", code, err)
+		}
+		// Modifications of the result must not affect the cached code.
+		code[0] = 42
+	}
+}
+
+func TestHistoricViewCache_HitRatiosOfUnusedCacheAreZero(t *testing.T) {
+	note := newHistoricViewCache(1).GetMemoryFootprint().String()
+	if strings.Contains(note, "NaN") {
+		t.Errorf("hit ratios of an unused cache should be reported as 0, got %s", note)
+	}
+	if want := "hit ratio 0.000000"; !strings.Contains(note, want) {
+		t.Errorf("report should contain %q, got %s", want, note)
+	}
+}
+
+func TestHistoricView_ConcurrentLookupsAreSafe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	source := archive.NewMockArchive(ctrl)
+	source.EXPECT().GetStorage(uint64(5), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ uint64, _ common.Address, key common.Key) (common.Value, error) {
+			return common.Value(key), nil
+		}).AnyTimes()
+
+	cache := newHistoricViewCache(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			view := cache.getView(source, 5)
+			for j := 0; j < 100; j++ {
+				key := common.Key{byte(j)}
+				if value, err := view.GetStorage(5, common.Address{}, key); err != nil || value != common.Value(key) {
+					t.Errorf("unexpected value, got %v, err %v", value, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGoState_ArchiveStatesShareHistoricViews(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	live := state.NewMockLiveDB(ctrl)
+	archive := archive.NewMockArchive(ctrl)
+	address := common.Address{1}
+
+	archive.EXPECT().GetBlockHeight().Return(uint64(10), false, nil).AnyTimes()
+	archive.EXPECT().GetBalance(uint64(5), address).Return(common.Balance{31: 1}, nil)
+	archive.EXPECT().GetMemoryFootprint().Return(common.NewMemoryFootprint(0))
+	live.EXPECT().GetMemoryFootprint().Return(common.NewMemoryFootprint(0))
+	live.EXPECT().Flush()
+	live.EXPECT().Close()
+	archive.EXPECT().Flush()
+	archive.EXPECT().Close()
+
+	db := newGoState(live, archive, nil, state.Parameters{HistoricViewCache: 4})
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		historic, err := db.GetArchiveState(5)
+		if err != nil {
+			t.Fatalf("failed to get archive state: %v", err)
+		}
+		if balance, err := historic.GetBalance(address); err != nil || balance != (common.Balance{31: 1}) {
+			t.Errorf("unexpected balance, got %v, err %v", balance, err)
+		}
+	}
+
+	footprint := db.GetMemoryFootprint().String()
+	if want := "view hits 2, misses 1"; !strings.Contains(footprint, want) {
+		t.Errorf("memory footprint should report %q, got %s", want, footprint)
+	}
+	if want := "lookup hits 2, misses 1"; !strings.Contains(footprint, want) {
+		t.Errorf("memory footprint should report %q, got %s", want, footprint)
+	}
+}
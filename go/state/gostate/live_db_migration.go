@@ -0,0 +1,209 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package gostate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+
+	mptIo "github.com/Fantom-foundation/Carmen/go/database/mpt/io"
+)
+
+// MigrateLiveDbToS5 copies all accounts, balances, nonces, codes, and storage
+// slots of the LiveDB of the given state into a fresh S5 LiveDB created in
+// the given directory, which must be an existing, empty directory. The new
+// LiveDB records the given block as its head block. Once written, the new
+// LiveDB is verified account by account against the source state.
+//
+// The general state.State interface offers no way to enumerate the content of
+// a state. Thus, the source has to be a Go state of schema 1-5 or a state
+// implementing the ContentExporter interface, like C++ states, which may be
+// wrapped in a synchronized state. All other sources are rejected with an
+// error wrapping state.UnsupportedConfiguration. The source is locked for the
+// duration of the migration and must not be modified by other means. The
+// resulting LiveDB is used by Go states of schema 5 and its hash is returned.
+// The content of the target directory is undefined if the migration fails.
+func MigrateLiveDbToS5(source state.State, directory string, block uint64) (hash common.Hash, err error) {
+	err = state.RunWithUnwrappedState(source, func(source state.State) error {
+		var content contentSource
+		switch s := source.(type) {
+		case *GoState:
+			content = s
+		case ContentExporter:
+			content = exportedContent{s}
+		default:
+			return fmt.Errorf("%w: migration is not supported for states of type %T", state.UnsupportedConfiguration, source)
+		}
+		hash, err = migrateLiveDbToS5(content, directory, block)
+		return err
+	})
+	return hash, err
+}
+
+// ContentExporter is implemented by states not managed by this package that
+// are able to enumerate the content of their LiveDB, in particular C++ states.
+type ContentExporter interface {
+	// VisitContent reports all existing accounts and their non-zero storage
+	// slots to the given callbacks. The order of the reported entries is
+	// undefined. The enumeration is aborted by the first error returned by a
+	// callback.
+	VisitContent(
+		visitAccount func(common.Address, common.Balance, common.Nonce, []byte) error,
+		visitSlot func(common.Address, common.Key, common.Value) error,
+	) error
+}
+
+// exportedContent adapts a ContentExporter to a contentSource.
+type exportedContent struct {
+	exporter ContentExporter
+}
+
+func (c exportedContent) visitContent(visitor contentVisitor) error {
+	return c.exporter.VisitContent(visitor.visitAccount, visitor.visitSlot)
+}
+
+func migrateLiveDbToS5(source contentSource, directory string, block uint64) (common.Hash, error) {
+	builder, err := mptIo.NewLiveDbBuilder(directory, block)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := source.visitContent(&builderVisitor{builder}); err != nil {
+		return common.Hash{}, errors.Join(fmt.Errorf("failed to copy content: %w", err), builder.Abort())
+	}
+	hash, err := builder.Finish()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to complete LiveDB: %w", err)
+	}
+
+	target, err := mpt.OpenGoFileState(directory, mpt.S5LiveConfig, mpt.DefaultMptStateCapacity)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to open migrated LiveDB: %w", err)
+	}
+	if err := verifyMigratedContent(source, target); err != nil {
+		return common.Hash{}, errors.Join(fmt.Errorf("verification of migrated LiveDB failed: %w", err), target.Close())
+	}
+	return hash, target.Close()
+}
+
+// builderVisitor writes all visited content into a LiveDB builder.
+type builderVisitor struct {
+	builder *mptIo.StateBuilder
+}
+
+func (v *builderVisitor) visitAccount(address common.Address, balance common.Balance, nonce common.Nonce, code []byte) error {
+	if err := v.builder.SetAccount(address, balance, nonce); err != nil {
+		return err
+	}
+	if len(code) == 0 {
+		return nil
+	}
+	return v.builder.SetCode(address, code)
+}
+
+func (v *builderVisitor) visitSlot(address common.Address, key common.Key, value common.Value) error {
+	return v.builder.SetStorage(address, key, value)
+}
+
+// verifyMigratedContent checks that the given target contains exactly the
+// content of the source. Every account and slot of the source is looked up
+// in the target, and the target is checked to contain no additional
+// accounts or slots by comparing the number of entries.
+func verifyMigratedContent(source contentSource, target *mpt.MptState) error {
+	want := verifyingVisitor{target: target}
+	if err := source.visitContent(&want); err != nil {
+		return err
+	}
+	got := countingVisitor{}
+	if err := visitMptContent(target, &got); err != nil {
+		return err
+	}
+	if want.accounts != got.accounts {
+		return fmt.Errorf("different number of accounts, source %d, target %d", want.accounts, got.accounts)
+	}
+	if want.slots != got.slots {
+		return fmt.Errorf("different number of storage slots, source %d, target %d", want.slots, got.slots)
+	}
+	return nil
+}
+
+// verifyingVisitor compares all visited content with the content of a
+// target state.
+type verifyingVisitor struct {
+	target   *mpt.MptState
+	accounts int
+	slots    int
+}
+
+func (v *verifyingVisitor) visitAccount(address common.Address, balance common.Balance, nonce common.Nonce, code []byte) error {
+	v.accounts++
+	exists, err := v.target.Exists(address)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("account %x does not exist in target", address)
+	}
+	got, err := v.target.GetBalance(address)
+	if err != nil {
+		return err
+	}
+	if got != balance {
+		return fmt.Errorf("different balance of account %x, source %x, target %x", address, balance, got)
+	}
+	gotNonce, err := v.target.GetNonce(address)
+	if err != nil {
+		return err
+	}
+	if gotNonce != nonce {
+		return fmt.Errorf("different nonce of account %x, source %x, target %x", address, nonce, gotNonce)
+	}
+	gotCode, err := v.target.GetCode(address)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(gotCode, code) {
+		return fmt.Errorf("different code of account %x, source %x, target %x", address, code, gotCode)
+	}
+	return nil
+}
+
+func (v *verifyingVisitor) visitSlot(address common.Address, key common.Key, value common.Value) error {
+	v.slots++
+	got, err := v.target.GetStorage(address, key)
+	if err != nil {
+		return err
+	}
+	if got != value {
+		return fmt.Errorf("different value of slot %x of account %x, source %x, target %x", key, address, value, got)
+	}
+	return nil
+}
+
+// countingVisitor counts the visited accounts and slots.
+type countingVisitor struct {
+	accounts int
+	slots    int
+}
+
+func (v *countingVisitor) visitAccount(common.Address, common.Balance, common.Nonce, []byte) error {
+	v.accounts++
+	return nil
+}
+
+func (v *countingVisitor) visitSlot(common.Address, common.Key, common.Value) error {
+	v.slots++
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2024 Fantom Foundation
+//
+// Use of this software is governed by the Business Source License included
+// in the LICENSE file and at fantom.foundation/bsl11.
+//
+// Change Date: 2028-4-16
+//
+// On the date above, in accordance with the Business Source License, use of
+// this software will be governed by the GNU Lesser General Public License v3.
+
+package gostate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Carmen/go/common"
+	"github.com/Fantom-foundation/Carmen/go/database/mpt"
+	"github.com/Fantom-foundation/Carmen/go/state"
+	"go.uber.org/mock/gomock"
+)
+
+// migrationUpdates covers all kinds of content of a LiveDB, including deleted
+// and re-created accounts whose outdated storage must not be migrated.
+var migrationUpdates = []common.Update{
+	{
+		CreatedAccounts: []common.Address{address1, address2, address3},
+		Balances:        []common.BalanceUpdate{{Account: address1, Balance: balance1}, {Account: address2, Balance: balance2}},
+		Nonces:          []common.NonceUpdate{{Account: address1, Nonce: nonce1}, {Account: address3, Nonce: nonce3}},
+		Codes:           []common.CodeUpdate{{Account: address2, Code: []byte{1, 2, 3}}},
+		Slots: []common.SlotUpdate{
+			{Account: address1, Key: key1, Value: val1},
+			{Account: address2, Key: key1, Value: val2},
+			{Account: address2, Key: key2, Value: val3},
+			{Account: address3, Key: key3, Value: val1},
+		},
+	},
+	{
+		DeletedAccounts: []common.Address{address3},
+		CreatedAccounts: []common.Address{address1},
+		Slots: []common.SlotUpdate{
+			{Account: address1, Key: key2, Value: val2},
+			{Account: address2, Key: key1, Value: val0},
+		},
+	},
+}
+
+func TestMigrateLiveDbToS5_ContentOfAllSchemasIsMigrated(t *testing.T) {
+	for _, config := range initGoStates() {
+		if config.config.Archive != state.NoArchive || config.config.Schema > 5 {
+			continue
+		}
+		t.Run(config.name(), func(t *testing.T) {
+			source, err := config.createState(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to initialize state %s; %s", config.name(), err)
+			}
+			defer source.Close()
+			for i, update := range migrationUpdates {
+				if err := source.Apply(uint64(i), update); err != nil {
+					t.Fatalf("failed to apply update: %v", err)
+				}
+			}
+
+			dir := t.TempDir()
+			hash, err := MigrateLiveDbToS5(source, dir, 12)
+			if err != nil {
+				t.Fatalf("failed to migrate LiveDB: %v", err)
+			}
+
+			target, err := mpt.OpenGoFileState(dir, mpt.S5LiveConfig, mpt.DefaultMptStateCapacity)
+			if err != nil {
+				t.Fatalf("failed to open migrated LiveDB: %v", err)
+			}
+			defer target.Close()
+
+			if got, err := target.GetHash(); err != nil || got != hash {
+				t.Errorf("unexpected hash of migrated LiveDB, wanted %x, got %x, err %v", hash, got, err)
+			}
+			if head, found := target.GetHeadBlock(); !found || head.Number != 12 {
+				t.Errorf("unexpected head block of migrated LiveDB, wanted 12, got %d, found %t", head.Number, found)
+			}
+			for _, address := range []common.Address{address1, address2, address3} {
+				want, err1 := source.Exists(address)
+				got, err2 := target.Exists(address)
+				if err1 != nil || err2 != nil || want != got {
+					t.Errorf("different existence of account %x, wanted %t, got %t, errors %v, %v", address, want, got, err1, err2)
+				}
+				if !want {
+					continue
+				}
+				wantBalance, err1 := source.GetBalance(address)
+				gotBalance, err2 := target.GetBalance(address)
+				if err1 != nil || err2 != nil || wantBalance != gotBalance {
+					t.Errorf("different balance of account %x, wanted %x, got %x, errors %v, %v", address, wantBalance, gotBalance, err1, err2)
+				}
+				wantNonce, err1 := source.GetNonce(address)
+				gotNonce, err2 := target.GetNonce(address)
+				if err1 != nil || err2 != nil || wantNonce != gotNonce {
+					t.Errorf("different nonce of account %x, wanted %x, got %x, errors %v, %v", address, wantNonce, gotNonce, err1, err2)
+				}
+				wantCode, err1 := source.GetCode(address)
+				gotCode, err2 := target.GetCode(address)
+				if err1 != nil || err2 != nil || !bytes.Equal(wantCode, gotCode) {
+					t.Errorf("different code of account %x, wanted %x, got %x, errors %v, %v", address, wantCode, gotCode, err1, err2)
+				}
+				for _, key := range []common.Key{key1, key2, key3} {
+					wantValue, err1 := source.GetStorage(address, key)
+					gotValue, err2 := target.GetStorage(address, key)
+					if err1 != nil || err2 != nil || wantValue != gotValue {
+						t.Errorf("different value of slot %x of account %x, wanted %x, got %x, errors %v, %v", key, address, wantValue, gotValue, err1, err2)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateLiveDbToS5_MigratedLiveDbCanBeUsedByS5State(t *testing.T) {
+	source, err := newGoMemoryState(state.Parameters{Schema: 3, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create source state: %v", err)
+	}
+	defer source.Close()
+	reference, err := newGoMemoryState(state.Parameters{Schema: 5, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create reference state: %v", err)
+	}
+	defer reference.Close()
+	for i, update := range migrationUpdates {
+		if err := errors.Join(source.Apply(uint64(i), update), reference.Apply(uint64(i), update)); err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	hash, err := MigrateLiveDbToS5(source, dir, 1)
+	if err != nil {
+		t.Fatalf("failed to migrate LiveDB: %v", err)
+	}
+	if want, err := reference.GetHash(); err != nil || want != hash {
+		t.Errorf("migrated LiveDB should have the hash of a S5 state with the same content, wanted %x, got %x, err %v", want, hash, err)
+	}
+}
+
+func TestMigrateLiveDbToS5_UnsupportedSourcesAreRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	other := state.NewMockState(ctrl)
+	if _, err := MigrateLiveDbToS5(other, t.TempDir(), 0); !errors.Is(err, state.UnsupportedConfiguration) {
+		t.Errorf("non-Go states should be rejected, got %v", err)
+	}
+	if _, err := MigrateLiveDbToS5(state.WrapIntoSyncedState(other), t.TempDir(), 0); !errors.Is(err, state.UnsupportedConfiguration) {
+		t.Errorf("synchronized non-Go states should be rejected, got %v", err)
+	}
+
+	live := state.NewMockLiveDB(ctrl)
+	live.EXPECT().Flush().AnyTimes()
+	live.EXPECT().Close().AnyTimes()
+	source := newGoState(live, nil, nil, state.Parameters{})
+	defer source.Close()
+	if _, err := MigrateLiveDbToS5(source, t.TempDir(), 0); !errors.Is(err, state.UnsupportedConfiguration) {
+		t.Errorf("LiveDBs not supporting the enumeration of their content should be rejected, got %v", err)
+	}
+}
+
+func TestMigrateLiveDbToS5_SynchronizedGoStatesAreSupported(t *testing.T) {
+	source, err := newGoMemoryState(state.Parameters{Schema: 2, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create source state: %v", err)
+	}
+	synced := state.WrapIntoSyncedState(source)
+	defer synced.Close()
+	if err := synced.Apply(1, migrationUpdates[0]); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+	if _, err := MigrateLiveDbToS5(synced, t.TempDir(), 1); err != nil {
+		t.Errorf("failed to migrate synchronized state: %v", err)
+	}
+}
+
+func TestMigrateLiveDbToS5_ContentExportersAreSupported(t *testing.T) {
+	source, err := newGoMemoryState(state.Parameters{Schema: 1, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create source state: %v", err)
+	}
+	defer source.Close()
+	reference, err := newGoMemoryState(state.Parameters{Schema: 5, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create reference state: %v", err)
+	}
+	defer reference.Close()
+	for i, update := range migrationUpdates {
+		if err := errors.Join(source.Apply(uint64(i), update), reference.Apply(uint64(i), update)); err != nil {
+			t.Fatalf("failed to apply update: %v", err)
+		}
+	}
+
+	exporter := &contentExporter{
+		State:  source,
+		source: state.UnsafeUnwrapSyncedState(source).(*GoState),
+	}
+	hash, err := MigrateLiveDbToS5(state.WrapIntoSyncedState(exporter), t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("failed to migrate content exporter: %v", err)
+	}
+	if want, err := reference.GetHash(); err != nil || want != hash {
+		t.Errorf("migrated LiveDB should have the hash of a S5 state with the same content, wanted %x, got %x, err %v", want, hash, err)
+	}
+}
+
+func TestMigrateLiveDbToS5_NonEmptyTargetDirectoryIsRejected(t *testing.T) {
+	source, err := newGoMemoryState(state.Parameters{Schema: 2, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create source state: %v", err)
+	}
+	defer source.Close()
+
+	dir := t.TempDir()
+	if _, err := MigrateLiveDbToS5(source, dir, 0); err != nil {
+		t.Fatalf("failed to migrate LiveDB: %v", err)
+	}
+	if _, err := MigrateLiveDbToS5(source, dir, 0); err == nil {
+		t.Errorf("migration into non-empty directory should fail")
+	}
+}
+
+func TestVerifyMigratedContent_DifferencesAreDetected(t *testing.T) {
+	source, err := newGoMemoryState(state.Parameters{Schema: 1, Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create source state: %v", err)
+	}
+	defer source.Close()
+	if err := source.Apply(0, migrationUpdates[0]); err != nil {
+		t.Fatalf("failed to apply update: %v", err)
+	}
+
+	tests := map[string]struct {
+		update common.Update
+		want   string
+	}{
+		"missing account":    {common.Update{DeletedAccounts: []common.Address{address3}}, "does not exist"},
+		"balance":            {common.Update{Balances: []common.BalanceUpdate{{Account: address1, Balance: balance3}}}, "different balance"},
+		"nonce":              {common.Update{Nonces: []common.NonceUpdate{{Account: address1, Nonce: nonce3}}}, "different nonce"},
+		"code":               {common.Update{Codes: []common.CodeUpdate{{Account: address2, Code: []byte{4}}}}, "different code"},
+		"slot":               {common.Update{Slots: []common.SlotUpdate{{Account: address1, Key: key1, Value: val3}}}, "different value"},
+		"additional slot":    {common.Update{Slots: []common.SlotUpdate{{Account: address1, Key: key3, Value: val3}}}, "number of storage slots"},
+		"additional account": {common.Update{CreatedAccounts: []common.Address{{0x42}}}, "number of accounts"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := mpt.OpenGoMemoryState(t.TempDir(), mpt.S5LiveConfig, mpt.DefaultMptStateCapacity)
+			if err != nil {
+				t.Fatalf("failed to create target: %v", err)
+			}
+			defer target.Close()
+			for i, update := range []common.Update{migrationUpdates[0], test.update} {
+				hints, err := target.Apply(uint64(i), update)
+				if err != nil {
+					t.Fatalf("failed to apply update: %v", err)
+				}
+				if hints != nil {
+					hints.Release()
+				}
+			}
+
+			goState := state.UnsafeUnwrapSyncedState(source).(*GoState)
+			err = verifyMigratedContent(goState, target)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("difference should be detected with %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+// contentExporter is a state enumerating its content through the
+// ContentExporter interface, like C++ states do.
+type contentExporter struct {
+	state.State
+	source contentSource
+}
+
+func (e *contentExporter) VisitContent(
+	visitAccount func(common.Address, common.Balance, common.Nonce, []byte) error,
+	visitSlot func(common.Address, common.Key, common.Value) error,
+) error {
+	return e.source.visitContent(&callbackVisitor{visitAccount, visitSlot})
+}
+
+// callbackVisitor forwards all visited content to the given callbacks.
+type callbackVisitor struct {
+	account func(common.Address, common.Balance, common.Nonce, []byte) error
+	slot    func(common.Address, common.Key, common.Value) error
+}
+
+func (v *callbackVisitor) visitAccount(address common.Address, balance common.Balance, nonce common.Nonce, code []byte) error {
+	return v.account(address, balance, nonce, code)
+}
+
+func (v *callbackVisitor) visitSlot(address common.Address, key common.Key, value common.Value) error {
+	return v.slot(address, key, value)
+}
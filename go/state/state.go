@@ -59,6 +59,10 @@ type State interface {
 	// GetMemoryFootprint computes an approximation of the memory used by this state.
 	GetMemoryFootprint() *common.MemoryFootprint
 
+	// GetDiskFootprint reports the disk space used by this state, including
+	// the space occupied by deleted data that may be reclaimed.
+	GetDiskFootprint() (*common.DiskFootprint, error)
+
 	// GetArchiveState provides a historical State view for given block.
 	// An error is returned if the archive is not enabled or if it is empty.
 	GetArchiveState(block uint64) (State, error)
@@ -80,6 +84,32 @@ type State interface {
 	backend.Snapshotable
 }
 
+// HashPreviewer is an optional extension of the State interface implemented by
+// states capable of computing the hash of the state resulting from applying an
+// update without modifying the state.
+type HashPreviewer interface {
+	// GetHashAfter computes the hash the state would have after applying the
+	// given update. The state itself is not modified.
+	GetHashAfter(update common.Update) (common.Hash, error)
+}
+
+// AdaptiveCacheProvider is an optional extension of the State and StateDB
+// interfaces implemented by instances maintaining caches whose capacity may be
+// adjusted at runtime.
+type AdaptiveCacheProvider interface {
+	// GetAdaptiveCaches lists the adjustable caches of this instance by name.
+	// Unless stated otherwise, the caches must only be used while the
+	// instance is not used by other operations.
+	GetAdaptiveCaches() map[string]common.AdaptiveCache
+}
+
+// DirtyNodeCounter is an optional extension of the State interface
+// implemented by states able to report the number of their modified nodes
+// not yet written to disk.
+type DirtyNodeCounter interface {
+	CountDirtyNodes() (int, error)
+}
+
 type LiveDB interface {
 	Exists(address common.Address) (bool, error)
 	GetBalance(address common.Address) (balance common.Balance, err error)
@@ -35,6 +35,7 @@ type VmStateDB interface {
 	Empty(common.Address) bool
 
 	Suicide(common.Address) bool
+	SuicideNewContract(common.Address) bool
 	HasSuicided(common.Address) bool
 
 	// Balance
@@ -138,6 +139,12 @@ type StateDB interface {
 	// GetMemoryFootprint computes an approximation of the memory used by this state.
 	GetMemoryFootprint() *common.MemoryFootprint
 
+	// GetPendingHash computes the hash of the state resulting from committing
+	// the changes of the current block without committing them. The changes
+	// remain part of the current block. Computing pending hashes is only
+	// supported by states implementing the HashPreviewer interface.
+	GetPendingHash() (common.Hash, error)
+
 	ResetBlockContext()
 }
 
@@ -235,6 +242,9 @@ type stateDB struct {
 	// A list of addresses, which have possibly become empty in the transaction
 	emptyCandidates []common.Address
 
+	// A list of addresses of accounts created in the current transaction.
+	createdAccounts []common.Address
+
 	// True, if this state DB is allowed to apply changes to the underlying state, false otherwise.
 	canApplyChanges bool
 
@@ -266,6 +276,10 @@ type accountLifeCycleState int
 //
 // Accounts with the state Suicided can only exist during a transaction. At the end of a
 // transaction, Suicided accounts transition automatically into NonExisting accounts.
+//
+// Additionally, accounts entering the Exists state through CreateAccount are flagged as
+// created in the current transaction until the end of the transaction. Following EIP-6780,
+// only those accounts may transition to Suicided through SuicideNewContract.
 
 const (
 	accountNonExisting    accountLifeCycleState = 1
@@ -291,6 +305,8 @@ type accountState struct {
 	original accountLifeCycleState
 	// The current account state visible to the state DB users.
 	current accountLifeCycleState
+	// True if the account was created in the current transaction.
+	createdInTransaction bool
 }
 
 type accountClearingState int
@@ -451,6 +467,7 @@ func createStateDBWith(state State, storedDataCacheCapacity int, canApplyChanges
 		undo:              make([]func(), 0, 100),
 		clearedAccounts:   make(map[common.Address]accountClearingState),
 		emptyCandidates:   make([]common.Address, 0, 100),
+		createdAccounts:   make([]common.Address, 0, 100),
 		canApplyChanges:   canApplyChanges,
 	}
 }
@@ -495,6 +512,7 @@ func (s *stateDB) CreateAccount(addr common.Address) {
 
 	exists := s.Exist(addr)
 	s.setAccountState(addr, accountExists)
+	s.markCreatedInTransaction(addr)
 
 	// Created because touched - will be deleted at the end of the transaction if it stays empty
 	s.emptyCandidates = append(s.emptyCandidates, addr)
@@ -534,6 +552,23 @@ func (s *stateDB) CreateAccount(addr common.Address) {
 	})
 }
 
+// markCreatedInTransaction flags the given account as created in the current
+// transaction, which is reverted by snapshot reverts and reset at the end of
+// the transaction.
+func (s *stateDB) markCreatedInTransaction(addr common.Address) {
+	val, exists := s.accounts[addr]
+	if !exists || val.createdInTransaction {
+		return
+	}
+	val.createdInTransaction = true
+	createdListLength := len(s.createdAccounts)
+	s.createdAccounts = append(s.createdAccounts, addr)
+	s.undo = append(s.undo, func() {
+		val.createdInTransaction = false
+		s.createdAccounts = s.createdAccounts[0:createdListLength]
+	})
+}
+
 func (s *stateDB) createAccountIfNotExists(addr common.Address) bool {
 	if s.Exist(addr) {
 		return false
@@ -580,6 +615,18 @@ func (s *stateDB) Suicide(addr common.Address) bool {
 	return true
 }
 
+// SuicideNewContract implements the self-destruct semantics introduced by
+// EIP-6780. The given account is only marked as suicided, as done by Suicide,
+// if it was created in the current transaction. Otherwise, the account and its
+// balance remain unmodified; transferring the balance to the beneficiary is up
+// to the caller. The result is true if the account was marked as suicided.
+func (s *stateDB) SuicideNewContract(addr common.Address) bool {
+	if val, exists := s.accounts[addr]; !exists || !val.createdInTransaction {
+		return false
+	}
+	return s.Suicide(addr)
+}
+
 func (s *stateDB) HasSuicided(addr common.Address) bool {
 	state := s.accounts[addr]
 	return state != nil && state.current == accountSelfDestructed
@@ -1149,18 +1196,58 @@ func (s *stateDB) EndBlock(block uint64) {
 		return
 	}
 
+	update, err := s.getBlockUpdate()
+	if err != nil {
+		s.errors = append(s.errors, err)
+	}
+
+	// Increment the reincarnation counter of cleared addresses to invalidate
+	// cached entries in the stored data cache.
+	for addr, clearingState := range s.clearedAccounts {
+		if clearingState == cleared || clearingState == clearedAndTainted {
+			s.reincarnation[addr] = s.reincarnation[addr] + 1
+		}
+	}
+
+	// Update the stored data cache with the values written by this block.
+	s.data.ForEach(func(slot slotId, value *slotValue) {
+		if !value.storedKnown || value.stored != value.current {
+			s.storedDataCache.Set(slot, storedDataCacheValue{value.current, s.reincarnation[slot.addr]})
+		}
+	})
+
+	// Skip applying changes if there have been any issues.
+	if err := s.Check(); err != nil {
+		return
+	}
+
+	// Send the update to the state.
+	if err := s.state.Apply(block, update); err != nil {
+		s.errors = append(s.errors, fmt.Errorf("failed to apply update for block %d: %w", block, err))
+		return
+	}
+
+	// Reset internal state for next block
+	s.ResetBlockContext()
+}
+
+// getBlockUpdate derives the update describing the changes of the current
+// block without modifying the block context.
+func (s *stateDB) getBlockUpdate() (common.Update, error) {
 	update := common.Update{}
+	var errs []error
 
 	// Clear all accounts that have been deleted at some point during this block.
 	// This will cause all storage slots of that accounts to be reset before new
 	// values may be written in the subsequent updates.
 	nonExistingAccounts := map[common.Address]bool{}
+	deletedAccounts := map[common.Address]bool{}
 	for addr, clearingState := range s.clearedAccounts {
 		if clearingState == cleared || clearingState == clearedAndTainted {
 			if s.accounts[addr].original == accountExists {
-				// Pretend this account was originally deleted, such that in the loop below
+				// Treat this account as originally deleted, such that in the loop below
 				// it would be detected as re-created in case its new state is Existing.
-				s.accounts[addr].original = accountNonExisting
+				deletedAccounts[addr] = true
 				// If the account was not later re-created, we mark it for deletion.
 				if s.accounts[addr].current != accountExists {
 					update.AppendDeleteAccount(addr)
@@ -1168,15 +1255,16 @@ func (s *stateDB) EndBlock(block uint64) {
 			} else {
 				nonExistingAccounts[addr] = true
 			}
-			// Increment the reincarnation counter of cleared addresses to invalidate
-			// cached entries in the stored data cache.
-			s.reincarnation[addr] = s.reincarnation[addr] + 1
 		}
 	}
 
 	// (Re-)create new or resurrected accounts.
 	for addr, value := range s.accounts {
-		if value.original != value.current {
+		original := value.original
+		if deletedAccounts[addr] {
+			original = accountNonExisting
+		}
+		if original != value.current {
 			if value.current == accountExists {
 				update.AppendCreateAccount(addr)
 				delete(nonExistingAccounts, addr)
@@ -1192,7 +1280,7 @@ func (s *stateDB) EndBlock(block uint64) {
 		if value.original == nil || value.original.Cmp(&value.current) != 0 {
 			newBalance, err := common.ToBalance(&value.current)
 			if err != nil {
-				s.errors = append(s.errors, fmt.Errorf("unable to convert big.Int balance %v to common.Balance: %w", &value.current, err))
+				errs = append(errs, fmt.Errorf("unable to convert big.Int balance %v to common.Balance: %w", &value.current, err))
 			} else {
 				update.AppendBalanceUpdate(addr, newBalance)
 			}
@@ -1213,7 +1301,6 @@ func (s *stateDB) EndBlock(block uint64) {
 	s.data.ForEach(func(slot slotId, value *slotValue) {
 		if !value.storedKnown || value.stored != value.current {
 			update.AppendSlotUpdate(slot.addr, slot.key, value.current)
-			s.storedDataCache.Set(slot, storedDataCacheValue{value.current, s.reincarnation[slot.addr]})
 		}
 	})
 
@@ -1227,19 +1314,7 @@ func (s *stateDB) EndBlock(block uint64) {
 		}
 	}
 
-	// Skip applying changes if there have been any issues.
-	if err := s.Check(); err != nil {
-		return
-	}
-
-	// Send the update to the state.
-	if err := s.state.Apply(block, update); err != nil {
-		s.errors = append(s.errors, fmt.Errorf("failed to apply update for block %d: %w", block, err))
-		return
-	}
-
-	// Reset internal state for next block
-	s.ResetBlockContext()
+	return update, errors.Join(errs...)
 }
 
 func (s *stateDB) BeginEpoch() {
@@ -1259,6 +1334,21 @@ func (s *stateDB) GetHash() common.Hash {
 	return hash
 }
 
+func (s *stateDB) GetPendingHash() (common.Hash, error) {
+	if err := s.Check(); err != nil {
+		return common.Hash{}, err
+	}
+	update, err := s.getBlockUpdate()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	previewer, ok := s.state.(HashPreviewer)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("%w: state does not support hash previews", UnsupportedConfiguration)
+	}
+	return previewer.GetHashAfter(update)
+}
+
 func (s *stateDB) Check() error {
 	return errors.Join(
 		errors.Join(s.errors...),
@@ -1321,6 +1411,14 @@ func (s *stateDB) GetMemoryFootprint() *common.MemoryFootprint {
 	return mf
 }
 
+// GetAdaptiveCaches lists the stored-data cache of this StateDB, whose
+// capacity may be adjusted in between blocks.
+func (s *stateDB) GetAdaptiveCaches() map[string]common.AdaptiveCache {
+	return map[string]common.AdaptiveCache{
+		"storedData": common.NewAdaptiveLruCache(s.storedDataCache, nil),
+	}
+}
+
 func (s *stateDB) GetArchiveStateDB(block uint64) (NonCommittableStateDB, error) {
 	archiveState, err := s.state.GetArchiveState(block)
 	if err != nil {
@@ -1339,6 +1437,12 @@ func (s *stateDB) resetTransactionContext() {
 	s.transientStorage.Clear()
 	s.undo = s.undo[0:0]
 	s.emptyCandidates = s.emptyCandidates[0:0]
+	for _, addr := range s.createdAccounts {
+		if val, exists := s.accounts[addr]; exists {
+			val.createdInTransaction = false
+		}
+	}
+	s.createdAccounts = s.createdAccounts[0:0]
 	s.logs = s.logs[0:0]
 }
 
@@ -1353,6 +1457,22 @@ func (s *stateDB) ResetBlockContext() {
 	s.resetTransactionContext()
 }
 
+// Release drops the content of the caches of this StateDB without closing
+// the underlying state, which may still be used by other StateDB instances.
+// After the release, no more operations may be conducted on this instance.
+func (s *stateDB) Release() {
+	s.ResetBlockContext()
+	s.ResetCaches()
+}
+
+// ResetCaches drops the values of previous blocks cached by this StateDB,
+// such that they are read from the underlying state again. Unlike Release,
+// the StateDB remains usable and retains the capacity of its caches.
+func (s *stateDB) ResetCaches() {
+	s.storedDataCache.Clear()
+	s.reincarnation = map[common.Address]uint64{}
+}
+
 func (s *stateDB) resetState(state State) {
 	s.ResetBlockContext()
 	s.storedDataCache.Clear()
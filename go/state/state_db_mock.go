@@ -15,6 +15,7 @@
 //
 //	mockgen -source state_db.go -destination state_db_mock.go -package state
 //
+
 // Package state is a generated GoMock package.
 package state
 
@@ -534,6 +535,20 @@ func (mr *MockVmStateDBMockRecorder) Suicide(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suicide", reflect.TypeOf((*MockVmStateDB)(nil).Suicide), arg0)
 }
 
+// SuicideNewContract mocks base method.
+func (m *MockVmStateDB) SuicideNewContract(arg0 common.Address) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuicideNewContract", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SuicideNewContract indicates an expected call of SuicideNewContract.
+func (mr *MockVmStateDBMockRecorder) SuicideNewContract(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuicideNewContract", reflect.TypeOf((*MockVmStateDB)(nil).SuicideNewContract), arg0)
+}
+
 // MockStateDB is a mock of StateDB interface.
 type MockStateDB struct {
 	ctrl     *gomock.Controller
@@ -952,6 +967,21 @@ func (mr *MockStateDBMockRecorder) GetNonce(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNonce", reflect.TypeOf((*MockStateDB)(nil).GetNonce), arg0)
 }
 
+// GetPendingHash mocks base method.
+func (m *MockStateDB) GetPendingHash() (common.Hash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPendingHash")
+	ret0, _ := ret[0].(common.Hash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPendingHash indicates an expected call of GetPendingHash.
+func (mr *MockStateDBMockRecorder) GetPendingHash() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPendingHash", reflect.TypeOf((*MockStateDB)(nil).GetPendingHash))
+}
+
 // GetRefund mocks base method.
 func (m *MockStateDB) GetRefund() uint64 {
 	m.ctrl.T.Helper()
@@ -1189,6 +1219,20 @@ func (mr *MockStateDBMockRecorder) Suicide(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suicide", reflect.TypeOf((*MockStateDB)(nil).Suicide), arg0)
 }
 
+// SuicideNewContract mocks base method.
+func (m *MockStateDB) SuicideNewContract(arg0 common.Address) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuicideNewContract", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SuicideNewContract indicates an expected call of SuicideNewContract.
+func (mr *MockStateDBMockRecorder) SuicideNewContract(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuicideNewContract", reflect.TypeOf((*MockStateDB)(nil).SuicideNewContract), arg0)
+}
+
 // MockNonCommittableStateDB is a mock of NonCommittableStateDB interface.
 type MockNonCommittableStateDB struct {
 	ctrl     *gomock.Controller
@@ -1723,6 +1767,20 @@ func (mr *MockNonCommittableStateDBMockRecorder) Suicide(arg0 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suicide", reflect.TypeOf((*MockNonCommittableStateDB)(nil).Suicide), arg0)
 }
 
+// SuicideNewContract mocks base method.
+func (m *MockNonCommittableStateDB) SuicideNewContract(arg0 common.Address) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuicideNewContract", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SuicideNewContract indicates an expected call of SuicideNewContract.
+func (mr *MockNonCommittableStateDBMockRecorder) SuicideNewContract(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuicideNewContract", reflect.TypeOf((*MockNonCommittableStateDB)(nil).SuicideNewContract), arg0)
+}
+
 // MockBulkLoad is a mock of BulkLoad interface.
 type MockBulkLoad struct {
 	ctrl     *gomock.Controller
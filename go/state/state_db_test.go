@@ -1059,6 +1059,106 @@ func TestStateDB_SuicideIsExecutedAtEndOfTransaction(t *testing.T) {
 	db.EndBlock(1)
 }
 
+func TestStateDB_SuicideNewContract_ExistingAccountIsRetained(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateStateDBUsing(mock)
+
+	// The balance of the account is not touched, and no update is produced.
+	mock.EXPECT().Check().AnyTimes()
+	mock.EXPECT().Exists(address1).Return(true, nil)
+	mock.EXPECT().GetBalance(address1).Return(common.Balance{31: 12}, nil)
+	mock.EXPECT().Apply(uint64(1), common.Update{})
+
+	if db.SuicideNewContract(address1) {
+		t.Errorf("account not created in the transaction should not be destructed")
+	}
+	if db.HasSuicided(address1) {
+		t.Errorf("account should not be marked as suicided")
+	}
+	if got := db.GetBalance(address1); got.Cmp(big.NewInt(12)) != 0 {
+		t.Errorf("balance should be retained, wanted 12, got %v", got)
+	}
+
+	db.EndTransaction()
+	if !db.Exist(address1) {
+		t.Errorf("account should still exist")
+	}
+	db.EndBlock(1)
+}
+
+func TestStateDB_SuicideNewContract_AccountCreatedInTransactionIsDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateStateDBUsing(mock)
+
+	// The account created and destructed in the same transaction never exists.
+	mock.EXPECT().Check().AnyTimes()
+	mock.EXPECT().Exists(address1).Return(false, nil)
+	mock.EXPECT().Apply(uint64(1), common.Update{})
+
+	db.CreateAccount(address1)
+	db.AddBalance(address1, big.NewInt(12))
+	if !db.SuicideNewContract(address1) {
+		t.Errorf("account created in the transaction should be destructed")
+	}
+	if !db.HasSuicided(address1) {
+		t.Errorf("account should be marked as suicided")
+	}
+	if got := db.GetBalance(address1); got.Sign() != 0 {
+		t.Errorf("balance should be cleared, got %v", got)
+	}
+
+	db.EndTransaction()
+	if db.Exist(address1) {
+		t.Errorf("account should be deleted")
+	}
+	db.EndBlock(1)
+}
+
+func TestStateDB_SuicideNewContract_AccountCreatedInPreviousTransactionIsRetained(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateStateDBUsing(mock)
+
+	mock.EXPECT().Check().AnyTimes()
+	mock.EXPECT().Exists(address1).Return(false, nil)
+	mock.EXPECT().Apply(uint64(1), common.Update{
+		CreatedAccounts: []common.Address{address1},
+		Balances:        []common.BalanceUpdate{{Account: address1, Balance: common.Balance{31: 12}}},
+		Nonces:          []common.NonceUpdate{{Account: address1, Nonce: common.ToNonce(1)}},
+		Codes:           []common.CodeUpdate{{Account: address1, Code: []byte{}}},
+	})
+
+	db.CreateAccount(address1)
+	db.AddBalance(address1, big.NewInt(12))
+	db.SetNonce(address1, 1)
+	db.EndTransaction()
+
+	db.BeginTransaction()
+	if db.SuicideNewContract(address1) {
+		t.Errorf("account created in a previous transaction should not be destructed")
+	}
+	db.EndTransaction()
+	db.EndBlock(1)
+}
+
+func TestStateDB_SuicideNewContract_CreationIsRevertedByRollback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateStateDBUsing(mock)
+
+	mock.EXPECT().Exists(address1).Return(true, nil)
+
+	snapshot := db.Snapshot()
+	db.CreateAccount(address1)
+	db.RevertToSnapshot(snapshot)
+
+	if db.SuicideNewContract(address1) {
+		t.Errorf("reverted creation should not enable the destruction of the account")
+	}
+}
+
 func TestStateDB_SuicideCanBeCanceledThroughRollback(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mock := NewMockState(ctrl)
@@ -4407,3 +4507,99 @@ func (m sameEffectAs) Matches(x any) bool {
 func (m sameEffectAs) String() string {
 	return fmt.Sprintf("Same effect as %v", m.want)
 }
+
+func TestStateDB_GetPendingHashComputesHashOfBlockChangesWithoutApplyingThem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	previewer := NewMockHashPreviewer(ctrl)
+	db := CreateStateDBUsing(struct {
+		*MockState
+		*MockHashPreviewer
+	}{mock, previewer})
+
+	mock.EXPECT().Exists(address1).Return(false, nil)
+	mock.EXPECT().Check().AnyTimes()
+
+	update := common.Update{
+		CreatedAccounts: []common.Address{address1},
+		Balances:        []common.BalanceUpdate{{Account: address1, Balance: common.Balance{31: 12}}},
+		Nonces:          []common.NonceUpdate{{Account: address1}},
+		Codes:           []common.CodeUpdate{{Account: address1, Code: []byte{}}},
+	}
+	hash := common.Hash{1, 2, 3}
+	previewer.EXPECT().GetHashAfter(update).Return(hash, nil)
+
+	db.BeginBlock()
+	db.BeginTransaction()
+	db.CreateAccount(address1)
+	db.AddBalance(address1, big.NewInt(12))
+	db.EndTransaction()
+
+	if got, err := db.GetPendingHash(); err != nil || got != hash {
+		t.Errorf("unexpected pending hash, wanted %x, got %x, err %v", hash, got, err)
+	}
+
+	// The changes are still part of the block.
+	mock.EXPECT().Apply(uint64(1), update)
+	db.EndBlock(1)
+}
+
+func TestStateDB_GetPendingHashFailsForStatesNotSupportingPreviews(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateStateDBUsing(mock)
+
+	mock.EXPECT().Check().AnyTimes()
+	if _, err := db.GetPendingHash(); !errors.Is(err, UnsupportedConfiguration) {
+		t.Errorf("unexpected error, wanted %v, got %v", UnsupportedConfiguration, err)
+	}
+}
+
+func TestStateDB_GetAdaptiveCaches_ListsStoredDataCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateCustomStateDBUsing(mock, 10)
+
+	mock.EXPECT().GetStorage(address1, key1).Return(val1, nil)
+	db.GetState(address1, key1)
+	db.GetState(address1, key1)
+
+	caches := db.(AdaptiveCacheProvider).GetAdaptiveCaches()
+	cache, found := caches["storedData"]
+	if !found || len(caches) != 1 {
+		t.Fatalf("unexpected caches: %v", caches)
+	}
+	stats := cache.GetCacheStatistics()
+	if stats.Capacity != 10 || stats.Size != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected statistics: %+v", stats)
+	}
+
+	cache.SetCacheCapacity(5)
+	if got := cache.GetCacheStatistics().Capacity; got != 5 {
+		t.Errorf("unexpected capacity, wanted 5, got %d", got)
+	}
+}
+
+func TestStateDB_ResetCachesReadsValuesFromStateAgain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewMockState(ctrl)
+	db := CreateCustomStateDBUsing(mock, 10)
+
+	// The value is read once for the initial access and once after the
+	// reset, while the access in between is served by the cache.
+	mock.EXPECT().GetStorage(address1, key1).Return(val1, nil).Times(2)
+	db.GetState(address1, key1)
+	db.ResetBlockContext()
+	db.GetState(address1, key1)
+
+	db.ResetBlockContext()
+	db.(*stateDB).ResetCaches()
+	if got := db.GetState(address1, key1); got != val1 {
+		t.Errorf("unexpected value, wanted %v, got %v", val1, got)
+	}
+
+	stats := db.(AdaptiveCacheProvider).GetAdaptiveCaches()["storedData"].GetCacheStatistics()
+	if stats.Capacity != 10 {
+		t.Errorf("capacity of cache should be retained, wanted 10, got %d", stats.Capacity)
+	}
+}
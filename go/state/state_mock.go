@@ -227,6 +227,21 @@ func (mr *MockStateMockRecorder) GetCodeSize(address any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCodeSize", reflect.TypeOf((*MockState)(nil).GetCodeSize), address)
 }
 
+// GetDiskFootprint mocks base method.
+func (m *MockState) GetDiskFootprint() (*common.DiskFootprint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDiskFootprint")
+	ret0, _ := ret[0].(*common.DiskFootprint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDiskFootprint indicates an expected call of GetDiskFootprint.
+func (mr *MockStateMockRecorder) GetDiskFootprint() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskFootprint", reflect.TypeOf((*MockState)(nil).GetDiskFootprint))
+}
+
 // GetHash mocks base method.
 func (m *MockState) GetHash() (common.Hash, error) {
 	m.ctrl.T.Helper()
@@ -330,6 +345,81 @@ func (mr *MockStateMockRecorder) Restore(data any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockState)(nil).Restore), data)
 }
 
+// MockHashPreviewer is a mock of HashPreviewer interface.
+type MockHashPreviewer struct {
+	ctrl     *gomock.Controller
+	recorder *MockHashPreviewerMockRecorder
+}
+
+// MockHashPreviewerMockRecorder is the mock recorder for MockHashPreviewer.
+type MockHashPreviewerMockRecorder struct {
+	mock *MockHashPreviewer
+}
+
+// NewMockHashPreviewer creates a new mock instance.
+func NewMockHashPreviewer(ctrl *gomock.Controller) *MockHashPreviewer {
+	mock := &MockHashPreviewer{ctrl: ctrl}
+	mock.recorder = &MockHashPreviewerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHashPreviewer) EXPECT() *MockHashPreviewerMockRecorder {
+	return m.recorder
+}
+
+// GetHashAfter mocks base method.
+func (m *MockHashPreviewer) GetHashAfter(update common.Update) (common.Hash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHashAfter", update)
+	ret0, _ := ret[0].(common.Hash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHashAfter indicates an expected call of GetHashAfter.
+func (mr *MockHashPreviewerMockRecorder) GetHashAfter(update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHashAfter", reflect.TypeOf((*MockHashPreviewer)(nil).GetHashAfter), update)
+}
+
+// MockAdaptiveCacheProvider is a mock of AdaptiveCacheProvider interface.
+type MockAdaptiveCacheProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdaptiveCacheProviderMockRecorder
+}
+
+// MockAdaptiveCacheProviderMockRecorder is the mock recorder for MockAdaptiveCacheProvider.
+type MockAdaptiveCacheProviderMockRecorder struct {
+	mock *MockAdaptiveCacheProvider
+}
+
+// NewMockAdaptiveCacheProvider creates a new mock instance.
+func NewMockAdaptiveCacheProvider(ctrl *gomock.Controller) *MockAdaptiveCacheProvider {
+	mock := &MockAdaptiveCacheProvider{ctrl: ctrl}
+	mock.recorder = &MockAdaptiveCacheProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdaptiveCacheProvider) EXPECT() *MockAdaptiveCacheProviderMockRecorder {
+	return m.recorder
+}
+
+// GetAdaptiveCaches mocks base method.
+func (m *MockAdaptiveCacheProvider) GetAdaptiveCaches() map[string]common.AdaptiveCache {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdaptiveCaches")
+	ret0, _ := ret[0].(map[string]common.AdaptiveCache)
+	return ret0
+}
+
+// GetAdaptiveCaches indicates an expected call of GetAdaptiveCaches.
+func (mr *MockAdaptiveCacheProviderMockRecorder) GetAdaptiveCaches() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdaptiveCaches", reflect.TypeOf((*MockAdaptiveCacheProvider)(nil).GetAdaptiveCaches))
+}
+
 // MockLiveDB is a mock of LiveDB interface.
 type MockLiveDB struct {
 	ctrl     *gomock.Controller
@@ -11,6 +11,7 @@
 package state
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/Fantom-foundation/Carmen/go/backend"
@@ -48,6 +49,20 @@ func UnsafeUnwrapSyncedState(state State) State {
 	return state
 }
 
+// RunWithUnwrappedState runs the given operation on the state nested in a
+// potentially synchronized state. Unlike UnsafeUnwrapSyncedState, the lock of
+// the synchronized state is held while the operation is running, such that the
+// operation is mutual exclusive with all other operations on the given state.
+// The nested state must not be retained beyond the operation.
+func RunWithUnwrappedState(state State, op func(State) error) error {
+	if syncedState, ok := state.(*syncedState); ok {
+		syncedState.mu.Lock()
+		defer syncedState.mu.Unlock()
+		return op(syncedState.state)
+	}
+	return op(state)
+}
+
 func (s *syncedState) Exists(address common.Address) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -102,6 +117,44 @@ func (s *syncedState) GetHash() (common.Hash, error) {
 	return s.state.GetHash()
 }
 
+func (s *syncedState) GetHashAfter(update common.Update) (common.Hash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previewer, ok := s.state.(HashPreviewer)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("%w: state does not support hash previews", UnsupportedConfiguration)
+	}
+	return previewer.GetHashAfter(update)
+}
+
+// GetAdaptiveCaches lists the adjustable caches of the wrapped state. The
+// resulting caches are synchronized with the operations on this state.
+func (s *syncedState) GetAdaptiveCaches() map[string]common.AdaptiveCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	provider, ok := s.state.(AdaptiveCacheProvider)
+	if !ok {
+		return nil
+	}
+	res := map[string]common.AdaptiveCache{}
+	for name, cache := range provider.GetAdaptiveCaches() {
+		res[name] = &syncedAdaptiveCache{cache: cache, mu: &s.mu}
+	}
+	return res
+}
+
+// CountDirtyNodes reports the number of modified nodes of the wrapped state
+// not yet written to disk. The count is obtained under the state's lock.
+func (s *syncedState) CountDirtyNodes() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.state.(DirtyNodeCounter)
+	if !ok {
+		return 0, fmt.Errorf("%w: state does not count dirty nodes", UnsupportedConfiguration)
+	}
+	return counter.CountDirtyNodes()
+}
+
 func (s *syncedState) Flush() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -120,6 +173,12 @@ func (s *syncedState) GetMemoryFootprint() *common.MemoryFootprint {
 	return s.state.GetMemoryFootprint()
 }
 
+func (s *syncedState) GetDiskFootprint() (*common.DiskFootprint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.GetDiskFootprint()
+}
+
 func (s *syncedState) GetArchiveState(block uint64) (State, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -161,3 +220,22 @@ func (s *syncedState) GetSnapshotVerifier(metadata []byte) (backend.SnapshotVeri
 	defer s.mu.Unlock()
 	return s.state.GetSnapshotVerifier(metadata)
 }
+
+// syncedAdaptiveCache wraps a cache of a synced state such that it is
+// accessed under the state's lock.
+type syncedAdaptiveCache struct {
+	cache common.AdaptiveCache
+	mu    *sync.Mutex
+}
+
+func (c *syncedAdaptiveCache) GetCacheStatistics() common.CacheStatistics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.GetCacheStatistics()
+}
+
+func (c *syncedAdaptiveCache) SetCacheCapacity(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.SetCacheCapacity(capacity)
+}